@@ -3,13 +3,11 @@ package api
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/fatih/color"
 )
 
 // Returns a cookie with given value and website to be used in requests
@@ -55,6 +53,9 @@ func getHeaders(website, userAgent string) map[string]string {
 	case utils.KEMONO :
 		referer = utils.KEMONO_URL
 		origin = utils.KEMONO_URL
+	case utils.DLSITE:
+		referer = utils.DLSITE_PLAY_URL
+		origin = utils.DLSITE_PLAY_URL
 	default :
 		// Shouldn't happen but could happen during development
 		panic(
@@ -85,6 +86,8 @@ func VerifyCookie(cookie *http.Cookie, website, userAgent string) (bool, error)
 		websiteUrl = utils.PIXIV_URL + "/dashboard"
 	case utils.KEMONO:
 		websiteUrl = utils.KEMONO_URL + "/favorites"
+	case utils.DLSITE:
+		websiteUrl = utils.DLSITE_PLAY_API_URL + "/product_count"
 	default:
 		// Shouldn't happen but could happen during development
 		panic(
@@ -100,14 +103,17 @@ func VerifyCookie(cookie *http.Cookie, website, userAgent string) (bool, error)
 		return false, nil
 	}
 
-	useHttp3 := utils.IsHttp3Supported(website, false)
+	// DLsite Play's API returns a 401 status code instead of
+	// redirecting to the login page if the cookie is invalid.
+	isApi := (website == utils.DLSITE)
+	useHttp3 := utils.IsHttp3Supported(website, isApi)
 	cookies := []*http.Cookie{cookie}
 	resp, err := request.CallRequest(
 		&request.RequestArgs{
 			Method:      "HEAD",
 			Url:         websiteUrl,
 			Cookies:     cookies,
-			CheckStatus: true,
+			CheckStatus: !isApi,
 			Http3:       useHttp3,
 			Http2:       !useHttp3,
 			Headers:     getHeaders(website, userAgent),
@@ -117,6 +123,9 @@ func VerifyCookie(cookie *http.Cookie, website, userAgent string) (bool, error)
 		return false, err
 	}
 	resp.Body.Close()
+	if isApi {
+		return resp.StatusCode == 200, nil
+	}
 
 	// check if the cookie is valid
 	resUrl := resp.Request.URL.String()
@@ -141,24 +150,20 @@ func VerifyAndGetCookie(website, cookieValue, userAgent string) *http.Cookie {
 			true,
 			utils.ERROR,
 		)
-		color.Red(
-			fmt.Sprintf(
-				"error %d: could not verify %s cookie.\nPlease refer to the log file for more details.",
-				utils.INPUT_ERROR,
-				utils.GetReadableSiteStr(website),
-			),
+		utils.ExitWithErrorf(
+			utils.EXIT_NETWORK_ERROR,
+			"error %d: could not verify %s cookie.\nPlease refer to the log file for more details.",
+			utils.INPUT_ERROR,
+			utils.GetReadableSiteStr(website),
 		)
-		os.Exit(1)
 	}
 	if cookieValue != "" && !cookieIsValid {
-		color.Red(
-			fmt.Sprintf(
-				"error %d: %s cookie is invalid",
-				utils.INPUT_ERROR,
-				utils.GetReadableSiteStr(website),
-			),
+		utils.ExitWithErrorf(
+			utils.EXIT_AUTH_ERROR,
+			"error %d: %s cookie is invalid",
+			utils.AUTH_ERROR,
+			utils.GetReadableSiteStr(website),
 		)
-		os.Exit(1)
 	}
 	return cookie
 }
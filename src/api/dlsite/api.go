@@ -0,0 +1,166 @@
+package dlsite
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/dlsite/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+func getDlsiteHeaders() map[string]string {
+	return map[string]string{
+		"Origin":  utils.DLSITE_PLAY_URL,
+		"Referer": utils.DLSITE_PLAY_URL + "/",
+	}
+}
+
+func callDlsitePlayApi(reqUrl string, params map[string]string, dlOptions *DlsiteDlOptions, format any) error {
+	useHttp3 := utils.IsHttp3Supported(utils.DLSITE, true)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url:         reqUrl,
+			Method:      "GET",
+			Params:      params,
+			Headers:     getDlsiteHeaders(),
+			Cookies:     dlOptions.SessionCookies,
+			UserAgent:   dlOptions.Configs.UserAgent,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"dlsite error %d: failed to get a response from %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			reqUrl,
+			err,
+		)
+	}
+	return utils.LoadJsonFromResponse(res, format)
+}
+
+// Retrieves all the purchased works of the user from DLsite Play
+func getPurchasedWorks(dlOptions *DlsiteDlOptions) ([]*models.DlsiteWorkToDl, error) {
+	var works []*models.DlsiteWorkToDl
+	reqUrl := utils.DLSITE_PLAY_API_URL + "/purchases"
+	for page := 1; ; page++ {
+		var purchasesJson models.DlsitePurchasesJson
+		err := callDlsitePlayApi(
+			reqUrl,
+			map[string]string{"page": strconv.Itoa(page)},
+			dlOptions,
+			&purchasesJson,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, work := range purchasesJson.Works {
+			works = append(works, &models.DlsiteWorkToDl{
+				WorkNo:    work.WorkNo,
+				Title:     work.Name.Get(),
+				MakerName: work.Maker.Name.Get(),
+			})
+		}
+
+		if len(purchasesJson.Works) == 0 || len(works) >= purchasesJson.Total {
+			break
+		}
+	}
+	return works, nil
+}
+
+// Returns the base URL of the work's contents with the signed query parameters
+func getDownloadToken(workNo string, dlOptions *DlsiteDlOptions) (string, string, error) {
+	var tokenJson models.DlsiteDownloadTokenJson
+	err := callDlsitePlayApi(
+		utils.DLSITE_PLAY_API_URL+"/download_token",
+		map[string]string{"workno": workNo},
+		dlOptions,
+		&tokenJson,
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	if tokenJson.Url == "" {
+		return "", "", fmt.Errorf(
+			"dlsite error %d: no download URL returned for %s, the work may not be available on DLsite Play",
+			utils.RESPONSE_ERROR,
+			workNo,
+		)
+	}
+
+	query := url.Values{}
+	for key, value := range tokenJson.Params {
+		query.Set(key, fmt.Sprint(value))
+	}
+	return tokenJson.Url, query.Encode(), nil
+}
+
+func getZipTree(baseUrl, query string, dlOptions *DlsiteDlOptions) (*models.DlsiteZipTreeJson, error) {
+	var zipTree models.DlsiteZipTreeJson
+	if err := callDlsitePlayApi(baseUrl+"ziptree.json?"+query, nil, dlOptions, &zipTree); err != nil {
+		return nil, err
+	}
+	return &zipTree, nil
+}
+
+func getWorkDetails(work *models.DlsiteWorkToDl, downloadPath string, dlOptions *DlsiteDlOptions) ([]*request.ToDownload, error) {
+	baseUrl, query, err := getDownloadToken(work.WorkNo, dlOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	zipTree, err := getZipTree(baseUrl, query, dlOptions)
+	if err != nil {
+		return nil, err
+	}
+	return processZipTree(zipTree, work, baseUrl, query, downloadPath), nil
+}
+
+// Retrieves the files to download for each of the given works from DLsite Play
+//
+// Note: The API calls are done sequentially to avoid overloading DLsite's servers
+func getMultipleWorkDetails(works []*models.DlsiteWorkToDl, downloadPath string, dlOptions *DlsiteDlOptions) []*request.ToDownload {
+	worksLen := len(works)
+	baseMsg := "Getting file details from DLsite Play [%d/" + fmt.Sprintf("%d]...", worksLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting file details from %d works on DLsite Play!",
+			worksLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting file details from %d works on DLsite Play.\nPlease refer to the logs for more details.",
+			worksLen,
+		),
+		worksLen,
+	)
+	progress.Start()
+
+	hasErr := false
+	var urlsToDownload []*request.ToDownload
+	for _, work := range works {
+		toDownload, err := getWorkDetails(work, downloadPath, dlOptions)
+		if err != nil {
+			hasErr = true
+			utils.LogError(err, "", false, utils.ERROR)
+		} else {
+			urlsToDownload = append(urlsToDownload, toDownload...)
+		}
+		progress.MsgIncrement(baseMsg)
+	}
+	progress.Stop(hasErr)
+	return urlsToDownload
+}
@@ -0,0 +1,63 @@
+package dlsite
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// DLsite product IDs, e.g. RJ123456, RJ01012345, BJ123456, VJ123456, etc.
+const WORK_ID_REGEX_STR = `[A-Z]{2}\d{6,8}`
+
+var WORK_ID_REGEX = regexp.MustCompile(`^` + WORK_ID_REGEX_STR + `$`)
+
+// DlsiteDl is the struct that contains the IDs of the DLsite works to download.
+//
+// If no work IDs are provided, all the purchased works of the user will be downloaded.
+type DlsiteDl struct {
+	WorkIds []string
+}
+
+// ValidateArgs validates the IDs of the DLsite works to download.
+//
+// Should be called after initialising the struct.
+func (d *DlsiteDl) ValidateArgs() {
+	for idx, workId := range d.WorkIds {
+		workId = strings.ToUpper(strings.TrimSpace(workId))
+		if !WORK_ID_REGEX.MatchString(workId) {
+			utils.ExitWithErrorf(
+				utils.EXIT_INPUT_ERROR,
+				"dlsite error %d: invalid DLsite work ID %q, must be in the format of \"RJ123456\"",
+				utils.INPUT_ERROR,
+				workId,
+			)
+		}
+		d.WorkIds[idx] = workId
+	}
+	d.WorkIds = utils.RemoveSliceDuplicates(d.WorkIds)
+}
+
+// DlsiteDlOptions is the struct that contains the options for downloading from DLsite Play.
+type DlsiteDlOptions struct {
+	Configs *configs.Config
+
+	SessionCookieId string
+	SessionCookies  []*http.Cookie
+}
+
+// ValidateArgs validates the session cookie ID of the DLsite account to download from.
+//
+// Should be called after initialising the struct.
+func (d *DlsiteDlOptions) ValidateArgs(userAgent string) {
+	if d.SessionCookieId != "" {
+		d.SessionCookies = []*http.Cookie{
+			api.VerifyAndGetCookie(utils.DLSITE, d.SessionCookieId, userAgent),
+		}
+	} else if len(d.SessionCookies) == 0 {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "dlsite error %d: session cookie ID is required to download your purchased works", utils.INPUT_ERROR)
+	}
+}
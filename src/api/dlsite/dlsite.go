@@ -0,0 +1,80 @@
+package dlsite
+
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/dlsite/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Filters the purchased works to the given work IDs.
+//
+// Works that were not found in the user's purchases will be logged.
+func filterWorks(purchasedWorks []*models.DlsiteWorkToDl, workIds []string) []*models.DlsiteWorkToDl {
+	purchasedMap := make(map[string]*models.DlsiteWorkToDl, len(purchasedWorks))
+	for _, work := range purchasedWorks {
+		purchasedMap[work.WorkNo] = work
+	}
+
+	works := make([]*models.DlsiteWorkToDl, 0, len(workIds))
+	for _, workId := range workIds {
+		if work, ok := purchasedMap[workId]; ok {
+			works = append(works, work)
+		} else {
+			utils.LogError(
+				nil,
+				"DLsite work "+workId+" was not found in your purchases and will be skipped",
+				false,
+				utils.INFO,
+			)
+		}
+	}
+	return works
+}
+
+// Start the download process for DLsite Play
+func DlsiteDownloadProcess(dlsiteDl *DlsiteDl, dlOptions *DlsiteDlOptions) {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting purchased works from DLsite Play...",
+		"Finished getting purchased works from DLsite Play!",
+		"Something went wrong while getting purchased works from DLsite Play.\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+	works, err := getPurchasedWorks(dlOptions)
+	hasErr := (err != nil)
+	if hasErr {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+	progress.Stop(hasErr)
+	if hasErr {
+		return
+	}
+
+	if len(dlsiteDl.WorkIds) > 0 {
+		works = filterWorks(works, dlsiteDl.WorkIds)
+	}
+
+	var urlsToDownload []*request.ToDownload
+	if len(works) > 0 {
+		urlsToDownload = getMultipleWorkDetails(works, utils.DOWNLOAD_PATH, dlOptions)
+	}
+
+	if len(urlsToDownload) > 0 {
+		request.DownloadUrls(
+			urlsToDownload,
+			&request.DlOptions{
+				MaxConcurrency: utils.MAX_CONCURRENT_DOWNLOADS,
+				Headers:        getDlsiteHeaders(),
+				UseHttp3:       utils.IsHttp3Supported(utils.DLSITE, false),
+			},
+			dlOptions.Configs,
+		)
+		utils.AlertWithoutErr(utils.Title, i18n.T("Downloaded all works from DLsite Play!"))
+	} else {
+		utils.AlertWithoutErr(utils.Title, i18n.T("No works to download from DLsite Play!"))
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import "encoding/json"
+
+type LocalisedName map[string]string
+
+// Returns the Japanese name if available, otherwise any of the available names
+func (n LocalisedName) Get() string {
+	if name, ok := n["ja_JP"]; ok && name != "" {
+		return name
+	}
+	for _, name := range n {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+type DlsiteWork struct {
+	WorkNo string        `json:"workno"`
+	Name   LocalisedName `json:"name"`
+	Maker  struct {
+		Name LocalisedName `json:"name"`
+	} `json:"maker"`
+}
+
+type DlsitePurchasesJson struct {
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+	Total  int          `json:"total"`
+	Works  []DlsiteWork `json:"works"`
+}
+
+type DlsiteDownloadTokenJson struct {
+	Url    string         `json:"url"`
+	Params map[string]any `json:"params"`
+}
+
+type DlsiteZipTreeEntry struct {
+	Type     string               `json:"type"` // "folder" or "file"
+	Name     string               `json:"name"`
+	Path     string               `json:"path"`
+	HashName string               `json:"hashname"`
+	Children []DlsiteZipTreeEntry `json:"children"`
+}
+
+type DlsiteZipTreeJson struct {
+	Hash string `json:"hash"`
+
+	// The key is the hashname of the file
+	// and the value would have a "type" key with the file type, e.g. "image",
+	// where the files can be found under the key of the file type.
+	PlayFile map[string]map[string]json.RawMessage `json:"playfile"`
+	Tree     []DlsiteZipTreeEntry                  `json:"tree"`
+}
+
+type DlsitePlayFileJson struct {
+	Files map[string]struct {
+		Name   string `json:"name"`
+		Length int64  `json:"length"`
+	} `json:"files"`
+}
+
+type DlsiteWorkToDl struct {
+	WorkNo    string
+	Title     string
+	MakerName string
+}
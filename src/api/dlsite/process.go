@@ -0,0 +1,114 @@
+package dlsite
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/dlsite/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Returns the optimised filename of the given play file from the ziptree.json
+//
+// DLsite Play only serves the files that have been optimised for viewing in the browser
+// which may have a different file extension from the original file, e.g. ".png" -> ".jpg".
+func getOptimisedFilename(playFile map[string]json.RawMessage) (string, error) {
+	var fileType string
+	if err := json.Unmarshal(playFile["type"], &fileType); err != nil {
+		return "", fmt.Errorf(
+			"dlsite error %d: failed to get the file type from ziptree.json, more info => %v",
+			utils.JSON_ERROR,
+			err,
+		)
+	}
+
+	var fileInfo models.DlsitePlayFileJson
+	if err := json.Unmarshal(playFile[fileType], &fileInfo); err != nil {
+		return "", fmt.Errorf(
+			"dlsite error %d: failed to get the %q file info from ziptree.json, more info => %v",
+			utils.JSON_ERROR,
+			fileType,
+			err,
+		)
+	}
+
+	optimised, ok := fileInfo.Files["optimized"]
+	if !ok || optimised.Name == "" {
+		return "", fmt.Errorf(
+			"dlsite error %d: no downloadable file found for the %q file type",
+			utils.RESPONSE_ERROR,
+			fileType,
+		)
+	}
+	return optimised.Name, nil
+}
+
+func processZipTreeEntries(entries []models.DlsiteZipTreeEntry, zipTree *models.DlsiteZipTreeJson, dirPath, baseUrl, query string) []*request.ToDownload {
+	var urlsToDownload []*request.ToDownload
+	for _, entry := range entries {
+		switch entry.Type {
+		case "folder":
+			folderName := entry.Name
+			if folderName == "" {
+				folderName = filepath.Base(entry.Path)
+			}
+			urlsToDownload = append(
+				urlsToDownload,
+				processZipTreeEntries(
+					entry.Children,
+					zipTree,
+					filepath.Join(dirPath, utils.CleanPathName(folderName)),
+					baseUrl,
+					query,
+				)...,
+			)
+		case "file":
+			playFile, ok := zipTree.PlayFile[entry.HashName]
+			if !ok {
+				continue
+			}
+
+			optimisedName, err := getOptimisedFilename(playFile)
+			if err != nil {
+				utils.LogError(
+					err,
+					fmt.Sprintf("skipping %q as it could not be downloaded from DLsite Play", entry.Name),
+					false,
+					utils.ERROR,
+				)
+				continue
+			}
+
+			// keep the original filename but use the extension of the optimised file
+			filename := utils.RemoveExtFromFilename(utils.CleanPathName(entry.Name)) + strings.ToLower(filepath.Ext(optimisedName))
+			urlsToDownload = append(urlsToDownload, &request.ToDownload{
+				Url:      fmt.Sprintf("%soptimized/%s?%s", baseUrl, optimisedName, query),
+				FilePath: filepath.Join(dirPath, filename),
+			})
+		}
+	}
+	return urlsToDownload
+}
+
+func processZipTree(zipTree *models.DlsiteZipTreeJson, work *models.DlsiteWorkToDl, baseUrl, query, downloadPath string) []*request.ToDownload {
+	workFolderPath := utils.GetPostFolder(
+		filepath.Join(downloadPath, utils.DLSITE_TITLE),
+		work.MakerName,
+		work.WorkNo,
+		work.Title,
+	)
+	urlsToDownload := processZipTreeEntries(zipTree.Tree, zipTree, workFolderPath, baseUrl, query)
+	events.PostResolved(&events.Post{
+		Site:      utils.DLSITE,
+		Id:        work.WorkNo,
+		Title:     work.Title,
+		Creator:   work.MakerName,
+		Folder:    workFolderPath,
+		FileCount: len(urlsToDownload),
+	})
+	return urlsToDownload
+}
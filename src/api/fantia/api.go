@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"strconv"
-	"sync"
 	"time"
-	"os"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/pipeline"
+	"github.com/KJHJason/Cultured-Downloader-CLI/postdb"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -71,6 +74,10 @@ func getFantiaPostDetails(postArg *fantiaPostArgs, dlOptions *FantiaDlOptions) (
 		errCode := utils.CONNECTION_ERROR
 		if err == nil {
 			errCode = res.StatusCode
+			res.Body.Close()
+			if res.StatusCode == http.StatusNotFound {
+				events.PostNotFound(utils.FANTIA, postArg.postId)
+			}
 		}
 
 		errMsg := fmt.Sprintf(
@@ -187,13 +194,11 @@ func SolveCaptcha(dlOptions *FantiaDlOptions, alertUser bool) error {
 		// Since reCAPTCHA is per session, the program shall avoid 
 		// trying to solve it and alert the user to login or create a Fantia account.
 		// It is possible that the reCAPTCHA is per IP address for guests, but I'm not sure.
-		color.Red(
-			fmt.Sprintf(
-				"fantia error %d: reCAPTCHA detected but you are not logged in. Please login to Fantia and try again.",
-				utils.CAPTCHA_ERROR,
-			),
+		utils.ExitWithErrorf(
+			utils.EXIT_AUTH_ERROR,
+			"fantia error %d: reCAPTCHA detected but you are not logged in. Please login to Fantia and try again.",
+			utils.CAPTCHA_ERROR,
 		)
-		os.Exit(1)
 	}
 
 	if dlOptions.AutoSolveCaptcha {
@@ -249,7 +254,7 @@ func dlFantiaPost(count, maxCount int, postId string, dlOptions *FantiaDlOptions
 		err = SolveCaptcha(dlOptions, true)
 		if err != nil {
 			if err := handleCaptchaErr(err, dlOptions, true); err != nil {
-				os.Exit(1)
+				utils.Exit(utils.EXIT_AUTH_ERROR)
 			}
 		}
 
@@ -337,15 +342,21 @@ func parseCreatorHtml(res *http.Response, creatorId string) ([]string, error) {
 }
 
 // Get all the creator's posts by using goquery to parse the HTML response to get the post IDs
-func getCreatorPosts(creatorId, pageNum string, dlOptions *FantiaDlOptions) ([]string, error) {
+//
+// Returns true if all of the creator's posts were listed, i.e. the pagination was not limited or stopped early.
+func getCreatorPosts(creatorId, pageNum string, dlOptions *FantiaDlOptions) ([]string, bool, error) {
 	var postIds []string
 	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	useHttp3 := utils.IsHttp3Supported(utils.FANTIA, false)
+	seenTracker := dlOptions.Configs.NewSeenTracker(
+		filepath.Join(utils.DOWNLOAD_PATH, utils.FANTIA_TITLE),
+	)
 	curPage := minPage
+	isFullListing := minPage <= 1 && !hasMax
 	for {
 		url := fmt.Sprintf("%s/fanclubs/%s/posts", utils.FANTIA_URL, creatorId)
 		params := map[string]string{
@@ -375,22 +386,66 @@ func getCreatorPosts(creatorId, pageNum string, dlOptions *FantiaDlOptions) ([]s
 				url,
 				err,
 			)
-			return nil, err
+			return nil, false, err
 		}
 
 		creatorPostIds, err := parseCreatorHtml(res, creatorId)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
-		postIds = append(postIds, creatorPostIds...)
+		numOfPosts, stop := seenTracker.SeenMultiple(creatorPostIds)
+		postIds = append(postIds, creatorPostIds[:numOfPosts]...)
 
-		// if there are no more posts, break
-		if len(creatorPostIds) == 0 || (hasMax && curPage >= maxPage) {
+		// if there are no more posts or the remaining posts were already downloaded, break
+		if stop || len(creatorPostIds) == 0 || (hasMax && curPage >= maxPage) {
+			isFullListing = isFullListing && !stop
 			break
 		}
 		curPage++
 	}
-	return postIds, nil
+
+	if dlOptions.Configs.IsAscOrder() {
+		utils.ReverseSlice(postIds)
+	}
+	return postIds, isFullListing, nil
+}
+
+// Requests the details of the recorded posts of the fanclub that are not in its full listing
+// so that the posts that have been deleted are flagged, as their details are not requested otherwise
+func probeUnlistedPosts(fanclubId string, listedPostIds []string, dlOptions *FantiaDlOptions) {
+	useHttp3 := utils.IsHttp3Supported(utils.FANTIA, true)
+	for _, postId := range postdb.GetUnlistedPostIds(utils.FANTIA, fanclubId, listedPostIds) {
+		res, err := request.CallRequest(
+			&request.RequestArgs{
+				Method:  "GET",
+				Url:     fantiaPostUrl + postId,
+				Cookies: dlOptions.SessionCookies,
+				Headers: map[string]string{
+					"Referer":      fmt.Sprintf("%s/posts/%s", utils.FANTIA_URL, postId),
+					"x-csrf-token": dlOptions.CsrfToken,
+				},
+				Http2:     !useHttp3,
+				Http3:     useHttp3,
+				UserAgent: dlOptions.Configs.UserAgent,
+			},
+		)
+		if err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			events.PostNotFound(utils.FANTIA, postId)
+		}
+	}
+}
+
+// Returns the IDs of all the current posts of the fanclub, e.g. to audit the downloaded posts
+//
+// The options' Configs should not stop the pagination early, i.e. its StopAfterSeen should be 0.
+func GetFanclubPostIds(fanclubId string, dlOptions *FantiaDlOptions) ([]string, error) {
+	postIds, _, err := getCreatorPosts(fanclubId, "", dlOptions)
+	return postIds, err
 }
 
 // Retrieves all the posts based on the slice of creator IDs and updates its PostIds slice
@@ -405,70 +460,35 @@ func (f *FantiaDl) getCreatorsPosts(dlOptions *FantiaDlOptions) {
 		)
 	}
 
-	var wg sync.WaitGroup
-	maxConcurrency := utils.MAX_API_CALLS
-	if creatorIdsLen < maxConcurrency {
-		maxConcurrency = creatorIdsLen
-	}
-	queue := make(chan struct{}, maxConcurrency)
-	resChan := make(chan []string, creatorIdsLen)
-	errChan := make(chan error, creatorIdsLen)
-
-	baseMsg := "Getting post ID(s) from Fanclubs(s) on Fantia [%d/" + fmt.Sprintf("%d]...", creatorIdsLen)
-	progress := spinner.New(
-		spinner.REQ_SPINNER,
-		"fgHiYellow",
-		fmt.Sprintf(
-			baseMsg,
-			0,
-		),
-		fmt.Sprintf(
-			"Finished getting post ID(s) from %d Fanclubs(s) on Fantia!",
-			creatorIdsLen,
-		),
-		fmt.Sprintf(
-			"Something went wrong while getting post IDs from %d Fanclubs(s) on Fantia.\nPlease refer to the logs for more details.",
-			creatorIdsLen,
-		),
-		creatorIdsLen,
-	)
-	progress.Start()
-	for idx, creatorId := range f.FanclubIds {
-		wg.Add(1)
-		go func(creatorId string, pageNumIdx int) {
-			defer func() {
-				wg.Done()
-				<-queue
-			}()
-
-			queue <- struct{}{}
-			postIds, err := getCreatorPosts(
-				creatorId,
-				f.FanclubPageNums[pageNumIdx],
+	results, _ := pipeline.Run(&pipeline.Options[[]string]{
+		Count:          creatorIdsLen,
+		MaxConcurrency: utils.MAX_API_CALLS,
+		Progress: &pipeline.Progress{
+			SpinnerType: spinner.REQ_SPINNER,
+			Msg:         i18n.T("Getting post ID(s) from Fanclubs(s) on Fantia"),
+			SuccessMsg: i18n.Sprintf(
+				"Finished getting post ID(s) from %d Fanclubs(s) on Fantia!",
+				creatorIdsLen,
+			),
+			ErrMsg: i18n.Sprintf(
+				"Something went wrong while getting post IDs from %d Fanclubs(s) on Fantia.\nPlease refer to the logs for more details.",
+				creatorIdsLen,
+			),
+		},
+		Task: func(idx int) ([]string, string, error) {
+			postIds, isFullListing, err := getCreatorPosts(
+				f.FanclubIds[idx],
+				f.FanclubPageNums[idx],
 				dlOptions,
 			)
-			if err != nil {
-				errChan <- err
-			} else {
-				resChan <- postIds
+			if err == nil && isFullListing {
+				probeUnlistedPosts(f.FanclubIds[idx], postIds, dlOptions)
 			}
+			return postIds, "", err
+		},
+	})
 
-			progress.MsgIncrement(baseMsg)
-		}(creatorId, idx)
-	}
-	wg.Wait()
-	close(queue)
-	close(resChan)
-	close(errChan)
-
-	hasErr := false
-	if len(errChan) > 0 {
-		hasErr = true
-		utils.LogErrors(false, errChan, utils.ERROR)
-	}
-	progress.Stop(hasErr)
-
-	for postIdsRes := range resChan {
+	for _, postIdsRes := range results {
 		f.PostIds = append(f.PostIds, postIdsRes...)
 	}
 	f.PostIds = utils.RemoveSliceDuplicates(f.PostIds)
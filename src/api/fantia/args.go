@@ -3,6 +3,8 @@ package fantia
 import (
 	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
 	"sync"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api"
@@ -13,20 +15,76 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+var (
+	POST_URL_REGEX = regexp.MustCompile(
+		`^https://fantia\.jp/posts/(?P<postId>\d+)/?$`,
+	)
+	POST_URL_REGEX_POST_ID_INDEX = POST_URL_REGEX.SubexpIndex("postId")
+
+	FANCLUB_URL_REGEX = regexp.MustCompile(
+		`^https://fantia\.jp/fanclubs/(?P<fanclubId>\d+)(?:/posts)?/?$`,
+	)
+	FANCLUB_URL_REGEX_FANCLUB_ID_INDEX = FANCLUB_URL_REGEX.SubexpIndex("fanclubId")
+)
+
 // FantiaDl is the struct that contains the
 // IDs of the Fantia fanclubs and posts to download.
 type FantiaDl struct {
 	FanclubIds      []string
+	FanclubUrls     []string
 	FanclubPageNums []string
-	PostIds         []string
+
+	PostIds  []string
+	PostUrls []string
+}
+
+// Returns the IDs extracted from the URLs with the given regex
+func getIdsFromUrls(urls []string, regex *regexp.Regexp, idIdx int) []string {
+	ids := make([]string, len(urls))
+	for i, url := range urls {
+		ids[i] = regex.FindStringSubmatch(url)[idIdx]
+	}
+	return ids
 }
 
-// ValidateArgs validates the IDs of the Fantia fanclubs and posts to download.
+// ValidateArgs validates the IDs and URLs of the Fantia fanclubs and posts to download.
 //
 // It also validates the page numbers of the fanclubs to download.
 //
 // Should be called after initialising the struct.
 func (f *FantiaDl) ValidateArgs() {
+	valid, outlier := utils.SliceMatchesRegex(FANCLUB_URL_REGEX, f.FanclubUrls)
+	if !valid {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"fantia error %d: invalid fanclub URL found for Fantia: %s",
+			utils.INPUT_ERROR,
+			outlier,
+		)
+	}
+
+	valid, outlier = utils.SliceMatchesRegex(POST_URL_REGEX, f.PostUrls)
+	if !valid {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"fantia error %d: invalid post URL found for Fantia: %s",
+			utils.INPUT_ERROR,
+			outlier,
+		)
+	}
+
+	// the page numbers correspond to the fanclub IDs followed by the fanclub URLs
+	f.FanclubIds = append(
+		f.FanclubIds,
+		getIdsFromUrls(f.FanclubUrls, FANCLUB_URL_REGEX, FANCLUB_URL_REGEX_FANCLUB_ID_INDEX)...,
+	)
+	f.PostIds = append(
+		f.PostIds,
+		getIdsFromUrls(f.PostUrls, POST_URL_REGEX, POST_URL_REGEX_POST_ID_INDEX)...,
+	)
+	f.FanclubUrls = nil
+	f.PostUrls = nil
+
 	utils.ValidateIds(f.PostIds)
 	utils.ValidateIds(f.FanclubIds)
 	f.PostIds = utils.RemoveSliceDuplicates(f.PostIds)
@@ -49,6 +107,18 @@ func (f *FantiaDl) ValidateArgs() {
 	)
 }
 
+const (
+	POST_FILTER_ALL  = "all"
+	POST_FILTER_FREE = "free"
+	POST_FILTER_PAID = "paid"
+)
+
+var ACCEPTED_POST_FILTERS = []string{
+	POST_FILTER_ALL,
+	POST_FILTER_FREE,
+	POST_FILTER_PAID,
+}
+
 // FantiaDlOptions is the struct that contains the options for downloading from Fantia.
 type FantiaDlOptions struct {
 	DlThumbnails     bool
@@ -57,7 +127,15 @@ type FantiaDlOptions struct {
 	DlGdrive         bool
 	AutoSolveCaptcha bool // whether to use chromedp to solve reCAPTCHA automatically
 
-	GdriveClient    *gdrive.GDrive
+	// PostFilter is used to only download the contents of posts that are
+	// free (POST_FILTER_FREE) or from plans that the user is subscribed to (POST_FILTER_PAID).
+	PostFilter string
+
+	// DlFollowing downloads from all the fanclubs
+	// that the user has joined or is following
+	DlFollowing bool
+
+	GdriveClient *gdrive.GDrive
 
 	Configs         *configs.Config
 
@@ -134,11 +212,34 @@ func (f *FantiaDlOptions) GetCsrfToken(userAgent string) error {
 //
 // Should be called after initialising the struct.
 func (f *FantiaDlOptions) ValidateArgs(userAgent string) error {
+	if f.PostFilter == "" {
+		f.PostFilter = POST_FILTER_ALL
+	}
+	f.PostFilter = strings.ToLower(f.PostFilter)
+	utils.ValidateStrArgs(
+		f.PostFilter,
+		ACCEPTED_POST_FILTERS,
+		[]string{
+			fmt.Sprintf(
+				"fantia error %d: Post filter %s is not allowed",
+				utils.INPUT_ERROR,
+				f.PostFilter,
+			),
+		},
+	)
+
 	if f.SessionCookieId != "" {
 		f.SessionCookies = []*http.Cookie{
 			api.VerifyAndGetCookie(utils.FANTIA, f.SessionCookieId, userAgent),
 		}
 	}
+	if f.DlFollowing && len(f.SessionCookies) == 0 {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"fantia error %d: a session cookie is required to download from the fanclubs you have joined or are following",
+			utils.INPUT_ERROR,
+		)
+	}
 
 	if f.DlGdrive && f.GdriveClient == nil {
 		f.DlGdrive = false
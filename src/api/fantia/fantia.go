@@ -1,7 +1,11 @@
 package fantia
 
 import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/filehost"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/twitter"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
@@ -11,6 +15,10 @@ func FantiaDownloadProcess(fantiaDl *FantiaDl, fantiaDlOptions *FantiaDlOptions)
 		return
 	}
 
+	if fantiaDlOptions.DlFollowing {
+		fantiaDl.addFollowedFanclubs(fantiaDlOptions)
+	}
+
 	if len(fantiaDl.FanclubIds) > 0 {
 		fantiaDl.getCreatorsPosts(fantiaDlOptions)
 	}
@@ -22,14 +30,16 @@ func FantiaDownloadProcess(fantiaDl *FantiaDl, fantiaDlOptions *FantiaDlOptions)
 		downloadedPosts = true
 	}
 
-	if fantiaDlOptions.GdriveClient != nil && len(gdriveLinks) > 0 {
+	if fantiaDlOptions.GdriveClient != nil && (len(gdriveLinks) > 0 || gdrive.HasDueDeferredFiles()) {
 		fantiaDlOptions.GdriveClient.DownloadGdriveUrls(gdriveLinks, fantiaDlOptions.Configs)
 		downloadedPosts = true
 	}
+	filehost.DownloadLinks(fantiaDlOptions.Configs)
+	twitter.ProcessLinks(fantiaDlOptions.Configs)
 
 	if downloadedPosts {
-		utils.AlertWithoutErr(utils.Title, "Downloaded all posts from Fantia!")
+		utils.AlertWithoutErr(utils.Title, i18n.T("Downloaded all posts from Fantia!"))
 	} else {
-		utils.AlertWithoutErr(utils.Title, "No posts to download from Fantia!")
+		utils.AlertWithoutErr(utils.Title, i18n.T("No posts to download from Fantia!"))
 	}
 }
@@ -0,0 +1,171 @@
+package fantia
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/fantia/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Fantia's "My Page" lists at most this many pages of the user's paid plans
+// which is used to prevent an infinite loop if the page stops changing
+const maxPaidPlanPages = 100
+
+var fanclubHrefRegex = regexp.MustCompile(`^/fanclubs/(\d+)`)
+
+// Returns the IDs of the fanclubs that the user is following,
+// which includes the fanclubs that the user has joined a free plan of
+func getFollowedFanclubIds(dlOptions *FantiaDlOptions) ([]string, error) {
+	useHttp3 := utils.IsHttp3Supported(utils.FANTIA, true)
+	url := utils.FANTIA_URL + "/api/v1/me/fanclubs"
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Method:  "GET",
+			Url:     url,
+			Cookies: dlOptions.SessionCookies,
+			Headers: map[string]string{
+				"Referer":      utils.FANTIA_URL,
+				"x-csrf-token": dlOptions.CsrfToken,
+			},
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+			UserAgent:   dlOptions.Configs.UserAgent,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"fantia error %d: failed to get followed fanclubs from %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			url,
+			err,
+		)
+	}
+
+	var followedJson models.FantiaFollowedFanclubsJson
+	if err := utils.LoadJsonFromResponse(res, &followedJson); err != nil {
+		return nil, err
+	}
+
+	fanclubIds := make([]string, len(followedJson.FanclubIds))
+	for i, fanclubId := range followedJson.FanclubIds {
+		fanclubIds[i] = strconv.Itoa(fanclubId)
+	}
+	return fanclubIds, nil
+}
+
+// Returns the IDs of the fanclubs that the user has joined a paid plan of
+// by parsing the paginated list of plans on the user's "My Page"
+func getPaidFanclubIds(dlOptions *FantiaDlOptions) ([]string, error) {
+	var fanclubIds []string
+	seenIds := make(map[string]struct{})
+	useHttp3 := utils.IsHttp3Supported(utils.FANTIA, false)
+	url := utils.FANTIA_URL + "/mypage/users/plans"
+	for page := 1; page <= maxPaidPlanPages; page++ {
+		res, err := request.CallRequest(
+			&request.RequestArgs{
+				Method:  "GET",
+				Url:     url,
+				Cookies: dlOptions.SessionCookies,
+				Params: map[string]string{
+					"type": "not_free",
+					"page": strconv.Itoa(page),
+				},
+				Http2:       !useHttp3,
+				Http3:       useHttp3,
+				CheckStatus: true,
+				UserAgent:   dlOptions.Configs.UserAgent,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"fantia error %d: failed to get paid plans from %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				url,
+				err,
+			)
+		}
+
+		doc, err := goquery.NewDocumentFromReader(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"fantia error %d, failed to parse response body when getting paid plans, more info => %v",
+				utils.HTML_ERROR,
+				err,
+			)
+		}
+
+		newIds := 0
+		doc.Find("a[href^='/fanclubs/']").Each(func(i int, s *goquery.Selection) {
+			href, _ := s.Attr("href")
+			matched := fanclubHrefRegex.FindStringSubmatch(href)
+			if matched == nil {
+				return
+			}
+			if _, ok := seenIds[matched[1]]; ok {
+				return
+			}
+			seenIds[matched[1]] = struct{}{}
+			fanclubIds = append(fanclubIds, matched[1])
+			newIds++
+		})
+
+		// the last page has been reached if there are no new fanclubs
+		if newIds == 0 {
+			break
+		}
+	}
+	return fanclubIds, nil
+}
+
+// Adds the fanclubs that the user has joined or is following
+// to the slice of fanclub IDs to download all of their posts
+func (f *FantiaDl) addFollowedFanclubs(dlOptions *FantiaDlOptions) {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting joined and followed fanclubs from Fantia...",
+		"Finished getting joined and followed fanclubs from Fantia!",
+		"Something went wrong while getting joined and followed fanclubs from Fantia.\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+
+	var errSlice []error
+	var followedIds []string
+	for _, getFanclubIds := range []func(*FantiaDlOptions) ([]string, error){getPaidFanclubIds, getFollowedFanclubIds} {
+		fanclubIds, err := getFanclubIds(dlOptions)
+		if err != nil {
+			errSlice = append(errSlice, err)
+			continue
+		}
+		followedIds = append(followedIds, fanclubIds...)
+	}
+
+	// fanclubs that were already given will keep their page numbers
+	existingIds := make(map[string]struct{}, len(f.FanclubIds))
+	for _, fanclubId := range f.FanclubIds {
+		existingIds[fanclubId] = struct{}{}
+	}
+	for _, fanclubId := range followedIds {
+		if _, ok := existingIds[fanclubId]; ok {
+			continue
+		}
+		existingIds[fanclubId] = struct{}{}
+		f.FanclubIds = append(f.FanclubIds, fanclubId)
+		f.FanclubPageNums = append(f.FanclubPageNums, "")
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+}
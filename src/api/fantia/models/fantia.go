@@ -19,23 +19,50 @@ type FantiaContent struct {
 	// for attachments such as pdfs that are embedded in the post content
 	DownloadUri string `json:"download_uri"`
 	Filename    string `json:"filename"`
+
+	// The fanclub plan required to view the content, nil if the content is free
+	Plan *struct {
+		ID    int    `json:"id"`
+		Price int    `json:"price"`
+		Name  string `json:"name"`
+	} `json:"plan"`
+
+	// "visible" if the user has access to the content
+	VisibleStatus string `json:"visible_status"`
+}
+
+// Returns true if the content does not require a paid plan to view
+func (c *FantiaContent) IsFree() bool {
+	return c.Plan == nil || c.Plan.Price == 0
+}
+
+// Returns true if the content requires a paid plan that the user is subscribed to
+func (c *FantiaContent) IsSubscribed() bool {
+	return !c.IsFree() && c.VisibleStatus == "visible"
 }
 
 type FantiaPost struct {
 	Post struct {
-		ID      int    `json:"id"`
-		Comment string `json:"comment"` // the main post content
-		Title   string `json:"title"`
-		Thumb   struct {
+		ID       int    `json:"id"`
+		Comment  string `json:"comment"` // the main post content
+		Title    string `json:"title"`
+		PostedAt string `json:"posted_at"`
+		Thumb    struct {
 			Original string `json:"original"`
 		} `json:"thumb"`
 		Fanclub struct {
+			ID   int `json:"id"`
 			User struct {
 				Name string `json:"name"`
 			} `json:"user"`
 		} `json:"fanclub"`
-		Status       string `json:"status"`
+		Status       string          `json:"status"`
 		PostContents []FantiaContent `json:"post_contents"`
 	} `json:"post"`
 	Redirect string `json:"redirect"` // if get flagged by the system, it will redirect to this recaptcha url
 }
+
+// Response of Fantia's API for the fanclubs that the user is following
+type FantiaFollowedFanclubsJson struct {
+	FanclubIds []int `json:"fanclub_ids"`
+}
@@ -5,12 +5,14 @@ import (
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/fantia/models"
-	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
-	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
 func dlImagesFromPost(content *models.FantiaContent, postFolderPath string) []*request.ToDownload {
@@ -65,8 +67,35 @@ func dlAttachmentsFromPost(content *models.FantiaContent, postFolderPath string)
 
 var errRecaptcha = fmt.Errorf("recaptcha detected for the current session")
 
+// Returns the post contents that matches the post filter option.
+func filterPostContents(postContents []models.FantiaContent, postFilter string) []models.FantiaContent {
+	if postFilter == POST_FILTER_ALL {
+		return postContents
+	}
+
+	filtered := make([]models.FantiaContent, 0, len(postContents))
+	for _, content := range postContents {
+		if (postFilter == POST_FILTER_FREE && content.IsFree()) ||
+			(postFilter == POST_FILTER_PAID && content.IsSubscribed()) {
+			filtered = append(filtered, content)
+		}
+	}
+	return filtered
+}
+
 // Process the JSON response from Fantia's API and
 // returns a slice of urls and a slice of gdrive urls to download from
+// Returns the main comment and the comments of the post contents separated by blank lines
+func joinFantiaComments(comments []string) string {
+	var nonEmpty []string
+	for _, comment := range comments {
+		if comment = strings.TrimSpace(comment); comment != "" {
+			nonEmpty = append(nonEmpty, comment)
+		}
+	}
+	return strings.Join(nonEmpty, "\n\n")
+}
+
 func processFantiaPost(res *http.Response, downloadPath string, dlOptions *FantiaDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
 	// processes a fantia post
 	// returns a map containing the post id and the url to download the file from
@@ -87,17 +116,25 @@ func processFantiaPost(res *http.Response, downloadPath string, dlOptions *Fanti
 	}
 
 	post := postJson.Post
+	postContent := filterPostContents(post.PostContents, dlOptions.PostFilter)
+	if dlOptions.PostFilter != POST_FILTER_ALL && len(postContent) == 0 {
+		// skip the post entirely as none of its contents matches the post filter
+		return nil, nil, nil
+	}
+
 	postId := strconv.Itoa(post.ID)
 	postTitle := post.Title
 	creatorName := post.Fanclub.User.Name
-	postFolderPath := utils.GetPostFolder(
-		filepath.Join(
-			downloadPath,
-			utils.FANTIA_TITLE,
-		),
-		creatorName,
+	siteFolderPath := filepath.Join(
+		downloadPath,
+		utils.FANTIA_TITLE,
+	)
+	postFolderPath := dlOptions.Configs.GetPostFolder(
+		siteFolderPath,
+		dlOptions.Configs.GetCreatorFolder(siteFolderPath, utils.FANTIA, strconv.Itoa(post.Fanclub.ID), creatorName),
 		postId,
 		postTitle,
+		post.PostedAt,
 	)
 
 	var urlsSlice []*request.ToDownload
@@ -116,11 +153,9 @@ func processFantiaPost(res *http.Response, downloadPath string, dlOptions *Fanti
 		dlOptions.Configs.LogUrls,
 	)
 
-	postContent := post.PostContents
-	if postContent == nil {
-		return urlsSlice, gdriveLinks, nil
-	}
+	comments := []string{post.Comment}
 	for _, content := range postContent {
+		comments = append(comments, content.Comment)
 		commentGdriveLinks := gdrive.ProcessPostText(
 			content.Comment,
 			postFolderPath,
@@ -137,6 +172,26 @@ func processFantiaPost(res *http.Response, downloadPath string, dlOptions *Fanti
 			urlsSlice = append(urlsSlice, dlAttachmentsFromPost(&content, postFolderPath)...)
 		}
 	}
+	fileFilter := events.GetFileFilter(map[string]bool{
+		"thumbnails":  dlOptions.DlThumbnails,
+		"images":      dlOptions.DlImages,
+		"attachments": dlOptions.DlAttachments,
+		"gdrive":      dlOptions.DlGdrive,
+		"free_only":   dlOptions.PostFilter == POST_FILTER_FREE,
+		"paid_only":   dlOptions.PostFilter == POST_FILTER_PAID,
+	})
+	events.PostResolved(&events.Post{
+		Site:        utils.FANTIA,
+		Id:          postId,
+		Title:       postTitle,
+		Creator:     creatorName,
+		CreatorId:   strconv.Itoa(post.Fanclub.ID),
+		Folder:      postFolderPath,
+		FileCount:   len(urlsSlice) + len(gdriveLinks),
+		FileFilter:  fileFilter,
+		PublishedAt: utils.ParsePostDate(post.PostedAt),
+		Body:        joinFantiaComments(comments),
+	})
 	return urlsSlice, gdriveLinks, nil
 }
 
@@ -0,0 +1,86 @@
+package fantia
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Returns the fanclubs on the first page of Fantia's fanclub search results for the query
+func SearchFanclubs(query, userAgent string) ([]*api.SearchedCreator, error) {
+	useHttp3 := utils.IsHttp3Supported(utils.FANTIA, false)
+	url := utils.FANTIA_URL + "/fanclubs"
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Method:      "GET",
+			Url:         url,
+			Params:      map[string]string{"keyword": query},
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+			UserAgent:   userAgent,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"fantia error %d: failed to search for fanclubs from %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			url,
+			err,
+		)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"fantia error %d, failed to parse response body when searching for fanclubs, more info => %v",
+			utils.HTML_ERROR,
+			err,
+		)
+	}
+
+	// the same fanclub can be linked multiple times, e.g. by its
+	// icon and its name, so the first link with a name is used
+	var creators []*api.SearchedCreator
+	seenIds := make(map[string]*api.SearchedCreator)
+	doc.Find("a[href^='/fanclubs/']").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		matched := fanclubHrefRegex.FindStringSubmatch(href)
+		if matched == nil {
+			return
+		}
+
+		name, _ := s.Attr("title")
+		if name == "" {
+			name = strings.Join(strings.Fields(s.Text()), " ")
+		}
+		if creator, ok := seenIds[matched[1]]; ok {
+			if creator.Name == "" {
+				creator.Name = name
+			}
+			return
+		}
+
+		creator := &api.SearchedCreator{
+			Name:    name,
+			Website: utils.FANTIA,
+			Url:     fmt.Sprintf("%s/fanclubs/%s", utils.FANTIA_URL, matched[1]),
+		}
+		seenIds[matched[1]] = creator
+		creators = append(creators, creator)
+	})
+
+	// the links to fanclubs without a name are usually from the page's navigation
+	named := creators[:0]
+	for _, creator := range creators {
+		if creator.Name != "" {
+			named = append(named, creator)
+		}
+	}
+	return named, nil
+}
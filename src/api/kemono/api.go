@@ -2,19 +2,20 @@ package kemono
 
 import (
 	"fmt"
-	"sync"
+	"path/filepath"
 	"strconv"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/pipeline"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
-type kemonoChanRes struct {
+type kemonoPostRes struct {
 	urlsToDownload []*request.ToDownload
 	gdriveLinks    []*request.ToDownload
-	err            error
 }
 
 func getKemonoPartyHeaders() map[string]string {
@@ -57,178 +58,246 @@ func getPostDetails(post *models.KemonoPostToDl, downloadPath string, dlOptions
 }
 
 func getMultiplePosts(posts []*models.KemonoPostToDl, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
-	var maxConcurrency int
 	postLen := len(posts)
-	if postLen > API_MAX_CONCURRENT {
-		maxConcurrency = API_MAX_CONCURRENT
-	} else {
-		maxConcurrency = postLen
-	}
-	wg := sync.WaitGroup{}
-	queue := make(chan struct{}, maxConcurrency)
-	resChan := make(chan *kemonoChanRes, postLen)
-
-	baseMsg := "Getting post details from Kemono Party [%d/" + fmt.Sprintf("%d]...", postLen)
-	progress := spinner.New(
-		spinner.REQ_SPINNER,
-		"fgHiYellow",
-		fmt.Sprintf(
-			baseMsg,
-			0,
-		),
-		fmt.Sprintf(
-			"Finished getting %d post details from Kemono Party!",
-			postLen,
-		),
-		fmt.Sprintf(
-			"Something went wrong while getting %d post details from Kemono Party.\nPlease refer to the logs for more details.",
-			postLen,
-		),
-		postLen,
-	)
-	progress.Start()
-	for _, post := range posts {
-		wg.Add(1)
-		go func(post *models.KemonoPostToDl) {
-			defer func() {
-				progress.MsgIncrement(baseMsg)
-				wg.Done()
-				<-queue
-			}()
-
-			queue <- struct{}{}
-			toDownload, foundGdriveLinks, err := getPostDetails(post, downloadPath, dlOptions)
+	results, _ := pipeline.Run(&pipeline.Options[*kemonoPostRes]{
+		Count:          postLen,
+		MaxConcurrency: API_MAX_CONCURRENT,
+		Progress: &pipeline.Progress{
+			SpinnerType: spinner.REQ_SPINNER,
+			Msg:         i18n.T("Getting post details from Kemono Party"),
+			SuccessMsg: i18n.Sprintf(
+				"Finished getting %d post details from Kemono Party!",
+				postLen,
+			),
+			ErrMsg: i18n.Sprintf(
+				"Something went wrong while getting %d post details from Kemono Party.\nPlease refer to the logs for more details.",
+				postLen,
+			),
+		},
+		Task: func(idx int) (*kemonoPostRes, string, error) {
+			toDownload, foundGdriveLinks, err := getPostDetails(posts[idx], downloadPath, dlOptions)
 			if err != nil {
-				resChan <- &kemonoChanRes{
-					err: err,
-				}
-				return
+				return nil, "", err
 			}
-			resChan <- &kemonoChanRes{
+			return &kemonoPostRes{
 				urlsToDownload: toDownload,
 				gdriveLinks:    foundGdriveLinks,
-			}
-		}(post)
-	}
-	wg.Wait()
-	close(queue)
-	close(resChan)
+			}, "", nil
+		},
+	})
 
-	hasError := false
 	var urlsToDownload, gdriveLinks []*request.ToDownload
-	for res := range resChan {
-		if res.err != nil {
-			if !hasError {
-				hasError = true
-			}
-			utils.LogError(res.err, "", false, utils.ERROR)
-			continue
-		}
+	for _, res := range results {
 		urlsToDownload = append(urlsToDownload, res.urlsToDownload...)
 		gdriveLinks = append(gdriveLinks, res.gdriveLinks...)
 	}
-	progress.Stop(hasError)
 	return urlsToDownload, gdriveLinks
 }
 
-func getCreatorPosts(creator *models.KemonoCreatorToDl, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
+// Returns the creator's posts at the offset of the creator's posts
+func getCreatorPostsPage(creator *models.KemonoCreatorToDl, offset int, params map[string]string, dlOptions *KemonoDlOptions) (models.KemonoJson, error) {
 	useHttp3 := utils.IsHttp3Supported(utils.KEMONO, true)
+	params["o"] = strconv.Itoa(offset)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url: fmt.Sprintf(
+				"%s/%s/user/%s",
+				utils.KEMONO_API_URL,
+				creator.Service,
+				creator.CreatorId,
+			),
+			Method:      "GET",
+			UserAgent:   dlOptions.Configs.UserAgent,
+			Headers:     getKemonoPartyHeaders(),
+			Cookies:     dlOptions.SessionCookies,
+			Params:      params,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var resJson models.KemonoJson
+	if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+		return nil, err
+	}
+	return resJson, nil
+}
+
+// GetCreatorPostIds returns the IDs of all the posts of the creator on the service, e.g. "fanbox"
+func GetCreatorPostIds(service, creatorId string, dlOptions *KemonoDlOptions) ([]string, error) {
+	creator := &models.KemonoCreatorToDl{Service: service, CreatorId: creatorId}
+	params := make(map[string]string)
+
+	var postIds []string
+	for offset := 0; ; {
+		resJson, err := getCreatorPostsPage(creator, offset, params, dlOptions)
+		if err != nil {
+			return nil, err
+		}
+		if len(resJson) == 0 {
+			return postIds, nil
+		}
+		for _, post := range resJson {
+			postIds = append(postIds, post.Id)
+		}
+		offset += len(resJson)
+	}
+}
+
+func getCreatorPosts(creator *models.KemonoCreatorToDl, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
 	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(creator.PageNum)
 	if err != nil {
 		return nil, nil, err
 	}
 	minOffset, maxOffset := utils.ConvertPageNumToOffset(minPage, maxPage, utils.KEMONO_PER_PAGE)
 
-	var postsToDl, gdriveLinksToDl []*request.ToDownload
+	var creatorPosts models.KemonoJson
+	seenTracker := dlOptions.Configs.NewSeenTracker(
+		filepath.Join(downloadPath, "Kemono-Party", creator.Service),
+	)
 	params := make(map[string]string)
+	if dlOptions.Search != "" {
+		params["q"] = dlOptions.Search
+	}
 	curOffset := minOffset
 	for {
-		params["o"] = strconv.Itoa(curOffset)
+		resJson, err := getCreatorPostsPage(creator, curOffset, params, dlOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(resJson) == 0 {
+			break
+		}
+
+		postIds := make([]string, len(resJson))
+		for idx, post := range resJson {
+			postIds[idx] = post.Id
+		}
+		numOfPosts, stop := seenTracker.SeenMultiple(postIds)
+		creatorPosts = append(creatorPosts, resJson[:numOfPosts]...)
+		if stop || (hasMax && curOffset >= maxOffset) {
+			break
+		}
+		curOffset += 25
+	}
+
+	if dlOptions.Configs.IsAscOrder() {
+		utils.ReverseSlice(creatorPosts)
+	}
+	postsToDl, gdriveLinksToDl := processMultipleJson(creatorPosts, downloadPath, dlOptions)
+	if dlOptions.DlCreatorExtras {
+		extrasToDl, errSlice := getCreatorExtras(creator, downloadPath, dlOptions)
+		if len(errSlice) > 0 {
+			utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+		}
+		postsToDl = append(postsToDl, extrasToDl...)
+	}
+	return postsToDl, gdriveLinksToDl, nil
+}
+
+func getMultipleCreators(creators []*models.KemonoCreatorToDl, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
+	creatorLen := len(creators)
+	results, _ := pipeline.Run(&pipeline.Options[*kemonoPostRes]{
+		Count:          creatorLen,
+		MaxConcurrency: 1, // get the creators' posts one at a time
+		Progress: &pipeline.Progress{
+			SpinnerType: spinner.REQ_SPINNER,
+			Msg:         i18n.T("Getting creator's posts from Kemono Party"),
+			SuccessMsg: i18n.Sprintf(
+				"Finished getting %d creator's posts from Kemono Party!",
+				creatorLen,
+			),
+			ErrMsg: i18n.Sprintf(
+				"Something went wrong while getting %d creator's posts from Kemono Party.\nPlease refer to the logs for more details.",
+				creatorLen,
+			),
+		},
+		Task: func(idx int) (*kemonoPostRes, string, error) {
+			postsToDl, gdriveLinksToDl, err := getCreatorPosts(creators[idx], downloadPath, dlOptions)
+			if err != nil {
+				return nil, "", err
+			}
+			return &kemonoPostRes{
+				urlsToDownload: postsToDl,
+				gdriveLinks:    gdriveLinksToDl,
+			}, "", nil
+		},
+	})
+
+	var urlsToDownload, gdriveLinks []*request.ToDownload
+	for _, res := range results {
+		urlsToDownload = append(urlsToDownload, res.urlsToDownload...)
+		gdriveLinks = append(gdriveLinks, res.gdriveLinks...)
+	}
+	return urlsToDownload, gdriveLinks
+}
+
+// Returns the creators' accounts on other services, e.g. a Patreon creator's Pixiv Fanbox account,
+// that are not already in the given slice of creators to download
+func getLinkedCreators(creators []*models.KemonoCreatorToDl, dlOptions *KemonoDlOptions) ([]*models.KemonoCreatorToDl, []error) {
+	seen := make(map[string]struct{}, len(creators))
+	for _, creator := range creators {
+		seen[creator.Service+"/"+creator.CreatorId] = struct{}{}
+	}
+
+	var errSlice []error
+	var linkedCreators []*models.KemonoCreatorToDl
+	useHttp3 := utils.IsHttp3Supported(utils.KEMONO, true)
+	for _, creator := range creators {
 		res, err := request.CallRequest(
 			&request.RequestArgs{
 				Url: fmt.Sprintf(
-					"%s/%s/user/%s",
+					"%s/v1/%s/user/%s/links",
 					utils.KEMONO_API_URL,
 					creator.Service,
 					creator.CreatorId,
 				),
 				Method:      "GET",
-				UserAgent:   dlOptions.Configs.UserAgent,
 				Headers:     getKemonoPartyHeaders(),
+				UserAgent:   dlOptions.Configs.UserAgent,
 				Cookies:     dlOptions.SessionCookies,
-				Params:      params,
 				Http2:       !useHttp3,
 				Http3:       useHttp3,
 				CheckStatus: true,
 			},
 		)
 		if err != nil {
-			return nil, nil, err
+			errSlice = append(errSlice, err)
+			continue
 		}
 
-		var resJson models.KemonoJson
+		var resJson models.KemonoLinkedCreatorJson
 		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
-			return nil, nil, err
-		}
-
-		if len(resJson) == 0 {
-			break
+			errSlice = append(errSlice, err)
+			continue
 		}
 
-		posts, gdriveLinks := processMultipleJson(resJson, downloadPath, dlOptions)
-		postsToDl = append(postsToDl, posts...)
-		gdriveLinksToDl = append(gdriveLinksToDl, gdriveLinks...)
+		for _, linkedCreator := range resJson {
+			key := linkedCreator.Service + "/" + linkedCreator.Id
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
 
-		if (hasMax && curOffset >= maxOffset) {
-			break
-		}
-		curOffset += 25
-	}
-	return postsToDl, gdriveLinksToDl, nil
-}
+			// skip services that the program cannot download from, e.g. Discord servers
+			linkedCreatorUrl := fmt.Sprintf("%s/%s/user/%s", utils.KEMONO_URL, linkedCreator.Service, linkedCreator.Id)
+			if !CREATOR_URL_REGEX.MatchString(linkedCreatorUrl) {
+				continue
+			}
 
-func getMultipleCreators(creators []*models.KemonoCreatorToDl, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
-	var errSlice []error
-	var urlsToDownload, gdriveLinks []*request.ToDownload
-	creatorLen := len(creators)
-	baseMsg := "Getting creator's posts from Kemono Party [%d/" + fmt.Sprintf("%d]...", creatorLen)
-	progress := spinner.New(
-		spinner.REQ_SPINNER,
-		"fgHiYellow",
-		fmt.Sprintf(
-			baseMsg,
-			0,
-		),
-		fmt.Sprintf(
-			"Finished getting %d creator's posts from Kemono Party!",
-			creatorLen,
-		),
-		fmt.Sprintf(
-			"Something went wrong while getting %d creator's posts from Kemono Party.\nPlease refer to the logs for more details.",
-			creatorLen,
-		),
-		creatorLen,
-	)
-	progress.Start()
-	for _, creator := range creators {
-		postsToDl, gdriveLinksToDl, err := getCreatorPosts(creator, downloadPath, dlOptions)
-		if err != nil {
-			errSlice = append(errSlice, err)
-			progress.MsgIncrement(baseMsg)
-			continue
+			linkedCreators = append(linkedCreators, &models.KemonoCreatorToDl{
+				Service:   linkedCreator.Service,
+				CreatorId: linkedCreator.Id,
+				PageNum:   creator.PageNum,
+			})
 		}
-		urlsToDownload = append(urlsToDownload, postsToDl...)
-		gdriveLinks = append(gdriveLinks, gdriveLinksToDl...)
-		progress.MsgIncrement(baseMsg)
 	}
-
-	hasError := false
-	if len(errSlice) > 0 {
-		hasError = true
-		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
-	}
-	progress.Stop(hasError)
-	return urlsToDownload, gdriveLinks
+	return linkedCreators, errSlice
 }
 
 func processFavCreator(resJson models.KemonoFavCreatorJson) []*models.KemonoCreatorToDl {
@@ -3,15 +3,15 @@ package kemono
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"regexp"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/fatih/color"
 )
 
 const (
@@ -21,6 +21,9 @@ const (
 	CREATOR_ID_GROUP_NAME      = "creatorId"
 	POST_ID_GROUP_NAME         = "postId"
 	API_MAX_CONCURRENT         = 3
+
+	// Kemono Party's API ignores search queries that are shorter than this
+	KEMONO_MIN_SEARCH_LEN = 3
 )
 
 var (
@@ -117,26 +120,22 @@ func (k *KemonoDl) RemoveDuplicates() {
 func (k *KemonoDl) ValidateArgs() {
 	valid, outlier := utils.SliceMatchesRegex(CREATOR_URL_REGEX, k.CreatorUrls)
 	if !valid {
-		color.Red(
-			fmt.Sprintf(
-				"kemono error %d: invalid creator URL found for kemono party: %s",
-				utils.INPUT_ERROR,
-				outlier,
-			),
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"kemono error %d: invalid creator URL found for kemono party: %s",
+			utils.INPUT_ERROR,
+			outlier,
 		)
-		os.Exit(1)
 	}
 
 	valid, outlier = utils.SliceMatchesRegex(POST_URL_REGEX, k.PostUrls)
 	if !valid {
-		color.Red(
-			fmt.Sprintf(
-				"kemono error %d: invalid post URL found for kemono party: %s",
-				utils.INPUT_ERROR,
-				outlier,
-			),
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"kemono error %d: invalid post URL found for kemono party: %s",
+			utils.INPUT_ERROR,
+			outlier,
 		)
-		os.Exit(1)
 	}
 
 	if len(k.CreatorUrls) > 0 {
@@ -169,7 +168,17 @@ type KemonoDlOptions struct {
 	DlAttachments bool
 	DlGdrive      bool
 
-	Configs       *configs.Config
+	// DlCreatorExtras is whether to also save the creators' announcements and fancards
+	DlCreatorExtras bool
+
+	// DlLinkedCreators is whether to also download from the
+	// creators' linked accounts on other services, e.g. Patreon and Pixiv Fanbox
+	DlLinkedCreators bool
+
+	// Search is the query to filter the creators' posts by, e.g. "PSD"
+	Search string
+
+	Configs *configs.Config
 
 	// GdriveClient is the Google Drive client to be
 	// used in the download process for Pixiv Fanbox posts
@@ -189,8 +198,18 @@ func (k *KemonoDlOptions) ValidateArgs(userAgent string) {
 			api.VerifyAndGetCookie(utils.KEMONO, k.SessionCookieId, userAgent),
 		}
 	} else {
-		color.Red("kemono error %d: session cookie ID is required", utils.INPUT_ERROR)
-		os.Exit(1)
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "kemono error %d: session cookie ID is required", utils.INPUT_ERROR)
+	}
+
+	k.Search = strings.TrimSpace(k.Search)
+	if k.Search != "" && utf8.RuneCountInString(k.Search) < KEMONO_MIN_SEARCH_LEN {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"kemono error %d: search query %q must be at least %d characters long",
+			utils.INPUT_ERROR,
+			k.Search,
+			KEMONO_MIN_SEARCH_LEN,
+		)
 	}
 
 	if k.DlGdrive && k.GdriveClient == nil {
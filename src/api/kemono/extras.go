@@ -0,0 +1,152 @@
+package kemono
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+func getCreatorExtrasJson(url string, dlOptions *KemonoDlOptions, v any) error {
+	useHttp3 := utils.IsHttp3Supported(utils.KEMONO, true)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url:         url,
+			Method:      "GET",
+			Headers:     getKemonoPartyHeaders(),
+			UserAgent:   dlOptions.Configs.UserAgent,
+			Cookies:     dlOptions.SessionCookies,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return utils.LoadJsonFromResponse(res, v)
+}
+
+// Saves the creator's announcements as HTML files in the
+// announcements folder and returns the inline images to download
+func getAnnouncements(creator *models.KemonoCreatorToDl, creatorFolderPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, error) {
+	var announcements models.KemonoAnnouncementJson
+	err := getCreatorExtrasJson(
+		fmt.Sprintf(
+			"%s/v1/%s/user/%s/announcements",
+			utils.KEMONO_API_URL,
+			creator.Service,
+			creator.CreatorId,
+		),
+		dlOptions,
+		&announcements,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var toDownload []*request.ToDownload
+	announcementsFolderPath := filepath.Join(creatorFolderPath, utils.KEMONO_ANNOUNCEMENTS_FOLDER)
+	for _, announcement := range announcements {
+		publishedAt := announcement.Published
+		if publishedAt == "" {
+			publishedAt = announcement.Added
+		}
+
+		// the date is prefixed so that the announcements are sorted chronologically
+		filename := announcement.Hash
+		if date := utils.ParsePostDate(publishedAt); !date.IsZero() {
+			filename = fmt.Sprintf("%s_%s", date.Format("2006-01-02"), announcement.Hash)
+		}
+		filePath := filepath.Join(announcementsFolderPath, utils.CleanPathName(filename)+".html")
+		shouldSave := dlOptions.Configs.OverwriteFiles || !storage.Exists(context.Background(), filePath)
+		if shouldSave && dlOptions.Configs.ShouldDlFile(filePath) {
+			if err := storage.WriteFile(context.Background(), filePath, []byte(announcement.Content)); err != nil {
+				return nil, fmt.Errorf(
+					"kemono error %d: failed to save announcement to %s, more info => %v",
+					utils.OS_ERROR,
+					filePath,
+					err,
+				)
+			}
+		}
+		toDownload = append(toDownload, getInlineImages(announcement.Content, announcementsFolderPath)...)
+	}
+	return toDownload, nil
+}
+
+// Returns the creator's fancard images to download to the fancards folder
+//
+// Only Pixiv Fanbox creators have fancards.
+func getFancards(creator *models.KemonoCreatorToDl, creatorFolderPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, error) {
+	if creator.Service != "fanbox" {
+		return nil, nil
+	}
+
+	var fancards models.KemonoFancardJson
+	err := getCreatorExtrasJson(
+		fmt.Sprintf(
+			"%s/v1/%s/user/%s/fancards",
+			utils.KEMONO_API_URL,
+			creator.Service,
+			creator.CreatorId,
+		),
+		dlOptions,
+		&fancards,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var toDownload []*request.ToDownload
+	fancardsFolderPath := filepath.Join(creatorFolderPath, utils.KEMONO_FANCARDS_FOLDER)
+	for _, fancard := range fancards {
+		if len(fancard.Hash) < 4 {
+			continue
+		}
+
+		// files on Kemono Party are stored by their hash, e.g. "/data/ab/cd/abcdef...jpg"
+		filename := fancard.Hash + fancard.Ext
+		toDownload = append(toDownload, &request.ToDownload{
+			Url: fmt.Sprintf(
+				"%s/data/%s/%s/%s",
+				utils.KEMONO_URL,
+				fancard.Hash[0:2],
+				fancard.Hash[2:4],
+				filename,
+			),
+			FilePath: filepath.Join(fancardsFolderPath, filename),
+		})
+	}
+	return toDownload, nil
+}
+
+// Saves the creator's announcements and returns the announcements'
+// inline images and the creator's fancards to download
+//
+// The extras are saved in the creator's folder alongside the creator's posts.
+func getCreatorExtras(creator *models.KemonoCreatorToDl, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []error) {
+	creatorFolderPath := filepath.Join(
+		downloadPath,
+		"Kemono-Party",
+		creator.Service,
+		utils.CleanPathName(creator.CreatorId),
+	)
+
+	var errSlice []error
+	toDownload, err := getAnnouncements(creator, creatorFolderPath, dlOptions)
+	if err != nil {
+		errSlice = append(errSlice, err)
+	}
+
+	fancards, err := getFancards(creator, creatorFolderPath, dlOptions)
+	if err != nil {
+		errSlice = append(errSlice, err)
+	}
+	toDownload = append(toDownload, fancards...)
+	return toDownload, errSlice
+}
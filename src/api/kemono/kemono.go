@@ -2,8 +2,12 @@ package kemono
 
 import (
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/filehost"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/twitter"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
@@ -46,6 +50,24 @@ func KemonoDownloadProcess(config *configs.Config, kemonoDl *KemonoDl, dlOptions
 		toDownload = append(toDownload, postsToDl...)
 		gdriveLinks = append(gdriveLinks, gdriveLinksToDl...)
 	}
+	if len(kemonoDl.CreatorsToDl) > 0 && dlOptions.DlLinkedCreators {
+		progress := spinner.New(
+			spinner.REQ_SPINNER,
+			"fgHiYellow",
+			"Getting linked accounts of creators from Kemono Party...",
+			"Finished getting linked accounts of creators from Kemono Party!",
+			"Something went wrong while getting linked accounts of creators from Kemono Party.\nPlease refer to the logs for more details.",
+			0,
+		)
+		progress.Start()
+		linkedCreators, errSlice := getLinkedCreators(kemonoDl.CreatorsToDl, dlOptions)
+		hasErr := len(errSlice) > 0
+		if hasErr {
+			utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+		}
+		kemonoDl.CreatorsToDl = append(kemonoDl.CreatorsToDl, linkedCreators...)
+		progress.Stop(hasErr)
+	}
 	if len(kemonoDl.CreatorsToDl) > 0 {
 		creatorsToDl, gdriveLinksToDl := getMultipleCreators(
 			kemonoDl.CreatorsToDl,
@@ -69,14 +91,16 @@ func KemonoDownloadProcess(config *configs.Config, kemonoDl *KemonoDl, dlOptions
 			config,
 		)
 	}
-	if dlOptions.GdriveClient != nil && len(gdriveLinks) > 0 {
+	if dlOptions.GdriveClient != nil && (len(gdriveLinks) > 0 || gdrive.HasDueDeferredFiles()) {
 		downloadedPosts = true
 		dlOptions.GdriveClient.DownloadGdriveUrls(gdriveLinks, config)
 	}
+	filehost.DownloadLinks(config)
+	twitter.ProcessLinks(config)
 
 	if downloadedPosts {
-		utils.AlertWithoutErr(utils.Title, "Downloaded all posts from Kemono Party!")
+		utils.AlertWithoutErr(utils.Title, i18n.T("Downloaded all posts from Kemono Party!"))
 	} else {
-		utils.AlertWithoutErr(utils.Title, "No posts to download from Kemono Party!")
+		utils.AlertWithoutErr(utils.Title, i18n.T("No posts to download from Kemono Party!"))
 	}
 }
@@ -48,3 +48,29 @@ type KemonoCreatorToDl struct {
 	CreatorId string
 	PageNum   string
 }
+
+type KemonoAnnouncementJson []struct {
+	Hash      string `json:"hash"`
+	Content   string `json:"content"`
+	Added     string `json:"added"`
+	Published string `json:"published"`
+}
+
+type KemonoFancardJson []struct {
+	Id   int    `json:"id"`
+	Hash string `json:"hash"`
+	Ext  string `json:"ext"`
+}
+
+type KemonoLinkedCreatorJson []struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Service string `json:"service"`
+}
+
+type KemonoCreatorJson []struct {
+	Favorited int    `json:"favorited"`
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Service   string `json:"service"`
+}
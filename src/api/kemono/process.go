@@ -1,14 +1,17 @@
 package kemono
 
 import (
-	"strings"
-	"regexp"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/filehost"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/twitter"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
 )
 
 var (
@@ -41,11 +44,12 @@ func getKemonoFilePath(postFolderPath, childDir, fileName string) string {
 }
 
 func processJson(resJson *models.MainKemonoJson, downloadPath string, dlOptions *KemonoDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
-	postFolderPath := utils.GetPostFolder(
+	postFolderPath := dlOptions.Configs.GetPostFolder(
 		filepath.Join(downloadPath, "Kemono-Party", resJson.Service),
 		resJson.User,
 		resJson.Id,
 		resJson.Title,
+		resJson.Published,
 	)
 
 	var gdriveLinks []*request.ToDownload
@@ -61,6 +65,8 @@ func processJson(resJson *models.MainKemonoJson, downloadPath string, dlOptions
 
 		if resJson.Embed.Url != "" {
 			embedsDirPath := filepath.Join(postFolderPath, utils.KEMONO_EMBEDS_FOLDER)
+			filehost.DetectLinks(resJson.Embed.Url, postFolderPath)
+			twitter.DetectLinks(resJson.Embed.Url, postFolderPath)
 			if dlOptions.Configs.LogUrls {
 				utils.DetectOtherExtDLLink(resJson.Embed.Url, embedsDirPath)
 			}
@@ -88,6 +94,23 @@ func processJson(resJson *models.MainKemonoJson, downloadPath string, dlOptions
 		dlOptions.Configs.LogUrls,
 	)
 	gdriveLinks = append(gdriveLinks, contentGdriveLinks...)
+	fileFilter := events.GetFileFilter(map[string]bool{
+		"attachments": dlOptions.DlAttachments,
+		"gdrive":      dlOptions.DlGdrive,
+	})
+	events.PostResolved(&events.Post{
+		Site:        utils.KEMONO,
+		Id:          resJson.Id,
+		Title:       resJson.Title,
+		Creator:     resJson.User,
+		CreatorId:   resJson.User,
+		Folder:      postFolderPath,
+		FileCount:   len(toDownload) + len(gdriveLinks),
+		FileFilter:  fileFilter,
+		PublishedAt: utils.ParsePostDate(resJson.Published),
+		Body:        resJson.Content,
+		BodyIsHtml:  true,
+	})
 	return toDownload, gdriveLinks
 }
 
@@ -0,0 +1,54 @@
+package kemono
+
+import (
+	"fmt"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Returns all the creators in Kemono Party's creators index
+//
+// Note: The index is large so it should be fetched once and searched locally.
+func GetCreatorsIndex(userAgent string) ([]*api.SearchedCreator, error) {
+	useHttp3 := utils.IsHttp3Supported(utils.KEMONO, true)
+	url := fmt.Sprintf("%s/v1/creators.txt", utils.KEMONO_API_URL)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url:         url,
+			Method:      "GET",
+			Headers:     getKemonoPartyHeaders(),
+			UserAgent:   userAgent,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"kemono error %d: failed to get the creators index from %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			url,
+			err,
+		)
+	}
+
+	var creatorsJson models.KemonoCreatorJson
+	if err := utils.LoadJsonFromResponse(res, &creatorsJson); err != nil {
+		return nil, err
+	}
+
+	creators := make([]*api.SearchedCreator, 0, len(creatorsJson))
+	for _, creator := range creatorsJson {
+		creators = append(creators, &api.SearchedCreator{
+			Name:       creator.Name,
+			Website:    utils.KEMONO,
+			Service:    creator.Service,
+			Url:        fmt.Sprintf("%s/%s/user/%s", utils.KEMONO_URL, creator.Service, creator.Id),
+			Popularity: creator.Favorited,
+		})
+	}
+	return creators, nil
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Returns the login page URL and the URL that the session cookie is set on for the website
+func getLoginUrls(website string) (loginUrl, cookieUrl string) {
+	switch website {
+	case utils.FANTIA:
+		return utils.FANTIA_URL + "/sessions/signin", utils.FANTIA_URL
+	case utils.PIXIV_FANBOX:
+		return utils.PIXIV_FANBOX_URL + "/login", utils.PIXIV_FANBOX_URL
+	case utils.PIXIV:
+		return "https://accounts.pixiv.net/login", utils.PIXIV_URL
+	case utils.KEMONO:
+		return utils.KEMONO_URL + "/account/login", utils.KEMONO_URL
+	case utils.DLSITE:
+		// DLsite Play will redirect to the DLsite login page if the user is not logged in
+		return utils.DLSITE_PLAY_URL + "/#/library", utils.DLSITE_PLAY_URL
+	default:
+		panic(
+			fmt.Errorf(
+				"error %d, invalid website, %q, in getLoginUrls",
+				utils.DEV_ERROR,
+				website,
+			),
+		)
+	}
+}
+
+func convertChromedpCookie(cookie *network.Cookie, sameSite http.SameSite) *http.Cookie {
+	parsedCookie := &http.Cookie{
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Domain:   cookie.Domain,
+		Path:     cookie.Path,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HTTPOnly,
+		SameSite: sameSite,
+	}
+	if !cookie.Session {
+		parsedCookie.Expires = time.Unix(int64(cookie.Expires), 0)
+	}
+	return parsedCookie
+}
+
+// Opens a browser window for the user to log in to the website and
+// returns the session cookie once it has been verified to be valid.
+//
+// The user has to fill in the login form themselves as the websites
+// may require a reCAPTCHA or two-factor authentication to be solved.
+func Login(website, userAgent string, timeout time.Duration) (*http.Cookie, error) {
+	loginUrl, cookieUrl := getLoginUrls(website)
+	sessionCookieInfo := utils.GetSessionCookieInfo(website)
+
+	var sessionCookie *http.Cookie
+	waitForSessionCookie := chromedp.ActionFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+
+		var lastChecked string
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			cookies, err := network.GetCookies().WithUrls([]string{cookieUrl}).Do(ctx)
+			if err != nil {
+				return err
+			}
+			for _, cookie := range cookies {
+				if cookie.Name != sessionCookieInfo.Name || cookie.Value == "" || cookie.Value == lastChecked {
+					continue
+				}
+
+				// Some websites set the session cookie before the user has logged in
+				// so the cookie has to be verified before it can be returned.
+				lastChecked = cookie.Value
+				parsedCookie := convertChromedpCookie(cookie, sessionCookieInfo.SameSite)
+				if isValid, err := VerifyCookie(parsedCookie, website, userAgent); err == nil && isValid {
+					sessionCookie = parsedCookie
+					return nil
+				}
+			}
+		}
+	})
+
+	allocCtx, cancel := utils.GetVisibleChromedpAlloc(userAgent)
+	defer cancel()
+
+	allocCtx, cancel = context.WithTimeout(allocCtx, timeout)
+	defer cancel()
+
+	err := utils.ExecuteChromedpActions(
+		allocCtx,
+		cancel,
+		chromedp.Navigate(loginUrl),
+		waitForSessionCookie,
+	)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf(
+				"error %d: timed out waiting for you to log in to %s",
+				utils.INPUT_ERROR,
+				utils.GetReadableSiteStr(website),
+			)
+		}
+		return nil, fmt.Errorf(
+			"error %d: failed to log in to %s via the browser, more info => %v",
+			utils.UNEXPECTED_ERROR,
+			utils.GetReadableSiteStr(website),
+			err,
+		)
+	}
+	return sessionCookie, nil
+}
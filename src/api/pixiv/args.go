@@ -2,8 +2,8 @@ package pixiv
 
 import "github.com/KJHJason/Cultured-Downloader-CLI/utils"
 
-// PixivDl contains the IDs of the Pixiv artworks and
-// illustrators and Tag Names to download.
+// PixivDl contains the IDs of the Pixiv artworks,
+// illustrators, manga and novel series, and Tag Names to download.
 type PixivDl struct {
 	ArtworkIds []string
 
@@ -12,6 +12,9 @@ type PixivDl struct {
 
 	TagNames         []string
 	TagNamesPageNums []string
+
+	SeriesIds      []string
+	NovelSeriesIds []string
 }
 
 // ValidateArgs validates the IDs of the Pixiv artworks and illustrators to download.
@@ -22,7 +25,11 @@ type PixivDl struct {
 func (p *PixivDl) ValidateArgs() {
 	utils.ValidateIds(p.ArtworkIds)
 	utils.ValidateIds(p.IllustratorIds)
+	utils.ValidateIds(p.SeriesIds)
+	utils.ValidateIds(p.NovelSeriesIds)
 	p.ArtworkIds = utils.RemoveSliceDuplicates(p.ArtworkIds)
+	p.SeriesIds = utils.RemoveSliceDuplicates(p.SeriesIds)
+	p.NovelSeriesIds = utils.RemoveSliceDuplicates(p.NovelSeriesIds)
 
 	if len(p.IllustratorPageNums) > 0 {
 		utils.ValidatePageNumInput(
@@ -56,3 +63,19 @@ func (p *PixivDl) ValidateArgs() {
 		p.TagNamesPageNums,
 	)
 }
+
+// Adds the illustrator IDs that were not already given to download all of their artworks
+func (p *PixivDl) addIllustratorIds(illustratorIds []string) {
+	existingIds := make(map[string]struct{}, len(p.IllustratorIds))
+	for _, illustratorId := range p.IllustratorIds {
+		existingIds[illustratorId] = struct{}{}
+	}
+	for _, illustratorId := range illustratorIds {
+		if _, ok := existingIds[illustratorId]; ok {
+			continue
+		}
+		existingIds[illustratorId] = struct{}{}
+		p.IllustratorIds = append(p.IllustratorIds, illustratorId)
+		p.IllustratorPageNums = append(p.IllustratorPageNums, "")
+	}
+}
@@ -0,0 +1,30 @@
+package pixivcommon
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Returns the filename prefixed with the work's 1-based index in the series
+// so that the files are sorted in reading order, e.g. "003_12345_p0.jpg".
+//
+// The filename is returned as it is if the work is not from a series, i.e. seriesIdx is 0.
+func GetSeriesFilename(filename string, seriesIdx int) string {
+	if seriesIdx <= 0 {
+		return filename
+	}
+	return fmt.Sprintf("%03d_%s", seriesIdx, filename)
+}
+
+// Prefixes the filenames of a series work's files with the work's index in the series
+func AddSeriesIndexToFilenames(toDownload []*request.ToDownload, seriesIdx int) {
+	for _, artwork := range toDownload {
+		artwork.FilePath = filepath.Join(
+			artwork.FilePath,
+			GetSeriesFilename(utils.GetLastPartOfUrl(artwork.Url), seriesIdx),
+		)
+	}
+}
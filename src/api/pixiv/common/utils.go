@@ -22,3 +22,27 @@ func ConvertPageNumToOffset(minPageNum, maxPageNum, perPage int, checkPixivMax b
 	}
 	return minOffset, maxOffset
 }
+
+// Image sizes of the illustrations and manga that can be downloaded from Pixiv
+var ACCEPTED_IMAGE_SIZE = []string{
+	"original",
+	"large",
+	"medium",
+}
+
+// Returns the URL of the image in the given image size.
+//
+// Falls back to the original image if the resized image URL is empty.
+func GetImageUrl(imageSize, originalUrl, largeUrl, mediumUrl string) string {
+	switch imageSize {
+	case "large":
+		if largeUrl != "" {
+			return largeUrl
+		}
+	case "medium":
+		if mediumUrl != "" {
+			return mediumUrl
+		}
+	}
+	return originalUrl
+}
@@ -2,12 +2,15 @@ package pixivmobile
 
 import (
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/ugoira"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -64,7 +67,7 @@ func (pixiv *PixivMobile) getUgoiraMetadata(illustId, dlFilePath string) (*model
 }
 
 // Query Pixiv's API (mobile) to get the JSON of an artwork ID
-func (pixiv *PixivMobile) getArtworkDetails(artworkId, downloadPath string) ([]*request.ToDownload, *models.Ugoira, error) {
+func (pixiv *PixivMobile) getArtworkJson(artworkId string) (*models.PixivMobileIllustJson, error) {
 	artworkUrl := pixiv.baseUrl + "/v1/illust/detail"
 	params := map[string]string{"illust_id": artworkId}
 
@@ -76,7 +79,7 @@ func (pixiv *PixivMobile) getArtworkDetails(artworkId, downloadPath string) ([]*
 		},
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf(
+		return nil, fmt.Errorf(
 			"pixiv mobile error %d: failed to get artwork details for %s, more info => %v",
 			utils.CONNECTION_ERROR,
 			artworkId,
@@ -86,11 +89,20 @@ func (pixiv *PixivMobile) getArtworkDetails(artworkId, downloadPath string) ([]*
 
 	var artworkJson models.PixivMobileArtworkJson
 	if err := utils.LoadJsonFromResponse(res, &artworkJson); err != nil {
+		return nil, err
+	}
+	return artworkJson.Illust, nil
+}
+
+// Query Pixiv's API (mobile) to get the files to download of an artwork ID
+func (pixiv *PixivMobile) getArtworkDetails(artworkId, downloadPath string) ([]*request.ToDownload, *models.Ugoira, error) {
+	artworkJson, err := pixiv.getArtworkJson(artworkId)
+	if err != nil {
 		return nil, nil, err
 	}
 
 	artworkDetails, ugoiraToDl, err := pixiv.processArtworkJson(
-		artworkJson.Illust,
+		artworkJson,
 		downloadPath,
 	)
 	return artworkDetails, ugoiraToDl, err
@@ -152,13 +164,60 @@ func (pixiv *PixivMobile) GetMultipleArtworkDetails(artworkIds []string, downloa
 	return artworksToDownload, ugoiraSlice
 }
 
-func (pixiv *PixivMobile) getIllustratorPostMainLogic(params map[string]string, userId, downloadPath string, offsetArg *offsetArgs) ([]*request.ToDownload, []*models.Ugoira, []error) {
-	var errSlice []error
-	var ugoiraSlice []*models.Ugoira
-	var artworksToDownload []*request.ToDownload
+// Checkpoint of an interrupted crawl of an illustrator's posts
+//
+// Only the IDs of the crawled artworks are saved as their JSON would make the checkpoint grow
+// with each page, so their JSON are requested again when the crawl is resumed.
+type illustratorCheckpoint struct {
+	NextUrl   string   `json:"next_url"`
+	Offset    int      `json:"offset"`
+	IllustIds []string `json:"illust_ids"`
+}
+
+// Returns the JSON of the artworks crawled before the crawl was interrupted
+//
+// The artworks that can no longer be retrieved, e.g. as they have been deleted, are logged and skipped.
+func (pixiv *PixivMobile) getCheckpointIllusts(checkpoint *illustratorCheckpoint) []*models.PixivMobileIllustJson {
+	illusts := make([]*models.PixivMobileIllustJson, 0, len(checkpoint.IllustIds))
+	for idx, illustId := range checkpoint.IllustIds {
+		if idx > 0 {
+			pixiv.Sleep()
+		}
+		illust, err := pixiv.getArtworkJson(illustId)
+		if err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			continue
+		}
+		illusts = append(illusts, illust)
+	}
+	return illusts
+}
+
+func (pixiv *PixivMobile) getIllustratorPostMainLogic(params map[string]string, userId string, offsetArg *offsetArgs) ([]*models.PixivMobileIllustJson, error) {
+	var illusts []*models.PixivMobileIllustJson
 	nextUrl := pixiv.baseUrl + "/v1/user/illusts"
 
 	curOffset := offsetArg.minOffset
+	checkpointKey := fmt.Sprintf(
+		"pixiv_mobile/user/%s/%s/%d-%d",
+		userId,
+		params["type"],
+		offsetArg.minOffset,
+		offsetArg.maxOffset,
+	)
+	var illustIds []string
+	var checkpoint illustratorCheckpoint
+	// the checkpoints saved with the JSON of the artworks by older versions have no IDs and are ignored
+	if utils.LoadCrawlCheckpoint(checkpointKey, &checkpoint) && len(checkpoint.IllustIds) > 0 {
+		// resume the interrupted crawl at the page after the last crawled page
+		illusts = pixiv.getCheckpointIllusts(&checkpoint)
+		illustIds = checkpoint.IllustIds
+		nextUrl = checkpoint.NextUrl
+		curOffset = checkpoint.Offset
+		params["offset"] = strconv.Itoa(curOffset)
+	}
+
+	seenTracker := pixiv.configs.NewSeenTracker(filepath.Join(utils.DOWNLOAD_PATH, utils.PIXIV_TITLE))
 	for nextUrl != "" {
 		res, err := pixiv.SendRequest(
 			&request.RequestArgs{
@@ -174,36 +233,42 @@ func (pixiv *PixivMobile) getIllustratorPostMainLogic(params map[string]string,
 				userId,
 				err,
 			)
-			return nil, nil, []error{err}
+			return nil, err
 		}
 
 		var resJson models.PixivMobileArtworksJson
 		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
-			return nil, nil, []error{err}
+			return nil, err
 		}
-
-		artworks, ugoira, errS := pixiv.processMultipleArtworkJson(&resJson, downloadPath)
-		if len(errS) > 0 {
-			errSlice = append(errSlice, errS...)
+		pageIllustIds := make([]string, len(resJson.Illusts))
+		for idx, illust := range resJson.Illusts {
+			pageIllustIds[idx] = strconv.Itoa(illust.Id)
 		}
-		artworksToDownload = append(artworksToDownload, artworks...)
-		ugoiraSlice = append(ugoiraSlice, ugoira...)
+		numOfIllusts, stop := seenTracker.SeenMultiple(pageIllustIds)
+		illusts = append(illusts, resJson.Illusts[:numOfIllusts]...)
+		illustIds = append(illustIds, pageIllustIds[:numOfIllusts]...)
 
 		curOffset += 30
 		params["offset"] = strconv.Itoa(curOffset)
 		jsonNextUrl := resJson.NextUrl
-		if jsonNextUrl == nil || (offsetArg.hasMax && curOffset >= offsetArg.maxOffset) {
+		if stop || jsonNextUrl == nil || (offsetArg.hasMax && curOffset >= offsetArg.maxOffset) {
 			nextUrl = ""
 		} else {
 			nextUrl = *jsonNextUrl
+			utils.SaveCrawlCheckpoint(checkpointKey, &illustratorCheckpoint{
+				NextUrl:   nextUrl,
+				Offset:    curOffset,
+				IllustIds: illustIds,
+			})
 			pixiv.Sleep()
 		}
 	}
-	return artworksToDownload, ugoiraSlice, errSlice
+	utils.DeleteCrawlCheckpoint(checkpointKey)
+	return illusts, nil
 }
 
 // Query Pixiv's API (mobile) to get all the posts JSON(s) of a user ID
-func (pixiv *PixivMobile) getIllustratorPosts(userId, pageNum, downloadPath, artworkType string) ([]*request.ToDownload, []*models.Ugoira, []error) {
+func (pixiv *PixivMobile) getIllustratorPosts(userId, pageNum, downloadPath, artworkType, order string) ([]*request.ToDownload, []*models.Ugoira, []error) {
 	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
 	if err != nil {
 		return nil, nil, []error{err}
@@ -225,31 +290,50 @@ func (pixiv *PixivMobile) getIllustratorPosts(userId, pageNum, downloadPath, art
 		maxOffset: maxOffset,
 		hasMax:    hasMax,
 	}
-	artworksToDl, ugoiraSlice, errSlice := pixiv.getIllustratorPostMainLogic(
+	var errSlice []error
+	illusts, err := pixiv.getIllustratorPostMainLogic(
 		params,
 		userId,
-		downloadPath,
 		offsetArgs,
 	)
+	if err != nil {
+		errSlice = append(errSlice, err)
+	}
 
 	if params["type"] == "illust" && artworkType == "all" {
 		// if the user is downloading both
 		// illust and manga, loop again to get the manga
 		params["type"] = "manga"
-		artworksToDl2, ugoiraSlice2, errSlice2 := pixiv.getIllustratorPostMainLogic(
+		params["offset"] = strconv.Itoa(minOffset)
+		manga, err := pixiv.getIllustratorPostMainLogic(
 			params,
 			userId,
-			downloadPath,
 			offsetArgs,
 		)
-		artworksToDl = append(artworksToDl, artworksToDl2...)
-		ugoiraSlice = append(ugoiraSlice, ugoiraSlice2...)
-		errSlice = append(errSlice, errSlice2...)
+		if err != nil {
+			errSlice = append(errSlice, err)
+		}
+		illusts = append(illusts, manga...)
 	}
+
+	// the illustrations and manga are sorted together so that
+	// the order applies across both instead of to each separately
+	isAsc := order == configs.ORDER_ASC
+	sort.SliceStable(illusts, func(i, j int) bool {
+		if isAsc {
+			return illusts[i].Id < illusts[j].Id
+		}
+		return illusts[i].Id > illusts[j].Id
+	})
+	artworksToDl, ugoiraSlice, errS := pixiv.processMultipleArtworkJson(
+		&models.PixivMobileArtworksJson{Illusts: illusts},
+		downloadPath,
+	)
+	errSlice = append(errSlice, errS...)
 	return artworksToDl, ugoiraSlice, errSlice
 }
 
-func (pixiv *PixivMobile) GetMultipleIllustratorPosts(userIds, pageNums []string, downloadPath, artworkType string) ([]*request.ToDownload, []*models.Ugoira) {
+func (pixiv *PixivMobile) GetMultipleIllustratorPosts(userIds, pageNums []string, downloadPath, artworkType, order string) ([]*request.ToDownload, []*models.Ugoira) {
 	userIdsLen := len(userIds)
 	lastIdx := userIdsLen - 1
 
@@ -281,6 +365,7 @@ func (pixiv *PixivMobile) GetMultipleIllustratorPosts(userIds, pageNums []string
 			pageNums[idx],
 			downloadPath,
 			artworkType,
+			order,
 		)
 		if err != nil {
 			errSlice = append(errSlice, err...)
@@ -306,6 +391,126 @@ func (pixiv *PixivMobile) GetMultipleIllustratorPosts(userIds, pageNums []string
 	return artworksToDownload, ugoiraSlice
 }
 
+// Query Pixiv's API (mobile) to get all the works in the series and returns a slice of artworks
+// to download, with the filenames prefixed by the work's index in the series, and a slice of Ugoira structures.
+func (pixiv *PixivMobile) getSeriesPosts(seriesId, downloadPath string) ([]*request.ToDownload, []*models.Ugoira, []error) {
+	var illusts []*models.PixivMobileIllustJson
+	params := map[string]string{
+		"illust_series_id": seriesId,
+		"filter":           "for_ios",
+	}
+	curOffset := 0
+	nextUrl := pixiv.baseUrl + "/v1/illust/series"
+	for nextUrl != "" {
+		res, err := pixiv.SendRequest(
+			&request.RequestArgs{
+				Url:         nextUrl,
+				Params:      params,
+				CheckStatus: true,
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf(
+				"pixiv mobile error %d: failed to get works of series ID %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				seriesId,
+				err,
+			)
+			return nil, nil, []error{err}
+		}
+
+		var resJson models.PixivMobileArtworksJson
+		if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+			return nil, nil, []error{err}
+		}
+		illusts = append(illusts, resJson.Illusts...)
+
+		curOffset += 30
+		params["offset"] = strconv.Itoa(curOffset)
+		jsonNextUrl := resJson.NextUrl
+		if jsonNextUrl == nil {
+			nextUrl = ""
+		} else {
+			nextUrl = *jsonNextUrl
+			pixiv.Sleep()
+		}
+	}
+
+	// the API lists the works from the latest to the first work in the series,
+	// so they are reversed to follow the series order instead of sorting them by
+	// their IDs as the works can be added to the series in any order
+	for i, j := 0, len(illusts)-1; i < j; i, j = i+1, j-1 {
+		illusts[i], illusts[j] = illusts[j], illusts[i]
+	}
+
+	var errSlice []error
+	var ugoiraSlice []*models.Ugoira
+	var artworksToDl []*request.ToDownload
+	for idx, illust := range illusts {
+		artworks, ugoiraInfo, err := pixiv.processArtworkJson(illust, downloadPath)
+		if err != nil {
+			errSlice = append(errSlice, err)
+			continue
+		}
+
+		if ugoiraInfo != nil {
+			ugoiraInfo.SeriesIdx = idx + 1
+			ugoiraSlice = append(ugoiraSlice, ugoiraInfo)
+			continue
+		}
+		pixivcommon.AddSeriesIndexToFilenames(artworks, idx+1)
+		artworksToDl = append(artworksToDl, artworks...)
+	}
+	return artworksToDl, ugoiraSlice, errSlice
+}
+
+func (pixiv *PixivMobile) GetMultipleSeriesPosts(seriesIds []string, downloadPath string) ([]*request.ToDownload, []*models.Ugoira) {
+	seriesIdsLen := len(seriesIds)
+	lastIdx := seriesIdsLen - 1
+
+	var errSlice []error
+	var ugoiraSlice []*models.Ugoira
+	var artworksToDownload []*request.ToDownload
+	baseMsg := "Getting works from series on Pixiv [%d/" + fmt.Sprintf("%d]...", seriesIdsLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting works from %d series on Pixiv!",
+			seriesIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting works from %d series on Pixiv!\nPlease refer to the logs for more details.",
+			seriesIdsLen,
+		),
+		seriesIdsLen,
+	)
+	progress.Start()
+	for idx, seriesId := range seriesIds {
+		artworkDetails, ugoiraInfo, errS := pixiv.getSeriesPosts(seriesId, downloadPath)
+		errSlice = append(errSlice, errS...)
+		artworksToDownload = append(artworksToDownload, artworkDetails...)
+		ugoiraSlice = append(ugoiraSlice, ugoiraInfo...)
+		if idx != lastIdx {
+			pixiv.Sleep()
+		}
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+
+	return artworksToDownload, ugoiraSlice
+}
+
 func (pixiv *PixivMobile) tagSearchLogic(tagName, downloadPath string, dlOptions *PixivMobileDlOptions, offsetArg *offsetArgs) ([]*request.ToDownload, []*models.Ugoira, []error) {
 	var errSlice []error
 	var ugoiraSlice []*models.Ugoira
@@ -390,3 +595,57 @@ func (pixiv *PixivMobile) TagSearch(tagName, downloadPath, pageNum string, dlOpt
 	}
 	return artworksToDl, ugoiraSlice, len(errSlice) > 0
 }
+
+// Query Pixiv's API (mobile) for the IDs of all the illustrators
+// that the user is following both publicly and privately
+func (pixiv *PixivMobile) GetFollowingIllustratorIds() ([]string, error) {
+	if pixiv.userId == "" {
+		return nil, fmt.Errorf(
+			"pixiv mobile error %d: unable to get your user ID from Pixiv, please check if your refresh token is valid",
+			utils.INPUT_ERROR,
+		)
+	}
+
+	var illustratorIds []string
+	for _, restrict := range []string{"public", "private"} {
+		nextUrl := pixiv.baseUrl + "/v1/user/following"
+		params := map[string]string{
+			"user_id":  pixiv.userId,
+			"restrict": restrict,
+		}
+		for nextUrl != "" {
+			res, err := pixiv.SendRequest(
+				&request.RequestArgs{
+					Url:         nextUrl,
+					Params:      params,
+					CheckStatus: true,
+				},
+			)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"pixiv mobile error %d: failed to get followed illustrators, more info => %v",
+					utils.CONNECTION_ERROR,
+					err,
+				)
+			}
+
+			var resJson models.PixivMobileFollowingJson
+			if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+				return nil, err
+			}
+			for _, userPreview := range resJson.UserPreviews {
+				illustratorIds = append(illustratorIds, strconv.Itoa(userPreview.User.Id))
+			}
+
+			if resJson.NextUrl == nil {
+				nextUrl = ""
+			} else {
+				// the next URL already contains the query parameters
+				nextUrl = *resJson.NextUrl
+				params = nil
+				pixiv.Sleep()
+			}
+		}
+	}
+	return illustratorIds, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/fatih/color"
@@ -17,7 +18,16 @@ type PixivMobileDlOptions struct {
 	RatingMode  string
 	ArtworkType string
 
-	Configs     *configs.Config
+	// Only artworks with at least this number of bookmarks will be downloaded
+	MinBookmarks int
+
+	// DlFollowing downloads the artworks of all the illustrators that the user is following
+	DlFollowing bool
+
+	// Size of the images to download which can be "original", "large", or "medium"
+	ImageSize string
+
+	Configs *configs.Config
 
 	MobileClient *PixivMobile
 	RefreshToken string
@@ -103,8 +113,33 @@ func (p *PixivMobileDlOptions) ValidateArgs(userAgent string) {
 		},
 	)
 
+	p.ImageSize = strings.ToLower(p.ImageSize)
+	utils.ValidateStrArgs(
+		p.ImageSize,
+		pixivcommon.ACCEPTED_IMAGE_SIZE,
+		[]string{
+			fmt.Sprintf(
+				"pixiv error %d: Image size %s is not allowed",
+				utils.INPUT_ERROR,
+				p.ImageSize,
+			),
+		},
+	)
+
+	if p.MinBookmarks < 0 {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"pixiv error %d: Minimum bookmarks of %d is not allowed",
+			utils.INPUT_ERROR,
+			p.MinBookmarks,
+		)
+	}
+
 	if p.RefreshToken != "" {
 		p.MobileClient = NewPixivMobile(p.RefreshToken, 10)
+		p.MobileClient.configs = p.Configs
+		p.MobileClient.minBookmarks = p.MinBookmarks
+		p.MobileClient.imageSize = p.ImageSize
 		if p.RatingMode != "all" {
 			color.Red(
 				utils.CombineStringsWithNewline(
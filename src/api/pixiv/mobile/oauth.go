@@ -146,7 +146,7 @@ func (pixiv *PixivMobile) refreshAccessToken() error {
 				"%s %d: failed to refresh token due to %s response from Pixiv\n"+
 					"Please check your refresh token and try again or use the \"-pixiv_start_oauth\" flag to get a new refresh token",
 				errPrefix,
-				utils.RESPONSE_ERROR,
+				utils.AUTH_ERROR,
 				res.Status,
 			)
 		} else {
@@ -168,6 +168,7 @@ func (pixiv *PixivMobile) refreshAccessToken() error {
 
 	expiresIn := oauthJson.ExpiresIn - 15 // usually 3600 but minus 15 seconds to be safe
 	pixiv.accessTokenMap.accessToken = oauthJson.AccessToken
+	pixiv.userId = oauthJson.User.Id
 	pixiv.accessTokenMap.expiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
 	return nil
 }
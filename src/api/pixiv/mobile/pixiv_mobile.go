@@ -1,15 +1,13 @@
 package pixivmobile
 
 import (
-	"fmt"
 	"net/http"
-	"os"
 	"sync"
 	"time"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/fatih/color"
 )
 
 type PixivMobile struct {
@@ -23,8 +21,14 @@ type PixivMobile struct {
 	redirectUri  string
 	refreshToken string
 
+	// ID of the logged in user from the OAuth response
+	userId string
+
 	// User given arguments
-	apiTimeout int
+	apiTimeout   int
+	configs      *configs.Config
+	minBookmarks int
+	imageSize    string
 
 	// Access token information
 	accessTokenMu  sync.Mutex
@@ -48,8 +52,7 @@ func NewPixivMobile(refreshToken string, timeout int) *PixivMobile {
 		// refresh the access token and verify it
 		err := pixivMobile.refreshAccessToken()
 		if err != nil {
-			color.Red(err.Error())
-			os.Exit(1)
+			utils.ExitWithErrorf(utils.GetExitCode(err), "%v", err)
 		}
 	}
 	return pixivMobile
@@ -63,6 +66,10 @@ func NewPixivMobile(refreshToken string, timeout int) *PixivMobile {
 // Additionally, pixiv.net is protected by cloudflare, so
 // to prevent the user's IP reputation from going down, delays are added.
 func (pixiv *PixivMobile) Sleep() {
+	// every request is already delayed by the "--delay" flag if it was given
+	if utils.HasRequestDelay() {
+		return
+	}
 	time.Sleep(utils.GetRandomTime(1.0, 1.5))
 }
 
@@ -99,40 +106,14 @@ func (pixiv *PixivMobile) SendRequest(reqArgs *request.RequestArgs) (*http.Respo
 	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_MOBILE, true)
 	reqArgs.Http3 = useHttp3
 	reqArgs.Http2 = !useHttp3
-	reqArgs.ValidateArgs()
-
-	req, err := http.NewRequest(reqArgs.Method, reqArgs.Url, nil)
-	if err != nil {
-		return nil, err
-	}
 
-	refreshed, err := pixiv.refreshTokenIfReq()
-	if err != nil {
+	// the access token is refreshed before the headers are set so that the request uses the new token
+	if _, err := pixiv.refreshTokenIfReq(); err != nil {
 		return nil, err
 	}
+	reqArgs.Headers = pixiv.getHeaders(reqArgs.Headers)
+	reqArgs.UserAgent = pixiv.userAgent
 
-	for k, v := range pixiv.getHeaders(reqArgs.Headers) {
-		req.Header.Set(k, v)
-	}
-	request.AddParams(reqArgs.Params, req)
-
-	var res *http.Response
-	client := request.GetHttpClient(reqArgs)
-	client.Timeout = time.Duration(reqArgs.Timeout) * time.Second
-	for i := 1; i <= utils.RETRY_COUNTER; i++ {
-		res, err = client.Do(req)
-		if err == nil {
-			if refreshed {
-				continue
-			} else if res.StatusCode == 200 || !reqArgs.CheckStatus {
-				return res, nil
-			}
-		}
-		time.Sleep(utils.GetRandomDelay())
-	}
-	return nil, fmt.Errorf(
-		"request to %s failed after %d retries",
-		reqArgs.Url,
-		utils.RETRY_COUNTER,
-	)
+	// sent like the other requests so that the limits, tracing, and WARC recording apply to the API calls as well
+	return request.CallRequest(reqArgs)
 }
@@ -1,17 +1,19 @@
 package pixivmobile
 
 import (
-	"strconv"
 	"path/filepath"
+	"strconv"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
 // Process the artwork JSON and returns a slice of map that contains the urls of the images and the file path
 func (pixiv *PixivMobile) processArtworkJson(artworkJson *models.PixivMobileIllustJson, downloadPath string) ([]*request.ToDownload, *models.Ugoira, error) {
-	if artworkJson == nil {
+	if artworkJson == nil || artworkJson.TotalBookmarks < pixiv.minBookmarks {
 		return nil, nil, nil
 	}
 
@@ -19,15 +21,30 @@ func (pixiv *PixivMobile) processArtworkJson(artworkJson *models.PixivMobileIllu
 	artworkTitle := artworkJson.Title
 	artworkType := artworkJson.Type
 	illustratorName := artworkJson.User.Name
-	artworkFolderPath := utils.GetPostFolder(
-		filepath.Join(downloadPath, utils.PIXIV_TITLE), illustratorName, artworkId, artworkTitle,
+	siteFolderPath := filepath.Join(downloadPath, utils.PIXIV_TITLE)
+	creatorFolder := pixiv.configs.GetCreatorFolder(siteFolderPath, utils.PIXIV, strconv.Itoa(artworkJson.User.Id), illustratorName)
+	artworkFolderPath := pixiv.configs.GetPostFolder(
+		siteFolderPath, creatorFolder, artworkId, artworkTitle, artworkJson.CreateDate,
 	)
 
+	artworkInfo := &events.Post{
+		Site:        utils.PIXIV,
+		Id:          artworkId,
+		Title:       artworkTitle,
+		Creator:     illustratorName,
+		CreatorId:   strconv.Itoa(artworkJson.User.Id),
+		Folder:      artworkFolderPath,
+		PublishedAt: utils.ParsePostDate(artworkJson.CreateDate),
+		Body:        artworkJson.Caption,
+		BodyIsHtml:  true,
+	}
 	if artworkType == "ugoira" {
 		ugoiraInfo, err := pixiv.getUgoiraMetadata(artworkId, artworkFolderPath)
 		if err != nil {
 			return nil, nil, err
 		}
+		artworkInfo.FileCount = 1
+		events.PostResolved(artworkInfo)
 		return nil, ugoiraInfo, nil
 	}
 
@@ -35,18 +52,35 @@ func (pixiv *PixivMobile) processArtworkJson(artworkJson *models.PixivMobileIllu
 	singlePageImageUrl := artworkJson.MetaSinglePage.OriginalImageUrl
 	if singlePageImageUrl != "" {
 		artworksToDownload = append(artworksToDownload, &request.ToDownload{
-			Url:      singlePageImageUrl,
+			Url: pixivcommon.GetImageUrl(
+				pixiv.imageSize,
+				singlePageImageUrl,
+				artworkJson.ImageUrls.Large,
+				artworkJson.ImageUrls.Medium,
+			),
 			FilePath: artworkFolderPath,
 		})
 	} else {
 		for _, image := range artworkJson.MetaPages {
-			imageUrl := image.ImageUrls.Original
+			imageUrl := pixivcommon.GetImageUrl(
+				pixiv.imageSize,
+				image.ImageUrls.Original,
+				image.ImageUrls.Large,
+				image.ImageUrls.Medium,
+			)
 			artworksToDownload = append(artworksToDownload, &request.ToDownload{
 				Url:      imageUrl,
 				FilePath: artworkFolderPath,
 			})
 		}
 	}
+	// the page count from the API is used so that the artwork is not shown as empty,
+	// e.g. by the PostSelector, if the URLs of its pages are missing from the JSON
+	artworkInfo.FileCount = artworkJson.PageCount
+	if artworkInfo.FileCount == 0 {
+		artworkInfo.FileCount = len(artworksToDownload)
+	}
+	events.PostResolved(artworkInfo)
 	return artworksToDownload, nil, nil
 }
 
@@ -4,6 +4,10 @@ type Ugoira struct {
 	Url      string
 	FilePath string
 	Frames   map[string]int64
+
+	// SeriesIdx is the 1-based index of the ugoira in the series
+	// that it was downloaded from, 0 if it was not downloaded from a series
+	SeriesIdx int
 }
 
 type UgoiraFramesJson []struct {
@@ -3,6 +3,9 @@ package models
 type PixivOauthJson struct {
 	AccessToken string `json:"access_token"`
 	ExpiresIn   float64 `json:"expires_in"`
+	User        struct {
+		Id string `json:"id"`
+	} `json:"user"`
 }
 
 type PixivOauthFlowJson struct {
@@ -23,16 +26,31 @@ type PixivMobileIllustJson struct {
 	Title string `json:"title"`
 	Type  string `json:"type"`
 
+	CreateDate string `json:"create_date"`
+	Caption    string `json:"caption"`
+
+	TotalBookmarks int `json:"total_bookmarks"`
+	PageCount      int `json:"page_count"`
+
 	User struct {
-		Name  string `json:"name"`
+		Id   int    `json:"id"`
+		Name string `json:"name"`
 	} `json:"user"`
 
+	// Resized image URLs of the first page
+	ImageUrls struct {
+		Medium string `json:"medium"`
+		Large  string `json:"large"`
+	} `json:"image_urls"`
+
 	MetaSinglePage struct {
 		OriginalImageUrl string `json:"original_image_url"`
 	} `json:"meta_single_page"`
 
 	MetaPages []struct {
 		ImageUrls struct {
+			Medium   string `json:"medium"`
+			Large    string `json:"large"`
 			Original string `json:"original"`
 		} `json:"image_urls"`
 	} `json:"meta_pages"`
@@ -45,3 +63,12 @@ type PixivMobileArtworksJson struct {
 	Illusts []*PixivMobileIllustJson `json:"illusts"`
 	NextUrl *string                  `json:"next_url"`
 }
+
+type PixivMobileFollowingJson struct {
+	UserPreviews []struct {
+		User struct {
+			Id int `json:"id"`
+		} `json:"user"`
+	} `json:"user_previews"`
+	NextUrl *string `json:"next_url"`
+}
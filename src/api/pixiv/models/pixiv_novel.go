@@ -0,0 +1,38 @@
+package models
+
+type PixivNovelSeriesJson struct {
+	Body struct {
+		Id         string `json:"id"`
+		UserId     string `json:"userId"`
+		UserName   string `json:"userName"`
+		Title      string `json:"title"`
+		Caption    string `json:"caption"`
+		Language   string `json:"language"`
+		CreateDate string `json:"createDate"`
+		Cover      struct {
+			Urls map[string]string `json:"urls"`
+		} `json:"cover"`
+	} `json:"body"`
+}
+
+type PixivNovelSeriesContentJson struct {
+	Body struct {
+		SeriesContents []struct {
+			Id     string `json:"id"`
+			Title  string `json:"title"`
+			Series struct {
+				ContentOrder int `json:"contentOrder"`
+			} `json:"series"`
+		} `json:"seriesContents"`
+	} `json:"body"`
+}
+
+type PixivNovelJson struct {
+	Body struct {
+		Id         string `json:"id"`
+		Title      string `json:"title"`
+		Content    string `json:"content"`
+		UserName   string `json:"userName"`
+		CreateDate string `json:"createDate"`
+	} `json:"body"`
+}
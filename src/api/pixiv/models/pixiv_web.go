@@ -2,9 +2,13 @@ package models
 
 type ArtworkDetails struct {
 	Body struct {
-		UserName   string `json:"userName"`
-		Title      string `json:"title"`
-		IllustType int64  `json:"illustType"`
+		UserId        string `json:"userId"`
+		UserName      string `json:"userName"`
+		Title         string `json:"title"`
+		IllustType    int64  `json:"illustType"`
+		CreateDate    string `json:"createDate"`
+		BookmarkCount int    `json:"bookmarkCount"`
+		Description   string `json:"description"`
 	}
 }
 
@@ -46,3 +50,24 @@ type PixivWebIllustratorJson struct {
         Manga   interface{} `json:"manga"`
     } `json:"body"`
 }
+
+type PixivWebSeriesJson struct {
+	Body struct {
+		Page struct {
+			Series []struct {
+				WorkId string `json:"workId"`
+				Order  int    `json:"order"`
+			} `json:"series"`
+			Total int `json:"total"`
+		} `json:"page"`
+	} `json:"body"`
+}
+
+type PixivWebFollowingJson struct {
+	Body struct {
+		Users []struct {
+			UserId string `json:"userId"`
+		} `json:"users"`
+		Total int `json:"total"`
+	} `json:"body"`
+}
@@ -0,0 +1,144 @@
+package pixivnovel
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// number of chapters per page of the series contents which is the maximum allowed by Pixiv
+const SERIES_CONTENT_LIMIT = 30
+
+// Returns the arguments for a request to Pixiv's web API which is used
+// for the novels since the Pixiv mobile API does not return the text of the novels
+func getReqArgs(url string, dlOptions *PixivNovelDlOptions) *request.RequestArgs {
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+	return &request.RequestArgs{
+		Url:         url,
+		Method:      "GET",
+		Cookies:     dlOptions.SessionCookies,
+		Headers:     pixivcommon.GetPixivRequestHeaders(),
+		CheckStatus: true,
+		UserAgent:   dlOptions.Configs.UserAgent,
+		Http2:       !useHttp3,
+		Http3:       useHttp3,
+	}
+}
+
+// Query Pixiv's API for the details of the novel series, e.g. its title and author
+func getSeriesDetails(seriesId string, dlOptions *PixivNovelDlOptions) (*models.PixivNovelSeriesJson, error) {
+	url := fmt.Sprintf("%s/novel/series/%s", utils.PIXIV_API_URL, seriesId)
+	res, err := request.CallRequest(getReqArgs(url, dlOptions))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"pixiv error %d: failed to get details of novel series ID %s due to %v",
+			utils.CONNECTION_ERROR,
+			seriesId,
+			err,
+		)
+	}
+
+	var seriesJson models.PixivNovelSeriesJson
+	if err := utils.LoadJsonFromResponse(res, &seriesJson); err != nil {
+		return nil, err
+	}
+	return &seriesJson, nil
+}
+
+// Query Pixiv's API for the chapters of the novel series sorted by their order in the series
+func getSeriesChapters(seriesId string, dlOptions *PixivNovelDlOptions) ([]*chapter, error) {
+	url := fmt.Sprintf("%s/novel/series_content/%s", utils.PIXIV_API_URL, seriesId)
+	reqArgs := getReqArgs(url, dlOptions)
+	reqArgs.Params = map[string]string{
+		"limit":    strconv.Itoa(SERIES_CONTENT_LIMIT),
+		"order_by": "asc",
+	}
+
+	var chapters []*chapter
+	for {
+		reqArgs.Params["last_order"] = strconv.Itoa(len(chapters))
+		res, err := request.CallRequest(reqArgs)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"pixiv error %d: failed to get chapters of novel series ID %s due to %v",
+				utils.CONNECTION_ERROR,
+				seriesId,
+				err,
+			)
+		}
+
+		var jsonBody models.PixivNovelSeriesContentJson
+		if err := utils.LoadJsonFromResponse(res, &jsonBody); err != nil {
+			return nil, err
+		}
+
+		seriesContents := jsonBody.Body.SeriesContents
+		for _, content := range seriesContents {
+			chapters = append(chapters, &chapter{
+				novelId: content.Id,
+				title:   content.Title,
+				order:   content.Series.ContentOrder,
+			})
+		}
+		if len(seriesContents) < SERIES_CONTENT_LIMIT {
+			break
+		}
+		pixivSleep()
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].order < chapters[j].order
+	})
+	return chapters, nil
+}
+
+// Query Pixiv's API for the text of the novel and sets it as the content of the chapter
+func getChapterContent(chapter *chapter, dlOptions *PixivNovelDlOptions) error {
+	url := fmt.Sprintf("%s/novel/%s", utils.PIXIV_API_URL, chapter.novelId)
+	res, err := request.CallRequest(getReqArgs(url, dlOptions))
+	if err != nil {
+		return fmt.Errorf(
+			"pixiv error %d: failed to get novel ID %s due to %v",
+			utils.CONNECTION_ERROR,
+			chapter.novelId,
+			err,
+		)
+	}
+
+	var novelJson models.PixivNovelJson
+	if err := utils.LoadJsonFromResponse(res, &novelJson); err != nil {
+		return err
+	}
+	if novelJson.Body.Title != "" {
+		chapter.title = novelJson.Body.Title
+	}
+	chapter.content = novelJson.Body.Content
+	chapter.createDate = novelJson.Body.CreateDate
+	return nil
+}
+
+// Returns the cover image of the novel series and its file extension, e.g. ".jpg"
+func getCoverImage(coverUrl string, dlOptions *PixivNovelDlOptions) ([]byte, string, error) {
+	res, err := request.CallRequest(getReqArgs(coverUrl, dlOptions))
+	if err != nil {
+		return nil, "", fmt.Errorf(
+			"pixiv error %d: failed to get the cover image from %s due to %v",
+			utils.CONNECTION_ERROR,
+			coverUrl,
+			err,
+		)
+	}
+
+	cover, err := utils.ReadResBody(res)
+	if err != nil {
+		return nil, "", err
+	}
+	return cover, strings.ToLower(filepath.Ext(utils.GetLastPartOfUrl(coverUrl))), nil
+}
@@ -0,0 +1,289 @@
+package pixivnovel
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The name of the meta element in the package document that contains the novel IDs of the chapters
+// in the EPUB, which is used to skip compiling the EPUB again if the chapters of the series have not changed
+const epubChaptersMetaName = "cultured-downloader:chapters"
+
+var epubChaptersMetaRegex = regexp.MustCompile(
+	`<meta name="` + regexp.QuoteMeta(epubChaptersMetaName) + `" content="([^"]*)"/>`,
+)
+
+var coverMimeTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+const epubContainerXml = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// Returns an XHTML page of the EPUB with the given title and body
+func getXhtmlPage(lang, title, body string) string {
+	return fmt.Sprintf(
+		`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%[1]s" lang="%[1]s">
+<head>
+<meta charset="UTF-8"/>
+<title>%[2]s</title>
+</head>
+<body>
+%[3]s</body>
+</html>
+`,
+		lang,
+		html.EscapeString(title),
+		body,
+	)
+}
+
+// Returns the filename of the chapter's XHTML page in the EPUB
+func getChapterFilename(chapterIdx int) string {
+	return fmt.Sprintf("chapter%03d.xhtml", chapterIdx+1)
+}
+
+// Returns the novel IDs of the chapters of the series in order, separated by commas
+func getChapterIds(series *novelSeries) string {
+	chapterIds := make([]string, len(series.chapters))
+	for idx, chapter := range series.chapters {
+		chapterIds[idx] = chapter.novelId
+	}
+	return strings.Join(chapterIds, ",")
+}
+
+// Returns the novel IDs of the chapters in the EPUB compiled by compileEpub, separated by commas,
+// or an empty string if the EPUB was compiled by an older version without them
+func readEpubChapterIds(epub []byte) (string, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(epub), int64(len(epub)))
+	if err != nil {
+		return "", err
+	}
+
+	contentOpf, err := zipReader.Open("OEBPS/content.opf")
+	if err != nil {
+		return "", err
+	}
+	defer contentOpf.Close()
+
+	data, err := io.ReadAll(contentOpf)
+	if err != nil {
+		return "", err
+	}
+	if matched := epubChaptersMetaRegex.FindSubmatch(data); matched != nil {
+		return html.UnescapeString(string(matched[1])), nil
+	}
+	return "", nil
+}
+
+// Returns the package document of the EPUB with the metadata of the series, e.g. its title and author
+func getContentOpf(series *novelSeries, coverExt string) string {
+	var manifest, spine strings.Builder
+	if coverExt != "" {
+		fmt.Fprintf(
+			&manifest,
+			"    <item id=\"cover-image\" href=\"cover%s\" media-type=\"%s\" properties=\"cover-image\"/>\n",
+			coverExt,
+			coverMimeTypes[coverExt],
+		)
+		manifest.WriteString("    <item id=\"cover\" href=\"cover.xhtml\" media-type=\"application/xhtml+xml\"/>\n")
+		spine.WriteString("    <itemref idref=\"cover\" linear=\"no\"/>\n")
+	}
+	spine.WriteString("    <itemref idref=\"nav\"/>\n")
+	for idx := range series.chapters {
+		fmt.Fprintf(
+			&manifest,
+			"    <item id=\"chapter%03d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n",
+			idx+1,
+			getChapterFilename(idx),
+		)
+		fmt.Fprintf(&spine, "    <itemref idref=\"chapter%03d\"/>\n", idx+1)
+	}
+
+	description := ""
+	if series.caption != "" {
+		description = fmt.Sprintf("    <dc:description>%s</dc:description>\n", html.EscapeString(series.caption))
+	}
+	coverMeta := ""
+	if coverExt != "" {
+		// for EPUB 2 readers which do not support the cover-image property
+		coverMeta = "    <meta name=\"cover\" content=\"cover-image\"/>\n"
+	}
+	coverMeta += fmt.Sprintf(
+		"    <meta name=\"%s\" content=\"%s\"/>\n",
+		epubChaptersMetaName,
+		html.EscapeString(getChapterIds(series)),
+	)
+	return fmt.Sprintf(
+		`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="%[1]s">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">%[2]s</dc:identifier>
+    <dc:title>%[3]s</dc:title>
+    <dc:creator>%[4]s</dc:creator>
+    <dc:language>%[1]s</dc:language>
+    <dc:source>%[2]s</dc:source>
+%[5]s%[6]s    <meta property="dcterms:modified">%[7]s</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+%[8]s  </manifest>
+  <spine toc="ncx">
+%[9]s  </spine>
+</package>
+`,
+		series.lang,
+		html.EscapeString(series.url),
+		html.EscapeString(series.title),
+		html.EscapeString(series.author),
+		description,
+		coverMeta,
+		time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		manifest.String(),
+		spine.String(),
+	)
+}
+
+// Returns the EPUB 3 navigation document and the EPUB 2 NCX table of contents of the series
+func getToc(series *novelSeries) (string, string) {
+	var navItems, navPoints strings.Builder
+	for idx, chapter := range series.chapters {
+		title := html.EscapeString(chapter.title)
+		fmt.Fprintf(&navItems, "<li><a href=\"%s\">%s</a></li>\n", getChapterFilename(idx), title)
+		fmt.Fprintf(
+			&navPoints,
+			"    <navPoint id=\"nav%[1]d\" playOrder=\"%[1]d\">\n"+
+				"      <navLabel><text>%[2]s</text></navLabel>\n"+
+				"      <content src=\"%[3]s\"/>\n"+
+				"    </navPoint>\n",
+			idx+1,
+			title,
+			getChapterFilename(idx),
+		)
+	}
+
+	nav := getXhtmlPage(
+		series.lang,
+		series.title,
+		fmt.Sprintf(
+			"<h1>%s</h1>\n<p>%s</p>\n<nav epub:type=\"toc\" id=\"toc\">\n<ol>\n%s</ol>\n</nav>\n",
+			html.EscapeString(series.title),
+			html.EscapeString(series.author),
+			navItems.String(),
+		),
+	)
+	ncx := fmt.Sprintf(
+		`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+    <meta name="dtb:uid" content="%s"/>
+  </head>
+  <docTitle><text>%s</text></docTitle>
+  <docAuthor><text>%s</text></docAuthor>
+  <navMap>
+%s  </navMap>
+</ncx>
+`,
+		html.EscapeString(series.url),
+		html.EscapeString(series.title),
+		html.EscapeString(series.author),
+		navPoints.String(),
+	)
+	return nav, ncx
+}
+
+// Compiles the chapters of the novel series into an EPUB with the
+// cover image, the table of contents, and the author of the series
+//
+// The cover will be left out if coverExt is not a supported image format.
+func compileEpub(series *novelSeries, cover []byte, coverExt string) ([]byte, error) {
+	if _, ok := coverMimeTypes[coverExt]; !ok || len(cover) == 0 {
+		coverExt = ""
+	}
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+
+	// the mimetype file must be the first file in the EPUB and must not be compressed
+	mimetypeWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mimetypeWriter.Write([]byte("application/epub+zip")); err != nil {
+		return nil, err
+	}
+
+	nav, ncx := getToc(series)
+	files := [][2]string{
+		{"META-INF/container.xml", epubContainerXml},
+		{"OEBPS/content.opf", getContentOpf(series, coverExt)},
+		{"OEBPS/nav.xhtml", nav},
+		{"OEBPS/toc.ncx", ncx},
+	}
+	if coverExt != "" {
+		files = append(files, [2]string{
+			"OEBPS/cover.xhtml",
+			getXhtmlPage(
+				series.lang,
+				series.title,
+				fmt.Sprintf(
+					"<div style=\"text-align: center;\"><img src=\"cover%s\" alt=\"%s\" style=\"max-width: 100%%; max-height: 100%%;\"/></div>\n",
+					coverExt,
+					html.EscapeString(series.title),
+				),
+			),
+		})
+	}
+	for idx, chapter := range series.chapters {
+		files = append(files, [2]string{
+			"OEBPS/" + getChapterFilename(idx),
+			getXhtmlPage(
+				series.lang,
+				chapter.title,
+				fmt.Sprintf("<h1>%s</h1>\n%s", html.EscapeString(chapter.title), toXhtml(chapter.content)),
+			),
+		})
+	}
+
+	for _, file := range files {
+		fileWriter, err := zipWriter.Create(file[0])
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fileWriter.Write([]byte(file[1])); err != nil {
+			return nil, err
+		}
+	}
+	if coverExt != "" {
+		coverWriter, err := zipWriter.Create("OEBPS/cover" + coverExt)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := coverWriter.Write(cover); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package pixivnovel
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Matches the tags of Pixiv's novel markup:
+// https://www.pixiv.help/hc/en-us/articles/235584628
+var NOVEL_TAG_REGEX = regexp.MustCompile(
+	`\[\[rb:(?P<rubyBase>.+?) *> *(?P<rubyText>.+?)\]\]` +
+		`|\[\[jumpuri:(?P<linkText>.+?) *> *(?P<linkUrl>https?://[^\]]+?)\]\]` +
+		`|\[chapter:(?P<chapter>.+?)\]` +
+		`|\[newpage\]` +
+		`|\[jump:\d+\]` +
+		`|\[(?:pixivimage|uploadedimage):[^\]]+\]`,
+)
+
+var (
+	rubyBaseIdx = NOVEL_TAG_REGEX.SubexpIndex("rubyBase")
+	rubyTextIdx = NOVEL_TAG_REGEX.SubexpIndex("rubyText")
+	linkTextIdx = NOVEL_TAG_REGEX.SubexpIndex("linkText")
+	linkUrlIdx  = NOVEL_TAG_REGEX.SubexpIndex("linkUrl")
+	chapterIdx  = NOVEL_TAG_REGEX.SubexpIndex("chapter")
+)
+
+// Converts the tags of the line to the output format with convertTag
+// and the text between the tags with convertText
+func convertLine(line string, convertText func(string) string, convertTag func([]string) string) string {
+	var converted strings.Builder
+	lastIdx := 0
+	for _, matchIdx := range NOVEL_TAG_REGEX.FindAllStringSubmatchIndex(line, -1) {
+		converted.WriteString(convertText(line[lastIdx:matchIdx[0]]))
+
+		matched := make([]string, len(matchIdx)/2)
+		for i := range matched {
+			if matchIdx[i*2] >= 0 {
+				matched[i] = line[matchIdx[i*2]:matchIdx[i*2+1]]
+			}
+		}
+		converted.WriteString(convertTag(matched))
+		lastIdx = matchIdx[1]
+	}
+	converted.WriteString(convertText(line[lastIdx:]))
+	return converted.String()
+}
+
+// Converts the novel's content in Pixiv's novel markup to plain text,
+// e.g. "[[rb:漢字 > かんじ]]" to "漢字(かんじ)"
+func toPlainText(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for idx, line := range lines {
+		lines[idx] = convertLine(
+			line,
+			func(text string) string { return text },
+			func(matched []string) string {
+				switch {
+				case matched[rubyBaseIdx] != "":
+					return fmt.Sprintf("%s(%s)", matched[rubyBaseIdx], matched[rubyTextIdx])
+				case matched[linkTextIdx] != "":
+					return fmt.Sprintf("%s (%s)", matched[linkTextIdx], matched[linkUrlIdx])
+				case matched[chapterIdx] != "":
+					return matched[chapterIdx]
+				}
+				// page breaks, page jumps, and images are left out of the text
+				return ""
+			},
+		)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Converts the novel's content in Pixiv's novel markup to the XHTML body
+// of an EPUB chapter where each line is a paragraph
+func toXhtml(content string) string {
+	var body strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		if trimmedLine == "" {
+			body.WriteString("<p><br/></p>\n")
+			continue
+		}
+		if trimmedLine == "[newpage]" {
+			body.WriteString("<hr class=\"newpage\"/>\n")
+			continue
+		}
+
+		heading := ""
+		convertedLine := convertLine(
+			line,
+			html.EscapeString,
+			func(matched []string) string {
+				switch {
+				case matched[rubyBaseIdx] != "":
+					return fmt.Sprintf(
+						"<ruby>%s<rt>%s</rt></ruby>",
+						html.EscapeString(matched[rubyBaseIdx]),
+						html.EscapeString(matched[rubyTextIdx]),
+					)
+				case matched[linkTextIdx] != "":
+					return fmt.Sprintf(
+						"<a href=\"%s\">%s</a>",
+						html.EscapeString(matched[linkUrlIdx]),
+						html.EscapeString(matched[linkTextIdx]),
+					)
+				case matched[chapterIdx] != "":
+					heading = html.EscapeString(matched[chapterIdx])
+				}
+				return ""
+			},
+		)
+		if heading != "" {
+			fmt.Fprintf(&body, "<h2>%s</h2>\n", heading)
+		}
+		if strings.TrimSpace(convertedLine) != "" {
+			fmt.Fprintf(&body, "<p>%s</p>\n", convertedLine)
+		}
+	}
+	return body.String()
+}
@@ -0,0 +1,261 @@
+package pixivnovel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// PixivNovelDlOptions is the struct that contains the options for downloading novel series from Pixiv.
+type PixivNovelDlOptions struct {
+	// Epub compiles the chapters of each series into a single EPUB instead of a text file per chapter
+	Epub bool
+
+	// SessionCookies are only needed for the novel series that are restricted to logged in users, e.g. R-18
+	SessionCookies []*http.Cookie
+	Configs        *configs.Config
+}
+
+type chapter struct {
+	novelId    string
+	title      string
+	order      int
+	content    string // the text of the novel in Pixiv's novel markup
+	createDate string
+}
+
+type novelSeries struct {
+	url      string
+	title    string
+	author   string
+	caption  string
+	lang     string
+	chapters []*chapter
+}
+
+func pixivSleep() {
+	// every request is already delayed by the "--delay" flag if it was given
+	if utils.HasRequestDelay() {
+		return
+	}
+	time.Sleep(utils.GetRandomTime(0.5, 1.0))
+}
+
+// Returns the URL of the largest cover image of the series
+func getCoverUrl(urls map[string]string) string {
+	for _, size := range []string{"original", "1200x1200", "480mw", "240mw"} {
+		if url := urls[size]; url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// Writes each chapter of the series that has not been downloaded yet
+// to a text file prefixed by the chapter's index in the series, e.g. "001_Chapter Title.txt"
+func writeChapterTextFiles(series *novelSeries, seriesFolder string, dlOptions *PixivNovelDlOptions) []error {
+	var errSlice []error
+	for idx, chapter := range series.chapters {
+		filePath := filepath.Join(
+			seriesFolder,
+			fmt.Sprintf("%03d_%s.txt", idx+1, utils.CleanPathName(utils.TruncateString(chapter.title, dlOptions.Configs.MaxTitleLength))),
+		)
+		if !dlOptions.Configs.OverwriteFiles && storage.Exists(context.Background(), filePath) {
+			continue
+		}
+
+		pixivSleep()
+		if err := getChapterContent(chapter, dlOptions); err != nil {
+			errSlice = append(errSlice, err)
+			continue
+		}
+		text := fmt.Sprintf("%s\n\n%s\n", chapter.title, toPlainText(chapter.content))
+		if err := storage.WriteFile(context.Background(), filePath, []byte(text)); err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"pixiv error %d: failed to write novel ID %s to %s, more info => %v",
+				utils.OS_ERROR,
+				chapter.novelId,
+				filePath,
+				err,
+			))
+		}
+	}
+	return errSlice
+}
+
+// Returns true if the EPUB at the file path has the same chapters as the series
+// where the chapters are compared by their novel IDs in order
+func isEpubUpToDate(series *novelSeries, filePath string) bool {
+	epub, err := storage.ReadFile(context.Background(), filePath)
+	if err != nil {
+		return false
+	}
+	chapterIds, err := readEpubChapterIds(epub)
+	return err == nil && chapterIds != "" && chapterIds == getChapterIds(series)
+}
+
+// Compiles all the chapters of the series into an EPUB named after the series in the series folder
+//
+// The EPUB is only compiled again if chapters have been added to, removed from, or reordered in the series
+// since it was last compiled, or if the OverwriteFiles option is set.
+func writeEpub(series *novelSeries, seriesFolder, coverUrl string, dlOptions *PixivNovelDlOptions) []error {
+	filePath := filepath.Join(
+		seriesFolder,
+		utils.CleanPathName(utils.TruncateString(series.title, dlOptions.Configs.MaxTitleLength))+".epub",
+	)
+	if !dlOptions.Configs.OverwriteFiles && isEpubUpToDate(series, filePath) {
+		return nil
+	}
+
+	var errSlice []error
+	for _, chapter := range series.chapters {
+		pixivSleep()
+		if err := getChapterContent(chapter, dlOptions); err != nil {
+			errSlice = append(errSlice, err)
+		}
+	}
+	if len(errSlice) > 0 {
+		// an EPUB with missing chapters would be mistaken for the complete series
+		return errSlice
+	}
+
+	var cover []byte
+	var coverExt string
+	if coverUrl != "" {
+		var err error
+		if cover, coverExt, err = getCoverImage(coverUrl, dlOptions); err != nil {
+			// the EPUB is still compiled without the cover
+			errSlice = append(errSlice, err)
+		}
+	}
+
+	epub, err := compileEpub(series, cover, coverExt)
+	if err != nil {
+		return append(errSlice, fmt.Errorf(
+			"pixiv error %d: failed to compile the EPUB of novel series %s, more info => %v",
+			utils.OS_ERROR,
+			series.url,
+			err,
+		))
+	}
+
+	if err := storage.WriteFile(context.Background(), filePath, epub); err != nil {
+		errSlice = append(errSlice, fmt.Errorf(
+			"pixiv error %d: failed to write the EPUB of novel series %s to %s, more info => %v",
+			utils.OS_ERROR,
+			series.url,
+			filePath,
+			err,
+		))
+	}
+	return errSlice
+}
+
+// Downloads the chapters of the novel series to the series folder in the illustrator's folder
+func downloadSeries(seriesId, downloadPath string, dlOptions *PixivNovelDlOptions) []error {
+	seriesJson, err := getSeriesDetails(seriesId, dlOptions)
+	if err != nil {
+		return []error{err}
+	}
+
+	seriesDetails := seriesJson.Body
+	siteFolderPath := filepath.Join(downloadPath, utils.PIXIV_TITLE)
+	seriesFolder := dlOptions.Configs.GetPostFolder(
+		siteFolderPath,
+		dlOptions.Configs.GetCreatorFolder(siteFolderPath, utils.PIXIV, seriesDetails.UserId, seriesDetails.UserName),
+		"novel_series_"+seriesId,
+		seriesDetails.Title,
+		seriesDetails.CreateDate,
+	)
+	if !dlOptions.Configs.ShouldDlPost(seriesFolder) {
+		return nil
+	}
+
+	pixivSleep()
+	chapters, err := getSeriesChapters(seriesId, dlOptions)
+	if err != nil {
+		return []error{err}
+	}
+
+	series := &novelSeries{
+		url:      fmt.Sprintf("%s/novel/series/%s", utils.PIXIV_URL, seriesId),
+		title:    seriesDetails.Title,
+		author:   seriesDetails.UserName,
+		caption:  seriesDetails.Caption,
+		lang:     seriesDetails.Language,
+		chapters: chapters,
+	}
+	if series.lang == "" {
+		series.lang = "ja"
+	}
+
+	fileCount := len(chapters)
+	if dlOptions.Epub {
+		fileCount = 1
+	}
+	events.PostResolved(&events.Post{
+		Site:        utils.PIXIV,
+		Id:          seriesId,
+		Title:       series.title,
+		Creator:     series.author,
+		Folder:      seriesFolder,
+		FileCount:   fileCount,
+		FileFilter:  events.GetFileFilter(map[string]bool{"epub": dlOptions.Epub}),
+		PublishedAt: utils.ParsePostDate(seriesDetails.CreateDate),
+		Body:        series.caption,
+	})
+
+	if dlOptions.Epub {
+		return writeEpub(series, seriesFolder, getCoverUrl(seriesDetails.Cover.Urls), dlOptions)
+	}
+	return writeChapterTextFiles(series, seriesFolder, dlOptions)
+}
+
+// Downloads the chapters of multiple novel series from Pixiv as text files,
+// or as a single EPUB for each series if the Epub option is set
+func DownloadMultipleSeries(seriesIds []string, downloadPath string, dlOptions *PixivNovelDlOptions) {
+	var errSlice []error
+	seriesIdsLen := len(seriesIds)
+
+	baseMsg := "Downloading novel series from Pixiv [%d/" + fmt.Sprintf("%d]...", seriesIdsLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished downloading %d novel series from Pixiv!",
+			seriesIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while downloading %d novel series from Pixiv!\nPlease refer to the logs for more details.",
+			seriesIdsLen,
+		),
+		seriesIdsLen,
+	)
+	progress.Start()
+	for idx, seriesId := range seriesIds {
+		if idx > 0 {
+			pixivSleep()
+		}
+		errSlice = append(errSlice, downloadSeries(seriesId, downloadPath, dlOptions)...)
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+}
@@ -3,28 +3,57 @@ package pixiv
 import (
 	"fmt"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/mobile"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/novel"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/ugoira"
-	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/web"
-	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/mobile"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
-func alertUser(artworksToDl []*request.ToDownload, ugoiraToDl []*models.Ugoira) {
-	if len(artworksToDl) > 0 || len(ugoiraToDl) > 0 {
-		utils.AlertWithoutErr(utils.Title, "Finished downloading artworks from Pixiv!")
+func alertUser(artworksToDl []*request.ToDownload, ugoiraToDl []*models.Ugoira, novelSeriesIds []string) {
+	if len(artworksToDl) > 0 || len(ugoiraToDl) > 0 || len(novelSeriesIds) > 0 {
+		utils.AlertWithoutErr(utils.Title, i18n.T("Finished downloading artworks from Pixiv!"))
 	} else {
-		utils.AlertWithoutErr(utils.Title, "No artworks to download from Pixiv!")
+		utils.AlertWithoutErr(utils.Title, i18n.T("No artworks to download from Pixiv!"))
 	}
 }
 
+// Adds the illustrators that the user is following to the illustrators to download from
+func addFollowingIllustrators(pixivDl *PixivDl, getFollowingIds func() ([]string, error)) {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting followed illustrators from Pixiv...",
+		"Finished getting followed illustrators from Pixiv!",
+		"Something went wrong while getting followed illustrators from Pixiv.\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+	illustratorIds, err := getFollowingIds()
+	hasErr := (err != nil)
+	if hasErr {
+		utils.LogError(err, "", false, utils.ERROR)
+	} else {
+		pixivDl.addIllustratorIds(illustratorIds)
+	}
+	progress.Stop(hasErr)
+}
+
 // Start the download process for Pixiv
-func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWebDlOptions, pixivUgoiraOptions *ugoira.UgoiraOptions) {
+func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWebDlOptions, pixivUgoiraOptions *ugoira.UgoiraOptions, novelEpub bool) {
 	var ugoiraToDl []*models.Ugoira
 	var artworksToDl []*request.ToDownload
+	if pixivDlOptions.DlFollowing {
+		addFollowingIllustrators(pixivDl, func() ([]string, error) {
+			return pixivweb.GetFollowingIllustratorIds(pixivDlOptions)
+		})
+	}
+
 	if len(pixivDl.IllustratorIds) > 0 {
 		artworkIdsSlice := pixivweb.GetMultipleIllustratorPosts(
 			pixivDl.IllustratorIds,
@@ -46,6 +75,16 @@ func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWeb
 		ugoiraToDl = append(ugoiraToDl, ugoiraSlice...)
 	}
 
+	if len(pixivDl.SeriesIds) > 0 {
+		artworkSlice, ugoiraSlice := pixivweb.GetMultipleSeriesPosts(
+			pixivDl.SeriesIds,
+			utils.DOWNLOAD_PATH,
+			pixivDlOptions,
+		)
+		artworksToDl = append(artworksToDl, artworkSlice...)
+		ugoiraToDl = append(ugoiraToDl, ugoiraSlice...)
+	}
+
 	if len(pixivDl.TagNames) > 0 {
 		// loop through each tag and page number
 		baseMsg := "Searching for artworks based on tag names on Pixiv [%d/" + fmt.Sprintf("%d]...", len(pixivDl.TagNames))
@@ -108,20 +147,39 @@ func PixivWebDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivweb.PixivWeb
 			request.CallRequest,
 		)
 	}
+	if len(pixivDl.NovelSeriesIds) > 0 {
+		pixivnovel.DownloadMultipleSeries(
+			pixivDl.NovelSeriesIds,
+			utils.DOWNLOAD_PATH,
+			&pixivnovel.PixivNovelDlOptions{
+				Epub:           novelEpub,
+				SessionCookies: pixivDlOptions.SessionCookies,
+				Configs:        pixivDlOptions.Configs,
+			},
+		)
+	}
 
-	alertUser(artworksToDl, ugoiraToDl)
+	alertUser(artworksToDl, ugoiraToDl, pixivDl.NovelSeriesIds)
 }
 
 // Start the download process for Pixiv
-func PixivMobileDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivmobile.PixivMobileDlOptions, pixivUgoiraOptions *ugoira.UgoiraOptions) {
+//
+// The novel series are downloaded with Pixiv's web API without a session
+// cookie, hence, the series restricted to logged in users cannot be downloaded.
+func PixivMobileDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivmobile.PixivMobileDlOptions, pixivUgoiraOptions *ugoira.UgoiraOptions, novelEpub bool) {
 	var ugoiraToDl []*models.Ugoira
 	var artworksToDl []*request.ToDownload
+	if pixivDlOptions.DlFollowing {
+		addFollowingIllustrators(pixivDl, pixivDlOptions.MobileClient.GetFollowingIllustratorIds)
+	}
+
 	if len(pixivDl.IllustratorIds) > 0 {
 		artworkSlice, ugoiraSlice := pixivDlOptions.MobileClient.GetMultipleIllustratorPosts(
 			pixivDl.IllustratorIds,
 			pixivDl.IllustratorPageNums,
 			utils.DOWNLOAD_PATH,
 			pixivDlOptions.ArtworkType,
+			pixivDlOptions.Configs.Order,
 		)
 		artworksToDl = artworkSlice
 		ugoiraToDl = ugoiraSlice
@@ -136,6 +194,15 @@ func PixivMobileDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivmobile.Pi
 		ugoiraToDl = append(ugoiraToDl, ugoiraSlice...)
 	}
 
+	if len(pixivDl.SeriesIds) > 0 {
+		artworkSlice, ugoiraSlice := pixivDlOptions.MobileClient.GetMultipleSeriesPosts(
+			pixivDl.SeriesIds,
+			utils.DOWNLOAD_PATH,
+		)
+		artworksToDl = append(artworksToDl, artworkSlice...)
+		ugoiraToDl = append(ugoiraToDl, ugoiraSlice...)
+	}
+
 	if len(pixivDl.TagNames) > 0 {
 		// loop through each tag and page number
 		baseMsg := "Searching for artworks based on tag names on Pixiv [%d/" + fmt.Sprintf("%d]...", len(pixivDl.TagNames))
@@ -197,6 +264,16 @@ func PixivMobileDownloadProcess(pixivDl *PixivDl, pixivDlOptions *pixivmobile.Pi
 			pixivDlOptions.MobileClient.SendRequest,
 		)
 	}
+	if len(pixivDl.NovelSeriesIds) > 0 {
+		pixivnovel.DownloadMultipleSeries(
+			pixivDl.NovelSeriesIds,
+			utils.DOWNLOAD_PATH,
+			&pixivnovel.PixivNovelDlOptions{
+				Epub:    novelEpub,
+				Configs: pixivDlOptions.Configs,
+			},
+		)
+	}
 
-	alertUser(artworksToDl, ugoiraToDl)
+	alertUser(artworksToDl, ugoiraToDl, pixivDl.NovelSeriesIds)
 }
@@ -2,19 +2,24 @@ package ugoira
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"syscall"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/pipeline"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
@@ -85,13 +90,114 @@ func ConvertUgoira(ugoiraInfo *models.Ugoira, imagesFolderPath string, ugoiraFfm
 	return nil
 }
 
+// A frame of the ugoira in the frames manifest
+type frameManifest struct {
+	File  string `json:"file"`
+	Delay int64  `json:"delay"` // in milliseconds
+}
+
+// Returns the path of the ugoira's frames manifest which is saved next to the ugoira's zip file
+func GetFramesManifestPath(zipFilePath string) string {
+	return utils.RemoveExtFromFilename(zipFilePath) + ".json"
+}
+
+// Saves the filename and delay of each frame of the ugoira in the order they are
+// played as a JSON file next to the ugoira's zip file, e.g. "12345_ugoira1920x1080.json",
+// so that the ugoira can be converted again or by other programs without Pixiv's API.
+func writeFramesManifest(ugoiraInfo *models.Ugoira, zipFilePath string) error {
+	sortedFilenames := make([]string, 0, len(ugoiraInfo.Frames))
+	for filename := range ugoiraInfo.Frames {
+		sortedFilenames = append(sortedFilenames, filename)
+	}
+	sort.Strings(sortedFilenames)
+
+	frames := make([]frameManifest, len(sortedFilenames))
+	for idx, filename := range sortedFilenames {
+		frames[idx] = frameManifest{
+			File:  filename,
+			Delay: ugoiraInfo.Frames[filename],
+		}
+	}
+
+	manifest, err := json.MarshalIndent(map[string]any{"frames": frames}, "", "    ")
+	if err != nil {
+		return fmt.Errorf(
+			"pixiv error %d: failed to marshal the frames of the ugoira %s, more info => %v",
+			utils.JSON_ERROR,
+			ugoiraInfo.Url,
+			err,
+		)
+	}
+
+	manifestPath := GetFramesManifestPath(zipFilePath)
+	if err := storage.WriteFile(context.Background(), manifestPath, manifest); err != nil {
+		return fmt.Errorf(
+			"pixiv error %d: failed to save the frames of the ugoira to %s, more info => %v",
+			utils.OS_ERROR,
+			manifestPath,
+			err,
+		)
+	}
+	return nil
+}
+
 // Returns the ugoira's zip file path and the ugoira's converted file path
-func GetUgoiraFilePaths(ugoireFilePath, ugoiraUrl, outputFormat string) (string, string) {
-	filePath := filepath.Join(ugoireFilePath, utils.GetLastPartOfUrl(ugoiraUrl))
+// which are prefixed with the ugoira's index in the series if it is from a series
+func GetUgoiraFilePaths(ugoira *models.Ugoira, outputFormat string) (string, string) {
+	filePath := filepath.Join(
+		ugoira.FilePath,
+		pixivcommon.GetSeriesFilename(utils.GetLastPartOfUrl(ugoira.Url), ugoira.SeriesIdx),
+	)
 	outputFilePath := utils.RemoveExtFromFilename(filePath) + outputFormat
 	return filePath, outputFilePath
 }
 
+// Extracts the downloaded ugoira's zip file and converts its frames to the output format
+//
+// Returns the name of the converted file for the progress message.
+func convertUgoiraZip(ctx context.Context, ugoira *models.Ugoira, ugoiraOptions *UgoiraOptions, config *configs.Config) (string, error) {
+	zipFilePath, outputPath := GetUgoiraFilePaths(ugoira, ugoiraOptions.OutputFormat)
+	if utils.PathExists(outputPath) || !utils.PathExists(zipFilePath) {
+		return "", nil
+	}
+
+	unzipFolderPath := filepath.Join(
+		filepath.Dir(zipFilePath),
+		"unzipped",
+	)
+	err := utils.ExtractFiles(ctx, zipFilePath, unzipFolderPath, true)
+	if err != nil {
+		if err == context.Canceled {
+			return "", err
+		}
+		return "", fmt.Errorf(
+			"pixiv error %d: failed to unzip file %s, more info => %v",
+			utils.OS_ERROR,
+			zipFilePath,
+			err,
+		)
+	}
+
+	err = ConvertUgoira(
+		ugoira,
+		unzipFolderPath,
+		&UgoiraFfmpegArgs{
+			ffmpegPath:    config.FfmpegPath,
+			outputPath:    outputPath,
+			ugoiraQuality: ugoiraOptions.Quality,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	if ugoiraOptions.DeleteZip {
+		os.Remove(zipFilePath)
+	}
+	return filepath.Base(outputPath), nil
+}
+
+// Extracts and converts the downloaded ugoira concurrently
+// using a queue that limits the number of conversions to utils.MAX_CONCURRENT_EXTRACTIONS.
 func convertMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions, config *configs.Config) {
 	// Create a context that can be cancelled when SIGINT/SIGTERM signal is received
 	ctx, cancel := context.WithCancel(context.Background())
@@ -106,90 +212,40 @@ func convertMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions,
 	}()
 	defer signal.Stop(sigs)
 
-	var errSlice []error
 	downloadInfoLen := len(ugoiraArgs.ToDownload)
-	baseMsg := "Converting Ugoira to %s [%d/" + fmt.Sprintf("%d]...", downloadInfoLen)
-	progress := spinner.New(
-		spinner.DL_SPINNER,
-		"fgHiYellow",
-		fmt.Sprintf(
-			baseMsg,
-			0,
-		),
-		fmt.Sprintf(
-			"Finished converting %d Ugoira to %s!",
-			downloadInfoLen,
-			ugoiraOptions.OutputFormat,
-		),
-		fmt.Sprintf(
-			"Something went wrong while converting %d Ugoira to %s!\nPlease refer to the logs for more details.",
-			downloadInfoLen,
-			ugoiraOptions.OutputFormat,
-		),
-		downloadInfoLen,
-	)
-	progress.Start()
-	for i, ugoira := range ugoiraArgs.ToDownload {
-		zipFilePath, outputPath := GetUgoiraFilePaths(ugoira.FilePath, ugoira.Url, ugoiraOptions.OutputFormat)
-		if utils.PathExists(outputPath) {
-			progress.MsgIncrement(baseMsg)
-			continue
-		}
-		if !utils.PathExists(zipFilePath) {
-			progress.MsgIncrement(baseMsg)
-			continue
-		}
-
-		unzipFolderPath := filepath.Join(
-			filepath.Dir(zipFilePath),
-			"unzipped",
-		)
-		err := utils.ExtractFiles(ctx, zipFilePath, unzipFolderPath, true)
-		if err != nil {
-			if err == context.Canceled {
+	pipeline.Run(&pipeline.Options[struct{}]{
+		Count:          downloadInfoLen,
+		MaxConcurrency: utils.MAX_CONCURRENT_EXTRACTIONS,
+		Progress: &pipeline.Progress{
+			SpinnerType: spinner.DL_SPINNER,
+			Msg:         i18n.Sprintf("Converting Ugoira to %s", ugoiraOptions.OutputFormat),
+			SuccessMsg: i18n.Sprintf(
+				"Finished converting %d Ugoira to %s!",
+				downloadInfoLen,
+				ugoiraOptions.OutputFormat,
+			),
+			ErrMsg: i18n.Sprintf(
+				"Something went wrong while converting %d Ugoira to %s!\nPlease refer to the logs for more details.",
+				downloadInfoLen,
+				ugoiraOptions.OutputFormat,
+			),
+			ShowInfo: true,
+		},
+		Task: func(idx int) (struct{}, string, error) {
+			filename, err := convertUgoiraZip(ctx, ugoiraArgs.ToDownload[idx], ugoiraOptions, config)
+			return struct{}{}, filename, err
+		},
+		ErrHandler: func(errs []error, progress *spinner.Spinner) {
+			if kill := utils.LogErrors(false, nil, utils.ERROR, errs...); kill {
 				progress.KillProgram(
-					fmt.Sprintf(
-						"Stopped converting ugoira to %s [%d/%d]!", 
-						ugoiraOptions.OutputFormat, 
-						i, 
-						len(ugoiraArgs.ToDownload),
+					i18n.Sprintf(
+						"Stopped converting ugoira to %s!",
+						ugoiraOptions.OutputFormat,
 					),
 				)
 			}
-			err := fmt.Errorf(
-				"pixiv error %d: failed to unzip file %s, more info => %v",
-				utils.OS_ERROR,
-				zipFilePath,
-				err,
-			)
-			errSlice = append(errSlice, err)
-			progress.MsgIncrement(baseMsg)
-			continue
-		}
-
-		err = ConvertUgoira(
-			ugoira,
-			unzipFolderPath,
-			&UgoiraFfmpegArgs{
-				ffmpegPath: config.FfmpegPath,
-				outputPath: outputPath,
-				ugoiraQuality: ugoiraOptions.Quality,
-			},
-		)
-		if err != nil {
-			errSlice = append(errSlice, err)
-		} else if ugoiraOptions.DeleteZip {
-			os.Remove(zipFilePath)
-		}
-		progress.MsgIncrement(baseMsg)
-	}
-
-	hasErr := false
-	if len(errSlice) > 0 {
-		hasErr = true
-		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
-	}
-	progress.Stop(hasErr)
+		},
+	})
 }
 
 type UgoiraArgs struct {
@@ -199,14 +255,29 @@ type UgoiraArgs struct {
 }
 
 // Downloads multiple Ugoira artworks and converts them based on the output format
+//
+// The ugoira are treated as images or videos based on the output format when the Only field of the config is set.
 func DownloadMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions, config *configs.Config, reqHandler request.RequestHandler) {
+	if !config.ShouldDlFile(ugoiraOptions.OutputFormat) {
+		return
+	}
+
+	var errSlice []error
 	var urlsToDownload []*request.ToDownload
 	for _, ugoira := range ugoiraArgs.ToDownload {
+		if !config.ShouldDlPost(ugoira.FilePath) {
+			continue
+		}
+
 		filePath, outputFilePath := GetUgoiraFilePaths(
-			ugoira.FilePath,
-			ugoira.Url,
+			ugoira,
 			ugoiraOptions.OutputFormat,
 		)
+		if !storage.Exists(context.Background(), GetFramesManifestPath(filePath)) {
+			if err := writeFramesManifest(ugoira, filePath); err != nil {
+				errSlice = append(errSlice, err)
+			}
+		}
 		if !utils.PathExists(outputFilePath) {
 			urlsToDownload = append(urlsToDownload, &request.ToDownload{
 				Url:      ugoira.Url,
@@ -215,6 +286,10 @@ func DownloadMultipleUgoira(ugoiraArgs *UgoiraArgs, ugoiraOptions *UgoiraOptions
 		}
 	}
 
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+
 	var useHttp3 bool
 	var headers map[string]string
 	if ugoiraArgs.UseMobileApi {
@@ -2,11 +2,9 @@ package ugoira
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/fatih/color"
 )
 
 // UgoiraDlOptions is the struct that contains the
@@ -33,25 +31,19 @@ func (u *UgoiraOptions) ValidateArgs() {
 
 	// u.Quality is only for .mp4 and .webm
 	if u.OutputFormat == ".mp4" && u.Quality < 0 || u.Quality > 51 {
-		color.Red(
-			fmt.Sprintf(
-				"pixiv error %d: Ugoira quality of %d is not allowed",
-				utils.INPUT_ERROR,
-				u.Quality,
-			),
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"pixiv error %d: Ugoira quality of %d is not allowed\nUgoira quality for FFmpeg must be between 0 and 51 for .mp4",
+			utils.INPUT_ERROR,
+			u.Quality,
 		)
-		color.Red("Ugoira quality for FFmpeg must be between 0 and 51 for .mp4")
-		os.Exit(1)
 	} else if u.OutputFormat == ".webm" && u.Quality < 0 || u.Quality > 63 {
-		color.Red(
-			fmt.Sprintf(
-				"pixiv error %d: Ugoira quality of %d is not allowed",
-				utils.INPUT_ERROR,
-				u.Quality,
-			),
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"pixiv error %d: Ugoira quality of %d is not allowed\nUgoira quality for FFmpeg must be between 0 and 63 for .webm",
+			utils.INPUT_ERROR,
+			u.Quality,
 		)
-		color.Red("Ugoira quality for FFmpeg must be between 0 and 63 for .webm")
-		os.Exit(1)
 	}
 
 	u.OutputFormat = strings.ToLower(u.OutputFormat)
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/postdb"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -26,6 +30,9 @@ func getArtworkDetailsLogic(artworkId string, reqArgs *request.RequestArgs) (*mo
 
 	if artworkDetailsRes.StatusCode != 200 {
 		artworkDetailsRes.Body.Close()
+		if artworkDetailsRes.StatusCode == http.StatusNotFound {
+			events.PostNotFound(utils.PIXIV, artworkId)
+		}
 		return nil, fmt.Errorf(
 			"pixiv error %d: failed to get details for artwork ID %s due to %s response from %s",
 			utils.RESPONSE_ERROR,
@@ -114,13 +121,19 @@ func getArtworkDetails(artworkId, downloadPath string, dlOptions *PixivWebDlOpti
 	}
 
 	artworkJsonBody := artworkDetailsJsonRes.Body
+	if artworkJsonBody.BookmarkCount < dlOptions.MinBookmarks {
+		return nil, nil, nil
+	}
+
 	illustratorName := artworkJsonBody.UserName
 	artworkName := artworkJsonBody.Title
-	artworkPostDir := utils.GetPostFolder(
-		filepath.Join(downloadPath, utils.PIXIV_TITLE),
-		illustratorName,
+	siteFolderPath := filepath.Join(downloadPath, utils.PIXIV_TITLE)
+	artworkPostDir := dlOptions.Configs.GetPostFolder(
+		siteFolderPath,
+		dlOptions.Configs.GetCreatorFolder(siteFolderPath, utils.PIXIV, artworkJsonBody.UserId, illustratorName),
 		artworkId,
 		artworkName,
+		artworkJsonBody.CreateDate,
 	)
 
 	artworkType := artworkJsonBody.IllustType
@@ -133,10 +146,28 @@ func getArtworkDetails(artworkId, downloadPath string, dlOptions *PixivWebDlOpti
 		artworkUrlsRes,
 		artworkType,
 		artworkPostDir,
+		dlOptions.ImageSize,
 	)
 	if err != nil {
 		return nil, nil, err
 	}
+
+	fileCount := len(urlsToDl)
+	if ugoiraInfo != nil {
+		fileCount++
+	}
+	events.PostResolved(&events.Post{
+		Site:        utils.PIXIV,
+		Id:          artworkId,
+		Title:       artworkName,
+		Creator:     illustratorName,
+		CreatorId:   artworkJsonBody.UserId,
+		Folder:      artworkPostDir,
+		FileCount:   fileCount,
+		PublishedAt: utils.ParsePostDate(artworkJsonBody.CreateDate),
+		Body:        artworkJsonBody.Description,
+		BodyIsHtml:  true,
+	})
 	return urlsToDl, ugoiraInfo, nil
 }
 
@@ -203,7 +234,10 @@ func GetMultipleArtworkDetails(artworkIds []string, downloadPath string, dlOptio
 }
 
 // Query Pixiv's API for all the illustrator's posts
-func getIllustratorPosts(illustratorId, pageNum string, dlOptions *PixivWebDlOptions) ([]string, error) {
+//
+// Returns true if all of the illustrator's artworks were listed, i.e. the pages and artwork types
+// were not limited and the listing was not cut short by the seen tracker.
+func getIllustratorPosts(illustratorId, pageNum string, dlOptions *PixivWebDlOptions) ([]string, bool, error) {
 	headers := pixivcommon.GetPixivRequestHeaders()
 	headers["Referer"] = pixivcommon.GetIllustUrl(illustratorId)
 	url := fmt.Sprintf("%s/user/%s/profile/all", utils.PIXIV_API_URL, illustratorId)
@@ -221,7 +255,7 @@ func getIllustratorPosts(illustratorId, pageNum string, dlOptions *PixivWebDlOpt
 		},
 	)
 	if err != nil {
-		return nil, fmt.Errorf(
+		return nil, false, fmt.Errorf(
 			"pixiv error %d: failed to get illustrator's posts with an ID of %s due to %v",
 			utils.CONNECTION_ERROR,
 			illustratorId,
@@ -230,7 +264,7 @@ func getIllustratorPosts(illustratorId, pageNum string, dlOptions *PixivWebDlOpt
 	}
 	if res.StatusCode != 200 {
 		res.Body.Close()
-		return nil, fmt.Errorf(
+		return nil, false, fmt.Errorf(
 			"pixiv error %d: failed to get illustrator's posts with an ID of %s due to %s response",
 			utils.RESPONSE_ERROR,
 			illustratorId,
@@ -240,12 +274,49 @@ func getIllustratorPosts(illustratorId, pageNum string, dlOptions *PixivWebDlOpt
 
 	var jsonBody models.PixivWebIllustratorJson
 	if err := utils.LoadJsonFromResponse(res, &jsonBody); err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	artworkIds, err := processIllustratorPostJson(&jsonBody, pageNum, dlOptions)
+	artworkIds, stopped, err := processIllustratorPostJson(&jsonBody, pageNum, dlOptions)
+	isFullListing := pageNum == "" && dlOptions.ArtworkType == "all" && !stopped
+	return artworkIds, isFullListing, err
+}
+
+// GetIllustratorPostIds returns the IDs of all the artworks of the illustrator
+func GetIllustratorPostIds(illustratorId string, dlOptions *PixivWebDlOptions) ([]string, error) {
+	artworkIds, _, err := getIllustratorPosts(illustratorId, "", dlOptions)
 	return artworkIds, err
 }
 
+// Requests the details of the recorded artworks of the illustrator that are not in its full listing
+// so that the artworks that have been deleted are flagged, as their details are not requested otherwise
+func probeUnlistedArtworks(illustratorId string, listedArtworkIds []string, dlOptions *PixivWebDlOptions) {
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+	for _, artworkId := range postdb.GetUnlistedPostIds(utils.PIXIV, illustratorId, listedArtworkIds) {
+		pixivSleep()
+		headers := pixivcommon.GetPixivRequestHeaders()
+		headers["Referer"] = pixivcommon.GetIllustUrl(artworkId)
+		res, err := request.CallRequest(
+			&request.RequestArgs{
+				Url:       fmt.Sprintf("%s/illust/%s", utils.PIXIV_API_URL, artworkId),
+				Method:    "GET",
+				Cookies:   dlOptions.SessionCookies,
+				Headers:   headers,
+				UserAgent: dlOptions.Configs.UserAgent,
+				Http2:     !useHttp3,
+				Http3:     useHttp3,
+			},
+		)
+		if err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			events.PostNotFound(utils.PIXIV, artworkId)
+		}
+	}
+}
+
 // Get posts from multiple illustrators and returns a slice of artwork IDs
 func GetMultipleIllustratorPosts(illustratorIds, pageNums []string, downloadPath string, dlOptions *PixivWebDlOptions) []string {
 	var errSlice []error
@@ -273,7 +344,7 @@ func GetMultipleIllustratorPosts(illustratorIds, pageNums []string, downloadPath
 	)
 	progress.Start()
 	for idx, illustratorId := range illustratorIds {
-		artworkIds, err := getIllustratorPosts(
+		artworkIds, isFullListing, err := getIllustratorPosts(
 			illustratorId,
 			pageNums[idx],
 			dlOptions,
@@ -282,6 +353,9 @@ func GetMultipleIllustratorPosts(illustratorIds, pageNums []string, downloadPath
 			errSlice = append(errSlice, err)
 		} else {
 			artworkIdsSlice = append(artworkIdsSlice, artworkIds...)
+			if isFullListing {
+				probeUnlistedArtworks(illustratorId, artworkIds, dlOptions)
+			}
 		}
 
 		if idx != lastIllustratorIdx {
@@ -300,6 +374,139 @@ func GetMultipleIllustratorPosts(illustratorIds, pageNums []string, downloadPath
 	return artworkIdsSlice
 }
 
+// Query Pixiv's API for the IDs of the works in the series sorted by their order in the series
+func getSeriesWorkIds(seriesId string, dlOptions *PixivWebDlOptions) ([]string, error) {
+	url := fmt.Sprintf("%s/series/%s", utils.PIXIV_API_URL, seriesId)
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+	reqArgs := &request.RequestArgs{
+		Url:         url,
+		Method:      "GET",
+		Cookies:     dlOptions.SessionCookies,
+		Headers:     pixivcommon.GetPixivRequestHeaders(),
+		Params:      map[string]string{},
+		CheckStatus: true,
+		UserAgent:   dlOptions.Configs.UserAgent,
+		Http2:       !useHttp3,
+		Http3:       useHttp3,
+	}
+
+	type seriesWork struct {
+		workId string
+		order  int
+	}
+	var works []seriesWork
+	for page := 1; ; page++ {
+		reqArgs.Params["p"] = strconv.Itoa(page)
+		res, err := request.CallRequest(reqArgs)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"pixiv error %d: failed to get works of series ID %s due to %v",
+				utils.CONNECTION_ERROR,
+				seriesId,
+				err,
+			)
+		}
+
+		var jsonBody models.PixivWebSeriesJson
+		if err := utils.LoadJsonFromResponse(res, &jsonBody); err != nil {
+			return nil, err
+		}
+
+		seriesPage := jsonBody.Body.Page
+		for _, work := range seriesPage.Series {
+			works = append(works, seriesWork{workId: work.WorkId, order: work.Order})
+		}
+		if len(seriesPage.Series) == 0 || len(works) >= seriesPage.Total {
+			break
+		}
+		pixivSleep()
+	}
+
+	sort.SliceStable(works, func(i, j int) bool {
+		return works[i].order < works[j].order
+	})
+	workIds := make([]string, 0, len(works))
+	for _, work := range works {
+		workIds = append(workIds, work.workId)
+	}
+	return workIds, nil
+}
+
+// Retrieves the details of all the works in the series in order and returns a slice
+// of artworks to download, with the filenames prefixed by the work's index in the series,
+// and a slice of Ugoira structures.
+func getSeriesPosts(seriesId, downloadPath string, dlOptions *PixivWebDlOptions) ([]*request.ToDownload, []*models.Ugoira, []error) {
+	workIds, err := getSeriesWorkIds(seriesId, dlOptions)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	var errSlice []error
+	var ugoiraSlice []*models.Ugoira
+	var artworksToDl []*request.ToDownload
+	for idx, workId := range workIds {
+		pixivSleep()
+		artworks, ugoiraInfo, err := getArtworkDetails(workId, downloadPath, dlOptions)
+		if err != nil {
+			errSlice = append(errSlice, err)
+			continue
+		}
+
+		if ugoiraInfo != nil {
+			ugoiraInfo.SeriesIdx = idx + 1
+			ugoiraSlice = append(ugoiraSlice, ugoiraInfo)
+			continue
+		}
+		pixivcommon.AddSeriesIndexToFilenames(artworks, idx+1)
+		artworksToDl = append(artworksToDl, artworks...)
+	}
+	return artworksToDl, ugoiraSlice, errSlice
+}
+
+// Get the works from multiple series and returns a slice of artworks to download and a slice of Ugoira structures
+func GetMultipleSeriesPosts(seriesIds []string, downloadPath string, dlOptions *PixivWebDlOptions) ([]*request.ToDownload, []*models.Ugoira) {
+	var errSlice []error
+	var ugoiraSlice []*models.Ugoira
+	var artworksToDl []*request.ToDownload
+	seriesIdsLen := len(seriesIds)
+
+	baseMsg := "Getting works from series on Pixiv [%d/" + fmt.Sprintf("%d]...", seriesIdsLen)
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		fmt.Sprintf(
+			baseMsg,
+			0,
+		),
+		fmt.Sprintf(
+			"Finished getting works from %d series on Pixiv!",
+			seriesIdsLen,
+		),
+		fmt.Sprintf(
+			"Something went wrong while getting works from %d series on Pixiv!\nPlease refer to the logs for more details.",
+			seriesIdsLen,
+		),
+		seriesIdsLen,
+	)
+	progress.Start()
+	for _, seriesId := range seriesIds {
+		artworks, ugoira, errS := getSeriesPosts(seriesId, downloadPath, dlOptions)
+		errSlice = append(errSlice, errS...)
+		artworksToDl = append(artworksToDl, artworks...)
+		ugoiraSlice = append(ugoiraSlice, ugoira...)
+		progress.MsgIncrement(baseMsg)
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+
+	return artworksToDl, ugoiraSlice
+}
+
 type pageNumArgs struct {
 	minPage int
 	maxPage int
@@ -414,3 +621,94 @@ func TagSearch(tagName, downloadPath, pageNum string, dlOptions *PixivWebDlOptio
 	)
 	return artworkSlice, ugoiraSlice, hasErr
 }
+
+// Number of followed users returned per request by Pixiv's API
+const followingPerPage = 24
+
+// Returns the user ID of the logged in user from the "PHPSESSID"
+// session cookie which has the format of "<userId>_<random string>"
+func getSelfUserId(dlOptions *PixivWebDlOptions) (string, error) {
+	sessionCookieName := utils.GetSessionCookieInfo(utils.PIXIV).Name
+	for _, cookie := range dlOptions.SessionCookies {
+		if cookie.Name != sessionCookieName {
+			continue
+		}
+		if userId, _, ok := strings.Cut(cookie.Value, "_"); ok && utils.NUMBER_REGEX.MatchString(userId) {
+			return userId, nil
+		}
+	}
+	return "", fmt.Errorf(
+		"pixiv error %d: unable to get your user ID from the session cookie, please check if your session cookie is valid",
+		utils.INPUT_ERROR,
+	)
+}
+
+// Query Pixiv's API for the IDs of the illustrators that the user is following
+// publicly ("show") or privately ("hide") based on the given rest parameter
+func getFollowingLogic(userId, rest string, dlOptions *PixivWebDlOptions) ([]string, error) {
+	headers := pixivcommon.GetPixivRequestHeaders()
+	headers["Referer"] = pixivcommon.GetUserUrl(userId)
+	url := fmt.Sprintf("%s/user/%s/following", utils.PIXIV_API_URL, userId)
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV, true)
+
+	var illustratorIds []string
+	for offset := 0; ; offset += followingPerPage {
+		res, err := request.CallRequest(
+			&request.RequestArgs{
+				Url:     url,
+				Method:  "GET",
+				Cookies: dlOptions.SessionCookies,
+				Headers: headers,
+				Params: map[string]string{
+					"offset": strconv.Itoa(offset),
+					"limit":  strconv.Itoa(followingPerPage),
+					"rest":   rest,
+				},
+				UserAgent:   dlOptions.Configs.UserAgent,
+				Http2:       !useHttp3,
+				Http3:       useHttp3,
+				CheckStatus: true,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"pixiv error %d: failed to get followed illustrators from %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				url,
+				err,
+			)
+		}
+
+		var followingJson models.PixivWebFollowingJson
+		if err := utils.LoadJsonFromResponse(res, &followingJson); err != nil {
+			return nil, err
+		}
+		for _, user := range followingJson.Body.Users {
+			illustratorIds = append(illustratorIds, user.UserId)
+		}
+
+		if len(followingJson.Body.Users) == 0 || offset+followingPerPage >= followingJson.Body.Total {
+			break
+		}
+		pixivSleep()
+	}
+	return illustratorIds, nil
+}
+
+// Query Pixiv's API for the IDs of all the illustrators that the user is following
+func GetFollowingIllustratorIds(dlOptions *PixivWebDlOptions) ([]string, error) {
+	userId, err := getSelfUserId(dlOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var illustratorIds []string
+	for _, rest := range []string{"show", "hide"} {
+		followingIds, err := getFollowingLogic(userId, rest, dlOptions)
+		if err != nil {
+			return nil, err
+		}
+		illustratorIds = append(illustratorIds, followingIds...)
+	}
+	return illustratorIds, nil
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
@@ -18,7 +19,16 @@ type PixivWebDlOptions struct {
 	RatingMode  string
 	ArtworkType string
 
-	Configs     *configs.Config
+	// Only artworks with at least this number of bookmarks will be downloaded
+	MinBookmarks int
+
+	// DlFollowing downloads the artworks of all the illustrators that the user is following
+	DlFollowing bool
+
+	// Size of the images to download which can be "original", "large", or "medium"
+	ImageSize string
+
+	Configs *configs.Config
 
 	SessionCookies  []*http.Cookie
 	SessionCookieId string
@@ -104,6 +114,28 @@ func (p *PixivWebDlOptions) ValidateArgs(userAgent string) {
 		},
 	)
 
+	p.ImageSize = strings.ToLower(p.ImageSize)
+	utils.ValidateStrArgs(
+		p.ImageSize,
+		pixivcommon.ACCEPTED_IMAGE_SIZE,
+		[]string{
+			fmt.Sprintf(
+				"pixiv error %d: Image size %s is not allowed",
+				utils.INPUT_ERROR,
+				p.ImageSize,
+			),
+		},
+	)
+
+	if p.MinBookmarks < 0 {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"pixiv error %d: Minimum bookmarks of %d is not allowed",
+			utils.INPUT_ERROR,
+			p.MinBookmarks,
+		)
+	}
+
 	if p.SessionCookieId != "" {
 		p.SessionCookies = []*http.Cookie{
 			api.VerifyAndGetCookie(utils.PIXIV, p.SessionCookieId, userAgent),
@@ -22,5 +22,9 @@ const (
 //
 // More info: https://github.com/Nandaka/PixivUtil2/issues/477
 func pixivSleep() {
+	// every request is already delayed by the "--delay" flag if it was given
+	if utils.HasRequestDelay() {
+		return
+	}
 	time.Sleep(utils.GetRandomTime(0.5, 1.0))
 }
@@ -2,69 +2,119 @@ package pixivweb
 
 import (
 	"net/http"
+	"path/filepath"
+	"sort"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/ugoira"
-	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/common"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
-func processIllustratorPostJson(resJson *models.PixivWebIllustratorJson, pageNum string, pixivDlOptions *PixivWebDlOptions) ([]string, error) {
+// Sorts the artwork IDs from the oldest to the newest artwork if asc is true,
+// otherwise from the newest to the oldest artwork
+func sortArtworkIds(artworkIds []string, asc bool) {
+	// since the IDs are numeric strings without leading zeros,
+	// a longer ID would be a newer artwork.
+	sort.Slice(artworkIds, func(i, j int) bool {
+		older, newer := artworkIds[i], artworkIds[j]
+		if !asc {
+			older, newer = newer, older
+		}
+		if len(older) != len(newer) {
+			return len(older) < len(newer)
+		}
+		return older < newer
+	})
+}
+
+// Returns the artwork IDs of the illustrator's JSON response map sorted from the newest to the oldest artwork
+//
+// Sorting is needed as the order of the keys when iterating over a map is random.
+func getSortedArtworkIds(artworks map[string]interface{}) []string {
+	artworkIds := make([]string, 0, len(artworks))
+	for artworkId := range artworks {
+		artworkIds = append(artworkIds, artworkId)
+	}
+	sortArtworkIds(artworkIds, false)
+	return artworkIds
+}
+
+// Returns the IDs of the artworks of a type in the illustrator's JSON response within the offsets
+// from the newest to the oldest artwork and true if they were cut short by the seen tracker
+func getArtworkIdsInRange(artworks interface{}, minOffset, maxOffset int, hasMax bool, seenTracker *utils.SeenTracker) ([]string, bool) {
+	t, ok := artworks.(map[string]interface{})
+	if !ok {
+		// where there are no posts or has an unknown type
+		return nil, false
+	}
+
+	var artworkIds []string
+	curOffset := 0
+	for _, artworkId := range getSortedArtworkIds(t) {
+		curOffset++
+		if curOffset < minOffset {
+			continue
+		}
+		if hasMax && curOffset > maxOffset {
+			break
+		}
+
+		artworkIds = append(artworkIds, artworkId)
+		if seenTracker.Seen(artworkId) {
+			return artworkIds, true
+		}
+	}
+	return artworkIds, false
+}
+
+// Returns the IDs of the illustrator's artworks within the page range and
+// true if they were cut short by the seen tracker, see configs.Config.NewSeenTracker
+func processIllustratorPostJson(resJson *models.PixivWebIllustratorJson, pageNum string, pixivDlOptions *PixivWebDlOptions) ([]string, bool, error) {
 	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	minOffset, maxOffset := pixivcommon.ConvertPageNumToOffset(minPage, maxPage, utils.PIXIV_PER_PAGE, false)
 
+	// the illustrations and manga are listed separately, hence each has its own seen tracker
+	siteFolderPath := filepath.Join(utils.DOWNLOAD_PATH, utils.PIXIV_TITLE)
 	var artworkIds []string
+	var stopped bool
 	if pixivDlOptions.ArtworkType == "all" || pixivDlOptions.ArtworkType == "illust_and_ugoira" {
-		illusts := resJson.Body.Illusts
-		switch t := illusts.(type) {
-		case map[string]interface{}:
-			curOffset := 0
-			for illustId := range t {
-				curOffset++
-				if curOffset < minOffset {
-					continue
-				}
-				if hasMax && curOffset > maxOffset {
-					break
-				}
-
-				artworkIds = append(artworkIds, illustId)
-			}
-		default: // where there are no posts or has an unknown type
-			break
-		}
+		illustIds, stop := getArtworkIdsInRange(
+			resJson.Body.Illusts,
+			minOffset,
+			maxOffset,
+			hasMax,
+			pixivDlOptions.Configs.NewSeenTracker(siteFolderPath),
+		)
+		artworkIds = append(artworkIds, illustIds...)
+		stopped = stop
 	}
 
 	if pixivDlOptions.ArtworkType == "all" || pixivDlOptions.ArtworkType == "manga" {
-		manga := resJson.Body.Manga
-		switch t := manga.(type) {
-		case map[string]interface{}:
-			curOffset := 0
-			for mangaId := range t {
-				curOffset++
-				if curOffset < minOffset {
-					continue
-				}
-				if hasMax && curOffset > maxOffset {
-					break
-				}
-
-				artworkIds = append(artworkIds, mangaId)
-			}
-		default: // where there are no posts or has an unknown type
-			break
-		}
+		mangaIds, stop := getArtworkIdsInRange(
+			resJson.Body.Manga,
+			minOffset,
+			maxOffset,
+			hasMax,
+			pixivDlOptions.Configs.NewSeenTracker(siteFolderPath),
+		)
+		artworkIds = append(artworkIds, mangaIds...)
+		stopped = stopped || stop
 	}
-	return artworkIds, nil
+
+	// the illustrations and manga are sorted together so that
+	// the order applies across both instead of to each separately
+	sortArtworkIds(artworkIds, pixivDlOptions.Configs.IsAscOrder())
+	return artworkIds, stopped, nil
 }
 
 // Process the artwork details JSON and returns a map of urls
 // with its file path or a Ugoira struct (One of them will be null depending on the artworkType)
-func processArtworkJson(res *http.Response, artworkType int64, postDownloadDir string) ([]*request.ToDownload, *models.Ugoira, error) {
+func processArtworkJson(res *http.Response, artworkType int64, postDownloadDir, imageSize string) ([]*request.ToDownload, *models.Ugoira, error) {
 	if artworkType == UGOIRA {
 		var ugoiraJson models.PixivWebArtworkUgoiraJson
 		if err := utils.LoadJsonFromResponse(res, &ugoiraJson); err != nil {
@@ -89,7 +139,12 @@ func processArtworkJson(res *http.Response, artworkType int64, postDownloadDir s
 	var urlsToDownload []*request.ToDownload
 	for _, artworkUrl := range artworkUrls.Body {
 		urlsToDownload = append(urlsToDownload, &request.ToDownload{
-			Url:      artworkUrl.Urls.Original,
+			Url: pixivcommon.GetImageUrl(
+				imageSize,
+				artworkUrl.Urls.Original,
+				artworkUrl.Urls.Regular,
+				artworkUrl.Urls.Small,
+			),
 			FilePath: postDownloadDir,
 		})
 	}
@@ -3,9 +3,13 @@ package pixivfanbox
 import (
 	"fmt"
 	"net/http"
-	"sync"
+	"path/filepath"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/pipeline"
+	"github.com/KJHJason/Cultured-Downloader-CLI/postdb"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
@@ -22,49 +26,27 @@ func GetPixivFanboxHeaders() map[string]string {
 // Query Pixiv Fanbox's API based on the slice of post IDs and
 // returns a map of urls and a map of GDrive urls to download from.
 func (pf *PixivFanboxDl) getPostDetails(dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
-	maxConcurrency := utils.MAX_API_CALLS
 	postIdsLen := len(pf.PostIds)
-	if postIdsLen < maxConcurrency {
-		maxConcurrency = postIdsLen
-	}
-	var wg sync.WaitGroup
-	queue := make(chan struct{}, maxConcurrency)
-	resChan := make(chan *http.Response, postIdsLen)
-	errChan := make(chan error, postIdsLen)
-
-	baseMsg := "Getting post details from Pixiv Fanbox [%d/" + fmt.Sprintf("%d]...", postIdsLen)
-	progress := spinner.New(
-		spinner.REQ_SPINNER,
-		"fgHiYellow",
-		fmt.Sprintf(
-			baseMsg,
-			0,
-		),
-		fmt.Sprintf(
-			"Finished getting %d post details from Pixiv Fanbox!",
-			postIdsLen,
-		),
-		fmt.Sprintf(
-			"Something went wrong while getting %d post details from Pixiv Fanbox.\nPlease refer to the logs for more details.",
-			postIdsLen,
-		),
-		postIdsLen,
-	)
-	progress.Start()
-
 	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_FANBOX, true)
 	url := fmt.Sprintf("%s/post.info", utils.PIXIV_FANBOX_API_URL)
-	for _, postId := range pf.PostIds {
-		wg.Add(1)
-		go func(postId string) {
-			defer func() {
-				wg.Done()
-				<-queue
-			}()
-
-			queue <- struct{}{}
+	responses, _ := pipeline.Run(&pipeline.Options[*http.Response]{
+		Count:          postIdsLen,
+		MaxConcurrency: utils.MAX_API_CALLS,
+		Progress: &pipeline.Progress{
+			SpinnerType: spinner.REQ_SPINNER,
+			Msg:         i18n.T("Getting post details from Pixiv Fanbox"),
+			SuccessMsg: i18n.Sprintf(
+				"Finished getting %d post details from Pixiv Fanbox!",
+				postIdsLen,
+			),
+			ErrMsg: i18n.Sprintf(
+				"Something went wrong while getting %d post details from Pixiv Fanbox.\nPlease refer to the logs for more details.",
+				postIdsLen,
+			),
+		},
+		Task: func(idx int) (*http.Response, string, error) {
 			header := GetPixivFanboxHeaders()
-			params := map[string]string{"postId": postId}
+			params := map[string]string{"postId": pf.PostIds[idx]}
 			res, err := request.CallRequest(
 				&request.RequestArgs{
 					Method:    "GET",
@@ -78,37 +60,28 @@ func (pf *PixivFanboxDl) getPostDetails(dlOptions *PixivFanboxDlOptions) ([]*req
 				},
 			)
 			if err != nil {
-				errChan <- fmt.Errorf(
+				return nil, "", fmt.Errorf(
 					"pixiv fanbox error %d: failed to get post details for %s, more info => %v",
 					utils.CONNECTION_ERROR,
 					url,
 					err,
 				)
 			} else if res.StatusCode != 200 {
-				errChan <- fmt.Errorf(
+				res.Body.Close()
+				if res.StatusCode == http.StatusNotFound {
+					events.PostNotFound(utils.PIXIV_FANBOX, pf.PostIds[idx])
+				}
+				return nil, "", fmt.Errorf(
 					"pixiv fanbox error %d: failed to get post details for %s due to a %s response",
 					utils.CONNECTION_ERROR,
 					url,
 					res.Status,
 				)
-			} else {
-				resChan <- res
 			}
-			progress.MsgIncrement(baseMsg)
-		}(postId)
-	}
-	wg.Wait()
-	close(queue)
-	close(resChan)
-	close(errChan)
-
-	hasErr := false
-	if len(errChan) > 0 {
-		hasErr = true
-		utils.LogErrors(false, errChan, utils.ERROR)
-	}
-	progress.Stop(hasErr)
-	return processMultiplePostJson(resChan, dlOptions)
+			return res, "", nil
+		},
+	})
+	return processMultiplePostJson(responses, dlOptions)
 }
 
 func getCreatorPaginatedPosts(creatorId string, dlOptions *PixivFanboxDlOptions) ([]string, error) {
@@ -161,103 +134,161 @@ func getCreatorPaginatedPosts(creatorId string, dlOptions *PixivFanboxDlOptions)
 	return resJson.Body, nil
 }
 
-type resStruct struct {
-	json *models.FanboxCreatorPostsJson
-	err  error
+// Returns the page of the creator's posts at the paginated URL
+func getFanboxPostsPage(reqUrl string, dlOptions *PixivFanboxDlOptions) (*models.FanboxCreatorPostsJson, error) {
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_FANBOX, true)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Method:    "GET",
+			Url:       reqUrl,
+			Cookies:   dlOptions.SessionCookies,
+			Headers:   GetPixivFanboxHeaders(),
+			UserAgent: dlOptions.Configs.UserAgent,
+			Http2:     !useHttp3,
+			Http3:     useHttp3,
+		},
+	)
+	if err != nil || res.StatusCode != 200 {
+		if err == nil {
+			res.Body.Close()
+			err = fmt.Errorf(
+				"pixiv fanbox error %d: failed to get post for %s due to a %s response",
+				utils.CONNECTION_ERROR,
+				reqUrl,
+				res.Status,
+			)
+		} else {
+			err = fmt.Errorf(
+				"pixiv fanbox error %d: failed to get post for %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				reqUrl,
+				err,
+			)
+		}
+		return nil, err
+	}
+
+	var resJson *models.FanboxCreatorPostsJson
+	if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
+		return nil, err
+	}
+	return resJson, nil
 }
 
 // GetFanboxCreatorPosts returns a slice of post IDs for a given creator
-func getFanboxPosts(creatorId, pageNum string, dlOptions *PixivFanboxDlOptions) ([]string, error) {
+//
+// Returns true if all of the creator's posts were listed, i.e. the pages were not limited,
+// all of them were retrieved, and the pagination was not stopped early.
+func getFanboxPosts(creatorId, pageNum string, dlOptions *PixivFanboxDlOptions) ([]string, bool, error) {
 	paginatedUrls, err := getCreatorPaginatedPosts(creatorId, dlOptions)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	minPage, maxPage, hasMax, err := utils.GetMinMaxFromStr(pageNum)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
+	isFullListing := minPage <= 1 && !hasMax
 
-	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_FANBOX, true)
-	headers := GetPixivFanboxHeaders()
-	var wg sync.WaitGroup
-	maxConcurrency := utils.MAX_API_CALLS
-	if len(paginatedUrls) < maxConcurrency {
-		maxConcurrency = len(paginatedUrls)
+	// only get the pages within the given page range
+	startIdx := minPage - 1
+	if startIdx > len(paginatedUrls) {
+		startIdx = len(paginatedUrls)
+	}
+	paginatedUrls = paginatedUrls[startIdx:]
+	if hasMax && maxPage-startIdx < len(paginatedUrls) {
+		paginatedUrls = paginatedUrls[:maxPage-startIdx]
 	}
-	queue := make(chan struct{}, maxConcurrency)
-	resChan := make(chan *resStruct, len(paginatedUrls))
-	for idx, paginatedUrl := range paginatedUrls {
-		curPage := idx + 1
-		if curPage < minPage {
-			continue
-		}
-		if hasMax && curPage > maxPage {
-			break
-		}
 
-		wg.Add(1)
-		go func(reqUrl string) {
-			defer func() {
-				wg.Done()
-				<-queue
-			}()
-			queue <- struct{}{}
-			fmt.Println(reqUrl, headers)
-			res, err := request.CallRequest(
-				&request.RequestArgs{
-					Method:    "GET",
-					Url:       reqUrl,
-					Cookies:   dlOptions.SessionCookies,
-					Headers:   headers,
-					UserAgent: dlOptions.Configs.UserAgent,
-					Http2:     !useHttp3,
-					Http3:     useHttp3,
-				},
-			)
-			if err != nil || res.StatusCode != 200 {
-				if err == nil {
-					res.Body.Close()
-				}
-				utils.LogError(
-					err,
-					fmt.Sprintf("failed to get post for %s", reqUrl),
-					false,
-					utils.ERROR,
-				)
-				return
+	// the pages are retrieved one by one when the pagination can be stopped early by the
+	// seen tracker so that the pages after the already downloaded posts are not requested
+	var postIds []string
+	var pageErrs []error
+	seenTracker := dlOptions.Configs.NewSeenTracker(
+		filepath.Join(utils.DOWNLOAD_PATH, "Pixiv-Fanbox"),
+	)
+	if seenTracker != nil {
+		for _, reqUrl := range paginatedUrls {
+			resJson, err := getFanboxPostsPage(reqUrl, dlOptions)
+			if err != nil {
+				pageErrs = append(pageErrs, err)
+				continue
 			}
 
-			var resJson *models.FanboxCreatorPostsJson
-			if err := utils.LoadJsonFromResponse(res, &resJson); err != nil {
-				resChan <- &resStruct{err: err}
-			} else {
-				resChan <- &resStruct{json: resJson}
+			pagePostIds := make([]string, len(resJson.Body.Items))
+			for itemIdx, postInfoMap := range resJson.Body.Items {
+				pagePostIds[itemIdx] = postInfoMap.Id
+			}
+			numOfPosts, stop := seenTracker.SeenMultiple(pagePostIds)
+			postIds = append(postIds, pagePostIds[:numOfPosts]...)
+			if stop {
+				isFullListing = false
+				break
 			}
-		}(paginatedUrl)
+		}
+		if len(pageErrs) > 0 {
+			utils.LogErrors(false, nil, utils.ERROR, pageErrs...)
+		}
+	} else {
+		var resJsons []*models.FanboxCreatorPostsJson
+		resJsons, pageErrs = pipeline.Run(&pipeline.Options[*models.FanboxCreatorPostsJson]{
+			Count:          len(paginatedUrls),
+			MaxConcurrency: utils.MAX_API_CALLS,
+			Task: func(idx int) (*models.FanboxCreatorPostsJson, string, error) {
+				resJson, err := getFanboxPostsPage(paginatedUrls[idx], dlOptions)
+				return resJson, "", err
+			},
+		})
+		for _, resJson := range resJsons {
+			for _, postInfoMap := range resJson.Body.Items {
+				postIds = append(postIds, postInfoMap.Id)
+			}
+		}
 	}
-	wg.Wait()
-	close(queue)
-	close(resChan)
+	isFullListing = isFullListing && len(pageErrs) == 0
 
-	// parse the JSON response
-	var errSlice []error
-	var postIds []string
-	for res := range resChan {
-		if res.err != nil {
-			errSlice = append(errSlice, res.err)
+	if dlOptions.Configs.IsAscOrder() {
+		utils.ReverseSlice(postIds)
+	}
+	return postIds, isFullListing, nil
+}
+
+// Requests the details of the recorded posts of the creator that are not in its full listing
+// so that the posts that have been deleted are flagged, as their details are not requested otherwise
+func probeUnlistedPosts(creatorId string, listedPostIds []string, dlOptions *PixivFanboxDlOptions) {
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_FANBOX, true)
+	url := fmt.Sprintf("%s/post.info", utils.PIXIV_FANBOX_API_URL)
+	for _, postId := range postdb.GetUnlistedPostIds(utils.PIXIV_FANBOX, creatorId, listedPostIds) {
+		res, err := request.CallRequest(
+			&request.RequestArgs{
+				Method:    "GET",
+				Url:       url,
+				Cookies:   dlOptions.SessionCookies,
+				Headers:   GetPixivFanboxHeaders(),
+				Params:    map[string]string{"postId": postId},
+				UserAgent: dlOptions.Configs.UserAgent,
+				Http2:     !useHttp3,
+				Http3:     useHttp3,
+			},
+		)
+		if err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
 			continue
 		}
-
-		for _, postInfoMap := range res.json.Body.Items {
-			postIds = append(postIds, postInfoMap.Id)
+		res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			events.PostNotFound(utils.PIXIV_FANBOX, postId)
 		}
 	}
+}
 
-	if len(errSlice) > 0 {
-		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
-	}
-	return postIds, nil
+// Returns the IDs of all the current posts of the creator, e.g. to audit the downloaded posts
+//
+// The options' Configs should not stop the pagination early, i.e. its StopAfterSeen should be 0.
+func GetCreatorPostIds(creatorId string, dlOptions *PixivFanboxDlOptions) ([]string, error) {
+	postIds, _, err := getFanboxPosts(creatorId, "", dlOptions)
+	return postIds, err
 }
 
 // Retrieves all the posts based on the slice of creator IDs and updates its slice of post IDs accordingly
@@ -293,7 +324,7 @@ func (pf *PixivFanboxDl) getCreatorsPosts(dlOptions *PixivFanboxDlOptions) {
 	)
 	progress.Start()
 	for idx, creatorId := range pf.CreatorIds {
-		retrievedPostIds, err := getFanboxPosts(
+		retrievedPostIds, isFullListing, err := getFanboxPosts(
 			creatorId,
 			pf.CreatorPageNums[idx],
 			dlOptions,
@@ -302,6 +333,9 @@ func (pf *PixivFanboxDl) getCreatorsPosts(dlOptions *PixivFanboxDlOptions) {
 			errSlice = append(errSlice, err)
 		} else {
 			pf.PostIds = append(pf.PostIds, retrievedPostIds...)
+			if isFullListing {
+				probeUnlistedPosts(creatorId, retrievedPostIds, dlOptions)
+			}
 		}
 		progress.MsgIncrement(baseMsg)
 	}
@@ -314,3 +348,89 @@ func (pf *PixivFanboxDl) getCreatorsPosts(dlOptions *PixivFanboxDlOptions) {
 	progress.Stop(hasErr)
 	pf.PostIds = utils.RemoveSliceDuplicates(pf.PostIds)
 }
+
+// Returns the IDs of the creators returned by the given Pixiv Fanbox API endpoint
+// that lists the creators related to the user, e.g. "plan.listSupporting"
+func getCreatorIdsFromList(endpoint string, dlOptions *PixivFanboxDlOptions) ([]string, error) {
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_FANBOX, true)
+	url := fmt.Sprintf("%s/%s", utils.PIXIV_FANBOX_API_URL, endpoint)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Method:      "GET",
+			Url:         url,
+			Cookies:     dlOptions.SessionCookies,
+			Headers:     GetPixivFanboxHeaders(),
+			UserAgent:   dlOptions.Configs.UserAgent,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"pixiv fanbox error %d: failed to get creators from %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			url,
+			err,
+		)
+	}
+
+	var creatorListJson models.FanboxCreatorListJson
+	if err := utils.LoadJsonFromResponse(res, &creatorListJson); err != nil {
+		return nil, err
+	}
+
+	creatorIds := make([]string, 0, len(creatorListJson.Body))
+	for _, creator := range creatorListJson.Body {
+		if creator.CreatorId != "" {
+			creatorIds = append(creatorIds, creator.CreatorId)
+		}
+	}
+	return creatorIds, nil
+}
+
+// Adds the creators that the user is supporting or following
+// to the slice of creator IDs to download all of their posts
+func (pf *PixivFanboxDl) addFollowedCreators(dlOptions *PixivFanboxDlOptions) {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting supported and followed creators from Pixiv Fanbox...",
+		"Finished getting supported and followed creators from Pixiv Fanbox!",
+		"Something went wrong while getting supported and followed creators from Pixiv Fanbox.\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+
+	var errSlice []error
+	var followedIds []string
+	for _, endpoint := range []string{"plan.listSupporting", "creator.listFollowing"} {
+		creatorIds, err := getCreatorIdsFromList(endpoint, dlOptions)
+		if err != nil {
+			errSlice = append(errSlice, err)
+			continue
+		}
+		followedIds = append(followedIds, creatorIds...)
+	}
+
+	// creators that were already given will keep their page numbers
+	existingIds := make(map[string]struct{}, len(pf.CreatorIds))
+	for _, creatorId := range pf.CreatorIds {
+		existingIds[creatorId] = struct{}{}
+	}
+	for _, creatorId := range followedIds {
+		if _, ok := existingIds[creatorId]; ok {
+			continue
+		}
+		existingIds[creatorId] = struct{}{}
+		pf.CreatorIds = append(pf.CreatorIds, creatorId)
+		pf.CreatorPageNums = append(pf.CreatorPageNums, "")
+	}
+
+	hasErr := false
+	if len(errSlice) > 0 {
+		hasErr = true
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	progress.Stop(hasErr)
+}
@@ -2,14 +2,12 @@ package pixivfanbox
 
 import (
 	"net/http"
-	"os"
 	"regexp"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/fatih/color"
 )
 
 // PixivFanboxDl is the struct that contains the IDs of the Pixiv Fanbox creators and posts to download.
@@ -33,12 +31,12 @@ func (pf *PixivFanboxDl) ValidateArgs() {
 
 	for _, creatorId := range pf.CreatorIds {
 		if !creatorIdRegex.MatchString(creatorId) {
-			color.Red(
+			utils.ExitWithErrorf(
+				utils.EXIT_INPUT_ERROR,
 				"error %d: invalid Pixiv Fanbox creator ID %q, must be alphanumeric with underscores, dashes, or periods",
 				utils.INPUT_ERROR,
 				creatorId,
 			)
-			os.Exit(1)
 		}
 	}
 
@@ -66,7 +64,14 @@ type PixivFanboxDlOptions struct {
 	DlAttachments bool
 	DlGdrive      bool
 
-	Configs       *configs.Config
+	// Markdown writes the article posts as Markdown with the images linked to the downloaded files
+	Markdown bool
+
+	// DlFollowing downloads from all the creators
+	// that the user is supporting or following
+	DlFollowing bool
+
+	Configs *configs.Config
 
 	// GdriveClient is the Google Drive client to be
 	// used in the download process for Pixiv Fanbox posts
@@ -85,6 +90,13 @@ func (pf *PixivFanboxDlOptions) ValidateArgs(userAgent string) {
 			api.VerifyAndGetCookie(utils.PIXIV_FANBOX, pf.SessionCookieId, userAgent),
 		}
 	}
+	if pf.DlFollowing && len(pf.SessionCookies) == 0 {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"pixiv fanbox error %d: a session cookie is required to download from the creators you are supporting or following",
+			utils.INPUT_ERROR,
+		)
+	}
 
 	if pf.DlGdrive && pf.GdriveClient == nil {
 		pf.DlGdrive = false
@@ -0,0 +1,124 @@
+package pixivfanbox
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const LOCKED_POSTS_FILENAME = "locked_posts.csv"
+
+var lockedPostsCsvHeader = []string{"post_id", "creator_id", "title", "fee_required_jpy", "url", "recorded_at"}
+
+// lockedPost contains the details of a Pixiv Fanbox post
+// that is above the user's plan and hence, could not be downloaded.
+type lockedPost struct {
+	postId      string
+	creatorId   string
+	title       string
+	feeRequired int
+}
+
+func (p *lockedPost) toCsvRecord(recordedAt string) []string {
+	return []string{
+		p.postId,
+		p.creatorId,
+		p.title,
+		strconv.Itoa(p.feeRequired),
+		fmt.Sprintf("%s/@%s/posts/%s", utils.PIXIV_FANBOX_URL, p.creatorId, p.postId),
+		recordedAt,
+	}
+}
+
+// Returns the post IDs of the locked posts that have already been recorded in the report
+func getRecordedLockedPosts(reportPath string) (map[string]struct{}, error) {
+	recorded := make(map[string]struct{})
+	f, err := os.Open(reportPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return recorded, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) > 0 && record[0] != lockedPostsCsvHeader[0] {
+			recorded[record[0]] = struct{}{}
+		}
+	}
+	return recorded, nil
+}
+
+// Records the locked posts in the locked_posts.csv report in the Pixiv Fanbox download folder
+// so that the user can see which posts are above their plan and the fee required to view them.
+//
+// Posts that have already been recorded in previous runs will not be duplicated.
+func writeLockedPostsReport(lockedPosts []*lockedPost, downloadPath string) error {
+	if len(lockedPosts) == 0 {
+		return nil
+	}
+
+	reportDir := filepath.Join(downloadPath, "Pixiv-Fanbox")
+	reportPath := filepath.Join(reportDir, LOCKED_POSTS_FILENAME)
+	recorded, err := getRecordedLockedPosts(reportPath)
+	if err != nil {
+		return fmt.Errorf(
+			"pixiv fanbox error %d: failed to read %s, more info => %v",
+			utils.OS_ERROR,
+			reportPath,
+			err,
+		)
+	}
+
+	os.MkdirAll(reportDir, 0666)
+	f, err := os.OpenFile(reportPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf(
+			"pixiv fanbox error %d: failed to open %s, more info => %v",
+			utils.OS_ERROR,
+			reportPath,
+			err,
+		)
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	if stat, err := f.Stat(); err == nil && stat.Size() == 0 {
+		writer.Write(lockedPostsCsvHeader)
+	}
+
+	recordedAt := time.Now().Format(time.RFC3339)
+	for _, post := range lockedPosts {
+		if _, ok := recorded[post.postId]; ok {
+			continue
+		}
+		recorded[post.postId] = struct{}{}
+		writer.Write(post.toCsvRecord(recordedAt))
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf(
+			"pixiv fanbox error %d: failed to write to %s, more info => %v",
+			utils.OS_ERROR,
+			reportPath,
+			err,
+		)
+	}
+	return nil
+}
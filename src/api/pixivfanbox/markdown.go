@@ -0,0 +1,160 @@
+package pixivfanbox
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const ARTICLE_MARKDOWN_FILENAME = "post.md"
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"#", `\#`,
+)
+
+// Returns the filename that the file at the URL is downloaded as by
+// request.DownloadUrl when the file path is a folder, e.g. "abc.jpeg" for ".../abc.JPEG"
+func getDownloadedFilename(url string) string {
+	filename := utils.GetLastPartOfUrl(url)
+	return utils.RemoveExtFromFilename(filename) + strings.ToLower(filepath.Ext(filename))
+}
+
+// Returns the text of the article block in Markdown with its bold styles and links
+//
+// The offsets of the styles and links are in UTF-16 code units as they are from JavaScript strings.
+func getMarkdownText(text string, boldRanges, linkRanges [][2]int, linkUrls []string) string {
+	// the Markdown to insert before the rune at the index
+	inserts := make(map[int][]string)
+	runeIdxes := make(map[int]int) // UTF-16 offset => rune index
+	offset := 0
+	runes := []rune(text)
+	for idx, r := range runes {
+		runeIdxes[offset] = idx
+		offset += len(utf16.Encode([]rune{r}))
+	}
+	runeIdxes[offset] = len(runes)
+
+	addRange := func(start, length int, open, close string) {
+		startIdx, ok := runeIdxes[start]
+		endIdx, ok2 := runeIdxes[start+length]
+		if !ok || !ok2 || startIdx >= endIdx {
+			return
+		}
+		inserts[startIdx] = append(inserts[startIdx], open)
+		inserts[endIdx] = append([]string{close}, inserts[endIdx]...)
+	}
+	for _, boldRange := range boldRanges {
+		addRange(boldRange[0], boldRange[1], "**", "**")
+	}
+	for idx, linkRange := range linkRanges {
+		addRange(linkRange[0], linkRange[1], "[", fmt.Sprintf("](<%s>)", linkUrls[idx]))
+	}
+
+	var markdown strings.Builder
+	for idx, r := range runes {
+		markdown.WriteString(strings.Join(inserts[idx], ""))
+		markdown.WriteString(markdownEscaper.Replace(string(r)))
+	}
+	markdown.WriteString(strings.Join(inserts[len(runes)], ""))
+	return markdown.String()
+}
+
+// Converts the blocks of the article post to Markdown where the images and attachments
+// are linked to the downloaded files in the post folder, or to their URLs if they are not downloaded
+func getArticleMarkdown(postTitle string, articleJson *models.FanboxArticleJson, dlOptions *PixivFanboxDlOptions) string {
+	blocks := []string{"# " + markdownEscaper.Replace(postTitle)}
+	for _, articleBlock := range articleJson.Blocks {
+		switch articleBlock.Type {
+		case "p", "header":
+			if articleBlock.Text == "" {
+				continue
+			}
+
+			var boldRanges, linkRanges [][2]int
+			var linkUrls []string
+			for _, style := range articleBlock.Styles {
+				if style.Type == "bold" {
+					boldRanges = append(boldRanges, [2]int{style.Offset, style.Length})
+				}
+			}
+			for _, link := range articleBlock.Links {
+				linkRanges = append(linkRanges, [2]int{link.Offset, link.Length})
+				linkUrls = append(linkUrls, link.Url)
+			}
+			text := getMarkdownText(articleBlock.Text, boldRanges, linkRanges, linkUrls)
+			if articleBlock.Type == "header" {
+				text = "## " + text
+			}
+			blocks = append(blocks, strings.ReplaceAll(text, "\n", "  \n"))
+		case "image":
+			imageInfo, ok := articleJson.ImageMap[articleBlock.ImageID]
+			if !ok {
+				continue
+			}
+			imagePath := imageInfo.OriginalUrl
+			if dlOptions.DlImages {
+				imagePath = path.Join(utils.IMAGES_FOLDER, getDownloadedFilename(imageInfo.OriginalUrl))
+			}
+			blocks = append(blocks, fmt.Sprintf("![](<%s>)", imagePath))
+		case "file":
+			fileInfo, ok := articleJson.FileMap[articleBlock.FileID]
+			if !ok {
+				continue
+			}
+			filename := fileInfo.Name + "." + strings.ToLower(fileInfo.Extension)
+			filePath := fileInfo.Url
+			if dlOptions.DlAttachments {
+				filePath = path.Join(utils.ATTACHMENT_FOLDER, filename)
+			}
+			blocks = append(blocks, fmt.Sprintf("[%s](<%s>)", markdownEscaper.Replace(filename), filePath))
+		case "url_embed":
+			urlEmbed, ok := articleJson.UrlEmbedMap[articleBlock.UrlEmbedID]
+			if !ok {
+				continue
+			}
+			for _, embedUrl := range getUrlEmbedUrls(&urlEmbed) {
+				blocks = append(blocks, fmt.Sprintf("<%s>", embedUrl))
+			}
+		}
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// Writes the article post as Markdown to the ARTICLE_MARKDOWN_FILENAME file in the post folder
+// so that it can be read offline with the downloaded images in place
+func writeArticleMarkdown(postTitle string, articleJson *models.FanboxArticleJson, postFolderPath string, dlOptions *PixivFanboxDlOptions) {
+	filePath := filepath.Join(postFolderPath, ARTICLE_MARKDOWN_FILENAME)
+	if !dlOptions.Configs.OverwriteFiles && storage.Exists(context.Background(), filePath) {
+		return
+	}
+
+	markdown := getArticleMarkdown(postTitle, articleJson, dlOptions)
+	if err := storage.WriteFile(context.Background(), filePath, []byte(markdown)); err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"pixiv fanbox error %d: failed to write the Markdown of the article to %s, more info => %v",
+				utils.OS_ERROR,
+				filePath,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+}
@@ -21,7 +21,11 @@ type FanboxPostJson struct {
 		Type          string          `json:"type"`
 		CreatorId     string          `json:"creatorId"`
 		CoverImageUrl string          `json:"coverImageUrl"`
+		FeeRequired   int             `json:"feeRequired"`
+		IsRestricted  bool            `json:"isRestricted"`
 		Body          json.RawMessage `json:"body"`
+
+		PublishedDatetime string `json:"publishedDatetime"`
 	} `json:"body"`
 }
 
@@ -66,9 +70,26 @@ type FanboxArticleBlocks []struct {
 		Length int    `json:"length"`
 		Url    string `json:"url"`
 	} `json:"links,omitempty"`
-	FileID string `json:"fileId,omitempty"`
+	FileID     string `json:"fileId,omitempty"`
+	UrlEmbedID string `json:"urlEmbedId,omitempty"`
 } 
 
+// An embedded link in an article post, e.g. a link card to an external site or to another Fanbox post
+type FanboxUrlEmbed struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"` // "default", "html", "html.card", "fanbox.post", or "fanbox.creator"
+	Url      string `json:"url,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Html     string `json:"html,omitempty"`
+	PostInfo struct {
+		ID        string `json:"id"`
+		CreatorId string `json:"creatorId"`
+	} `json:"postInfo"`
+	Profile struct {
+		CreatorId string `json:"creatorId"`
+	} `json:"profile"`
+}
+
 type FanboxArticleJson struct {
 	Blocks FanboxArticleBlocks `json:"blocks"`
 	ImageMap map[string]struct {
@@ -86,4 +107,23 @@ type FanboxArticleJson struct {
 		Size      int    `json:"size"`
 		Url       string `json:"url"`
 	} `json:"fileMap"`
+	UrlEmbedMap map[string]FanboxUrlEmbed `json:"urlEmbedMap"`
+}
+
+// Response of the plan.listSupporting and creator.listFollowing endpoints
+type FanboxCreatorListJson struct {
+	Body []struct {
+		CreatorId string `json:"creatorId"`
+	} `json:"body"`
+}
+
+type FanboxCreatorSearchJson struct {
+	Body struct {
+		Creators []struct {
+			CreatorId string `json:"creatorId"`
+			User      struct {
+				Name string `json:"name"`
+			} `json:"user"`
+		} `json:"creators"`
+	} `json:"body"`
 }
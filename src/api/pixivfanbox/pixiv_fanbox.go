@@ -1,7 +1,11 @@
 package pixivfanbox
 
 import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/filehost"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/twitter"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
@@ -11,6 +15,10 @@ func PixivFanboxDownloadProcess(pixivFanboxDl *PixivFanboxDl, pixivFanboxDlOptio
 		return
 	}
 
+	if pixivFanboxDlOptions.DlFollowing {
+		pixivFanboxDl.addFollowedCreators(pixivFanboxDlOptions)
+	}
+
 	if len(pixivFanboxDl.CreatorIds) > 0 {
 		pixivFanboxDl.getCreatorsPosts(
 			pixivFanboxDlOptions,
@@ -38,14 +46,16 @@ func PixivFanboxDownloadProcess(pixivFanboxDl *PixivFanboxDl, pixivFanboxDlOptio
 			pixivFanboxDlOptions.Configs,
 		)
 	}
-	if pixivFanboxDlOptions.GdriveClient != nil && len(gdriveUrlsToDownload) > 0 {
+	if pixivFanboxDlOptions.GdriveClient != nil && (len(gdriveUrlsToDownload) > 0 || gdrive.HasDueDeferredFiles()) {
 		downloadedPosts = true
 		pixivFanboxDlOptions.GdriveClient.DownloadGdriveUrls(gdriveUrlsToDownload, pixivFanboxDlOptions.Configs)
 	}
+	filehost.DownloadLinks(pixivFanboxDlOptions.Configs)
+	twitter.ProcessLinks(pixivFanboxDlOptions.Configs)
 
 	if downloadedPosts {
-		utils.AlertWithoutErr(utils.Title, "Downloaded all posts from Pixiv Fanbox!")
+		utils.AlertWithoutErr(utils.Title, i18n.T("Downloaded all posts from Pixiv Fanbox!"))
 	} else {
-		utils.AlertWithoutErr(utils.Title, "No posts to download from Pixiv Fanbox!")
+		utils.AlertWithoutErr(utils.Title, i18n.T("No posts to download from Pixiv Fanbox!"))
 	}
 }
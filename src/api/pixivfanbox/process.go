@@ -3,20 +3,32 @@ package pixivfanbox
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/filehost"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/twitter"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
 )
 
 // Pixiv Fanbox permitted file extensions based on
 // https://fanbox.pixiv.help/hc/en-us/articles/360011057793-What-types-of-attachments-can-I-post-
 var pixivFanboxAllowedImageExt = []string{"jpg", "jpeg", "png", "gif"}
 
+var (
+	urlEmbedHtmlRegex    = regexp.MustCompile(`(?i)(?:src|href)=["'](?P<url>https?://[^"']+)["']`)
+	urlEmbedHtmlRegexIdx = urlEmbedHtmlRegex.SubexpIndex("url")
+)
+
 func detectUrlsAndPasswordsInPost(text, postFolderPath string, articleBlocks models.FanboxArticleBlocks, dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, bool) {
 	loggedPassword := false 
 	if utils.DetectPasswordInText(text) {
@@ -40,6 +52,8 @@ func detectUrlsAndPasswordsInPost(text, postFolderPath string, articleBlocks mod
 	}
 
 	var gdriveLinks []*request.ToDownload
+	filehost.DetectLinks(text, postFolderPath)
+	twitter.DetectLinks(text, postFolderPath)
 	if dlOptions.Configs.LogUrls {
 		utils.DetectOtherExtDLLink(text, postFolderPath)
 	}
@@ -52,7 +66,78 @@ func detectUrlsAndPasswordsInPost(text, postFolderPath string, articleBlocks mod
 	return gdriveLinks, loggedPassword
 }
 
-func processFanboxArticlePost(postBody json.RawMessage, postFolderPath string, dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
+// Returns the text of the post where the text of each block of an article is on a new line
+func getFanboxPostText(postType string, postBody json.RawMessage) string {
+	if postType == "article" {
+		var articleJson models.FanboxArticleJson
+		if err := json.Unmarshal(postBody, &articleJson); err != nil {
+			return ""
+		}
+
+		var lines []string
+		for _, articleBlock := range articleJson.Blocks {
+			if articleBlock.Text != "" {
+				lines = append(lines, articleBlock.Text)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	var textContent models.FanboxTextPostJson
+	if err := json.Unmarshal(postBody, &textContent); err != nil {
+		return ""
+	}
+	return textContent.Text
+}
+
+// Detects the file hosting and external links in the URL of a link or an embed in an article post
+//
+// Returns the GDrive link to download if the URL is a GDrive link, otherwise nil.
+func detectLinkUrl(linkUrl, postFolderPath string, dlOptions *PixivFanboxDlOptions) *request.ToDownload {
+	filehost.DetectLinks(linkUrl, postFolderPath)
+	twitter.DetectLinks(linkUrl, postFolderPath)
+	utils.DetectOtherExtDLLink(linkUrl, postFolderPath)
+	if utils.DetectGDriveLinks(linkUrl, postFolderPath, true, dlOptions.Configs.LogUrls) && dlOptions.DlGdrive {
+		return &request.ToDownload{
+			Url:      linkUrl,
+			FilePath: filepath.Join(postFolderPath, utils.GDRIVE_FOLDER),
+		}
+	}
+	return nil
+}
+
+// Returns the URLs in the url_embed block of an article post
+//
+// The "html" and "html.card" embeds only have the embedded HTML, e.g. an iframe,
+// so the URLs are taken from its src and href attributes.
+func getUrlEmbedUrls(urlEmbed *models.FanboxUrlEmbed) []string {
+	switch urlEmbed.Type {
+	case "default":
+		if urlEmbed.Url != "" {
+			return []string{urlEmbed.Url}
+		}
+	case "html", "html.card":
+		var urls []string
+		for _, matched := range urlEmbedHtmlRegex.FindAllStringSubmatch(urlEmbed.Html, -1) {
+			embedUrl := html.UnescapeString(matched[urlEmbedHtmlRegexIdx])
+			if !utils.SliceContains(urls, embedUrl) {
+				urls = append(urls, embedUrl)
+			}
+		}
+		return urls
+	case "fanbox.post":
+		if urlEmbed.PostInfo.ID != "" {
+			return []string{fmt.Sprintf("https://www.fanbox.cc/@%s/posts/%s", urlEmbed.PostInfo.CreatorId, urlEmbed.PostInfo.ID)}
+		}
+	case "fanbox.creator":
+		if urlEmbed.Profile.CreatorId != "" {
+			return []string{fmt.Sprintf("https://www.fanbox.cc/@%s", urlEmbed.Profile.CreatorId)}
+		}
+	}
+	return nil
+}
+
+func processFanboxArticlePost(postBody json.RawMessage, postTitle, postFolderPath string, dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
 	var articleJson models.FanboxArticleJson
 	if err := utils.LoadJsonFromBytes(postBody, &articleJson); err != nil {
 		return nil, nil, err
@@ -87,6 +172,18 @@ func processFanboxArticlePost(postBody json.RawMessage, postFolderPath string, d
 	if len(articleBlocks) == 0 {
 		return urlsSlice, gdriveLinks, nil
 	}
+	if dlOptions.Markdown {
+		writeArticleMarkdown(postTitle, &articleJson, postFolderPath, dlOptions)
+	}
+
+	// the password may be in a different block from its label
+	var articleTexts []string
+	for _, articleBlock := range articleBlocks {
+		if articleBlock.Text != "" {
+			articleTexts = append(articleTexts, articleBlock.Text)
+		}
+	}
+	utils.SavePasswords(utils.ExtractPasswordsFromText(strings.Join(articleTexts, "\n")), postFolderPath)
 
 	loggedPassword := false
 	for _, articleBlock := range articleBlocks {
@@ -105,14 +202,20 @@ func processFanboxArticlePost(postBody json.RawMessage, postFolderPath string, d
 		articleLinks := articleBlock.Links
 		if len(articleLinks) > 0 {
 			for _, articleLink := range articleLinks {
-				linkUrl := articleLink.Url
-				utils.DetectOtherExtDLLink(linkUrl, postFolderPath)
-				if utils.DetectGDriveLinks(linkUrl, postFolderPath, true, dlOptions.Configs.LogUrls) && dlOptions.DlGdrive {
-					gdriveLinks = append(gdriveLinks, &request.ToDownload{
-						Url:      linkUrl,
-						FilePath: filepath.Join(postFolderPath, utils.GDRIVE_FOLDER),
-					})
-					continue
+				if gdriveLink := detectLinkUrl(articleLink.Url, postFolderPath, dlOptions); gdriveLink != nil {
+					gdriveLinks = append(gdriveLinks, gdriveLink)
+				}
+			}
+		}
+
+		if articleBlock.Type == "url_embed" {
+			urlEmbed, ok := articleJson.UrlEmbedMap[articleBlock.UrlEmbedID]
+			if !ok {
+				continue
+			}
+			for _, embedUrl := range getUrlEmbedUrls(&urlEmbed) {
+				if gdriveLink := detectLinkUrl(embedUrl, postFolderPath, dlOptions); gdriveLink != nil {
+					gdriveLinks = append(gdriveLinks, gdriveLink)
 				}
 			}
 		}
@@ -216,21 +319,24 @@ func processFanboxImagePost(postBody json.RawMessage, postFolderPath string, dlO
 
 // Process the JSON response from Pixiv Fanbox's API and
 // returns a map of urls and a map of GDrive urls to download from
-func processFanboxPostJson(res *http.Response, downloadPath string, dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, []*request.ToDownload, error) {
+//
+// If the post is above the user's plan, the returned lockedPost will be non-nil.
+func processFanboxPostJson(res *http.Response, downloadPath string, dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, []*request.ToDownload, *lockedPost, error) {
 	var post models.FanboxPostJson
 	if err := utils.LoadJsonFromResponse(res, &post); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	postJson := post.Body
 	postId := postJson.Id
 	postTitle := postJson.Title
 	creatorId := postJson.CreatorId
-	postFolderPath := utils.GetPostFolder(
+	postFolderPath := dlOptions.Configs.GetPostFolder(
 		filepath.Join(downloadPath, "Pixiv-Fanbox"),
 		creatorId,
 		postId,
 		postTitle,
+		postJson.PublishedDatetime,
 	)
 
 	var urlsSlice []*request.ToDownload
@@ -247,8 +353,18 @@ func processFanboxPostJson(res *http.Response, downloadPath string, dlOptions *P
 	//	2. With a simple formatting that obly contains info about the text and files ("file", "image")
 	postType := postJson.Type
 	postBody := postJson.Body
-	if postBody == nil {
-		return urlsSlice, nil, nil
+	if postBody == nil || string(postBody) == "null" {
+		// the post body is null when the post is above the user's plan
+		var locked *lockedPost
+		if postJson.IsRestricted || postJson.FeeRequired > 0 {
+			locked = &lockedPost{
+				postId:      postId,
+				creatorId:   creatorId,
+				title:       postTitle,
+				feeRequired: postJson.FeeRequired,
+			}
+		}
+		return urlsSlice, nil, locked, nil
 	}
 
 	var err error
@@ -260,7 +376,7 @@ func processFanboxPostJson(res *http.Response, downloadPath string, dlOptions *P
 	case "image":
 		newUrlsSlice, gdriveLinks, err = processFanboxImagePost(postBody, postFolderPath, dlOptions)
 	case "article":
-		newUrlsSlice, gdriveLinks, err = processFanboxArticlePost(postBody, postFolderPath, dlOptions)
+		newUrlsSlice, gdriveLinks, err = processFanboxArticlePost(postBody, postTitle, postFolderPath, dlOptions)
 	case "text": // text post
 		// Usually has no content but try to detect for any external download links
 		var textContent models.FanboxTextPostJson
@@ -274,7 +390,7 @@ func processFanboxPostJson(res *http.Response, downloadPath string, dlOptions *P
 		}
 	default: // unknown post type
 		jsonBytes, _ := json.MarshalIndent(post, "", "\t")
-		return nil, nil, fmt.Errorf(
+		return nil, nil, nil, fmt.Errorf(
 			"pixiv fanbox error %d: unknown post type, %q\nPixiv Fanbox post content:\n%s",
 			utils.JSON_ERROR,
 			postType,
@@ -283,17 +399,36 @@ func processFanboxPostJson(res *http.Response, downloadPath string, dlOptions *P
 	}
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	urlsSlice = append(urlsSlice, newUrlsSlice...)
-	return urlsSlice, gdriveLinks, nil
+	fileFilter := events.GetFileFilter(map[string]bool{
+		"thumbnails":  dlOptions.DlThumbnails,
+		"images":      dlOptions.DlImages,
+		"attachments": dlOptions.DlAttachments,
+		"gdrive":      dlOptions.DlGdrive,
+	})
+	events.PostResolved(&events.Post{
+		Site:        utils.PIXIV_FANBOX,
+		Id:          postId,
+		Title:       postTitle,
+		Creator:     creatorId,
+		CreatorId:   creatorId,
+		Folder:      postFolderPath,
+		FileCount:   len(urlsSlice) + len(gdriveLinks),
+		FileFilter:  fileFilter,
+		PublishedAt: utils.ParsePostDate(postJson.PublishedDatetime),
+		Body:        getFanboxPostText(postType, postBody),
+	})
+	return urlsSlice, gdriveLinks, nil, nil
 }
 
-func processMultiplePostJson(resChan chan *http.Response, dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
+func processMultiplePostJson(responses []*http.Response, dlOptions *PixivFanboxDlOptions) ([]*request.ToDownload, []*request.ToDownload) {
 	// parse the responses
 	var errSlice []error
+	var lockedPosts []*lockedPost
 	var urlsSlice, gdriveUrls []*request.ToDownload
-	baseMsg := "Processing received JSON(s) from Pixiv Fanbox [%d/" + fmt.Sprintf("%d]...", len(resChan))
+	baseMsg := "Processing received JSON(s) from Pixiv Fanbox [%d/" + fmt.Sprintf("%d]...", len(responses))
 	progress := spinner.New(
 		spinner.JSON_SPINNER,
 		"fgHiYellow",
@@ -303,17 +438,17 @@ func processMultiplePostJson(resChan chan *http.Response, dlOptions *PixivFanbox
 		),
 		fmt.Sprintf(
 			"Finished processing %d JSON(s) from Pixiv Fanbox!",
-			len(resChan),
+			len(responses),
 		),
 		fmt.Sprintf(
 			"Something went wrong while processing %d JSON(s) from Pixiv Fanbox.\nPlease refer to the logs for more details.",
-			len(resChan),
+			len(responses),
 		),
-		len(resChan),
+		len(responses),
 	)
 	progress.Start()
-	for res := range resChan {
-		postUrls, postGdriveLinks, err := processFanboxPostJson(
+	for _, res := range responses {
+		postUrls, postGdriveLinks, locked, err := processFanboxPostJson(
 			res,
 			utils.DOWNLOAD_PATH,
 			dlOptions,
@@ -323,15 +458,29 @@ func processMultiplePostJson(resChan chan *http.Response, dlOptions *PixivFanbox
 		} else {
 			urlsSlice = append(urlsSlice, postUrls...)
 			gdriveUrls = append(gdriveUrls, postGdriveLinks...)
+			if locked != nil {
+				lockedPosts = append(lockedPosts, locked)
+			}
 		}
 		progress.MsgIncrement(baseMsg)
 	}
 
+	if err := writeLockedPostsReport(lockedPosts, utils.DOWNLOAD_PATH); err != nil {
+		errSlice = append(errSlice, err)
+	}
+
 	hasErr := false
 	if len(errSlice) > 0 {
 		hasErr = true
 		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
 	}
 	progress.Stop(hasErr)
+	if len(lockedPosts) > 0 {
+		color.Yellow(
+			"\n%d Pixiv Fanbox post(s) are above your plan, refer to %s for more details.",
+			len(lockedPosts),
+			filepath.Join(utils.DOWNLOAD_PATH, "Pixiv-Fanbox", LOCKED_POSTS_FILENAME),
+		)
+	}
 	return urlsSlice, gdriveUrls
 }
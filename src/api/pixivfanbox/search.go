@@ -0,0 +1,54 @@
+package pixivfanbox
+
+import (
+	"fmt"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Returns the creators on the first page of Pixiv Fanbox's creator search results for the query
+func SearchCreators(query, userAgent string) ([]*api.SearchedCreator, error) {
+	useHttp3 := utils.IsHttp3Supported(utils.PIXIV_FANBOX, true)
+	url := fmt.Sprintf("%s/creator.search", utils.PIXIV_FANBOX_API_URL)
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Method:  "GET",
+			Url:     url,
+			Headers: GetPixivFanboxHeaders(),
+			Params: map[string]string{
+				"q":    query,
+				"page": "0",
+			},
+			UserAgent:   userAgent,
+			Http2:       !useHttp3,
+			Http3:       useHttp3,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"pixiv fanbox error %d: failed to search for creators from %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			url,
+			err,
+		)
+	}
+
+	var searchJson models.FanboxCreatorSearchJson
+	if err := utils.LoadJsonFromResponse(res, &searchJson); err != nil {
+		return nil, err
+	}
+
+	var creators []*api.SearchedCreator
+	for _, creator := range searchJson.Body.Creators {
+		creators = append(creators, &api.SearchedCreator{
+			Name:    creator.User.Name,
+			Website: utils.PIXIV_FANBOX,
+			Url:     fmt.Sprintf("%s/@%s", utils.PIXIV_FANBOX_URL, creator.CreatorId),
+		})
+	}
+	return creators, nil
+}
@@ -0,0 +1,12 @@
+package api
+
+// A creator found by searching a website with the search command
+type SearchedCreator struct {
+	Name    string
+	Website string // the website that the creator was found on, e.g. utils.KEMONO
+	Service string // the service of the creator on Kemono Party, e.g. "patreon", or empty for the other websites
+	Url     string // the creator's URL that can be downloaded from
+
+	// Popularity is used to rank creators with the same name, e.g. the number of favourites on Kemono Party
+	Popularity int
+}
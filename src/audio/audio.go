@@ -0,0 +1,172 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// File extensions of the audio attachments that will be processed
+var AUDIO_EXTS = []string{".mp3", ".wav", ".flac"}
+
+// Options of how the downloaded audio attachments are processed using FFmpeg
+type Options struct {
+	// FfmpegPath is the path to the FFmpeg binary
+	FfmpegPath string
+
+	// ConvertWav converts the WAV files to FLAC files which
+	// are lossless but usually around half the size of the WAV files
+	ConvertWav bool
+
+	// WriteTags writes the post title and creator name as the album and artist tags of the audio files
+	WriteTags bool
+}
+
+// Returns true if the audio attachments will be processed
+func (o *Options) IsEnabled() bool {
+	return o.ConvertWav || o.WriteTags
+}
+
+// Returns true if the file is an audio file based on its file extension
+func IsAudioFile(filePath string) bool {
+	return utils.SliceContains(AUDIO_EXTS, strings.ToLower(filepath.Ext(filePath)))
+}
+
+// Handler processes the audio attachments after they have been downloaded,
+// i.e. converting the WAV files to FLAC files and tagging them using the metadata of their post.
+type Handler struct {
+	events.BaseHandler
+
+	options *Options
+
+	mu    sync.RWMutex
+	posts map[string]*events.Post // the resolved posts keyed by their folder
+}
+
+// Returns a new Handler that processes the downloaded audio attachments based on the given options
+func NewHandler(options *Options) *Handler {
+	return &Handler{
+		options: options,
+		posts:   make(map[string]*events.Post),
+	}
+}
+
+func (h *Handler) OnPostResolved(post *events.Post) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.posts[filepath.Clean(post.Folder)] = post
+}
+
+func (h *Handler) OnFileDone(file *events.File, err error) {
+	if err != nil || file.Skipped || !IsAudioFile(file.FilePath) {
+		return
+	}
+
+	if err := h.processAudio(file.FilePath); err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+}
+
+// Returns the post of the file by going up the file's parent folders,
+// e.g. for files in the "attachments" folder of the post, or nil if not found
+func (h *Handler) getPost(filePath string) *events.Post {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	dir := filepath.Dir(filePath)
+	for {
+		if post, ok := h.posts[dir]; ok {
+			return post
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return nil
+		}
+		dir = parentDir
+	}
+}
+
+// Returns the FFmpeg arguments to set the tags of the audio file based on its post
+func getTagArgs(filePath string, post *events.Post) []string {
+	args := []string{
+		"-metadata", "title=" + utils.RemoveExtFromFilename(filepath.Base(filePath)),
+	}
+	if post == nil {
+		return args
+	}
+	return append(
+		args,
+		"-metadata", "album="+post.Title,
+		"-metadata", "artist="+post.Creator,
+		"-metadata", "album_artist="+post.Creator,
+	)
+}
+
+// Runs FFmpeg with the given arguments to write to the output path
+//
+// The output file will be removed if FFmpeg fails to prevent leaving behind a corrupted file.
+func (h *Handler) runFfmpeg(inputPath, outputPath string, args []string) error {
+	cmdArgs := append([]string{"-y", "-i", inputPath}, args...)
+	cmd := exec.Command(h.options.FfmpegPath, append(cmdArgs, outputPath)...)
+	if utils.DEBUG_MODE {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf(
+			"audio error %d: failed to process %s with FFmpeg, more info => %v",
+			utils.CMD_ERROR,
+			inputPath,
+			err,
+		)
+	}
+	return nil
+}
+
+// Converts the WAV file to a FLAC file and/or writes the tags of the audio file based on the options
+func (h *Handler) processAudio(filePath string) error {
+	var tagArgs []string
+	if h.options.WriteTags {
+		tagArgs = getTagArgs(filePath, h.getPost(filePath))
+	}
+
+	// the original WAV file is kept so that
+	// it will not be re-downloaded in the next run
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext == ".wav" && h.options.ConvertWav {
+		flacPath := utils.RemoveExtFromFilename(filePath) + ".flac"
+		return h.runFfmpeg(filePath, flacPath, append([]string{"-c:a", "flac"}, tagArgs...))
+	}
+	if len(tagArgs) == 0 || ext == ".wav" {
+		// WAV files have limited support for tags
+		return nil
+	}
+
+	// the audio stream is copied as-is and only the tags are rewritten
+	tmpPath := utils.RemoveExtFromFilename(filePath) + ".tagged" + ext
+	args := append([]string{"-map", "0", "-c", "copy"}, tagArgs...)
+	if ext == ".mp3" {
+		// ID3v2.3 is the most widely supported version
+		args = append(args, "-id3v2_version", "3")
+	}
+	if err := h.runFfmpeg(filePath, tmpPath, args); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf(
+			"audio error %d: failed to replace %s with the tagged audio file, more info => %v",
+			utils.OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package cmds
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/fantia"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv/web"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox"
+	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/postdb"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// The creator of the URL given to the "audit" command
+type auditCreator struct {
+	site       string
+	creatorId  string
+	siteFolder string // the folder of the website in the download path
+
+	// returns the IDs of all the current posts of the creator
+	getPostIds func(cookies []*http.Cookie, session, userAgent string) ([]string, error)
+
+	// returns the URL of the post of the creator
+	getPostUrl func(postId string) string
+}
+
+var (
+	auditCookieFile string
+	auditSession    string
+	auditCmd        = &cobra.Command{
+		Use:   "audit <creator-url>",
+		Short: "Check the downloaded posts of a creator against the website",
+		Long: utils.CombineStringsWithNewline(
+			"Compares the current posts of the creator on the website with the downloaded posts and reports the posts",
+			"that have not been downloaded, the posts whose downloads did not finish, the posts whose number of files",
+			"changed, and the downloaded posts that have been deleted on the website.",
+			"",
+			"Supports Fantia fanclub URLs, e.g. https://fantia.jp/fanclubs/1234,",
+			"Pixiv Fanbox creator URLs, e.g. https://www.fanbox.cc/@creator,",
+			"Pixiv illustrator URLs, e.g. https://www.pixiv.net/users/1234,",
+			"and Kemono creator URLs, e.g. https://kemono.party/fanbox/user/1234.",
+			"",
+			"Note:",
+			"The changed and deleted posts can only be reported for the posts downloaded since the posts",
+			"started to be recorded in the app data folder.",
+		),
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			creator := parseAuditCreatorUrl(strings.TrimSpace(args[0]))
+			if creator == nil {
+				utils.ExitWithErrorf(
+					utils.EXIT_INPUT_ERROR,
+					"error %d: %q is not a supported Fantia, Pixiv Fanbox, Pixiv, or Kemono creator URL",
+					utils.INPUT_ERROR,
+					args[0],
+				)
+			}
+
+			var cookies []*http.Cookie
+			if cookieFile := getCookieFile(auditCookieFile, auditSession, creator.site); cookieFile != "" {
+				cookies = parseCookieFile(cookieFile, auditSession, creator.site)
+			}
+
+			config, _ := utils.LoadConfigFile()
+			userAgent := utils.GetSiteUserAgent(creator.site, config)
+
+			upstreamIds, err := creator.getPostIds(cookies, auditSession, userAgent)
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			posts, err := postdb.LoadPosts()
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			printAudit(creator, upstreamIds, postdb.GetCreatorPosts(posts, creator.site, creator.creatorId))
+		},
+	}
+)
+
+// Returns the creator of the Fantia, Pixiv Fanbox, Pixiv, or Kemono creator URL or nil if the URL is not supported
+func parseAuditCreatorUrl(creatorUrl string) *auditCreator {
+	if matched := textparser.F_FANCLUB_URL_REGEX.FindStringSubmatch(creatorUrl); matched != nil {
+		fanclubId := matched[textparser.F_FANCLUB_REGEX_FANCLUB_ID_INDEX]
+		return &auditCreator{
+			site:       utils.FANTIA,
+			creatorId:  fanclubId,
+			siteFolder: filepath.Join(utils.DOWNLOAD_PATH, utils.FANTIA_TITLE),
+			getPostIds: func(cookies []*http.Cookie, session, userAgent string) ([]string, error) {
+				dlOptions := &fantia.FantiaDlOptions{
+					Configs:         &configs.Config{UserAgent: userAgent},
+					SessionCookieId: session,
+					SessionCookies:  cookies,
+				}
+				if err := dlOptions.ValidateArgs(userAgent); err != nil {
+					return nil, err
+				}
+				return fantia.GetFanclubPostIds(fanclubId, dlOptions)
+			},
+			getPostUrl: func(postId string) string {
+				return fmt.Sprintf("%s/posts/%s", utils.FANTIA_URL, postId)
+			},
+		}
+	}
+
+	if matched := textparser.PF_CREATOR_URL_REGEX.FindStringSubmatch(creatorUrl); matched != nil {
+		creatorId := matched[textparser.PF_CREATOR_REGEX_CREATOR_ID_INDEX_1]
+		if creatorId == "" {
+			creatorId = matched[textparser.PF_CREATOR_REGEX_CREATOR_ID_INDEX_2]
+		}
+		return &auditCreator{
+			site:       utils.PIXIV_FANBOX,
+			creatorId:  creatorId,
+			siteFolder: filepath.Join(utils.DOWNLOAD_PATH, "Pixiv-Fanbox"),
+			getPostIds: func(cookies []*http.Cookie, session, userAgent string) ([]string, error) {
+				dlOptions := &pixivfanbox.PixivFanboxDlOptions{
+					Configs:         &configs.Config{UserAgent: userAgent},
+					SessionCookieId: session,
+					SessionCookies:  cookies,
+				}
+				dlOptions.ValidateArgs(userAgent)
+				return pixivfanbox.GetCreatorPostIds(creatorId, dlOptions)
+			},
+			getPostUrl: func(postId string) string {
+				return fmt.Sprintf("%s/@%s/posts/%s", utils.PIXIV_FANBOX_URL, creatorId, postId)
+			},
+		}
+	}
+
+	if matched := textparser.P_ARTIST_URL_REGEX.FindStringSubmatch(creatorUrl); matched != nil {
+		illustratorId := matched[textparser.P_ARTIST_REGEX_ID_INDEX]
+		return &auditCreator{
+			site:       utils.PIXIV,
+			creatorId:  illustratorId,
+			siteFolder: filepath.Join(utils.DOWNLOAD_PATH, utils.PIXIV_TITLE),
+			getPostIds: func(cookies []*http.Cookie, session, userAgent string) ([]string, error) {
+				if session != "" {
+					cookies = []*http.Cookie{api.VerifyAndGetCookie(utils.PIXIV, session, userAgent)}
+				}
+				return pixivweb.GetIllustratorPostIds(illustratorId, &pixivweb.PixivWebDlOptions{
+					ArtworkType:    "all",
+					Configs:        &configs.Config{UserAgent: userAgent},
+					SessionCookies: cookies,
+				})
+			},
+			getPostUrl: func(postId string) string {
+				return fmt.Sprintf("%s/artworks/%s", utils.PIXIV_URL, postId)
+			},
+		}
+	}
+
+	if matched := textparser.K_CREATOR_URL_REGEX.FindStringSubmatch(creatorUrl); matched != nil {
+		service := matched[textparser.K_CREATOR_URL_REGEX.SubexpIndex(kemono.SERVICE_GROUP_NAME)]
+		creatorId := matched[textparser.K_CREATOR_REGEX_CREATOR_ID_INDEX]
+		return &auditCreator{
+			site:       utils.KEMONO,
+			creatorId:  creatorId,
+			siteFolder: filepath.Join(utils.DOWNLOAD_PATH, "Kemono-Party", service),
+			getPostIds: func(cookies []*http.Cookie, session, userAgent string) ([]string, error) {
+				if session != "" {
+					cookies = []*http.Cookie{api.VerifyAndGetCookie(utils.KEMONO, session, userAgent)}
+				}
+				return kemono.GetCreatorPostIds(service, creatorId, &kemono.KemonoDlOptions{
+					Configs:        &configs.Config{UserAgent: userAgent},
+					SessionCookies: cookies,
+				})
+			},
+			getPostUrl: func(postId string) string {
+				return fmt.Sprintf("%s/%s/user/%s/post/%s", utils.KEMONO_URL, service, creatorId, postId)
+			},
+		}
+	}
+	return nil
+}
+
+// Prints the posts of the creator that have not been downloaded, whose number of files changed,
+// and that have been deleted on the website by comparing the current posts with the recorded posts
+func printAudit(creator *auditCreator, upstreamIds []string, recordedPosts []*postdb.Post) {
+	upstream := make(map[string]struct{}, len(upstreamIds))
+	for _, postId := range upstreamIds {
+		upstream[postId] = struct{}{}
+	}
+
+	// the post folders are also checked for the posts downloaded before the posts were recorded
+	// where only the post folders whose downloads finished are returned
+	downloaded := utils.GetDownloadedPostIds(creator.siteFolder)
+	recorded := make(map[string]struct{}, len(recordedPosts))
+	for _, post := range recordedPosts {
+		recorded[post.Id] = struct{}{}
+	}
+
+	var missing []string
+	for _, postId := range upstreamIds {
+		_, isRecorded := recorded[postId]
+		_, isDownloaded := downloaded[postId]
+		if !isRecorded && !isDownloaded {
+			missing = append(missing, postId)
+		}
+	}
+
+	var incomplete, changed, deleted []*postdb.Post
+	for _, post := range recordedPosts {
+		if _, ok := upstream[post.Id]; !ok {
+			deleted = append(deleted, post)
+			continue
+		}
+		if _, ok := downloaded[post.Id]; !ok {
+			incomplete = append(incomplete, post)
+		}
+		if post.FileCountChanged() {
+			changed = append(changed, post)
+		}
+	}
+
+	color.Cyan(
+		"%s creator %s: %d post(s) on the website, %d recorded post(s)",
+		utils.GetReadableSiteStr(creator.site),
+		creator.creatorId,
+		len(upstreamIds),
+		len(recordedPosts),
+	)
+
+	if len(missing) > 0 {
+		color.Yellow("\nPosts that have not been downloaded (%d):", len(missing))
+		for _, postId := range missing {
+			fmt.Println("  " + creator.getPostUrl(postId))
+		}
+	}
+	if len(incomplete) > 0 {
+		color.Yellow("\nPosts whose downloads did not finish and will be resumed by the next run (%d):", len(incomplete))
+		for _, post := range incomplete {
+			fmt.Printf("  %s  %s\n", creator.getPostUrl(post.Id), post.Folder)
+		}
+	}
+	if len(changed) > 0 {
+		color.Yellow("\nPosts whose number of files changed (%d):", len(changed))
+		for _, post := range changed {
+			fmt.Printf(
+				"  %s  %d -> %d file(s) on %s  %s\n",
+				creator.getPostUrl(post.Id),
+				post.PrevFileCount,
+				post.FileCount,
+				post.FileCountChangedAt.Local().Format("2006-01-02"),
+				post.Folder,
+			)
+		}
+	}
+	if len(deleted) > 0 {
+		color.Red("\nDownloaded posts that have been deleted on the website (%d):", len(deleted))
+		for _, post := range deleted {
+			if post.IsDeletedUpstream() {
+				fmt.Printf(
+					"  %s  %s (not found since %s)\n",
+					creator.getPostUrl(post.Id),
+					post.Folder,
+					post.DeletedUpstreamAt.Local().Format("2006-01-02"),
+				)
+			} else {
+				fmt.Printf("  %s  %s\n", creator.getPostUrl(post.Id), post.Folder)
+			}
+		}
+	}
+	if len(missing) == 0 && len(incomplete) == 0 && len(changed) == 0 && len(deleted) == 0 {
+		color.Green("\nThe downloaded posts are up to date with the website.")
+	}
+}
+
+func init() {
+	auditCmd.Flags().StringVarP(
+		&auditCookieFile,
+		"cookie_file",
+		"c",
+		"",
+		utils.CombineStringsWithNewline(
+			"Pass in a file path to your saved Netscape/Mozilla generated cookie file to use for the requests.",
+			"Otherwise, the cookie file saved by the \"login\" command will be used, if any.",
+		),
+	)
+	auditCmd.Flags().StringVarP(
+		&auditSession,
+		"session",
+		"s",
+		"",
+		"Your session cookie value to use for the requests to the website.",
+	)
+	RootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,150 @@
+package cmds
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+type cookieToCheck struct {
+	site       string
+	session    string
+	cookieFile string // resolved from the root command's flags or the config file
+}
+
+var (
+	cookiesToCheck = []*cookieToCheck{
+		{site: utils.FANTIA},
+		{site: utils.PIXIV_FANBOX},
+		{site: utils.PIXIV},
+		{site: utils.KEMONO},
+		{site: utils.DLSITE},
+	}
+	checkUserAgent string
+	checkCmd       = &cobra.Command{
+		Use:   "check",
+		Short: "Check the validity of your session cookies",
+		Long: utils.CombineStringsWithNewline(
+			"Validates the supplied session cookies against each website without downloading anything.",
+			"Cookie files can be supplied with the root command's flags, e.g. \"--fantia_cookie_file\", or saved in the config file.",
+			"The expiry date of the cookie will be shown if it is available in the supplied cookie file.",
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			checked := 0
+			allValid := true
+			for _, toCheck := range cookiesToCheck {
+				toCheck.cookieFile = getCookieFile("", toCheck.session, toCheck.site)
+				if toCheck.session == "" && toCheck.cookieFile == "" {
+					continue
+				}
+
+				checked++
+				if !checkCookie(toCheck) {
+					allValid = false
+				}
+			}
+
+			if checked == 0 {
+				utils.ExitWithErrorf(
+					utils.EXIT_INPUT_ERROR,
+					"error %d: no session cookies or cookie files were supplied to check",
+					utils.INPUT_ERROR,
+				)
+			}
+			if !allValid {
+				os.Exit(utils.EXIT_AUTH_ERROR)
+			}
+		},
+	}
+)
+
+// Returns the cookie to check from either the session cookie value or the cookie file
+func (c *cookieToCheck) getCookie() (*http.Cookie, error) {
+	if c.cookieFile == "" {
+		return api.GetCookie(c.session, c.site), nil
+	}
+
+	cookies, err := utils.ParseNetscapeCookieFile(c.cookieFile, "", c.site)
+	if err != nil {
+		return nil, err
+	}
+	return cookies[0], nil
+}
+
+func getCookieExpiryStr(cookie *http.Cookie, isFromFile bool) string {
+	if !isFromFile || cookie.Expires.IsZero() {
+		return "expiry date unknown"
+	}
+
+	expiry := cookie.Expires.Local().Format("2006-01-02 15:04:05")
+	if cookie.Expires.Before(time.Now()) {
+		return "expired on " + expiry
+	}
+	return "expires on " + expiry
+}
+
+// Checks the given cookie against the website and prints the result
+//
+// Returns true if the cookie is valid.
+func checkCookie(toCheck *cookieToCheck) bool {
+	siteName := utils.GetReadableSiteStr(toCheck.site)
+	cookie, err := toCheck.getCookie()
+	if err != nil {
+		color.Red("✗ %s: %v", siteName, err)
+		return false
+	}
+
+	userAgent := checkUserAgent
+	if userAgent == "" {
+		config, _ := utils.LoadConfigFile()
+		userAgent = utils.GetSiteUserAgent(toCheck.site, config)
+	}
+	isValid, err := api.VerifyCookie(cookie, toCheck.site, userAgent)
+	if err != nil {
+		utils.LogError(
+			err,
+			fmt.Sprintf("error occurred when trying to verify %s cookie.", siteName),
+			false,
+			utils.ERROR,
+		)
+		color.Red("✗ %s: could not verify the cookie, please refer to the logs for more details", siteName)
+		return false
+	}
+
+	expiryStr := getCookieExpiryStr(cookie, toCheck.cookieFile != "")
+	if !isValid {
+		color.Red("✗ %s: cookie is invalid (%s)", siteName, expiryStr)
+		return false
+	}
+	color.Green("✓ %s: cookie is valid (%s)", siteName, expiryStr)
+	return true
+}
+
+func init() {
+	for _, toCheck := range cookiesToCheck {
+		siteName := utils.GetReadableSiteStr(toCheck.site)
+		checkCmd.Flags().StringVar(
+			&toCheck.session,
+			toCheck.site+"_session",
+			"",
+			fmt.Sprintf(
+				"Your %s session cookie value to check.",
+				siteName,
+			),
+		)
+	}
+	checkCmd.Flags().StringVarP(
+		&checkUserAgent,
+		"user_agent",
+		"u",
+		"",
+		"Set a custom User-Agent header to use when checking the cookies.",
+	)
+	RootCmd.AddCommand(checkCmd)
+}
@@ -1,10 +1,438 @@
 package cmds
 
 import (
-	"github.com/spf13/cobra"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/audio"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/feed"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/htmlarchive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/metrics"
+	"github.com/KJHJason/Cultured-Downloader-CLI/mirror"
+	"github.com/KJHJason/Cultured-Downloader-CLI/postdb"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/stats"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/twitter"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	strictCookies    bool
+	cookieExpiryDays int
+	persistCookies   bool
+	checksumManifest bool
+	extractArchives  bool
+	dlFileHosts      bool
+	dlTwitterMedia   bool
+	htmlArchive      bool
+	verifyImages     bool
+	downloadLog      bool
+	metricsFile      string
+	postOrder        string
+	maxTotalSize     string
+	maxTotalFiles    int
+	stopAfterSeen    int
+	postLayout       string
+	onCreatorRename  string
+	maxTitleLength   int
+	requestDelay     []float64
+	extraHeaders     []string
+	onlyFileType     string
+	audioFfmpegPath  string
+	audioToFlac      bool
+	tagAudio         bool
+	waitForLock      bool
+	forceLock        bool
+	interactive      bool
+	minFreeSpace     string
+	onLowDiskSpace   string
+	mirrorRemote     string
+	noMirror         bool
+	storageType      string
+	feedFile         string
+
+	// set if the downloaded creator folders will be mirrored with rclone after the run
+	mirrorHandler *mirror.Handler
+
+	// set if an HTML page will be written in each post folder after the run
+	htmlArchiveHandler *htmlarchive.Handler
+
+	// set if the newly downloaded posts will be added to an RSS feed after the run
+	feedHandler *feed.Handler
+
+	// set by the download commands to save the statistics of the run after it
+	statsHandler *stats.Handler
+
+	// set by the download commands to save the resolved posts for the "audit" command after the run
+	postDbHandler *postdb.Handler
 )
 
+const (
+	LOW_DISK_SPACE_ABORT = "abort"
+	LOW_DISK_SPACE_PAUSE = "pause"
+)
+
+// Parses the given cookie file for the session cookie of the website and checks its expiry date.
+//
+// If the cookie file could not be parsed or if the cookie has expired
+// or expires within the --cookie_expiry_days and the --strict_cookies flag is set, the program will exit with an error message.
+//
+// If the --persist_cookies flag is set, rotated session cookies will be written back to the cookie file.
+func parseCookieFile(cookieFile, session, website string) []*http.Cookie {
+	cookies, err := utils.ParseNetscapeCookieFile(
+		cookieFile,
+		session,
+		website,
+	)
+	if err == nil {
+		if cookieExpiryDays < 0 {
+			utils.ExitWithErrorf(
+				utils.EXIT_INPUT_ERROR,
+				"error %d: --cookie_expiry_days must not be negative but got %d",
+				utils.INPUT_ERROR,
+				cookieExpiryDays,
+			)
+		}
+		err = utils.CheckCookieExpiry(cookies, website, cookieExpiryDays, strictCookies)
+	}
+	if err != nil {
+		utils.LogError(
+			err,
+			"",
+			true,
+			utils.ERROR,
+		)
+	}
+	if persistCookies && cookieFile != "" {
+		request.PersistCookies(cookies, cookieFile)
+	}
+	return cookies
+}
+
+// Sets the per-run download quota of the config from the --max_total_size and --max_total_files flags
+//
+// If the --max_total_size flag is invalid, the program will exit with an error message.
+func setDownloadQuota(config *configs.Config, website string) {
+	if maxTotalSize != "" {
+		size, err := utils.ParseFileSizeStr(maxTotalSize)
+		if err != nil {
+			utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+		}
+		config.MaxTotalSize = size
+	}
+	config.MaxTotalFiles = maxTotalFiles
+	config.QueueFilePath = request.GetQueueFilePath(website)
+}
+
+// Sets the random delay before each request from the --delay flag
+//
+// If the --delay flag is invalid, the program will exit with an error message.
+func setRequestDelay() {
+	if len(requestDelay) == 0 {
+		return
+	}
+	if len(requestDelay) != 2 {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"error %d: --delay must be in the format of \"min,max\" in seconds, e.g. \"2,5\"",
+			utils.INPUT_ERROR,
+		)
+	}
+	if err := utils.SetRequestDelay(requestDelay[0], requestDelay[1]); err != nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+}
+
+// Sets the headers from the --header flags to add to every request
+//
+// If any of the --header flags are invalid, the program will exit with an error message.
+func setExtraHeaders() {
+	if len(extraHeaders) == 0 {
+		return
+	}
+	headers, err := request.ParseHeaders(extraHeaders)
+	if err != nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+	request.SetGlobalHeaders(headers)
+}
+
+// Acquires the locks on the download directory and the website's queue file
+// so that overlapping runs, e.g. from cron, do not download the same files or corrupt the queue file.
+//
+// If another run is holding either lock and the --wait_for_lock flag
+// is not set, the program will exit with an error message.
+func (cmdInfo *commonFlags) acquireLocks() {
+	lockPaths := []string{
+		filepath.Join(utils.DOWNLOAD_PATH, utils.LOCK_FILENAME),
+		request.GetQueueFilePath(cmdInfo.site) + ".lock",
+	}
+	var releaseFuncs []func()
+	for _, lockPath := range lockPaths {
+		release, err := utils.AcquireLock(lockPath, waitForLock, forceLock)
+		if err != nil {
+			for _, release := range releaseFuncs {
+				release()
+			}
+			utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+		}
+		releaseFuncs = append(releaseFuncs, release)
+	}
+
+	releaseLocks = func() {
+		for _, release := range releaseFuncs {
+			release()
+		}
+	}
+}
+
+// Sets the minimum free disk space to keep from the --min_free_space and --on_low_disk_space flags
+//
+// If any of the flags are invalid, the program will exit with an error message.
+func setDiskSpaceOptions() {
+	if onLowDiskSpace != LOW_DISK_SPACE_ABORT && onLowDiskSpace != LOW_DISK_SPACE_PAUSE {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			"error %d: --on_low_disk_space must be either %q or %q but got %q",
+			utils.INPUT_ERROR,
+			LOW_DISK_SPACE_ABORT,
+			LOW_DISK_SPACE_PAUSE,
+			onLowDiskSpace,
+		)
+	}
+
+	var minFree int64
+	if minFreeSpace != "" && minFreeSpace != "0" {
+		var err error
+		if minFree, err = utils.ParseFileSizeStr(minFreeSpace); err != nil {
+			utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+		}
+	}
+	request.SetDiskSpaceOptions(minFree, onLowDiskSpace == LOW_DISK_SPACE_PAUSE)
+}
+
+// Sets the storage backend that the downloaded files are written to from the storage
+// section of the config file where the type can be overridden by the --storage flag
+//
+// If the storage config is invalid, the program will exit with an error message.
+func setStorage() {
+	storageConfig := &utils.StorageConfig{}
+	if config, err := utils.LoadConfigFile(); err == nil && config.Storage != nil {
+		storageConfig = config.Storage
+	}
+	if storageType != "" {
+		storageConfig.Type = storageType
+	}
+
+	backend, err := storage.NewBackend(storageConfig)
+	if err != nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+	storage.SetBackend(backend)
+	if storage.IsLocal() {
+		return
+	}
+
+	color.Yellow(
+		"Writing the downloaded files to %s, so checksum manifests, archive extraction, image verification, audio processing,\n"+
+			"Twitter/X media downloads, the renaming of creator folders, and the conversion of Pixiv ugoira that need the files on the local disk will be skipped.",
+		backend.Name(),
+	)
+	checksumManifest = false
+	extractArchives = false
+	dlTwitterMedia = false
+	onCreatorRename = utils.CREATOR_RENAME_KEEP
+	verifyImages = false
+	audioToFlac = false
+	tagAudio = false
+}
+
+// Sets the external program to download the media of the Twitter/X links in the posts with if the --dl_twitter_media flag is set
+//
+// If neither gallery-dl nor yt-dlp is configured or found, the program will exit with an error message.
+func setTwitterMedia() {
+	if !dlTwitterMedia {
+		return
+	}
+
+	var tools *utils.ToolsConfig
+	if config, err := utils.LoadConfigFile(); err == nil {
+		tools = config.Tools
+	}
+	if err := twitter.SetMediaDownloader(tools); err != nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+}
+
+// Saves the statistics of the run for the "stats" command if it was a run of a download command
+//
+// Also called by utils.Exit so that the runs stopped by a fatal error are recorded too.
+func saveRunStats() {
+	if statsHandler == nil {
+		return
+	}
+	handler := statsHandler
+	statsHandler = nil // so that the run is only saved once
+	if err := handler.Save(); err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+}
+
+// Saves the posts resolved in the run for the "audit" command if it was a run of a download command
+// and warns about the previously downloaded posts that have been deleted on the website
+func savePostDb() {
+	if postDbHandler == nil {
+		return
+	}
+	deleted, err := postDbHandler.Save()
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+	if len(deleted) == 0 {
+		return
+	}
+
+	color.Yellow(
+		"%d previously downloaded post(s) no longer exist on the website, their downloaded files may be the only remaining copies:",
+		len(deleted),
+	)
+	for _, post := range deleted {
+		fmt.Printf("  [%s] %s => %s\n", utils.GetReadableSiteStr(post.Site), post.Id, post.Folder)
+	}
+}
+
+// Registers a handler to mirror the downloaded creator folders with rclone after the run
+// if the config file has a mirror remote or the --mirror_remote flag is set, unless the --no_mirror flag is set
+//
+// If the mirror config is invalid or rclone cannot be found, the program will exit with an error message.
+func setMirror() {
+	if noMirror {
+		return
+	}
+
+	mirrorConfig := &utils.MirrorConfig{}
+	if config, err := utils.LoadConfigFile(); err == nil && config.Mirror != nil {
+		mirrorConfig = config.Mirror
+	}
+	if mirrorRemote != "" {
+		mirrorConfig.Remote = mirrorRemote
+	}
+	if mirrorConfig.Remote == "" {
+		return
+	}
+	if err := mirrorConfig.Validate(); err != nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+
+	rclonePath, err := mirror.FindRclone("")
+	if err != nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+	mirrorHandler = mirror.NewHandler(mirrorConfig, rclonePath)
+	events.Register(mirrorHandler)
+}
+
+// Mirrors the downloaded creator folders with rclone if the mirror was set up by setMirror
+func runMirror() {
+	if mirrorHandler != nil {
+		mirrorHandler.Mirror()
+	}
+}
+
+// Registers a handler to write an HTML page in each post folder after the run if the --html_archive flag is set
+func setHtmlArchive() {
+	if htmlArchive {
+		htmlArchiveHandler = htmlarchive.NewHandler()
+		events.Register(htmlArchiveHandler)
+	}
+}
+
+// Writes the HTML pages of the resolved posts if they were set up by setHtmlArchive
+func writeHtmlArchives() {
+	if htmlArchiveHandler != nil {
+		htmlArchiveHandler.WriteAll()
+	}
+}
+
+// Registers a handler to add the newly downloaded posts to an RSS feed after the run
+// if the --feed_file flag is set or the config file has a feed file
+func setFeed() {
+	feedPath := feedFile
+	if feedPath == "" {
+		if config, err := utils.LoadConfigFile(); err == nil {
+			feedPath = config.FeedFile
+		}
+	}
+	if feedPath != "" {
+		feedHandler = feed.NewHandler(feedPath)
+		events.Register(feedHandler)
+	}
+}
+
+// Adds the newly downloaded posts to the RSS feed if it was set up by setFeed
+func writeFeed() {
+	if feedHandler != nil {
+		feedHandler.Write()
+	}
+}
+
+// Registers a handler to append each downloaded file to the download log if the --download_log flag is set
+func setDownloadLog() {
+	if downloadLog {
+		events.Register(
+			request.NewDownloadLogHandler(
+				filepath.Join(utils.DOWNLOAD_PATH, request.DOWNLOAD_LOG_FILENAME),
+			),
+		)
+	}
+}
+
+// Registers a PostSelector to let the user pick which of the
+// resolved posts to download if the --interactive flag is set
+func setPostSelector(config *configs.Config) {
+	if interactive {
+		config.PostSelector = configs.NewPostSelector()
+		events.Register(config.PostSelector)
+	}
+}
+
+// Registers a handler to convert and tag the downloaded audio attachments
+// if the --audio_to_flac or --tag_audio flags are set
+//
+// If FFmpeg cannot be found, the program will exit with an error message.
+func setAudioHandler(config *configs.Config) {
+	audioOptions := &audio.Options{
+		ConvertWav: audioToFlac,
+		WriteTags:  tagAudio,
+	}
+	if !audioOptions.IsEnabled() {
+		return
+	}
+
+	config.FfmpegPath = audioFfmpegPath
+	config.ValidateFfmpeg()
+	audioOptions.FfmpegPath = config.FfmpegPath
+	events.Register(audio.NewHandler(audioOptions))
+}
+
+// Registers a handler to write the download metrics of the website
+// in the Prometheus text format if the --metrics_file flag is set
+func setMetrics(website string) {
+	if metricsFile != "" {
+		events.Register(
+			metrics.NewHandler(metricsFile, website, request.RemainingQueueLen),
+		)
+	}
+}
+
 func getMultipleIdsMsg() string {
 	return "For multiple IDs, separate them with a comma.\nExample: \"12345,67891\" (without the quotes)"
 }
@@ -13,67 +441,207 @@ type textFilePath struct {
 	variable *string
 	desc     string
 }
+type gdriveFilterFlags struct {
+	maxDepth  int
+	fileTypes []string
+	minSize   string
+	maxSize   string
+}
+
+// Converts the GDrive filter flags to a gdrive.Filters struct
+//
+// If the file size flags are invalid, the program will exit with an error message.
+func (f *gdriveFilterFlags) getFilters() *gdrive.Filters {
+	filters := &gdrive.Filters{
+		MaxDepth: f.maxDepth,
+		FileExts: f.fileTypes,
+	}
+
+	var err error
+	if f.minSize != "" {
+		if filters.MinFileSize, err = utils.ParseFileSizeStr(f.minSize); err != nil {
+			utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+		}
+	}
+	if f.maxSize != "" {
+		if filters.MaxFileSize, err = utils.ParseFileSizeStr(f.maxSize); err != nil {
+			utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+		}
+	}
+	return filters
+}
+
+// Sets the User-Agent header to the one saved in the config file for the website, the default one in the
+// config file, or the built-in one for the website, in that order, if the --user_agent flag was not supplied
+func (cmdInfo *commonFlags) applyUserAgentConfig() {
+	if cmdInfo.cmd.Flags().Changed("user_agent") {
+		return
+	}
+
+	// the built-in User-Agent header of the website is used if the config file is invalid
+	config, _ := utils.LoadConfigFile()
+	*cmdInfo.userAgentVar = utils.GetSiteUserAgent(cmdInfo.site, config)
+}
+
+// Sets the GDrive flags that were not supplied to the values saved in the config file, if any
+func (cmdInfo *commonFlags) applyGdriveConfig() {
+	config, err := utils.LoadConfigFile()
+	if err != nil || config.Gdrive == nil {
+		return
+	}
+
+	flags := cmdInfo.cmd.Flags()
+	gdriveConfig := config.Gdrive
+	if cmdInfo.gdriveApiKeyVar != nil && !flags.Changed("gdrive_api_key") {
+		if apiKeys := gdriveConfig.GetApiKeys(); len(apiKeys) > 0 {
+			*cmdInfo.gdriveApiKeyVar = strings.Join(apiKeys, ",")
+		}
+	}
+	if cmdInfo.gdriveWorkersVar != nil && !flags.Changed("gdrive_workers") && gdriveConfig.Workers > 0 {
+		*cmdInfo.gdriveWorkersVar = gdriveConfig.Workers
+	}
+
+	filters := cmdInfo.gdriveFilters
+	if filters == nil {
+		return
+	}
+	if !flags.Changed("gdrive_max_depth") && gdriveConfig.MaxDepth != nil {
+		filters.maxDepth = *gdriveConfig.MaxDepth
+	}
+	if !flags.Changed("gdrive_file_types") && len(gdriveConfig.FileTypes) > 0 {
+		filters.fileTypes = gdriveConfig.FileTypes
+	}
+	if !flags.Changed("gdrive_min_size") && gdriveConfig.MinSize != "" {
+		filters.minSize = gdriveConfig.MinSize
+	}
+	if !flags.Changed("gdrive_max_size") && gdriveConfig.MaxSize != "" {
+		filters.maxSize = gdriveConfig.MaxSize
+	}
+}
+
 type commonFlags struct {
-	cmd             *cobra.Command
-	overwriteVar    *bool
-	cookieFileVar   *string
-	userAgentVar    *string
-	gdriveApiKeyVar *string  
-	logUrlsVar      *bool
-	textFile        textFilePath
+	cmd              *cobra.Command
+	site             string
+	overwriteVar     *bool
+	cookieFileVar    *string
+	userAgentVar     *string
+	gdriveApiKeyVar  *string
+	gdriveWorkersVar *int
+	gdriveFilters    *gdriveFilterFlags
+	logUrlsVar       *bool
+	hasCreatorPosts  bool
+	hasCreatorNames  bool // the creator folders are named after the creator instead of their ID
+	canStopEarly     bool
+	hasAudio         bool
+	textFile         textFilePath
 }
 
 func init() {
 	commonCmdFlags := [...]commonFlags{
 		{
-			cmd: fantiaCmd,
-			overwriteVar:    &fantiaOverwrite,
-			cookieFileVar:   &fantiaCookieFile,
-			userAgentVar:    &fantiaUserAgent,
-			gdriveApiKeyVar: &fantiaGdriveApiKey,
-			logUrlsVar:      &fantiaLogUrls,
+			cmd:              fantiaCmd,
+			site:             utils.FANTIA,
+			overwriteVar:     &fantiaOverwrite,
+			cookieFileVar:    &fantiaCookieFile,
+			userAgentVar:     &fantiaUserAgent,
+			gdriveApiKeyVar:  &fantiaGdriveApiKey,
+			gdriveWorkersVar: &fantiaGdriveWorkers,
+			gdriveFilters:    &fantiaGdriveFilters,
+			logUrlsVar:       &fantiaLogUrls,
+			hasCreatorPosts:  true,
+			hasCreatorNames:  true,
+			canStopEarly:     true,
+			hasAudio:         true,
 			textFile: textFilePath {
 				variable: &fantiaDlTextFile,
 				desc:     "Path to a text file containing Fanclub and/or post URL(s) to download from Fantia.",
 			},
 		},
 		{
-			cmd: pixivFanboxCmd,
-			overwriteVar:    &fanboxOverwriteFiles,
-			cookieFileVar:   &fanboxCookieFile,
-			userAgentVar:    &fanboxUserAgent,
-			gdriveApiKeyVar: &fanboxGdriveApiKey,
-			logUrlsVar:      &fanboxLogUrls,
+			cmd:              pixivFanboxCmd,
+			site:             utils.PIXIV_FANBOX,
+			overwriteVar:     &fanboxOverwriteFiles,
+			cookieFileVar:    &fanboxCookieFile,
+			userAgentVar:     &fanboxUserAgent,
+			gdriveApiKeyVar:  &fanboxGdriveApiKey,
+			gdriveWorkersVar: &fanboxGdriveWorkers,
+			gdriveFilters:    &fanboxGdriveFilters,
+			logUrlsVar:       &fanboxLogUrls,
+			hasCreatorPosts:  true,
+			canStopEarly:     true,
+			hasAudio:         true,
 			textFile: textFilePath {
 				variable: &fanboxDlTextFile,
 				desc:     "Path to a text file containing creator and/or post URL(s) to download from Pixiv Fanbox.",
 			},
 		},
 		{
-			cmd: pixivCmd,
-			overwriteVar:  &pixivOverwrite,
-			cookieFileVar: &pixivCookieFile,
-			userAgentVar:  &pixivUserAgent,
+			cmd:             pixivCmd,
+			site:            utils.PIXIV,
+			overwriteVar:    &pixivOverwrite,
+			cookieFileVar:   &pixivCookieFile,
+			userAgentVar:    &pixivUserAgent,
+			hasCreatorPosts: true,
+			hasCreatorNames: true,
+			canStopEarly:    true,
 			textFile: textFilePath {
 				variable: &pixivDlTextFile,
-				desc:     "Path to a text file containing artwork, illustrator, and tag name URL(s) to download from Pixiv.",
+				desc:     "Path to a text file containing artwork, illustrator, series, and tag name URL(s) to download from Pixiv.",
 			},
 		},
 		{
-			cmd: kemonoCmd,
-			overwriteVar:    &kemonoOverwrite,
-			cookieFileVar:   &kemonoCookieFile,
-			userAgentVar:    &kemonoUserAgent,
-			gdriveApiKeyVar: &kemonoGdriveApiKey,
-			logUrlsVar:      &kemonoLogUrls,
+			cmd:              kemonoCmd,
+			site:             utils.KEMONO,
+			overwriteVar:     &kemonoOverwrite,
+			cookieFileVar:    &kemonoCookieFile,
+			userAgentVar:     &kemonoUserAgent,
+			gdriveApiKeyVar:  &kemonoGdriveApiKey,
+			gdriveWorkersVar: &kemonoGdriveWorkers,
+			gdriveFilters:    &kemonoGdriveFilters,
+			logUrlsVar:       &kemonoLogUrls,
+			hasCreatorPosts:  true,
+			canStopEarly:     true,
 			textFile: textFilePath {
 				variable: &kemonoDlTextFile,
-				desc: "Path to a text file containing creator and/or post URL(s) to download from Kemono Party.",
+				desc:     "Path to a text file containing creator and/or post URL(s) to download from Kemono Party.",
+			},
+		},
+		{
+			cmd:           dlsiteCmd,
+			site:          utils.DLSITE,
+			overwriteVar:  &dlsiteOverwrite,
+			cookieFileVar: &dlsiteCookieFile,
+			userAgentVar:  &dlsiteUserAgent,
+			textFile: textFilePath{
+				variable: &dlsiteDlTextFile,
+				desc:     "Path to a text file containing DLsite work URL(s) to download from your purchases on DLsite Play.",
 			},
 		},
 	}
-	for _, cmdInfo := range commonCmdFlags {
+	for idx := range commonCmdFlags {
+		cmdInfo := &commonCmdFlags[idx]
 		cmd := cmdInfo.cmd
+		cmd.PreRun = func(cmd *cobra.Command, args []string) {
+			runStatus = &utils.RunStatus{}
+			events.Register(runStatus)
+			statsHandler = stats.NewHandler(cmdInfo.site)
+			events.Register(statsHandler)
+			utils.RegisterExitHook(saveRunStats)
+			postDbHandler = postdb.NewHandler()
+			events.Register(postDbHandler)
+			events.Register(request.NewEmptyPostHandler())
+			cmdInfo.acquireLocks()
+			setStorage()
+			setTwitterMedia()
+			setHtmlArchive()
+			setFeed()
+			setDiskSpaceOptions()
+			setMirror()
+			cmdInfo.applyUserAgentConfig()
+			if cmdInfo.gdriveApiKeyVar != nil {
+				cmdInfo.applyGdriveConfig()
+			}
+		}
 		cmd.Flags().BoolVarP(
 			cmdInfo.overwriteVar,
 			"overwrite",
@@ -89,7 +657,11 @@ func init() {
 			"user_agent",
 			"u",
 			"",
-			"Set a custom User-Agent header to use when communicating with the API(s) or when downloading.",
+			utils.CombineStringsWithNewline(
+				"Set a custom User-Agent header to use when communicating with the API(s) or when downloading.",
+				"Defaults to the \"site_user_agents\" or \"user_agent\" in the config file, otherwise each request will",
+				"rotate through the \"user_agents\" list in the config file or use a built-in one for the website and your OS.",
+			),
 		)
 		cmd.Flags().StringVarP(
 			cmdInfo.textFile.variable,
@@ -109,6 +681,336 @@ func init() {
 				"Chrome Extension URL: https://chrome.google.com/webstore/detail/get-cookiestxt-locally/cclelndahbckbenkjhflpdbgdldlbecc",
 			),
 		)
+		cmd.Flags().BoolVar(
+			&strictCookies,
+			"strict_cookies",
+			false,
+			utils.CombineStringsWithNewline(
+				"Stop the program if the session cookie in the cookie file has expired or expires within the --cookie_expiry_days.",
+				"Otherwise, only a warning will be printed.",
+			),
+		)
+		cmd.Flags().IntVar(
+			&cookieExpiryDays,
+			"cookie_expiry_days",
+			utils.COOKIE_EXPIRY_WARNING_DAYS,
+			utils.CombineStringsWithNewline(
+				"The number of days before the session cookie in the cookie file expires to start warning about it.",
+				"Set to 0 to only warn about the session cookies that have expired.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			&persistCookies,
+			"persist_cookies",
+			false,
+			utils.CombineStringsWithNewline(
+				"Write the session cookie back to the cookie file if the website rotates it during the download process.",
+				"Useful for long downloads where the original session cookie may be replaced by the website.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			&checksumManifest,
+			"checksums",
+			false,
+			utils.CombineStringsWithNewline(
+				fmt.Sprintf(
+					"Write a %s manifest in each post folder after downloading.",
+					utils.CHECKSUM_MANIFEST_FILENAME,
+				),
+				fmt.Sprintf(
+					"Useful for verifying the files after copying them elsewhere, e.g. \"sha256sum -c %s\".",
+					utils.CHECKSUM_MANIFEST_FILENAME,
+				),
+			),
+		)
+		cmd.Flags().BoolVar(
+			&extractArchives,
+			"extract_archives",
+			false,
+			utils.CombineStringsWithNewline(
+				"Extract the zip, RAR, and 7z archives in each post folder after downloading into a folder next to each archive.",
+				fmt.Sprintf(
+					"Encrypted archives are extracted with the passwords detected in the post which are saved in %s.",
+					utils.EXTRACTED_PASSWORDS_FILENAME,
+				),
+				"If none of them work, you will be prompted for the password when running in a terminal.",
+				"Note that encrypted zip files can only be extracted with the \"7z_path\" in the tools section of the config file.",
+				"Use the list_archive command to preview the files in an archive without extracting it.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			&htmlArchive,
+			"html_archive",
+			false,
+			utils.CombineStringsWithNewline(
+				fmt.Sprintf(
+					"Write a %s page in each post folder after downloading with the post's text, images, videos, audio, and links to its other files.",
+					htmlarchive.ARCHIVE_FILENAME,
+				),
+				"The page only uses relative paths to the downloaded files so that the post folders can be browsed offline without any other tool.",
+				"When the files are not written to the local disk, the page only links to the files downloaded in that run.",
+			),
+		)
+		cmd.Flags().StringVar(
+			&feedFile,
+			"feed_file",
+			"",
+			utils.CombineStringsWithNewline(
+				"Path to an RSS feed file to add the posts with newly downloaded files to after each run, e.g. to follow your archive in a feed reader.",
+				fmt.Sprintf(
+					"The latest %d posts are kept in the feed. Defaults to the \"feed_file\" in the config file.",
+					feed.MAX_ITEMS,
+				),
+			),
+		)
+		cmd.Flags().BoolVar(
+			&verifyImages,
+			"verify_images",
+			false,
+			utils.CombineStringsWithNewline(
+				"Decode the downloaded JPEG, PNG, and GIF images to detect truncated or corrupted images and re-download them.",
+				"Note that empty files will always be re-downloaded regardless of this flag.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			&downloadLog,
+			"download_log",
+			false,
+			utils.CombineStringsWithNewline(
+				fmt.Sprintf(
+					"Append the source URL, file path, post ID, size, SHA-256 hash, and time of each downloaded file to %s in the download directory.",
+					request.DOWNLOAD_LOG_FILENAME,
+				),
+				"Useful for building external indexes or deduplicating files across tools.",
+				"The files in the log can be verified and re-queued later with the verify command.",
+			),
+		)
+		cmd.Flags().StringVar(
+			&metricsFile,
+			"metrics_file",
+			"",
+			utils.CombineStringsWithNewline(
+				"Path to write the download, byte, error counts, and queue depth to in the Prometheus text format.",
+				"Point node_exporter's textfile collector to the file's folder to graph the downloads in Grafana, e.g. \"cultured_downloader.prom\".",
+			),
+		)
+		cmd.Flags().StringVar(
+			&maxTotalSize,
+			"max_total_size",
+			"",
+			utils.CombineStringsWithNewline(
+				"Stop downloading after this many bytes have been downloaded in this run, e.g. \"50GB\".",
+				"The remaining files will be saved and downloaded first in the next run.",
+			),
+		)
+		cmd.Flags().IntVar(
+			&maxTotalFiles,
+			"max_total_files",
+			0,
+			utils.CombineStringsWithNewline(
+				"Stop downloading after this many files have been downloaded in this run, 0 means no limit.",
+				"The remaining files will be saved and downloaded first in the next run.",
+			),
+		)
+		cmd.Flags().StringArrayVar(
+			&extraHeaders,
+			"header",
+			[]string{},
+			utils.CombineStringsWithNewline(
+				"Add a header to every request in the format of \"Key: Value\", can be used multiple times.",
+				"Replaces any header of the same name set by the program or by the \"host_headers\" in the config file.",
+				"Useful when a website starts requiring a new header before the program has been updated.",
+			),
+		)
+		cmd.Flags().StringVar(
+			&onlyFileType,
+			"only",
+			"",
+			utils.CombineStringsWithNewline(
+				"Only download one type of file, either \"images\", \"videos\", \"attachments\", or \"text\".",
+				"The type is based on the file extension where \"attachments\" are the files that are not images, videos, or text, e.g. .zip and .psd files,",
+				"and \"text\" includes .txt and .html files like the creators' announcements from Kemono Party.",
+				"Files without an extension in their URL or filename will always be downloaded as their type cannot be determined.",
+			),
+		)
+		cmd.Flags().Float64SliceVar(
+			&requestDelay,
+			"delay",
+			[]float64{},
+			utils.CombineStringsWithNewline(
+				"Random delay in seconds before each request in the format of \"min,max\", e.g. \"2,5\".",
+				"Increase it to slow down the program to avoid getting your account flagged or decrease it for websites with tolerant CDNs.",
+				fmt.Sprintf(
+					"Also replaces the default delay between retries of %d-%d seconds and the default delays between Pixiv's API calls.",
+					utils.MIN_RETRY_DELAY,
+					utils.MAX_RETRY_DELAY,
+				),
+			),
+		)
+		cmd.Flags().StringVar(
+			&minFreeSpace,
+			"min_free_space",
+			"1GB",
+			utils.CombineStringsWithNewline(
+				"Minimum free disk space to keep on the drive of the download directory, e.g. \"10GB\", or 0 to disable the check.",
+				"The free space is checked before starting the downloads and before each file using its size from the Content-Length header,",
+				"so the run can be stopped or paused based on the --on_low_disk_space flag instead of failing midway through a file.",
+			),
+		)
+		cmd.Flags().StringVar(
+			&onLowDiskSpace,
+			"on_low_disk_space",
+			LOW_DISK_SPACE_ABORT,
+			utils.CombineStringsWithNewline(
+				"What to do when the disk is about to run out of space, either \"abort\" or \"pause\".",
+				"\"abort\" stops the run and saves the remaining files to be downloaded first in the next run.",
+				"\"pause\" pauses the downloads of this run, but not of the other running instances of the program,",
+				"until you have freed up some space and run the \"resume\" command.",
+			),
+		)
+		cmd.Flags().StringVar(
+			&storageType,
+			"storage",
+			"",
+			utils.CombineStringsWithNewline(
+				fmt.Sprintf(
+					"Where to write the downloaded files to, either %s.",
+					strings.Join(storage.SUPPORTED_TYPES, ", "),
+				),
+				"Overrides the \"type\" in the \"storage\" section of the config file where the details of the backend are set,",
+				"e.g. the bucket of an S3-compatible service, the URL of a WebDAV server, or the host of an SFTP server. Defaults to \"local\" for the download directory.",
+			),
+		)
+		cmd.Flags().StringVar(
+			&mirrorRemote,
+			"mirror_remote",
+			"",
+			utils.CombineStringsWithNewline(
+				"rclone remote and path to copy the downloaded creator folders to after the run, e.g. \"b2:my-bucket/archive\".",
+				"Overrides the \"remote\" in the \"mirror\" section of the config file where the mode and extra rclone flags can also be set.",
+				"The creator folders are copied to the same path relative to the download directory on the remote.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			&noMirror,
+			"no_mirror",
+			false,
+			"Do not mirror the downloaded creator folders even if a mirror remote is set in the config file.",
+		)
+		cmd.Flags().BoolVar(
+			&waitForLock,
+			"wait_for_lock",
+			false,
+			utils.CombineStringsWithNewline(
+				"Wait for another run using the same download directory or website queue to finish instead of exiting.",
+				"Useful for scheduled runs, e.g. from cron, that may overlap with a long previous run.",
+			),
+		)
+		cmd.Flags().BoolVar(
+			&forceLock,
+			"force_lock",
+			false,
+			utils.CombineStringsWithNewline(
+				"Take over the lock on the download directory and website queue from another run.",
+				"Only use this if the other run is stuck, e.g. on another machine sharing the download directory.",
+				"Locks of runs that are no longer running are released automatically.",
+			),
+		)
+		if cmdInfo.hasCreatorPosts {
+			cmd.Flags().StringVar(
+				&postOrder,
+				"order",
+				configs.ORDER_DESC,
+				utils.CombineStringsWithNewline(
+					"Order to download the posts of a creator in, either \"desc\" (newest first) or \"asc\" (oldest first).",
+					"\"desc\" is suited for incremental runs while \"asc\" is suited for complete archives where consistent numbering matters.",
+				),
+			)
+			cmd.Flags().StringVar(
+				&postLayout,
+				"layout",
+				configs.LAYOUT_FLAT,
+				utils.CombineStringsWithNewline(
+					"Folder layout of the downloaded posts, either \"flat\" (<creator>/<post>) or \"date\" (<creator>/<YYYY>/<MM>/<post>).",
+					"\"date\" uses the publish date of the post which keeps the folders manageable for creators with thousands of posts.",
+					"Kemono Party posts are always grouped by service first, e.g. \"Kemono-Party/patreon/<creator>/<post>\",",
+					"so a creator imported from multiple services will have a separate folder for each service.",
+				),
+			)
+			cmd.Flags().IntVar(
+				&maxTitleLength,
+				"max_title_length",
+				0,
+				utils.CombineStringsWithNewline(
+					"Maximum number of characters of the post title in the post folder names, 0 means no limit.",
+					"Note that the post folder names will always be capped at 255 bytes due to file system limits.",
+				),
+			)
+			cmd.Flags().BoolVar(
+				&interactive,
+				"interactive",
+				false,
+				utils.CombineStringsWithNewline(
+					"List the resolved posts with their publish date, number of files, and estimated size before downloading them",
+					"so that you can pick which posts to download, e.g. \"1,3,5-8\", \"all\", or \"none\".",
+					"The size is estimated from the average size of the files downloaded in the past runs.",
+				),
+			)
+		}
+		if cmdInfo.hasAudio {
+			cmd.Flags().BoolVar(
+				&audioToFlac,
+				"audio_to_flac",
+				false,
+				utils.CombineStringsWithNewline(
+					"Convert the downloaded WAV audio attachments to FLAC files using FFmpeg which are lossless but around half the size.",
+					"The original WAV files are kept so that they will not be re-downloaded in the next run.",
+				),
+			)
+			cmd.Flags().BoolVar(
+				&tagAudio,
+				"tag_audio",
+				false,
+				utils.CombineStringsWithNewline(
+					"Write the tags of the downloaded MP3 and FLAC audio attachments using FFmpeg, e.g. for voice works.",
+					"The title will be the filename while the album and artist will be the post title and creator name.",
+				),
+			)
+			cmd.Flags().StringVar(
+				&audioFfmpegPath,
+				"ffmpeg_path",
+				"",
+				utils.CombineStringsWithNewline(
+					"Configure the path to the FFmpeg executable for the \"--audio_to_flac\" and \"--tag_audio\" flags.",
+					"Defaults to the \"ffmpeg_path\" in the \"tools\" section of the config file or FFmpeg in your PATH.",
+					"Download Link: "+utils.FFMPEG_DOWNLOAD_URL,
+				),
+			)
+		}
+		if cmdInfo.hasCreatorNames {
+			cmd.Flags().StringVar(
+				&onCreatorRename,
+				"on_creator_rename",
+				utils.CREATOR_RENAME_KEEP,
+				utils.CombineStringsWithNewline(
+					"What to do with a creator's folder when the creator changes their name, either \"keep\", \"rename\", or \"symlink\".",
+					"\"keep\" continues saving the posts in the original folder, \"rename\" renames the folder to the new name,",
+					"and \"symlink\" renames the folder and leaves a symlink with the original name.",
+					"The names of each creator are tracked by their ID in the app data folder to prevent duplicate creator folders.",
+				),
+			)
+		}
+		if cmdInfo.canStopEarly {
+			cmd.Flags().IntVar(
+				&stopAfterSeen,
+				"stop_after_seen",
+				0,
+				utils.CombineStringsWithNewline(
+					"Stop getting the posts of a creator once this many already downloaded posts in a row are encountered, 0 means never stop.",
+					"Useful for incremental runs on creators with many posts. Ignored when --order is \"asc\".",
+				),
+			)
+		}
 		if cmdInfo.gdriveApiKeyVar != nil {
 			cmd.Flags().StringVar(
 				cmdInfo.gdriveApiKeyVar,
@@ -116,10 +1018,56 @@ func init() {
 				"",
 				utils.CombineStringsWithNewline(
 					"Google Drive API key to use for downloading gdrive files.",
+					"Multiple API keys can be separated by commas where the next API key will be used once the",
+					"current API key has exceeded its quota, i.e. a 403 error with the \"userRateLimitExceeded\" reason.",
 					"Guide: https://github.com/KJHJason/Cultured-Downloader/blob/main/doc/google_api_key_guide.md",
 				),
 			)
 		}
+		if cmdInfo.gdriveWorkersVar != nil {
+			cmd.Flags().IntVar(
+				cmdInfo.gdriveWorkersVar,
+				"gdrive_workers",
+				utils.GDRIVE_MAX_CONCURRENT_DOWNLOADS,
+				utils.CombineStringsWithNewline(
+					"Maximum number of Google Drive files to download concurrently.",
+					"Lower this value if you are getting rate limited by Google Drive.",
+				),
+			)
+		}
+		if cmdInfo.gdriveFilters != nil {
+			cmd.Flags().IntVar(
+				&cmdInfo.gdriveFilters.maxDepth,
+				"gdrive_max_depth",
+				gdrive.NO_MAX_DEPTH,
+				utils.CombineStringsWithNewline(
+					"Maximum number of subfolder levels to recurse into for Google Drive folder links.",
+					"0 will only download the files directly inside the linked folder and -1 means no limit.",
+				),
+			)
+			cmd.Flags().StringSliceVar(
+				&cmdInfo.gdriveFilters.fileTypes,
+				"gdrive_file_types",
+				[]string{},
+				utils.CombineStringsWithNewline(
+					"Only download files with these extensions from Google Drive folder links.",
+					"For multiple file types, separate them with a comma.",
+					"Example: \"zip,psd,mp4\" (without the quotes)",
+				),
+			)
+			cmd.Flags().StringVar(
+				&cmdInfo.gdriveFilters.minSize,
+				"gdrive_min_size",
+				"",
+				"Skip files from Google Drive folder links that are smaller than this size, e.g. \"1MB\".",
+			)
+			cmd.Flags().StringVar(
+				&cmdInfo.gdriveFilters.maxSize,
+				"gdrive_max_size",
+				"",
+				"Skip files from Google Drive folder links that are larger than this size, e.g. \"2GB\".",
+			)
+		}
 		if cmdInfo.logUrlsVar != nil {
 			cmd.Flags().BoolVarP(
 				cmdInfo.logUrlsVar,
@@ -131,6 +1079,35 @@ func init() {
 					"Note that not all URLs are logged, only URLs to external file hosting providers like MEGA, Google Drive, etc. are logged.",
 				),
 			)
+			cmd.Flags().BoolVar(
+				&dlFileHosts,
+				"dl_file_hosts",
+				false,
+				utils.CombineStringsWithNewline(
+					"Download the files from the GigaFile (gigafile.nu and xgf.nu) and firestorage links in the posts.",
+					fmt.Sprintf(
+						"The files are saved in the %q folder of the post while the links that cannot be downloaded are logged to %s.",
+						utils.FILE_HOSTS_FOLDER,
+						utils.OTHER_LINKS_FILENAME,
+					),
+				),
+			)
+			cmd.Flags().BoolVar(
+				&dlTwitterMedia,
+				"dl_twitter_media",
+				false,
+				utils.CombineStringsWithNewline(
+					fmt.Sprintf(
+						"Download the media of the Twitter/X links in the posts to the %q folder of the post.",
+						utils.TWITTER_FOLDER,
+					),
+					"Requires the \"gallery_dl_path\" or \"yt_dlp_path\" in the \"tools\" section of the config file where gallery-dl is preferred.",
+					fmt.Sprintf(
+						"The detected Twitter/X links are always recorded in %s in the download directory.",
+						twitter.TWITTER_LINKS_FILENAME,
+					),
+				),
+			)
 		}
 		RootCmd.AddCommand(cmd)
 	}
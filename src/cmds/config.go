@@ -0,0 +1,374 @@
+package cmds
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/mirror"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Keeps track of the number of problems found by the config doctor command
+type doctorReport struct {
+	errCount  int
+	warnCount int
+}
+
+func (r *doctorReport) ok(format string, args ...interface{}) {
+	color.Green("✓ "+format, args...)
+}
+
+func (r *doctorReport) warn(format string, args ...interface{}) {
+	r.warnCount++
+	color.Yellow("! "+format, args...)
+}
+
+func (r *doctorReport) fail(format string, args ...interface{}) {
+	r.errCount++
+	color.Red("✗ "+format, args...)
+}
+
+var (
+	doctorUserAgent string
+	configCmd       = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the config file",
+		Long:  "Commands for managing the config file that is saved in the program's app data folder.",
+	}
+	configDoctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose problems with the config file",
+		Long: utils.CombineStringsWithNewline(
+			"Validates the config file and checks that the download directory is writable,",
+			"the saved cookie files can be parsed, the Google Drive API key works, and FFmpeg can be found.",
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			report := &doctorReport{}
+			config := checkConfigSchema(report)
+			if config != nil {
+				checkDownloadDir(report, config)
+				checkConfigCookieFiles(report, config)
+				checkGdriveConfig(report, config)
+				checkHostLimits(report, config)
+				checkBandwidthLimits(report, config)
+				checkUserAgents(report, config)
+				checkProgress(report, config)
+				checkExternalTools(report, config)
+				checkMirror(report, config)
+				checkStorage(report, config)
+			}
+			checkFfmpeg(report, config)
+
+			fmt.Println()
+			if report.errCount > 0 {
+				utils.ExitWithErrorf(1, "Found %d problem(s) and %d warning(s)", report.errCount, report.warnCount)
+			}
+			color.Green("Found no problems and %d warning(s)", report.warnCount)
+		},
+	}
+)
+
+// Parses the config file and reports any unknown fields or invalid values
+//
+// Returns nil if the config file could not be parsed.
+func checkConfigSchema(report *doctorReport) *utils.ConfigFile {
+	configFilePath := utils.GetConfigFilePath()
+	if !utils.PathExists(configFilePath) {
+		report.warn("No config file found at %s, run the \"init\" command to create one", configFilePath)
+		return &utils.ConfigFile{}
+	}
+
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		report.fail("Failed to read the config file at %s: %v", configFilePath, err)
+		return nil
+	}
+
+	config := &utils.ConfigFile{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		// try again without the strict check to see if it is only due to unknown fields
+		config = &utils.ConfigFile{}
+		if lenientErr := json.Unmarshal(data, config); lenientErr != nil {
+			report.fail("The config file at %s is not valid JSON: %v", configFilePath, lenientErr)
+			return nil
+		}
+		report.warn("The config file contains an unknown field which will be ignored: %v", err)
+	} else {
+		report.ok("The config file at %s is valid", configFilePath)
+	}
+
+	if config.Language != "" && !i18n.IsSupported(config.Language) {
+		report.warn(
+			"Unsupported language %q in the config file, please use one of %s",
+			config.Language,
+			strings.Join(i18n.SUPPORTED_LANGUAGES, ", "),
+		)
+	}
+	return config
+}
+
+func checkDownloadDir(report *doctorReport, config *utils.ConfigFile) {
+	if config.DownloadDir == "" {
+		report.warn("No download directory set, the current working directory will be used")
+		return
+	}
+	if !utils.PathExists(config.DownloadDir) {
+		report.fail("The download directory at %s does not exist", config.DownloadDir)
+		return
+	}
+
+	testFile, err := os.CreateTemp(config.DownloadDir, ".cultured-downloader-*")
+	if err != nil {
+		report.fail("The download directory at %s is not writable: %v", config.DownloadDir, err)
+		return
+	}
+	testFile.Close()
+	os.Remove(testFile.Name())
+	report.ok("The download directory at %s is writable", config.DownloadDir)
+}
+
+func checkConfigCookieFiles(report *doctorReport, config *utils.ConfigFile) {
+	for _, siteCookie := range siteCookieFiles {
+		cookieFile, ok := config.CookieFiles[siteCookie.site]
+		if !ok {
+			continue
+		}
+
+		siteName := utils.GetReadableSiteStr(siteCookie.site)
+		cookies, err := utils.ParseNetscapeCookieFile(cookieFile, "", siteCookie.site)
+		if err != nil {
+			report.fail("%s cookie file: %v", siteName, err)
+			continue
+		}
+		if err := utils.CheckCookieExpiry(cookies, siteCookie.site, utils.COOKIE_EXPIRY_WARNING_DAYS, true); err != nil {
+			report.warn("%s cookie file: %v", siteName, err)
+			continue
+		}
+		report.ok("%s cookie file at %s can be parsed", siteName, cookieFile)
+	}
+
+	for site := range config.CookieFiles {
+		if !utils.SliceContains(loginSites, site) {
+			report.warn("Unknown website %q in the config file's cookie files", site)
+		}
+	}
+}
+
+func checkGdriveConfig(report *doctorReport, config *utils.ConfigFile) {
+	gdriveConfig := config.Gdrive
+	if gdriveConfig == nil {
+		return
+	}
+
+	for _, apiKey := range gdriveConfig.GetApiKeys() {
+		isValid, err := gdrive.ApiKeyIsValid(apiKey, doctorUserAgent)
+		if err != nil {
+			report.warn("Could not verify the Google Drive API key %s: %v", gdrive.MaskApiKey(apiKey), err)
+		} else if !isValid {
+			report.fail("The Google Drive API key %s is invalid", gdrive.MaskApiKey(apiKey))
+		} else {
+			report.ok("The Google Drive API key %s is valid", gdrive.MaskApiKey(apiKey))
+		}
+	}
+
+	if gdriveConfig.Workers < 0 {
+		report.fail("The number of Google Drive workers must be at least 1 but got %d", gdriveConfig.Workers)
+	}
+	filters := &gdrive.Filters{
+		FileExts: gdriveConfig.FileTypes,
+		MaxDepth: gdrive.NO_MAX_DEPTH,
+	}
+	if gdriveConfig.MaxDepth != nil {
+		filters.MaxDepth = *gdriveConfig.MaxDepth
+	}
+
+	var err error
+	if gdriveConfig.MinSize != "" {
+		if filters.MinFileSize, err = utils.ParseFileSizeStr(gdriveConfig.MinSize); err != nil {
+			report.fail("Invalid Google Drive minimum file size: %v", err)
+		}
+	}
+	if gdriveConfig.MaxSize != "" {
+		if filters.MaxFileSize, err = utils.ParseFileSizeStr(gdriveConfig.MaxSize); err != nil {
+			report.fail("Invalid Google Drive maximum file size: %v", err)
+		}
+	}
+	if err := filters.ValidateArgs(); err != nil {
+		report.fail("Invalid Google Drive filters: %v", err)
+	}
+}
+
+func checkHostLimits(report *doctorReport, config *utils.ConfigFile) {
+	for host, limit := range config.HostLimits {
+		if limit == nil {
+			report.warn("No limits set for %s in the config file's host limits", host)
+			continue
+		}
+		if err := limit.Validate(host); err != nil {
+			report.fail("Invalid host limits: %v", err)
+			continue
+		}
+		report.ok("Host limits for %s are valid", host)
+	}
+}
+
+func checkBandwidthLimits(report *doctorReport, config *utils.ConfigFile) {
+	for _, limit := range config.BandwidthLimits {
+		if limit == nil {
+			continue
+		}
+		if _, _, _, err := limit.Parse(); err != nil {
+			report.fail("Invalid bandwidth limit: %v", err)
+			continue
+		}
+		report.ok("Bandwidth limit of %s/s from %s to %s is valid", limit.Limit, limit.Start, limit.End)
+	}
+}
+
+func checkUserAgents(report *doctorReport, config *utils.ConfigFile) {
+	for site := range config.SiteUserAgents {
+		if !utils.SliceContains(loginSites, site) {
+			report.warn("Unknown website %q in the config file's site user agents", site)
+		}
+	}
+	if len(config.UserAgents) > 0 {
+		report.ok("Rotating through %d user agent(s) from the config file", len(config.UserAgents))
+	}
+}
+
+func checkProgress(report *doctorReport, config *utils.ConfigFile) {
+	if config.Progress == nil {
+		return
+	}
+	if err := spinner.ValidateTheme(config.Progress); err != nil {
+		report.fail("Invalid progress settings: %v", err)
+		return
+	}
+	report.ok("The progress settings are valid")
+}
+
+func checkExternalTools(report *doctorReport, config *utils.ConfigFile) {
+	if config.Tools == nil {
+		return
+	}
+	for _, tool := range []struct {
+		key  string
+		path string
+	}{
+		{"7z_path", config.Tools.SevenZipPath},
+		{"unrar_path", config.Tools.UnrarPath},
+		{"gallery_dl_path", config.Tools.GalleryDlPath},
+		{"yt_dlp_path", config.Tools.YtDlpPath},
+	} {
+		if tool.path == "" {
+			continue
+		}
+		if _, err := exec.LookPath(tool.path); err != nil {
+			report.fail("The config file's tools %s %q was not found", tool.key, tool.path)
+			continue
+		}
+		report.ok("The config file's tools %s was found at %s", tool.key, tool.path)
+	}
+}
+
+func checkMirror(report *doctorReport, config *utils.ConfigFile) {
+	if config.Mirror == nil {
+		return
+	}
+	if err := config.Mirror.Validate(); err != nil {
+		report.fail("%v", err)
+		return
+	}
+
+	rclonePath, err := mirror.FindRclone("")
+	if err != nil {
+		report.fail("%v", err)
+		return
+	}
+
+	// remotes without a colon are local paths which do not have to be configured in rclone
+	remoteName, _, isRemote := strings.Cut(config.Mirror.Remote, ":")
+	if isRemote {
+		output, err := exec.Command(rclonePath, "listremotes").Output()
+		if err != nil {
+			report.fail("Failed to list the remotes configured in rclone at %s: %v", rclonePath, err)
+			return
+		}
+		if !utils.SliceContains(strings.Fields(string(output)), remoteName+":") {
+			report.fail("The mirror remote %q is not configured in rclone, run \"rclone config\" to add it", remoteName)
+			return
+		}
+	}
+	report.ok("Mirroring to %s with rclone at %s", config.Mirror.Remote, rclonePath)
+}
+
+func checkStorage(report *doctorReport, config *utils.ConfigFile) {
+	if config.Storage == nil {
+		return
+	}
+	backend, err := storage.NewBackend(config.Storage)
+	if err != nil {
+		report.fail("%v", err)
+		return
+	}
+	report.ok("The downloaded files will be written to %s", backend.Name())
+}
+
+func checkFfmpeg(report *doctorReport, config *utils.ConfigFile) {
+	configuredPath := ""
+	if config != nil && config.Tools != nil {
+		configuredPath = config.Tools.FfmpegPath
+	}
+	ffmpegPath, err := utils.FindFfmpeg(configuredPath)
+	if err != nil {
+		if configuredPath != "" {
+			report.fail("FFmpeg was not found at the config file's tools ffmpeg_path %q", configuredPath)
+			return
+		}
+		report.warn(
+			"FFmpeg was not found in your PATH, it is required for converting Pixiv ugoira and audio attachments, download link: %s",
+			utils.FFMPEG_DOWNLOAD_URL,
+		)
+		return
+	}
+
+	version, err := utils.GetFfmpegVersion(ffmpegPath)
+	if err != nil {
+		report.fail("%v", err)
+		return
+	}
+	if !utils.IsFfmpegVersionSupported(version) {
+		report.warn(
+			"FFmpeg %s at %s is older than version %d and may not work as expected",
+			version,
+			ffmpegPath,
+			utils.MIN_FFMPEG_MAJOR_VERSION,
+		)
+		return
+	}
+	report.ok("FFmpeg %s was found at %s", version, ffmpegPath)
+}
+
+func init() {
+	configDoctorCmd.Flags().StringVarP(
+		&doctorUserAgent,
+		"user_agent",
+		"u",
+		"",
+		"Set a custom User-Agent header to use when checking the Google Drive API key.",
+	)
+	configCmd.AddCommand(configDoctorCmd)
+	RootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,91 @@
+package cmds
+
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/dlsite"
+	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dlsiteDlTextFile string
+	dlsiteCookieFile string
+	dlsiteSession    string
+	dlsiteWorkIds    []string
+	dlsiteOverwrite  bool
+	dlsiteUserAgent  string
+	dlsiteCmd        = &cobra.Command{
+		Use:   "dlsite",
+		Short: "Download from DLsite Play",
+		Long: utils.CombineStringsWithNewline(
+			"Supports downloads of your purchased works on DLsite via DLsite Play.",
+			"Note that DLsite Play serves files optimised for viewing in the browser instead of the original files.",
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			if dlsiteDlTextFile != "" {
+				workIds := textparser.ParseDlsiteTextFile(dlsiteDlTextFile)
+				dlsiteWorkIds = append(dlsiteWorkIds, workIds...)
+			}
+
+			dlsiteConfig := &configs.Config{
+				OverwriteFiles:   dlsiteOverwrite,
+				UserAgent:        dlsiteUserAgent,
+				ChecksumManifest: checksumManifest,
+				ExtractArchives:  extractArchives,
+				VerifyImages:     verifyImages,
+				Only:             onlyFileType,
+			}
+			dlsiteConfig.ValidateOnly()
+			setDownloadQuota(dlsiteConfig, utils.DLSITE)
+			setMetrics(utils.DLSITE)
+			setDownloadLog()
+			setRequestDelay()
+			setExtraHeaders()
+			dlsiteDl := &dlsite.DlsiteDl{
+				WorkIds: dlsiteWorkIds,
+			}
+			dlsiteDl.ValidateArgs()
+
+			dlsiteDlOptions := &dlsite.DlsiteDlOptions{
+				Configs:         dlsiteConfig,
+				SessionCookieId: dlsiteSession,
+			}
+			if cookieFile := getCookieFile(dlsiteCookieFile, dlsiteSession, utils.DLSITE); cookieFile != "" {
+				dlsiteDlOptions.SessionCookies = parseCookieFile(
+					cookieFile,
+					dlsiteSession,
+					utils.DLSITE,
+				)
+			}
+			dlsiteDlOptions.ValidateArgs(dlsiteUserAgent)
+
+			utils.PrintWarningMsg()
+			dlsite.DlsiteDownloadProcess(
+				dlsiteDl,
+				dlsiteDlOptions,
+			)
+		},
+	}
+)
+
+func init() {
+	dlsiteCmd.Flags().StringVarP(
+		&dlsiteSession,
+		"session",
+		"s",
+		"",
+		"Your \"play_session\" cookie value from play.dlsite.com to use for the requests to DLsite Play.",
+	)
+	dlsiteCmd.Flags().StringSliceVar(
+		&dlsiteWorkIds,
+		"work_id",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"DLsite work ID(s) to download from your purchases.",
+			"Leave blank to download all of your purchased works.",
+			"For multiple IDs, separate them with a comma.",
+			"Example: \"RJ123456,RJ01012345\" (without the quotes)",
+		),
+	)
+}
@@ -14,15 +14,21 @@ var (
 	fantiaCookieFile       string
 	fantiaSession          string
 	fantiaFanclubIds       []string
+	fantiaFanclubUrls      []string
 	fantiaPageNums         []string
 	fantiaPostIds          []string
+	fantiaPostUrls         []string
 	fantiaDlGdrive         bool
 	fantiaGdriveApiKey     string
+	fantiaGdriveWorkers    int
+	fantiaGdriveFilters    gdriveFilterFlags
 	fantiaDlThumbnails     bool
 	fantiaDlImages         bool
 	fantiaDlAttachments    bool
 	fantiaOverwrite        bool
 	fantiaAutoSolveCaptcha bool
+	fantiaPostFilter       string
+	fantiaDlFollowing      bool
 	fantiaLogUrls          bool
 	fantiaUserAgent        string
 	fantiaCmd              = &cobra.Command{
@@ -41,24 +47,47 @@ var (
 			}
 
 			fantiaConfig := &configs.Config{
-				OverwriteFiles: fantiaOverwrite,
-				UserAgent:      fantiaUserAgent,
-				LogUrls:        fantiaLogUrls,
+				OverwriteFiles:   fantiaOverwrite,
+				UserAgent:        fantiaUserAgent,
+				LogUrls:          fantiaLogUrls,
+				ChecksumManifest: checksumManifest,
+				ExtractArchives:  extractArchives,
+				VerifyImages:     verifyImages,
+				Order:            postOrder,
+				Layout:           postLayout,
+				OnCreatorRename:  onCreatorRename,
+				MaxTitleLength:   maxTitleLength,
+				StopAfterSeen:    stopAfterSeen,
+				Only:             onlyFileType,
 			}
+			fantiaConfig.ValidateOrder()
+			fantiaConfig.ValidateLayout()
+			fantiaConfig.ValidateOnCreatorRename()
+			fantiaConfig.ValidateOnly()
+			setDownloadQuota(fantiaConfig, utils.FANTIA)
+			setMetrics(utils.FANTIA)
+			setDownloadLog()
+			setPostSelector(fantiaConfig)
+			setAudioHandler(fantiaConfig)
+			setRequestDelay()
+			setExtraHeaders()
 
 			var gdriveClient *gdrive.GDrive
 			if fantiaGdriveApiKey != "" {
 				gdriveClient = gdrive.GetNewGDrive(
-					fantiaGdriveApiKey,
+					gdrive.SplitApiKeys(fantiaGdriveApiKey),
 					fantiaConfig,
-					utils.MAX_CONCURRENT_DOWNLOADS,
+					fantiaGdriveWorkers,
+					fantiaGdriveFilters.getFilters(),
 				)
 			}
 
 			fantiaDl := &fantia.FantiaDl{
 				FanclubIds:      fantiaFanclubIds,
+				FanclubUrls:     fantiaFanclubUrls,
 				FanclubPageNums: fantiaPageNums,
 				PostIds:         fantiaPostIds,
+				PostUrls:        fantiaPostUrls,
 			}
 			fantiaDl.ValidateArgs()
 
@@ -68,25 +97,18 @@ var (
 				DlAttachments:    fantiaDlAttachments,
 				DlGdrive:         fantiaDlGdrive,
 				AutoSolveCaptcha: fantiaAutoSolveCaptcha,
+				PostFilter:       fantiaPostFilter,
+				DlFollowing:      fantiaDlFollowing,
 				GdriveClient:     gdriveClient,
 				Configs:          fantiaConfig,
 				SessionCookieId:  fantiaSession,
 			}
-			if fantiaCookieFile != "" {
-				cookies, err := utils.ParseNetscapeCookieFile(
-					fantiaCookieFile,
+			if cookieFile := getCookieFile(fantiaCookieFile, fantiaSession, utils.FANTIA); cookieFile != "" {
+				fantiaDlOptions.SessionCookies = parseCookieFile(
+					cookieFile,
 					fantiaSession,
 					utils.FANTIA,
 				)
-				if err != nil {
-					utils.LogError(
-						err,
-						"",
-						true,
-						utils.ERROR,
-					)
-				}
-				fantiaDlOptions.SessionCookies = cookies
 			}
 
 			err := fantiaDlOptions.ValidateArgs(fantiaUserAgent)
@@ -126,13 +148,23 @@ func init() {
 			mutlipleIdsMsg,
 		),
 	)
+	fantiaCmd.Flags().StringSliceVar(
+		&fantiaFanclubUrls,
+		"fanclub_url",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Fantia Fanclub URL(s) to download from.",
+			"Multiple URLs can be supplied by separating them with a comma.",
+			"Example: \"https://fantia.jp/fanclubs/1234,https://fantia.jp/fanclubs/5678\" (without the quotes)",
+		),
+	)
 	fantiaCmd.Flags().StringSliceVar(
 		&fantiaPageNums,
 		"page_num",
 		[]string{},
 		utils.CombineStringsWithNewline(
-			"Min and max page numbers to search for corresponding to the order of the supplied Fantia Fanclub ID(s).",
-			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Min and max page numbers to search for corresponding to the order of the supplied Fantia Fanclub ID(s) followed by the Fanclub URL(s).",
+			"Format: \"num\", \"minNum-maxNum\", \"minNum-\" (to the last page), \"-maxNum\" (the first pages), or \"all\" to download all pages",
 			"Leave blank to download all pages from each Fantia Fanclub.",
 		),
 	)
@@ -145,6 +177,16 @@ func init() {
 			mutlipleIdsMsg,
 		),
 	)
+	fantiaCmd.Flags().StringSliceVar(
+		&fantiaPostUrls,
+		"post_url",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Fantia post URL(s) to download.",
+			"Multiple URLs can be supplied by separating them with a comma.",
+			"Example: \"https://fantia.jp/posts/1234,https://fantia.jp/posts/5678\" (without the quotes)",
+		),
+	)
 	fantiaCmd.Flags().BoolVarP(
 		&fantiaDlGdrive,
 		"dl_gdrive",
@@ -173,6 +215,26 @@ func init() {
 		true,
 		"Whether to download the attachments of a post on Fantia.",
 	)
+	fantiaCmd.Flags().StringVar(
+		&fantiaPostFilter,
+		"post_filter",
+		fantia.POST_FILTER_ALL,
+		utils.CombineStringsWithNewline(
+			"Filter the posts to download based on the fanclub plan required to view their contents.",
+			"Options: \"all\" to download everything, \"free\" to only download contents that do not require a plan,",
+			"or \"paid\" to only download contents from paid plans that you are subscribed to.",
+			"Posts without any matching contents will be skipped entirely.",
+		),
+	)
+	fantiaCmd.Flags().BoolVar(
+		&fantiaDlFollowing,
+		"dl_following",
+		false,
+		utils.CombineStringsWithNewline(
+			"Download from all the fanclubs that you have joined (paid and free plans) or are following on Fantia.",
+			"Requires your session cookie and all pages of each fanclub will be downloaded with the other flags like \"--post_filter\" applied.",
+		),
+	)
 	fantiaCmd.Flags().BoolVarP(
 		&fantiaAutoSolveCaptcha,
 		"auto_solve_recaptcha",
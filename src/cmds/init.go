@@ -0,0 +1,197 @@
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Interactively set up the config file",
+	Long: utils.CombineStringsWithNewline(
+		"Walks you through setting up the download directory, cookie files for each website,",
+		"and the default Google Drive settings before saving them to the config file.",
+		"Leave any prompt blank to keep its current value.",
+	),
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := utils.LoadConfigFile()
+		if err != nil {
+			color.Red(err.Error())
+			color.Yellow("A new config file will be created instead.")
+			config = &utils.ConfigFile{Language: "en"}
+		}
+
+		reader := bufio.NewReader(os.Stdin)
+		promptDownloadDir(reader, config)
+		promptCookieFiles(reader, config)
+		promptGdriveConfig(reader, config)
+
+		if err := utils.SaveConfigFile(config); err != nil {
+			utils.LogError(err, "", true, utils.ERROR)
+		}
+		color.Green("\nSaved the config file to %s", utils.GetConfigFilePath())
+	},
+}
+
+// Prints the message and returns the trimmed user input
+//
+// If the user did not input anything, the current value will be returned.
+func prompt(reader *bufio.Reader, msg, currentVal string) string {
+	if currentVal != "" {
+		msg = fmt.Sprintf("%s [%s]", msg, currentVal)
+	}
+	fmt.Print(color.YellowString(msg + ": "))
+
+	input, err := reader.ReadString('\n')
+	if err != nil && input == "" {
+		return currentVal
+	}
+	if input = strings.TrimSpace(input); input == "" {
+		return currentVal
+	}
+	return input
+}
+
+func promptDownloadDir(reader *bufio.Reader, config *utils.ConfigFile) {
+	color.Cyan("\n[1/3] Download directory")
+	for {
+		downloadDir := prompt(reader, "Directory to download the files to", config.DownloadDir)
+		if downloadDir == "" {
+			return
+		}
+		if err := os.MkdirAll(downloadDir, 0755); err != nil {
+			color.Red("Failed to create the directory: %v", err)
+			continue
+		}
+		if absPath, err := filepath.Abs(downloadDir); err == nil {
+			downloadDir = absPath
+		}
+		config.DownloadDir = downloadDir
+		return
+	}
+}
+
+func promptCookieFiles(reader *bufio.Reader, config *utils.ConfigFile) {
+	color.Cyan("\n[2/3] Cookie files")
+	color.Cyan("You can also use the login command to log in via the browser instead, e.g. \"login fantia\".")
+	if config.CookieFiles == nil {
+		config.CookieFiles = make(map[string]string)
+	}
+
+	for _, site := range loginSites {
+		siteName := utils.GetReadableSiteStr(site)
+		for {
+			cookieFile := prompt(
+				reader,
+				fmt.Sprintf("Path to your %s cookie file", siteName),
+				config.CookieFiles[site],
+			)
+			if cookieFile == "" {
+				break
+			}
+
+			if _, err := utils.ParseNetscapeCookieFile(cookieFile, "", site); err != nil {
+				color.Red(err.Error())
+				continue
+			}
+			if absPath, err := filepath.Abs(cookieFile); err == nil {
+				cookieFile = absPath
+			}
+			config.CookieFiles[site] = cookieFile
+			break
+		}
+	}
+}
+
+func promptGdriveConfig(reader *bufio.Reader, config *utils.ConfigFile) {
+	color.Cyan("\n[3/3] Google Drive")
+	color.Cyan("Guide: https://github.com/KJHJason/Cultured-Downloader/blob/main/doc/google_api_key_guide.md")
+	gdriveConfig := config.Gdrive
+	if gdriveConfig == nil {
+		gdriveConfig = &utils.GdriveConfig{}
+	}
+
+	gdriveConfig.ApiKey = prompt(reader, "Google Drive API key", gdriveConfig.ApiKey)
+	if gdriveConfig.ApiKey == "" {
+		// the other GDrive settings are not used without an API key
+		return
+	}
+
+	if gdriveConfig.Workers == 0 {
+		gdriveConfig.Workers = utils.GDRIVE_MAX_CONCURRENT_DOWNLOADS
+	}
+	gdriveConfig.Workers = promptInt(
+		reader,
+		"Maximum number of Google Drive files to download concurrently",
+		gdriveConfig.Workers,
+		1,
+	)
+
+	maxDepth := gdrive.NO_MAX_DEPTH
+	if gdriveConfig.MaxDepth != nil {
+		maxDepth = *gdriveConfig.MaxDepth
+	}
+	maxDepth = promptInt(
+		reader,
+		"Maximum subfolder levels to recurse into (-1 for no limit)",
+		maxDepth,
+		gdrive.NO_MAX_DEPTH,
+	)
+	gdriveConfig.MaxDepth = &maxDepth
+
+	fileTypes := prompt(
+		reader,
+		"File extensions to download, separated by a comma (e.g. zip,psd)",
+		strings.Join(gdriveConfig.FileTypes, ","),
+	)
+	gdriveConfig.FileTypes = nil
+	for _, fileType := range strings.Split(fileTypes, ",") {
+		if fileType = strings.TrimSpace(fileType); fileType != "" {
+			gdriveConfig.FileTypes = append(gdriveConfig.FileTypes, fileType)
+		}
+	}
+
+	gdriveConfig.MinSize = promptFileSize(reader, "Minimum file size (e.g. 1MB)", gdriveConfig.MinSize)
+	gdriveConfig.MaxSize = promptFileSize(reader, "Maximum file size (e.g. 2GB)", gdriveConfig.MaxSize)
+	config.Gdrive = gdriveConfig
+}
+
+// Prompts for an integer that is at least minVal
+func promptInt(reader *bufio.Reader, msg string, currentVal, minVal int) int {
+	for {
+		input := prompt(reader, msg, strconv.Itoa(currentVal))
+		num, err := strconv.Atoi(input)
+		if err != nil || num < minVal {
+			color.Red("Please enter a number that is at least %d", minVal)
+			continue
+		}
+		return num
+	}
+}
+
+func promptFileSize(reader *bufio.Reader, msg, currentVal string) string {
+	for {
+		input := prompt(reader, msg, currentVal)
+		if input == "" {
+			return ""
+		}
+		if _, err := utils.ParseFileSizeStr(input); err != nil {
+			color.Red(err.Error())
+			continue
+		}
+		return input
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(initCmd)
+}
@@ -18,10 +18,15 @@ var (
 	kemonoPostUrls      []string
 	kemonoDlGdrive      bool
 	kemonoGdriveApiKey  string
+	kemonoGdriveWorkers int
+	kemonoGdriveFilters gdriveFilterFlags
 	kemonoDlAttachments bool
 	kemonoOverwrite     bool
 	kemonoLogUrls       bool
 	kemonoDlFav         bool
+	kemonoSearch        string
+	kemonoDlExtras      bool
+	kemonoDlLinked      bool
 	kemonoUserAgent     string
 	kemonoCmd           = &cobra.Command{
 		Use:   "kemono",
@@ -29,16 +34,34 @@ var (
 		Long:  "Supports downloads from creators and posts on Kemono Party.",
 		Run: func(cmd *cobra.Command, args []string) {
 			kemonoConfig := &configs.Config{
-				OverwriteFiles: kemonoOverwrite,
-				UserAgent:      kemonoUserAgent,
-				LogUrls:        kemonoLogUrls,
+				OverwriteFiles:   kemonoOverwrite,
+				UserAgent:        kemonoUserAgent,
+				LogUrls:          kemonoLogUrls,
+				ChecksumManifest: checksumManifest,
+				ExtractArchives:  extractArchives,
+				VerifyImages:     verifyImages,
+				Order:            postOrder,
+				Layout:           postLayout,
+				MaxTitleLength:   maxTitleLength,
+				StopAfterSeen:    stopAfterSeen,
+				Only:             onlyFileType,
 			}
+			kemonoConfig.ValidateOrder()
+			kemonoConfig.ValidateLayout()
+			kemonoConfig.ValidateOnly()
+			setDownloadQuota(kemonoConfig, utils.KEMONO)
+			setMetrics(utils.KEMONO)
+			setDownloadLog()
+			setPostSelector(kemonoConfig)
+			setRequestDelay()
+			setExtraHeaders()
 			var gdriveClient *gdrive.GDrive
 			if kemonoGdriveApiKey != "" {
 				gdriveClient = gdrive.GetNewGDrive(
-					kemonoGdriveApiKey,
+					gdrive.SplitApiKeys(kemonoGdriveApiKey),
 					kemonoConfig,
-					utils.MAX_CONCURRENT_DOWNLOADS,
+					kemonoGdriveWorkers,
+					kemonoGdriveFilters.getFilters(),
 				)
 			}
 
@@ -55,27 +78,21 @@ var (
 			kemonoDl.ValidateArgs()
 
 			kemonoDlOptions := &kemono.KemonoDlOptions{
-				DlAttachments:   kemonoDlAttachments,
-				DlGdrive:        kemonoDlGdrive,
-				Configs:         kemonoConfig,
-				SessionCookieId: kemonoSession,
-				GdriveClient:    gdriveClient,
+				DlAttachments:    kemonoDlAttachments,
+				DlGdrive:         kemonoDlGdrive,
+				Search:           kemonoSearch,
+				DlCreatorExtras:  kemonoDlExtras,
+				DlLinkedCreators: kemonoDlLinked,
+				Configs:          kemonoConfig,
+				SessionCookieId:  kemonoSession,
+				GdriveClient:     gdriveClient,
 			}
-			if kemonoCookieFile != "" {
-				cookies, err := utils.ParseNetscapeCookieFile(
-					kemonoCookieFile,
+			if cookieFile := getCookieFile(kemonoCookieFile, kemonoSession, utils.KEMONO); cookieFile != "" {
+				kemonoDlOptions.SessionCookies = parseCookieFile(
+					cookieFile,
 					kemonoSession,
 					utils.KEMONO,
 				)
-				if err != nil {
-					utils.LogError(
-						err,
-						"",
-						true,
-						utils.ERROR,
-					)
-				}
-				kemonoDlOptions.SessionCookies = cookies
 			}
 
 			kemonoDlOptions.ValidateArgs(kemonoUserAgent)
@@ -120,10 +137,39 @@ func init() {
 		[]string{},
 		utils.CombineStringsWithNewline(
 			"Min and max page numbers to search for corresponding to the order of the supplied Kemono Party creator URL(s).",
-			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Format: \"num\", \"minNum-maxNum\", \"minNum-\" (to the last page), \"-maxNum\" (the first pages), or \"all\" to download all pages",
 			"Leave blank to download all pages from each creator on Kemono Party.",
 		),
 	)
+	kemonoCmd.Flags().StringVar(
+		&kemonoSearch,
+		"search",
+		"",
+		utils.CombineStringsWithNewline(
+			"Only download the creators' posts that match the search query, e.g. \"PSD\" or \"high res\".",
+			"The query must be at least 3 characters long and does not apply to the supplied post URL(s).",
+		),
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlExtras,
+		"dl_creator_extras",
+		false,
+		utils.CombineStringsWithNewline(
+			"Whether to also save the announcements and fancards of the creators alongside their posts.",
+			"Announcements are saved as HTML files in the \"announcements\" folder and fancards in the \"fancards\" folder of the creator.",
+			"Note that only Pixiv Fanbox creators have fancards.",
+		),
+	)
+	kemonoCmd.Flags().BoolVar(
+		&kemonoDlLinked,
+		"dl_linked_accounts",
+		false,
+		utils.CombineStringsWithNewline(
+			"Whether to also download from the linked accounts of the supplied creators on other services,",
+			"e.g. the creator's Pixiv Fanbox and Gumroad accounts when supplying their Patreon account.",
+			"The page numbers of the creator, if any, will also be used for their linked accounts.",
+		),
+	)
 	kemonoCmd.Flags().StringSliceVar(
 		&kemonoPostUrls,
 		"post_url",
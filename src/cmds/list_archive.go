@@ -0,0 +1,68 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var listArchiveCmd = &cobra.Command{
+	Use:   "list_archive <archive>...",
+	Short: "List the files in archives without extracting them",
+	Long: utils.CombineStringsWithNewline(
+		"Lists the files and folders in the given zip, rar, or 7z archives without extracting them",
+		"to preview what the \"--extract_archives\" flag of the download commands will extract.",
+	),
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		hasErr := false
+		for idx, archive := range args {
+			if idx > 0 {
+				fmt.Println()
+			}
+
+			entries, err := utils.ListArchiveFiles(context.Background(), archive)
+			if err != nil {
+				color.Red(err.Error())
+				hasErr = true
+				continue
+			}
+			printArchiveEntries(archive, entries)
+		}
+		if hasErr {
+			utils.Exit(utils.EXIT_INPUT_ERROR)
+		}
+	},
+}
+
+func printArchiveEntries(archive string, entries []*utils.ArchiveEntry) {
+	fileCount := 0
+	var totalSize int64
+	for _, entry := range entries {
+		if !entry.IsDir {
+			fileCount++
+			totalSize += entry.Size
+		}
+	}
+
+	color.Cyan("%s: %d file(s), %s", archive, fileCount, utils.FormatFileSize(totalSize))
+	for _, entry := range entries {
+		size := utils.FormatFileSize(entry.Size)
+		if entry.IsDir {
+			size = "<dir>"
+		}
+		fmt.Printf(
+			"%s  %10s  %s\n",
+			entry.ModTime.Local().Format("2006-01-02 15:04"),
+			size,
+			entry.Name,
+		)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(listArchiveCmd)
+}
@@ -0,0 +1,106 @@
+package cmds
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	loginSites = []string{
+		utils.FANTIA,
+		utils.PIXIV_FANBOX,
+		utils.PIXIV,
+		utils.KEMONO,
+		utils.DLSITE,
+	}
+	loginCookieFile string
+	loginTimeout    int
+	loginUserAgent  string
+	loginCmd        = &cobra.Command{
+		Use:   fmt.Sprintf("login {%s}", strings.Join(loginSites, "|")),
+		Short: "Log in to a website via the browser to save its session cookie",
+		Long: utils.CombineStringsWithNewline(
+			"Opens a browser window for you to log in to the website.",
+			"Once you have logged in, the session cookie will be saved to a cookie file that is only readable by you",
+			"and the cookie file will be saved to the config file to be used in future runs.",
+			"Requires Google Chrome or Chromium to be installed.",
+		),
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: loginSites,
+		Run: func(cmd *cobra.Command, args []string) {
+			website := args[0]
+			siteName := utils.GetReadableSiteStr(website)
+			if loginTimeout <= 0 {
+				utils.ExitWithErrorf(
+					utils.EXIT_INPUT_ERROR,
+					"error %d: timeout must be at least 1 minute",
+					utils.INPUT_ERROR,
+				)
+			}
+			if loginUserAgent == "" {
+				loginUserAgent = utils.GetDefaultUserAgent(website)
+			}
+			if loginCookieFile == "" {
+				loginCookieFile = filepath.Join(utils.APP_PATH, "cookies", website+"_cookies.txt")
+			}
+
+			color.Yellow(
+				"Please log in to %s in the opened browser window within %d minute(s)...",
+				siteName,
+				loginTimeout,
+			)
+			cookie, err := api.Login(
+				website,
+				loginUserAgent,
+				time.Duration(loginTimeout)*time.Minute,
+			)
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+
+			err = utils.WriteNetscapeCookieFile(loginCookieFile, []*http.Cookie{cookie})
+			if err == nil {
+				err = utils.SetConfigCookieFiles(map[string]string{website: loginCookieFile})
+			}
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			color.Green("Logged in to %s and saved the session cookie to %s", siteName, loginCookieFile)
+		},
+	}
+)
+
+func init() {
+	loginCmd.Flags().StringVarP(
+		&loginCookieFile,
+		"cookie_file",
+		"c",
+		"",
+		utils.CombineStringsWithNewline(
+			"File path to save the session cookie to.",
+			"Defaults to a cookie file in the program's app data folder.",
+		),
+	)
+	loginCmd.Flags().IntVar(
+		&loginTimeout,
+		"timeout",
+		5,
+		"Number of minutes to wait for you to log in before giving up.",
+	)
+	loginCmd.Flags().StringVarP(
+		&loginUserAgent,
+		"user_agent",
+		"u",
+		"",
+		"Set a custom User-Agent header to use for the browser and when verifying the session cookie.",
+	)
+	RootCmd.AddCommand(loginCmd)
+}
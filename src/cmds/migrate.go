@@ -0,0 +1,272 @@
+package cmds
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Config file of the Python/GUI edition of Cultured Downloader
+type guiConfigFile struct {
+	DownloadDir string `json:"download_directory"`
+	Language    string `json:"language"`
+
+	// the settings used for the downloads
+	GdriveApiKey  string   `json:"gdrive_api_key"`
+	GdriveApiKeys []string `json:"gdrive_api_keys"`
+	UserAgent     string   `json:"user_agent"`
+	FfmpegPath    string   `json:"ffmpeg_path"`
+}
+
+// Cookies saved by the Python/GUI edition which are in the format used by Selenium
+type guiCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expiry   float64 `json:"expiry"`
+	Secure   bool    `json:"secure"`
+	HttpOnly bool    `json:"httpOnly"`
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <folder>",
+	Short: "Import the data from the Python/GUI edition of Cultured Downloader",
+	Long: utils.CombineStringsWithNewline(
+		"Imports the download directory, language, Google Drive API keys, User-Agent header, FFmpeg path and saved cookies",
+		"from the folder where the Python/GUI edition of Cultured Downloader stores its data into this program's config file.",
+		"Note that encrypted data cannot be imported, please disable encryption in the GUI edition before migrating.",
+	),
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		guiDataDir := args[0]
+		if !utils.PathExists(guiDataDir) {
+			utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "error %d: folder at %s does not exist", utils.INPUT_ERROR, guiDataDir)
+		}
+
+		config, err := utils.LoadConfigFile()
+		if err != nil {
+			utils.LogError(err, "", true, utils.ERROR)
+		}
+
+		migrated := migrateGuiConfig(guiDataDir, config)
+		migrated += migrateGuiCookies(guiDataDir, config)
+		if migrated == 0 {
+			color.Yellow("Found nothing to migrate in %s", guiDataDir)
+			return
+		}
+
+		if err := utils.SaveConfigFile(config); err != nil {
+			utils.LogError(err, "", true, utils.ERROR)
+		}
+		color.Green("Migrated %d setting(s) to %s", migrated, utils.GetConfigFilePath())
+	},
+}
+
+// Migrates the settings in the GUI edition's config file and returns the number of settings migrated
+func migrateGuiConfig(guiDataDir string, config *utils.ConfigFile) int {
+	var guiConfigPath string
+	for _, path := range []string{
+		filepath.Join(guiDataDir, "config.json"),
+		filepath.Join(guiDataDir, "configs", "config.json"),
+	} {
+		if utils.PathExists(path) {
+			guiConfigPath = path
+			break
+		}
+	}
+	if guiConfigPath == "" {
+		return 0
+	}
+
+	data, err := os.ReadFile(guiConfigPath)
+	if err != nil {
+		color.Red("Failed to read %s: %v", guiConfigPath, err)
+		return 0
+	}
+	var guiConfig guiConfigFile
+	if err := json.Unmarshal(data, &guiConfig); err != nil {
+		color.Red("Failed to parse %s: %v", guiConfigPath, err)
+		return 0
+	}
+
+	migrated := 0
+	if guiConfig.DownloadDir != "" {
+		if utils.PathExists(guiConfig.DownloadDir) {
+			config.DownloadDir = guiConfig.DownloadDir
+			color.Green("✓ Download directory: %s", guiConfig.DownloadDir)
+			migrated++
+		} else {
+			color.Yellow("! Skipped the download directory as %s does not exist", guiConfig.DownloadDir)
+		}
+	}
+	if guiConfig.Language != "" {
+		if i18n.IsSupported(guiConfig.Language) {
+			config.Language = guiConfig.Language
+			color.Green("✓ Language: %s", guiConfig.Language)
+			migrated++
+		} else {
+			color.Yellow("! Skipped the language %q as it is not supported", guiConfig.Language)
+		}
+	}
+	migrated += migrateGuiDownloadSettings(&guiConfig, config)
+	return migrated
+}
+
+// Migrates the GUI edition's settings used for the downloads and returns the number of settings migrated
+func migrateGuiDownloadSettings(guiConfig *guiConfigFile, config *utils.ConfigFile) int {
+	migrated := 0
+	if guiConfig.GdriveApiKey != "" {
+		if gdrive.API_KEY_REGEX.MatchString(guiConfig.GdriveApiKey) {
+			if config.Gdrive == nil {
+				config.Gdrive = &utils.GdriveConfig{}
+			}
+			config.Gdrive.ApiKey = guiConfig.GdriveApiKey
+			color.Green("✓ Google Drive API key")
+			migrated++
+		} else {
+			color.Yellow("! Skipped the Google Drive API key as it is encrypted or invalid")
+		}
+	}
+
+	var apiKeys []string
+	for _, apiKey := range guiConfig.GdriveApiKeys {
+		if !gdrive.API_KEY_REGEX.MatchString(apiKey) {
+			color.Yellow("! Skipped a Google Drive API key as it is encrypted or invalid")
+			continue
+		}
+		if apiKey != guiConfig.GdriveApiKey && !utils.SliceContains(apiKeys, apiKey) {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+	if len(apiKeys) > 0 {
+		if config.Gdrive == nil {
+			config.Gdrive = &utils.GdriveConfig{}
+		}
+		for _, apiKey := range apiKeys {
+			if !utils.SliceContains(config.Gdrive.ApiKeys, apiKey) {
+				config.Gdrive.ApiKeys = append(config.Gdrive.ApiKeys, apiKey)
+			}
+		}
+		color.Green("✓ %d Google Drive API key(s) to rotate through", len(apiKeys))
+		migrated++
+	}
+
+	if guiConfig.UserAgent != "" {
+		config.UserAgent = guiConfig.UserAgent
+		color.Green("✓ User-Agent header: %s", guiConfig.UserAgent)
+		migrated++
+	}
+	if guiConfig.FfmpegPath != "" {
+		if utils.PathExists(guiConfig.FfmpegPath) {
+			if config.Tools == nil {
+				config.Tools = &utils.ToolsConfig{}
+			}
+			config.Tools.FfmpegPath = guiConfig.FfmpegPath
+			color.Green("✓ FFmpeg path: %s", guiConfig.FfmpegPath)
+			migrated++
+		} else {
+			color.Yellow("! Skipped the FFmpeg path as %s does not exist", guiConfig.FfmpegPath)
+		}
+	}
+	return migrated
+}
+
+// Returns the session cookies of the website from a cookie file saved by the GUI edition
+func parseGuiCookieFile(filePath, website string) []*http.Cookie {
+	if cookies, err := utils.ParseNetscapeCookieFile(filePath, "", website); err == nil {
+		return cookies
+	}
+	if filepath.Ext(filePath) != ".json" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil
+	}
+	var guiCookies []guiCookie
+	if err := json.Unmarshal(data, &guiCookies); err != nil {
+		return nil
+	}
+
+	sessionCookieInfo := utils.GetSessionCookieInfo(website)
+	var cookies []*http.Cookie
+	for _, guiCookie := range guiCookies {
+		if guiCookie.Name != sessionCookieInfo.Name || guiCookie.Value == "" {
+			continue
+		}
+		if !strings.HasSuffix(guiCookie.Domain, strings.TrimPrefix(sessionCookieInfo.Domain, ".")) {
+			continue // same cookie name but from another website
+		}
+
+		cookie := &http.Cookie{
+			Name:     guiCookie.Name,
+			Value:    guiCookie.Value,
+			Domain:   guiCookie.Domain,
+			Path:     guiCookie.Path,
+			Secure:   guiCookie.Secure,
+			HttpOnly: guiCookie.HttpOnly,
+			SameSite: sessionCookieInfo.SameSite,
+		}
+		if guiCookie.Expiry > 0 {
+			cookie.Expires = time.Unix(int64(guiCookie.Expiry), 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+// Migrates the cookies saved by the GUI edition and returns the number of websites migrated
+func migrateGuiCookies(guiDataDir string, config *utils.ConfigFile) int {
+	foundCookies := make(map[string][]*http.Cookie)
+	filepath.WalkDir(guiDataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".json" && ext != ".txt" {
+			return nil
+		}
+
+		for _, site := range loginSites {
+			if _, ok := foundCookies[site]; ok {
+				continue
+			}
+			if cookies := parseGuiCookieFile(path, site); len(cookies) > 0 {
+				foundCookies[site] = cookies
+			}
+		}
+		return nil
+	})
+
+	if config.CookieFiles == nil {
+		config.CookieFiles = make(map[string]string)
+	}
+	migrated := 0
+	for site, cookies := range foundCookies {
+		siteName := utils.GetReadableSiteStr(site)
+		cookieFile := filepath.Join(utils.APP_PATH, "cookies", site+"_cookies.txt")
+		if err := utils.WriteNetscapeCookieFile(cookieFile, cookies); err != nil {
+			color.Red("✗ %s cookies: %v", siteName, err)
+			continue
+		}
+		config.CookieFiles[site] = cookieFile
+		color.Green("✓ %s cookies", siteName)
+		migrated++
+	}
+	return migrated
+}
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,44 @@
+package cmds
+
+import (
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pauseCmd = &cobra.Command{
+		Use:   "pause",
+		Short: "Pause the downloads of all running instances of the program",
+		Long: utils.CombineStringsWithNewline(
+			"Creates the pause file which makes every running instance of the program stop starting new downloads",
+			"and stop reading the files that are being downloaded until the \"resume\" command is run,",
+			"letting you temporarily free up your bandwidth without stopping a long download.",
+			"Note that some websites may close the connection of a file that has been paused for too long,",
+			"in which case the file will be downloaded again in the next run.",
+		),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := utils.SetPaused(true); err != nil {
+				utils.ExitWithErrorf(utils.EXIT_ERROR, "%v", err)
+			}
+			color.Green("Paused the downloads, run the \"resume\" command to resume them")
+		},
+	}
+	resumeCmd = &cobra.Command{
+		Use:   "resume",
+		Short: "Resume the downloads paused by the \"pause\" command or by running out of disk space",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := utils.SetPaused(false); err != nil {
+				utils.ExitWithErrorf(utils.EXIT_ERROR, "%v", err)
+			}
+			color.Green("Resumed the downloads")
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(pauseCmd)
+	RootCmd.AddCommand(resumeCmd)
+}
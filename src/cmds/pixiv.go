@@ -2,7 +2,6 @@ package cmds
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixiv"
@@ -13,7 +12,6 @@ import (
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
 	"github.com/spf13/cobra"
-	"github.com/fatih/color"
 )
 
 var (
@@ -31,6 +29,12 @@ var (
 	pixivIllustratorPageNums []string
 	pixivTagNames            []string
 	pixivPageNums            []string
+	pixivSeriesIds           []string
+	pixivNovelSeriesIds      []string
+	pixivNovelEpub           bool
+	pixivMinBookmarks        int
+	pixivDlFollowing         bool
+	pixivImageSize           string
 	pixivSortOrder           string
 	pixivSearchMode          string
 	pixivRatingMode          string
@@ -56,15 +60,35 @@ var (
 			}
 
 			pixivConfig := &configs.Config{
-				FfmpegPath:     pixivFfmpegPath,
-				OverwriteFiles: pixivOverwrite,
-				UserAgent:      pixivUserAgent,
+				FfmpegPath:       pixivFfmpegPath,
+				OverwriteFiles:   pixivOverwrite,
+				UserAgent:        pixivUserAgent,
+				ChecksumManifest: checksumManifest,
+				ExtractArchives:  extractArchives,
+				VerifyImages:     verifyImages,
+				Order:            postOrder,
+				StopAfterSeen:    stopAfterSeen,
+				Layout:           postLayout,
+				OnCreatorRename:  onCreatorRename,
+				MaxTitleLength:   maxTitleLength,
+				Only:             onlyFileType,
 			}
+			pixivConfig.ValidateOrder()
+			pixivConfig.ValidateLayout()
+			pixivConfig.ValidateOnCreatorRename()
+			pixivConfig.ValidateOnly()
+			setDownloadQuota(pixivConfig, utils.PIXIV)
+			setMetrics(utils.PIXIV)
+			setDownloadLog()
+			setPostSelector(pixivConfig)
+			setRequestDelay()
+			setExtraHeaders()
 			pixivConfig.ValidateFfmpeg()
 
 			if pixivDlTextFile != "" {
-				artworkIds, illustratorInfoSlice, tagInfoSlice := textparser.ParsePixivTextFile(pixivDlTextFile)
+				artworkIds, illustratorInfoSlice, tagInfoSlice, seriesIds := textparser.ParsePixivTextFile(pixivDlTextFile)
 				pixivArtworkIds = append(pixivArtworkIds, artworkIds...)
+				pixivSeriesIds = append(pixivSeriesIds, seriesIds...)
 
 				for _, illustratorInfo := range illustratorInfoSlice {
 					pixivIllustratorIds = append(pixivIllustratorIds, illustratorInfo.ArtistId)
@@ -82,6 +106,8 @@ var (
 				IllustratorPageNums: pixivIllustratorPageNums,
 				TagNames:            pixivTagNames,
 				TagNamesPageNums:    pixivPageNums,
+				SeriesIds:           pixivSeriesIds,
+				NovelSeriesIds:      pixivNovelSeriesIds,
 			}
 			pixivDl.ValidateArgs()
 
@@ -93,25 +119,28 @@ var (
 			pixivUgoiraOptions.ValidateArgs()
 
 			if pixivRefreshToken == "" && pixivSession == "" {
-				color.Red("You must provide a refresh token or session cookie ID to download from Pixiv.")
-				os.Exit(1)
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "You must provide a refresh token or session cookie ID to download from Pixiv.")
 			}
 
 			utils.PrintWarningMsg()
 			if pixivRefreshToken != "" {
 				pixivDlOptions := &pixivmobile.PixivMobileDlOptions{
-					SortOrder:       pixivSortOrder,
-					SearchMode:      pixivSearchMode,
-					RatingMode:      pixivRatingMode,
-					ArtworkType:     pixivArtworkType,
-					Configs:         pixivConfig,
-					RefreshToken:    pixivRefreshToken,
+					SortOrder:    pixivSortOrder,
+					SearchMode:   pixivSearchMode,
+					RatingMode:   pixivRatingMode,
+					ArtworkType:  pixivArtworkType,
+					MinBookmarks: pixivMinBookmarks,
+					DlFollowing:  pixivDlFollowing,
+					ImageSize:    pixivImageSize,
+					Configs:      pixivConfig,
+					RefreshToken: pixivRefreshToken,
 				}
 				pixivDlOptions.ValidateArgs(pixivUserAgent)
 				pixiv.PixivMobileDownloadProcess(
 					pixivDl,
 					pixivDlOptions,
 					pixivUgoiraOptions,
+					pixivNovelEpub,
 				)
 			} else {
 				pixivDlOptions := &pixivweb.PixivWebDlOptions{
@@ -119,30 +148,25 @@ var (
 					SearchMode:      pixivSearchMode,
 					RatingMode:      pixivRatingMode,
 					ArtworkType:     pixivArtworkType,
+					MinBookmarks:    pixivMinBookmarks,
+					DlFollowing:     pixivDlFollowing,
+					ImageSize:       pixivImageSize,
 					Configs:         pixivConfig,
 					SessionCookieId: pixivSession,
 				}
-				if pixivCookieFile != "" {
-					cookies, err := utils.ParseNetscapeCookieFile(
-						pixivCookieFile,
+				if cookieFile := getCookieFile(pixivCookieFile, pixivSession, utils.PIXIV); cookieFile != "" {
+					pixivDlOptions.SessionCookies = parseCookieFile(
+						cookieFile,
 						pixivSession,
 						utils.PIXIV,
 					)
-					if err != nil {
-						utils.LogError(
-							err,
-							"",
-							true,
-							utils.ERROR,
-						)
-					}
-					pixivDlOptions.SessionCookies = cookies
 				}
 				pixivDlOptions.ValidateArgs(pixivUserAgent)
 				pixiv.PixivWebDownloadProcess(
 					pixivDl,
 					pixivDlOptions,
 					pixivUgoiraOptions,
+					pixivNovelEpub,
 				)
 			}
 		},
@@ -154,10 +178,11 @@ func init() {
 	pixivCmd.Flags().StringVar(
 		&pixivFfmpegPath,
 		"ffmpeg_path",
-		"ffmpeg",
+		"",
 		utils.CombineStringsWithNewline(
 			"Configure the path to the FFmpeg executable.",
-			"Download Link: https://ffmpeg.org/download.html",
+			"Defaults to the \"ffmpeg_path\" in the \"tools\" section of the config file or FFmpeg in your PATH.",
+			"Download Link: "+utils.FFMPEG_DOWNLOAD_URL,
 		),
 	)
 	pixivCmd.Flags().BoolVar(
@@ -240,6 +265,8 @@ func init() {
 		utils.CombineStringsWithNewline(
 			"Illustrator ID(s) to download.",
 			mutlipleIdsMsg,
+			"If the crawl of an illustrator's gallery with the \"--refresh_token\" flag gets interrupted,",
+			"the next run will resume from the last crawled page if it is within 7 days.",
 		),
 	)
 	pixivCmd.Flags().StringSliceVar(
@@ -248,10 +275,40 @@ func init() {
 		[]string{},
 		utils.CombineStringsWithNewline(
 			"Min and max page numbers to search for corresponding to the order of the supplied illustrator ID(s).",
-			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Format: \"num\", \"minNum-maxNum\", \"minNum-\" (to the last page), \"-maxNum\" (the first pages), or \"all\" to download all pages",
 			"Leave blank to download all pages from each illustrator.",
 		),
 	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivSeriesIds,
+		"series_id",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Manga series ID(s) to download, e.g. 123456 from https://www.pixiv.net/user/1234/series/123456.",
+			"All works in the series will be downloaded in order with their filenames prefixed by their index in the series.",
+			mutlipleIdsMsg,
+		),
+	)
+	pixivCmd.Flags().StringSliceVar(
+		&pixivNovelSeriesIds,
+		"novel_series_id",
+		[]string{},
+		utils.CombineStringsWithNewline(
+			"Novel series ID(s) to download, e.g. 123456 from https://www.pixiv.net/novel/series/123456.",
+			"Each chapter will be saved as a text file prefixed by its index in the series unless the \"--novel_epub\" flag is set.",
+			"Series restricted to logged in users, e.g. R-18, can only be downloaded with the \"--session\" flag.",
+			mutlipleIdsMsg,
+		),
+	)
+	pixivCmd.Flags().BoolVar(
+		&pixivNovelEpub,
+		"novel_epub",
+		false,
+		utils.CombineStringsWithNewline(
+			"Compile all the chapters of each novel series into a single EPUB with the cover, table of contents, and author of the series",
+			"instead of saving each chapter as a text file. The EPUB is only compiled again when chapters have been added to or removed from the series.",
+		),
+	)
 	pixivCmd.Flags().StringSliceVar(
 		&pixivTagNames,
 		"tag_name",
@@ -268,7 +325,7 @@ func init() {
 		[]string{},
 		utils.CombineStringsWithNewline(
 			"Min and max page numbers to search for corresponding to the order of the supplied tag name(s).",
-			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Format: \"num\", \"minNum-maxNum\", \"minNum-\" (to the last page), \"-maxNum\" (the first pages), or \"all\" to download all pages",
 			"Leave blank to search all pages for each tag name.",
 		),
 	)
@@ -322,4 +379,36 @@ func init() {
 			"- If you're using the \"-pixiv_refresh_token\" flag and are downloading by tag names, only \"all\" is supported.",
 		),
 	)
+	pixivCmd.Flags().IntVar(
+		&pixivMinBookmarks,
+		"min_bookmarks",
+		0,
+		utils.CombineStringsWithNewline(
+			"Only download artworks with at least this number of bookmarks.",
+			"Applies to the artworks from illustrators, tag searches, and series, as well as the supplied artwork IDs.",
+		),
+	)
+	pixivCmd.Flags().StringVar(
+		&pixivImageSize,
+		"image_size",
+		"original",
+		utils.CombineStringsWithNewline(
+			"Image Size Options:",
+			"- original: Download the illustrations and manga in their original size",
+			"- large: Download the resized images that are at most 1200px wide or tall",
+			"- medium: Download the resized images that are at most 540px wide or tall",
+			"Notes:",
+			"- The resized images are usually JPEG files.",
+			"- Ugoira will always be downloaded in their original size.",
+		),
+	)
+	pixivCmd.Flags().BoolVar(
+		&pixivDlFollowing,
+		"dl_following",
+		false,
+		utils.CombineStringsWithNewline(
+			"Download the artworks of all the illustrators that you are following on Pixiv, including private follows.",
+			"All pages of each illustrator will be downloaded with the other flags like \"--artwork_type\" applied.",
+		),
+	)
 }
@@ -1,11 +1,13 @@
 package cmds
 
 import (
+	"fmt"
+
 	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox"
+	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +22,11 @@ var (
 	fanboxDlImages       bool
 	fanboxDlAttachments  bool
 	fanboxDlGdrive       bool
+	fanboxDlFollowing    bool
+	fanboxMarkdown       bool
 	fanboxGdriveApiKey   string
+	fanboxGdriveWorkers  int
+	fanboxGdriveFilters  gdriveFilterFlags
 	fanboxOverwriteFiles bool
 	fanboxLogUrls        bool
 	fanboxUserAgent      string
@@ -30,16 +36,35 @@ var (
 		Long:  "Supports downloads from Pixiv Fanbox creators and individual posts.",
 		Run: func(cmd *cobra.Command, args []string) {
 			pixivFanboxConfig := &configs.Config{
-				OverwriteFiles: fanboxOverwriteFiles,
-				UserAgent:      fanboxUserAgent,
-				LogUrls:        fanboxLogUrls,
+				OverwriteFiles:   fanboxOverwriteFiles,
+				UserAgent:        fanboxUserAgent,
+				LogUrls:          fanboxLogUrls,
+				ChecksumManifest: checksumManifest,
+				ExtractArchives:  extractArchives,
+				VerifyImages:     verifyImages,
+				Order:            postOrder,
+				Layout:           postLayout,
+				MaxTitleLength:   maxTitleLength,
+				StopAfterSeen:    stopAfterSeen,
+				Only:             onlyFileType,
 			}
+			pixivFanboxConfig.ValidateOrder()
+			pixivFanboxConfig.ValidateLayout()
+			pixivFanboxConfig.ValidateOnly()
+			setDownloadQuota(pixivFanboxConfig, utils.PIXIV_FANBOX)
+			setMetrics(utils.PIXIV_FANBOX)
+			setDownloadLog()
+			setPostSelector(pixivFanboxConfig)
+			setAudioHandler(pixivFanboxConfig)
+			setRequestDelay()
+			setExtraHeaders()
 			var gdriveClient *gdrive.GDrive
 			if fanboxGdriveApiKey != "" {
 				gdriveClient = gdrive.GetNewGDrive(
-					fanboxGdriveApiKey,
+					gdrive.SplitApiKeys(fanboxGdriveApiKey),
 					pixivFanboxConfig,
-					utils.MAX_CONCURRENT_DOWNLOADS,
+					fanboxGdriveWorkers,
+					fanboxGdriveFilters.getFilters(),
 				)
 			}
 
@@ -66,23 +91,16 @@ var (
 				Configs:         pixivFanboxConfig,
 				GdriveClient:    gdriveClient,
 				DlGdrive:        fanboxDlGdrive,
+				DlFollowing:     fanboxDlFollowing,
+				Markdown:        fanboxMarkdown,
 				SessionCookieId: fanboxSession,
 			}
-			if fanboxCookieFile != "" {
-				cookies, err := utils.ParseNetscapeCookieFile(
-					fanboxCookieFile,
+			if cookieFile := getCookieFile(fanboxCookieFile, fanboxSession, utils.PIXIV_FANBOX); cookieFile != "" {
+				pixivFanboxDlOptions.SessionCookies = parseCookieFile(
+					cookieFile,
 					fanboxSession,
 					utils.PIXIV_FANBOX,
 				)
-				if err != nil {
-					utils.LogError(
-						err,
-						"",
-						true,
-						utils.ERROR,
-					)
-				}
-				pixivFanboxDlOptions.SessionCookies = cookies
 			}
 			pixivFanboxDlOptions.ValidateArgs(fanboxUserAgent)
 
@@ -119,7 +137,7 @@ func init() {
 		[]string{},
 		utils.CombineStringsWithNewline(
 			"Min and max page numbers to search for corresponding to the order of the supplied Pixiv Fanbox creator ID(s).",
-			"Format: \"num\", \"minNum-maxNum\", or \"\" to download all pages",
+			"Format: \"num\", \"minNum-maxNum\", \"minNum-\" (to the last page), \"-maxNum\" (the first pages), or \"all\" to download all pages",
 			"Leave blank to download all pages from each creator.",
 		),
 	)
@@ -160,4 +178,25 @@ func init() {
 		true,
 		"Whether to download the Google Drive links of a Pixiv Fanbox post.",
 	)
+	pixivFanboxCmd.Flags().BoolVar(
+		&fanboxDlFollowing,
+		"dl_following",
+		false,
+		utils.CombineStringsWithNewline(
+			"Download from all the creators that you are supporting or following on Pixiv Fanbox.",
+			"Requires your session cookie and all pages of each creator will be downloaded.",
+		),
+	)
+	pixivFanboxCmd.Flags().BoolVar(
+		&fanboxMarkdown,
+		"markdown",
+		false,
+		utils.CombineStringsWithNewline(
+			fmt.Sprintf(
+				"Write each article post as Markdown to %s in the post folder to read it offline.",
+				pixivfanbox.ARTICLE_MARKDOWN_FILENAME,
+			),
+			"The images and attachments are linked to the downloaded files or to their URLs if they are not downloaded.",
+		),
+	)
 }
@@ -2,36 +2,245 @@ package cmds
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/filehost"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/systemd"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
+type siteCookieFile struct {
+	site       string
+	cookieFile string
+}
+
 var (
-	downloadPath string
+	// Cookie files for each website that can be supplied in a single run.
+	// Can also be saved to the config file for future runs.
+	siteCookieFiles = []*siteCookieFile{
+		{site: utils.FANTIA},
+		{site: utils.PIXIV_FANBOX},
+		{site: utils.PIXIV},
+		{site: utils.KEMONO},
+		{site: utils.DLSITE},
+	}
+	downloadPath    string
+	debugDump       = &utils.DebugDump{}
+	debugDumpMaxAge int
+	tracePath       string
+	warcDir         string
+	ipVersion       int
+	connectTimeout  int
+	readTimeout     int
+	minSpeed        string
+	minSpeedTime    int
+	breakerFailures int
+	breakerCooldown int
+	dnsServer       string
+	language        string
+	outputFormat    string
+	stopJsonOutput  func()
+	stopSystemd     func()
+
+	// runStatus is set by the download commands to exit with the outcome of the run
+	runStatus    *utils.RunStatus
+	releaseLocks = func() {}
 	RootCmd      = &cobra.Command{
-		Use:     "cultured-downloader-cli",
+		Use: "cultured-downloader-cli",
 		Version: fmt.Sprintf(
 			"%s by KJHJason\n%s", 
 			utils.VERSION, 
 			"GitHub Repo: https://github.com/KJHJason/Cultured-Downloader-CLI",
 		),
-		Short:   "Download images, videos, etc. from various websites like Fantia.",
-		Long:    "Cultured Downloader CLI is a command-line tool for downloading images, videos, etc. from various websites like Pixiv, Pixiv Fanbox, Fantia, and more.",
+		Short: "Download images, videos, etc. from various websites like Fantia.",
+		Long:  "Cultured Downloader CLI is a command-line tool for downloading images, videos, etc. from various websites like Pixiv, Pixiv Fanbox, Fantia, and more.",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			stopSystemd = systemd.Setup()
+			var err error
+			if stopJsonOutput, err = utils.SetOutputFormat(outputFormat); err != nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+			}
+			config, configErr := utils.LoadConfigFile()
+			if err := setLanguage(config); err != nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+			}
+
+			if debugDump.Dir != "" {
+				debugDump.MaxAge = time.Duration(debugDumpMaxAge) * 24 * time.Hour
+				if err := utils.SetDebugDump(debugDump); err != nil {
+					utils.LogError(err, "failed to delete old JSON responses in the debug dump folder", false, utils.ERROR)
+				}
+			}
+
+			if tracePath != "" {
+				if err := request.SetTraceFile(tracePath); err != nil {
+					utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+				}
+			}
+			if warcDir != "" {
+				if err := request.SetWarcDir(warcDir); err != nil {
+					utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+				}
+			}
+			if err := request.SetNetworkOptions(ipVersion, dnsServer); err != nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+			}
+			if err := request.SetTimeouts(time.Duration(connectTimeout)*time.Second, time.Duration(readTimeout)*time.Second); err != nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+			}
+			if err := setMinSpeed(); err != nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+			}
+			if err := request.SetCircuitBreaker(breakerFailures, time.Duration(breakerCooldown)*time.Second); err != nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+			}
+			filehost.SetEnabled(dlFileHosts)
+
+			// an invalid config file is not used and will be reported by the "config doctor" command
+			if configErr != nil && cmd != configDoctorCmd {
+				utils.LogError(configErr, "the config file was ignored and the default settings were used", false, utils.INFO)
+				color.Yellow(
+					"Warning: the config file at %s could not be loaded and will be ignored: %v\nRun the \"config doctor\" command for more details.",
+					utils.GetConfigFilePath(),
+					configErr,
+				)
+			}
+			if configErr == nil {
+				if len(config.HostLimits) > 0 && cmd != configDoctorCmd {
+					if err := request.SetHostLimits(config.HostLimits); err != nil {
+						utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v\nPlease fix the host limits in the config file at %s", err, utils.GetConfigFilePath())
+					}
+				}
+				if len(config.BandwidthLimits) > 0 && cmd != configDoctorCmd {
+					if err := request.SetBandwidthLimits(config.BandwidthLimits); err != nil {
+						utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v\nPlease fix the bandwidth limits in the config file at %s", err, utils.GetConfigFilePath())
+					}
+				}
+				if config.Progress != nil && cmd != configDoctorCmd {
+					if err := spinner.SetTheme(config.Progress); err != nil {
+						utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v\nPlease fix the progress settings in the config file at %s", err, utils.GetConfigFilePath())
+					}
+				}
+				utils.SetUserAgentRotation(config.UserAgents)
+				request.SetHostHeaders(config.HostHeaders)
+			}
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			request.ReportCircuitBreakers()
+
+			// the HTML pages are written first so that they are mirrored with the rest of the post folders
+			writeHtmlArchives()
+			writeFeed()
+
+			// mirror before releasing the locks so that another run cannot change the files while they are being copied
+			runMirror()
+			releaseLocks()
+			request.CloseWarc()
+			saveRunStats()
+			savePostDb()
+			stopJsonOutput()
+			stopSystemd()
+			if runStatus != nil {
+				if exitCode := runStatus.ExitCode(); exitCode != utils.EXIT_OK {
+					os.Exit(exitCode)
+				}
+			}
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if downloadPath != "" {
 				err := utils.SetDefaultDownloadPath(downloadPath)
 				if err != nil {
 					color.Red(err.Error())
 				} else {
-					color.Green("Download path set to: %s", downloadPath)
+					color.Green(i18n.T("Download path set to: %s"), downloadPath)
+				}
+			}
+
+			cookieFiles := make(map[string]string)
+			for _, siteCookie := range siteCookieFiles {
+				if siteCookie.cookieFile != "" {
+					cookieFiles[siteCookie.site] = siteCookie.cookieFile
+				}
+			}
+			if len(cookieFiles) > 0 {
+				err := utils.SetConfigCookieFiles(cookieFiles)
+				if err != nil {
+					color.Red(err.Error())
+				} else {
+					color.Green(i18n.T("Saved the cookie file(s) to the config file for future runs"))
 				}
 			}
 		},
 	}
 )
 
+// Sets the minimum download speed from the --min_speed and --min_speed_time flags
+func setMinSpeed() error {
+	if minSpeed == "" {
+		return request.SetMinSpeed(0, 0)
+	}
+	bytesPerSecond, err := utils.ParseFileSizeStr(minSpeed)
+	if err != nil {
+		return err
+	}
+	return request.SetMinSpeed(bytesPerSecond, time.Duration(minSpeedTime)*time.Second)
+}
+
+// Sets the language of the messages shown to the user
+//
+// The precedence is as follows:
+//  1. The language supplied to the "--lang" flag
+//  2. The language in the config file
+//  3. The LC_ALL, LC_MESSAGES, or LANG environment variables if they are set to a supported language
+//  4. English
+func setLanguage(config *utils.ConfigFile) error {
+	if language != "" {
+		if err := i18n.SetLanguage(language); err != nil {
+			return fmt.Errorf("error %d: %v", utils.INPUT_ERROR, err)
+		}
+		return nil
+	}
+
+	if config != nil && i18n.IsSupported(config.Language) {
+		// an unsupported language will be reported by the "config doctor" command instead
+		return i18n.SetLanguage(config.Language)
+	}
+	if envLanguage := i18n.DetectLanguage(); i18n.IsSupported(envLanguage) {
+		return i18n.SetLanguage(envLanguage)
+	}
+	return i18n.SetLanguage(i18n.EN)
+}
+
+// Returns the cookie file path to use for the given website
+//
+// The precedence is as follows:
+//  1. The cookie file supplied to the subcommand, e.g. "fantia --cookie_file"
+//  2. Nothing if a session cookie value was supplied
+//  3. The website's cookie file supplied to the root command, e.g. "--fantia_cookie_file"
+//  4. The website's cookie file saved in the config file
+func getCookieFile(cmdCookieFile, session, website string) string {
+	if cmdCookieFile != "" {
+		return cmdCookieFile
+	}
+	if session != "" {
+		return ""
+	}
+
+	for _, siteCookie := range siteCookieFiles {
+		if siteCookie.site == website && siteCookie.cookieFile != "" {
+			return siteCookie.cookieFile
+		}
+	}
+	return utils.GetConfigCookieFile(website)
+}
+
 func init() {
 	RootCmd.Flags().StringVarP(
 		&downloadPath,
@@ -46,5 +255,149 @@ func init() {
 			"had used the Cultured Downloader Python program, the program will automatically use the path you had set.",
 		),
 	)
+	for _, siteCookie := range siteCookieFiles {
+		RootCmd.PersistentFlags().StringVar(
+			&siteCookie.cookieFile,
+			siteCookie.site+"_cookie_file",
+			"",
+			utils.CombineStringsWithNewline(
+				fmt.Sprintf(
+					"Path to your %s cookie file, allowing one run to use separate cookie files for each website.",
+					utils.GetReadableSiteStr(siteCookie.site),
+				),
+				"If used without a command, the path will be saved to the config file for future runs.",
+			),
+		)
+	}
+	RootCmd.PersistentFlags().StringVar(
+		&debugDump.Dir,
+		"debug_dump_dir",
+		"",
+		utils.CombineStringsWithNewline(
+			"Save a copy of every JSON response from the APIs to this folder for debugging site API changes.",
+			"The filenames will contain the time and the request URL of the response.",
+		),
+	)
+	RootCmd.PersistentFlags().BoolVar(
+		&debugDump.Pretty,
+		"debug_dump_pretty",
+		true,
+		"Indent the JSON responses saved to the --debug_dump_dir folder.",
+	)
+	RootCmd.PersistentFlags().IntVar(
+		&debugDump.MaxFiles,
+		"debug_dump_max_files",
+		0,
+		"Maximum number of JSON responses to keep in the --debug_dump_dir folder, 0 means no limit.",
+	)
+	RootCmd.PersistentFlags().IntVar(
+		&debugDumpMaxAge,
+		"debug_dump_max_age",
+		0,
+		"Maximum age in days of the JSON responses to keep in the --debug_dump_dir folder, 0 means no limit.",
+	)
+	RootCmd.PersistentFlags().StringVar(
+		&tracePath,
+		"trace",
+		"",
+		utils.CombineStringsWithNewline(
+			"Path to a JSONL file to record every request and its response (headers, timing, and truncated text bodies) to.",
+			"Useful for debugging site API changes. Cookies and authorization headers will be redacted.",
+		),
+	)
+	RootCmd.PersistentFlags().StringVar(
+		&warcDir,
+		"warc",
+		"",
+		utils.CombineStringsWithNewline(
+			"Path to a folder to record every fetched page, API response, and downloaded file with its request into WARC files,",
+			"e.g. for preservation with web archiving tools like pywb or ReplayWeb.page. Cookies and authorization headers will be redacted.",
+		),
+	)
+	RootCmd.PersistentFlags().IntVar(
+		&ipVersion,
+		"ip_version",
+		0,
+		"Only connect to the websites over IPv4 (4) or IPv6 (6), 0 means both.",
+	)
+	RootCmd.PersistentFlags().StringVar(
+		&dnsServer,
+		"dns",
+		"",
+		utils.CombineStringsWithNewline(
+			"DNS server to resolve the websites' domains with instead of your system's DNS resolver, e.g. \"1.1.1.1\" or \"1.1.1.1:53\".",
+			"A DNS-over-HTTPS JSON API endpoint can also be used, e.g. \"https://cloudflare-dns.com/dns-query\".",
+			"Useful if your ISP blocks or poisons the DNS records of websites like pixiv.net.",
+		),
+	)
+	RootCmd.PersistentFlags().IntVar(
+		&connectTimeout,
+		"connect_timeout",
+		30,
+		"Seconds to wait for a connection to a website to be established, including the TLS handshake, 0 means no limit.",
+	)
+	RootCmd.PersistentFlags().IntVar(
+		&readTimeout,
+		"read_timeout",
+		60,
+		utils.CombineStringsWithNewline(
+			"Seconds to wait for the response or the next bytes of a download before the connection is considered stalled, 0 means no limit.",
+			"Stalled downloads are restarted instead of blocking the download forever, e.g. when a CDN connection hangs.",
+		),
+	)
+	RootCmd.PersistentFlags().StringVar(
+		&minSpeed,
+		"min_speed",
+		"",
+		utils.CombineStringsWithNewline(
+			"Minimum download speed per file, e.g. \"100KB\", below which a download is continued with a new connection",
+			"from the downloaded bytes, or restarted if the server does not support it. Disabled by default.",
+		),
+	)
+	RootCmd.PersistentFlags().IntVar(
+		&minSpeedTime,
+		"min_speed_time",
+		30,
+		"Seconds of downloading to measure the speed over for the --min_speed flag.",
+	)
+	RootCmd.PersistentFlags().IntVar(
+		&breakerFailures,
+		"breaker_failures",
+		10,
+		utils.CombineStringsWithNewline(
+			"Number of failed requests in a row to a host, i.e. connection errors and 5xx or 429 status codes,",
+			"after which the requests to the host are stopped for --breaker_cooldown seconds, 0 means never stop.",
+			"The files that were skipped are reported once per host at the end instead of an error for each file.",
+		),
+	)
+	RootCmd.PersistentFlags().IntVar(
+		&breakerCooldown,
+		"breaker_cooldown",
+		300,
+		"Seconds to stop the requests to a host for after --breaker_failures failed requests in a row before trying it again.",
+	)
+	RootCmd.PersistentFlags().StringVar(
+		&language,
+		"lang",
+		"",
+		utils.CombineStringsWithNewline(
+			fmt.Sprintf(
+				"Language of the messages shown to the user, %s.",
+				strings.Join(i18n.SUPPORTED_LANGUAGES, ", "),
+			),
+			"Otherwise, the language will be based on the \"language\" in the config file or the LANG environment variable.",
+		),
+	)
+	RootCmd.PersistentFlags().StringVar(
+		&outputFormat,
+		"output",
+		utils.OUTPUT_TEXT,
+		utils.CombineStringsWithNewline(
+			"Format of the errors shown to the user, either \"text\" or \"json\".",
+			"For \"json\", each error will be written to stderr as a JSON object on its own line with the",
+			"\"category\" (e.g. \"connection\" or \"captcha\"), \"code\", \"site\", \"post\", \"url\", and \"message\" fields,",
+			"allowing scripts to react to specific types of failures.",
+		),
+	)
 	RootCmd.CompletionOptions.HiddenDefaultCmd = true
 }
@@ -0,0 +1,85 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/spf13/cobra"
+)
+
+// The download commands of the websites supported by textparser.GetUrlWebsite
+var siteCmds map[string]*cobra.Command
+
+// Runs the website's download command in a new process of the program
+// with the URLs written to a text file for the "--txt_filepath" flag.
+//
+// The args are passed to the download command after the "--txt_filepath" flag
+// and env, if any, is added to the environment variables of the process.
+func runDownloadCmd(website string, urls, args, env []string) error {
+	urlsStr := strings.Join(urls, ", ")
+	txtFile, err := os.CreateTemp("", "cultured-downloader-*.txt")
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to create text file for %s, more info => %v",
+			utils.OS_ERROR,
+			urlsStr,
+			err,
+		)
+	}
+	defer os.Remove(txtFile.Name())
+
+	_, err = txtFile.WriteString(strings.Join(urls, "\n") + "\n")
+	txtFile.Close()
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to write text file for %s, more info => %v",
+			utils.OS_ERROR,
+			urlsStr,
+			err,
+		)
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to get the path of the program, more info => %v",
+			utils.OS_ERROR,
+			err,
+		)
+	}
+
+	cmdArgs := append([]string{siteCmds[website].Name(), "--txt_filepath", txtFile.Name()}, args...)
+	cmd := exec.Command(exePath, cmdArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == utils.EXIT_NOTHING_TO_DO {
+			return nil
+		}
+		return fmt.Errorf(
+			"error %d: failed to download %s, more info => %v",
+			utils.UNEXPECTED_ERROR,
+			urlsStr,
+			err,
+		)
+	}
+	return nil
+}
+
+func init() {
+	siteCmds = map[string]*cobra.Command{
+		utils.FANTIA:       fantiaCmd,
+		utils.PIXIV_FANBOX: pixivFanboxCmd,
+		utils.PIXIV:        pixivCmd,
+		utils.KEMONO:       kemonoCmd,
+		utils.DLSITE:       dlsiteCmd,
+	}
+}
@@ -0,0 +1,361 @@
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/fantia"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/kemono"
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox"
+	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// The websites that can be searched for creators
+var searchableSites = []string{utils.KEMONO, utils.PIXIV_FANBOX, utils.FANTIA}
+
+var (
+	searchSites     []string
+	searchLimit     int
+	searchDownload  bool
+	searchFilePath  string
+	searchUserAgent string
+	searchCmd       = &cobra.Command{
+		Use:   "search [name]",
+		Short: "Fuzzy-find a creator by name and add them to your follows.yaml file",
+		Long: utils.CombineStringsWithNewline(
+			"Searches Kemono Party's creators index and Pixiv Fanbox's and Fantia's creator search",
+			"for creators whose name fuzzy-matches the given name, e.g. \"kjh\" matches \"KJHJason\".",
+			"",
+			"Pick the creators to add by their number in the results or type another name to search again.",
+			"The picked creators are added to the follows.yaml file to be downloaded by the sync command",
+			"or are downloaded right away if the --download flag is set.",
+		),
+		Args: cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, site := range searchSites {
+				if !utils.SliceContains(searchableSites, site) {
+					utils.ExitWithErrorf(
+						utils.EXIT_INPUT_ERROR,
+						"Invalid website %q for the --sites flag, must be one of %s",
+						site,
+						strings.Join(searchableSites, ", "),
+					)
+				}
+			}
+
+			searcher := &creatorSearcher{
+				sites:     searchSites,
+				userAgent: searchUserAgent,
+			}
+			creators := promptCreators(searcher, strings.Join(args, " "))
+			if len(creators) == 0 {
+				os.Exit(utils.EXIT_NOTHING_TO_DO)
+			}
+
+			if searchDownload {
+				failed := 0
+				for _, creator := range creators {
+					color.Cyan("\nDownloading %s", creator.Url)
+					website := textparser.GetUrlWebsite(creator.Url)
+					if err := runDownloadCmd(website, []string{creator.Url}, nil, nil); err != nil {
+						utils.LogError(err, "", false, utils.ERROR)
+						failed++
+					}
+				}
+				if failed > 0 {
+					os.Exit(utils.EXIT_PARTIAL_FAILURE)
+				}
+				return
+			}
+
+			urls := make([]string, len(creators))
+			for idx, creator := range creators {
+				urls[idx] = creator.Url
+			}
+			added, err := addToFollowsFile(searchFilePath, urls)
+			if err != nil {
+				utils.ExitWithErrorf(utils.EXIT_ERROR, "%v", err)
+			}
+			for _, url := range urls {
+				if utils.SliceContains(added, url) {
+					color.Green("Added %s to %s", url, searchFilePath)
+				} else {
+					color.Yellow("%s is already in %s", url, searchFilePath)
+				}
+			}
+		},
+	}
+)
+
+// Searches the websites for creators and caches Kemono Party's
+// creators index as it is searched locally for every name
+type creatorSearcher struct {
+	sites     []string
+	userAgent string
+
+	kemonoCreators []*api.SearchedCreator
+	kemonoFetched  bool
+}
+
+// Returns the creators from the websites that may match the name, logging the errors of the websites that failed
+func (s *creatorSearcher) getCandidates(name string) []*api.SearchedCreator {
+	var candidates []*api.SearchedCreator
+	for _, site := range s.sites {
+		var creators []*api.SearchedCreator
+		var err error
+		switch site {
+		case utils.KEMONO:
+			if !s.kemonoFetched {
+				s.kemonoCreators, err = s.getKemonoCreators()
+				s.kemonoFetched = err == nil
+			}
+			creators = s.kemonoCreators
+		case utils.PIXIV_FANBOX:
+			creators, err = pixivfanbox.SearchCreators(name, s.userAgent)
+		case utils.FANTIA:
+			creators, err = fantia.SearchFanclubs(name, s.userAgent)
+		}
+
+		if err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			continue
+		}
+		candidates = append(candidates, creators...)
+	}
+	return candidates
+}
+
+// Returns the creators in Kemono Party's creators index that can be downloaded by the kemono command
+func (s *creatorSearcher) getKemonoCreators() ([]*api.SearchedCreator, error) {
+	progress := spinner.New(
+		spinner.REQ_SPINNER,
+		"fgHiYellow",
+		"Getting the creators index from Kemono Party...",
+		"Finished getting the creators index from Kemono Party!",
+		"Something went wrong while getting the creators index from Kemono Party.\nPlease refer to the logs for more details.",
+		0,
+	)
+	progress.Start()
+	creators, err := kemono.GetCreatorsIndex(s.userAgent)
+	progress.Stop(err != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// e.g. Discord servers cannot be downloaded
+	supported := creators[:0]
+	for _, creator := range creators {
+		if textparser.GetUrlWebsite(creator.Url) != "" {
+			supported = append(supported, creator)
+		}
+	}
+	return supported, nil
+}
+
+// Returns the creators that fuzzy-match the name, best matches first, up to the --limit flag
+func (s *creatorSearcher) search(name string) []*api.SearchedCreator {
+	type match struct {
+		creator *api.SearchedCreator
+		score   int
+	}
+
+	var matches []match
+	for _, creator := range s.getCandidates(name) {
+		score, ok := fuzzyMatch(name, creator.Name)
+		if !ok {
+			if creator.Website == utils.KEMONO {
+				continue
+			}
+			// the websites' own search may have matched the creator by
+			// something other than their name, e.g. their description
+			score = -1
+		}
+		matches = append(matches, match{creator: creator, score: score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].creator.Popularity > matches[j].creator.Popularity
+	})
+
+	if searchLimit > 0 && len(matches) > searchLimit {
+		matches = matches[:searchLimit]
+	}
+	creators := make([]*api.SearchedCreator, len(matches))
+	for idx, m := range matches {
+		creators[idx] = m.creator
+	}
+	return creators
+}
+
+// Returns the score of how well the name matches the query and false if it does not match.
+//
+// The name matches if it contains the query or if the characters of the query appear in
+// the name in order, e.g. "kjh" matches "KJHJason", where names that contain the query
+// score higher, especially if they start with it, followed by names where the characters
+// of the query are consecutive or at the start of the words in the name.
+func fuzzyMatch(query, name string) (int, bool) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	name = strings.ToLower(name)
+	nameRunes := []rune(name)
+	if idx := strings.Index(name, query); idx != -1 {
+		score := 2000 - len(nameRunes) // shorter names are closer to the query
+		if idx == 0 {
+			score += 1000
+		}
+		if name == query {
+			score += 1000
+		}
+		return score, true
+	}
+
+	queryRunes := []rune(query)
+	score, queryIdx := 0, 0
+	prevMatched := false
+	for idx, r := range nameRunes {
+		if queryIdx == len(queryRunes) {
+			break
+		}
+		if unicode.IsSpace(queryRunes[queryIdx]) {
+			queryIdx++ // e.g. "kjh jason" still matches "KJHJason"
+		}
+		if queryIdx == len(queryRunes) || r != queryRunes[queryIdx] {
+			prevMatched = false
+			continue
+		}
+
+		score++
+		if prevMatched {
+			score += 5
+		}
+		if idx == 0 || !unicode.IsLetter(nameRunes[idx-1]) && !unicode.IsDigit(nameRunes[idx-1]) {
+			score += 10
+		}
+		prevMatched = true
+		queryIdx++
+	}
+	if queryIdx < len(queryRunes) {
+		return 0, false
+	}
+	if score > 1999 {
+		score = 1999 // always below the names that contain the query
+	}
+	return score, true
+}
+
+// Parses the numbers of the picked creators, e.g. "1,3", and returns false if the input is not a list of numbers
+func parseCreatorNums(input string, creatorCount int) ([]int, bool) {
+	var idxs []int
+	for _, part := range strings.Split(input, ",") {
+		num, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || num < 1 || num > creatorCount {
+			return nil, false
+		}
+		idxs = append(idxs, num-1)
+	}
+	return idxs, true
+}
+
+// Prompts the user for a name to search for until they pick the creators
+// from the results or leave the input empty, which returns no creators.
+//
+// The initial name is searched for first if it is not empty.
+func promptCreators(searcher *creatorSearcher, name string) []*api.SearchedCreator {
+	reader := bufio.NewReader(os.Stdin)
+	readInput := func(prompt string) (string, bool) {
+		fmt.Print(color.YellowString(prompt))
+		input, err := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		return input, err == nil || input != ""
+	}
+
+	for {
+		if name == "" {
+			var ok bool
+			if name, ok = readInput("Creator name to search for (leave empty to quit): "); !ok || name == "" {
+				return nil
+			}
+		}
+
+		results := searcher.search(name)
+		if len(results) == 0 {
+			color.Yellow("No creators found for %q", name)
+			name = ""
+			continue
+		}
+
+		color.Cyan("\nCreators matching %q:", name)
+		for idx, creator := range results {
+			site := utils.GetReadableSiteStr(creator.Website)
+			if creator.Service != "" {
+				site = fmt.Sprintf("%s/%s", site, creator.Service)
+			}
+			fmt.Printf("  [%d] %s (%s) %s\n", idx+1, creator.Name, site, creator.Url)
+		}
+
+		input, ok := readInput("Creator(s) to add, e.g. \"1,3\", or another name to search for (leave empty to quit): ")
+		if !ok || input == "" {
+			return nil
+		}
+		if idxs, isNums := parseCreatorNums(input, len(results)); isNums {
+			creators := make([]*api.SearchedCreator, len(idxs))
+			for i, idx := range idxs {
+				creators[i] = results[idx]
+			}
+			return creators
+		}
+		name = input
+	}
+}
+
+func init() {
+	searchCmd.Flags().StringSliceVar(
+		&searchSites,
+		"sites",
+		searchableSites,
+		utils.CombineStringsWithNewline(
+			fmt.Sprintf(
+				"Websites to search for creators, any of %s.",
+				strings.Join(searchableSites, ", "),
+			),
+			"Kemono Party's creators index is downloaded once and searched locally while the others are searched online.",
+		),
+	)
+	searchCmd.Flags().IntVar(
+		&searchLimit,
+		"limit",
+		20,
+		"Maximum number of matching creators to list, 0 means no limit.",
+	)
+	searchCmd.Flags().BoolVar(
+		&searchDownload,
+		"download",
+		false,
+		"Download the picked creators right away with your saved cookie files instead of adding them to the follows.yaml file.",
+	)
+	searchCmd.Flags().StringVar(
+		&searchFilePath,
+		"file",
+		getFollowsFilePath(),
+		"Path to the follows.yaml file to add the picked creators to.",
+	)
+	searchCmd.Flags().StringVarP(
+		&searchUserAgent,
+		"user_agent",
+		"u",
+		"",
+		"Set a custom User-Agent header to use when searching for creators.",
+	)
+	RootCmd.AddCommand(searchCmd)
+}
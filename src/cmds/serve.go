@@ -0,0 +1,269 @@
+package cmds
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// Maximum number of URLs that can be waiting to be downloaded
+	serveQueueSize = 100
+
+	// How often the clipboard is checked for new URLs when "--clipboard" is used
+	clipboardPollInterval = time.Second
+)
+
+type serveDownloadReq struct {
+	Url string `json:"url"`
+}
+
+type serveJob struct {
+	website string
+	url     string
+}
+
+var (
+	servePort      int
+	serveToken     string
+	serveClipboard bool
+	serveJobs      = make(chan *serveJob, serveQueueSize)
+	serveCmd       = &cobra.Command{
+		Use:   "serve",
+		Short: "Listen on localhost for URLs to download from a browser extension",
+		Long: utils.CombineStringsWithNewline(
+			"Starts a HTTP server on 127.0.0.1 that accepts \"download this URL\" requests,",
+			"e.g. from a companion browser extension or a bookmarklet.",
+			"",
+			"Send a POST request to /download with the JSON body {\"url\": \"<url>\"}",
+			"and the header \"Authorization: Bearer <token>\" to queue a URL.",
+			"A GET request to /status with the same header returns the number of queued URLs.",
+			"",
+			"Queued URLs are downloaded one at a time by running the website's download command,",
+			"so your cookie files and sessions saved in the config file will be used.",
+			"",
+			"With the \"--clipboard\" flag, supported post and creator URLs that are copied",
+			"to the system clipboard will also be queued automatically.",
+		),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if serveToken == "" {
+				serveToken = generateServeToken()
+				color.Yellow("No token was given, generated token for this run: %s", serveToken)
+			}
+
+			addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(servePort))
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				utils.ExitWithErrorf(1, "error %d: failed to listen on %s, more info => %v", utils.CONNECTION_ERROR, addr, err)
+			}
+
+			go runServeJobs()
+			if serveClipboard {
+				readClipboard, err := utils.NewClipboardReader()
+				if err != nil {
+					utils.ExitWithErrorf(1, "%v", err)
+				}
+				go watchClipboard(readClipboard)
+				color.Green("Watching the clipboard for URLs")
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/download", handleServeDownload)
+			mux.HandleFunc("/status", handleServeStatus)
+			color.Green("Listening for URLs on http://%s", addr)
+			if err := http.Serve(listener, mux); err != nil {
+				utils.ExitWithErrorf(1, "error %d: server stopped, more info => %v", utils.CONNECTION_ERROR, err)
+			}
+		},
+	}
+)
+
+// Returns a random hex token to authenticate the requests to the server
+func generateServeToken() string {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		utils.ExitWithErrorf(1, "error %d: failed to generate token, more info => %v", utils.UNEXPECTED_ERROR, err)
+	}
+	return hex.EncodeToString(tokenBytes)
+}
+
+// Writes the CORS headers and handles the preflight request of the browser.
+//
+// Returns true if the request has been fully handled.
+func handleServeCors(w http.ResponseWriter, r *http.Request) bool {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Vary", "Origin")
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+	return false
+}
+
+// Returns true if the request has the correct bearer token
+func isServeAuthorised(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(serveToken)) == 1
+}
+
+func writeServeJson(w http.ResponseWriter, statusCode int, body map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+func handleServeDownload(w http.ResponseWriter, r *http.Request) {
+	if handleServeCors(w, r) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeServeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	if !isServeAuthorised(r) {
+		writeServeJson(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
+		return
+	}
+
+	var reqBody serveDownloadReq
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&reqBody); err != nil {
+		writeServeJson(w, http.StatusBadRequest, map[string]any{"error": "invalid JSON body"})
+		return
+	}
+
+	url := strings.TrimSpace(reqBody.Url)
+	website := textparser.GetUrlWebsite(url)
+	if website == "" {
+		writeServeJson(w, http.StatusBadRequest, map[string]any{"error": "unsupported URL"})
+		return
+	}
+
+	if !queueServeJob(website, url) {
+		writeServeJson(w, http.StatusServiceUnavailable, map[string]any{"error": "queue is full"})
+		return
+	}
+	writeServeJson(w, http.StatusAccepted, map[string]any{
+		"website": website,
+		"queued":  len(serveJobs),
+	})
+}
+
+// Adds the URL to the download queue.
+//
+// Returns false if the queue is full.
+func queueServeJob(website, url string) bool {
+	select {
+	case serveJobs <- &serveJob{website: website, url: url}:
+		color.Cyan("Queued %s URL: %s", utils.GetReadableSiteStr(website), url)
+		return true
+	default:
+		return false
+	}
+}
+
+// Polls the clipboard and queues any supported URLs that have not been queued before
+func watchClipboard(readClipboard func() (string, error)) {
+	// ignore whatever was copied before the watcher was started
+	lastText, _ := readClipboard()
+	queuedUrls := make(map[string]struct{})
+	for {
+		time.Sleep(clipboardPollInterval)
+		text, err := readClipboard()
+		if err != nil {
+			// the clipboard may be temporarily unavailable, e.g. if it is empty on some systems
+			continue
+		}
+		if text == lastText {
+			continue
+		}
+		lastText = text
+
+		for _, url := range strings.Fields(text) {
+			if _, ok := queuedUrls[url]; ok {
+				continue
+			}
+			website := textparser.GetUrlWebsite(url)
+			if website == "" {
+				continue
+			}
+			if !queueServeJob(website, url) {
+				color.Yellow("Queue is full, skipping %s", url)
+				continue
+			}
+			queuedUrls[url] = struct{}{}
+		}
+	}
+}
+
+func handleServeStatus(w http.ResponseWriter, r *http.Request) {
+	if handleServeCors(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeServeJson(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	if !isServeAuthorised(r) {
+		writeServeJson(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
+		return
+	}
+	writeServeJson(w, http.StatusOK, map[string]any{"queued": len(serveJobs)})
+}
+
+// Downloads the queued URLs one at a time
+func runServeJobs() {
+	for job := range serveJobs {
+		if err := runDownloadCmd(job.website, []string{job.url}, nil, nil); err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			continue
+		}
+		color.Green("Finished downloading %s", job.url)
+	}
+}
+
+func init() {
+	serveCmd.Flags().IntVar(
+		&servePort,
+		"port",
+		6970,
+		"The port on 127.0.0.1 to listen on.",
+	)
+	serveCmd.Flags().StringVar(
+		&serveToken,
+		"token",
+		"",
+		utils.CombineStringsWithNewline(
+			"The token that requests must send in the \"Authorization: Bearer <token>\" header.",
+			"A random token will be generated and printed if not given.",
+		),
+	)
+	serveCmd.Flags().BoolVar(
+		&serveClipboard,
+		"clipboard",
+		false,
+		utils.CombineStringsWithNewline(
+			"Monitor the system clipboard and queue any supported post or creator URLs that are copied.",
+			"On Linux, wl-clipboard, xclip, or xsel must be installed.",
+		),
+	)
+	RootCmd.AddCommand(serveCmd)
+}
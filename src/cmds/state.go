@@ -0,0 +1,323 @@
+package cmds
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/pixivfanbox"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive"
+	"github.com/KJHJason/Cultured-Downloader-CLI/postdb"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/stats"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Version 2 added the app data files and the download log
+const STATE_FILE_VERSION = 2
+
+// Portable state of the program that can be imported on another computer
+type exportedState struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Config     *utils.ConfigFile `json:"config"`
+
+	// LockedPosts maps the file path relative to the download directory,
+	// e.g. "Pixiv-Fanbox/locked_posts.csv", to its contents
+	LockedPosts map[string]string `json:"locked_posts,omitempty"`
+
+	// AppFiles maps the file path relative to the app data folder, e.g. "queue/fantia_queue.json",
+	// to its contents, which are the files that the next runs resume from, see getAppStateFiles
+	AppFiles map[string]string `json:"app_files,omitempty"`
+
+	// DownloadLog is the contents of the DOWNLOAD_LOG_FILENAME file in the download directory
+	DownloadLog string `json:"download_log,omitempty"`
+
+	// Cookies maps the website to the contents of its cookie file
+	// and is only included if the --include_cookies flag is set.
+	Cookies map[string]*exportedCookieFile `json:"cookies,omitempty"`
+}
+
+type exportedCookieFile struct {
+	Ext      string `json:"ext"`
+	Contents string `json:"contents"`
+}
+
+var (
+	stateIncludeCookies bool
+	stateDownloadDir    string
+	stateOverwrite      bool
+	stateCmd            = &cobra.Command{
+		Use:   "state",
+		Short: "Export or import the program's state",
+		Long: utils.CombineStringsWithNewline(
+			"Export the program's state to a portable JSON file and import it on another computer.",
+			"The state includes the config file, the locked posts reports and the download log in the download directory,",
+			"and the files in the app data folder that the next runs resume from, i.e. the download queues, the recorded",
+			"posts and creators, the crawl checkpoints, the deferred GDrive files, the run stats, and the follows.yaml file.",
+			"Since downloaded files are skipped based on the files in the download directory,",
+			"copy the download directory alongside the state file to avoid re-downloading them.",
+		),
+	}
+	stateExportCmd = &cobra.Command{
+		Use:   "export <file>",
+		Short: "Export the program's state to a JSON file",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := utils.LoadConfigFile()
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+
+			state := &exportedState{
+				Version:     STATE_FILE_VERSION,
+				ExportedAt:  time.Now().UTC(),
+				Config:      config,
+				LockedPosts: readLockedPostReports(config.DownloadDir),
+				AppFiles:    readAppStateFiles(),
+			}
+			if config.DownloadDir != "" {
+				if downloadLog, err := os.ReadFile(filepath.Join(config.DownloadDir, request.DOWNLOAD_LOG_FILENAME)); err == nil {
+					state.DownloadLog = string(downloadLog)
+				}
+			}
+			if stateIncludeCookies {
+				state.Cookies = make(map[string]*exportedCookieFile)
+				for site, cookieFile := range config.CookieFiles {
+					contents, err := os.ReadFile(cookieFile)
+					if err != nil {
+						color.Red("Failed to read the %s cookie file at %s: %v", utils.GetReadableSiteStr(site), cookieFile, err)
+						continue
+					}
+					state.Cookies[site] = &exportedCookieFile{
+						Ext:      filepath.Ext(cookieFile),
+						Contents: string(contents),
+					}
+				}
+			}
+
+			stateJson, err := json.MarshalIndent(state, "", "    ")
+			if err != nil {
+				utils.LogError(err, "failed to marshal the state", true, utils.ERROR)
+			}
+
+			// only readable by the current user as it may contain the session cookies
+			if err := utils.WritePrivateFile(args[0], stateJson); err != nil {
+				utils.ExitWithErrorf(1, "error %d: failed to write the state file at %s, more info => %v", utils.OS_ERROR, args[0], err)
+			}
+			color.Green("Exported the state to %s", args[0])
+			if stateIncludeCookies {
+				color.Yellow("The state file contains your session cookies, do not share it with anyone!")
+			}
+		},
+	}
+	stateImportCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import the program's state from a JSON file",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			stateJson, err := os.ReadFile(args[0])
+			if err != nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "error %d: failed to read the state file at %s, more info => %v", utils.OS_ERROR, args[0], err)
+			}
+
+			var state exportedState
+			if err := json.Unmarshal(stateJson, &state); err != nil || state.Config == nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "error %d: %s is not a valid state file", utils.INPUT_ERROR, args[0])
+			}
+			if state.Version > STATE_FILE_VERSION {
+				utils.ExitWithErrorf(
+					utils.EXIT_INPUT_ERROR,
+					"error %d: the state file was exported by a newer version of the program, please update and try again",
+					utils.INPUT_ERROR,
+				)
+			}
+
+			config := state.Config
+			exportedDownloadDir := config.DownloadDir
+			if stateDownloadDir != "" {
+				config.DownloadDir = stateDownloadDir
+			}
+			if config.DownloadDir != "" && !utils.PathExists(config.DownloadDir) {
+				color.Yellow(
+					"The download directory at %s does not exist on this computer, use the --dl_path flag to set a new one",
+					config.DownloadDir,
+				)
+				config.DownloadDir = ""
+			}
+
+			importCookieFiles(&state, config)
+			writeLockedPostReports(config.DownloadDir, state.LockedPosts)
+			writeAppStateFiles(state.AppFiles, exportedDownloadDir, config.DownloadDir)
+			if config.DownloadDir != "" && state.DownloadLog != "" {
+				writeStateFile(
+					filepath.Join(config.DownloadDir, request.DOWNLOAD_LOG_FILENAME),
+					replaceDownloadDir(state.DownloadLog, exportedDownloadDir, config.DownloadDir),
+				)
+			}
+			if err := utils.SaveConfigFile(config); err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			color.Green("Imported the state from %s", args[0])
+		},
+	}
+)
+
+// Returns the contents of the locked posts report of each website in the download directory
+func readLockedPostReports(downloadDir string) map[string]string {
+	if downloadDir == "" {
+		return nil
+	}
+
+	reports := make(map[string]string)
+	matches, _ := filepath.Glob(filepath.Join(downloadDir, "*", pixivfanbox.LOCKED_POSTS_FILENAME))
+	for _, match := range matches {
+		contents, err := os.ReadFile(match)
+		if err != nil {
+			continue
+		}
+		relPath, err := filepath.Rel(downloadDir, match)
+		if err != nil {
+			continue
+		}
+		reports[filepath.ToSlash(relPath)] = string(contents)
+	}
+	return reports
+}
+
+// Writes the imported file unless it already exists and the --overwrite flag is not set
+func writeStateFile(filePath, contents string) {
+	if !stateOverwrite && utils.PathExists(filePath) {
+		color.Yellow("Skipped importing %s as it already exists", filePath)
+		return
+	}
+
+	os.MkdirAll(filepath.Dir(filePath), 0666)
+	if err := os.WriteFile(filePath, []byte(contents), 0666); err != nil {
+		color.Red("Failed to write %s: %v", filePath, err)
+	}
+}
+
+// Writes the locked posts reports to the download directory
+func writeLockedPostReports(downloadDir string, reports map[string]string) {
+	if downloadDir == "" {
+		return
+	}
+
+	for relPath, contents := range reports {
+		writeStateFile(filepath.Join(downloadDir, filepath.FromSlash(relPath)), contents)
+	}
+}
+
+// Returns the paths of the files in the app data folder that the next runs resume from
+func getAppStateFiles() []string {
+	appFiles := []string{
+		postdb.GetDbFilePath(),
+		utils.GetCreatorsFilePath(),
+		utils.GetCrawlCheckpointFilePath(),
+		gdrive.GetDeferredFilesPath(),
+		stats.GetStatsFilePath(),
+		getFollowsFilePath(),
+	}
+	queueFiles, _ := filepath.Glob(request.GetQueueFilePath("*"))
+	return append(appFiles, queueFiles...)
+}
+
+// Returns the contents of the files in the app data folder that the next runs resume from
+func readAppStateFiles() map[string]string {
+	appFiles := make(map[string]string)
+	for _, appFile := range getAppStateFiles() {
+		contents, err := os.ReadFile(appFile)
+		if err != nil {
+			continue
+		}
+		relPath, err := filepath.Rel(utils.APP_PATH, appFile)
+		if err != nil {
+			continue
+		}
+		appFiles[filepath.ToSlash(relPath)] = string(contents)
+	}
+	return appFiles
+}
+
+// Writes the files to the app data folder with the paths in the
+// exported download directory changed to the imported download directory
+func writeAppStateFiles(appFiles map[string]string, exportedDownloadDir, downloadDir string) {
+	for relPath, contents := range appFiles {
+		appFile := filepath.Join(utils.APP_PATH, filepath.FromSlash(relPath))
+		if cleanRelPath, err := filepath.Rel(utils.APP_PATH, appFile); err != nil || strings.HasPrefix(cleanRelPath, "..") {
+			color.Red("Skipped importing %s as it is outside the app data folder", relPath)
+			continue
+		}
+		writeStateFile(appFile, replaceDownloadDir(contents, exportedDownloadDir, downloadDir))
+	}
+}
+
+// Returns the JSON contents with the paths in the exported download directory changed to the
+// imported download directory, e.g. the post folders in posts.json and the file paths in the queues
+func replaceDownloadDir(contents, exportedDownloadDir, downloadDir string) string {
+	if exportedDownloadDir == "" || downloadDir == "" || exportedDownloadDir == downloadDir {
+		return contents
+	}
+
+	// the paths are compared as they are escaped in the JSON strings, e.g. the backslashes on Windows
+	oldDir, _ := json.Marshal(exportedDownloadDir)
+	newDir, _ := json.Marshal(downloadDir)
+	return strings.ReplaceAll(
+		contents,
+		strings.Trim(string(oldDir), `"`),
+		strings.Trim(string(newDir), `"`),
+	)
+}
+
+// Writes the imported cookies to the app data folder and
+// updates the cookie file paths in the config to point to them.
+//
+// Cookie file paths from the other computer that were not exported are removed.
+func importCookieFiles(state *exportedState, config *utils.ConfigFile) {
+	cookieFiles := make(map[string]string)
+	for site, cookieFile := range config.CookieFiles {
+		if utils.PathExists(cookieFile) {
+			cookieFiles[site] = cookieFile
+		}
+	}
+
+	for site, exportedCookie := range state.Cookies {
+		cookieFile := filepath.Join(utils.APP_PATH, "cookies", site+"_cookies"+exportedCookie.Ext)
+		os.MkdirAll(filepath.Dir(cookieFile), 0700)
+		if err := utils.WritePrivateFile(cookieFile, []byte(exportedCookie.Contents)); err != nil {
+			color.Red("Failed to write the %s cookie file at %s: %v", utils.GetReadableSiteStr(site), cookieFile, err)
+			continue
+		}
+		cookieFiles[site] = cookieFile
+	}
+	config.CookieFiles = cookieFiles
+}
+
+func init() {
+	stateExportCmd.Flags().BoolVar(
+		&stateIncludeCookies,
+		"include_cookies",
+		false,
+		"Include the contents of your saved cookie files in the exported state file.",
+	)
+	stateImportCmd.Flags().StringVarP(
+		&stateDownloadDir,
+		"dl_path",
+		"p",
+		"",
+		"Set the download directory on this computer instead of using the one from the state file.",
+	)
+	stateImportCmd.Flags().BoolVar(
+		&stateOverwrite,
+		"overwrite",
+		false,
+		"Overwrite the existing files on this computer with the ones from the state file instead of skipping them.",
+	)
+	stateCmd.AddCommand(stateExportCmd, stateImportCmd)
+	RootCmd.AddCommand(stateCmd)
+}
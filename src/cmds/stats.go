@@ -0,0 +1,143 @@
+package cmds
+
+import (
+	"fmt"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/stats"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsSite    string
+	statsCreator string
+	statsLast    int
+	statsCmd     = &cobra.Command{
+		Use:   "stats",
+		Short: "Show the statistics of the past runs",
+		Long: utils.CombineStringsWithNewline(
+			"Shows the posts, files, bytes, errors, and duration of the past runs of the download commands",
+			"and compares the most recent runs of each website with the runs before them",
+			"to help notice when a website silently starts failing, e.g. after changes to its API.",
+		),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if statsLast < 1 {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "error %d: --last must be at least 1 but got %d", utils.INPUT_ERROR, statsLast)
+			}
+
+			runs, err := stats.LoadRuns()
+			if err != nil {
+				utils.LogError(err, "", true, utils.ERROR)
+			}
+			if statsSite != "" {
+				var siteRuns []*stats.Run
+				for _, run := range runs {
+					if run.Site == statsSite {
+						siteRuns = append(siteRuns, run)
+					}
+				}
+				runs = siteRuns
+			}
+			if statsCreator != "" {
+				runs = stats.FilterByCreator(runs, statsCreator)
+			}
+			if len(runs) == 0 {
+				color.Yellow("No runs have been recorded yet in %s", stats.GetStatsFilePath())
+				return
+			}
+
+			printRunHistory(runs)
+			printRunTrends(runs)
+		},
+	}
+)
+
+func printRunHistory(runs []*stats.Run) {
+	recentRuns := runs
+	if len(recentRuns) > statsLast {
+		recentRuns = recentRuns[len(recentRuns)-statsLast:]
+	}
+
+	color.Cyan("Last %d run(s):", len(recentRuns))
+	for _, run := range recentRuns {
+		line := fmt.Sprintf(
+			"%s  %-12s %8s  %4d post(s)  %5d file(s)  %10s  %d error(s)",
+			run.StartedAt.Local().Format("2006-01-02 15:04"),
+			run.Site,
+			run.Duration,
+			run.Posts,
+			run.Files,
+			utils.FormatFileSize(run.Bytes),
+			run.Errors,
+		)
+		if run.Errors > 0 {
+			color.Yellow("%s", line)
+		} else {
+			fmt.Println(line)
+		}
+	}
+}
+
+func printRunTrends(runs []*stats.Run) {
+	color.Cyan(
+		"\nTrends of the last %d run(s) of each website compared with the %d run(s) before them:",
+		statsLast,
+		statsLast,
+	)
+	for _, trend := range stats.GetTrends(runs, statsLast) {
+		if trend.PreviousRuns == 0 {
+			fmt.Printf(
+				"%s: %.1f file(s) and %.1f error(s) per run\n",
+				trend.Site,
+				trend.RecentFilesPerRun,
+				trend.RecentErrorsPerRun,
+			)
+		} else {
+			fmt.Printf(
+				"%s: %.1f file(s) per run (previously %.1f), %.1f error(s) per run (previously %.1f)\n",
+				trend.Site,
+				trend.RecentFilesPerRun,
+				trend.PreviousFilesPerRun,
+				trend.RecentErrorsPerRun,
+				trend.PreviousErrorsPerRun,
+			)
+		}
+
+		if trend.FailingStreak > 0 {
+			color.Red("  ✗ the last %d run(s) logged errors without downloading any files", trend.FailingStreak)
+		}
+		if trend.EmptyStreak > 0 {
+			color.Yellow("  ! the last %d run(s) found no posts although an earlier run did", trend.EmptyStreak)
+		}
+	}
+}
+
+func init() {
+	statsCmd.Flags().StringVar(
+		&statsSite,
+		"site",
+		"",
+		fmt.Sprintf(
+			"Only show the runs of the website, e.g. %q.",
+			utils.FANTIA,
+		),
+	)
+	statsCmd.Flags().StringVar(
+		&statsCreator,
+		"creator",
+		"",
+		utils.CombineStringsWithNewline(
+			"Only show the statistics of the posts of the creator in each run,",
+			"e.g. the creator name on Fantia or the creator ID on Pixiv Fanbox.",
+		),
+	)
+	statsCmd.Flags().IntVar(
+		&statsLast,
+		"last",
+		10,
+		"Number of the most recent runs to show and to compare with the runs before them.",
+	)
+	RootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,405 @@
+package cmds
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/cmds/textparser"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Options of a followed creator that can also be set as the defaults for all creators
+type followOptions struct {
+	// PageNum is the range of pages to download, e.g. "1-5"
+	PageNum string `yaml:"page_num"`
+
+	// DownloadPath is the folder to download to instead of the saved download path
+	DownloadPath string `yaml:"download_path"`
+
+	// Args are the flags to pass to the website's download command, e.g. ["--post_filter", "free"]
+	Args []string `yaml:"args"`
+}
+
+type followedCreator struct {
+	Url           string `yaml:"url"`
+	followOptions `yaml:",inline"`
+}
+
+// The follows.yaml file listing the creators to download across all websites
+type followsFile struct {
+	Defaults followOptions      `yaml:"defaults"`
+	Creators []*followedCreator `yaml:"creators"`
+
+	// Schedules maps a website, e.g. "pixiv", to the cron expression of when
+	// its creators are synced which takes precedence over the "--schedule" flag
+	Schedules map[string]string `yaml:"schedules"`
+}
+
+var (
+	syncFilePath string
+	syncSchedule string
+	syncCmd      = &cobra.Command{
+		Use:   "sync",
+		Short: "Download the new posts of all the creators in your follows.yaml file",
+		Long: utils.CombineStringsWithNewline(
+			"Downloads from every creator listed in the follows.yaml file one at a time,",
+			"using the download command of the creator's website with your saved cookie files.",
+			"",
+			"Example follows.yaml:",
+			"defaults:",
+			"  args: [\"--stop_after_seen\", \"10\"]",
+			"creators:",
+			"  - url: https://fantia.jp/fanclubs/1234",
+			"    page_num: 1-5",
+			"    args: [\"--post_filter\", \"free\"]",
+			"  - url: https://kemono.party/patreon/user/5678",
+			"    download_path: /mnt/archive",
+			"",
+			"The page_num and download_path of a creator override the defaults",
+			"while the creator's args are added after the default args.",
+			"",
+			"With the \"--schedule\" flag, the program keeps running and syncs the creators whenever the",
+			"cron expression is due, e.g. \"0 3 * * *\" for every day at 3am, where each website can have its own schedule:",
+			"schedules:",
+			"  pixiv: \"0 */6 * * *\"",
+			"  fantia: \"30 3 * * mon,thu\"",
+		),
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if syncSchedule != "" {
+				defaultSchedule, err := utils.ParseCronSchedule(syncSchedule)
+				if err != nil {
+					utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+				}
+				watchSync(defaultSchedule)
+				return
+			}
+
+			follows, err := loadFollowsFile(syncFilePath)
+			if err != nil {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+			}
+
+			failed := syncCreators(follows, follows.Creators)
+			if failed > 0 {
+				utils.ExitWithErrorf(utils.EXIT_PARTIAL_FAILURE, "\nFailed to sync %d of %d creator(s), please refer to the logs for more details", failed, len(follows.Creators))
+			}
+			color.Green("\nSynced %d creator(s)", len(follows.Creators))
+		},
+	}
+)
+
+// Syncs the given creators one at a time and returns the number of creators that failed
+func syncCreators(follows *followsFile, creators []*followedCreator) int {
+	failed := 0
+	for idx, creator := range creators {
+		color.Cyan("\n[%d/%d] Syncing %s", idx+1, len(creators), creator.Url)
+		if err := syncCreator(creator, &follows.Defaults); err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			failed++
+		}
+	}
+	return failed
+}
+
+// Returns the schedules of the websites of the followed creators where
+// the websites without a schedule in the follows.yaml file use the default schedule
+func getSyncSchedules(follows *followsFile, defaultSchedule *utils.CronSchedule) (map[string]*utils.CronSchedule, error) {
+	for website := range follows.Schedules {
+		if _, ok := siteCmds[website]; !ok {
+			return nil, fmt.Errorf(
+				"error %d: unknown website %q in the schedules of the follows file at %s",
+				utils.INPUT_ERROR,
+				website,
+				syncFilePath,
+			)
+		}
+	}
+
+	schedules := make(map[string]*utils.CronSchedule)
+	for _, creator := range follows.Creators {
+		website := textparser.GetUrlWebsite(getFollowedCreatorLine(creator, &follows.Defaults))
+		if _, ok := schedules[website]; ok {
+			continue
+		}
+
+		schedules[website] = defaultSchedule
+		if expr, ok := follows.Schedules[website]; ok {
+			schedule, err := utils.ParseCronSchedule(expr)
+			if err != nil {
+				return nil, err
+			}
+			schedules[website] = schedule
+		}
+	}
+	return schedules, nil
+}
+
+// Returns the follows.yaml file and the schedules of its websites or the previous ones if it has become invalid
+//
+// If there are no previous ones, i.e. when the sync first starts, the program will exit with an error message.
+func reloadSyncFollows(prevFollows *followsFile, prevSchedules map[string]*utils.CronSchedule, defaultSchedule *utils.CronSchedule) (*followsFile, map[string]*utils.CronSchedule) {
+	follows, err := loadFollowsFile(syncFilePath)
+	var schedules map[string]*utils.CronSchedule
+	if err == nil {
+		schedules, err = getSyncSchedules(follows, defaultSchedule)
+	}
+	if err == nil {
+		return follows, schedules
+	}
+
+	if prevFollows == nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+	utils.LogError(err, "the previous follows file will be used until it is fixed", false, utils.ERROR)
+	return prevFollows, prevSchedules
+}
+
+// Keeps syncing the followed creators of each website whenever its schedule is due until the program is stopped.
+//
+// The follows.yaml file is read again before each sync so that it can be edited without restarting the program.
+func watchSync(defaultSchedule *utils.CronSchedule) {
+	follows, schedules := reloadSyncFollows(nil, nil, defaultSchedule)
+	for {
+		var nextRun time.Time
+		var dueWebsites []string
+		now := time.Now()
+		for website, schedule := range schedules {
+			next := schedule.Next(now)
+			if nextRun.IsZero() || next.Before(nextRun) {
+				nextRun = next
+				dueWebsites = []string{website}
+			} else if next.Equal(nextRun) {
+				dueWebsites = append(dueWebsites, website)
+			}
+		}
+		if nextRun.IsZero() {
+			// no creators in the follows.yaml file yet
+			nextRun = defaultSchedule.Next(now)
+		}
+		sort.Strings(dueWebsites)
+		color.Cyan("\nThe next sync of %s will start at %s", strings.Join(dueWebsites, ", "), nextRun.Format("2006-01-02 15:04"))
+		time.Sleep(time.Until(nextRun))
+
+		follows, schedules = reloadSyncFollows(follows, schedules, defaultSchedule)
+		var creators []*followedCreator
+		for _, creator := range follows.Creators {
+			website := textparser.GetUrlWebsite(getFollowedCreatorLine(creator, &follows.Defaults))
+			if utils.SliceContains(dueWebsites, website) {
+				creators = append(creators, creator)
+			}
+		}
+		if len(creators) == 0 {
+			continue
+		}
+
+		color.Cyan("\nSyncing the creators of %s", strings.Join(dueWebsites, ", "))
+		if failed := syncCreators(follows, creators); failed > 0 {
+			color.Red("\nFailed to sync %d of %d creator(s), please refer to the logs for more details", failed, len(creators))
+		} else {
+			color.Green("\nSynced %d creator(s)", len(creators))
+		}
+	}
+}
+
+// Returns the default path to the follows.yaml file in the app data folder
+func getFollowsFilePath() string {
+	return filepath.Join(utils.APP_PATH, "follows.yaml")
+}
+
+// Returns the parsed follows.yaml file at the given path after validating the creators' URLs and page numbers
+func loadFollowsFile(filePath string) (*followsFile, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to read follows file at %s, more info => %v",
+			utils.OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+
+	var follows followsFile
+	if err := yaml.Unmarshal(data, &follows); err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to parse follows file at %s, more info => %v",
+			utils.INPUT_ERROR,
+			filePath,
+			err,
+		)
+	}
+
+	for _, creator := range follows.Creators {
+		if textparser.GetUrlWebsite(getFollowedCreatorLine(creator, &follows.Defaults)) == "" {
+			return nil, fmt.Errorf(
+				"error %d: unsupported URL or invalid page number for %q in %s",
+				utils.INPUT_ERROR,
+				creator.Url,
+				filePath,
+			)
+		}
+	}
+	return &follows, nil
+}
+
+// Adds the creators' URLs that are not in the follows.yaml file at the given path to its
+// list of creators, creating the file if it does not exist, and returns the added URLs.
+//
+// The file is edited as a YAML node tree to keep the user's comments and formatting.
+func addToFollowsFile(filePath string, urls []string) ([]string, error) {
+	var doc yaml.Node
+	data, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf(
+			"error %d: failed to read follows file at %s, more info => %v",
+			utils.OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to parse follows file at %s, more info => %v",
+			utils.INPUT_ERROR,
+			filePath,
+			err,
+		)
+	}
+	if doc.Kind == 0 {
+		// empty or new file
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode}},
+		}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf(
+			"error %d: follows file at %s is not a mapping of \"defaults\" and \"creators\"",
+			utils.INPUT_ERROR,
+			filePath,
+		)
+	}
+	var creatorsNode *yaml.Node
+	for idx := 0; idx+1 < len(root.Content); idx += 2 {
+		if root.Content[idx].Value == "creators" {
+			creatorsNode = root.Content[idx+1]
+			break
+		}
+	}
+	if creatorsNode == nil || creatorsNode.Kind != yaml.SequenceNode {
+		creatorsNode = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(
+			root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "creators"},
+			creatorsNode,
+		)
+	}
+
+	var existingUrls []string
+	for _, creatorNode := range creatorsNode.Content {
+		var creator followedCreator
+		if creatorNode.Decode(&creator) == nil {
+			existingUrls = append(existingUrls, creator.Url)
+		}
+	}
+
+	var added []string
+	for _, url := range urls {
+		if utils.SliceContains(existingUrls, url) || utils.SliceContains(added, url) {
+			continue
+		}
+		creatorsNode.Content = append(creatorsNode.Content, &yaml.Node{
+			Kind: yaml.MappingNode,
+			Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "url"},
+				{Kind: yaml.ScalarNode, Value: url},
+			},
+		})
+		added = append(added, url)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to encode follows file at %s, more info => %v",
+			utils.UNEXPECTED_ERROR,
+			filePath,
+			err,
+		)
+	}
+	os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to write follows file at %s, more info => %v",
+			utils.OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+	return added, nil
+}
+
+// Returns the line for the "--txt_filepath" text file with the creator's page number, e.g. "<url>; 1-5"
+func getFollowedCreatorLine(creator *followedCreator, defaults *followOptions) string {
+	pageNum := creator.PageNum
+	if pageNum == "" {
+		pageNum = defaults.PageNum
+	}
+	if pageNum == "" {
+		return creator.Url
+	}
+	return fmt.Sprintf("%s; %s", creator.Url, pageNum)
+}
+
+// Downloads the creator's posts by running the download command of the creator's website
+func syncCreator(creator *followedCreator, defaults *followOptions) error {
+	line := getFollowedCreatorLine(creator, defaults)
+	website := textparser.GetUrlWebsite(line)
+
+	downloadPath := creator.DownloadPath
+	if downloadPath == "" {
+		downloadPath = defaults.DownloadPath
+	}
+	var env []string
+	if downloadPath != "" {
+		env = append(env, fmt.Sprintf("%s=%s", utils.DOWNLOAD_PATH_ENV, downloadPath))
+	}
+
+	args := append(append([]string{}, defaults.Args...), creator.Args...)
+	return runDownloadCmd(website, []string{line}, args, env)
+}
+
+func init() {
+	syncCmd.Flags().StringVar(
+		&syncFilePath,
+		"file",
+		getFollowsFilePath(),
+		"Path to the follows.yaml file listing the creators to download.",
+	)
+	syncCmd.Flags().StringVar(
+		&syncSchedule,
+		"schedule",
+		"",
+		utils.CombineStringsWithNewline(
+			"Keep running and sync the creators whenever the cron expression is due, e.g. \"0 3 * * *\" for every day at 3am.",
+			"Supports the standard 5 fields, \"minute hour day-of-month month day-of-week\", and macros like \"@daily\".",
+			"The \"schedules\" in the follows.yaml file can set a different schedule for each website.",
+		),
+	)
+	RootCmd.AddCommand(syncCmd)
+}
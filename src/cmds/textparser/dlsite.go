@@ -0,0 +1,51 @@
+package textparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/api/dlsite"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	DL_WORK_URL_REGEX = regexp.MustCompile(
+		// e.g. https://www.dlsite.com/maniax/work/=/product_id/RJ123456.html or https://play.dlsite.com/#/work/RJ123456
+		fmt.Sprintf(
+			`^https://(?:www\.dlsite\.com/\w+/work/=/product_id/|play\.dlsite\.com/(?:#/)?work/)(?P<workId>%s)(?:\.html)?/?$`,
+			dlsite.WORK_ID_REGEX_STR,
+		),
+	)
+	DL_WORK_REGEX_WORK_ID_INDEX = DL_WORK_URL_REGEX.SubexpIndex("workId")
+)
+
+// ParseDlsiteTextFile parses the text file at the given path and returns a slice of DLsite work IDs.
+func ParseDlsiteTextFile(textFilePath string) []string {
+	lowercaseDlsite := strings.ToLower(utils.DLSITE_TITLE)
+	f, reader := openTextFile(
+		textFilePath,
+		lowercaseDlsite,
+	)
+	defer f.Close()
+
+	var workIds []string
+	for {
+		lineBytes, isEof := readLine(reader, textFilePath, lowercaseDlsite)
+		if isEof {
+			break
+		}
+
+		url := strings.TrimSpace(string(lineBytes))
+		if url == "" {
+			continue
+		}
+
+		if matched := DL_WORK_URL_REGEX.FindStringSubmatch(url); matched != nil {
+			workIds = append(workIds, matched[DL_WORK_REGEX_WORK_ID_INDEX])
+			continue
+		}
+	}
+
+	return workIds
+}
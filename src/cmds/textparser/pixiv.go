@@ -18,21 +18,28 @@ var (
 		),
 	)
 	P_ILLUST_REGEX_ID_INDEX = P_ILLUST_URL_REGEX.SubexpIndex("illustId")
-	P_ARTIST_URL_REGEX = regexp.MustCompile(
+	P_ARTIST_URL_REGEX      = regexp.MustCompile(
 		fmt.Sprintf(
 			`^%susers/(?P<artistId>\d+)%s$`,
 			P_BASE_REGEX_STR,
 			PAGE_NUM_REGEX_STR,
 		),
 	)
-	P_ARTIST_REGEX_ID_INDEX = P_ARTIST_URL_REGEX.SubexpIndex("artistId")
+	P_ARTIST_REGEX_ID_INDEX       = P_ARTIST_URL_REGEX.SubexpIndex("artistId")
 	P_ARTIST_REGEX_PAGE_NUM_INDEX = P_ARTIST_URL_REGEX.SubexpIndex(PAGE_NUM_REGEX_GRP_NAME)
-	P_TAG_URL_REGEX = regexp.MustCompile(
+	P_TAG_URL_REGEX               = regexp.MustCompile(
 		// ^https://www\.pixiv\.net/(?:en/)?tags/(?P<tag>[\w-%()]+)(?:/(?:artworks|illustrations|manga))?(?:\?[\w=&-.]+)?(?:; (?P<pageNum>[1-9]\d*(?:-[1-9]\d*)?))?$
 		"^" + P_BASE_REGEX_STR + `tags/(?P<tag>[\w-%()]+)(?:/(?:artworks|illustrations|manga))?(?:\?[\w=&-.]+)?` + PAGE_NUM_REGEX_STR + "$",
 	)
-	P_TAG_REGEX_TAG_INDEX = P_TAG_URL_REGEX.SubexpIndex("tag")
+	P_TAG_REGEX_TAG_INDEX      = P_TAG_URL_REGEX.SubexpIndex("tag")
 	P_TAG_REGEX_PAGE_NUM_INDEX = P_TAG_URL_REGEX.SubexpIndex(PAGE_NUM_REGEX_GRP_NAME)
+	P_SERIES_URL_REGEX         = regexp.MustCompile(
+		fmt.Sprintf(
+			`^%suser/\d+/series/(?P<seriesId>\d+)$`,
+			P_BASE_REGEX_STR,
+		),
+	)
+	P_SERIES_REGEX_ID_INDEX = P_SERIES_URL_REGEX.SubexpIndex("seriesId")
 )
 
 type parsedPixivArtist struct {
@@ -44,8 +51,8 @@ type parsedPixivTag struct {
 	PageNum  string
 }
 
-// ParsePixivTextFile parses the text file at the given path and returns a slice of post IDs, a slice of parsedPixivArtist, and a slice of parsedPixivTag.
-func ParsePixivTextFile(textFilePath string) ([]string, []*parsedPixivArtist, []*parsedPixivTag) {
+// ParsePixivTextFile parses the text file at the given path and returns a slice of post IDs, a slice of parsedPixivArtist, a slice of parsedPixivTag, and a slice of series IDs.
+func ParsePixivTextFile(textFilePath string) ([]string, []*parsedPixivArtist, []*parsedPixivTag, []string) {
 	f, reader := openTextFile(
 		textFilePath, 
 		utils.PIXIV,
@@ -55,6 +62,7 @@ func ParsePixivTextFile(textFilePath string) ([]string, []*parsedPixivArtist, []
 	var postIds []string
 	var artistIds []*parsedPixivArtist
 	var tags []*parsedPixivTag
+	var seriesIds []string
 	for {
 		lineBytes, isEof := readLine(reader, textFilePath, utils.PIXIV)
 		if isEof {
@@ -86,7 +94,12 @@ func ParsePixivTextFile(textFilePath string) ([]string, []*parsedPixivArtist, []
 			})
 			continue
 		}
+
+		if matched := P_SERIES_URL_REGEX.FindStringSubmatch(url); matched != nil {
+			seriesIds = append(seriesIds, matched[P_SERIES_REGEX_ID_INDEX])
+			continue
+		}
 	}
 
-	return postIds, artistIds, tags
+	return postIds, artistIds, tags, seriesIds
 }
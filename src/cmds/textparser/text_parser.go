@@ -6,14 +6,13 @@ import (
 	"io"
 	"fmt"
 
-	"github.com/fatih/color"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
 const PAGE_NUM_REGEX_GRP_NAME = "pageNum"
 
 var PAGE_NUM_REGEX_STR = fmt.Sprintf(
-	`(?:; (?P<%s>[1-9]\d*(?:-[1-9]\d*)?))?`,
+	`(?:; (?P<%s>(?i:all)|[1-9]\d*(?:-(?:[1-9]\d*)?)?|-[1-9]\d*))?`,
 	PAGE_NUM_REGEX_GRP_NAME,
 )
 
@@ -30,8 +29,7 @@ func openTextFile(textFilePath, website string) (*os.File, *bufio.Reader) {
 			textFilePath,
 			err,
 		)
-		color.Red(errMsg)
-		os.Exit(1)
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%s", errMsg)
 	}
 	return f, bufio.NewReader(f)
 }
@@ -53,8 +51,7 @@ func readLine(reader *bufio.Reader, textFilePath, website string) ([]byte, bool)
 			textFilePath,
 			err,
 		)
-		color.Red(errMsg)
-		os.Exit(1)
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%s", errMsg)
 	}
 	return lineBytes, false
 }
@@ -0,0 +1,38 @@
+package textparser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+type websiteUrlRegexes struct {
+	website string
+	regexes []*regexp.Regexp
+}
+
+// The regexes of the URLs that can be parsed from the text file of each website
+var websitesUrlRegexes = []websiteUrlRegexes{
+	{website: utils.FANTIA, regexes: []*regexp.Regexp{F_POST_URL_REGEX, F_FANCLUB_URL_REGEX}},
+	{website: utils.PIXIV_FANBOX, regexes: []*regexp.Regexp{PF_POST_URL_REGEX, PF_CREATOR_URL_REGEX}},
+	{website: utils.PIXIV, regexes: []*regexp.Regexp{P_ILLUST_URL_REGEX, P_ARTIST_URL_REGEX, P_TAG_URL_REGEX, P_SERIES_URL_REGEX}},
+	{website: utils.KEMONO, regexes: []*regexp.Regexp{K_POST_URL_REGEX, K_CREATOR_URL_REGEX}},
+	{website: utils.DLSITE, regexes: []*regexp.Regexp{DL_WORK_URL_REGEX}},
+}
+
+// Returns the website, e.g. utils.FANTIA, of the given URL if it is a
+// supported URL that can be written to the website's text file for the "--txt_filepath" flag.
+//
+// Returns an empty string if the URL is not supported.
+func GetUrlWebsite(url string) string {
+	url = strings.TrimSpace(url)
+	for _, websiteRegexes := range websitesUrlRegexes {
+		for _, regex := range websiteRegexes.regexes {
+			if regex.MatchString(url) {
+				return websiteRegexes.website
+			}
+		}
+	}
+	return ""
+}
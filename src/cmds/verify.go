@@ -0,0 +1,231 @@
+package cmds
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyRemoveCorrupted bool
+	verifyRequeue         bool
+	verifyCmd             = &cobra.Command{
+		Use:   "verify [directory]",
+		Short: "Verify downloaded files against their SHA256SUMS manifests and the download log",
+		Long: utils.CombineStringsWithNewline(
+			"Re-hashes the files listed in the SHA256SUMS manifests written by the \"--checksums\" flag",
+			fmt.Sprintf(
+				"and the files recorded in the %s download log written by the \"--download_log\" flag,",
+				request.DOWNLOAD_LOG_FILENAME,
+			),
+			"and reports any files that are missing, truncated, or corrupted, e.g. by bit rot.",
+			"Defaults to verifying your saved download directory, or the current working directory if not set.",
+		),
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			rootDir := utils.DOWNLOAD_PATH
+			if len(args) > 0 {
+				rootDir = args[0]
+			}
+			if rootDir == "" {
+				rootDir = "."
+			}
+			if !utils.PathExists(rootDir) {
+				utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "error %d: directory at %s does not exist", utils.INPUT_ERROR, rootDir)
+			}
+
+			var manifests []string
+			filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() && d.Name() == utils.CHECKSUM_MANIFEST_FILENAME {
+					manifests = append(manifests, path)
+				}
+				return nil
+			})
+
+			logPath := filepath.Join(rootDir, request.DOWNLOAD_LOG_FILENAME)
+			logEntries, err := request.ReadDownloadLog(logPath)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				color.Red(err.Error())
+			}
+			if len(manifests) == 0 && len(logEntries) == 0 {
+				color.Yellow(
+					"No %s manifests or %s download log found in %s",
+					utils.CHECKSUM_MANIFEST_FILENAME,
+					request.DOWNLOAD_LOG_FILENAME,
+					rootDir,
+				)
+				return
+			}
+
+			totalChecked, totalBad := 0, 0
+			var badFiles []*utils.ChecksumResult
+			reported := make(map[string]struct{})
+			for _, manifest := range manifests {
+				results, checked, err := utils.VerifyChecksumManifest(manifest)
+				totalChecked += checked
+				if err != nil {
+					color.Red(err.Error())
+					totalBad++
+					continue
+				}
+
+				for _, result := range results {
+					if entry, ok := logEntries[filepath.Clean(result.FilePath)]; ok {
+						result.Url = entry.Url
+					}
+					reported[filepath.Clean(result.FilePath)] = struct{}{}
+					badFiles = append(badFiles, result)
+				}
+			}
+
+			logResults, logChecked := request.VerifyDownloadLog(logEntries)
+			for _, result := range logResults {
+				// the files in the manifests are also in the download log if both flags were set
+				if _, ok := reported[result.FilePath]; !ok {
+					badFiles = append(badFiles, result)
+				}
+			}
+
+			removed := 0
+			for _, result := range badFiles {
+				totalBad++
+				if printChecksumResult(result) {
+					removed++
+				}
+			}
+
+			color.Cyan(
+				"\nVerified %d file(s) from %d manifest(s) and %d file(s) from the download log",
+				totalChecked,
+				len(manifests),
+				logChecked,
+			)
+			if totalBad == 0 {
+				color.Green("All files are intact")
+				return
+			}
+
+			if removed > 0 {
+				color.Yellow("%d corrupted file(s) have been removed", removed)
+			}
+			if verifyRequeue {
+				requeueFiles(rootDir, badFiles)
+			} else if removed > 0 {
+				color.Yellow("Please run the download command again to re-download them")
+			}
+			utils.ExitWithErrorf(1, "Found %d problem(s)", totalBad)
+		},
+	}
+)
+
+// Prints the result of the file that did not pass the verification and removes it
+// if it is corrupted and the --remove_corrupted or --requeue flag is set.
+//
+// Returns true if the file was removed.
+func printChecksumResult(result *utils.ChecksumResult) bool {
+	switch {
+	case result.Status == utils.CHECKSUM_MISSING:
+		color.Red("✗ missing: %s", result.FilePath)
+		return false
+	case result.Status == utils.CHECKSUM_TRUNCATED:
+		color.Red("✗ truncated: %s", result.FilePath)
+	case result.Err != nil:
+		color.Red("✗ unreadable: %s (%v)", result.FilePath, result.Err)
+	default:
+		color.Red("✗ corrupted: %s", result.FilePath)
+	}
+	if !verifyRemoveCorrupted && !verifyRequeue {
+		return false
+	}
+
+	// the download commands will re-download the file as it no longer exists
+	if err := os.Remove(result.FilePath); err != nil {
+		color.Red("  failed to remove %s: %v", result.FilePath, err)
+		return false
+	}
+	return true
+}
+
+// Returns the website of the file based on its folder in the download directory, e.g. "Fantia/Creator/..." is Fantia
+func getSiteFromPath(rootDir, filePath string) string {
+	relPath, err := filepath.Rel(rootDir, filePath)
+	if err != nil {
+		return ""
+	}
+	siteFolder, _, _ := strings.Cut(filepath.ToSlash(relPath), "/")
+	for _, site := range []string{utils.FANTIA, utils.PIXIV, utils.PIXIV_FANBOX, utils.KEMONO, utils.DLSITE} {
+		if siteFolder == utils.GetReadableSiteStr(site) {
+			return site
+		}
+	}
+	return ""
+}
+
+// Adds the missing and corrupted files with a known source URL to the download queue
+// of their website so that they are downloaded first by the next run of its download command
+func requeueFiles(rootDir string, badFiles []*utils.ChecksumResult) {
+	toRequeue := make(map[string][]*request.ToDownload)
+	skipped := 0
+	for _, result := range badFiles {
+		site := getSiteFromPath(rootDir, result.FilePath)
+		if result.Url == "" || site == "" || utils.PathExists(result.FilePath) {
+			// the file could not be removed or its source is not known
+			skipped++
+			continue
+		}
+		toRequeue[site] = append(toRequeue[site], &request.ToDownload{
+			Url:      result.Url,
+			FilePath: result.FilePath,
+		})
+	}
+
+	for site, toDownload := range toRequeue {
+		if err := request.RequeueFiles(site, toDownload); err != nil {
+			color.Red(err.Error())
+			continue
+		}
+		color.Yellow(
+			"Queued %d file(s) to be downloaded by the next run of the %s download command",
+			len(toDownload),
+			utils.GetReadableSiteStr(site),
+		)
+	}
+	if skipped > 0 {
+		color.Yellow(
+			"%d file(s) could not be queued as their source URL is not in the %s download log",
+			skipped,
+			request.DOWNLOAD_LOG_FILENAME,
+		)
+	}
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(
+		&verifyRemoveCorrupted,
+		"remove_corrupted",
+		false,
+		utils.CombineStringsWithNewline(
+			"Remove the corrupted and truncated files so that they will be re-downloaded",
+			"the next time you run the download command for the same posts.",
+		),
+	)
+	verifyCmd.Flags().BoolVar(
+		&verifyRequeue,
+		"requeue",
+		false,
+		utils.CombineStringsWithNewline(
+			"Remove the corrupted and truncated files and queue them with the missing files to be downloaded",
+			"by the next run of the download command of their website, even if their posts are not downloaded again.",
+			fmt.Sprintf("Only the files recorded in the %s download log can be queued.", request.DOWNLOAD_LOG_FILENAME),
+		),
+	)
+	RootCmd.AddCommand(verifyCmd)
+}
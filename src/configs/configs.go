@@ -1,12 +1,49 @@
 package configs
 
 import (
-	"os"
-	"os/exec"
+	"fmt"
+	"strings"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/fatih/color"
 )
 
+const (
+	// Download creator posts starting from the newest post
+	ORDER_DESC = "desc"
+
+	// Download creator posts starting from the oldest post
+	ORDER_ASC = "asc"
+)
+
+var ACCEPTED_ORDERS = []string{ORDER_DESC, ORDER_ASC}
+
+const (
+	// Save posts in "<creator>/<post>"
+	LAYOUT_FLAT = "flat"
+
+	// Save posts in "<creator>/<YYYY>/<MM>/<post>" based on the publish date of the post
+	LAYOUT_DATE = "date"
+)
+
+var ACCEPTED_LAYOUTS = []string{LAYOUT_FLAT, LAYOUT_DATE}
+
+const (
+	// Only download images, e.g. ".jpg" and ".png" files
+	ONLY_IMAGES = "images"
+
+	// Only download videos, e.g. ".mp4" and ".mov" files
+	ONLY_VIDEOS = "videos"
+
+	// Only download the files that are not images, videos, or text, e.g. ".zip" and ".psd" files
+	ONLY_ATTACHMENTS = "attachments"
+
+	// Only download text files, e.g. ".txt" files and Kemono Party announcements
+	ONLY_TEXT = "text"
+)
+
+var ACCEPTED_ONLY = []string{ONLY_IMAGES, ONLY_VIDEOS, ONLY_ATTACHMENTS, ONLY_TEXT}
+
 type Config struct {
 	// DownloadPath will be used as the base path for all downloads
 	DownloadPath   string
@@ -24,13 +61,228 @@ type Config struct {
 	LogUrls		   bool
 
 	// UserAgent is the user agent to be used in the download process
-	UserAgent      string
+	UserAgent string
+
+	// ChecksumManifest is a flag to write a SHA256SUMS manifest
+	// in each post folder after the files have been downloaded
+	ChecksumManifest bool
+
+	// ExtractArchives is a flag to extract the archives in each post folder
+	// after the files have been downloaded with the passwords detected in the post
+	ExtractArchives bool
+
+	// VerifyImages is a flag to decode the downloaded images to
+	// detect truncated or corrupted images which will then be re-downloaded
+	VerifyImages bool
+
+	// Order is the order in which creator posts are downloaded,
+	// either ORDER_DESC (newest first) or ORDER_ASC (oldest first)
+	Order string
+
+	// MaxTotalSize is the maximum number of bytes to download in a run, 0 means no limit
+	MaxTotalSize int64
+
+	// MaxTotalFiles is the maximum number of files to download in a run, 0 means no limit
+	MaxTotalFiles int
+
+	// QueueFilePath is the path to persist the remaining files to
+	// when the per-run quota has been reached so that they can be downloaded in the next run
+	QueueFilePath string
+
+	// StopAfterSeen is the number of already downloaded posts in a row
+	// after which the pagination of a creator's posts is stopped, 0 means never stop
+	StopAfterSeen int
+
+	// Layout is the folder layout of the downloaded posts,
+	// either LAYOUT_FLAT or LAYOUT_DATE
+	Layout string
+
+	// MaxTitleLength is the maximum number of characters of the
+	// post title in the post folder name, 0 means no limit
+	MaxTitleLength int
+
+	// Only is the type of files to download, either ONLY_IMAGES,
+	// ONLY_VIDEOS, ONLY_ATTACHMENTS, ONLY_TEXT, or empty to download all files
+	Only string
+
+	// OnCreatorRename is what to do with the creator's folder when the creator renames,
+	// either utils.CREATOR_RENAME_KEEP, utils.CREATOR_RENAME_MOVE, or utils.CREATOR_RENAME_SYMLINK
+	OnCreatorRename string
+
+	// PostSelector lets the user pick which of the resolved posts to download, nil to download all posts
+	PostSelector *PostSelector
+}
+
+// Returns true if the file is in a post that should be downloaded based on the PostSelector of the config
+func (c *Config) ShouldDlPost(filePath string) bool {
+	if c.PostSelector == nil {
+		return true
+	}
+	return c.PostSelector.IsSelected(filePath)
 }
 
+// Validates the Order field of the config and defaults it to ORDER_DESC if empty
+//
+// Otherwise, the program exits after printing error messages for the user to read
+func (c *Config) ValidateOrder() {
+	c.Order = strings.ToLower(c.Order)
+	if c.Order == "" {
+		c.Order = ORDER_DESC
+		return
+	}
+
+	utils.ValidateStrArgs(
+		c.Order,
+		ACCEPTED_ORDERS,
+		[]string{
+			fmt.Sprintf(
+				"config error %d: Order %s is not allowed",
+				utils.INPUT_ERROR,
+				c.Order,
+			),
+		},
+	)
+}
+
+// Validates the Layout field of the config and defaults it to LAYOUT_FLAT if empty
+//
+// Otherwise, the program exits after printing error messages for the user to read
+func (c *Config) ValidateLayout() {
+	c.Layout = strings.ToLower(c.Layout)
+	if c.Layout == "" {
+		c.Layout = LAYOUT_FLAT
+		return
+	}
+
+	utils.ValidateStrArgs(
+		c.Layout,
+		ACCEPTED_LAYOUTS,
+		[]string{
+			fmt.Sprintf(
+				"config error %d: Layout %s is not allowed",
+				utils.INPUT_ERROR,
+				c.Layout,
+			),
+		},
+	)
+}
+
+// Validates the OnCreatorRename field of the config and defaults it to utils.CREATOR_RENAME_KEEP if empty
+//
+// Otherwise, the program exits after printing error messages for the user to read
+func (c *Config) ValidateOnCreatorRename() {
+	c.OnCreatorRename = strings.ToLower(c.OnCreatorRename)
+	if c.OnCreatorRename == "" {
+		c.OnCreatorRename = utils.CREATOR_RENAME_KEEP
+		return
+	}
+
+	utils.ValidateStrArgs(
+		c.OnCreatorRename,
+		utils.ACCEPTED_CREATOR_RENAMES,
+		[]string{
+			fmt.Sprintf(
+				"config error %d: OnCreatorRename %s is not allowed",
+				utils.INPUT_ERROR,
+				c.OnCreatorRename,
+			),
+		},
+	)
+}
+
+// Validates the Only field of the config which can be empty to download all files
+//
+// Otherwise, the program exits after printing error messages for the user to read
+func (c *Config) ValidateOnly() {
+	c.Only = strings.ToLower(c.Only)
+	if c.Only == "" {
+		return
+	}
+
+	utils.ValidateStrArgs(
+		c.Only,
+		ACCEPTED_ONLY,
+		[]string{
+			fmt.Sprintf(
+				"config error %d: File type %s is not allowed",
+				utils.INPUT_ERROR,
+				c.Only,
+			),
+		},
+	)
+}
+
+// Returns a directory path for a post based on the Layout field of the config
+//
+// The post title will be truncated based on the MaxTitleLength field of the config and
+// publishedAt is the publish date of the post from the website's API which is only used for LAYOUT_DATE.
+func (c *Config) GetPostFolder(downloadPath, creatorName, postId, postTitle, publishedAt string) string {
+	postTitle = utils.TruncateString(postTitle, c.MaxTitleLength)
+	if c.Layout == LAYOUT_DATE {
+		return utils.GetDatedPostFolder(
+			downloadPath,
+			creatorName,
+			postId,
+			postTitle,
+			utils.ParsePostDate(publishedAt),
+		)
+	}
+	return utils.GetPostFolder(downloadPath, creatorName, postId, postTitle)
+}
+
+// Returns the name of the creator's folder in the site folder which stays the same after the creator renames,
+// unless the OnCreatorRename field is set to rename the folder, based on the creator name history in utils.ResolveCreatorFolder
+func (c *Config) GetCreatorFolder(siteFolderPath, site, creatorId, creatorName string) string {
+	return utils.ResolveCreatorFolder(siteFolderPath, site, creatorId, creatorName, c.OnCreatorRename)
+}
+
+// Returns true if creator posts should be downloaded starting from the oldest post
+func (c *Config) IsAscOrder() bool {
+	return c.Order == ORDER_ASC
+}
+
+// Returns a SeenTracker for the posts in the given site folder based on the StopAfterSeen field
+//
+// Returns nil if the pagination should never be stopped early,
+// which is also the case for ORDER_ASC as the oldest posts are paginated last.
+func (c *Config) NewSeenTracker(siteFolderPath string) *utils.SeenTracker {
+	if c.IsAscOrder() {
+		return nil
+	}
+	return utils.NewSeenTracker(siteFolderPath, c.StopAfterSeen)
+}
+
+// Sets the FfmpegPath field of the config to the absolute path of FFmpeg found by utils.FindFfmpeg
+// and prints a warning if its version is older than the supported version.
+//
+// Otherwise, the program exits after printing error messages for the user to read if FFmpeg cannot be found.
 func (c *Config) ValidateFfmpeg() {
-	_, ffmpegErr := exec.LookPath(c.FfmpegPath)
-	if ffmpegErr != nil {
-		color.Red("FFmpeg is not installed.\nPlease install it from https://ffmpeg.org/ and either use the --ffmpeg_path flag or add the FFmpeg path to your PATH environment variable or alias depending on your OS.")
-		os.Exit(1)
+	ffmpegPath, err := utils.FindFfmpeg(c.FfmpegPath)
+	if err != nil {
+		utils.ExitWithErrorf(
+			utils.EXIT_INPUT_ERROR,
+			utils.CombineStringsWithNewline(
+				err.Error(),
+				"FFmpeg is not installed.",
+				"Please install it from "+utils.FFMPEG_DOWNLOAD_URL+" and either use the --ffmpeg_path flag,",
+				"set the \"ffmpeg_path\" in the \"tools\" section of the config file,",
+				"or add the FFmpeg path to your PATH environment variable or alias depending on your OS.",
+			),
+		)
+	}
+	c.FfmpegPath = ffmpegPath
+
+	version, err := utils.GetFfmpegVersion(ffmpegPath)
+	if err != nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+	if !utils.IsFfmpegVersionSupported(version) {
+		color.Yellow(
+			"FFmpeg %s at %s is older than version %d and may not work as expected, please update it from %s",
+			version,
+			ffmpegPath,
+			utils.MIN_FFMPEG_MAJOR_VERSION,
+			utils.FFMPEG_DOWNLOAD_URL,
+		)
 	}
 }
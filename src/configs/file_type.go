@@ -0,0 +1,54 @@
+package configs
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+var (
+	imageExts = []string{
+		".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".avif", ".apng", ".tif", ".tiff", ".heic", ".jfif",
+	}
+	videoExts = []string{
+		".mp4", ".webm", ".mov", ".mkv", ".avi", ".m4v", ".wmv", ".flv", ".ts",
+	}
+	textExts = []string{
+		".txt", ".md", ".html", ".htm", ".rtf",
+	}
+)
+
+// Returns the type of the file based on its extension, i.e. ONLY_IMAGES,
+// ONLY_VIDEOS, ONLY_TEXT, ONLY_ATTACHMENTS, or an empty string if the file has no extension
+func GetFileType(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return ""
+	}
+
+	for _, fileType := range []struct {
+		name string
+		exts []string
+	}{
+		{ONLY_IMAGES, imageExts},
+		{ONLY_VIDEOS, videoExts},
+		{ONLY_TEXT, textExts},
+	} {
+		for _, fileTypeExt := range fileType.exts {
+			if ext == fileTypeExt {
+				return fileType.name
+			}
+		}
+	}
+	return ONLY_ATTACHMENTS
+}
+
+// Returns true if the file should be downloaded based on the Only field of the config
+//
+// Files without an extension will always be downloaded as their type cannot be determined.
+func (c *Config) ShouldDlFile(filename string) bool {
+	if c.Only == "" {
+		return true
+	}
+	fileType := GetFileType(filename)
+	return fileType == "" || fileType == c.Only
+}
@@ -0,0 +1,164 @@
+package configs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/stats"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+// PostSelector lets the user pick which of the resolved posts to download before their files are downloaded
+type PostSelector struct {
+	events.BaseHandler
+
+	mu        sync.Mutex
+	reader    *bufio.Reader
+	pending   []*events.Post  // the resolved posts that the user has not picked from yet
+	selected  map[string]bool // whether the post is selected keyed by its folder
+	estimator *stats.SizeEstimator
+}
+
+// Returns a new PostSelector that should be registered to receive the resolved posts
+func NewPostSelector() *PostSelector {
+	// the size estimates are not shown if the statistics of the past runs cannot be loaded
+	runs, _ := stats.LoadRuns()
+	return &PostSelector{
+		reader:    bufio.NewReader(os.Stdin),
+		selected:  make(map[string]bool),
+		estimator: stats.NewSizeEstimator(runs),
+	}
+}
+
+func (s *PostSelector) OnPostResolved(post *events.Post) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, post)
+}
+
+// Parses the user's selection of the numbered posts,
+// e.g. "1,3,5-8", "all", or "none", into the indexes of the selected posts
+func parsePostSelection(input string, postCount int) (map[int]struct{}, error) {
+	selection := make(map[int]struct{})
+	switch strings.ToLower(input) {
+	case "", "all":
+		for idx := 0; idx < postCount; idx++ {
+			selection[idx] = struct{}{}
+		}
+		return selection, nil
+	case "none":
+		return selection, nil
+	}
+
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		startStr, endStr, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(startStr))
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(endStr))
+		}
+		if err != nil || start < 1 || end > postCount || start > end {
+			return nil, fmt.Errorf("invalid selection %q, please use numbers from 1 to %d", part, postCount)
+		}
+		for num := start; num <= end; num++ {
+			selection[num-1] = struct{}{}
+		}
+	}
+	return selection, nil
+}
+
+// Prints the pending posts and asks the user to pick which of them to download
+func (s *PostSelector) promptPending() {
+	posts := s.pending
+	s.pending = nil
+
+	// newest posts first and the posts without a publish date last
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].PublishedAt.After(posts[j].PublishedAt)
+	})
+
+	var totalSize int64
+	lines := make([]string, len(posts))
+	for idx, post := range posts {
+		date := "----------"
+		if !post.PublishedAt.IsZero() {
+			date = post.PublishedAt.Local().Format("2006-01-02")
+		}
+		lines[idx] = fmt.Sprintf("  [%d] %s  %s (%d file(s)", idx+1, date, post.Title, post.FileCount)
+		if size := s.estimator.Estimate(post.Site, post.Creator, post.FileCount); size > 0 {
+			totalSize += size
+			lines[idx] += ", ~" + utils.FormatFileSize(size)
+		}
+		lines[idx] += ")"
+	}
+
+	if totalSize > 0 {
+		color.Cyan(
+			"\nFound %d post(s) to download (~%s estimated from the average file size of the past runs):",
+			len(posts),
+			utils.FormatFileSize(totalSize),
+		)
+	} else {
+		color.Cyan("\nFound %d post(s) to download:", len(posts))
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+
+	var selection map[int]struct{}
+	for {
+		fmt.Print(color.YellowString("Posts to download, e.g. \"1,3,5-8\", \"all\", or \"none\" [all]: "))
+		input, err := s.reader.ReadString('\n')
+		if err != nil && input == "" {
+			// e.g. stdin is not a terminal
+			input = "all"
+		}
+
+		if selection, err = parsePostSelection(strings.TrimSpace(input), len(posts)); err == nil {
+			break
+		}
+		color.Red(err.Error())
+	}
+
+	for idx, post := range posts {
+		_, ok := selection[idx]
+		s.selected[filepath.Clean(post.Folder)] = ok
+	}
+}
+
+// Returns true if the file is in the folder of a post that the user has selected
+// or if the file does not belong to any resolved post, e.g. a file in the queue from the previous run.
+//
+// The user will be asked to pick from the posts that have been resolved since the last time they were asked.
+func (s *PostSelector) IsSelected(filePath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) > 0 {
+		s.promptPending()
+	}
+
+	dir := filepath.Clean(filePath)
+	for {
+		if selected, ok := s.selected[dir]; ok {
+			return selected
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return true
+		}
+		dir = parentDir
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"os"
+
 	"github.com/KJHJason/Cultured-Downloader-CLI/cmds"
-	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
 func main() {
@@ -16,5 +18,8 @@ func main() {
 		utils.LogError(err, "", false, utils.ERROR)
 	}
 
-	cmds.RootCmd.Execute()
+	if err := cmds.RootCmd.Execute(); err != nil {
+		// cobra has already printed the error, e.g. an unknown flag
+		os.Exit(utils.EXIT_INPUT_ERROR)
+	}
 }
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Post contains the details of a post, artwork, etc. that has been resolved into files to download
+type Post struct {
+	Site      string // e.g. "fantia", see the constants in the utils package
+	Id        string
+	Title     string
+	Creator   string
+	CreatorId string // empty if the website's creator ID is not known, e.g. for DLsite
+	Folder    string // the folder that the post's files will be downloaded to
+	FileCount int
+
+	// FileFilter is the download options that the FileCount depends on, see GetFileFilter,
+	// empty if the number of files of the post does not depend on the download options
+	FileFilter string
+
+	// PublishedAt is the zero time if the website does not provide the publish date of the post
+	PublishedAt time.Time
+
+	// Body is the text of the post, e.g. its description, which is HTML if BodyIsHtml is true
+	Body       string
+	BodyIsHtml bool
+}
+
+// Returns the FileFilter of a post from the download options that its number of files depends on,
+// e.g. {"images": true, "attachments": false} returns "images"
+func GetFileFilter(options map[string]bool) string {
+	var enabled []string
+	for option, isEnabled := range options {
+		if isEnabled {
+			enabled = append(enabled, option)
+		}
+	}
+	sort.Strings(enabled)
+	return strings.Join(enabled, ",")
+}
+
+// File contains the details of a file that is being downloaded
+type File struct {
+	Url string
+
+	// FilePath may be the folder that the file will be downloaded to until
+	// its filename is known from the response, e.g. from the Content-Disposition header
+	FilePath string
+
+	// Size is the number of bytes written to the file which is only set when it has been downloaded
+	Size int64
+
+	// Skipped is true if the file was not downloaded, e.g. as it already exists
+	// or the download quota has been reached, which is only set when it is done
+	Skipped bool
+}
+
+// Handler receives the events of the download process which the CLI's download spinner consumes too.
+//
+// Frontends embedding the packages of this program, e.g. a GUI,
+// can register a Handler to render their own progress without scraping the stdout.
+// Embed BaseHandler to only implement the events that are needed.
+//
+// The methods may be called concurrently from multiple goroutines.
+type Handler interface {
+	// OnPostResolved is called when a post's details have been retrieved and its files are known
+	OnPostResolved(post *Post)
+
+	// OnPostNotFound is called when the website responds with a 404 for the details of a post,
+	// e.g. as the post has been deleted by the creator
+	OnPostNotFound(site, postId string)
+
+	// OnFileStart is called when a file starts downloading,
+	// before it is known whether the file will be skipped
+	OnFileStart(file *File)
+
+	// OnFileProgress is called periodically while a file is downloading.
+	// total will be -1 if the file size is unknown.
+	OnFileProgress(file *File, downloaded, total int64)
+
+	// OnFileDone is called once for each file that has been started, err will be nil if it was successful.
+	// The file's Skipped field is true if it was not downloaded.
+	OnFileDone(file *File, err error)
+
+	// OnError is called when an error is logged
+	OnError(err error)
+}
+
+// BaseHandler implements Handler with methods that do nothing
+type BaseHandler struct{}
+
+func (BaseHandler) OnPostResolved(post *Post)                          {}
+func (BaseHandler) OnPostNotFound(site, postId string)                 {}
+func (BaseHandler) OnFileStart(file *File)                             {}
+func (BaseHandler) OnFileProgress(file *File, downloaded, total int64) {}
+func (BaseHandler) OnFileDone(file *File, err error)                   {}
+func (BaseHandler) OnError(err error)                                  {}
+
+var (
+	handlersMu sync.RWMutex
+	handlers   []*registeredHandler
+)
+
+type registeredHandler struct {
+	handler Handler
+}
+
+// Register adds the handler to receive the events of the download process
+// and returns a function to unregister it.
+func Register(handler Handler) func() {
+	registered := &registeredHandler{handler: handler}
+	handlersMu.Lock()
+	handlers = append(handlers, registered)
+	handlersMu.Unlock()
+
+	return func() {
+		handlersMu.Lock()
+		defer handlersMu.Unlock()
+		for idx, h := range handlers {
+			if h == registered {
+				handlers = append(handlers[:idx], handlers[idx+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// HasHandlers returns true if there are any registered handlers.
+//
+// Used to avoid the overhead of creating the events when nobody is listening.
+func HasHandlers() bool {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	return len(handlers) > 0
+}
+
+func emit(fn func(handler Handler)) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	for _, h := range handlers {
+		fn(h.handler)
+	}
+}
+
+func PostResolved(post *Post) {
+	emit(func(handler Handler) { handler.OnPostResolved(post) })
+}
+
+func PostNotFound(site, postId string) {
+	emit(func(handler Handler) { handler.OnPostNotFound(site, postId) })
+}
+
+func FileStart(file *File) {
+	emit(func(handler Handler) { handler.OnFileStart(file) })
+}
+
+func FileProgress(file *File, downloaded, total int64) {
+	emit(func(handler Handler) { handler.OnFileProgress(file, downloaded, total) })
+}
+
+func FileDone(file *File, err error) {
+	emit(func(handler Handler) { handler.OnFileDone(file, err) })
+}
+
+func Error(err error) {
+	emit(func(handler Handler) { handler.OnError(err) })
+}
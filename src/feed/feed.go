@@ -0,0 +1,246 @@
+package feed
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Maximum number of posts kept in the feed where the oldest posts are removed first
+const MAX_ITEMS = 200
+
+type rssGuid struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category,omitempty"`
+	Guid        rssGuid `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name    `xml:"rss"`
+	Version string      `xml:"version,attr"`
+	Channel *rssChannel `xml:"channel"`
+}
+
+// A post that had at least one of its files downloaded in this run
+type newPost struct {
+	post      *events.Post
+	fileCount int
+	doneAt    time.Time
+}
+
+// Handler keeps track of the posts with newly downloaded files
+// to add them to the RSS feed file once the run has finished.
+//
+// The posts with files that were all skipped as they were already downloaded are not added.
+type Handler struct {
+	events.BaseHandler
+
+	feedPath string
+
+	mu       sync.Mutex
+	posts    map[string]*events.Post // the resolved posts keyed by their folder
+	newPosts map[string]*newPost
+}
+
+// Returns a new Handler that adds the newly downloaded posts to the RSS feed at the given path
+func NewHandler(feedPath string) *Handler {
+	return &Handler{
+		feedPath: feedPath,
+		posts:    make(map[string]*events.Post),
+		newPosts: make(map[string]*newPost),
+	}
+}
+
+func (h *Handler) OnPostResolved(post *events.Post) {
+	if post.Folder == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.posts[filepath.Clean(post.Folder)] = post
+}
+
+func (h *Handler) OnFileDone(file *events.File, err error) {
+	if err != nil || file.Skipped {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// go up the file's parent folders, e.g. for the files in the "attachments" folder of the post
+	for dir := filepath.Dir(file.FilePath); ; {
+		if post, ok := h.posts[dir]; ok {
+			if _, ok := h.newPosts[dir]; !ok {
+				h.newPosts[dir] = &newPost{post: post}
+			}
+			h.newPosts[dir].fileCount++
+			h.newPosts[dir].doneAt = time.Now()
+			return
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return
+		}
+		dir = parentDir
+	}
+}
+
+// Returns the file URL of the local path, e.g. "file:///home/user/Downloads/Fantia/Creator"
+func getFileUrl(path string) string {
+	fileUrl := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	if fileUrl.Path != "" && fileUrl.Path[0] != '/' {
+		// Windows paths, e.g. "C:/Users"
+		fileUrl.Path = "/" + fileUrl.Path
+	}
+	return fileUrl.String()
+}
+
+// Returns the feed item of the post
+func getFeedItem(p *newPost) *rssItem {
+	post := p.post
+	description := fmt.Sprintf("%d new file(s) from %s on %s, saved to %s", p.fileCount, post.Creator, post.Site, post.Folder)
+	if !post.PublishedAt.IsZero() {
+		description += fmt.Sprintf(" (published on %s)", post.PublishedAt.Format("2006-01-02"))
+	}
+	return &rssItem{
+		Title:       fmt.Sprintf("%s - %s", post.Creator, post.Title),
+		Link:        getFileUrl(post.Folder),
+		Description: description,
+		Category:    post.Site,
+		Guid: rssGuid{
+			IsPermaLink: "false",
+			Value:       fmt.Sprintf("%s:%s:%d", post.Site, post.Id, p.doneAt.UnixNano()),
+		},
+		PubDate: p.doneAt.Format(time.RFC1123Z),
+	}
+}
+
+// Reads the items of the existing feed file in the storage backend, if any
+func readFeedItems(feedPath string) ([]*rssItem, error) {
+	data, err := storage.ReadFile(context.Background(), feedPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+	if feed.Channel == nil {
+		return nil, nil
+	}
+	return feed.Channel.Items, nil
+}
+
+// Adds the posts with newly downloaded files to the top of the RSS feed file and removes the oldest
+// posts if there are more than MAX_ITEMS posts, does nothing if no files were downloaded in this run.
+func (h *Handler) Write() {
+	h.mu.Lock()
+	newPosts := make([]*newPost, 0, len(h.newPosts))
+	for _, p := range h.newPosts {
+		newPosts = append(newPosts, p)
+	}
+	h.mu.Unlock()
+	if len(newPosts) == 0 {
+		return
+	}
+
+	if err := h.write(newPosts); err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"feed error %d: failed to update the RSS feed at %s, more info => %v",
+				utils.OS_ERROR,
+				h.feedPath,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+}
+
+func (h *Handler) write(newPosts []*newPost) error {
+	oldItems, err := readFeedItems(h.feedPath)
+	if err != nil {
+		return err
+	}
+
+	// the most recently downloaded posts are at the top
+	sort.Slice(newPosts, func(i, j int) bool {
+		return newPosts[i].doneAt.After(newPosts[j].doneAt)
+	})
+	items := make([]*rssItem, 0, len(newPosts)+len(oldItems))
+	for _, p := range newPosts {
+		items = append(items, getFeedItem(p))
+	}
+	items = append(items, oldItems...)
+	if len(items) > MAX_ITEMS {
+		items = items[:MAX_ITEMS]
+	}
+
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: &rssChannel{
+			Title:         "Cultured Downloader",
+			Link:          getFileUrl(utils.DOWNLOAD_PATH),
+			Description:   "Posts newly downloaded by Cultured Downloader",
+			LastBuildDate: time.Now().Format(time.RFC1123Z),
+			Items:         items,
+		},
+	}
+	data, err := xml.MarshalIndent(feed, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	data = append([]byte(xml.Header), data...)
+	if !storage.IsLocal() {
+		// the remote backends only show the file once it has been fully uploaded
+		return storage.WriteFile(context.Background(), h.feedPath, data)
+	}
+
+	// the feed is replaced in one go so that feed readers never see a partially written file
+	os.MkdirAll(filepath.Dir(h.feedPath), 0755)
+	tmpPath := h.feedPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, h.feedPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package filehost
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// A file on the download page of a file hosting service
+type hostedFile struct {
+	url     string
+	cookies []*http.Cookie // e.g. the session cookie from the download page
+	headers map[string]string
+}
+
+// A file hosting service, e.g. GigaFile, whose download pages are commonly linked in the posts
+type fileHost struct {
+	name     string
+	urlRegex *regexp.Regexp
+
+	// returns the files on the download page
+	resolve func(pageUrl string, config *configs.Config) ([]*hostedFile, error)
+}
+
+// A download page that was detected in a post
+type hostLink struct {
+	url        string
+	folderPath string
+	host       *fileHost
+}
+
+var (
+	fileHosts = []*fileHost{gigafileHost, firestorageHost}
+
+	// whether the download pages should be detected and downloaded
+	enabled bool
+
+	linksMu sync.Mutex
+	links   []*hostLink
+	queued  = make(map[string]struct{})
+)
+
+// Sets whether the files on the download pages of the supported
+// file hosting services detected in the posts should be downloaded
+func SetEnabled(enable bool) {
+	enabled = enable
+}
+
+// Returns the supported file hosting service of the URL or nil if it is not supported
+func getFileHost(url string) *fileHost {
+	for _, host := range fileHosts {
+		if host.urlRegex.MatchString(url) {
+			return host
+		}
+	}
+	return nil
+}
+
+// Detects the download pages of the supported file hosting services in the text
+// and queues them to be downloaded to the post folder by DownloadLinks.
+//
+// Returns true if any download page was detected.
+func DetectLinks(text, postFolderPath string) bool {
+	if !enabled {
+		return false
+	}
+
+	detected := false
+	for _, host := range fileHosts {
+		for _, url := range host.urlRegex.FindAllString(text, -1) {
+			detected = true
+			folderPath := filepath.Join(postFolderPath, utils.FILE_HOSTS_FOLDER)
+			key := url + "|" + folderPath
+
+			linksMu.Lock()
+			if _, ok := queued[key]; !ok {
+				queued[key] = struct{}{}
+				links = append(links, &hostLink{url: url, folderPath: folderPath, host: host})
+			}
+			linksMu.Unlock()
+		}
+	}
+	return detected
+}
+
+// Returns the queued download pages and clears the queue
+func popLinks() []*hostLink {
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	queuedLinks := links
+	links = nil
+	queued = make(map[string]struct{})
+	return queuedLinks
+}
+
+// Logs the download page to the post's OTHER_LINKS_FILENAME file so that it can be downloaded manually
+func logUnresolvedLink(link *hostLink, err error) {
+	request.MarkPostIncomplete(link.folderPath)
+	utils.LogError(err, "", false, utils.ERROR)
+	utils.LogMessageToPath(
+		fmt.Sprintf(
+			"Could not download the files from the %s link in the post's description:\n%s\n\n",
+			link.host.name,
+			link.url,
+		),
+		filepath.Join(filepath.Dir(link.folderPath), utils.OTHER_LINKS_FILENAME),
+		utils.INFO,
+	)
+}
+
+// Downloads the files on the download pages detected by DetectLinks
+//
+// The download pages that cannot be resolved are logged to the
+// post's OTHER_LINKS_FILENAME file to be downloaded manually instead.
+func DownloadLinks(config *configs.Config) {
+	type fileToDl struct {
+		file       *hostedFile
+		folderPath string
+	}
+
+	var files []*fileToDl
+	for _, link := range popLinks() {
+		if !config.ShouldDlPost(link.folderPath) {
+			continue
+		}
+
+		hostedFiles, err := link.host.resolve(link.url, config)
+		if err == nil && len(hostedFiles) == 0 {
+			err = fmt.Errorf(
+				"file host error %d: no files were found on the %s download page, %s",
+				utils.RESPONSE_ERROR,
+				link.host.name,
+				link.url,
+			)
+		}
+		if err != nil {
+			logUnresolvedLink(link, err)
+			continue
+		}
+		for _, file := range hostedFiles {
+			files = append(files, &fileToDl{file: file, folderPath: link.folderPath})
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	folderPaths := make([]string, len(files))
+	for idx, file := range files {
+		folderPaths[idx] = file.folderPath
+	}
+	request.MarkPostsInProgress(folderPaths)
+	defer request.MarkPostsComplete(folderPaths)
+
+	request.DownloadConcurrently(&request.ConcurrentDl{
+		Count:          len(files),
+		MaxConcurrency: utils.FILE_HOSTS_MAX_CONCURRENT_DOWNLOADS,
+		FileDesc:       "files from file hosting services",
+		File: func(idx int) *events.File {
+			return &events.File{Url: files[idx].file.url, FilePath: files[idx].folderPath}
+		},
+		DlFunc: func(idx int, dlFile *events.File) error {
+			file := files[idx]
+			if request.QuotaReached(config) {
+				request.MarkPostIncomplete(file.folderPath)
+				dlFile.Skipped = true
+				return nil
+			}
+
+			err := request.DownloadUrl(
+				dlFile,
+				&request.RequestArgs{
+					Url:            file.file.url,
+					Method:         "GET",
+					Timeout:        utils.DOWNLOAD_TIMEOUT,
+					Cookies:        file.file.cookies,
+					Headers:        file.file.headers,
+					UserAgent:      config.UserAgent,
+					Http2:          true,
+					RequestHandler: request.CallRequest,
+				},
+				config.OverwriteFiles,
+				config.VerifyImages,
+			)
+			if err != nil {
+				request.MarkPostIncomplete(file.folderPath)
+			}
+			if err != nil && err != context.Canceled {
+				utils.LogMessageToPath(
+					fmt.Sprintf("Failed to download %s, more info => %v\n\n", file.file.url, err),
+					filepath.Join(filepath.Dir(file.folderPath), utils.OTHER_LINKS_FILENAME),
+					utils.ERROR,
+				)
+			}
+			return err
+		},
+	})
+	if config.VerifyImages {
+		request.SaveVerifiedImages()
+	}
+}
+
+// Returns the HTML of the download page and the response for its final URL after any redirects
+func getDownloadPage(pageUrl string, config *configs.Config) ([]byte, *http.Response, error) {
+	res, err := request.CallRequest(
+		&request.RequestArgs{
+			Url:         pageUrl,
+			Method:      "GET",
+			Timeout:     30,
+			UserAgent:   config.UserAgent,
+			Http2:       true,
+			CheckStatus: true,
+		},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"file host error %d: failed to get the download page %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			pageUrl,
+			err,
+		)
+	}
+
+	body, err := utils.ReadResBody(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, res, nil
+}
@@ -0,0 +1,47 @@
+package filehost
+
+import (
+	"html"
+	"regexp"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	// e.g. https://firestorage.jp/download/0123456789abcdef0123456789abcdef01234567
+	FIRESTORAGE_URL_REGEX = regexp.MustCompile(`https?://(?:www\.)?firestorage\.jp/download/\w+`)
+
+	// the download links on the download page which are on the file servers' subdomains
+	FIRESTORAGE_FILE_REGEX = regexp.MustCompile(`href="(?P<url>https?://[\w-]+\.firestorage\.jp/download/[^"]+)"`)
+
+	firestorageHost = &fileHost{
+		name:     "firestorage",
+		urlRegex: FIRESTORAGE_URL_REGEX,
+		resolve:  resolveFirestorage,
+	}
+)
+
+// Returns the files linked on the firestorage download page
+func resolveFirestorage(pageUrl string, config *configs.Config) ([]*hostedFile, error) {
+	body, res, err := getDownloadPage(pageUrl, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*hostedFile
+	var fileUrls []string
+	for _, matched := range FIRESTORAGE_FILE_REGEX.FindAllSubmatch(body, -1) {
+		fileUrl := html.UnescapeString(string(matched[1]))
+		if utils.SliceContains(fileUrls, fileUrl) {
+			continue
+		}
+		fileUrls = append(fileUrls, fileUrl)
+		files = append(files, &hostedFile{
+			url:     fileUrl,
+			cookies: res.Cookies(),
+			headers: map[string]string{"Referer": res.Request.URL.String()},
+		})
+	}
+	return files, nil
+}
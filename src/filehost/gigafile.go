@@ -0,0 +1,68 @@
+package filehost
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	// e.g. https://46.gigafile.nu/0320-b2c6f7e1a8e3d4c5b6a7f8e9d0c1b2a3f
+	// or the shortened https://xgf.nu/AbCd which redirects to the download page
+	GIGAFILE_URL_REGEX = regexp.MustCompile(`https?://(?:\d+\.gigafile\.nu|xgf\.nu)/[\w-]+`)
+
+	GIGAFILE_PAGE_REGEX = regexp.MustCompile(`^https?://(?P<server>\d+\.gigafile\.nu)/(?P<fileId>[\w-]+)`)
+
+	// the files of a download page with multiple files ("matomete") are downloaded with download(index, 'fileId')
+	GIGAFILE_MATOMETE_REGEX = regexp.MustCompile(`download\(\s*\d+\s*,\s*'(?P<fileId>[\w-]+)'`)
+
+	gigafileHost = &fileHost{
+		name:     "GigaFile",
+		urlRegex: GIGAFILE_URL_REGEX,
+		resolve:  resolveGigafile,
+	}
+)
+
+// Returns the files on the GigaFile download page which are downloaded from download.php
+// with the session cookie that is set when the download page is visited
+func resolveGigafile(pageUrl string, config *configs.Config) ([]*hostedFile, error) {
+	body, res, err := getDownloadPage(pageUrl, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the final URL is used as the shortened URLs redirect to the download page
+	matched := GIGAFILE_PAGE_REGEX.FindStringSubmatch(res.Request.URL.String())
+	if matched == nil {
+		return nil, fmt.Errorf(
+			"file host error %d: %s did not redirect to a GigaFile download page but to %s",
+			utils.RESPONSE_ERROR,
+			pageUrl,
+			res.Request.URL.String(),
+		)
+	}
+	server := matched[GIGAFILE_PAGE_REGEX.SubexpIndex("server")]
+	fileIds := []string{matched[GIGAFILE_PAGE_REGEX.SubexpIndex("fileId")]}
+	if matometeIds := GIGAFILE_MATOMETE_REGEX.FindAllSubmatch(body, -1); len(matometeIds) > 0 {
+		fileIds = fileIds[:0]
+		for _, matometeId := range matometeIds {
+			fileId := string(matometeId[1])
+			if !utils.SliceContains(fileIds, fileId) {
+				fileIds = append(fileIds, fileId)
+			}
+		}
+	}
+
+	cookies := res.Cookies()
+	files := make([]*hostedFile, len(fileIds))
+	for idx, fileId := range fileIds {
+		files[idx] = &hostedFile{
+			url:     fmt.Sprintf("https://%s/download.php?file=%s", server, fileId),
+			cookies: cookies,
+			headers: map[string]string{"Referer": res.Request.URL.String()},
+		}
+	}
+	return files, nil
+}
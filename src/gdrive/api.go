@@ -1,13 +1,16 @@
 package gdrive
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive/models"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive/models"
 )
 
 // censor the key=... part of the URL to <REDACTED>.
@@ -29,12 +32,67 @@ func getFailedApiCallErr(res *http.Response) error {
 	)
 }
 
+// Returns the reasons in the GDrive API error response, e.g. "downloadQuotaExceeded",
+// or nil if the response is not a 403 or 429 error response.
+//
+// The response body is restored so that it can still be read by the caller.
+func getErrorReasons(res *http.Response) []string {
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+
+	var errJson models.GDriveErrorJson
+	if err := json.Unmarshal(body, &errJson); err != nil {
+		return nil
+	}
+	reasons := make([]string, len(errJson.Error.Errors))
+	for idx, errInfo := range errJson.Error.Errors {
+		reasons[idx] = errInfo.Reason
+	}
+	return reasons
+}
+
+// Converts the GDrive API file JSON to a GdriveFileToDl struct
+//
+// If the file is a shortcut, the shortcut's target ID and MIME type will be used instead.
+// If the file is a Google Docs, Sheets, or Slides file, the file will be exported instead.
+func convertGdriveFile(file *models.GDriveFile, filePath string) *models.GdriveFileToDl {
+	fileToDl := &models.GdriveFileToDl{
+		Id:          file.Id,
+		Name:        file.Name,
+		Size:        file.Size,
+		MimeType:    file.MimeType,
+		Md5Checksum: file.Md5Checksum,
+		FilePath:    filePath,
+	}
+	if file.MimeType == GDRIVE_SHORTCUT_MIME_TYPE && file.ShortcutDetails.TargetId != "" {
+		fileToDl.Id = file.ShortcutDetails.TargetId
+		fileToDl.MimeType = file.ShortcutDetails.TargetMimeType
+		fileToDl.IsShortcut = true
+	}
+	if exportMimeType, name, ok := getExportFormat(fileToDl.MimeType, fileToDl.Name); ok {
+		fileToDl.ExportMimeType = exportMimeType
+		fileToDl.Name = name
+	}
+	return fileToDl
+}
+
 // Returns the contents of the given GDrive folder
 func (gdrive *GDrive) GetFolderContents(folderId, logPath string, config *configs.Config) ([]*models.GdriveFileToDl, error) {
 	params := map[string]string{
-		"key":    gdrive.apiKey,
 		"q":      fmt.Sprintf("'%s' in parents", folderId),
 		"fields": fmt.Sprintf("nextPageToken,files(%s)", GDRIVE_FILE_FIELDS),
+
+		// Without these, folders in shared drives will return an empty list of files
+		"supportsAllDrives":         "true",
+		"includeItemsFromAllDrives": "true",
 	}
 	var files []*models.GdriveFileToDl
 	pageToken := ""
@@ -44,7 +102,7 @@ func (gdrive *GDrive) GetFolderContents(folderId, logPath string, config *config
 		} else {
 			delete(params, "pageToken")
 		}
-		res, err := request.CallRequest(
+		res, err := gdrive.callApi(
 			&request.RequestArgs{
 				Url:       gdrive.apiUrl,
 				Method:    "GET",
@@ -79,14 +137,7 @@ func (gdrive *GDrive) GetFolderContents(folderId, logPath string, config *config
 		}
 
 		for _, file := range gdriveFolder.Files {
-			files = append(files, &models.GdriveFileToDl{
-				Id:          file.Id,
-				Name:        file.Name,
-				Size:        file.Size,
-				MimeType:    file.MimeType,
-				Md5Checksum: file.Md5Checksum,
-				FilePath:    "",
-			})
+			files = append(files, convertGdriveFile(&file, ""))
 		}
 
 		if gdriveFolder.NextPageToken == "" {
@@ -98,8 +149,14 @@ func (gdrive *GDrive) GetFolderContents(folderId, logPath string, config *config
 	return files, nil
 }
 
-// Retrieves the content of a GDrive folder and its subfolders recursively using GDrive API v3
-func (gdrive *GDrive) GetNestedFolderContents(folderId, logPath string, config *configs.Config) ([]*models.GdriveFileToDl, error) {
+func (gdrive *GDrive) getNestedFolderContents(folderId, logPath string, depth int, visited map[string]struct{}, config *configs.Config) ([]*models.GdriveFileToDl, error) {
+	// Shortcuts can point to a parent folder, hence
+	// keep track of the visited folders to avoid an infinite loop.
+	if _, ok := visited[folderId]; ok {
+		return nil, nil
+	}
+	visited[folderId] = struct{}{}
+
 	var files []*models.GdriveFileToDl
 	folderContents, err := gdrive.GetFolderContents(folderId, logPath, config)
 	if err != nil {
@@ -107,27 +164,60 @@ func (gdrive *GDrive) GetNestedFolderContents(folderId, logPath string, config *
 	}
 
 	for _, file := range folderContents {
-		if file.MimeType == "application/vnd.google-apps.folder" {
-			subFolderFiles, err := gdrive.GetNestedFolderContents(file.Id, logPath, config)
+		if file.MimeType == GDRIVE_FOLDER_MIME_TYPE {
+			if !gdrive.filters.canRecurse(depth) {
+				continue
+			}
+
+			subFolderFiles, err := gdrive.getNestedFolderContents(file.Id, logPath, depth+1, visited, config)
 			if err != nil {
 				return nil, err
 			}
 			files = append(files, subFolderFiles...)
-		} else {
+			continue
+		}
+
+		if file.IsShortcut {
+			// the size and md5 checksum of the target file
+			// are not returned in the folder listing
+			file, err = gdrive.GetFileDetails(
+				&models.GDriveToDl{
+					Id:       file.Id,
+					Type:     "file",
+					FilePath: file.FilePath,
+				},
+				config,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if gdrive.filters.isAllowed(file) && config.ShouldDlFile(file.Name) {
 			files = append(files, file)
 		}
 	}
 	return files, nil
 }
 
+// Retrieves the content of a GDrive folder and its subfolders recursively using GDrive API v3
+//
+// The GDrive client's filters will be applied to limit the recursion depth and the files returned.
+func (gdrive *GDrive) GetNestedFolderContents(folderId, logPath string, config *configs.Config) ([]*models.GdriveFileToDl, error) {
+	return gdrive.getNestedFolderContents(folderId, logPath, 0, make(map[string]struct{}), config)
+}
+
 // Retrieves the file details of the given GDrive file using GDrive API v3
+//
+// If the file is a shortcut to another file, the details of the target file will be returned instead.
+// However, if the shortcut points to a folder, the returned file will have IsShortcut set to true
+// with the folder's ID and MIME type for the caller to retrieve the folder contents.
 func (gdrive *GDrive) GetFileDetails(gdriveInfo *models.GDriveToDl, config *configs.Config) (*models.GdriveFileToDl, error) {
 	params := map[string]string{
-		"key":    gdrive.apiKey,
-		"fields": GDRIVE_FILE_FIELDS,
+		"fields":            GDRIVE_FILE_FIELDS,
+		"supportsAllDrives": "true",
 	}
 	url := fmt.Sprintf("%s/%s", gdrive.apiUrl, gdriveInfo.Id)
-	res, err := request.CallRequest(
+	res, err := gdrive.callApi(
 		&request.RequestArgs{
 			Url:       url,
 			Method:    "GET",
@@ -156,12 +246,16 @@ func (gdrive *GDrive) GetFileDetails(gdriveInfo *models.GDriveToDl, config *conf
 		return nil, err
 	}
 
-	return &models.GdriveFileToDl{
-		Id:          gdriveFile.Id,
-		Name:        gdriveFile.Name,
-		Size:        gdriveFile.Size,
-		MimeType:    gdriveFile.MimeType,
-		Md5Checksum: gdriveFile.Md5Checksum,
-		FilePath:    gdriveInfo.FilePath,
-	}, nil
+	fileInfo := convertGdriveFile(&gdriveFile, gdriveInfo.FilePath)
+	if fileInfo.IsShortcut && fileInfo.MimeType != GDRIVE_FOLDER_MIME_TYPE {
+		return gdrive.GetFileDetails(
+			&models.GDriveToDl{
+				Id:       fileInfo.Id,
+				Type:     gdriveInfo.Type,
+				FilePath: gdriveInfo.FilePath,
+			},
+			config,
+		)
+	}
+	return fileInfo, nil
 }
@@ -0,0 +1,192 @@
+package gdrive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Google resets the download quota of a file after about 24 hours
+const DOWNLOAD_QUOTA_RETRY_AFTER = 24 * time.Hour
+
+// Returned, wrapped, when the file has been downloaded too many times
+// by other users and cannot be downloaded until its download quota resets
+var errDownloadQuotaExceeded = errors.New("the download quota of the file has been exceeded")
+
+// A file whose download quota was exceeded that will be downloaded
+// again by the first run after its retry time
+type deferredFile struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Size        string    `json:"size"`
+	MimeType    string    `json:"mime_type"`
+	Md5Checksum string    `json:"md5_checksum"`
+	FilePath    string    `json:"file_path"`
+	RetryAfter  time.Time `json:"retry_after"`
+
+	ExportMimeType string `json:"export_mime_type,omitempty"`
+}
+
+var deferredFilesMu sync.Mutex
+
+// Returns the path to the file in the app data folder that stores
+// the GDrive files that could not be downloaded due to their download quota
+func GetDeferredFilesPath() string {
+	return filepath.Join(utils.APP_PATH, "gdrive_deferred.json")
+}
+
+func getDeferredFileKey(file *models.GdriveFileToDl) string {
+	return file.Id + ":" + file.FilePath
+}
+
+func loadDeferredFiles() (map[string]*deferredFile, error) {
+	files := make(map[string]*deferredFile)
+	deferredFilesJson, err := os.ReadFile(GetDeferredFilesPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return files, nil
+		}
+		return nil, fmt.Errorf(
+			"gdrive error %d: failed to read deferred GDrive files, more info => %v",
+			utils.OS_ERROR,
+			err,
+		)
+	}
+
+	if err = json.Unmarshal(deferredFilesJson, &files); err != nil {
+		return nil, fmt.Errorf(
+			"gdrive error %d: failed to unmarshal deferred GDrive files, more info => %v",
+			utils.JSON_ERROR,
+			err,
+		)
+	}
+	return files, nil
+}
+
+func saveDeferredFiles(files map[string]*deferredFile) error {
+	deferredFilesJson, err := json.MarshalIndent(files, "", "\t")
+	if err != nil {
+		return fmt.Errorf(
+			"gdrive error %d: failed to marshal deferred GDrive files, more info => %v",
+			utils.JSON_ERROR,
+			err,
+		)
+	}
+
+	os.MkdirAll(utils.APP_PATH, 0666)
+	if err = os.WriteFile(GetDeferredFilesPath(), deferredFilesJson, 0666); err != nil {
+		return fmt.Errorf(
+			"gdrive error %d: failed to write deferred GDrive files, more info => %v",
+			utils.OS_ERROR,
+			err,
+		)
+	}
+	return nil
+}
+
+// Returns true if the download response is an error due to the file's download quota being exceeded
+func isDownloadQuotaExceeded(res *http.Response) bool {
+	for _, reason := range getErrorReasons(res) {
+		if reason == "downloadQuotaExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the file to the deferred files to be downloaded again by the first run after the given time,
+// e.g. after DOWNLOAD_QUOTA_RETRY_AFTER if the file's download quota has been exceeded
+func deferFile(file *models.GdriveFileToDl, retryAfter time.Time) {
+	deferredFilesMu.Lock()
+	defer deferredFilesMu.Unlock()
+	files, err := loadDeferredFiles()
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return
+	}
+
+	files[getDeferredFileKey(file)] = &deferredFile{
+		Id:          file.Id,
+		Name:        file.Name,
+		Size:        file.Size,
+		MimeType:    file.MimeType,
+		Md5Checksum: file.Md5Checksum,
+		FilePath:    file.FilePath,
+		RetryAfter:  retryAfter,
+
+		ExportMimeType: file.ExportMimeType,
+	}
+	if err := saveDeferredFiles(files); err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+}
+
+// Removes the given files from the deferred files if they were deferred
+func removeDeferredFiles(downloaded []*models.GdriveFileToDl) {
+	deferredFilesMu.Lock()
+	defer deferredFilesMu.Unlock()
+	files, err := loadDeferredFiles()
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return
+	}
+
+	removed := false
+	for _, file := range downloaded {
+		key := getDeferredFileKey(file)
+		if _, ok := files[key]; ok {
+			delete(files, key)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	if err := saveDeferredFiles(files); err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+}
+
+// Returns the deferred files whose retry time has passed
+func getDueDeferredFiles() []*models.GdriveFileToDl {
+	deferredFilesMu.Lock()
+	defer deferredFilesMu.Unlock()
+	files, err := loadDeferredFiles()
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil
+	}
+
+	now := time.Now()
+	var dueFiles []*models.GdriveFileToDl
+	for _, file := range files {
+		if now.Before(file.RetryAfter) {
+			continue
+		}
+		dueFiles = append(dueFiles, &models.GdriveFileToDl{
+			Id:          file.Id,
+			Name:        file.Name,
+			Size:        file.Size,
+			MimeType:    file.MimeType,
+			Md5Checksum: file.Md5Checksum,
+			FilePath:    file.FilePath,
+
+			ExportMimeType: file.ExportMimeType,
+		})
+	}
+	return dueFiles
+}
+
+// Returns true if there are deferred GDrive files that can be downloaded again
+// so that they are downloaded even if there are no new GDrive links to download
+func HasDueDeferredFiles() bool {
+	return len(getDueDeferredFiles()) > 0
+}
@@ -3,21 +3,27 @@ package gdrive
 import (
 	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"syscall"
-	"strconv"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive/models"
+	"github.com/fatih/color"
 )
 
 func md5HashFile(file *os.File) (string, error) {
@@ -34,6 +40,11 @@ func md5HashFile(file *os.File) (string, error) {
 }
 
 func checkIfCanSkipDl(filePath string, fileInfo *models.GdriveFileToDl) (bool, error) {
+	if !storage.IsLocal() {
+		// the file cannot be hashed without downloading it from the storage backend
+		fileSize, err := storage.GetBackend().Size(context.Background(), filePath)
+		return err == nil && strconv.FormatInt(fileSize, 10) == fileInfo.Size, nil
+	}
 	if !utils.PathExists(filePath) {
 		return false, nil
 	}
@@ -74,11 +85,27 @@ func checkIfCanSkipDl(filePath string, fileInfo *models.GdriveFileToDl) (bool, e
 
 // Downloads the given GDrive file using GDrive API v3
 //
-// If the md5Checksum has a mismatch, the file will be overwritten and downloaded again
-func (gdrive *GDrive) DownloadFile(fileInfo *models.GdriveFileToDl, filePath string, config *configs.Config, queue chan struct{}) error {
-	skipDl, err := checkIfCanSkipDl(filePath, fileInfo)
-	if skipDl || err != nil {
-		return err
+// If the md5Checksum has a mismatch, the file will be overwritten and downloaded again.
+//
+// Google Docs, Sheets, and Slides files are exported in the format of their ExportMimeType instead.
+// As they have no md5Checksum, they are only exported again if the files should be overwritten.
+//
+// The file is downloaded to dlFile.FilePath and dlFile.Skipped will be set if the download is skipped.
+func (gdrive *GDrive) DownloadFile(fileInfo *models.GdriveFileToDl, dlFile *events.File, config *configs.Config) error {
+	if fileInfo.ExportMimeType != "" {
+		if !config.OverwriteFiles && storage.Exists(context.Background(), dlFile.FilePath) {
+			dlFile.Skipped = true
+			return nil
+		}
+	} else {
+		skipDl, err := checkIfCanSkipDl(dlFile.FilePath, fileInfo)
+		if err != nil {
+			return err
+		}
+		if skipDl {
+			dlFile.Skipped = true
+			return nil
+		}
 	}
 
 	// Create a context that can be cancelled when SIGINT/SIGTERM signal is received
@@ -94,14 +121,21 @@ func (gdrive *GDrive) DownloadFile(fileInfo *models.GdriveFileToDl, filePath str
 	}()
 	defer signal.Stop(sigs)
 
-	queue <- struct{}{}
-	params := map[string]string{
-		"key":              gdrive.apiKey,
-		"alt":              "media", // to tell Google that we are downloading the file
-		"acknowledgeAbuse": "true",  // If the files are marked as abusive, download them anyway
+	var url string
+	var params map[string]string
+	if fileInfo.ExportMimeType != "" {
+		url = fmt.Sprintf("%s/%s/export", gdrive.apiUrl, fileInfo.Id)
+		params = map[string]string{"mimeType": fileInfo.ExportMimeType}
+	} else {
+		url = fmt.Sprintf("%s/%s", gdrive.apiUrl, fileInfo.Id)
+		params = map[string]string{
+			"alt":               "media", // to tell Google that we are downloading the file
+			"acknowledgeAbuse":  "true",  // If the files are marked as abusive, download them anyway
+			"supportsAllDrives": "true",  // Allow downloading files from shared drives
+		}
 	}
-	url := fmt.Sprintf("%s/%s", gdrive.apiUrl, fileInfo.Id)
-	res, err := request.CallRequest(
+	dlFile.Url = url
+	res, err := gdrive.callApi(
 		&request.RequestArgs{
 			Url:       url,
 			Method:    "GET",
@@ -118,16 +152,19 @@ func (gdrive *GDrive) DownloadFile(fileInfo *models.GdriveFileToDl, filePath str
 	}
 	defer res.Body.Close()
 	if res.StatusCode != 200 {
+		if isDownloadQuotaExceeded(res) {
+			return fmt.Errorf("gdrive error %d: %w", utils.RESPONSE_ERROR, errDownloadQuotaExceeded)
+		}
 		return getFailedApiCallErr(res)
 	}
-	return request.DlToFile(res, url, filePath)
+	return request.DlToFile(ctx, res, dlFile)
 }
 
 func filterDownloads(files []*models.GdriveFileToDl) []*models.GdriveFileToDl {
 	var notAllowedForDownload []*models.GdriveFileToDl
 	allowedForDownload := make([]*models.GdriveFileToDl, 0, len(files))
 	for _, file := range files {
-		if strings.Contains(file.MimeType, "application/vnd.google-apps") {
+		if strings.Contains(file.MimeType, "application/vnd.google-apps") && file.ExportMimeType == "" {
 			notAllowedForDownload = append(notAllowedForDownload, file)
 		} else {
 			allowedForDownload = append(allowedForDownload, file)
@@ -147,19 +184,21 @@ func filterDownloads(files []*models.GdriveFileToDl) []*models.GdriveFileToDl {
 	return allowedForDownload
 }
 
-func processGdriveDlError(errChan chan *models.GdriveError, progress *spinner.Spinner) {
+func processGdriveDlError(errs []error, progress *spinner.Spinner) {
 	killProgram := false
-	for errInfo := range errChan {
-		errMsg := censorApiKeyFromStr(errInfo.Err.Error())
-		if errMsg == context.Canceled.Error() {
-			if !killProgram {
-				killProgram = true
-			}
+	for _, err := range errs {
+		if err == context.Canceled {
+			killProgram = true
 			continue
 		}
 
+		errInfo, ok := err.(*models.GdriveError)
+		if !ok {
+			utils.LogError(err, "", false, utils.ERROR)
+			continue
+		}
 		utils.LogMessageToPath(
-			censorApiKeyFromStr(errMsg),
+			censorApiKeyFromStr(errInfo.Error()),
 			errInfo.FilePath,
 			utils.ERROR,
 		)
@@ -167,83 +206,125 @@ func processGdriveDlError(errChan chan *models.GdriveError, progress *spinner.Sp
 
 	if killProgram {
 		progress.KillProgram(
-			"Stopped downloading GDrive files (incomplete downloads will be deleted)...",
+			i18n.T("Stopped downloading GDrive files (incomplete downloads will be deleted)..."),
 		)
 	}
 }
 
 // Downloads the multiple GDrive file in parallel using GDrive API v3
+//
+// The number of concurrent downloads is limited by the GDrive client's max download workers.
+//
+// Files whose download quota has been exceeded are deferred to be downloaded
+// again by the first run after DOWNLOAD_QUOTA_RETRY_AFTER.
 func (gdrive *GDrive) DownloadMultipleFiles(files []*models.GdriveFileToDl, config *configs.Config) {
 	allowedForDownload := filterDownloads(files)
-	if len(allowedForDownload) == 0 {
-		return
-	}
-
-	maxConcurrency := gdrive.maxDownloadWorkers
-	if len(allowedForDownload) < maxConcurrency {
-		maxConcurrency = len(allowedForDownload)
+	filePaths := make([]string, len(allowedForDownload))
+	for idx, file := range allowedForDownload {
+		filePaths[idx] = file.FilePath
 	}
-	var wg sync.WaitGroup
-	queue := make(chan struct{}, maxConcurrency)
-	errChan := make(chan *models.GdriveError, len(allowedForDownload))
+	request.MarkPostsInProgress(filePaths)
 
-	baseMsg := "Downloading GDrive files [%d/" + fmt.Sprintf("%d]...", len(allowedForDownload))
-	progress := spinner.New(
-		spinner.DL_SPINNER,
-		"fgHiYellow",
-		fmt.Sprintf(
-			baseMsg,
-			0,
-		),
-		fmt.Sprintf(
-			"Finished downloading %d GDrive files!",
-			len(allowedForDownload),
-		),
-		fmt.Sprintf(
-			"Something went wrong while downloading %d GDrive files!\nPlease refer to the generated log files for more details.",
-			len(allowedForDownload),
-		),
-		len(allowedForDownload),
-	)
-	progress.Start()
-	for _, file := range allowedForDownload {
-		wg.Add(1)
-		go func(file *models.GdriveFileToDl) {
-			defer func() {
-				wg.Done()
-				<-queue
-			}()
+	var skippedFiles, deferredFiles int32
+	// only the downloaded files are removed from the deferred files
+	// so that the ones that failed for other reasons are retried by the next run
+	downloaded := make([]bool, len(allowedForDownload))
+	request.DownloadConcurrently(&request.ConcurrentDl{
+		Count:          len(allowedForDownload),
+		MaxConcurrency: gdrive.maxDownloadWorkers,
+		FileDesc:       "GDrive files",
+		File: func(idx int) *events.File {
+			file := allowedForDownload[idx]
+			return &events.File{
+				Url:      fmt.Sprintf("%s/%s", gdrive.apiUrl, file.Id),
+				FilePath: filepath.Join(file.FilePath, file.Name),
+			}
+		},
+		DlFunc: func(idx int, dlFile *events.File) error {
+			file := allowedForDownload[idx]
+			if request.QuotaReached(config) {
+				// deferred like the files in the remaining queue to be downloaded by the next run
+				atomic.AddInt32(&skippedFiles, 1)
+				deferFile(file, time.Now())
+				request.MarkPostIncomplete(file.FilePath)
+				dlFile.Skipped = true
+				return nil
+			}
 
 			os.MkdirAll(file.FilePath, 0666)
-			filePath := filepath.Join(file.FilePath, file.Name)
-
-			err := gdrive.DownloadFile(file, filePath, config, queue)
-			if err != nil && err != context.Canceled {
-				err = fmt.Errorf(
-					"failed to download file: %s (ID: %s, MIME Type: %s)\nRefer to error details below:\n%v",
-					file.Name, file.Id, file.MimeType, err,
+			err := gdrive.DownloadFile(file, dlFile, config)
+			if err != nil {
+				request.MarkPostIncomplete(file.FilePath)
+			}
+			if errors.Is(err, errDownloadQuotaExceeded) {
+				atomic.AddInt32(&deferredFiles, 1)
+				retryAfter := time.Now().Add(DOWNLOAD_QUOTA_RETRY_AFTER)
+				deferFile(file, retryAfter)
+				utils.LogError(
+					nil,
+					fmt.Sprintf(
+						"The download quota of the GDrive file %s (ID: %s) has been exceeded, it will be downloaded again after %s",
+						file.Name,
+						file.Id,
+						retryAfter.Format("2006-01-02 15:04:05"),
+					),
+					false,
+					utils.INFO,
 				)
-				errChan <- &models.GdriveError{
-					Err: err,
+				dlFile.Skipped = true
+				return nil
+			}
+			if err == nil {
+				downloaded[idx] = true
+			} else if err != context.Canceled {
+				err = &models.GdriveError{
+					Err: fmt.Errorf(
+						"failed to download file: %s (ID: %s, MIME Type: %s)\nRefer to error details below:\n%v",
+						file.Name, file.Id, file.MimeType, err,
+					),
 					FilePath: filepath.Join(
 						file.FilePath,
 						GDRIVE_ERROR_FILENAME,
 					),
 				}
 			}
-			progress.MsgIncrement(baseMsg)
-		}(file)
+			return err
+		},
+		ErrHandler: processGdriveDlError,
+	})
+	if skippedFiles > 0 {
+		color.Yellow(
+			"Download quota reached, %d GDrive file(s) have been saved to %s and will be downloaded in the next run.",
+			skippedFiles,
+			GetDeferredFilesPath(),
+		)
+	}
+	if deferredFiles > 0 {
+		color.Yellow(
+			"The download quota of %d GDrive file(s) has been exceeded, they will be downloaded again in a run after %s.",
+			deferredFiles,
+			DOWNLOAD_QUOTA_RETRY_AFTER,
+		)
 	}
-	wg.Wait()
-	close(queue)
-	close(errChan)
 
-	hasErr := false
-	if len(errChan) > 0 {
-		hasErr = true
-		processGdriveDlError(errChan, progress)
+	var downloadedFiles []*models.GdriveFileToDl
+	for idx, file := range allowedForDownload {
+		if downloaded[idx] {
+			downloadedFiles = append(downloadedFiles, file)
+		}
+	}
+	removeDeferredFiles(downloadedFiles)
+	request.MarkPostsComplete(filePaths)
+
+	if !config.ChecksumManifest && !config.ExtractArchives {
+		return
+	}
+	if config.ExtractArchives {
+		request.ExtractPostArchives(filePaths)
+	}
+	if config.ChecksumManifest {
+		utils.WriteChecksumManifests(filePaths)
 	}
-	progress.Stop(hasErr)
 }
 
 // Uses regex to extract the file ID and the file type (type: file, folder) from the given URL
@@ -257,7 +338,8 @@ func GetFileIdAndTypeFromUrl(url string) (string, string) {
 	matchedFileType := matched[utils.GDRIVE_REGEX_TYPE_INDEX]
 	if strings.Contains(matchedFileType, "folder") {
 		fileType = "folder"
-	} else if strings.Contains(matchedFileType, "file") {
+	} else if strings.HasSuffix(matchedFileType, "/d") {
+		// Google Docs, Sheets, Slides and Drawings are files that are exported, see convertGdriveFile
 		fileType = "file"
 	} else {
 		err := fmt.Errorf(
@@ -284,6 +366,20 @@ func (gdrive *GDrive) getGdriveFileInfo(gdriveId *models.GDriveToDl, config *con
 				FilePath: gdriveId.FilePath,
 			}
 		}
+		if fileInfo.IsShortcut && fileInfo.MimeType == GDRIVE_FOLDER_MIME_TYPE {
+			// the file link was a shortcut to a folder
+			return gdrive.getGdriveFileInfo(
+				&models.GDriveToDl{
+					Id:       fileInfo.Id,
+					Type:     "folder",
+					FilePath: gdriveId.FilePath,
+				},
+				config,
+			)
+		}
+		if !config.ShouldDlFile(fileInfo.Name) {
+			return nil, nil
+		}
 		fileInfo.FilePath = gdriveId.FilePath
 		return []*models.GdriveFileToDl{fileInfo}, nil
 	case "folder":
@@ -317,8 +413,12 @@ func (gdrive *GDrive) getGdriveFileInfo(gdriveId *models.GDriveToDl, config *con
 }
 
 // Downloads multiple GDrive files based on a slice of GDrive URL strings in parallel
+//
+// The deferred files whose download quota was exceeded in an earlier run
+// are also downloaded if they can be downloaded again.
 func (gdrive *GDrive) DownloadGdriveUrls(gdriveUrls []*request.ToDownload, config *configs.Config) error {
-	if len(gdriveUrls) == 0 {
+	dueFiles := getDueDeferredFiles()
+	if len(gdriveUrls) == 0 && len(dueFiles) == 0 {
 		return nil
 	}
 
@@ -326,7 +426,7 @@ func (gdrive *GDrive) DownloadGdriveUrls(gdriveUrls []*request.ToDownload, confi
 	var gdriveIds []*models.GDriveToDl
 	for _, gdriveUrl := range gdriveUrls {
 		fileId, fileType := GetFileIdAndTypeFromUrl(gdriveUrl.Url)
-		if fileId != "" && fileType != "" {
+		if fileId != "" && fileType != "" && config.ShouldDlPost(gdriveUrl.FilePath) {
 			gdriveIds = append(gdriveIds, &models.GDriveToDl{
 				Id:       fileId,
 				Type:     fileType,
@@ -380,6 +480,23 @@ func (gdrive *GDrive) DownloadGdriveUrls(gdriveUrls []*request.ToDownload, confi
 	}
 	progress.Stop(hasErr)
 
+	if len(dueFiles) > 0 {
+		queued := make(map[string]struct{}, len(gdriveFilesInfo))
+		for _, file := range gdriveFilesInfo {
+			queued[getDeferredFileKey(file)] = struct{}{}
+		}
+		for _, file := range dueFiles {
+			if _, ok := queued[getDeferredFileKey(file)]; !ok {
+				gdriveFilesInfo = append(gdriveFilesInfo, file)
+			}
+		}
+		utils.LogError(
+			nil,
+			fmt.Sprintf("Downloading %d deferred GDrive file(s) again as their download quota should have reset", len(dueFiles)),
+			false,
+			utils.INFO,
+		)
+	}
 	gdrive.DownloadMultipleFiles(gdriveFilesInfo, config)
 	return nil
 }
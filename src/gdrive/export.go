@@ -0,0 +1,36 @@
+package gdrive
+
+import (
+	"strings"
+)
+
+type exportFormat struct {
+	mimeType string
+	ext      string
+}
+
+// The formats that the Google Docs, Sheets, Slides, and Drawings files are exported as
+// since they cannot be downloaded directly like other files:
+// https://developers.google.com/drive/api/guides/ref-export-formats
+var exportFormats = map[string]exportFormat{
+	"application/vnd.google-apps.document":     {mimeType: "application/pdf", ext: ".pdf"},
+	"application/vnd.google-apps.presentation": {mimeType: "application/pdf", ext: ".pdf"},
+	"application/vnd.google-apps.drawing":      {mimeType: "application/pdf", ext: ".pdf"},
+	"application/vnd.google-apps.spreadsheet": {
+		mimeType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		ext:      ".xlsx",
+	},
+}
+
+// Returns the MIME type to export the Google Workspace file as and the
+// file name with the extension of the exported file if the file can be exported
+func getExportFormat(mimeType, name string) (string, string, bool) {
+	format, ok := exportFormats[mimeType]
+	if !ok {
+		return "", name, false
+	}
+	if !strings.HasSuffix(strings.ToLower(name), format.ext) {
+		name += format.ext
+	}
+	return format.mimeType, name, true
+}
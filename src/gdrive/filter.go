@@ -0,0 +1,112 @@
+package gdrive
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/gdrive/models"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const NO_MAX_DEPTH = -1
+
+// Filters is used to limit which files are retrieved from GDrive folders.
+//
+// This is useful for huge shared folders (e.g. a creator's entire archive)
+// where downloading everything is not desired.
+type Filters struct {
+	// MaxDepth is the maximum number of subfolder levels to recurse into.
+	// 0 means only the files directly in the linked folder.
+	// NO_MAX_DEPTH means no limit.
+	MaxDepth int
+
+	// FileExts is the file extensions to download, e.g. ".zip".
+	// If empty, files of any extension will be downloaded.
+	FileExts []string
+
+	// MinFileSize and MaxFileSize are the file size limits in bytes.
+	// If 0, there will be no limit.
+	MinFileSize int64
+	MaxFileSize int64
+}
+
+// Returns the default filters which does not filter out any files
+func GetDefaultFilters() *Filters {
+	return &Filters{
+		MaxDepth: NO_MAX_DEPTH,
+	}
+}
+
+// Validates the filters and normalises the file extensions to lowercase with a leading dot.
+//
+// If the filters are invalid, an error will be returned.
+func (f *Filters) ValidateArgs() error {
+	if f.MaxDepth < NO_MAX_DEPTH {
+		return fmt.Errorf(
+			"gdrive error %d: max depth must be at least %d, got %d",
+			utils.INPUT_ERROR,
+			NO_MAX_DEPTH,
+			f.MaxDepth,
+		)
+	}
+
+	if f.MinFileSize < 0 || f.MaxFileSize < 0 {
+		return fmt.Errorf(
+			"gdrive error %d: file size limits cannot be negative",
+			utils.INPUT_ERROR,
+		)
+	}
+	if f.MaxFileSize != 0 && f.MinFileSize > f.MaxFileSize {
+		return fmt.Errorf(
+			"gdrive error %d: min file size (%d bytes) cannot be greater than the max file size (%d bytes)",
+			utils.INPUT_ERROR,
+			f.MinFileSize,
+			f.MaxFileSize,
+		)
+	}
+
+	for idx, ext := range f.FileExts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		f.FileExts[idx] = ext
+	}
+	return nil
+}
+
+// Returns true if the given subfolder depth is allowed to be recursed into
+func (f *Filters) canRecurse(depth int) bool {
+	return f.MaxDepth == NO_MAX_DEPTH || depth < f.MaxDepth
+}
+
+// Returns true if the given file passes the file type and size filters
+func (f *Filters) isAllowed(file *models.GdriveFileToDl) bool {
+	if len(f.FileExts) > 0 {
+		ext := strings.ToLower(filepath.Ext(file.Name))
+		if !utils.SliceContains(f.FileExts, ext) {
+			return false
+		}
+	}
+
+	if f.MinFileSize == 0 && f.MaxFileSize == 0 {
+		return true
+	}
+
+	// GDrive returns the size as a string (int64 format)
+	fileSize, err := strconv.ParseInt(file.Size, 10, 64)
+	if err != nil {
+		// size is not available for some files like shortcuts,
+		// hence, let it through and let the download process handle it.
+		return true
+	}
+	if f.MinFileSize != 0 && fileSize < f.MinFileSize {
+		return false
+	}
+	if f.MaxFileSize != 0 && fileSize > f.MaxFileSize {
+		return false
+	}
+	return true
+}
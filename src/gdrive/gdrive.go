@@ -2,13 +2,11 @@ package gdrive
 
 import (
 	"fmt"
-	"os"
 	"regexp"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
-	"github.com/fatih/color"
 )
 
 const (
@@ -18,7 +16,12 @@ const (
 
 	// file fields to fetch from GDrive API:
 	// https://developers.google.com/drive/api/v3/reference/files
-	GDRIVE_FILE_FIELDS = "id,name,size,mimeType,md5Checksum"
+	GDRIVE_FILE_FIELDS = "id,name,size,mimeType,md5Checksum,shortcutDetails"
+
+	GDRIVE_API_URL = "https://www.googleapis.com/drive/v3/files"
+
+	GDRIVE_FOLDER_MIME_TYPE   = "application/vnd.google-apps.folder"
+	GDRIVE_SHORTCUT_MIME_TYPE = "application/vnd.google-apps.shortcut"
 )
 
 var (
@@ -27,44 +30,73 @@ var (
 )
 
 type GDrive struct {
-	apiKey             string // Google Drive API key to use
-	apiUrl             string // https://www.googleapis.com/drive/v3/files
-	timeout            int    // timeout in seconds for GDrive API v3
-	downloadTimeout    int    // timeout in seconds for GDrive file downloads
-	maxDownloadWorkers int    // max concurrent workers for downloading files
+	apiKeys            *apiKeyPool // Google Drive API keys to use
+	apiUrl             string      // https://www.googleapis.com/drive/v3/files
+	timeout            int         // timeout in seconds for GDrive API v3
+	downloadTimeout    int         // timeout in seconds for GDrive file downloads
+	maxDownloadWorkers int         // max concurrent workers for downloading files
+	filters            *Filters    // filters to apply when retrieving files from GDrive folders
 }
 
-// Returns a GDrive structure with the given API key and max download workers
-func GetNewGDrive(apiKey string, config *configs.Config, maxDownloadWorkers int) *GDrive {
+// Returns a GDrive structure with the given API keys, max download workers, and folder filters
+//
+// The next API key will be used when the current API key has exceeded its quota.
+//
+// If filters is nil, the default filters will be used which does not filter out any files.
+func GetNewGDrive(apiKeys []string, config *configs.Config, maxDownloadWorkers int, filters *Filters) *GDrive {
+	if maxDownloadWorkers < 1 {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "The number of Google Drive download workers must be at least 1.")
+	}
+
+	if filters == nil {
+		filters = GetDefaultFilters()
+	} else if err := filters.ValidateArgs(); err != nil {
+		utils.ExitWithErrorf(utils.EXIT_INPUT_ERROR, "%v", err)
+	}
+
 	gdrive := &GDrive{
-		apiKey:             apiKey,
-		apiUrl:             "https://www.googleapis.com/drive/v3/files",
+		apiKeys:            newApiKeyPool(apiKeys),
+		apiUrl:             GDRIVE_API_URL,
 		timeout:            15,
 		downloadTimeout:    900, // 15 minutes
 		maxDownloadWorkers: maxDownloadWorkers,
+		filters:            filters,
 	}
 
-	gdriveIsValid, err := gdrive.GDriveKeyIsValid(config.UserAgent)
-	if err != nil {
-		color.Red(err.Error())
-		os.Exit(1)
-	} else if !gdriveIsValid {
-		color.Red("Google Drive API key is invalid.")
-		os.Exit(1)
+	for _, apiKey := range apiKeys {
+		gdriveIsValid, err := gdrive.GDriveKeyIsValid(apiKey, config.UserAgent)
+		if err != nil {
+			utils.ExitWithErrorf(utils.GetExitCode(err), "%v", err)
+		} else if !gdriveIsValid {
+			if len(apiKeys) > 1 {
+				utils.ExitWithErrorf(utils.EXIT_AUTH_ERROR, "Google Drive API key %s is invalid.", MaskApiKey(apiKey))
+			}
+			utils.ExitWithErrorf(utils.EXIT_AUTH_ERROR, "Google Drive API key is invalid.")
+		}
 	}
 	return gdrive
 }
 
+// Checks if the given Google Drive API key is valid without
+// exiting the program unlike GetNewGDrive if the API key is invalid
+func ApiKeyIsValid(apiKey, userAgent string) (bool, error) {
+	gdrive := &GDrive{
+		apiUrl:  GDRIVE_API_URL,
+		timeout: 15,
+	}
+	return gdrive.GDriveKeyIsValid(apiKey, userAgent)
+}
+
 // Checks if the given Google Drive API key is valid
 //
 // Will return true if the given Google Drive API key is valid
-func (gdrive *GDrive) GDriveKeyIsValid(userAgent string) (bool, error) {
-	match := API_KEY_REGEX.MatchString(gdrive.apiKey)
+func (gdrive *GDrive) GDriveKeyIsValid(apiKey, userAgent string) (bool, error) {
+	match := API_KEY_REGEX.MatchString(apiKey)
 	if !match {
 		return false, nil
 	}
 
-	params := map[string]string{"key": gdrive.apiKey}
+	params := map[string]string{"key": apiKey}
 	res, err := request.CallRequest(
 		&request.RequestArgs{
 			Url:       gdrive.apiUrl,
@@ -0,0 +1,153 @@
+package gdrive
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	// cooldown of an API key that has exceeded one of the per-100-seconds quotas of the GDrive API
+	RATE_LIMIT_COOLDOWN = 100 * time.Second
+
+	// cooldown of an API key that has exceeded its daily quota
+	DAILY_LIMIT_COOLDOWN = 24 * time.Hour
+)
+
+// The reasons in the GDrive API error responses for an API key that
+// has exceeded its quota and the cooldown of the API key for each of them
+var quotaReasons = map[string]time.Duration{
+	"userRateLimitExceeded": RATE_LIMIT_COOLDOWN,
+	"rateLimitExceeded":     RATE_LIMIT_COOLDOWN,
+	"dailyLimitExceeded":    DAILY_LIMIT_COOLDOWN,
+	"quotaExceeded":         DAILY_LIMIT_COOLDOWN,
+}
+
+type apiKey struct {
+	key           string
+	cooldownUntil time.Time
+}
+
+// The GDrive API keys that are used one at a time where the next API key
+// is used once the current one has exceeded its quota until its cooldown is over
+type apiKeyPool struct {
+	mu   sync.Mutex
+	keys []*apiKey
+	cur  int
+}
+
+// Returns the API keys from the comma-separated API keys, e.g. from the --gdrive_api_key flag, without any duplicates
+func SplitApiKeys(apiKeys string) []string {
+	var keys []string
+	for _, key := range strings.Split(apiKeys, ",") {
+		key = strings.TrimSpace(key)
+		if key != "" && !utils.SliceContains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Returns the API key with all but its last 4 characters hidden so that it can be shown to the user
+func MaskApiKey(apiKey string) string {
+	if len(apiKey) <= 4 {
+		return strings.Repeat("*", len(apiKey))
+	}
+	return "..." + apiKey[len(apiKey)-4:]
+}
+
+func newApiKeyPool(keys []string) *apiKeyPool {
+	pool := &apiKeyPool{keys: make([]*apiKey, len(keys))}
+	for idx, key := range keys {
+		pool.keys[idx] = &apiKey{key: key}
+	}
+	return pool
+}
+
+// Returns the current API key or the next API key that is not cooling down
+func (p *apiKeyPool) get() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var nextAvailable time.Time
+	for i := 0; i < len(p.keys); i++ {
+		idx := (p.cur + i) % len(p.keys)
+		key := p.keys[idx]
+		if !now.Before(key.cooldownUntil) {
+			p.cur = idx
+			return key.key, nil
+		}
+		if nextAvailable.IsZero() || key.cooldownUntil.Before(nextAvailable) {
+			nextAvailable = key.cooldownUntil
+		}
+	}
+	return "", fmt.Errorf(
+		"gdrive error %d: all %d Google Drive API key(s) have exceeded their quota, the next API key can be used at %s",
+		utils.RESPONSE_ERROR,
+		len(p.keys),
+		nextAvailable.Format("2006-01-02 15:04:05"),
+	)
+}
+
+// Puts the API key on cooldown so that the next API key will be used
+func (p *apiKeyPool) cooldown(key string, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.key == key {
+			k.cooldownUntil = time.Now().Add(duration)
+			return
+		}
+	}
+}
+
+// Returns the cooldown of the API key if the response is an error
+// due to the API key exceeding its quota, otherwise 0.
+func getQuotaCooldown(res *http.Response) time.Duration {
+	for _, reason := range getErrorReasons(res) {
+		if cooldown, ok := quotaReasons[reason]; ok {
+			return cooldown
+		}
+	}
+	return 0
+}
+
+// Sends the request to the GDrive API with the current API key and sends it again with
+// the next API key if the API key has exceeded its quota until all API keys are cooling down
+func (gdrive *GDrive) callApi(reqArgs *request.RequestArgs) (*http.Response, error) {
+	for {
+		key, err := gdrive.apiKeys.get()
+		if err != nil {
+			return nil, err
+		}
+
+		reqArgs.Params["key"] = key
+		res, err := request.CallRequest(reqArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		cooldown := getQuotaCooldown(res)
+		if cooldown == 0 {
+			return res, nil
+		}
+		res.Body.Close()
+		gdrive.apiKeys.cooldown(key, cooldown)
+		utils.LogError(
+			nil,
+			fmt.Sprintf(
+				"Google Drive API key %s has exceeded its quota and will not be used for %s",
+				MaskApiKey(key),
+				cooldown,
+			),
+			false,
+			utils.INFO,
+		)
+	}
+}
@@ -1,12 +1,16 @@
 package models
 
 type GDriveFile struct {
-	Kind        string `json:"kind"`
-	Id          string `json:"id"`
-	Name        string `json:"name"`
-	Size        string `json:"size"`
-	MimeType    string `json:"mimeType"`
-	Md5Checksum string `json:"md5Checksum"`
+	Kind            string `json:"kind"`
+	Id              string `json:"id"`
+	Name            string `json:"name"`
+	Size            string `json:"size"`
+	MimeType        string `json:"mimeType"`
+	Md5Checksum     string `json:"md5Checksum"`
+	ShortcutDetails struct {
+		TargetId       string `json:"targetId"`
+		TargetMimeType string `json:"targetMimeType"`
+	} `json:"shortcutDetails"`
 }
 
 type GDriveFolder struct {
@@ -16,6 +20,18 @@ type GDriveFolder struct {
 	NextPageToken    string       `json:"nextPageToken"`
 }
 
+// Error response of the GDrive API v3, e.g. when the API key has exceeded its quota
+type GDriveErrorJson struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
 type GDriveToDl struct {
 	Id 	     string
 	Type     string
@@ -29,9 +45,23 @@ type GdriveFileToDl struct {
 	MimeType    string
 	Md5Checksum string
 	FilePath    string
+
+	// ExportMimeType is the MIME type to export the file as if it is a
+	// Google Docs, Sheets, or Slides file which cannot be downloaded directly.
+	// The file extension of the exported file has been added to the Name field.
+	ExportMimeType string
+
+	// IsShortcut is true if the file was a shortcut and
+	// the Id and MimeType fields have been replaced with the shortcut's target.
+	// The rest of the target's details have to be retrieved separately.
+	IsShortcut bool
 }
 
 type GdriveError struct {
 	Err      error
 	FilePath string
 }
+
+func (e *GdriveError) Error() string {
+	return e.Err.Error()
+}
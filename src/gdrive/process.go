@@ -4,7 +4,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/filehost"
 	"github.com/KJHJason/Cultured-Downloader-CLI/request"
+	"github.com/KJHJason/Cultured-Downloader-CLI/twitter"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
@@ -21,6 +23,8 @@ func ProcessPostText(postBodyStr, postFolderPath string, downloadGdrive bool, lo
 			return c == '\n'
 		},
 	)
+	utils.SavePasswords(utils.ExtractPasswordsFromText(postBodyStr), postFolderPath)
+
 	loggedPassword := false
 	var detectedGdriveLinks []*request.ToDownload
 	for _, text := range postBodySlice {
@@ -37,6 +41,8 @@ func ProcessPostText(postBodyStr, postFolderPath string, downloadGdrive bool, lo
 			}
 		}
 
+		filehost.DetectLinks(text, postFolderPath)
+		twitter.DetectLinks(text, postFolderPath)
 		if logUrls {
 			utils.DetectOtherExtDLLink(text, postFolderPath)
 		}	
@@ -0,0 +1,420 @@
+package htmlarchive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/fatih/color"
+)
+
+// The filename of the HTML page written in each post folder
+const ARCHIVE_FILENAME = "post.html"
+
+var (
+	imageExts = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".avif", ".bmp"}
+	videoExts = []string{".mp4", ".webm", ".mov", ".m4v"}
+	audioExts = []string{".mp3", ".wav", ".flac", ".ogg", ".m4a"}
+
+	// the elements in the HTML body of a post that are kept, any other element
+	// is replaced with its content unless it is one of the droppedElements
+	allowedElements = []string{
+		"html", "head", "body",
+		"a", "img", "p", "br", "hr", "div", "span",
+		"b", "strong", "i", "em", "u", "s", "strike", "del", "ins", "sub", "sup", "small", "mark",
+		"blockquote", "pre", "code", "h1", "h2", "h3", "h4", "h5", "h6",
+		"ul", "ol", "li", "dl", "dt", "dd",
+		"table", "thead", "tbody", "tfoot", "tr", "th", "td", "caption",
+		"figure", "figcaption", "ruby", "rt", "rp",
+	}
+
+	// the elements in the HTML body of a post that are removed with their content
+	// as they can run scripts, load other pages, or their content is not meant to be shown
+	droppedElements = []string{
+		"script", "style", "iframe", "frame", "frameset", "object", "embed", "applet", "form",
+		"link", "meta", "base", "svg", "math", "template", "noscript", "title", "textarea", "select",
+	}
+
+	// the attributes that are kept on the allowed elements
+	allowedAttrs = []string{"href", "src", "alt", "title", "width", "height", "colspan", "rowspan", "lang", "dir"}
+
+	// the attributes whose URLs are checked by isSafeUrl
+	urlAttrs = []string{"href", "src"}
+)
+
+// A file in the post folder linked from the HTML page
+type archivedFile struct {
+	Name string // the path relative to the post folder, e.g. "attachments/file.zip"
+	Href string // Name escaped for the URL
+	Kind string // "image", "video", "audio", or "file"
+}
+
+type archivePage struct {
+	Post      *events.Post
+	Published string
+	Body      template.HTML
+	Files     []*archivedFile
+}
+
+var pageTemplate = template.Must(template.New("post").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Post.Title}} - {{.Post.Creator}}</title>
+<style>
+body { max-width: 960px; margin: 0 auto; padding: 1em; font-family: sans-serif; line-height: 1.5; color: #222; background: #fafafa; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1em; }
+header p { color: #666; margin: 0.25em 0 1em; }
+.body { overflow-wrap: anywhere; margin-bottom: 2em; }
+.body img, .media img, .media video { max-width: 100%; height: auto; display: block; margin: 0 auto 1em; }
+.media audio { width: 100%; margin-bottom: 1em; }
+.files li { margin-bottom: 0.25em; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Post.Title}}</h1>
+<p>{{.Post.Creator}} &middot; {{.Post.Site}} post {{.Post.Id}}{{if .Published}} &middot; {{.Published}}{{end}}</p>
+</header>
+{{if .Body}}<div class="body">{{.Body}}</div>
+{{end}}<div class="media">
+{{range .Files}}{{if eq .Kind "image"}}<a href="{{.Href}}"><img src="{{.Href}}" alt="{{.Name}}" loading="lazy"></a>
+{{else if eq .Kind "video"}}<video src="{{.Href}}" controls preload="metadata"></video>
+{{else if eq .Kind "audio"}}<p>{{.Name}}</p><audio src="{{.Href}}" controls preload="none"></audio>
+{{end}}{{end}}</div>
+<h2>Files</h2>
+<ul class="files">
+{{range .Files}}<li><a href="{{.Href}}">{{.Name}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// Handler keeps track of the resolved posts to write an HTML page in each post folder once the run has finished
+// that shows the post's text with its downloaded images, videos, audio, and links to its other files.
+//
+// The page only links to the files with relative paths so the post folder can be browsed offline and moved elsewhere.
+type Handler struct {
+	events.BaseHandler
+
+	mu    sync.Mutex
+	posts map[string]*events.Post // the resolved posts keyed by their folder
+
+	// the paths of the downloaded files which are linked instead of the files in
+	// the post folder when the files are not written to the local disk
+	downloaded []string
+}
+
+// Returns a new Handler that writes an HTML page in the folder of each resolved post
+func NewHandler() *Handler {
+	return &Handler{
+		posts: make(map[string]*events.Post),
+	}
+}
+
+func (h *Handler) OnPostResolved(post *events.Post) {
+	if post.Folder == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.posts[filepath.Clean(post.Folder)] = post
+}
+
+func (h *Handler) OnFileDone(file *events.File, err error) {
+	if err != nil || file.Skipped || storage.IsLocal() {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.downloaded = append(h.downloaded, filepath.Clean(file.FilePath))
+}
+
+// Returns the kind of the file based on its file extension
+func getFileKind(filePath string) string {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	switch {
+	case utils.SliceContains(imageExts, ext):
+		return "image"
+	case utils.SliceContains(videoExts, ext):
+		return "video"
+	case utils.SliceContains(audioExts, ext):
+		return "audio"
+	default:
+		return "file"
+	}
+}
+
+// Escapes each part of the relative path so that it can be used as a relative URL
+func escapeRelPath(relPath string) string {
+	parts := strings.Split(relPath, "/")
+	for idx, part := range parts {
+		parts[idx] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// Returns the downloaded files in the post folder sorted by their path
+func getArchivedFiles(folderPath string) ([]*archivedFile, error) {
+	var filePaths []string
+	err := filepath.WalkDir(folderPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			filePaths = append(filePaths, filePath)
+		}
+		return nil
+	})
+	return toArchivedFiles(folderPath, filePaths), err
+}
+
+// Returns the files in the post folder to link from the HTML page sorted by their path
+func toArchivedFiles(folderPath string, filePaths []string) []*archivedFile {
+	var files []*archivedFile
+	for _, filePath := range filePaths {
+		filename := filepath.Base(filePath)
+		if strings.HasPrefix(filename, ".") {
+			continue
+		}
+		if filepath.Dir(filePath) == folderPath && (filename == ARCHIVE_FILENAME || filename == utils.CHECKSUM_MANIFEST_FILENAME) {
+			continue
+		}
+
+		relPath, err := filepath.Rel(folderPath, filePath)
+		if err != nil || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		relPath = filepath.ToSlash(relPath)
+		files = append(files, &archivedFile{
+			Name: relPath,
+			Href: escapeRelPath(relPath),
+			Kind: getFileKind(relPath),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files
+}
+
+// Returns true if the URL of a link or an image is a relative URL or a http, https, or mailto URL.
+//
+// The control characters and whitespace are removed first like browsers do, e.g. "java\tscript:".
+func isSafeUrl(rawUrl string) bool {
+	rawUrl = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, rawUrl)
+
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsedUrl.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+// Removes everything from the untrusted HTML body of a post except for the allowed elements
+// and attributes, and the links and images to URLs that are not allowed by isSafeUrl
+func sanitizeBody(doc *goquery.Document) {
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		name := strings.ToLower(goquery.NodeName(s))
+		if utils.SliceContains(droppedElements, name) {
+			s.Remove()
+			return
+		}
+		if !utils.SliceContains(allowedElements, name) {
+			s.ReplaceWithSelection(s.Contents())
+			return
+		}
+
+		for _, node := range s.Nodes {
+			attrs := node.Attr[:0]
+			for _, attr := range node.Attr {
+				key := strings.ToLower(attr.Key)
+				if attr.Namespace != "" || !utils.SliceContains(allowedAttrs, key) {
+					continue
+				}
+				if utils.SliceContains(urlAttrs, key) && !isSafeUrl(attr.Val) {
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			node.Attr = attrs
+		}
+	})
+}
+
+// Returns the text of the post as HTML where a HTML body is reduced to the allowed elements
+// and attributes and its images replaced with the downloaded ones that have the same filename
+func renderBody(post *events.Post, files []*archivedFile) template.HTML {
+	if strings.TrimSpace(post.Body) == "" {
+		return ""
+	}
+	if !post.BodyIsHtml {
+		escaped := html.EscapeString(strings.TrimSpace(post.Body))
+		return template.HTML(strings.ReplaceAll(escaped, "\n", "<br>\n"))
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(post.Body))
+	if err != nil {
+		return template.HTML(html.EscapeString(post.Body))
+	}
+	sanitizeBody(doc)
+
+	localImages := make(map[string]string)
+	for _, file := range files {
+		if file.Kind == "image" {
+			localImages[path.Base(file.Name)] = file.Href
+		}
+	}
+	doc.Find("img").Each(func(_ int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		parsedUrl, err := url.Parse(src)
+		if err != nil {
+			return
+		}
+		if href, ok := localImages[path.Base(parsedUrl.Path)]; ok {
+			s.SetAttr("src", href)
+		}
+	})
+
+	body, err := doc.Find("body").Html()
+	if err != nil {
+		return template.HTML(html.EscapeString(post.Body))
+	}
+	return template.HTML(body)
+}
+
+// Writes the HTML page of the post in its folder which links to the given files
+func writeArchive(post *events.Post, folderPath string, files []*archivedFile) error {
+	page := &archivePage{
+		Post:  post,
+		Body:  renderBody(post, files),
+		Files: files,
+	}
+	if !post.PublishedAt.IsZero() {
+		page.Published = post.PublishedAt.Format("2006-01-02 15:04")
+	}
+
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, page); err != nil {
+		return fmt.Errorf(
+			"html archive error %d: failed to render the HTML page of %s, more info => %v",
+			utils.DEV_ERROR,
+			folderPath,
+			err,
+		)
+	}
+	archivePath := filepath.Join(folderPath, ARCHIVE_FILENAME)
+	if err := storage.WriteFile(context.Background(), archivePath, buf.Bytes()); err != nil {
+		return fmt.Errorf(
+			"html archive error %d: failed to write %s, more info => %v",
+			utils.OS_ERROR,
+			archivePath,
+			err,
+		)
+	}
+	return nil
+}
+
+// Returns the files of each post to link from its HTML page keyed by the post folder.
+//
+// The files in the post folders on the local disk are listed, including the ones from previous runs,
+// while only the files downloaded in this run are known for the other storage backends.
+func (h *Handler) getPostFiles() (map[string][]*archivedFile, []error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	postFiles := make(map[string][]*archivedFile, len(h.posts))
+	if !storage.IsLocal() {
+		filePaths := make(map[string][]string)
+		for _, filePath := range h.downloaded {
+			if folder := h.getPostFolder(filePath); folder != "" {
+				filePaths[folder] = append(filePaths[folder], filePath)
+			}
+		}
+		for folder, paths := range filePaths {
+			postFiles[folder] = toArchivedFiles(folder, paths)
+		}
+		return postFiles, nil
+	}
+
+	var errSlice []error
+	for folder := range h.posts {
+		if !utils.PathExists(folder) {
+			continue
+		}
+		files, err := getArchivedFiles(folder)
+		if err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"html archive error %d: failed to list the files in %s, more info => %v",
+				utils.OS_ERROR,
+				folder,
+				err,
+			))
+			continue
+		}
+		postFiles[folder] = files
+	}
+	return postFiles, errSlice
+}
+
+// Returns the folder of the resolved post that the file is in by going up its parent folders, or "" if not found
+func (h *Handler) getPostFolder(filePath string) string {
+	for dir := filepath.Dir(filePath); ; {
+		if _, ok := h.posts[dir]; ok {
+			return dir
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return ""
+		}
+		dir = parentDir
+	}
+}
+
+// Writes the HTML page in the folder of each resolved post that exists and logs the posts that failed
+func (h *Handler) WriteAll() {
+	postFiles, errSlice := h.getPostFiles()
+	if len(postFiles) == 0 && len(errSlice) == 0 {
+		return
+	}
+
+	written := 0
+	for folder, files := range postFiles {
+		h.mu.Lock()
+		post := h.posts[folder]
+		h.mu.Unlock()
+		if err := writeArchive(post, folder, files); err != nil {
+			errSlice = append(errSlice, err)
+			continue
+		}
+		written++
+	}
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	color.Green("Wrote %s pages for %d post(s)", ARCHIVE_FILENAME, written)
+}
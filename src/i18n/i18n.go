@@ -0,0 +1,98 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	EN = "en"
+	JA = "ja"
+)
+
+var (
+	SUPPORTED_LANGUAGES = []string{EN, JA}
+
+	// Translations of the English messages keyed by the language.
+	//
+	// English messages are used as the keys so that
+	// a missing translation will fall back to the English message.
+	translations = map[string]map[string]string{
+		JA: jaMessages,
+	}
+
+	mu   sync.RWMutex
+	lang = EN
+)
+
+// Returns the language code from a locale, e.g. "ja" from "ja_JP.UTF-8"
+func normaliseLanguage(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if idx := strings.IndexAny(locale, "_-.@"); idx != -1 {
+		locale = locale[:idx]
+	}
+	return locale
+}
+
+// Returns true if the language is supported, e.g. "ja" or "ja_JP.UTF-8"
+func IsSupported(language string) bool {
+	language = normaliseLanguage(language)
+	for _, supported := range SUPPORTED_LANGUAGES {
+		if language == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the language from the LC_ALL, LC_MESSAGES, or LANG
+// environment variables, in that order, or an empty string if none are set.
+func DetectLanguage() string {
+	for _, envVar := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale := os.Getenv(envVar); locale != "" && locale != "C" && locale != "POSIX" {
+			return normaliseLanguage(locale)
+		}
+	}
+	return ""
+}
+
+// Sets the language of the messages shown to the user, e.g. "ja" or "ja_JP.UTF-8"
+func SetLanguage(language string) error {
+	if !IsSupported(language) {
+		return fmt.Errorf(
+			"unsupported language %q, please use one of %s",
+			language,
+			strings.Join(SUPPORTED_LANGUAGES, ", "),
+		)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	lang = normaliseLanguage(language)
+	return nil
+}
+
+// Returns the language of the messages shown to the user
+func GetLanguage() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return lang
+}
+
+// Returns the message translated to the set language,
+// or the given English message if there is no translation.
+func T(msg string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if translated, ok := translations[lang][msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Same as fmt.Sprintf but with the format translated to the set language
+func Sprintf(format string, a ...any) string {
+	return fmt.Sprintf(T(format), a...)
+}
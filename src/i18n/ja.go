@@ -0,0 +1,58 @@
+package i18n
+
+// Japanese translations of the messages shown to the user.
+//
+// Explicit argument indexes, e.g. "%[2]s", are used when the word order differs from the English message.
+var jaMessages = map[string]string{
+	// warnings
+	"CAUTION:": "注意：",
+	"Please do NOT terminate the program while it is downloading unless you really have to!": "やむを得ない場合を除き、ダウンロード中にプログラムを終了しないでください！",
+	"Doing so MAY result in incomplete downloads and corrupted files.":                       "終了すると、ダウンロードが不完全になったりファイルが破損したりする可能性があります。",
+
+	// root command
+	"Download path set to: %s":                                    "ダウンロード先を設定しました：%s",
+	"Saved the cookie file(s) to the config file for future runs": "次回以降のためにクッキーファイルを設定ファイルに保存しました",
+
+	// downloads
+	"Downloading %s":              "%sをダウンロード中",
+	"Finished downloading %d %s!": "%[2]sを%[1]d件ダウンロードしました！",
+	"Something went wrong while downloading %d %s!\nPlease refer to the generated log files for more details.": "%[2]s（%[1]d件）のダウンロード中にエラーが発生しました！\n詳細は生成されたログファイルを参照してください。",
+	"Stopped downloading %s (incomplete downloads will be deleted)...":                                         "%sのダウンロードを中止しました（不完全なファイルは削除されます）...",
+	"Stopped downloading GDrive files (incomplete downloads will be deleted)...":                               "GDriveファイルのダウンロードを中止しました（不完全なファイルは削除されます）...",
+
+	// Fantia
+	"Getting post ID(s) from Fanclubs(s) on Fantia":                                                                          "Fantiaのファンクラブから投稿IDを取得中",
+	"Finished getting post ID(s) from %d Fanclubs(s) on Fantia!":                                                             "Fantiaの%d件のファンクラブから投稿IDを取得しました！",
+	"Something went wrong while getting post IDs from %d Fanclubs(s) on Fantia.\nPlease refer to the logs for more details.": "Fantiaの%d件のファンクラブから投稿IDを取得中にエラーが発生しました。\n詳細はログを参照してください。",
+	"Downloaded all posts from Fantia!":                                                                                      "Fantiaの全ての投稿をダウンロードしました！",
+	"No posts to download from Fantia!":                                                                                      "Fantiaにダウンロードする投稿はありません！",
+
+	// Pixiv Fanbox
+	"Getting post details from Pixiv Fanbox":                                                                            "Pixiv Fanboxから投稿の詳細を取得中",
+	"Finished getting %d post details from Pixiv Fanbox!":                                                               "Pixiv Fanboxから%d件の投稿の詳細を取得しました！",
+	"Something went wrong while getting %d post details from Pixiv Fanbox.\nPlease refer to the logs for more details.": "Pixiv Fanboxから%d件の投稿の詳細を取得中にエラーが発生しました。\n詳細はログを参照してください。",
+	"Downloaded all posts from Pixiv Fanbox!":                                                                           "Pixiv Fanboxの全ての投稿をダウンロードしました！",
+	"No posts to download from Pixiv Fanbox!":                                                                           "Pixiv Fanboxにダウンロードする投稿はありません！",
+
+	// Pixiv
+	"Converting Ugoira to %s":              "うごイラを%sに変換中",
+	"Finished converting %d Ugoira to %s!": "%d件のうごイラを%sに変換しました！",
+	"Something went wrong while converting %d Ugoira to %s!\nPlease refer to the logs for more details.": "%d件のうごイラを%sに変換中にエラーが発生しました！\n詳細はログを参照してください。",
+	"Stopped converting ugoira to %s!":          "うごイラの%sへの変換を中止しました！",
+	"Finished downloading artworks from Pixiv!": "Pixivの作品のダウンロードが完了しました！",
+	"No artworks to download from Pixiv!":       "Pixivにダウンロードする作品はありません！",
+
+	// Kemono Party
+	"Getting post details from Kemono Party":                                                                               "Kemono Partyから投稿の詳細を取得中",
+	"Finished getting %d post details from Kemono Party!":                                                                  "Kemono Partyから%d件の投稿の詳細を取得しました！",
+	"Something went wrong while getting %d post details from Kemono Party.\nPlease refer to the logs for more details.":    "Kemono Partyから%d件の投稿の詳細を取得中にエラーが発生しました。\n詳細はログを参照してください。",
+	"Getting creator's posts from Kemono Party":                                                                            "Kemono Partyからクリエイターの投稿を取得中",
+	"Finished getting %d creator's posts from Kemono Party!":                                                               "Kemono Partyから%d人のクリエイターの投稿を取得しました！",
+	"Something went wrong while getting %d creator's posts from Kemono Party.\nPlease refer to the logs for more details.": "Kemono Partyから%d人のクリエイターの投稿を取得中にエラーが発生しました。\n詳細はログを参照してください。",
+	"Downloaded all posts from Kemono Party!":                                                                              "Kemono Partyの全ての投稿をダウンロードしました！",
+	"No posts to download from Kemono Party!":                                                                              "Kemono Partyにダウンロードする投稿はありません！",
+
+	// DLsite
+	"Downloaded all works from DLsite Play!": "DLsite Playの全ての作品をダウンロードしました！",
+	"No works to download from DLsite Play!": "DLsite Playにダウンロードする作品はありません！",
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Handler counts the downloads, bytes, and errors of a website and writes them in the
+// Prometheus text format to a file that can be collected by node_exporter's textfile collector.
+//
+// The file is rewritten atomically after every downloaded file and error.
+type Handler struct {
+	events.BaseHandler
+
+	mu         sync.Mutex
+	filePath   string
+	site       string
+	queueDepth func() int
+
+	filesDownloaded int64
+	bytesDownloaded int64
+	downloadErrors  int64
+	errors          int64
+}
+
+// Returns a new Handler that writes the metrics of the website to the given file path.
+//
+// queueDepth returns the number of files waiting to be downloaded in the next run and may be nil.
+func NewHandler(filePath, site string, queueDepth func() int) *Handler {
+	h := &Handler{
+		filePath:   filePath,
+		site:       site,
+		queueDepth: queueDepth,
+	}
+
+	// write the initial metrics so that the
+	// file exists even if nothing is downloaded
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.write()
+	return h
+}
+
+func (h *Handler) OnFileDone(file *events.File, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.downloadErrors++
+	} else if !file.Skipped {
+		h.filesDownloaded++
+		if fileSize, err := utils.GetFileSize(file.FilePath); err == nil {
+			h.bytesDownloaded += fileSize
+		}
+	}
+	h.write()
+}
+
+func (h *Handler) OnError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errors++
+	h.write()
+}
+
+// Writes the metrics to the file, should be called with h.mu held.
+func (h *Handler) write() {
+	queueDepth := 0
+	if h.queueDepth != nil {
+		queueDepth = h.queueDepth()
+	}
+
+	var sb strings.Builder
+	writeMetric := func(name, metricType, help string, value int64) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", name, metricType)
+		fmt.Fprintf(&sb, "%s{site=%q} %d\n", name, h.site, value)
+	}
+	writeMetric(
+		"cultured_downloader_files_downloaded_total", "counter",
+		"Number of files downloaded in the current run.", h.filesDownloaded,
+	)
+	writeMetric(
+		"cultured_downloader_bytes_downloaded_total", "counter",
+		"Number of bytes downloaded in the current run.", h.bytesDownloaded,
+	)
+	writeMetric(
+		"cultured_downloader_download_errors_total", "counter",
+		"Number of files that failed to download in the current run.", h.downloadErrors,
+	)
+	writeMetric(
+		"cultured_downloader_errors_total", "counter",
+		"Number of errors logged in the current run.", h.errors,
+	)
+	writeMetric(
+		"cultured_downloader_queue_depth", "gauge",
+		"Number of files that will be downloaded in the next run due to the download quota.", int64(queueDepth),
+	)
+	writeMetric(
+		"cultured_downloader_last_update_timestamp_seconds", "gauge",
+		"Unix time of the last update of the metrics.", time.Now().Unix(),
+	)
+
+	// write to a temporary file first so that the
+	// textfile collector will never read a partially written file
+	os.MkdirAll(filepath.Dir(h.filePath), 0666)
+	tmpFilePath := h.filePath + ".tmp"
+	if err := os.WriteFile(tmpFilePath, []byte(sb.String()), 0644); err != nil {
+		return
+	}
+	os.Rename(tmpFilePath, h.filePath)
+}
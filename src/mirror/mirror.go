@@ -0,0 +1,143 @@
+package mirror
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+const RCLONE_DOWNLOAD_URL = "https://rclone.org/downloads/"
+
+// Matches the year and month folders of the "date" layout, e.g. "<creator>/2023/04/<post>"
+var (
+	yearFolderRegex  = regexp.MustCompile(`^\d{4}$`)
+	monthFolderRegex = regexp.MustCompile(`^\d{2}$`)
+)
+
+// Returns the path to the rclone executable to use in the order of the given path,
+// the "rclone_path" in the tools section of the config file, and "rclone" in the PATH.
+func FindRclone(rclonePath string) (string, error) {
+	if rclonePath == "" {
+		if config, err := utils.LoadConfigFile(); err == nil && config.Tools != nil {
+			rclonePath = config.Tools.RclonePath
+		}
+	}
+	if rclonePath == "" {
+		rclonePath = "rclone"
+	}
+	foundPath, err := exec.LookPath(rclonePath)
+	if err != nil {
+		return "", fmt.Errorf(
+			"mirror error %d: rclone could not be found at %q, download it from %s, more info => %v",
+			utils.CMD_ERROR,
+			rclonePath,
+			RCLONE_DOWNLOAD_URL,
+			err,
+		)
+	}
+	return foundPath, nil
+}
+
+// Returns the creator folder of the post folder, skipping the year and month folders of the "date" layout
+func getCreatorFolder(postFolder string) string {
+	creatorFolder := filepath.Dir(filepath.Clean(postFolder))
+	yearFolder := filepath.Dir(creatorFolder)
+	if monthFolderRegex.MatchString(filepath.Base(creatorFolder)) && yearFolderRegex.MatchString(filepath.Base(yearFolder)) {
+		return filepath.Dir(yearFolder)
+	}
+	return creatorFolder
+}
+
+// Returns the remote path of the creator folder which is the
+// same path relative to the download directory on the remote
+func getRemotePath(remote, creatorFolder string) string {
+	relPath, err := filepath.Rel(utils.DOWNLOAD_PATH, creatorFolder)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		relPath = filepath.Base(creatorFolder)
+	}
+	if !strings.HasSuffix(remote, ":") {
+		remote = strings.TrimRight(remote, "/") + "/"
+	}
+	return remote + filepath.ToSlash(relPath)
+}
+
+// Handler keeps track of the creator folders of the resolved
+// posts to mirror them with rclone once the run has finished
+type Handler struct {
+	events.BaseHandler
+
+	config     *utils.MirrorConfig
+	rclonePath string
+
+	mu      sync.Mutex
+	folders map[string]struct{}
+}
+
+// Returns a new Handler that mirrors the creator folders to the remote in the given config
+func NewHandler(config *utils.MirrorConfig, rclonePath string) *Handler {
+	return &Handler{
+		config:     config,
+		rclonePath: rclonePath,
+		folders:    make(map[string]struct{}),
+	}
+}
+
+func (h *Handler) OnPostResolved(post *events.Post) {
+	if post.Folder == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.folders[getCreatorFolder(post.Folder)] = struct{}{}
+}
+
+// Mirrors the creator folders that exist to the remote one at a time and logs the folders that failed
+func (h *Handler) Mirror() {
+	h.mu.Lock()
+	folders := make([]string, 0, len(h.folders))
+	for folder := range h.folders {
+		if utils.PathExists(folder) {
+			folders = append(folders, folder)
+		}
+	}
+	h.mu.Unlock()
+	sort.Strings(folders)
+
+	mode := h.config.Mode
+	if mode == "" {
+		mode = utils.MIRROR_COPY
+	}
+	for idx, folder := range folders {
+		remotePath := getRemotePath(h.config.Remote, folder)
+		color.Cyan("\n[%d/%d] Mirroring %s to %s...", idx+1, len(folders), folder, remotePath)
+
+		args := append([]string{mode, folder, remotePath}, h.config.Args...)
+		cmd := exec.Command(h.rclonePath, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			utils.LogError(
+				fmt.Errorf(
+					"mirror error %d: failed to %s %s to %s with rclone, more info => %v",
+					utils.CMD_ERROR,
+					mode,
+					folder,
+					remotePath,
+					err,
+				),
+				"",
+				false,
+				utils.ERROR,
+			)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Task is called for each index from 0 to Count-1 and returns the result of the task,
+// an optional info string to show in the progress message, e.g. the downloaded filename, and the error if any.
+type Task[T any] func(idx int) (result T, info string, err error)
+
+// Progress contains the messages of the spinner that shows the progress of the tasks
+type Progress struct {
+	// SpinnerType is the type of spinner to use, e.g. spinner.REQ_SPINNER
+	SpinnerType string
+
+	// Msg is the message shown while the tasks are running, e.g. "Getting post details from Fantia".
+	// The number of completed tasks will be appended to it, e.g. "Getting post details from Fantia [1/10]...".
+	Msg string
+
+	// SuccessMsg and ErrMsg are shown when all the tasks have finished
+	SuccessMsg string
+	ErrMsg     string
+
+	// ShowInfo is a flag to show the info string returned by the last completed task
+	// or the filename of the last done file if the files are counted
+	ShowInfo bool
+
+	// IsOwnFile makes the spinner count the files of the tasks through the FileDone events
+	// instead of the completed tasks, where each task must emit exactly one FileDone event.
+	// It returns true if the file is from one of the tasks as other files may be downloading at the same time.
+	//
+	// If nil, the completed tasks are counted.
+	IsOwnFile func(file *events.File) bool
+}
+
+// Updates the spinner with the files of the tasks through the events of the download process
+type fileProgressHandler struct {
+	events.BaseHandler
+	opts     *Progress
+	progress *spinner.Spinner
+	baseMsg  string
+	hasErr   int32
+}
+
+func (h *fileProgressHandler) OnFileDone(file *events.File, err error) {
+	if !h.opts.IsOwnFile(file) {
+		return
+	}
+	if err != nil {
+		atomic.StoreInt32(&h.hasErr, 1)
+		if err == context.Canceled {
+			return
+		}
+	}
+
+	if !h.opts.ShowInfo {
+		h.progress.MsgIncrement(h.baseMsg)
+		return
+	}
+	var filename string
+	if filepath.Ext(file.FilePath) != "" {
+		filename = filepath.Base(file.FilePath)
+	}
+	h.progress.MsgIncrementWithInfo(h.baseMsg, filename)
+}
+
+// Any errors logged while the files are downloading are shown with the spinner's error message
+func (h *fileProgressHandler) OnError(err error) {
+	atomic.StoreInt32(&h.hasErr, 1)
+}
+
+type Options[T any] struct {
+	// Count is the number of tasks to run
+	Count int
+
+	// MaxConcurrency is the maximum number of tasks to run at any given time.
+	// It will be capped to Count and will default to 1 if it is less than 1.
+	MaxConcurrency int
+
+	// Progress is used to show a spinner while the tasks are running.
+	// If nil, no spinner will be shown.
+	Progress *Progress
+
+	Task Task[T]
+
+	// Lane returns the lane of the task at the index where the tasks in the lower lanes are started first,
+	// e.g. the small files before the large videos so that they are not queued behind them.
+	// The tasks in the same lane are started in the order of their index.
+	//
+	// If nil, all the tasks are started in the order of their index.
+	Lane func(idx int) int
+
+	// ErrHandler is called with the errors of the failed tasks before the spinner is stopped.
+	// The spinner is passed in to allow the handler to call KillProgram if needed (it may be nil).
+	//
+	// If nil, the errors will be logged using utils.LogErrors.
+	ErrHandler func(errs []error, progress *spinner.Spinner)
+}
+
+// Returns the indexes of the tasks in the order that they should be started based on their lanes
+func getStartOrder(count int, lane func(idx int) int) []int {
+	order := make([]int, count)
+	for idx := range order {
+		order[idx] = idx
+	}
+	if lane == nil {
+		return order
+	}
+
+	lanes := make([]int, count)
+	for idx := range lanes {
+		lanes[idx] = lane(idx)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return lanes[order[i]] < lanes[order[j]]
+	})
+	return order
+}
+
+// Run runs the tasks concurrently using a queue that
+// limits the number of running tasks to opts.MaxConcurrency.
+//
+// Returns the results of the successful tasks in the order of their index
+// and the errors of the failed tasks which would have already been handled by opts.ErrHandler.
+func Run[T any](opts *Options[T]) ([]T, []error) {
+	if opts.Count == 0 {
+		return nil, nil
+	}
+
+	maxConcurrency := opts.MaxConcurrency
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	if opts.Count < maxConcurrency {
+		maxConcurrency = opts.Count
+	}
+
+	var progress *spinner.Spinner
+	var baseMsg string
+	if opts.Progress != nil {
+		baseMsg = opts.Progress.Msg + " [%d/" + fmt.Sprintf("%d]...", opts.Count)
+		progress = spinner.New(
+			opts.Progress.SpinnerType,
+			"fgHiYellow",
+			fmt.Sprintf(
+				baseMsg,
+				0,
+			),
+			opts.Progress.SuccessMsg,
+			opts.Progress.ErrMsg,
+			opts.Count,
+		)
+		progress.Start()
+	}
+
+	var fileProgress *fileProgressHandler
+	if progress != nil && opts.Progress.IsOwnFile != nil {
+		fileProgress = &fileProgressHandler{
+			opts:     opts.Progress,
+			progress: progress,
+			baseMsg:  baseMsg,
+		}
+		unregister := events.Register(fileProgress)
+		defer unregister()
+	}
+
+	var wg sync.WaitGroup
+	queue := make(chan struct{}, maxConcurrency)
+	results := make([]T, opts.Count)
+	errs := make([]error, opts.Count)
+	for _, i := range getStartOrder(opts.Count, opts.Lane) {
+		// no new tasks are started while paused with the pause file
+		utils.WaitIfPaused(context.Background())
+
+		// acquire the slot before spawning the goroutine
+		// so that the tasks are started in the order of their lane and index
+		queue <- struct{}{}
+		wg.Add(1)
+		go func(idx int) {
+			defer func() {
+				<-queue
+				wg.Done()
+			}()
+
+			result, info, err := opts.Task(idx)
+			results[idx], errs[idx] = result, err
+			if progress == nil || fileProgress != nil || err == context.Canceled {
+				return
+			}
+
+			if opts.Progress.ShowInfo {
+				progress.MsgIncrementWithInfo(baseMsg, info)
+			} else {
+				progress.MsgIncrement(baseMsg)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(queue)
+
+	var succeeded []T
+	var failed []error
+	for idx, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		} else {
+			succeeded = append(succeeded, results[idx])
+		}
+	}
+
+	if len(failed) > 0 {
+		if opts.ErrHandler != nil {
+			opts.ErrHandler(failed, progress)
+		} else {
+			utils.LogErrors(false, nil, utils.ERROR, failed...)
+		}
+	}
+	if progress != nil {
+		hasErr := len(failed) > 0
+		if fileProgress != nil && atomic.LoadInt32(&fileProgress.hasErr) == 1 {
+			hasErr = true
+		}
+		progress.Stop(hasErr)
+	}
+	return succeeded, failed
+}
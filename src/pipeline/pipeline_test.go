@@ -0,0 +1,258 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+)
+
+// Discards the errors of the failed tasks instead of logging them
+func ignoreErrs(errs []error, progress *spinner.Spinner) {}
+
+func TestRunBoundedConcurrency(t *testing.T) {
+	tests := []struct {
+		name           string
+		count          int
+		maxConcurrency int
+		wantMax        int32
+	}{
+		{name: "sequential", count: 5, maxConcurrency: 1, wantMax: 1},
+		{name: "less than count", count: 12, maxConcurrency: 3, wantMax: 3},
+		{name: "capped to count", count: 2, maxConcurrency: 8, wantMax: 2},
+		{name: "defaults to 1", count: 4, maxConcurrency: 0, wantMax: 1},
+		{name: "negative defaults to 1", count: 4, maxConcurrency: -2, wantMax: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, maxRunning int32
+			results, errs := Run(&Options[int]{
+				Count:          tt.count,
+				MaxConcurrency: tt.maxConcurrency,
+				ErrHandler:     ignoreErrs,
+				Task: func(idx int) (int, string, error) {
+					cur := atomic.AddInt32(&running, 1)
+					for {
+						prevMax := atomic.LoadInt32(&maxRunning)
+						if cur <= prevMax || atomic.CompareAndSwapInt32(&maxRunning, prevMax, cur) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					atomic.AddInt32(&running, -1)
+					return idx, "", nil
+				},
+			})
+			if len(errs) != 0 || len(results) != tt.count {
+				t.Fatalf("Run() = %d results, %v errors, want %d results", len(results), errs, tt.count)
+			}
+			if maxRunning != tt.wantMax {
+				t.Errorf("max running tasks = %d, want %d", maxRunning, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestRunResultOrder(t *testing.T) {
+	tests := []struct {
+		name           string
+		count          int
+		maxConcurrency int
+	}{
+		{name: "sequential", count: 4, maxConcurrency: 1},
+		{name: "concurrent", count: 8, maxConcurrency: 8},
+		{name: "partially concurrent", count: 9, maxConcurrency: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, _ := Run(&Options[string]{
+				Count:          tt.count,
+				MaxConcurrency: tt.maxConcurrency,
+				ErrHandler:     ignoreErrs,
+				Task: func(idx int) (string, string, error) {
+					// the tasks with the lower indexes finish last
+					time.Sleep(time.Duration(tt.count-idx) * 2 * time.Millisecond)
+					return fmt.Sprintf("task %d", idx), "", nil
+				},
+			})
+
+			want := make([]string, tt.count)
+			for idx := range want {
+				want[idx] = fmt.Sprintf("task %d", idx)
+			}
+			if !reflect.DeepEqual(results, want) {
+				t.Errorf("Run() results = %v, want %v", results, want)
+			}
+		})
+	}
+}
+
+func TestRunStartOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		lanes []int
+		want  []int
+	}{
+		{name: "no lanes", lanes: nil, want: []int{0, 1, 2, 3}},
+		{name: "same lane", lanes: []int{1, 1, 1}, want: []int{0, 1, 2}},
+		{name: "lower lanes first", lanes: []int{2, 0, 1, 0}, want: []int{1, 3, 2, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count := len(tt.want)
+			var lane func(idx int) int
+			if tt.lanes != nil {
+				lane = func(idx int) int { return tt.lanes[idx] }
+			}
+
+			var mu sync.Mutex
+			var started []int
+			Run(&Options[int]{
+				Count:          count,
+				MaxConcurrency: 1,
+				Lane:           lane,
+				ErrHandler:     ignoreErrs,
+				Task: func(idx int) (int, string, error) {
+					mu.Lock()
+					started = append(started, idx)
+					mu.Unlock()
+					return idx, "", nil
+				},
+			})
+			if !reflect.DeepEqual(started, tt.want) {
+				t.Errorf("started tasks = %v, want %v", started, tt.want)
+			}
+			if got := getStartOrder(count, lane); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getStartOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunErrorAggregation(t *testing.T) {
+	tests := []struct {
+		name        string
+		count       int
+		failing     []int
+		wantResults []int
+	}{
+		{name: "no errors", count: 3, failing: nil, wantResults: []int{0, 1, 2}},
+		{name: "some errors", count: 5, failing: []int{1, 3}, wantResults: []int{0, 2, 4}},
+		{name: "all errors", count: 2, failing: []int{0, 1}, wantResults: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskErrs := make(map[int]error)
+			var wantErrs []error
+			for _, idx := range tt.failing {
+				taskErrs[idx] = fmt.Errorf("task %d failed", idx)
+				wantErrs = append(wantErrs, taskErrs[idx])
+			}
+
+			var handled [][]error
+			results, errs := Run(&Options[int]{
+				Count:          tt.count,
+				MaxConcurrency: tt.count,
+				ErrHandler: func(errs []error, progress *spinner.Spinner) {
+					handled = append(handled, errs)
+				},
+				Task: func(idx int) (int, string, error) {
+					return idx, "", taskErrs[idx]
+				},
+			})
+
+			if !reflect.DeepEqual(results, tt.wantResults) {
+				t.Errorf("Run() results = %v, want %v", results, tt.wantResults)
+			}
+			if len(errs) != len(wantErrs) {
+				t.Fatalf("Run() errors = %v, want %v", errs, wantErrs)
+			}
+			for idx, err := range errs {
+				if !errors.Is(err, wantErrs[idx]) {
+					t.Errorf("Run() errors[%d] = %v, want %v", idx, err, wantErrs[idx])
+				}
+			}
+
+			switch {
+			case len(wantErrs) == 0 && len(handled) != 0:
+				t.Errorf("ErrHandler was called with %v when no task failed", handled)
+			case len(wantErrs) != 0 && (len(handled) != 1 || !reflect.DeepEqual(handled[0], errs)):
+				t.Errorf("ErrHandler calls = %v, want one call with %v", handled, errs)
+			}
+		})
+	}
+}
+
+func TestRunNoTasks(t *testing.T) {
+	called := false
+	results, errs := Run(&Options[int]{
+		Count: 0,
+		Task: func(idx int) (int, string, error) {
+			called = true
+			return idx, "", nil
+		},
+	})
+	if called || results != nil || errs != nil {
+		t.Errorf("Run() with no tasks = %v, %v, called = %v", results, errs, called)
+	}
+}
+
+func TestFileProgressHandler(t *testing.T) {
+	ownFiles := []*events.File{
+		{Url: "https://example.com/a.jpg", FilePath: "post/a.jpg"},
+		{Url: "https://example.com/b", FilePath: "post"},
+		{Url: "https://example.com/c.png", FilePath: "post/c.png"},
+	}
+	otherFile := &events.File{Url: "https://example.com/d.jpg", FilePath: "other/d.jpg"}
+
+	baseMsg := "Downloading files [%d/3]..."
+	progress := spinner.New(spinner.DL_SPINNER, "fgHiYellow", fmt.Sprintf(baseMsg, 0), "", "", len(ownFiles))
+	handler := &fileProgressHandler{
+		opts: &Progress{
+			ShowInfo: true,
+			IsOwnFile: func(file *events.File) bool {
+				for _, ownFile := range ownFiles {
+					if file == ownFile {
+						return true
+					}
+				}
+				return false
+			},
+		},
+		progress: progress,
+		baseMsg:  baseMsg,
+	}
+	unregister := events.Register(handler)
+	defer unregister()
+
+	tests := []struct {
+		name       string
+		file       *events.File
+		err        error
+		wantMsg    string
+		wantHasErr int32
+	}{
+		{name: "downloaded", file: ownFiles[0], wantMsg: "Downloading files [1/3]... (a.jpg)"},
+		{name: "other download", file: otherFile, wantMsg: "Downloading files [1/3]... (a.jpg)"},
+		{name: "no filename", file: ownFiles[1], wantMsg: "Downloading files [2/3]..."},
+		{name: "failed", file: ownFiles[2], err: errors.New("failed"), wantMsg: "Downloading files [3/3]... (c.png)", wantHasErr: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events.FileStart(tt.file)
+			events.FileDone(tt.file, tt.err)
+			if progress.Msg != tt.wantMsg {
+				t.Errorf("spinner message = %q, want %q", progress.Msg, tt.wantMsg)
+			}
+			if hasErr := atomic.LoadInt32(&handler.hasErr); hasErr != tt.wantHasErr {
+				t.Errorf("hasErr = %d, want %d", hasErr, tt.wantHasErr)
+			}
+		})
+	}
+}
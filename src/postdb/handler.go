@@ -0,0 +1,72 @@
+package postdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+)
+
+// Handler records the posts resolved in a run of a download command
+// and the posts that were not found on the website which are saved by Save after the run
+type Handler struct {
+	events.BaseHandler
+
+	mu       sync.Mutex
+	posts    map[string]*Post
+	notFound map[string]struct{}
+}
+
+// Returns a new Handler that records the posts resolved in the run
+func NewHandler() *Handler {
+	return &Handler{
+		posts:    make(map[string]*Post),
+		notFound: make(map[string]struct{}),
+	}
+}
+
+func getSeenAt() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func (h *Handler) OnPostResolved(post *events.Post) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.posts[getPostKey(post.Site, post.Id)] = &Post{
+		Site:       post.Site,
+		Id:         post.Id,
+		CreatorId:  post.CreatorId,
+		Creator:    post.Creator,
+		Title:      post.Title,
+		Folder:     post.Folder,
+		FileCount:  post.FileCount,
+		FileFilter: post.FileFilter,
+		LastSeenAt: getSeenAt(),
+	}
+}
+
+func (h *Handler) OnPostNotFound(site, postId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.notFound[getPostKey(site, postId)] = struct{}{}
+}
+
+// Saves the posts resolved in the run to the posts file in the app data folder
+// and returns the previously resolved posts that were newly found to be deleted on the website
+func (h *Handler) Save() ([]*Post, error) {
+	h.mu.Lock()
+	resolved := make([]*Post, 0, len(h.posts))
+	for _, post := range h.posts {
+		resolved = append(resolved, post)
+	}
+	notFoundKeys := make([]string, 0, len(h.notFound))
+	for key := range h.notFound {
+		notFoundKeys = append(notFoundKeys, key)
+	}
+	h.mu.Unlock()
+
+	if len(resolved) == 0 && len(notFoundKeys) == 0 {
+		return nil, nil
+	}
+	return updatePosts(resolved, notFoundKeys, getSeenAt())
+}
@@ -0,0 +1,221 @@
+package postdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Post is the state of a post that has been resolved by a download command
+type Post struct {
+	Site      string `json:"site"`
+	Id        string `json:"id"`
+	CreatorId string `json:"creator_id,omitempty"`
+	Creator   string `json:"creator"`
+	Title     string `json:"title"`
+	Folder    string `json:"folder"`
+	FileCount int    `json:"file_count"`
+
+	// FileFilter is the download options that the FileCount of the last run depends on, e.g. --dl_images,
+	// see events.GetFileFilter, and FileCounts is the file count of the post for each of the FileFilter
+	// of the runs so far, as only the file counts of the runs with the same download options can be compared.
+	FileFilter string         `json:"file_filter,omitempty"`
+	FileCounts map[string]int `json:"file_counts,omitempty"`
+
+	// PrevFileCount is the file count of the post before it changed and FileCountChangedAt
+	// is when the change was noticed, both are zero if the file count did not change
+	// the last time that the post was resolved by a run with the same download options.
+	PrevFileCount      int       `json:"prev_file_count,omitempty"`
+	FileCountChangedAt time.Time `json:"file_count_changed_at"`
+
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+
+	// DeletedUpstreamAt is when the website first responded with a 404 for the post,
+	// zero if the post still exists or was resolved again afterwards.
+	DeletedUpstreamAt time.Time `json:"deleted_upstream_at"`
+}
+
+// Returns true if the file count of the post changed the last time that it was resolved
+func (p *Post) FileCountChanged() bool {
+	return !p.FileCountChangedAt.IsZero()
+}
+
+// Returns true if the post no longer exists on the website, i.e. the downloaded files may be its only copy
+func (p *Post) IsDeletedUpstream() bool {
+	return !p.DeletedUpstreamAt.IsZero()
+}
+
+var postsMu sync.Mutex
+
+// Returns the path to the file in the app data folder that stores the state of the resolved posts
+func GetDbFilePath() string {
+	return filepath.Join(utils.APP_PATH, "posts.json")
+}
+
+func getPostKey(site, postId string) string {
+	return site + "|" + postId
+}
+
+// Returns the state of the resolved posts with the website and post ID as the key, see GetCreatorPosts
+func LoadPosts() (map[string]*Post, error) {
+	postsMu.Lock()
+	defer postsMu.Unlock()
+	return loadPosts()
+}
+
+func loadPosts() (map[string]*Post, error) {
+	posts := make(map[string]*Post)
+	dbFile, err := os.ReadFile(GetDbFilePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return posts, nil
+		}
+		return nil, fmt.Errorf(
+			"post db error %d: failed to read the posts file, more info => %v",
+			utils.OS_ERROR,
+			err,
+		)
+	}
+
+	if err = json.Unmarshal(dbFile, &posts); err != nil {
+		return nil, fmt.Errorf(
+			"post db error %d: failed to unmarshal the posts file, more info => %v",
+			utils.JSON_ERROR,
+			err,
+		)
+	}
+	return posts, nil
+}
+
+func savePosts(posts map[string]*Post) error {
+	dbFile, err := json.Marshal(posts)
+	if err != nil {
+		return fmt.Errorf(
+			"post db error %d: failed to marshal the posts, more info => %v",
+			utils.JSON_ERROR,
+			err,
+		)
+	}
+
+	os.MkdirAll(utils.APP_PATH, 0666)
+	if err = os.WriteFile(GetDbFilePath(), dbFile, 0666); err != nil {
+		return fmt.Errorf(
+			"post db error %d: failed to write the posts file, more info => %v",
+			utils.OS_ERROR,
+			err,
+		)
+	}
+	return nil
+}
+
+// Returns the posts of the creator on the website sorted by their post IDs
+func GetCreatorPosts(posts map[string]*Post, site, creatorId string) []*Post {
+	var creatorPosts []*Post
+	for _, post := range posts {
+		if post.Site == site && post.CreatorId == creatorId {
+			creatorPosts = append(creatorPosts, post)
+		}
+	}
+	sort.Slice(creatorPosts, func(i, j int) bool {
+		return lessPostId(creatorPosts[i].Id, creatorPosts[j].Id)
+	})
+	return creatorPosts
+}
+
+// Returns the IDs of the recorded posts of the creator on the website that are not in the given
+// full listing of the creator's current posts and have not been flagged as deleted upstream yet.
+//
+// As the details of these posts are not requested by the run, they have to be requested
+// separately to find out if they were deleted, e.g. by checking for a 404 response.
+func GetUnlistedPostIds(site, creatorId string, listedPostIds []string) []string {
+	posts, err := LoadPosts()
+	if err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+		return nil
+	}
+
+	listed := make(map[string]struct{}, len(listedPostIds))
+	for _, postId := range listedPostIds {
+		listed[postId] = struct{}{}
+	}
+
+	var unlisted []string
+	for _, post := range GetCreatorPosts(posts, site, creatorId) {
+		if _, ok := listed[post.Id]; !ok && !post.IsDeletedUpstream() {
+			unlisted = append(unlisted, post.Id)
+		}
+	}
+	return unlisted
+}
+
+// Returns true if the post ID is before the other post ID
+// which compares the numeric post IDs by their length first, e.g. "99" is before "100"
+func lessPostId(postId, otherPostId string) bool {
+	if len(postId) != len(otherPostId) {
+		return len(postId) < len(otherPostId)
+	}
+	return postId < otherPostId
+}
+
+// Merges the resolved posts into the posts file, notes the posts whose file count changed,
+// and flags the saved posts that were not found on the website at the given time as deleted upstream.
+//
+// Returns the saved posts that were newly flagged as deleted upstream.
+func updatePosts(resolved []*Post, notFoundKeys []string, notFoundAt time.Time) ([]*Post, error) {
+	postsMu.Lock()
+	defer postsMu.Unlock()
+	posts, err := loadPosts()
+	if err != nil {
+		return nil, err
+	}
+
+	// posts that were never resolved are not flagged as they were never downloaded
+	var deleted []*Post
+	for _, key := range notFoundKeys {
+		if savedPost, ok := posts[key]; ok && !savedPost.IsDeletedUpstream() {
+			savedPost.DeletedUpstreamAt = notFoundAt
+			deleted = append(deleted, savedPost)
+		}
+	}
+
+	for _, post := range resolved {
+		key := getPostKey(post.Site, post.Id)
+		savedPost, ok := posts[key]
+		if !ok {
+			post.FirstSeenAt = post.LastSeenAt
+			post.FileCounts = map[string]int{post.FileFilter: post.FileCount}
+			posts[key] = post
+			continue
+		}
+
+		if savedPost.FileCounts == nil {
+			// the posts saved before the file counts were kept for each FileFilter
+			savedPost.FileCounts = map[string]int{savedPost.FileFilter: savedPost.FileCount}
+		}
+		if prevFileCount, ok := savedPost.FileCounts[post.FileFilter]; ok && prevFileCount != post.FileCount {
+			savedPost.PrevFileCount = prevFileCount
+			savedPost.FileCountChangedAt = post.LastSeenAt
+		} else if ok {
+			savedPost.PrevFileCount = 0
+			savedPost.FileCountChangedAt = time.Time{}
+		}
+		savedPost.FileCounts[post.FileFilter] = post.FileCount
+		savedPost.CreatorId = post.CreatorId
+		savedPost.Creator = post.Creator
+		savedPost.Title = post.Title
+		savedPost.Folder = post.Folder
+		savedPost.FileCount = post.FileCount
+		savedPost.FileFilter = post.FileFilter
+		savedPost.LastSeenAt = post.LastSeenAt
+		savedPost.DeletedUpstreamAt = time.Time{}
+	}
+	return deleted, savePosts(posts)
+}
@@ -110,7 +110,7 @@ func (args *RequestArgs) getDefaultArgs() {
 	}
 
 	if args.UserAgent == "" {
-		args.UserAgent = utils.USER_AGENT
+		args.UserAgent = utils.NextUserAgent()
 	}
 
 	if args.Context == nil {
@@ -0,0 +1,130 @@
+package request
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Limit of the download speed between the start and end durations since midnight
+type bandwidthWindow struct {
+	start          time.Duration
+	end            time.Duration
+	bytesPerSecond int64
+}
+
+// Returns true if the time of the day falls within the window
+func (w *bandwidthWindow) contains(timeOfDay time.Duration) bool {
+	switch {
+	case w.start == w.end:
+		return true
+	case w.start < w.end:
+		return timeOfDay >= w.start && timeOfDay < w.end
+	default:
+		// the window wraps around midnight
+		return timeOfDay >= w.start || timeOfDay < w.end
+	}
+}
+
+var (
+	bandwidthMu      sync.Mutex
+	bandwidthWindows []*bandwidthWindow
+
+	// the time when the next bytes can be read so that the reads
+	// of all the concurrent downloads share the same limit
+	bandwidthNextAt time.Time
+)
+
+// Sets the scheduled limits of the download speed from the config file.
+//
+// The limits are evaluated on every read of the downloads,
+// so long runs like the "serve" command will follow the schedule as the day goes on.
+func SetBandwidthLimits(limits []*utils.BandwidthLimitConfig) error {
+	windows := make([]*bandwidthWindow, 0, len(limits))
+	for _, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		start, end, bytesPerSecond, err := limit.Parse()
+		if err != nil {
+			return err
+		}
+		windows = append(windows, &bandwidthWindow{
+			start:          start,
+			end:            end,
+			bytesPerSecond: bytesPerSecond,
+		})
+	}
+
+	bandwidthMu.Lock()
+	defer bandwidthMu.Unlock()
+	bandwidthWindows = windows
+	return nil
+}
+
+// Returns the download speed limit in bytes per second at the given time or 0 if there is no limit
+func getBandwidthLimit(now time.Time) int64 {
+	bandwidthMu.Lock()
+	defer bandwidthMu.Unlock()
+
+	timeOfDay := time.Duration(now.Hour())*time.Hour +
+		time.Duration(now.Minute())*time.Minute +
+		time.Duration(now.Second())*time.Second
+	for _, window := range bandwidthWindows {
+		if window.contains(timeOfDay) {
+			return window.bytesPerSecond
+		}
+	}
+	return 0
+}
+
+// Reserves the time to read n bytes at the given speed limit and returns how long to wait before reading them
+func reserveBandwidth(n int, bytesPerSecond int64) time.Duration {
+	bandwidthMu.Lock()
+	defer bandwidthMu.Unlock()
+
+	now := time.Now()
+	if bandwidthNextAt.Before(now) {
+		bandwidthNextAt = now
+	}
+	waitFor := bandwidthNextAt.Sub(now)
+	bandwidthNextAt = bandwidthNextAt.Add(time.Duration(float64(n) / float64(bytesPerSecond) * float64(time.Second)))
+	return waitFor
+}
+
+// Limits the speed of reading the response body based on the scheduled bandwidth limits
+type bandwidthLimitedReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (b *bandwidthLimitedReader) Read(p []byte) (int, error) {
+	bytesPerSecond := getBandwidthLimit(time.Now())
+	if bytesPerSecond == 0 {
+		return b.reader.Read(p)
+	}
+
+	// read in smaller chunks for a smoother download speed
+	chunkSize := int(bytesPerSecond / 4)
+	if chunkSize < 4096 {
+		chunkSize = 4096
+	}
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+
+	n, err := b.reader.Read(p)
+	if n > 0 {
+		if waitFor := reserveBandwidth(n, bytesPerSecond); waitFor > 0 {
+			select {
+			case <-time.After(waitFor):
+			case <-b.ctx.Done():
+				return n, b.ctx.Err()
+			}
+		}
+	}
+	return n, err
+}
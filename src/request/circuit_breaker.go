@@ -0,0 +1,174 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Returned, wrapped, for the requests that are not sent as their host is failing
+var errCircuitOpen = errors.New("the host has failed too many times in a row")
+
+// Stops the requests to a host for a cooldown period after too many consecutive failures
+// so that a host that is down does not generate an error for every queued file.
+//
+// Once the cooldown is over, a single request is let through to check if the host has recovered.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int       // consecutive failures
+	openUntil time.Time // the requests are rejected until then, zero if the requests are allowed
+	probing   bool      // a request is checking if the host has recovered after the cooldown
+	trips     int       // number of times the requests were stopped
+	rejected  int       // number of requests that were not sent
+}
+
+var (
+	breakerMaxFailures int
+	breakerCooldown    time.Duration
+
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*circuitBreaker)
+)
+
+// Sets the number of consecutive failed requests to a host after which the requests to it
+// are stopped for the cooldown period, 0 means the requests are never stopped.
+//
+// A failure is a connection error, a 5xx status code, or a 429 status code.
+func SetCircuitBreaker(maxFailures int, cooldown time.Duration) error {
+	if maxFailures < 0 {
+		return fmt.Errorf(
+			"error %d: the number of failures for the circuit breaker cannot be negative but got %d",
+			utils.INPUT_ERROR,
+			maxFailures,
+		)
+	}
+	if maxFailures > 0 && cooldown <= 0 {
+		return fmt.Errorf(
+			"error %d: the cooldown of the circuit breaker must be more than 0 seconds but got %s",
+			utils.INPUT_ERROR,
+			cooldown,
+		)
+	}
+	breakerMaxFailures = maxFailures
+	breakerCooldown = cooldown
+	return nil
+}
+
+// Returns the circuit breaker of the host or nil if the circuit breaker is disabled
+func getCircuitBreaker(host string) *circuitBreaker {
+	if breakerMaxFailures == 0 {
+		return nil
+	}
+
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	host = strings.ToLower(host)
+	breaker, ok := breakers[host]
+	if !ok {
+		breaker = &circuitBreaker{}
+		breakers[host] = breaker
+	}
+	return breaker
+}
+
+// Returns an error wrapping errCircuitOpen if the request to the host should not be sent
+func (b *circuitBreaker) allow(host string) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if !b.probing && !time.Now().Before(b.openUntil) {
+		b.probing = true
+		return nil
+	}
+
+	b.rejected++
+	return fmt.Errorf(
+		"error %d: skipped the request to %s until %s, %w",
+		utils.CONNECTION_ERROR,
+		host,
+		b.openUntil.Format("15:04:05"),
+		errCircuitOpen,
+	)
+}
+
+// Records the outcome of a request sent to the host
+func (b *circuitBreaker) record(res *http.Response, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasProbing := b.probing
+	b.probing = false
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err == nil && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+
+	b.failures++
+	// the failures of the requests that were already sent when the requests were stopped are ignored
+	if wasProbing || (b.openUntil.IsZero() && b.failures >= breakerMaxFailures) {
+		// the jitter spreads out the probes of the hosts that failed at the same time
+		jitter := utils.GetRandomTime(0, breakerCooldown.Seconds()/10)
+		b.openUntil = time.Now().Add(breakerCooldown + jitter)
+		b.failures = 0
+		b.trips++
+	}
+}
+
+// Logs an error for each host whose requests were stopped by the circuit breaker
+// with the number of requests that were skipped instead of logging an error for each of them
+func ReportCircuitBreakers() {
+	breakersMu.Lock()
+	hosts := make([]string, 0, len(breakers))
+	for host, breaker := range breakers {
+		breaker.mu.Lock()
+		if breaker.trips > 0 {
+			hosts = append(hosts, host)
+		}
+		breaker.mu.Unlock()
+	}
+	breakersMu.Unlock()
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		breaker := getCircuitBreaker(host)
+		breaker.mu.Lock()
+		trips, rejected := breaker.trips, breaker.rejected
+		breaker.mu.Unlock()
+		utils.LogError(
+			fmt.Errorf(
+				"error %d: the requests to %s were stopped %d time(s) for %s after %d failures in a row, "+
+					"%d request(s) were skipped and will be retried in the next run",
+				utils.CONNECTION_ERROR,
+				host,
+				trips,
+				breakerCooldown,
+				breakerMaxFailures,
+				rejected,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+}
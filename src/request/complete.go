@@ -0,0 +1,167 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	incompletePostsMu sync.Mutex
+
+	// the post folders with a file that was not downloaded in this run,
+	// which are not marked as complete again until the next run
+	incompletePosts = make(map[string]struct{})
+)
+
+// Returns the marker to write to the post folders, i.e. the time that they were marked
+func getMarkerContent() []byte {
+	return []byte(time.Now().Format(time.RFC3339) + "\n")
+}
+
+// Marks the post that the file is in as incomplete as the file was not downloaded,
+// e.g. due to an error or the download quota, by replacing its POST_COMPLETE_FILENAME
+// marker, if any, with the POST_INCOMPLETE_FILENAME marker.
+//
+// The post will not be marked as complete again in this run, so that it will be resumed by the next run.
+func MarkPostIncomplete(filePath string) {
+	postFolder := utils.GetPostFolderFromPath(filePath)
+	if postFolder == "" {
+		return
+	}
+
+	incompletePostsMu.Lock()
+	_, marked := incompletePosts[postFolder]
+	incompletePosts[postFolder] = struct{}{}
+	incompletePostsMu.Unlock()
+	if marked {
+		return
+	}
+
+	ctx := context.Background()
+	storage.GetBackend().Remove(ctx, filepath.Join(postFolder, utils.POST_COMPLETE_FILENAME))
+	markerPath := filepath.Join(postFolder, utils.POST_INCOMPLETE_FILENAME)
+	if err := storage.WriteFile(ctx, markerPath, getMarkerContent()); err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"error %d: failed to mark the post at %s as incomplete, more info => %v",
+				utils.OS_ERROR,
+				postFolder,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+}
+
+// Returns the post folders that the files are in
+func getPostFolders(filePaths []string) map[string]struct{} {
+	postFolders := make(map[string]struct{})
+	for _, filePath := range filePaths {
+		if postFolder := utils.GetPostFolderFromPath(filePath); postFolder != "" {
+			postFolders[postFolder] = struct{}{}
+		}
+	}
+	return postFolders
+}
+
+// Writes the POST_INCOMPLETE_FILENAME marker to the post folders that the files to download are in
+// before downloading them, so that the posts are resumed by the next run if this run is interrupted.
+//
+// The post folders that were already marked as complete by a previous run are left as they are.
+func MarkPostsInProgress(filePaths []string) {
+	ctx := context.Background()
+	var errSlice []error
+	for postFolder := range getPostFolders(filePaths) {
+		incompletePostsMu.Lock()
+		_, incomplete := incompletePosts[postFolder]
+		incompletePostsMu.Unlock()
+		if incomplete || storage.Exists(ctx, filepath.Join(postFolder, utils.POST_COMPLETE_FILENAME)) {
+			continue
+		}
+
+		markerPath := filepath.Join(postFolder, utils.POST_INCOMPLETE_FILENAME)
+		if storage.Exists(ctx, markerPath) {
+			continue
+		}
+		if err := storage.WriteFile(ctx, markerPath, getMarkerContent()); err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"error %d: failed to mark the post at %s as in progress, more info => %v",
+				utils.OS_ERROR,
+				postFolder,
+				err,
+			))
+		}
+	}
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+}
+
+// Writes the POST_COMPLETE_FILENAME marker to the post folder and removes its
+// POST_INCOMPLETE_FILENAME marker unless a file of the post was not downloaded in this run
+func markPostComplete(ctx context.Context, postFolder string) error {
+	incompletePostsMu.Lock()
+	_, incomplete := incompletePosts[postFolder]
+	incompletePostsMu.Unlock()
+	if incomplete {
+		return nil
+	}
+
+	markerPath := filepath.Join(postFolder, utils.POST_COMPLETE_FILENAME)
+	if !storage.Exists(ctx, markerPath) {
+		if err := storage.WriteFile(ctx, markerPath, getMarkerContent()); err != nil {
+			return fmt.Errorf(
+				"error %d: failed to mark the post at %s as complete, more info => %v",
+				utils.OS_ERROR,
+				postFolder,
+				err,
+			)
+		}
+	}
+	storage.GetBackend().Remove(ctx, filepath.Join(postFolder, utils.POST_INCOMPLETE_FILENAME))
+	return nil
+}
+
+// Marks the post folders that the downloaded files are in as complete, see markPostComplete,
+// which is called after the files in a batch of files, e.g. GDrive files, have been downloaded
+func MarkPostsComplete(filePaths []string) {
+	ctx := context.Background()
+	var errSlice []error
+	for postFolder := range getPostFolders(filePaths) {
+		if err := markPostComplete(ctx, postFolder); err != nil {
+			errSlice = append(errSlice, err)
+		}
+	}
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+}
+
+// EmptyPostHandler marks the resolved posts without any files to download as complete,
+// e.g. text-only posts, as they are never in a batch of files to download
+type EmptyPostHandler struct {
+	events.BaseHandler
+}
+
+// Returns a new EmptyPostHandler
+func NewEmptyPostHandler() *EmptyPostHandler {
+	return &EmptyPostHandler{}
+}
+
+func (h *EmptyPostHandler) OnPostResolved(post *events.Post) {
+	if post.FileCount > 0 || post.Folder == "" || !utils.POST_FOLDER_REGEX.MatchString(filepath.Base(post.Folder)) {
+		return
+	}
+	if err := markPostComplete(context.Background(), post.Folder); err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+}
@@ -0,0 +1,90 @@
+package request
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	// Used to guard the values of the cookies used in the requests
+	// as they may be updated when the website rotates the session cookie.
+	cookieMu sync.RWMutex
+
+	// Maps the session cookies to the cookie file they were parsed from
+	persistedCookies = make(map[*http.Cookie]string)
+)
+
+// PersistCookies registers the given cookies that were parsed from the cookie file
+// so that any rotated values received via the Set-Cookie header will be written back to the cookie file.
+func PersistCookies(cookies []*http.Cookie, cookieFilePath string) {
+	cookieMu.Lock()
+	defer cookieMu.Unlock()
+
+	for _, cookie := range cookies {
+		persistedCookies[cookie] = cookieFilePath
+	}
+}
+
+// Returns true if the cookie set by the response is for the domain of the request cookie
+// where a cookie without a domain is for the host that sent the response
+func isSameCookieDomain(reqCookie, resCookie *http.Cookie, res *http.Response) bool {
+	resDomain := strings.TrimPrefix(resCookie.Domain, ".")
+	if resDomain == "" && res.Request != nil {
+		resDomain = res.Request.URL.Hostname()
+	}
+	reqDomain := strings.TrimPrefix(reqCookie.Domain, ".")
+	return strings.EqualFold(resDomain, reqDomain) ||
+		strings.HasSuffix(strings.ToLower(resDomain), "."+strings.ToLower(reqDomain))
+}
+
+// Updates the values of the request cookies if
+// the website has rotated them via the Set-Cookie header.
+//
+// If the cookie was registered with PersistCookies, the new value will also be written to its cookie file.
+func updateRotatedCookies(reqCookies []*http.Cookie, res *http.Response) {
+	if len(reqCookies) == 0 {
+		return
+	}
+
+	resCookies := res.Cookies()
+	if len(resCookies) == 0 {
+		return
+	}
+
+	cookieMu.Lock()
+	defer cookieMu.Unlock()
+	for _, resCookie := range resCookies {
+		if resCookie.Value == "" || resCookie.MaxAge < 0 {
+			// the website is trying to delete the cookie
+			continue
+		}
+
+		for _, reqCookie := range reqCookies {
+			if reqCookie.Name != resCookie.Name || reqCookie.Value == resCookie.Value {
+				continue
+			}
+			if !isSameCookieDomain(reqCookie, resCookie, res) {
+				continue // same cookie name but for another website
+			}
+
+			reqCookie.Value = resCookie.Value
+			if resCookie.MaxAge > 0 {
+				reqCookie.Expires = time.Now().Add(time.Duration(resCookie.MaxAge) * time.Second)
+			} else if !resCookie.Expires.IsZero() {
+				reqCookie.Expires = resCookie.Expires
+			}
+
+			cookieFilePath, ok := persistedCookies[reqCookie]
+			if !ok {
+				continue
+			}
+			if err := utils.UpdateCookieFile(cookieFilePath, reqCookie); err != nil {
+				utils.LogError(err, "", false, utils.ERROR)
+			}
+		}
+	}
+}
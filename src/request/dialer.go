@@ -0,0 +1,258 @@
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	// Types of the DNS records in the DNS-over-HTTPS JSON API
+	dohTypeA    = 1
+	dohTypeAAAA = 28
+
+	dnsTimeout = 10 * time.Second
+)
+
+// Options of how the connections to the websites are made.
+//
+// The zero value uses the system's DNS resolver with both IPv4 and IPv6.
+type networkOptions struct {
+	// ipVersion is 4 or 6 to only connect over IPv4 or IPv6, or 0 for both
+	ipVersion int
+
+	// resolver is the DNS server to use if dohUrl is empty, nil to use the system's DNS resolver
+	resolver *net.Resolver
+
+	// dohUrl is the DNS-over-HTTPS JSON API endpoint, e.g. "https://cloudflare-dns.com/dns-query"
+	dohUrl string
+}
+
+var netOptions *networkOptions
+
+// Sets how the connections to the websites are made.
+//
+// ipVersion can be 4 or 6 to only connect over IPv4 or IPv6, or 0 for both.
+//
+// dnsServer can be a DNS server address, e.g. "1.1.1.1" or "1.1.1.1:53", a DNS-over-HTTPS
+// JSON API endpoint, e.g. "https://cloudflare-dns.com/dns-query", or empty to use the system's DNS resolver.
+func SetNetworkOptions(ipVersion int, dnsServer string) error {
+	if ipVersion != 0 && ipVersion != 4 && ipVersion != 6 {
+		return fmt.Errorf(
+			"error %d: IP version must be 4 or 6 but got %d",
+			utils.INPUT_ERROR,
+			ipVersion,
+		)
+	}
+	if ipVersion == 0 && dnsServer == "" {
+		netOptions = nil
+		return nil
+	}
+
+	options := &networkOptions{ipVersion: ipVersion}
+	if strings.HasPrefix(dnsServer, "https://") {
+		if _, err := url.ParseRequestURI(dnsServer); err != nil {
+			return fmt.Errorf(
+				"error %d: invalid DNS-over-HTTPS URL %q, more info => %v",
+				utils.INPUT_ERROR,
+				dnsServer,
+				err,
+			)
+		}
+		options.dohUrl = dnsServer
+	} else if dnsServer != "" {
+		if _, _, err := net.SplitHostPort(dnsServer); err != nil {
+			dnsServer = net.JoinHostPort(dnsServer, "53")
+		}
+		dialer := &net.Dialer{Timeout: dnsTimeout}
+		options.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, dnsServer)
+			},
+		}
+	}
+	netOptions = options
+	return nil
+}
+
+type dohAnswer struct {
+	Type int    `json:"type"`
+	Data string `json:"data"`
+}
+
+type dohResponse struct {
+	Status int         `json:"Status"`
+	Answer []dohAnswer `json:"Answer"`
+}
+
+// Queries the DNS-over-HTTPS JSON API for the IP addresses of the given record type
+func (o *networkOptions) lookupDoh(ctx context.Context, host string, recordType int) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+
+	dohUrl := fmt.Sprintf("%s?name=%s&type=%d", o.dohUrl, url.QueryEscape(host), recordType)
+	req, err := http.NewRequestWithContext(ctx, "GET", dohUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	// the DNS-over-HTTPS server itself is resolved with the system's DNS resolver
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("DNS-over-HTTPS server returned a %s response", res.Status)
+	}
+
+	var dohRes dohResponse
+	if err := json.NewDecoder(res.Body).Decode(&dohRes); err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for _, answer := range dohRes.Answer {
+		if answer.Type != recordType {
+			continue // e.g. CNAME records
+		}
+		if ip := net.ParseIP(answer.Data); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// Returns the IP addresses of the host based on the network options
+func (o *networkOptions) lookupIPs(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	var ips []net.IP
+	if o.dohUrl != "" {
+		var recordTypes []int
+		switch o.ipVersion {
+		case 4:
+			recordTypes = []int{dohTypeA}
+		case 6:
+			recordTypes = []int{dohTypeAAAA}
+		default:
+			recordTypes = []int{dohTypeA, dohTypeAAAA}
+		}
+
+		var lookupErr error
+		for _, recordType := range recordTypes {
+			recordIps, err := o.lookupDoh(ctx, host, recordType)
+			if err != nil {
+				lookupErr = err
+				continue
+			}
+			ips = append(ips, recordIps...)
+		}
+		if len(ips) == 0 && lookupErr != nil {
+			return nil, lookupErr
+		}
+	} else {
+		resolver := o.resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		network := "ip"
+		if o.ipVersion != 0 {
+			network = fmt.Sprintf("ip%d", o.ipVersion)
+		}
+
+		var err error
+		if ips, err = resolver.LookupIP(ctx, network, host); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no IP addresses found for %s", host)
+	}
+	return ips, nil
+}
+
+// Returns the network to dial based on the IP version, e.g. "tcp4" for an IPv4 address
+func getIpNetwork(network string, ip net.IP) string {
+	if ip.To4() != nil {
+		return network + "4"
+	}
+	return network + "6"
+}
+
+// Dials the address over TCP after resolving it based on the network options
+func (o *networkOptions) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := o.lookupIPs(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to resolve %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			host,
+			err,
+		)
+	}
+
+	dialer := &net.Dialer{Timeout: connectTimeout}
+	var dialErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, getIpNetwork("tcp", ip), net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+		if errors.Is(err, context.Canceled) {
+			break
+		}
+	}
+	return nil, dialErr
+}
+
+// Dials the address over QUIC for HTTP/3 after resolving it based on the network options
+func (o *networkOptions) dialQuic(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := o.lookupIPs(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to resolve %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			host,
+			err,
+		)
+	}
+
+	// the TLS server name has already been set to the host by the HTTP/3 round tripper
+	var dialErr error
+	for _, ip := range ips {
+		conn, err := quic.DialAddrEarly(ctx, net.JoinHostPort(ip.String(), port), tlsCfg, cfg)
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+		if errors.Is(err, context.Canceled) {
+			break
+		}
+	}
+	return nil, dialErr
+}
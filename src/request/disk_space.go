@@ -0,0 +1,138 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+var (
+	diskSpaceMu sync.Mutex
+
+	// minFreeSpace is the number of bytes to keep free on the
+	// file system of the downloaded files, 0 to disable the check
+	minFreeSpace    int64
+	pauseOnLowSpace bool
+
+	// reservedSpace is the total size of the downloads in progress
+	// which is kept until they finish to err on the side of caution
+	reservedSpace int64
+
+	// lowDiskSpace is set when the downloads have been stopped due to low disk space
+	lowDiskSpace bool
+	lowSpaceMsg  string
+)
+
+// Returned when a file was not downloaded as there is not enough free disk space
+var errLowDiskSpace = fmt.Errorf(
+	"error %d: not enough free disk space to download the file",
+	utils.OS_ERROR,
+)
+
+// Sets the number of bytes to keep free on the file system of the downloaded files, 0 to disable the check.
+//
+// If pause is true, the downloads will be paused with the pause file until the user has freed up
+// some space and resumes them instead of stopping and saving the remaining files for the next run.
+func SetDiskSpaceOptions(minFree int64, pause bool) {
+	diskSpaceMu.Lock()
+	defer diskSpaceMu.Unlock()
+	minFreeSpace = minFree
+	pauseOnLowSpace = pause
+}
+
+// Returns true if the downloads have been stopped due to low disk space
+func isLowDiskSpace() bool {
+	diskSpaceMu.Lock()
+	defer diskSpaceMu.Unlock()
+	return lowDiskSpace
+}
+
+// Checks that there is enough free space on the file system of the
+// file path to download the given number of bytes, if known, and reserves it
+// until the returned function is called after the download has finished.
+//
+// If there is not enough space, the downloads will either be paused until the user resumes them
+// or errLowDiskSpace will be returned for this and all the following downloads.
+func reserveDiskSpace(ctx context.Context, filePath string, size int64) (func(), error) {
+	if size < 0 {
+		size = 0 // unknown size, the minimum free space will have to be enough
+	}
+	for {
+		diskSpaceMu.Lock()
+		if minFreeSpace <= 0 || !storage.IsLocal() {
+			diskSpaceMu.Unlock()
+			return func() {}, nil
+		}
+		if lowDiskSpace {
+			diskSpaceMu.Unlock()
+			return nil, errLowDiskSpace
+		}
+
+		freeSpace, err := utils.GetFreeSpace(filePath)
+		if err != nil {
+			// the downloads should not be stopped if the free space cannot be determined
+			diskSpaceMu.Unlock()
+			return func() {}, nil
+		}
+		needed := size + reservedSpace + minFreeSpace
+		if freeSpace >= needed {
+			reservedSpace += size
+			lowSpaceMsg = ""
+			diskSpaceMu.Unlock()
+			return func() {
+				diskSpaceMu.Lock()
+				reservedSpace -= size
+				diskSpaceMu.Unlock()
+			}, nil
+		}
+
+		msg := fmt.Sprintf(
+			"Only %s of free disk space is left for %s but %s is needed, including the %s to keep free.",
+			utils.FormatFileSize(freeSpace),
+			filePath,
+			utils.FormatFileSize(needed),
+			utils.FormatFileSize(minFreeSpace),
+		)
+		if !pauseOnLowSpace {
+			lowDiskSpace = true
+			diskSpaceMu.Unlock()
+			color.Red("\n%s\nStopping the downloads before the disk runs out of space...", msg)
+			return nil, errLowDiskSpace
+		}
+
+		// only notify once for all the workers that are waiting
+		notify := lowSpaceMsg == ""
+		lowSpaceMsg = msg
+		diskSpaceMu.Unlock()
+		if notify {
+			color.Red("\n%s\nPlease free up some disk space before resuming the downloads.", msg)
+		}
+		// only this run is paused as the other running programs may be downloading to another disk
+		if err := utils.SetRunPaused(true); err != nil {
+			return nil, err
+		}
+		err = utils.WaitIfPaused(ctx)
+
+		// in case the run was stopped while paused as the pause file would otherwise be left behind
+		utils.SetRunPaused(false)
+		if err != nil {
+			return nil, err
+		}
+		diskSpaceMu.Lock()
+		lowSpaceMsg = ""
+		diskSpaceMu.Unlock()
+	}
+}
+
+// Checks that there is at least the minimum free space on the file system of
+// the download folder before starting the downloads, pausing or stopping them if there is not
+func checkDiskSpace(ctx context.Context) {
+	release, err := reserveDiskSpace(ctx, utils.DOWNLOAD_PATH, 0)
+	if err == nil {
+		release()
+	}
+}
@@ -2,6 +2,7 @@ package request
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,33 +13,53 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/pipeline"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
 	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 )
 
+// Returns the full file path of the file to be downloaded
+//
+// If the given file path is a directory, the filename will be taken from the
+// Content-Disposition header of the response or the URL if the header is not present.
+// The file extension will then be corrected based on the MIME type of the file's content.
 func getFullFilePath(res *http.Response, filePath string) (string, error) {
+	mimeType := getContentMimeType(res)
+
 	// check if filepath already have a filename attached
 	if filepath.Ext(filePath) != "" {
-		filePathDir := filepath.Dir(filePath)
-		os.MkdirAll(filePathDir, 0666)
+		if storage.IsLocal() {
+			os.MkdirAll(filepath.Dir(filePath), 0666)
+		}
+		filePath = fixFilenameExt(filePath, mimeType)
 		filePathWithoutExt := utils.RemoveExtFromFilename(filePath)
 		return filePathWithoutExt + strings.ToLower(filepath.Ext(filePath)), nil
 	}
 
-	os.MkdirAll(filePath, 0666)
-	filename, err := url.PathUnescape(res.Request.URL.String())
-	if err != nil {
-		// should never happen but just in case
-		return "", fmt.Errorf(
-			"error %d: failed to unescape URL, more info => %v\nurl: %s",
-			utils.UNEXPECTED_ERROR,
-			err,
-			res.Request.URL.String(),
-		)
+	if storage.IsLocal() {
+		os.MkdirAll(filePath, 0666)
 	}
-	filename = utils.GetLastPartOfUrl(filename)
+	filename := getContentDispositionFilename(res)
+	if filename == "" {
+		unescapedUrl, err := url.PathUnescape(res.Request.URL.String())
+		if err != nil {
+			// should never happen but just in case
+			return "", fmt.Errorf(
+				"error %d: failed to unescape URL, more info => %v\nurl: %s",
+				utils.UNEXPECTED_ERROR,
+				err,
+				res.Request.URL.String(),
+			)
+		}
+		filename = utils.GetLastPartOfUrl(unescapedUrl)
+	}
+	filename = fixFilenameExt(filename, mimeType)
 	filenameWithoutExt := utils.RemoveExtFromFilename(filename)
 	filePath = filepath.Join(
 		filePath,
@@ -49,8 +70,8 @@ func getFullFilePath(res *http.Response, filePath string) (string, error) {
 
 // check if the file size matches the content length
 // if not, then the file does not exist or is corrupted and should be re-downloaded
-func checkIfCanSkipDl(contentLength int64, filePath string, forceOverwrite bool) bool {
-	fileSize, err := utils.GetFileSize(filePath)
+func checkIfCanSkipDl(ctx context.Context, contentLength int64, filePath string, forceOverwrite bool) bool {
+	fileSize, err := storage.GetBackend().Size(ctx, filePath)
 	if err != nil {
 		if err != os.ErrNotExist {
 			// if the error wasn't because the file does not exist,
@@ -75,51 +96,158 @@ func checkIfCanSkipDl(contentLength int64, filePath string, forceOverwrite bool)
 	return false
 }
 
-func DlToFile(res *http.Response, url, filePath string) error {
-	file, err := os.Create(filePath) // create the file
+// Emits the download progress of the file to the registered event handlers
+type progressReader struct {
+	reader       io.Reader
+	file         *events.File
+	downloaded   int64
+	total        int64
+	lastReported int64
+}
+
+// Stops reading the response body while paused with the pause file
+// to free up the bandwidth until the downloads are resumed
+type pausableReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (p *pausableReader) Read(b []byte) (int, error) {
+	if err := utils.WaitIfPaused(p.ctx); err != nil {
+		return 0, err
+	}
+	return p.reader.Read(b)
+}
+
+// report the progress every 512KB to avoid flooding the event handlers
+const progressReportInterval = 512 * 1024
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.downloaded += int64(n)
+	if p.downloaded-p.lastReported >= progressReportInterval || (err == io.EOF && p.downloaded != p.lastReported) {
+		p.lastReported = p.downloaded
+		events.FileProgress(p.file, p.downloaded, p.total)
+	}
+	return n, err
+}
+
+// Size of the buffers used to copy the response body to the file which is larger than
+// io.Copy's default of 32KB to reduce the number of write calls on fast connections
+const dlBufferSize = 1024 * 1024
+
+// Pool of the buffers for DlToFile to avoid allocating a new buffer for each download
+var dlBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, dlBufferSize)
+		return &buf
+	},
+}
+
+// Writes the response body to the file at dlFile.FilePath where ctx is the context of the request
+// which is used to continue the download with a new request if it stalls
+//
+// The download progress will be emitted to the registered event handlers, if any,
+// but the caller is responsible for the FileStart and FileDone events of dlFile.
+//
+// The file is written to the storage backend which is the local disk unless configured otherwise.
+func DlToFile(ctx context.Context, res *http.Response, dlFile *events.File) error {
+	file, err := storage.GetBackend().Create(ctx, dlFile.FilePath, res.ContentLength) // create the file
 	if err != nil {
 		return fmt.Errorf(
 			"error %d: failed to create file, more info => %v\nfile path: %s",
 			utils.OS_ERROR,
 			err,
-			filePath,
+			dlFile.FilePath,
 		)
 	}
 
 	// write the body to file
 	// https://stackoverflow.com/a/11693049/16377492
-	_, err = io.Copy(file, res.Body)
-	if err != nil {
-		file.Close()
-		if fileErr := os.Remove(filePath); fileErr != nil {
-			utils.LogError(
-				fmt.Errorf(
-					"download error %d: failed to remove file at %s, more info => %v",
-					utils.OS_ERROR,
-					filePath,
-					fileErr,
-				),
-				"",
-				false,
-				utils.ERROR,
-			)
+	stallBody := newStallResumingBody(ctx, res)
+	defer stallBody.Close()
+	var body io.Reader = &pausableReader{
+		ctx:    ctx,
+		reader: &bandwidthLimitedReader{ctx: ctx, reader: stallBody},
+	}
+	if events.HasHandlers() {
+		body = &progressReader{
+			reader: body,
+			file:   dlFile,
+			total:  res.ContentLength,
 		}
+	}
+	buf := dlBufferPool.Get().(*[]byte)
+	// the file is wrapped to hide its ReadFrom method which would make io.CopyBuffer ignore the buffer
+	written, err := io.CopyBuffer(struct{ io.Writer }{file}, body, *buf)
+	dlBufferPool.Put(buf)
+	if err != nil {
+		file.Abort()
 
-		if err != context.Canceled {
-			errorMsg := fmt.Sprintf("failed to download %s due to %v", url, err)
-			utils.LogError(err, errorMsg, false, utils.ERROR)
-			err = nil
+		// the stalled downloads that could not be continued are returned to be restarted by DownloadUrl
+		if err != context.Canceled && !isStalled(err) {
+			err = fmt.Errorf(
+				"error %d: failed to download %s due to %v",
+				utils.DOWNLOAD_ERROR,
+				dlFile.Url,
+				err,
+			)
 		}
 		return err
 	}
-	file.Close()
+	if err := file.Close(); err != nil {
+		return err
+	}
+	addToQuota(written)
+	dlFile.Size = written
 	return nil
 }
 
-// DownloadUrl is used to download a file from a URL
+// DownloadUrl is used to download a file from a URL to dlFile.FilePath which can be
+// the path of the file or the folder to download it to, where it will be updated to the full file path
 //
-// Note: If the file already exists, the download process will be skipped
-func DownloadUrl(filePath string, queue chan struct{}, reqArgs *RequestArgs, overwriteExistingFile bool) error {
+// Empty files, stalled downloads, and corrupted images if verifyImages is true, will be deleted
+// and re-downloaded up to the defined max retries in the constants.go in utils package.
+//
+// Note: If the file already exists, the download process will be skipped and dlFile.Skipped will be set
+func DownloadUrl(dlFile *events.File, reqArgs *RequestArgs, overwriteExistingFile, verifyImages bool) error {
+	folderOrFilePath := dlFile.FilePath
+	var err error
+	for i := 1; i <= utils.RETRY_COUNTER; i++ {
+		dlFile.FilePath = folderOrFilePath
+		err = downloadUrl(dlFile, reqArgs, overwriteExistingFile)
+		if isStalled(err) {
+			if i < utils.RETRY_COUNTER {
+				time.Sleep(utils.GetRandomDelay())
+			}
+			continue
+		}
+		if err != nil || dlFile.Skipped {
+			return err
+		}
+
+		if err = verifyDownloadedFile(dlFile.FilePath, verifyImages); err == nil {
+			return nil
+		}
+		if fileErr := os.Remove(dlFile.FilePath); fileErr != nil {
+			return fileErr
+		}
+
+		if i < utils.RETRY_COUNTER {
+			time.Sleep(utils.GetRandomDelay())
+		}
+	}
+	return fmt.Errorf(
+		"error %d: failed to download a valid file from %s after %d retries, more info => %v",
+		utils.DOWNLOAD_ERROR,
+		reqArgs.Url,
+		utils.RETRY_COUNTER,
+		err,
+	)
+}
+
+// Downloads the file from the URL and updates dlFile.FilePath to the full file path of the file
+func downloadUrl(dlFile *events.File, reqArgs *RequestArgs, overwriteExistingFile bool) error {
 	// Create a context that can be cancelled when SIGINT/SIGTERM signal is received
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -133,7 +261,6 @@ func DownloadUrl(filePath string, queue chan struct{}, reqArgs *RequestArgs, ove
 	}()
 	defer signal.Stop(sigs)
 
-	queue <- struct{}{}
 	// Send a HEAD request first to get the expected file size from the Content-Length header.
 	// A GET request might work but most of the time
 	// as the Content-Length header may not present due to chunked encoding.
@@ -160,7 +287,7 @@ func DownloadUrl(filePath string, queue chan struct{}, reqArgs *RequestArgs, ove
 	reqArgs.Context = ctx
 	res, err := reqArgs.RequestHandler(reqArgs)
 	if err != nil {
-		if err != context.Canceled {
+		if err != context.Canceled && !errors.Is(err, errCircuitOpen) {
 			err = fmt.Errorf(
 				"error %d: failed to download file, more info => %v\nurl: %s",
 				utils.DOWNLOAD_ERROR,
@@ -172,64 +299,118 @@ func DownloadUrl(filePath string, queue chan struct{}, reqArgs *RequestArgs, ove
 	}
 	defer res.Body.Close()
 
-	filePath, err = getFullFilePath(res, filePath)
+	filePath, err := getFullFilePath(res, dlFile.FilePath)
 	if err != nil {
 		return err
 	}
+	dlFile.FilePath = filePath
 
-	if !checkIfCanSkipDl(fileReqContentLength, filePath, overwriteExistingFile) {
-		err = DlToFile(res, reqArgs.Url, filePath)
+	if checkIfCanSkipDl(ctx, fileReqContentLength, filePath, overwriteExistingFile) {
+		dlFile.Skipped = true
+		return nil
 	}
-	return err
+
+	release, err := reserveDiskSpace(ctx, filePath, fileReqContentLength)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return DlToFile(ctx, res, dlFile)
+}
+
+// DownloadConcurrently is used to download multiple files concurrently
+// using a queue that limits the number of downloads at any given time to dlInfo.MaxConcurrency.
+//
+// The FileStart and FileDone events are emitted for each file and the progress of the downloads
+// will be shown with a spinner that counts them alongside the name of the last downloaded file, if any.
+func DownloadConcurrently(dlInfo *ConcurrentDl) {
+	errHandler := dlInfo.ErrHandler
+	if errHandler == nil {
+		errHandler = func(errs []error, progress *spinner.Spinner) {
+			if kill := utils.LogErrors(false, nil, utils.ERROR, errs...); kill {
+				progress.KillProgram(
+					i18n.Sprintf(
+						"Stopped downloading %s (incomplete downloads will be deleted)...",
+						dlInfo.FileDesc,
+					),
+				)
+			}
+		}
+	}
+
+	// the files of the downloads to count with the spinner
+	var dlFiles sync.Map
+	pipeline.Run(&pipeline.Options[struct{}]{
+		Count:          dlInfo.Count,
+		MaxConcurrency: dlInfo.MaxConcurrency,
+		Progress: &pipeline.Progress{
+			SpinnerType: spinner.DL_SPINNER,
+			Msg:         i18n.Sprintf("Downloading %s", dlInfo.FileDesc),
+			SuccessMsg: i18n.Sprintf(
+				"Finished downloading %d %s!",
+				dlInfo.Count,
+				dlInfo.FileDesc,
+			),
+			ErrMsg: i18n.Sprintf(
+				"Something went wrong while downloading %d %s!\nPlease refer to the generated log files for more details.",
+				dlInfo.Count,
+				dlInfo.FileDesc,
+			),
+			ShowInfo: true,
+			IsOwnFile: func(file *events.File) bool {
+				_, ok := dlFiles.Load(file)
+				return ok
+			},
+		},
+		Task: func(idx int) (struct{}, string, error) {
+			dlFile := dlInfo.File(idx)
+			dlFiles.Store(dlFile, struct{}{})
+			defer dlFiles.Delete(dlFile)
+
+			events.FileStart(dlFile)
+			err := dlInfo.DlFunc(idx, dlFile)
+			events.FileDone(dlFile, err)
+			return struct{}{}, "", err
+		},
+		Lane:       dlInfo.Lane,
+		ErrHandler: errHandler,
+	})
 }
 
 // DownloadUrls is used to download multiple files from URLs concurrently
 //
 // Note: If the file already exists, the download process will be skipped
 func DownloadUrlsWithHandler(urlInfoSlice []*ToDownload, dlOptions *DlOptions, config *configs.Config, reqHandler RequestHandler) {
-	urlsLen := len(urlInfoSlice)
-	if urlsLen == 0 {
-		return
-	}
-	if urlsLen < dlOptions.MaxConcurrency {
-		dlOptions.MaxConcurrency = urlsLen
+	downloadUrls(urlInfoSlice, dlOptions, config, reqHandler, false)
+}
+
+func downloadUrls(urlInfoSlice []*ToDownload, dlOptions *DlOptions, config *configs.Config, reqHandler RequestHandler, checkQuota bool) {
+	filePaths := make([]string, len(urlInfoSlice))
+	for idx, urlInfo := range urlInfoSlice {
+		filePaths[idx] = urlInfo.FilePath
 	}
+	MarkPostsInProgress(filePaths)
+
+	DownloadConcurrently(&ConcurrentDl{
+		Count:          len(urlInfoSlice),
+		MaxConcurrency: dlOptions.MaxConcurrency,
+		FileDesc:       "files",
+		File: func(idx int) *events.File {
+			return &events.File{Url: urlInfoSlice[idx].Url, FilePath: urlInfoSlice[idx].FilePath}
+		},
+		DlFunc: func(idx int, dlFile *events.File) error {
+			urlInfo := urlInfoSlice[idx]
+			if checkQuota && QuotaReached(config) {
+				addToRemainingQueue(urlInfo)
+				MarkPostIncomplete(urlInfo.FilePath)
+				dlFile.Skipped = true
+				return nil
+			}
 
-	var wg sync.WaitGroup
-	queue := make(chan struct{}, dlOptions.MaxConcurrency)
-	errChan := make(chan error, urlsLen)
-
-	baseMsg := "Downloading files [%d/" + fmt.Sprintf("%d]...", urlsLen)
-	progress := spinner.New(
-		spinner.DL_SPINNER,
-		"fgHiYellow",
-		fmt.Sprintf(
-			baseMsg,
-			0,
-		),
-		fmt.Sprintf(
-			"Finished downloading %d files",
-			urlsLen,
-		),
-		fmt.Sprintf(
-			"Something went wrong while downloading %d files.\nPlease refer to the logs for more details.",
-			urlsLen,
-		),
-		urlsLen,
-	)
-	progress.Start()
-	for _, urlInfo := range urlInfoSlice {
-		wg.Add(1)
-		go func(fileUrl, filePath string) {
-			defer func() {
-				wg.Done()
-				<-queue
-			}()
 			err := DownloadUrl(
-				filePath,
-				queue,
+				dlFile,
 				&RequestArgs{
-					Url:            fileUrl,
+					Url:            urlInfo.Url,
 					Method:         "GET",
 					Timeout:        utils.DOWNLOAD_TIMEOUT,
 					Cookies:        dlOptions.Cookies,
@@ -240,33 +421,99 @@ func DownloadUrlsWithHandler(urlInfoSlice []*ToDownload, dlOptions *DlOptions, c
 					RequestHandler: reqHandler,
 				},
 				config.OverwriteFiles,
+				config.VerifyImages,
 			)
 			if err != nil {
-				errChan <- err
+				MarkPostIncomplete(urlInfo.FilePath)
 			}
-
-			if err != context.Canceled {
-				progress.MsgIncrement(baseMsg)
+			if checkQuota && errors.Is(err, errLowDiskSpace) {
+				addToRemainingQueue(urlInfo)
+				dlFile.Skipped = true
+				return nil
 			}
-		}(urlInfo.Url, urlInfo.FilePath)
+			if errors.Is(err, errCircuitOpen) {
+				// reported once per host by ReportCircuitBreakers instead
+				if checkQuota {
+					addToRemainingQueue(urlInfo)
+				}
+				dlFile.Skipped = true
+				return nil
+			}
+			return err
+		},
+		Lane: func(idx int) int {
+			return getFileLane(urlInfoSlice[idx].getFilename())
+		},
+	})
+	MarkPostsComplete(filePaths)
+	if config.VerifyImages {
+		SaveVerifiedImages()
 	}
-	wg.Wait()
-	close(queue)
-	close(errChan)
-
-	hasErr := false
-	if len(errChan) > 0 {
-		hasErr = true
-		if kill := utils.LogErrors(false, errChan, utils.ERROR); kill {
-			progress.KillProgram(
-				"Stopped downloading files (incomplete downloads will be deleted)...",
-			)
+}
+
+// Returns the files to download that match the file type in the Only field
+// of the config and marks the posts of the other files as incomplete
+func filterUrlsByFileType(urlInfoSlice []*ToDownload, config *configs.Config) []*ToDownload {
+	if config.Only == "" {
+		return urlInfoSlice
+	}
+
+	filtered := make([]*ToDownload, 0, len(urlInfoSlice))
+	for _, urlInfo := range urlInfoSlice {
+		if config.ShouldDlFile(urlInfo.getFilename()) {
+			filtered = append(filtered, urlInfo)
+		} else {
+			// the post is only complete once the other types of files have been downloaded too
+			MarkPostIncomplete(urlInfo.FilePath)
 		}
 	}
-	progress.Stop(hasErr)
+	return filtered
+}
+
+// Returns the files to download that are in the posts selected by the user if the PostSelector of the config is set
+func filterUrlsBySelectedPosts(urlInfoSlice []*ToDownload, config *configs.Config) []*ToDownload {
+	if config.PostSelector == nil {
+		return urlInfoSlice
+	}
+
+	filtered := make([]*ToDownload, 0, len(urlInfoSlice))
+	for _, urlInfo := range urlInfoSlice {
+		if config.ShouldDlPost(urlInfo.FilePath) {
+			filtered = append(filtered, urlInfo)
+		}
+	}
+	return filtered
 }
 
 // Same as DownloadUrlsWithHandler but uses the default request handler (CallRequest)
+//
+// If config.ExtractArchives is true, the archives in the post folders will be extracted afterwards.
+// If config.ChecksumManifest is true, a SHA256SUMS manifest will be written in the post folders afterwards.
+//
+// If the per-run quota in the config has been reached, the remaining files will be
+// persisted to config.QueueFilePath and be downloaded first in the next run.
 func DownloadUrls(urlInfoSlice []*ToDownload, dlOptions *DlOptions, config *configs.Config) {
-	DownloadUrlsWithHandler(urlInfoSlice, dlOptions, config, CallRequest)
+	urlInfoSlice = loadRemainingQueue(urlInfoSlice, config.QueueFilePath)
+	urlInfoSlice = filterUrlsByFileType(urlInfoSlice, config)
+	urlInfoSlice = filterUrlsBySelectedPosts(urlInfoSlice, config)
+	if len(urlInfoSlice) > 0 {
+		checkDiskSpace(context.Background())
+	}
+	downloadUrls(urlInfoSlice, dlOptions, config, CallRequest, true)
+	saveRemainingQueue(config.QueueFilePath)
+	if !config.ChecksumManifest && !config.ExtractArchives {
+		return
+	}
+
+	filePaths := make([]string, len(urlInfoSlice))
+	for idx, urlInfo := range urlInfoSlice {
+		filePaths[idx] = urlInfo.FilePath
+	}
+	if config.ExtractArchives {
+		// before the checksum manifests so that the extracted files are included
+		ExtractPostArchives(filePaths)
+	}
+	if config.ChecksumManifest {
+		utils.WriteChecksumManifests(filePaths)
+	}
 }
@@ -0,0 +1,166 @@
+package request
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/storage"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const DOWNLOAD_LOG_FILENAME = "downloaded.jsonl"
+
+type DownloadLogEntry struct {
+	Url      string `json:"url"`
+	FilePath string `json:"file_path"`
+	PostId   string `json:"post_id,omitempty"`
+	Time     string `json:"time"`
+
+	// Size and Sha256 are used by the verify command to find the truncated or corrupted files,
+	// where Sha256 is only set when the files are written to the local disk
+	Size   int64  `json:"size,omitempty"`
+	Sha256 string `json:"sha256,omitempty"`
+}
+
+// DownloadLogHandler appends each downloaded file with its source URL, post ID, size, SHA-256 hash,
+// and download time as a JSON line to the download log for building external indexes.
+type DownloadLogHandler struct {
+	events.BaseHandler
+
+	mu      sync.Mutex
+	logPath string
+}
+
+// Returns a new DownloadLogHandler that appends to the download log at the given path
+func NewDownloadLogHandler(logPath string) *DownloadLogHandler {
+	return &DownloadLogHandler{logPath: logPath}
+}
+
+func (d *DownloadLogHandler) OnFileDone(file *events.File, err error) {
+	if err != nil || file.Skipped {
+		return
+	}
+
+	logEntry := &DownloadLogEntry{
+		Url:      file.Url,
+		FilePath: file.FilePath,
+		PostId:   utils.GetPostIdFromPath(file.FilePath),
+		Time:     time.Now().UTC().Format(time.RFC3339),
+		Size:     file.Size,
+	}
+	if storage.IsLocal() {
+		logEntry.Sha256, _ = utils.GetFileSha256(file.FilePath)
+	}
+	entry, err := json.Marshal(logEntry)
+	if err != nil {
+		// should never happen but just in case
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.appendLine(entry); err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"error %d: failed to write to the download log at %s, more info => %v",
+				utils.OS_ERROR,
+				d.logPath,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+	}
+}
+
+func (d *DownloadLogHandler) appendLine(line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(d.logPath), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(d.logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Returns the latest entry of each file in the download log at the given path keyed by the file path
+func ReadDownloadLog(logPath string) (map[string]*DownloadLogEntry, error) {
+	f, err := os.Open(logPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make(map[string]*DownloadLogEntry)
+	reader := bufio.NewReader(f)
+	for {
+		line, err := utils.ReadLine(reader)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf(
+				"error %d: failed to read the download log at %s, more info => %v",
+				utils.OS_ERROR,
+				logPath,
+				err,
+			)
+		}
+
+		var entry DownloadLogEntry
+		if err := json.Unmarshal(line, &entry); err != nil || entry.FilePath == "" {
+			continue
+		}
+		entries[filepath.Clean(entry.FilePath)] = &entry
+	}
+}
+
+// Verifies the files in the download log against their recorded size and SHA-256 hash
+// and returns the results of the files that did not pass the verification alongside the number of files checked.
+//
+// The entries without a hash are skipped as they were either written before the hashes were recorded
+// or the files were written to another storage backend.
+func VerifyDownloadLog(entries map[string]*DownloadLogEntry) ([]*utils.ChecksumResult, int) {
+	var results []*utils.ChecksumResult
+	checked := 0
+	for filePath, entry := range entries {
+		if entry.Sha256 == "" {
+			continue
+		}
+
+		checked++
+		size, err := utils.GetFileSize(filePath)
+		if err != nil {
+			results = append(results, &utils.ChecksumResult{FilePath: filePath, Url: entry.Url, Status: utils.CHECKSUM_MISSING})
+			continue
+		}
+		if entry.Size > 0 && size < entry.Size {
+			results = append(results, &utils.ChecksumResult{FilePath: filePath, Url: entry.Url, Status: utils.CHECKSUM_TRUNCATED})
+			continue
+		}
+
+		hash, err := utils.GetFileSha256(filePath)
+		if err != nil || !strings.EqualFold(hash, entry.Sha256) {
+			results = append(results, &utils.ChecksumResult{FilePath: filePath, Url: entry.Url, Status: utils.CHECKSUM_MISMATCH, Err: err})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].FilePath < results[j].FilePath
+	})
+	return results, checked
+}
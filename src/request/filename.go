@@ -0,0 +1,89 @@
+package request
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Number of bytes that http.DetectContentType considers when sniffing the MIME type
+const sniffLen = 512
+
+// Used to replace the response body after peeking at it for sniffing the MIME type
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// Returns the filename from the Content-Disposition header of the response, if any
+func getContentDispositionFilename(res *http.Response) string {
+	contentDisposition := res.Header.Get("Content-Disposition")
+	if contentDisposition == "" {
+		return ""
+	}
+
+	// mime.ParseMediaType also decodes the RFC 5987 "filename*" parameter
+	_, params, err := mime.ParseMediaType(contentDisposition)
+	if err != nil {
+		return ""
+	}
+
+	// only keep the base name to avoid writing outside of the post folder
+	filename := filepath.Base(strings.ReplaceAll(params["filename"], "\\", "/"))
+	if filename == "." || filename == "/" {
+		return ""
+	}
+	return utils.CleanPathName(filename)
+}
+
+// Returns the MIME type of the response body by sniffing its first 512 bytes.
+//
+// If the sniffed MIME type has no known file extension, the MIME type
+// from the Content-Type header of the response will be returned instead.
+func getContentMimeType(res *http.Response) string {
+	bufReader := bufio.NewReaderSize(res.Body, sniffLen)
+	head, _ := bufReader.Peek(sniffLen)
+	res.Body = &peekedBody{Reader: bufReader, Closer: res.Body}
+
+	sniffedType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if _, ok := utils.MIME_TYPE_EXTENSIONS[sniffedType]; ok {
+		return sniffedType
+	}
+
+	headerType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return headerType
+}
+
+// Returns the filename with its extension corrected based on the MIME type of the file's content.
+//
+// The extension is only changed if the filename has no extension or if its extension
+// is for a different type of file, e.g. a PNG image with a ".jpg" extension.
+func fixFilenameExt(filename, mimeType string) string {
+	mimeExts, ok := utils.MIME_TYPE_EXTENSIONS[mimeType]
+	if !ok {
+		return filename
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return filename + mimeExts[0]
+	}
+	if utils.SliceContains(mimeExts, ext) {
+		return filename
+	}
+
+	for _, otherExts := range utils.MIME_TYPE_EXTENSIONS {
+		if utils.SliceContains(otherExts, ext) {
+			// the extension is for another known type of file
+			return utils.RemoveExtFromFilename(filename) + mimeExts[0]
+		}
+	}
+
+	// unknown extensions are kept as the MIME type might just be too generic
+	return filename
+}
@@ -0,0 +1,73 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	extraHeadersMu sync.RWMutex
+
+	// headers from the "--header" flag that are added to every request
+	globalHeaders map[string]string
+
+	// headers from the config file that are added to the requests to each domain and its subdomains
+	hostHeaders map[string]map[string]string
+)
+
+// Parses the headers in the format of "Key: Value"
+func ParseHeaders(rawHeaders []string) (map[string]string, error) {
+	headers := make(map[string]string, len(rawHeaders))
+	for _, rawHeader := range rawHeaders {
+		key, value, ok := strings.Cut(rawHeader, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf(
+				"error %d: invalid header %q, expected the format of \"Key: Value\"",
+				utils.INPUT_ERROR,
+				rawHeader,
+			)
+		}
+		headers[key] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// Sets the headers to add to every request which take precedence over the headers from the config file
+func SetGlobalHeaders(headers map[string]string) {
+	extraHeadersMu.Lock()
+	defer extraHeadersMu.Unlock()
+	globalHeaders = headers
+}
+
+// Sets the headers from the config file to add to the requests to each domain and its subdomains
+func SetHostHeaders(headers map[string]map[string]string) {
+	lowered := make(map[string]map[string]string, len(headers))
+	for domain, domainHeaders := range headers {
+		lowered[strings.ToLower(domain)] = domainHeaders
+	}
+
+	extraHeadersMu.Lock()
+	defer extraHeadersMu.Unlock()
+	hostHeaders = lowered
+}
+
+// Adds the headers set by SetHostHeaders and SetGlobalHeaders to the request,
+// replacing any headers of the same name set by the program
+func AddExtraHeaders(req *http.Request) {
+	extraHeadersMu.RLock()
+	defer extraHeadersMu.RUnlock()
+
+	if domainHeaders, ok := matchHost(req.URL.Hostname(), hostHeaders); ok {
+		for key, value := range domainHeaders {
+			req.Header.Set(key, value)
+		}
+	}
+	for key, value := range globalHeaders {
+		req.Header.Set(key, value)
+	}
+}
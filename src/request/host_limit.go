@@ -0,0 +1,157 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Limits the rate and the number of parallel connections of the requests to a host
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // minimum time between the start of each request
+	nextAt   time.Time
+
+	// conns is used as a semaphore and is nil if the connections are not limited
+	conns chan struct{}
+}
+
+var (
+	hostLimitersMu sync.RWMutex
+	hostLimiters   map[string]*hostLimiter
+)
+
+// Sets the limits of the requests to each host from the config file.
+//
+// The limits of a host also apply to its subdomains,
+// e.g. the limits of "pximg.net" apply to "i.pximg.net".
+func SetHostLimits(limits map[string]*utils.HostLimitConfig) error {
+	limiters := make(map[string]*hostLimiter, len(limits))
+	for host, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		if err := limit.Validate(host); err != nil {
+			return err
+		}
+
+		limiter := &hostLimiter{}
+		if limit.RequestsPerSecond > 0 {
+			limiter.interval = time.Duration(float64(time.Second) / limit.RequestsPerSecond)
+		}
+		if limit.MaxConnections > 0 {
+			limiter.conns = make(chan struct{}, limit.MaxConnections)
+		}
+		limiters[strings.ToLower(host)] = limiter
+	}
+
+	hostLimitersMu.Lock()
+	defer hostLimitersMu.Unlock()
+	hostLimiters = limiters
+	return nil
+}
+
+// Returns the value of the longest domain in the map that matches the host or its parent domains
+func matchHost[T any](host string, domainMap map[string]T) (T, bool) {
+	var matched T
+	matchedLen := 0
+	host = strings.ToLower(host)
+	for domain, value := range domainMap {
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > matchedLen {
+			matched = value
+			matchedLen = len(domain)
+		}
+	}
+	return matched, matchedLen > 0
+}
+
+// Returns the limiter of the longest configured domain that matches the host, if any
+func getHostLimiter(host string) *hostLimiter {
+	hostLimitersMu.RLock()
+	defer hostLimitersMu.RUnlock()
+
+	limiter, _ := matchHost(host, hostLimiters)
+	return limiter
+}
+
+// Waits until a request can be sent to the host and returns a function to release its connection
+func (l *hostLimiter) acquire(req *http.Request) (func(), error) {
+	if l.conns != nil {
+		select {
+		case l.conns <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	release := func() {
+		if l.conns != nil {
+			<-l.conns
+		}
+	}
+
+	if l.interval > 0 {
+		l.mu.Lock()
+		now := time.Now()
+		waitFor := l.nextAt.Sub(now)
+		if waitFor < 0 {
+			waitFor = 0
+		}
+		l.nextAt = now.Add(waitFor + l.interval)
+		l.mu.Unlock()
+
+		if waitFor > 0 {
+			select {
+			case <-time.After(waitFor):
+			case <-req.Context().Done():
+				release()
+				return nil, req.Context().Err()
+			}
+		}
+	}
+	return release, nil
+}
+
+// Releases the connection of the host once the response body has been fully read or closed
+type hostLimitedBody struct {
+	io.ReadCloser
+	releaseOnce sync.Once
+	release     func()
+}
+
+func (b *hostLimitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.releaseOnce.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *hostLimitedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.releaseOnce.Do(b.release)
+	return err
+}
+
+// Sends the request with the client after waiting for the limits of the request's host in the config file
+func DoWithHostLimit(client *http.Client, req *http.Request) (*http.Response, error) {
+	limiter := getHostLimiter(req.URL.Hostname())
+	if limiter == nil {
+		return client.Do(req)
+	}
+
+	release, err := limiter.acquire(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &hostLimitedBody{ReadCloser: res.Body, release: release}
+	return res, nil
+}
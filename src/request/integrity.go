@@ -0,0 +1,144 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+
+	// register the image formats that can be verified with image.Decode
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// File extensions of the images that can be decoded to verify their integrity
+var verifiableImageExts = []string{".jpg", ".jpeg", ".png", ".gif"}
+
+// The size and modification time of an image when it was decoded without errors
+type verifiedImage struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"` // in nanoseconds since the Unix epoch
+}
+
+var (
+	verifiedImagesMu    sync.Mutex
+	verifiedImagesOnce  sync.Once
+	verifiedImagesDirty bool
+
+	// Maps the file path of the images that have been verified by this or a previous run
+	// so that the existing images are not fully decoded again on every run unless they have changed
+	verifiedImages map[string]*verifiedImage
+)
+
+func getVerifiedImagesFilePath() string {
+	return filepath.Join(utils.APP_PATH, "verified_images.json")
+}
+
+// Loads the images verified by the previous runs, if any, which must be called with verifiedImagesMu held
+func loadVerifiedImages() {
+	verifiedImagesOnce.Do(func() {
+		verifiedImages = make(map[string]*verifiedImage)
+		if data, err := os.ReadFile(getVerifiedImagesFilePath()); err == nil {
+			json.Unmarshal(data, &verifiedImages)
+		}
+	})
+}
+
+// Returns true if the image was verified before and its size and modification time have not changed since
+func isImageVerified(filePath string, fileInfo os.FileInfo) bool {
+	verifiedImagesMu.Lock()
+	defer verifiedImagesMu.Unlock()
+	loadVerifiedImages()
+
+	verified, ok := verifiedImages[filePath]
+	return ok && verified.Size == fileInfo.Size() && verified.ModTime == fileInfo.ModTime().UnixNano()
+}
+
+func setImageVerified(filePath string, fileInfo os.FileInfo) {
+	verifiedImagesMu.Lock()
+	defer verifiedImagesMu.Unlock()
+	loadVerifiedImages()
+
+	verifiedImages[filePath] = &verifiedImage{Size: fileInfo.Size(), ModTime: fileInfo.ModTime().UnixNano()}
+	verifiedImagesDirty = true
+}
+
+// Saves the images verified in this run so that they are not decoded again by the next runs
+func SaveVerifiedImages() {
+	verifiedImagesMu.Lock()
+	defer verifiedImagesMu.Unlock()
+	if !verifiedImagesDirty {
+		return
+	}
+
+	data, err := json.Marshal(verifiedImages)
+	if err != nil {
+		// should never happen but just in case
+		return
+	}
+	os.MkdirAll(utils.APP_PATH, 0666)
+	if err := os.WriteFile(getVerifiedImagesFilePath(), data, 0666); err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"error %d: failed to save the verified images to %s, more info => %v",
+				utils.OS_ERROR,
+				getVerifiedImagesFilePath(),
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+		return
+	}
+	verifiedImagesDirty = false
+}
+
+// Verifies the integrity of the downloaded file at the given file path
+//
+// Empty files are always treated as corrupted while images
+// are only decoded to detect truncation or corruption if verifyImages is true.
+// The images that were decoded before are only decoded again if their size or modification time has changed.
+//
+// Returns nil if the file does not exist as the download error would have already been logged.
+func verifyDownloadedFile(filePath string, verifyImages bool) error {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if fileInfo.Size() == 0 {
+		return fmt.Errorf("downloaded file %s is empty", filePath)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if !verifyImages || !utils.SliceContains(verifiableImageExts, ext) {
+		return nil
+	}
+	if isImageVerified(filePath, fileInfo) {
+		return nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// fully decode the image as image.DecodeConfig only reads the header
+	if _, _, err := image.Decode(file); err != nil {
+		return fmt.Errorf("downloaded image %s is corrupted, more info => %v", filePath, err)
+	}
+	setImageVerified(filePath, fileInfo)
+	return nil
+}
@@ -0,0 +1,33 @@
+package request
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+)
+
+// The lanes of the files to download where the files in the lower lanes are downloaded first
+const (
+	// SMALL_FILE_LANE is for the images, text, and JSON files, e.g. the thumbnails and metadata
+	SMALL_FILE_LANE = iota
+
+	// LARGE_FILE_LANE is for the videos, archives, and the other files
+	// whose size cannot be guessed, which can take a long time to download
+	LARGE_FILE_LANE
+)
+
+// Returns the lane of the file based on its extension so that the small files
+// are not queued behind the large files like multi-GB videos
+func getFileLane(filename string) int {
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		return SMALL_FILE_LANE
+	}
+
+	switch configs.GetFileType(filename) {
+	case configs.ONLY_IMAGES, configs.ONLY_TEXT:
+		return SMALL_FILE_LANE
+	default:
+		return LARGE_FILE_LANE
+	}
+}
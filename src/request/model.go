@@ -1,10 +1,25 @@
 package request
 
-import "net/http"
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
 
 type ToDownload struct {
-	Url      string
-	FilePath string
+	Url      string `json:"url"`
+	FilePath string `json:"file_path"`
+}
+
+// Returns the filename from the file path if it has a file extension, otherwise from the URL
+func (t *ToDownload) getFilename() string {
+	if filepath.Ext(t.FilePath) != "" {
+		return filepath.Base(t.FilePath)
+	}
+	return utils.GetLastPartOfUrl(t.Url)
 }
 
 type DlOptions struct {
@@ -21,3 +36,38 @@ type DlOptions struct {
 	// Otherwise, HTTP/2 will be used by default
 	UseHttp3 bool
 }
+
+// ConcurrentDl contains the information needed
+// to download multiple files concurrently using DownloadConcurrently
+type ConcurrentDl struct {
+	// Count is the number of files to download
+	Count int
+
+	// MaxConcurrency is the maximum number of concurrent downloads
+	MaxConcurrency int
+
+	// FileDesc is used in the spinner messages, e.g. "files" or "GDrive files"
+	FileDesc string
+
+	// File returns the details of the file at the given index for the FileStart event
+	// which is passed to DlFunc to be updated while the file is downloading.
+	File func(idx int) *events.File
+
+	// DlFunc downloads the file at the given index and returns the error if any.
+	// dlFile.Skipped should be set if the file was not downloaded, e.g. as it already exists.
+	//
+	// A download slot in the queue would have already been acquired before DlFunc is called.
+	DlFunc func(idx int, dlFile *events.File) error
+
+	// Lane returns the lane of the file at the given index, e.g. SMALL_FILE_LANE,
+	// where the files in the lower lanes are downloaded first.
+	//
+	// If nil, the files are downloaded in the order of their index.
+	Lane func(idx int) int
+
+	// ErrHandler is called with the errors from DlFunc after all downloads have finished.
+	// The spinner is passed in to allow the handler to call KillProgram if needed.
+	//
+	// If nil, the errors will be logged using utils.LogErrors.
+	ErrHandler func(errs []error, progress *spinner.Spinner)
+}
@@ -0,0 +1,103 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
+	"github.com/KJHJason/Cultured-Downloader-CLI/pipeline"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+// Extracts the archives in each post folder that the given file paths are in concurrently
+// using a queue that limits the number of extractions to utils.MAX_CONCURRENT_EXTRACTIONS
+// with the passwords saved in the post folder's EXTRACTED_PASSWORDS_FILENAME file.
+//
+// If none of the passwords work, the user will be prompted for the password of each of
+// these archives one at a time after the other archives have been extracted if stdin is a terminal
+// and the password that works will be saved to the post folder's EXTRACTED_PASSWORDS_FILENAME file.
+func ExtractPostArchives(filePaths []string) {
+	archives, errSlice := utils.GetPostArchives(filePaths)
+	if len(archives) == 0 {
+		if len(errSlice) > 0 {
+			utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+		}
+		return
+	}
+
+	// Create a context that can be cancelled when SIGINT/SIGTERM signal is received
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Catch SIGINT/SIGTERM signal and cancel the context when received
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+	defer signal.Stop(sigs)
+
+	var mu sync.Mutex
+	failed := make(map[int]error)
+	results, _ := pipeline.Run(&pipeline.Options[struct{}]{
+		Count:          len(archives),
+		MaxConcurrency: utils.MAX_CONCURRENT_EXTRACTIONS,
+		Progress: &pipeline.Progress{
+			SpinnerType: spinner.DL_SPINNER,
+			Msg:         i18n.T("Extracting post archives"),
+			SuccessMsg:  i18n.Sprintf("Finished extracting %d post archive(s)!", len(archives)),
+			ErrMsg: i18n.Sprintf(
+				"Failed to extract some of the %d post archive(s) with the detected passwords!",
+				len(archives),
+			),
+			ShowInfo: true,
+		},
+		Task: func(idx int) (struct{}, string, error) {
+			err := archives[idx].Extract(ctx)
+			if err != nil && err != context.Canceled {
+				mu.Lock()
+				failed[idx] = err
+				mu.Unlock()
+			}
+			return struct{}{}, filepath.Base(archives[idx].FilePath), err
+		},
+		ErrHandler: func(errs []error, progress *spinner.Spinner) {
+			// the errors are logged after the user has been prompted for the passwords
+		},
+	})
+
+	var reader *bufio.Reader
+	if utils.CanPromptPassword() {
+		reader = bufio.NewReader(os.Stdin)
+	}
+	extracted := len(results)
+	for idx, archive := range archives {
+		err, ok := failed[idx]
+		if !ok {
+			continue
+		}
+		if reader != nil && ctx.Err() == nil {
+			err = archive.PromptPassword(ctx, reader, err)
+		}
+		if err == nil {
+			extracted++
+		} else if err != context.Canceled {
+			errSlice = append(errSlice, err)
+		}
+	}
+
+	if len(errSlice) > 0 {
+		utils.LogErrors(false, nil, utils.ERROR, errSlice...)
+	}
+	if extracted > 0 {
+		color.Green("Extracted %d post archive(s)", extracted)
+	}
+}
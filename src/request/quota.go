@@ -0,0 +1,243 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+var (
+	quotaMu    sync.Mutex
+	totalBytes int64
+	totalFiles int
+
+	// files that were not downloaded due to the quota
+	// which will be persisted to the queue file for the next run
+	remainingQueue []*ToDownload
+	loadQueueOnce  sync.Once
+	queueLoaded    bool
+)
+
+// Returns the path to the file used for persisting
+// the remaining download queue of the website for the next run
+func GetQueueFilePath(website string) string {
+	return filepath.Join(utils.APP_PATH, "queue", website+"_queue.json")
+}
+
+// Adds the downloaded file to the total bytes and files downloaded in this run
+func addToQuota(size int64) {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	totalBytes += size
+	totalFiles++
+}
+
+// Returns true if the total bytes or files downloaded in this run has reached
+// the per-run quota set in the config, if any, or if there is not enough free disk space
+func QuotaReached(config *configs.Config) bool {
+	if isLowDiskSpace() {
+		return true
+	}
+
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	if config.MaxTotalSize > 0 && totalBytes >= config.MaxTotalSize {
+		return true
+	}
+	return config.MaxTotalFiles > 0 && totalFiles >= config.MaxTotalFiles
+}
+
+// Returns the number of files in the remaining queue that will be downloaded in the next run
+func RemainingQueueLen() int {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	return len(remainingQueue)
+}
+
+// Adds the file to the remaining queue to be persisted for the next run
+func addToRemainingQueue(urlInfo *ToDownload) {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	remainingQueue = append(remainingQueue, urlInfo)
+}
+
+// Reads the remaining queue from the previous run, if any.
+//
+// The queued files will be placed in front of the given slice with any duplicates removed.
+func loadRemainingQueue(urlInfoSlice []*ToDownload, queueFilePath string) []*ToDownload {
+	if queueFilePath == "" {
+		return urlInfoSlice
+	}
+
+	loadQueueOnce.Do(func() {
+		if !utils.PathExists(queueFilePath) {
+			return
+		}
+
+		queueFile, err := os.ReadFile(queueFilePath)
+		if err != nil {
+			utils.LogError(
+				fmt.Errorf(
+					"error %d: failed to read queue file at %s, more info => %v",
+					utils.OS_ERROR,
+					queueFilePath,
+					err,
+				),
+				"",
+				false,
+				utils.ERROR,
+			)
+			return
+		}
+
+		var queued []*ToDownload
+		if err := json.Unmarshal(queueFile, &queued); err != nil {
+			utils.LogError(
+				fmt.Errorf(
+					"error %d: failed to unmarshal queue file at %s, more info => %v",
+					utils.JSON_ERROR,
+					queueFilePath,
+					err,
+				),
+				"",
+				false,
+				utils.ERROR,
+			)
+			return
+		}
+
+		queueLoaded = true
+		if len(queued) > 0 {
+			color.Yellow("Resuming %d file(s) left from the previous run...", len(queued))
+		}
+		urlInfoSlice = append(queued, urlInfoSlice...)
+	})
+
+	var result []*ToDownload
+	seen := make(map[ToDownload]struct{})
+	for _, urlInfo := range urlInfoSlice {
+		if _, ok := seen[*urlInfo]; !ok {
+			seen[*urlInfo] = struct{}{}
+			result = append(result, urlInfo)
+		}
+	}
+	return result
+}
+
+// Writes the remaining queue to the queue file so that the files can be downloaded in the next run
+//
+// If there are no remaining files, the queue file from the previous run will be removed, if any.
+func saveRemainingQueue(queueFilePath string) {
+	if queueFilePath == "" {
+		return
+	}
+
+	// the queue is copied so that the lock is not held while writing the file or logging the errors,
+	// as the error events can be handled by the metrics handler which reads the queue length
+	quotaMu.Lock()
+	queued := make([]*ToDownload, len(remainingQueue))
+	copy(queued, remainingQueue)
+	quotaMu.Unlock()
+
+	if len(queued) == 0 {
+		if queueLoaded {
+			os.Remove(queueFilePath)
+		}
+		return
+	}
+
+	queueFile, err := json.MarshalIndent(queued, "", "    ")
+	if err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"error %d: failed to marshal the remaining queue, more info => %v",
+				utils.JSON_ERROR,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+		return
+	}
+
+	os.MkdirAll(filepath.Dir(queueFilePath), 0666)
+	if err := os.WriteFile(queueFilePath, queueFile, 0666); err != nil {
+		utils.LogError(
+			fmt.Errorf(
+				"error %d: failed to write queue file at %s, more info => %v",
+				utils.OS_ERROR,
+				queueFilePath,
+				err,
+			),
+			"",
+			false,
+			utils.ERROR,
+		)
+		return
+	}
+	reason := "Download quota reached"
+	if isLowDiskSpace() {
+		reason = "Not enough free disk space"
+	}
+	color.Yellow(
+		"%s, %d file(s) have been saved to %s and will be downloaded in the next run.",
+		reason,
+		len(queued),
+		queueFilePath,
+	)
+}
+
+// Adds the files to the queue file of the website to be downloaded first by the next run of its download command,
+// e.g. the missing or corrupted files found by the verify command.
+func RequeueFiles(website string, toDownload []*ToDownload) error {
+	queueFilePath := GetQueueFilePath(website)
+	var queued []*ToDownload
+	if queueFile, err := os.ReadFile(queueFilePath); err == nil {
+		if err := json.Unmarshal(queueFile, &queued); err != nil {
+			return fmt.Errorf(
+				"error %d: failed to unmarshal queue file at %s, more info => %v",
+				utils.JSON_ERROR,
+				queueFilePath,
+				err,
+			)
+		}
+	}
+
+	seen := make(map[ToDownload]struct{}, len(queued))
+	for _, urlInfo := range queued {
+		seen[*urlInfo] = struct{}{}
+	}
+	for _, urlInfo := range toDownload {
+		if _, ok := seen[*urlInfo]; !ok {
+			seen[*urlInfo] = struct{}{}
+			queued = append(queued, urlInfo)
+		}
+	}
+
+	queueFile, err := json.MarshalIndent(queued, "", "    ")
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to marshal the queue of %s, more info => %v",
+			utils.JSON_ERROR,
+			website,
+			err,
+		)
+	}
+	os.MkdirAll(filepath.Dir(queueFilePath), 0666)
+	if err := os.WriteFile(queueFilePath, queueFile, 0666); err != nil {
+		return fmt.Errorf(
+			"error %d: failed to write queue file at %s, more info => %v",
+			utils.OS_ERROR,
+			queueFilePath,
+			err,
+		)
+	}
+	return nil
+}
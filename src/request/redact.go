@@ -0,0 +1,70 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const redactedValue = "<redacted>"
+
+var (
+	// Headers that contain credentials which will be redacted in the trace and WARC files
+	redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+	// Query parameters and body fields that contain credentials, e.g. the GDrive API key
+	// in the "key" query parameter and the tokens in the Pixiv OAuth responses
+	redactedParams = []string{
+		"key",
+		"api_key",
+		"token",
+		"access_token",
+		"refresh_token",
+		"id_token",
+		"client_secret",
+		"password",
+	}
+
+	// matches the credentials in JSON bodies, e.g. "access_token": "...",
+	// including the value cut off at the end of a truncated body
+	secretJsonFieldRegex = regexp.MustCompile(
+		`(?i)("(?:` + strings.Join(redactedParams, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*(?:"|\\?$)`,
+	)
+	// matches the credentials in form encoded bodies and URLs, e.g. refresh_token=...
+	secretFormFieldRegex = regexp.MustCompile(
+		`(?i)(^|[?&\s"'])((?:` + strings.Join(redactedParams, "|") + `)=)[^&\s"'<]+`,
+	)
+)
+
+// Returns true if the header contains credentials
+func isRedactedHeader(key string) bool {
+	return utils.SliceContains(redactedHeaders, http.CanonicalHeaderKey(key))
+}
+
+// Returns a copy of the URL with the values of the query parameters that contain credentials redacted
+func redactUrl(reqUrl *url.URL) *url.URL {
+	redactedUrl := *reqUrl
+	query := reqUrl.Query()
+	redacted := false
+	for key := range query {
+		if utils.SliceContains(redactedParams, strings.ToLower(key)) {
+			query.Set(key, redactedValue)
+			redacted = true
+		}
+	}
+	if redacted {
+		redactedUrl.RawQuery = query.Encode()
+	}
+	return &redactedUrl
+}
+
+// Returns the body with the values of the fields that contain credentials redacted
+// and true if any of the fields were redacted
+func redactBody(body []byte) ([]byte, bool) {
+	redacted := secretJsonFieldRegex.ReplaceAll(body, []byte(`$1"`+redactedValue+`"`))
+	redacted = secretFormFieldRegex.ReplaceAll(redacted, []byte("${1}${2}"+redactedValue))
+	return redacted, string(redacted) != string(body)
+}
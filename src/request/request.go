@@ -4,33 +4,49 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
-	"strings"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
 	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
-	"github.com/fatih/color"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
 )
 
 // Get a new HTTP/2 or HTTP/3 client based on the request arguments
 func GetHttpClient(reqArgs *RequestArgs) *http.Client {
 	if reqArgs.Http2 {
+		transport := &http.Transport{
+			DisableCompression:    reqArgs.DisableCompression,
+			DialContext:           (&net.Dialer{Timeout: connectTimeout}).DialContext,
+			TLSHandshakeTimeout:   connectTimeout,
+			ResponseHeaderTimeout: readTimeout,
+		}
+		if netOptions != nil {
+			transport.DialContext = netOptions.dialContext
+		}
 		return &http.Client{
-			Transport: &http.Transport{
-				DisableCompression: reqArgs.DisableCompression,
-			},
+			Transport: transport,
 		}
 	}
-	return &http.Client{
-		Transport: &http3.RoundTripper{
-			DisableCompression: reqArgs.DisableCompression,
+
+	roundTripper := &http3.RoundTripper{
+		DisableCompression: reqArgs.DisableCompression,
+		QuicConfig: &quic.Config{
+			HandshakeIdleTimeout: connectTimeout,
+			MaxIdleTimeout:       readTimeout,
 		},
 	}
+	if netOptions != nil {
+		roundTripper.Dial = netOptions.dialQuic
+	}
+	return &http.Client{
+		Transport: roundTripper,
+	}
 }
 
 // add headers to the request
@@ -54,6 +70,8 @@ func AddCookies(reqUrl string, cookies []*http.Cookie, req *http.Request) {
 		return
 	}
 
+	cookieMu.RLock()
+	defer cookieMu.RUnlock()
 	for _, cookie := range cookies {
 		if strings.Contains(reqUrl, cookie.Domain) {
 			req.AddCookie(cookie)
@@ -78,16 +96,31 @@ func AddParams(params map[string]string, req *http.Request) {
 func sendRequest(req *http.Request, reqArgs *RequestArgs) (*http.Response, error) {
 	AddCookies(reqArgs.Url, reqArgs.Cookies, req)
 	AddHeaders(reqArgs.Headers, reqArgs.UserAgent, req)
+	AddExtraHeaders(req)
 	AddParams(reqArgs.Params, req)
 
 	var err error
 	var res *http.Response
 
+	utils.SleepRequestDelay()
 	client := GetHttpClient(reqArgs)
 	client.Timeout = time.Duration(reqArgs.Timeout) * time.Second
+	breaker := getCircuitBreaker(req.URL.Hostname())
 	for i := 1; i <= utils.RETRY_COUNTER; i++ {
-		res, err = client.Do(req)
+		if err := breaker.allow(req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+
+		startedAt := time.Now()
+		res, err = DoWithHostLimit(client, req)
 		if err == nil {
+			withReadTimeout(res)
+		}
+		breaker.record(res, err)
+		traceRequest(req, res, err, startedAt)
+		recordWarc(req, res, err, startedAt)
+		if err == nil {
+			updateRotatedCookies(reqArgs.Cookies, res)
 			if !reqArgs.CheckStatus {
 				return res, nil
 			} else if res.StatusCode == 200 {
@@ -96,7 +129,8 @@ func sendRequest(req *http.Request, reqArgs *RequestArgs) (*http.Response, error
 			res.Body.Close()
 		} else if errors.Is(err, context.Canceled) {
 			return nil, context.Canceled
-		} else {
+		} else if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			// only the timed out requests, e.g. due to the connect or read timeout, are retried
 			break
 		}
 
@@ -161,14 +195,12 @@ func CheckInternetConnection() {
 		},
 	)
 	if err != nil {
-		color.Red(
-			fmt.Sprintf(
-				"error %d: unable to connect to the internet, more info => %v",
-				utils.DEV_ERROR,
-				err,
-			),
+		utils.ExitWithErrorf(
+			utils.EXIT_NETWORK_ERROR,
+			"error %d: unable to connect to the internet, more info => %v",
+			utils.DEV_ERROR,
+			err,
 		)
-		os.Exit(1)
 	}
 }
 
@@ -0,0 +1,165 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	// minimum download speed in bytes per second, 0 means the speed is not checked
+	minSpeed int64
+
+	// the amount of time spent receiving a download that its speed is measured over
+	minSpeedWindow time.Duration
+)
+
+// Returned, wrapped, when a download is slower than the minimum speed
+var errSlowDownload = errors.New("the download was too slow")
+
+// Sets the minimum download speed in bytes per second below which
+// a download is restarted after receiving it at that speed for the given duration
+func SetMinSpeed(bytesPerSecond int64, window time.Duration) error {
+	if bytesPerSecond < 0 {
+		return fmt.Errorf(
+			"error %d: the minimum download speed cannot be negative",
+			utils.INPUT_ERROR,
+		)
+	}
+	if bytesPerSecond > 0 && window <= 0 {
+		return fmt.Errorf(
+			"error %d: the duration to measure the minimum download speed over must be more than 0 seconds but got %s",
+			utils.INPUT_ERROR,
+			window,
+		)
+	}
+	minSpeed = bytesPerSecond
+	minSpeedWindow = window
+	return nil
+}
+
+// Returns true if the download stalled due to the read timeout or the minimum speed
+func isStalled(err error) bool {
+	return errors.Is(err, errReadTimeout) || errors.Is(err, errSlowDownload)
+}
+
+// Reads the response body of a download and continues it with a new
+// request from the received bytes if the download stalls, like download managers do.
+//
+// If the server does not support continuing the download, the stall error is returned
+// so that the download is restarted.
+type stallResumingBody struct {
+	ctx      context.Context
+	res      *http.Response // the response currently being read
+	received int64
+	resumes  int
+
+	// the time spent waiting for the response body and the bytes received in the current window
+	windowWait  time.Duration
+	windowBytes int64
+}
+
+func newStallResumingBody(ctx context.Context, res *http.Response) *stallResumingBody {
+	return &stallResumingBody{ctx: ctx, res: res}
+}
+
+// Returns an error wrapping errSlowDownload if the download speed in the
+// current window is below the minimum speed, the time spent paused or limited
+// by the bandwidth limits is not counted as only the reads of the body are timed.
+func (b *stallResumingBody) checkSpeed(n int, waited time.Duration) error {
+	if minSpeed == 0 {
+		return nil
+	}
+
+	b.windowWait += waited
+	b.windowBytes += int64(n)
+	if b.windowWait < minSpeedWindow {
+		return nil
+	}
+
+	speed := int64(float64(b.windowBytes) / b.windowWait.Seconds())
+	b.windowWait = 0
+	b.windowBytes = 0
+	if speed >= minSpeed {
+		return nil
+	}
+	return fmt.Errorf(
+		"error %d: the download from %s was slower than %s/s at %s/s, %w",
+		utils.CONNECTION_ERROR,
+		b.res.Request.URL.Hostname(),
+		utils.FormatFileSize(minSpeed),
+		utils.FormatFileSize(speed),
+		errSlowDownload,
+	)
+}
+
+func (b *stallResumingBody) Read(p []byte) (int, error) {
+	startedAt := time.Now()
+	n, err := b.res.Body.Read(p)
+	b.received += int64(n)
+	if err == nil {
+		err = b.checkSpeed(n, time.Since(startedAt))
+	}
+	if !isStalled(err) || b.resumes >= utils.RETRY_COUNTER {
+		return n, err
+	}
+
+	b.resumes++
+	if resumeErr := b.resume(); resumeErr != nil {
+		utils.LogError(resumeErr, "the download will be restarted instead", false, utils.INFO)
+		return n, err
+	}
+	return n, nil
+}
+
+// Continues the download from the received bytes with a Range request
+func (b *stallResumingBody) resume() error {
+	prevRes := b.res
+	prevRes.Body.Close()
+	if prevRes.StatusCode != http.StatusOK && prevRes.StatusCode != http.StatusPartialContent {
+		return errors.New("the download cannot be continued as it was not successful")
+	}
+	if prevRes.Uncompressed {
+		// the received bytes are the decompressed bytes which do not match the byte range of the file
+		return errors.New("the download cannot be continued as it was compressed")
+	}
+
+	req := prevRes.Request.Clone(b.ctx)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.received))
+	// the server sends the whole file instead if it has changed since
+	if etag := prevRes.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		req.Header.Set("If-Range", etag)
+	} else if lastModified := prevRes.Header.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Range", lastModified)
+	}
+
+	res, err := sendRequest(req, &RequestArgs{
+		Url:     req.URL.String(),
+		Timeout: utils.DOWNLOAD_TIMEOUT,
+		Http2:   prevRes.ProtoMajor != 3,
+		Http3:   prevRes.ProtoMajor == 3,
+	})
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusPartialContent ||
+		!strings.HasPrefix(res.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", b.received)) {
+		res.Body.Close()
+		return fmt.Errorf("the download from %s cannot be continued, status code => %s", req.URL.String(), res.Status)
+	}
+
+	b.res = res
+	b.windowWait = 0
+	b.windowBytes = 0
+	return nil
+}
+
+// Closes the response body that is being read which is a new response if the download was continued
+func (b *stallResumingBody) Close() error {
+	return b.res.Body.Close()
+}
@@ -0,0 +1,98 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	// maximum time to establish a connection, including the TLS handshake, 0 means no limit
+	connectTimeout time.Duration
+
+	// maximum time to wait for the response headers or the next bytes of the body, 0 means no limit
+	readTimeout time.Duration
+)
+
+// Returned, wrapped, when no data was received within the read timeout
+var errReadTimeout = errors.New("the connection stalled")
+
+// Sets the connect timeout and the read timeout which is the maximum time
+// without receiving any data after which a stalled connection is closed
+func SetTimeouts(connect, read time.Duration) error {
+	if connect < 0 || read < 0 {
+		return fmt.Errorf(
+			"error %d: the connect and read timeouts cannot be negative but got %s and %s",
+			utils.INPUT_ERROR,
+			connect,
+			read,
+		)
+	}
+	connectTimeout = connect
+	readTimeout = read
+	return nil
+}
+
+// Closes the response body if a read did not receive any data within the read timeout
+// so that a stalled connection does not block the download forever.
+//
+// Only the time spent waiting for the connection is counted, so a download
+// that is paused or limited by the bandwidth limits does not time out.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	host  string
+	timer *time.Timer
+
+	mu       sync.Mutex
+	timedOut bool
+}
+
+// Wraps the response body with the read timeout if it is set
+func withReadTimeout(res *http.Response) {
+	if readTimeout == 0 {
+		return
+	}
+
+	body := &idleTimeoutBody{
+		ReadCloser: res.Body,
+		host:       res.Request.URL.Hostname(),
+	}
+	body.timer = time.AfterFunc(readTimeout, func() {
+		body.mu.Lock()
+		body.timedOut = true
+		body.mu.Unlock()
+		body.ReadCloser.Close()
+	})
+	body.timer.Stop()
+	res.Body = body
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	b.timer.Reset(readTimeout)
+	n, err := b.ReadCloser.Read(p)
+	b.timer.Stop()
+
+	b.mu.Lock()
+	timedOut := b.timedOut
+	b.mu.Unlock()
+	if timedOut {
+		return n, fmt.Errorf(
+			"error %d: no data was received from %s for %s, %w",
+			utils.CONNECTION_ERROR,
+			b.host,
+			readTimeout,
+			errReadTimeout,
+		)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,129 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Maximum number of bytes of the response body to record in the trace file
+const traceBodyLimit = 16 * 1024
+
+var (
+	traceMu   sync.Mutex
+	traceFile *os.File
+)
+
+type traceEntry struct {
+	StartedAt       string            `json:"started_at"`
+	DurationMs      int64             `json:"duration_ms"`
+	Method          string            `json:"method"`
+	Url             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	BodyTruncated   bool              `json:"response_body_truncated,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// Enables the tracing of every request and its response
+// by appending them as JSON lines to the trace file at the given path
+func SetTraceFile(tracePath string) error {
+	os.MkdirAll(filepath.Dir(tracePath), 0666)
+	f, err := os.OpenFile(tracePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to open trace file at %s, more info => %v",
+			utils.OS_ERROR,
+			tracePath,
+			err,
+		)
+	}
+
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	traceFile = f
+	return nil
+}
+
+// Returns the headers as a map with the credentials redacted
+func getTraceHeaders(headers http.Header) map[string]string {
+	traceHeaders := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if isRedactedHeader(key) {
+			traceHeaders[key] = redactedValue
+		} else {
+			traceHeaders[key] = strings.Join(values, ", ")
+		}
+	}
+	return traceHeaders
+}
+
+// Returns true if the response body is text that is worth recording, e.g. JSON or HTML
+func isTextResponse(res *http.Response) bool {
+	contentType := res.Header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml")
+}
+
+// Records the request and its response or error to the trace file if tracing is enabled
+// with the credentials in the headers, URL, and response body redacted.
+//
+// The response body, if any, will be restored after reading the first traceBodyLimit bytes.
+func traceRequest(req *http.Request, res *http.Response, err error, startedAt time.Time) {
+	traceMu.Lock()
+	isTracing := traceFile != nil
+	traceMu.Unlock()
+	if !isTracing {
+		return
+	}
+
+	entry := &traceEntry{
+		StartedAt:      startedAt.UTC().Format(time.RFC3339Nano),
+		DurationMs:     time.Since(startedAt).Milliseconds(),
+		Method:         req.Method,
+		Url:            redactUrl(req.URL).String(),
+		RequestHeaders: getTraceHeaders(req.Header),
+	}
+	if err != nil {
+		errMsg, _ := redactBody([]byte(err.Error()))
+		entry.Error = string(errMsg)
+	} else {
+		entry.Status = res.StatusCode
+		entry.ResponseHeaders = getTraceHeaders(res.Header)
+		if isTextResponse(res) {
+			body, _ := io.ReadAll(io.LimitReader(res.Body, traceBodyLimit+1))
+			res.Body = &peekedBody{
+				Reader: io.MultiReader(bytes.NewReader(body), res.Body),
+				Closer: res.Body,
+			}
+			if len(body) > traceBodyLimit {
+				body = body[:traceBodyLimit]
+				entry.BodyTruncated = true
+			}
+			body, _ = redactBody(body)
+			entry.ResponseBody = string(body)
+		}
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		// should never happen but just in case
+		return
+	}
+
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	traceFile.Write(append(line, '\n'))
+}
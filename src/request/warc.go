@@ -0,0 +1,375 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	// a new WARC file is started once the current one is larger than this which is the size recommended by the WARC specification
+	warcMaxFileSize = 1024 * 1024 * 1024
+
+	warcDateFormat = "2006-01-02T15:04:05Z"
+
+	// text responses up to this size are checked for credentials to redact before they are recorded
+	warcRedactLimit = 8 * 1024 * 1024
+)
+
+// Records the requests and their responses into WARC files
+// that can be read by web archiving tools such as pywb or ReplayWeb.page
+type warcWriter struct {
+	mu     sync.Mutex
+	dir    string
+	prefix string // the filename prefix of the WARC files, e.g. "cultured-downloader-20230501120000"
+	serial int
+	file   *os.File
+	size   int64
+}
+
+var warc *warcWriter
+
+// Enables the recording of every request and its response to WARC files in the given folder.
+//
+// Each record is compressed as a separate gzip member
+// so that the files can be read by the usual web archiving tools.
+func SetWarcDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf(
+			"error %d: failed to create the WARC folder at %s, more info => %v",
+			utils.OS_ERROR,
+			dir,
+			err,
+		)
+	}
+	warc = &warcWriter{
+		dir:    dir,
+		prefix: "cultured-downloader-" + time.Now().UTC().Format("20060102150405"),
+	}
+	return nil
+}
+
+// Closes the current WARC file, if any, once all the responses have been recorded
+func CloseWarc() {
+	if warc == nil {
+		return
+	}
+	warc.mu.Lock()
+	defer warc.mu.Unlock()
+	if warc.file != nil {
+		warc.file.Close()
+		warc.file = nil
+	}
+}
+
+// Returns a new WARC record ID
+func newWarcRecordId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Returns the SHA-1 digest in the format used by the WARC digest headers
+func formatWarcDigest(h hash.Hash) string {
+	return "sha1:" + base32.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Writes the headers sorted by their name with the credentials redacted like in the trace file
+func writeWarcHttpHeaders(buf *bytes.Buffer, headers http.Header) {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range headers[key] {
+			if isRedactedHeader(key) {
+				value = redactedValue
+			}
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+// Returns the HTTP request line and headers of the request
+func getWarcRequestBlock(req *http.Request) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", req.Method, redactUrl(req.URL).RequestURI())
+	headers := req.Header.Clone()
+	headers.Set("Host", req.URL.Host)
+	writeWarcHttpHeaders(&buf, headers)
+	return buf.Bytes()
+}
+
+// Returns the HTTP status line and headers of the response.
+//
+// As the body is recorded after Go has removed the transfer encoding and decompressed it,
+// the headers describing them are removed so that the record can be replayed.
+// The length of the text responses is also removed as their credentials may be redacted.
+func getWarcResponseHeader(res *http.Response) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/%d.%d %s\r\n", res.ProtoMajor, res.ProtoMinor, res.Status)
+	headers := res.Header.Clone()
+	headers.Del("Transfer-Encoding")
+	if res.Uncompressed || isTextResponse(res) {
+		headers.Del("Content-Encoding")
+		headers.Del("Content-Length")
+	}
+	writeWarcHttpHeaders(&buf, headers)
+	return buf.Bytes()
+}
+
+// Opens the next WARC file with a warcinfo record if there is no file or if the current file is too large.
+//
+// Must be called with the lock held.
+func (w *warcWriter) rotate() error {
+	if w.file != nil && w.size < warcMaxFileSize {
+		return nil
+	}
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+
+	filename := fmt.Sprintf("%s-%05d.warc.gz", w.prefix, w.serial)
+	file, err := os.OpenFile(filepath.Join(w.dir, filename), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w.serial++
+	w.file = file
+	w.size = 0
+
+	info := fmt.Sprintf(
+		"software: Cultured Downloader CLI %s\r\nformat: WARC File Format 1.1\r\n"+
+			"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n",
+		utils.VERSION,
+	)
+	return w.writeRecord([]string{
+		"WARC-Type: warcinfo",
+		"WARC-Date: " + time.Now().UTC().Format(warcDateFormat),
+		"WARC-Filename: " + filename,
+		"WARC-Record-ID: " + newWarcRecordId(),
+		"Content-Type: application/warc-fields",
+	}, int64(len(info)), strings.NewReader(info))
+}
+
+// Writes a record with the given headers and block to the current WARC file.
+//
+// Must be called with the lock held.
+func (w *warcWriter) writeRecord(headers []string, blockLen int64, block io.Reader) error {
+	gzipWriter := gzip.NewWriter(w.file)
+	bufWriter := bufio.NewWriter(gzipWriter)
+	bufWriter.WriteString("WARC/1.1\r\n")
+	for _, header := range headers {
+		bufWriter.WriteString(header + "\r\n")
+	}
+	fmt.Fprintf(bufWriter, "Content-Length: %d\r\n\r\n", blockLen)
+	if _, err := io.Copy(bufWriter, block); err != nil {
+		return err
+	}
+	bufWriter.WriteString("\r\n\r\n")
+	if err := bufWriter.Flush(); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+
+	if info, err := w.file.Stat(); err == nil {
+		w.size = info.Size()
+	}
+	return nil
+}
+
+// Records the request and its response to the WARC file where the response body is read from the spooled file
+func (w *warcWriter) writeExchange(exchange *warcExchange) error {
+	if _, err := exchange.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if exchange.isText && exchange.bodyLen <= warcRedactLimit {
+		if err := exchange.redactSpool(); err != nil {
+			return err
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.rotate(); err != nil {
+		return err
+	}
+
+	date := exchange.startedAt.UTC().Format(warcDateFormat)
+	targetUri := redactUrl(exchange.req.URL).String()
+	responseId := newWarcRecordId()
+	responseHeaders := []string{
+		"WARC-Type: response",
+		"WARC-Target-URI: " + targetUri,
+		"WARC-Date: " + date,
+		"WARC-Record-ID: " + responseId,
+		"WARC-Payload-Digest: " + formatWarcDigest(exchange.payloadHash),
+		"WARC-Block-Digest: " + formatWarcDigest(exchange.blockHash),
+		"Content-Type: application/http;msgtype=response",
+	}
+	if exchange.truncated {
+		responseHeaders = append(responseHeaders, "WARC-Truncated: disconnect")
+	}
+	blockLen := int64(len(exchange.resHeader)) + exchange.bodyLen
+	block := io.MultiReader(bytes.NewReader(exchange.resHeader), exchange.spool)
+	if err := w.writeRecord(responseHeaders, blockLen, block); err != nil {
+		return err
+	}
+
+	reqBlock := getWarcRequestBlock(exchange.req)
+	reqHash := sha1.New()
+	reqHash.Write(reqBlock)
+	return w.writeRecord([]string{
+		"WARC-Type: request",
+		"WARC-Target-URI: " + targetUri,
+		"WARC-Date: " + date,
+		"WARC-Record-ID: " + newWarcRecordId(),
+		"WARC-Concurrent-To: " + responseId,
+		"WARC-Block-Digest: " + formatWarcDigest(reqHash),
+		"Content-Type: application/http;msgtype=request",
+	}, int64(len(reqBlock)), bytes.NewReader(reqBlock))
+}
+
+// A request and its response whose body is spooled to a temporary
+// file as it is read so that it can be recorded once it has been fully read
+type warcExchange struct {
+	req       *http.Request
+	startedAt time.Time
+	resHeader []byte
+	isText    bool
+
+	body        io.ReadCloser
+	spool       *os.File
+	bodyLen     int64
+	payloadHash hash.Hash
+	blockHash   hash.Hash
+	truncated   bool
+	err         error // set if the body could not be spooled
+
+	closeOnce sync.Once
+}
+
+// Redacts the credentials in the spooled text body, e.g. the tokens in the Pixiv OAuth responses,
+// and updates the length and digests of the body to be recorded
+func (e *warcExchange) redactSpool() error {
+	body, err := io.ReadAll(e.spool)
+	if err != nil {
+		return err
+	}
+	if _, err := e.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	redacted, ok := redactBody(body)
+	if !ok {
+		return nil
+	}
+
+	if err := e.spool.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := e.spool.Write(redacted); err != nil {
+		return err
+	}
+	if _, err := e.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	e.bodyLen = int64(len(redacted))
+	e.payloadHash = sha1.New()
+	e.payloadHash.Write(redacted)
+	e.blockHash = sha1.New()
+	e.blockHash.Write(e.resHeader)
+	e.blockHash.Write(redacted)
+	return nil
+}
+
+func (e *warcExchange) Read(p []byte) (int, error) {
+	n, err := e.body.Read(p)
+	if n > 0 && e.err == nil {
+		if _, spoolErr := e.spool.Write(p[:n]); spoolErr != nil {
+			e.err = spoolErr
+		}
+		e.payloadHash.Write(p[:n])
+		e.blockHash.Write(p[:n])
+		e.bodyLen += int64(n)
+	}
+	return n, err
+}
+
+// Reads the rest of the body, e.g. the trailing newline after a decoded JSON response,
+// and records the exchange before closing the body
+func (e *warcExchange) Close() error {
+	e.closeOnce.Do(func() {
+		if _, err := io.Copy(io.Discard, e); err != nil {
+			e.truncated = true
+		}
+		if e.err == nil {
+			e.err = warc.writeExchange(e)
+		}
+		if e.err != nil {
+			utils.LogError(
+				fmt.Errorf(
+					"error %d: failed to record the response of %s to the WARC file, more info => %v",
+					utils.OS_ERROR,
+					redactUrl(e.req.URL).String(),
+					e.err,
+				),
+				"",
+				false,
+				utils.ERROR,
+			)
+		}
+		e.spool.Close()
+		os.Remove(e.spool.Name())
+	})
+	return e.body.Close()
+}
+
+// Wraps the response body to record the request and its response
+// to the WARC file once the body has been closed if WARC recording is enabled
+func recordWarc(req *http.Request, res *http.Response, err error, startedAt time.Time) {
+	if warc == nil || err != nil {
+		return
+	}
+
+	spool, spoolErr := os.CreateTemp("", "cultured-downloader-*.warc.tmp")
+	if spoolErr != nil {
+		utils.LogError(spoolErr, "failed to record the response to the WARC file", false, utils.ERROR)
+		return
+	}
+	exchange := &warcExchange{
+		req:         req,
+		startedAt:   startedAt,
+		resHeader:   getWarcResponseHeader(res),
+		isText:      isTextResponse(res),
+		body:        res.Body,
+		spool:       spool,
+		payloadHash: sha1.New(),
+		blockHash:   sha1.New(),
+	}
+	exchange.blockHash.Write(exchange.resHeader)
+	res.Body = exchange
+}
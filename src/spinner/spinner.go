@@ -17,9 +17,29 @@ const (
 	REQ_SPINNER  = "pong"
 	JSON_SPINNER = "aesthetic"
 	DL_SPINNER   = "material"
+
+	// How often the message of a spinner is printed again in plain mode if it has changed
+	PLAIN_PROGRESS_INTERVAL = 15 * time.Second
 )
 
 var (
+	// plain is a flag to print the spinner messages on their own lines without any animations,
+	// e.g. when the output is written to a log instead of a terminal
+	plain bool
+
+	// lineMode is a flag to only print the outcome message of each spinner, i.e. one line per stage
+	lineMode bool
+
+	// compact is a flag to leave out the extra info in the spinner messages, e.g. the name of the last downloaded file
+	compact bool
+
+	// the spinner type and colour of the theme that override the ones given to New if not empty
+	themeSpinnerType string
+	themeColour      string
+
+	successColour = color.New(color.FgGreen)
+	errColour     = color.New(color.FgRed)
+
 	spinnerTypes map[string]SpinnerInfo
 	colourMap  = map[string]color.Attribute{
 		"black":   color.FgBlack,
@@ -45,6 +65,63 @@ var (
 func init() {
 	spinnerTypes = GetSpinnerTypes()
 	spinnersJson = nil // free up memory since it is no longer needed
+
+	// the spinners are printed to stdout, so the animations would only
+	// fill the logs with carriage returns if it is redirected, e.g. by nohup or cron
+	plain = !utils.IsTerminal(os.Stdout)
+}
+
+// SetPlain sets whether the spinners should print their messages
+// on their own lines without any animations and carriage returns.
+//
+// The spinners are already in plain mode if stdout is not a terminal
+// but it can also be set for outputs like the systemd journal.
+func SetPlain(isPlain bool) {
+	plain = isPlain
+}
+
+// ValidateTheme returns an error if the progress config has an
+// invalid mode or verbosity or an unsupported spinner type or colour
+func ValidateTheme(config *utils.ProgressConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if _, ok := spinnerTypes[config.Style]; config.Style != "" && !ok {
+		return fmt.Errorf(
+			"error %d: the progress style %q is not supported, please refer to https://github.com/sindresorhus/cli-spinners for the styles",
+			utils.INPUT_ERROR,
+			config.Style,
+		)
+	}
+	for _, colour := range []string{config.Colour, config.SuccessColour, config.ErrorColour} {
+		if _, ok := colourMap[colour]; colour != "" && !ok {
+			return fmt.Errorf(
+				"error %d: the progress colour %q is not supported, e.g. use \"cyan\" or \"fgHiCyan\"",
+				utils.INPUT_ERROR,
+				colour,
+			)
+		}
+	}
+	return nil
+}
+
+// SetTheme sets the appearance of all the spinners based on the progress config of the config file
+func SetTheme(config *utils.ProgressConfig) error {
+	if err := ValidateTheme(config); err != nil {
+		return err
+	}
+
+	lineMode = config.Mode == utils.PROGRESS_LINE
+	compact = config.Verbosity == utils.VERBOSITY_COMPACT
+	themeSpinnerType = config.Style
+	themeColour = config.Colour
+	if config.SuccessColour != "" {
+		successColour = color.New(colourMap[config.SuccessColour])
+	}
+	if config.ErrorColour != "" {
+		errColour = color.New(colourMap[config.ErrorColour])
+	}
+	return nil
 }
 
 // ListSpinnerTypes lists all the supported spinner types
@@ -108,7 +185,15 @@ type Spinner struct {
 //
 // For the spinner type and colour, please refer to the source code or 
 // use ListSpinnerTypes() and ListColours() to print all the supported spinner types and colours.
+//
+// The spinner type and colour are overridden by the ones set by SetTheme, if any.
 func New(spinnerType, colour, message, successMsg, errMsg string, maxCount int) *Spinner {
+	if themeSpinnerType != "" {
+		spinnerType = themeSpinnerType
+	}
+	if themeColour != "" {
+		colour = themeColour
+	}
 	colourAttribute, ok := colourMap[colour]
 	if !ok {
 		panic(
@@ -147,6 +232,16 @@ func (s *Spinner) Start() {
 	s.active = true
 	s.mu.Unlock()
 
+	if lineMode {
+		// only the outcome message is printed by Stop
+		return
+	}
+	if plain {
+		s.Colour.Printf("%s\n", s.Msg)
+		go s.printPlainProgress(s.Msg)
+		return
+	}
+
 	go func() {
 		for {
 			for _, frame := range s.Spinner.Frames {
@@ -176,6 +271,31 @@ func (s *Spinner) Start() {
 	}()
 }
 
+// Prints the spinner message on its own line every PLAIN_PROGRESS_INTERVAL if it has changed
+// until the spinner is stopped so that the logs show the progress of the long stages,
+// e.g. the number of files downloaded so far.
+func (s *Spinner) printPlainProgress(lastMsg string) {
+	ticker := time.NewTicker(PLAIN_PROGRESS_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if !s.active {
+				s.mu.Unlock()
+				return
+			}
+			if s.Msg != lastMsg {
+				lastMsg = s.Msg
+				s.Colour.Printf("%s\n", s.Msg)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
 // Add adds i to the spinner count
 func (s *Spinner) Add(i int) int {
 	s.mu.Lock()
@@ -211,6 +331,25 @@ func (s* Spinner) MsgIncrement(baseMsg string) {
 	)
 }
 
+// MsgIncrementWithInfo is the same as MsgIncrement
+// but appends the given info to the message, e.g. the name of the last downloaded file.
+//
+// If info is empty or the verbosity is compact, it will behave the same as MsgIncrement.
+func (s *Spinner) MsgIncrementWithInfo(baseMsg, info string) {
+	if info == "" || compact {
+		s.MsgIncrement(baseMsg)
+		return
+	}
+
+	s.UpdateMsg(
+		fmt.Sprintf(
+			baseMsg+" (%s)",
+			s.Add(1),
+			info,
+		),
+	)
+}
+
 func (s *Spinner) stopSpinner() {
 	s.active = false
 	if s.count != 0 {
@@ -220,6 +359,15 @@ func (s *Spinner) stopSpinner() {
 	close(s.stop)
 }
 
+// Returns the prefix and suffix to clear the
+// spinner's line unless the spinner is in plain or line mode
+func getClearLine() (string, string) {
+	if plain || lineMode {
+		return "", ""
+	}
+	return "\r", CLEAR_LINE
+}
+
 // Stop stops the spinner and prints an outcome message
 func (s *Spinner) Stop(hasErr bool) {
 	s.mu.Lock()
@@ -229,37 +377,42 @@ func (s *Spinner) Stop(hasErr bool) {
 	}
 
 	s.stopSpinner()
+	prefix, suffix := getClearLine()
 	if hasErr && s.ErrMsg != "" {
-		color.Red(
-			"\r✗ %s%s\n",
+		errColour.Printf(
+			"%s✗ %s%s\n",
+			prefix,
 			s.ErrMsg,
-			CLEAR_LINE,
+			suffix,
 		)
 	} else if s.SuccessMsg != "" {
-		color.Green(
-			"\r✓ %s%s", 
+		successColour.Printf(
+			"%s✓ %s%s\n",
+			prefix,
 			s.SuccessMsg,
-			CLEAR_LINE,
+			suffix,
 		)
 	}
 }
 
 // KillProgram stops the spinner, 
-// prints the given message and exits the program with code 2.
+// prints the given message and exits the program with the EXIT_INTERRUPTED code.
 //
 // Used for Ctrl + C interrupts.
 func (s *Spinner) KillProgram(msg string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if !s.active {
-		os.Exit(2)
+		os.Exit(utils.EXIT_INTERRUPTED)
 	}
 
 	s.stopSpinner()
-	color.Red(
-		"\r✗ %s%s\n",
+	prefix, suffix := getClearLine()
+	errColour.Printf(
+		"%s✗ %s%s\n",
+		prefix,
 		msg,
-		CLEAR_LINE,
+		suffix,
 	)
-	os.Exit(2)
+	os.Exit(utils.EXIT_INTERRUPTED)
 }
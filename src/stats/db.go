@@ -0,0 +1,86 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// Only the most recent runs are kept so that the statistics file does not grow indefinitely
+const MAX_SAVED_RUNS = 1000
+
+var runsMu sync.Mutex
+
+// Returns the path to the file in the app data folder that stores the statistics of the past runs
+func GetStatsFilePath() string {
+	return filepath.Join(utils.APP_PATH, "run_stats.json")
+}
+
+// Returns the statistics of the past runs from the oldest to the most recent run
+func LoadRuns() ([]*Run, error) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	return loadRuns()
+}
+
+func loadRuns() ([]*Run, error) {
+	var runs []*Run
+	statsFile, err := os.ReadFile(GetStatsFilePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return runs, nil
+		}
+		return nil, fmt.Errorf(
+			"stats error %d: failed to read the statistics file, more info => %v",
+			utils.OS_ERROR,
+			err,
+		)
+	}
+
+	if err = json.Unmarshal(statsFile, &runs); err != nil {
+		return nil, fmt.Errorf(
+			"stats error %d: failed to unmarshal the statistics file, more info => %v",
+			utils.JSON_ERROR,
+			err,
+		)
+	}
+	return runs, nil
+}
+
+// Appends the run to the statistics file and removes the oldest runs above MAX_SAVED_RUNS
+func addRun(run *Run) error {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	runs, err := loadRuns()
+	if err != nil {
+		return err
+	}
+
+	runs = append(runs, run)
+	if len(runs) > MAX_SAVED_RUNS {
+		runs = runs[len(runs)-MAX_SAVED_RUNS:]
+	}
+	statsFile, err := json.Marshal(runs)
+	if err != nil {
+		return fmt.Errorf(
+			"stats error %d: failed to marshal the statistics, more info => %v",
+			utils.JSON_ERROR,
+			err,
+		)
+	}
+
+	os.MkdirAll(utils.APP_PATH, 0666)
+	if err = os.WriteFile(GetStatsFilePath(), statsFile, 0666); err != nil {
+		return fmt.Errorf(
+			"stats error %d: failed to write the statistics file, more info => %v",
+			utils.OS_ERROR,
+			err,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package stats
+
+// The total number of files and bytes downloaded in the past runs
+type fileTotals struct {
+	files int
+	bytes int64
+}
+
+func (t *fileTotals) add(files int, bytes int64) {
+	t.files += files
+	t.bytes += bytes
+}
+
+// Returns the average file size or 0 if no files have been downloaded
+func (t *fileTotals) average() int64 {
+	if t == nil || t.files == 0 {
+		return 0
+	}
+	return t.bytes / int64(t.files)
+}
+
+// SizeEstimator estimates the size of the files to download
+// from the average size of the files downloaded in the past runs
+// as the file sizes are not known until the files are downloaded.
+type SizeEstimator struct {
+	sites    map[string]*fileTotals
+	creators map[[2]string]*fileTotals // keyed by the website and the creator
+}
+
+// Returns a new SizeEstimator from the past runs, see LoadRuns
+func NewSizeEstimator(runs []*Run) *SizeEstimator {
+	estimator := &SizeEstimator{
+		sites:    make(map[string]*fileTotals),
+		creators: make(map[[2]string]*fileTotals),
+	}
+	for _, run := range runs {
+		if _, ok := estimator.sites[run.Site]; !ok {
+			estimator.sites[run.Site] = &fileTotals{}
+		}
+		estimator.sites[run.Site].add(run.Files, run.Bytes)
+
+		for _, creatorStats := range run.Creators {
+			key := [2]string{run.Site, creatorStats.Creator}
+			if _, ok := estimator.creators[key]; !ok {
+				estimator.creators[key] = &fileTotals{}
+			}
+			estimator.creators[key].add(creatorStats.Files, creatorStats.Bytes)
+		}
+	}
+	return estimator
+}
+
+// Returns the estimated size of the given number of files of the creator on the website
+// based on the average size of the creator's files or the website's files if the creator has no past runs.
+//
+// Returns 0 if no files of the website have been downloaded in the past runs.
+func (e *SizeEstimator) Estimate(site, creator string, fileCount int) int64 {
+	avgSize := e.creators[[2]string{site, creator}].average()
+	if avgSize == 0 {
+		avgSize = e.sites[site].average()
+	}
+	return avgSize * int64(fileCount)
+}
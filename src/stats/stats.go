@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+)
+
+// The statistics of the posts of a creator that were downloaded in a run
+type CreatorStats struct {
+	Creator string `json:"creator"`
+	Posts   int    `json:"posts"`
+	Files   int    `json:"files"`
+	Bytes   int64  `json:"bytes"`
+	Errors  int    `json:"errors"` // the files that failed to download
+}
+
+// The statistics of a run of a download command
+type Run struct {
+	Site      string        `json:"site"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+
+	Posts  int   `json:"posts"`
+	Files  int   `json:"files"`
+	Bytes  int64 `json:"bytes"`
+	Errors int   `json:"errors"` // all the errors that were logged, not only the failed downloads
+
+	Creators []*CreatorStats `json:"creators,omitempty"`
+}
+
+// Handler records the statistics of a run of a download command
+// for the given website which are saved by Save after the run
+type Handler struct {
+	events.BaseHandler
+
+	mu          sync.Mutex
+	run         *Run
+	creators    map[string]*CreatorStats
+	postFolders map[string]*CreatorStats // the post folder => the creator of the post
+	seenPosts   map[string]struct{}
+}
+
+// Returns a new Handler that records the statistics of a run starting now for the website
+func NewHandler(site string) *Handler {
+	return &Handler{
+		run:         &Run{Site: site, StartedAt: time.Now()},
+		creators:    make(map[string]*CreatorStats),
+		postFolders: make(map[string]*CreatorStats),
+		seenPosts:   make(map[string]struct{}),
+	}
+}
+
+func (h *Handler) OnPostResolved(post *events.Post) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.seenPosts[post.Site+"|"+post.Id]; ok {
+		return
+	}
+	h.seenPosts[post.Site+"|"+post.Id] = struct{}{}
+
+	creator, ok := h.creators[post.Creator]
+	if !ok {
+		creator = &CreatorStats{Creator: post.Creator}
+		h.creators[post.Creator] = creator
+	}
+	creator.Posts++
+	h.run.Posts++
+	h.postFolders[filepath.Clean(post.Folder)] = creator
+}
+
+// Returns the creator of the post that the file is downloaded to, should be called with h.mu held.
+//
+// The file may be in a subfolder of the post folder, e.g. "images" or "gdrive".
+func (h *Handler) getFileCreator(filePath string) *CreatorStats {
+	for dir := filepath.Dir(filePath); ; dir = filepath.Dir(dir) {
+		if creator, ok := h.postFolders[dir]; ok {
+			return creator
+		}
+		if parentDir := filepath.Dir(dir); parentDir == dir {
+			return nil
+		}
+	}
+}
+
+func (h *Handler) OnFileDone(file *events.File, err error) {
+	// the size is from the event as the file may be on a remote storage backend
+	fileSize := file.Size
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	creator := h.getFileCreator(filepath.Clean(file.FilePath))
+	if err != nil {
+		if creator != nil {
+			creator.Errors++
+		}
+		return
+	}
+	if file.Skipped {
+		return
+	}
+
+	h.run.Files++
+	h.run.Bytes += fileSize
+	if creator != nil {
+		creator.Files++
+		creator.Bytes += fileSize
+	}
+}
+
+func (h *Handler) OnError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.run.Errors++
+}
+
+// Saves the statistics of the run to the statistics file in the app data folder
+func (h *Handler) Save() error {
+	h.mu.Lock()
+	run := *h.run
+	run.Duration = time.Since(run.StartedAt).Round(time.Second)
+	run.Creators = make([]*CreatorStats, 0, len(h.creators))
+	for _, creator := range h.creators {
+		run.Creators = append(run.Creators, creator)
+	}
+	h.mu.Unlock()
+
+	sort.Slice(run.Creators, func(i, j int) bool {
+		return run.Creators[i].Creator < run.Creators[j].Creator
+	})
+	return addRun(&run)
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"sort"
+)
+
+// Trend compares the most recent runs of a website with the runs before them
+type Trend struct {
+	Site string
+
+	RecentRuns   int
+	PreviousRuns int
+
+	RecentFilesPerRun    float64
+	PreviousFilesPerRun  float64
+	RecentErrorsPerRun   float64
+	PreviousErrorsPerRun float64
+
+	// FailingStreak is the number of the most recent runs in a row that logged errors without downloading any files
+	FailingStreak int
+
+	// EmptyStreak is the number of the most recent runs in a row that found no posts
+	// although an earlier run did, e.g. when a website's API changed without returning errors
+	EmptyStreak int
+}
+
+// Returns the runs of the creator where the statistics of each run are only of the creator's posts
+//
+// The errors that were logged in a run are only counted for the failed downloads of the creator's files.
+func FilterByCreator(runs []*Run, creator string) []*Run {
+	var creatorRuns []*Run
+	for _, run := range runs {
+		for _, creatorStats := range run.Creators {
+			if creatorStats.Creator != creator {
+				continue
+			}
+			creatorRuns = append(creatorRuns, &Run{
+				Site:      run.Site,
+				StartedAt: run.StartedAt,
+				Duration:  run.Duration,
+				Posts:     creatorStats.Posts,
+				Files:     creatorStats.Files,
+				Bytes:     creatorStats.Bytes,
+				Errors:    creatorStats.Errors,
+				Creators:  []*CreatorStats{creatorStats},
+			})
+			break
+		}
+	}
+	return creatorRuns
+}
+
+// Returns the average files and errors per run of the runs
+func getAverages(runs []*Run) (float64, float64) {
+	if len(runs) == 0 {
+		return 0, 0
+	}
+	files, errCount := 0, 0
+	for _, run := range runs {
+		files += run.Files
+		errCount += run.Errors
+	}
+	return float64(files) / float64(len(runs)), float64(errCount) / float64(len(runs))
+}
+
+// Returns the trend of the runs of a website which are sorted from the oldest to the most recent run
+func getTrend(site string, runs []*Run, window int) *Trend {
+	recentStart := len(runs) - window
+	if recentStart < 0 {
+		recentStart = 0
+	}
+	previousStart := recentStart - window
+	if previousStart < 0 {
+		previousStart = 0
+	}
+	recentRuns, previousRuns := runs[recentStart:], runs[previousStart:recentStart]
+
+	trend := &Trend{
+		Site:         site,
+		RecentRuns:   len(recentRuns),
+		PreviousRuns: len(previousRuns),
+	}
+	trend.RecentFilesPerRun, trend.RecentErrorsPerRun = getAverages(recentRuns)
+	trend.PreviousFilesPerRun, trend.PreviousErrorsPerRun = getAverages(previousRuns)
+
+	for idx := len(runs) - 1; idx >= 0 && runs[idx].Errors > 0 && runs[idx].Files == 0; idx-- {
+		trend.FailingStreak++
+	}
+	emptyStreak := 0
+	for idx := len(runs) - 1; idx >= 0; idx-- {
+		if runs[idx].Posts > 0 {
+			// only a streak after a run that found posts is unusual
+			trend.EmptyStreak = emptyStreak
+			break
+		}
+		emptyStreak++
+	}
+	return trend
+}
+
+// Returns the trend of each website in the runs, sorted by the website,
+// where the last window runs are compared with the window runs before them
+func GetTrends(runs []*Run, window int) []*Trend {
+	siteRuns := make(map[string][]*Run)
+	for _, run := range runs {
+		siteRuns[run.Site] = append(siteRuns[run.Site], run)
+	}
+
+	trends := make([]*Trend, 0, len(siteRuns))
+	for site, runs := range siteRuns {
+		trends = append(trends, getTrend(site, runs, window))
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		return trends[i].Site < trends[j].Site
+	})
+	return trends
+}
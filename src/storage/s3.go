@@ -0,0 +1,483 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+
+	// the payload is not hashed when streaming the downloads which S3 allows over HTTPS
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3EmptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" // SHA256 of ""
+
+	// the maximum size of an object uploaded with a single PUT request,
+	// larger objects are uploaded in parts with a multipart upload
+	s3MaxPutSize = 5 * 1024 * 1024 * 1024
+
+	// the minimum size of each part of a multipart upload which is increased
+	// for objects that would otherwise need more than s3MaxParts parts
+	s3MinPartSize = 64 * 1024 * 1024
+	s3MaxParts    = 10000
+)
+
+// Writes the files to an S3-compatible bucket using AWS Signature Version 4
+type s3Backend struct {
+	config   *utils.S3StorageConfig
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func newS3Backend(config *utils.S3StorageConfig) (*s3Backend, error) {
+	if config == nil || config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf(
+			"storage error %d: the endpoint and bucket must be set in the s3 section of the storage config",
+			utils.INPUT_ERROR,
+		)
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return nil, fmt.Errorf(
+			"storage error %d: invalid S3 endpoint %q, please use a URL like \"https://s3.us-west-002.backblazeb2.com\"",
+			utils.INPUT_ERROR,
+			config.Endpoint,
+		)
+	}
+
+	configCopy := *config
+	if configCopy.Region == "" {
+		configCopy.Region = s3DefaultRegion
+	}
+	if configCopy.AccessKeyId == "" {
+		configCopy.AccessKeyId = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if configCopy.SecretAccessKey == "" {
+		configCopy.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if configCopy.AccessKeyId == "" || configCopy.SecretAccessKey == "" {
+		return nil, fmt.Errorf(
+			"storage error %d: the S3 access key ID and secret access key must be set in the storage config "+
+				"or the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables",
+			utils.INPUT_ERROR,
+		)
+	}
+	return &s3Backend{
+		config:   &configCopy,
+		endpoint: endpoint,
+		client:   &http.Client{},
+	}, nil
+}
+
+func (b *s3Backend) Name() string {
+	return "S3"
+}
+
+// Escapes the object key for the URL path where only the unreserved characters and slashes are kept as-is
+func escapeS3Key(key string) string {
+	var sb strings.Builder
+	for _, c := range []byte(key) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) != -1 {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// Returns the URL of the object of the file
+func (b *s3Backend) getObjectUrl(filePath string) *url.URL {
+	key := b.config.Prefix + RelPath(filePath)
+	objectUrl := *b.endpoint
+	basePath := strings.TrimRight(objectUrl.Path, "/")
+	if b.config.PathStyle {
+		objectUrl.RawPath = basePath + "/" + escapeS3Key(b.config.Bucket) + "/" + escapeS3Key(key)
+	} else {
+		objectUrl.Host = b.config.Bucket + "." + objectUrl.Host
+		objectUrl.RawPath = basePath + "/" + escapeS3Key(key)
+	}
+	objectUrl.Path, _ = url.PathUnescape(objectUrl.RawPath)
+	return &objectUrl
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Returns the query string of the URL with its parameters sorted and encoded as AWS Signature Version 4 expects,
+// e.g. "partNumber=1&uploadId=abc" for the URL "?uploadId=abc&partNumber=1"
+func getS3CanonicalQuery(reqUrl *url.URL) string {
+	query := reqUrl.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, s3QueryEscape(key)+"="+s3QueryEscape(value))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+func s3QueryEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// Signs the request with AWS Signature Version 4 using the hex-encoded SHA256 of the payload
+func (b *s3Backend) sign(req *http.Request, payloadHash string) {
+	req.URL.RawQuery = getS3CanonicalQuery(req.URL)
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		"host;x-amz-content-sha256;x-amz-date",
+		payloadHash,
+	}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, b.config.Region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+b.config.SecretAccessKey), date)
+	signingKey = hmacSha256(signingKey, b.config.Region)
+	signingKey = hmacSha256(signingKey, "s3")
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%s",
+			b.config.AccessKeyId,
+			scope,
+			hex.EncodeToString(hmacSha256(signingKey, stringToSign)),
+		),
+	)
+}
+
+// Sends the signed request and returns an error with the response body if the status code is not 2XX
+func (b *s3Backend) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	b.sign(req, payloadHash)
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		defer res.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return res, fmt.Errorf("S3 returned a %s response: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+	return res, nil
+}
+
+// Uploads the body of the given size to the object of the file
+func (b *s3Backend) putObject(ctx context.Context, filePath string, body io.Reader, size int64) error {
+	if size > s3MaxPutSize {
+		return b.putMultipartObject(ctx, filePath, body, size)
+	}
+
+	objectUrl := b.getObjectUrl(filePath)
+	req, err := http.NewRequestWithContext(ctx, "PUT", objectUrl.String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+
+	res, err := b.do(req, s3UnsignedPayload)
+	if err != nil {
+		return fmt.Errorf(
+			"storage error %d: failed to upload %s to S3, more info => %v",
+			utils.CONNECTION_ERROR,
+			objectUrl.String(),
+			err,
+		)
+	}
+	res.Body.Close()
+	return nil
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name           `xml:"CompleteMultipartUpload"`
+	Parts   []*s3CompletedPart `xml:"Part"`
+}
+
+// Returns the size of each part of a multipart upload of an object of the given size
+func getS3PartSize(size int64) int64 {
+	partSize := int64(s3MinPartSize)
+	if minPartSize := (size + s3MaxParts - 1) / s3MaxParts; minPartSize > partSize {
+		partSize = minPartSize
+	}
+	return partSize
+}
+
+// Sends the signed request to the URL of the object with the query and returns the response body
+func (b *s3Backend) doObjectRequest(ctx context.Context, method string, objectUrl *url.URL, query url.Values, body []byte) ([]byte, error) {
+	reqUrl := *objectUrl
+	reqUrl.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	payloadHash := sha256.Sum256(body)
+	res, err := b.do(req, hex.EncodeToString(payloadHash[:]))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+// Uploads the body of the given size to the object of the file in parts as S3 rejects
+// PUT requests larger than s3MaxPutSize, where each part is streamed from the body
+// without buffering it.
+//
+// The multipart upload is aborted if any part fails so that the uploaded parts are not kept in the bucket.
+func (b *s3Backend) putMultipartObject(ctx context.Context, filePath string, body io.Reader, size int64) (err error) {
+	objectUrl := b.getObjectUrl(filePath)
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf(
+				"storage error %d: failed to upload %s to S3, more info => %v",
+				utils.CONNECTION_ERROR,
+				objectUrl.String(),
+				err,
+			)
+		}
+	}()
+
+	resBody, err := b.doObjectRequest(ctx, "POST", objectUrl, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return err
+	}
+	var initiateResult s3InitiateMultipartUploadResult
+	if err := xml.Unmarshal(resBody, &initiateResult); err != nil || initiateResult.UploadId == "" {
+		return fmt.Errorf("S3 did not return the upload ID of the multipart upload: %s", strings.TrimSpace(string(resBody)))
+	}
+	uploadId := initiateResult.UploadId
+	defer func() {
+		if err != nil {
+			// the context may have been cancelled which is why the upload is aborted with a new one
+			abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			b.doObjectRequest(abortCtx, "DELETE", objectUrl, url.Values{"uploadId": {uploadId}}, nil)
+		}
+	}()
+
+	partSize := getS3PartSize(size)
+	completed := &s3CompleteMultipartUpload{}
+	for offset, partNum := int64(0), 1; offset < size; offset, partNum = offset+partSize, partNum+1 {
+		partLen := partSize
+		if remaining := size - offset; remaining < partLen {
+			partLen = remaining
+		}
+
+		partUrl := *objectUrl
+		partUrl.RawQuery = url.Values{
+			"partNumber": {strconv.Itoa(partNum)},
+			"uploadId":   {uploadId},
+		}.Encode()
+		req, err := http.NewRequestWithContext(ctx, "PUT", partUrl.String(), io.NopCloser(io.LimitReader(body, partLen)))
+		if err != nil {
+			return err
+		}
+		req.ContentLength = partLen
+
+		res, err := b.do(req, s3UnsignedPayload)
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d, more info => %v", partNum, err)
+		}
+		res.Body.Close()
+		completed.Parts = append(completed.Parts, &s3CompletedPart{
+			PartNumber: partNum,
+			ETag:       res.Header.Get("ETag"),
+		})
+	}
+
+	completeBody, err := xml.Marshal(completed)
+	if err != nil {
+		return err
+	}
+	resBody, err = b.doObjectRequest(ctx, "POST", objectUrl, url.Values{"uploadId": {uploadId}}, completeBody)
+	if err != nil {
+		return err
+	}
+
+	// S3 may respond with a 200 status code and an error in the body if completing the upload failed
+	if bytes.Contains(resBody, []byte("<Error>")) {
+		return fmt.Errorf("S3 failed to complete the multipart upload: %s", strings.TrimSpace(string(resBody)))
+	}
+	return nil
+}
+
+func (b *s3Backend) Create(ctx context.Context, filePath string, size int64) (File, error) {
+	if size < 0 {
+		// S3 needs the size of the object before uploading it
+		tmpFile, err := os.CreateTemp("", "cultured-downloader-*.part")
+		if err != nil {
+			return nil, err
+		}
+		return &spooledFile{
+			File: tmpFile,
+			upload: func(body io.Reader, size int64) error {
+				return b.putObject(ctx, filePath, body, size)
+			},
+		}, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	file := &streamedFile{pw: pw, cancel: cancel, done: make(chan error, 1)}
+	go func() {
+		err := b.putObject(ctx, filePath, pr, size)
+		pr.CloseWithError(err)
+		file.done <- err
+	}()
+	return file, nil
+}
+
+func (b *s3Backend) Size(ctx context.Context, filePath string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", b.getObjectUrl(filePath).String(), nil)
+	if err != nil {
+		return -1, err
+	}
+	res, err := b.do(req, s3EmptyPayload)
+	if res != nil && res.StatusCode == 404 {
+		return -1, os.ErrNotExist
+	}
+	if err != nil {
+		return -1, err
+	}
+	res.Body.Close()
+	return res.ContentLength, nil
+}
+
+func (b *s3Backend) Remove(ctx context.Context, filePath string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", b.getObjectUrl(filePath).String(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := b.do(req, s3EmptyPayload)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (b *s3Backend) ReadFile(ctx context.Context, filePath string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.getObjectUrl(filePath).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.do(req, s3EmptyPayload)
+	if res != nil && res.StatusCode == 404 {
+		return nil, os.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+var errUploadAborted = errors.New("upload aborted")
+
+// Streams the written data to an upload request that is running in the background
+type streamedFile struct {
+	pw     *io.PipeWriter
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func (f *streamedFile) Write(p []byte) (int, error) {
+	return f.pw.Write(p)
+}
+
+func (f *streamedFile) Close() error {
+	f.pw.Close()
+	err := <-f.done
+	f.cancel()
+	return err
+}
+
+func (f *streamedFile) Abort() {
+	// the request will fail as the body is shorter than its Content-Length
+	f.pw.CloseWithError(errUploadAborted)
+	f.cancel()
+	<-f.done
+}
+
+// Writes the data to a temporary file on the local disk which is uploaded once
+// it is closed, for files that cannot be streamed as their size is unknown
+type spooledFile struct {
+	*os.File
+	upload func(body io.Reader, size int64) error
+}
+
+func (f *spooledFile) Close() error {
+	defer os.Remove(f.Name())
+	defer f.File.Close()
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return f.upload(f.File, size)
+}
+
+func (f *spooledFile) Abort() {
+	f.File.Close()
+	os.Remove(f.Name())
+}
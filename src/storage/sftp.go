@@ -0,0 +1,389 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sftpDefaultPort = "22"
+	sftpDialTimeout = 30 * time.Second
+
+	// the suffix of the files being written which are renamed once they are complete
+	sftpPartialExt = ".part"
+)
+
+// Writes the files to a folder on an SSH server over SFTP
+type sftpBackend struct {
+	addr      string
+	path      string
+	sshConfig *ssh.ClientConfig
+
+	// the client is connected on first use and reconnected if the connection is lost
+	mu     sync.Mutex
+	client *sftpClient
+
+	// the folders that have been created or already exist
+	createdDirs sync.Map
+}
+
+// Returns the auth methods from the config where the key is tried before the password
+func getSftpAuthMethods(config *utils.SftpStorageConfig) ([]ssh.AuthMethod, error) {
+	var authMethods []ssh.AuthMethod
+	if config.KeyFile != "" {
+		keyData, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"storage error %d: failed to read the SFTP key file %s, more info => %v",
+				utils.OS_ERROR,
+				config.KeyFile,
+				err,
+			)
+		}
+
+		var signer ssh.Signer
+		if config.KeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(config.KeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(keyData)
+		}
+		if err != nil {
+			return nil, fmt.Errorf(
+				"storage error %d: failed to parse the SFTP key file %s, more info => %v",
+				utils.INPUT_ERROR,
+				config.KeyFile,
+				err,
+			)
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+	if config.Password != "" {
+		authMethods = append(authMethods, ssh.Password(config.Password))
+	}
+
+	if len(authMethods) == 0 {
+		return nil, fmt.Errorf(
+			"storage error %d: either the key_file or password must be set in the sftp section of the storage config",
+			utils.INPUT_ERROR,
+		)
+	}
+	return authMethods, nil
+}
+
+// Returns the callback to verify the server's host key with the known_hosts file
+func getSftpHostKeyCallback(config *utils.SftpStorageConfig) (ssh.HostKeyCallback, error) {
+	knownHostsFile := config.KnownHostsFile
+	if knownHostsFile == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"storage error %d: failed to get the home folder for the known_hosts file, more info => %v",
+				utils.OS_ERROR,
+				err,
+			)
+		}
+		knownHostsFile = filepath.Join(homeDir, ".ssh", "known_hosts")
+	}
+
+	callback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"storage error %d: failed to read the known_hosts file %s to verify the SFTP server, "+
+				"please connect to the server with ssh once or add its host key with ssh-keyscan, more info => %v",
+			utils.INPUT_ERROR,
+			knownHostsFile,
+			err,
+		)
+	}
+	return callback, nil
+}
+
+func newSftpBackend(config *utils.SftpStorageConfig) (*sftpBackend, error) {
+	if config == nil || config.Host == "" || config.User == "" {
+		return nil, fmt.Errorf(
+			"storage error %d: the host and user must be set in the sftp section of the storage config",
+			utils.INPUT_ERROR,
+		)
+	}
+
+	addr := config.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, sftpDefaultPort)
+	}
+	authMethods, err := getSftpAuthMethods(config)
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := getSftpHostKeyCallback(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sftpBackend{
+		addr: addr,
+		path: config.Path,
+		sshConfig: &ssh.ClientConfig{
+			User:            config.User,
+			Auth:            authMethods,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         sftpDialTimeout,
+		},
+	}, nil
+}
+
+func (b *sftpBackend) Name() string {
+	return "SFTP"
+}
+
+// Returns the connected client or connects to the server if it is not connected
+func (b *sftpBackend) getClient() (*sftpClient, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.client != nil {
+		if b.client.closedErr() == nil {
+			return b.client, nil
+		}
+		b.client.Close()
+		b.client = nil
+	}
+
+	sshClient, err := ssh.Dial("tcp", b.addr, b.sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"storage error %d: failed to connect to the SFTP server %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			b.addr,
+			err,
+		)
+	}
+	client, err := newSftpClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf(
+			"storage error %d: failed to start SFTP on %s, more info => %v",
+			utils.CONNECTION_ERROR,
+			b.addr,
+			err,
+		)
+	}
+	b.client = client
+	return client, nil
+}
+
+// Returns the path of the file on the server
+func (b *sftpBackend) getRemotePath(filePath string) string {
+	if b.path == "" {
+		return RelPath(filePath)
+	}
+	return path.Join(b.path, RelPath(filePath))
+}
+
+// Creates the parent folders of the remote path that have not been created yet
+func (b *sftpBackend) mkdirAll(ctx context.Context, client *sftpClient, remotePath string) error {
+	dir := path.Dir(remotePath)
+	if dir == "." || dir == "/" {
+		return nil
+	}
+
+	curDir := ""
+	if strings.HasPrefix(dir, "/") {
+		curDir = "/"
+	}
+	for _, part := range strings.Split(strings.TrimPrefix(dir, "/"), "/") {
+		curDir = path.Join(curDir, part)
+		if _, ok := b.createdDirs.Load(curDir); ok {
+			continue
+		}
+
+		// most servers return a generic failure if the folder already exists
+		if err := client.Mkdir(ctx, curDir); err != nil {
+			if _, statErr := client.Stat(ctx, curDir); statErr != nil {
+				return fmt.Errorf(
+					"storage error %d: failed to create the SFTP folder %s, more info => %v",
+					utils.CONNECTION_ERROR,
+					curDir,
+					err,
+				)
+			}
+		}
+		b.createdDirs.Store(curDir, struct{}{})
+	}
+	return nil
+}
+
+func (b *sftpBackend) Create(ctx context.Context, filePath string, size int64) (File, error) {
+	client, err := b.getClient()
+	if err != nil {
+		return nil, err
+	}
+	remotePath := b.getRemotePath(filePath)
+	if err := b.mkdirAll(ctx, client, remotePath); err != nil {
+		return nil, err
+	}
+
+	partPath := remotePath + sftpPartialExt
+	handle, err := client.Create(ctx, partPath)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"storage error %d: failed to create %s on the SFTP server, more info => %v",
+			utils.CONNECTION_ERROR,
+			partPath,
+			err,
+		)
+	}
+	return &sftpFile{
+		client:     client,
+		ctx:        ctx,
+		handle:     handle,
+		remotePath: remotePath,
+		partPath:   partPath,
+	}, nil
+}
+
+func (b *sftpBackend) Size(ctx context.Context, filePath string) (int64, error) {
+	client, err := b.getClient()
+	if err != nil {
+		return -1, err
+	}
+	return client.Stat(ctx, b.getRemotePath(filePath))
+}
+
+func (b *sftpBackend) Remove(ctx context.Context, filePath string) error {
+	client, err := b.getClient()
+	if err != nil {
+		return err
+	}
+	return client.Remove(ctx, b.getRemotePath(filePath))
+}
+
+func (b *sftpBackend) ReadFile(ctx context.Context, filePath string) ([]byte, error) {
+	client, err := b.getClient()
+	if err != nil {
+		return nil, err
+	}
+	handle, err := client.Open(ctx, b.getRemotePath(filePath))
+	if err != nil {
+		return nil, err
+	}
+	defer client.CloseHandle(ctx, handle)
+
+	var data []byte
+	for {
+		chunk, err := client.ReadAt(ctx, handle, int64(len(data)), sftpMaxReadSize)
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+	}
+}
+
+// Writes to a partial file on the server which is renamed once it is closed.
+//
+// The writes are sent without waiting for their responses, up to sftpMaxInflightWrites,
+// so that the upload is not slowed down by the latency to the server.
+type sftpFile struct {
+	client     *sftpClient
+	ctx        context.Context
+	handle     string
+	remotePath string
+	partPath   string
+
+	offset   int64
+	inflight []<-chan sftpPacket
+	err      error // the error of the first failed write, if any
+}
+
+// Waits for the responses of the oldest writes until there are at most n writes waiting
+func (f *sftpFile) waitInflight(n int) error {
+	for len(f.inflight) > n {
+		res, err := f.client.wait(f.ctx, f.inflight[0])
+		f.inflight = f.inflight[1:]
+		if err == nil {
+			err = checkSftpStatus(res)
+		}
+		if err != nil && f.err == nil {
+			f.err = fmt.Errorf(
+				"storage error %d: failed to write to %s on the SFTP server, more info => %v",
+				utils.CONNECTION_ERROR,
+				f.partPath,
+				err,
+			)
+		}
+	}
+	return f.err
+}
+
+func (f *sftpFile) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if err := f.waitInflight(sftpMaxInflightWrites - 1); err != nil {
+			return written, err
+		}
+
+		chunkSize := len(p)
+		if chunkSize > sftpMaxWriteSize {
+			chunkSize = sftpMaxWriteSize
+		}
+		resCh, err := f.client.WriteAt(f.handle, f.offset, p[:chunkSize])
+		if err != nil {
+			f.err = err
+			return written, err
+		}
+		f.inflight = append(f.inflight, resCh)
+		f.offset += int64(chunkSize)
+		written += chunkSize
+		p = p[chunkSize:]
+	}
+	return written, nil
+}
+
+func (f *sftpFile) Close() error {
+	if err := f.waitInflight(0); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.client.CloseHandle(f.ctx, f.handle); err != nil {
+		f.Abort()
+		return fmt.Errorf(
+			"storage error %d: failed to close %s on the SFTP server, more info => %v",
+			utils.CONNECTION_ERROR,
+			f.partPath,
+			err,
+		)
+	}
+	if err := f.client.Rename(f.ctx, f.partPath, f.remotePath); err != nil {
+		f.Abort()
+		return fmt.Errorf(
+			"storage error %d: failed to rename %s to %s on the SFTP server, more info => %v",
+			utils.CONNECTION_ERROR,
+			f.partPath,
+			f.remotePath,
+			err,
+		)
+	}
+	return nil
+}
+
+func (f *sftpFile) Abort() {
+	// the context may have been cancelled which is why the partial file is removed with a new one
+	ctx, cancel := context.WithTimeout(context.Background(), sftpDialTimeout)
+	defer cancel()
+	f.client.CloseHandle(ctx, f.handle)
+	f.client.Remove(ctx, f.partPath)
+}
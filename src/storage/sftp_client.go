@@ -0,0 +1,472 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Packet types and flags of version 3 of the SFTP protocol which is supported by all SFTP servers
+// (https://datatracker.ietf.org/doc/html/draft-ietf-secsh-filexfer-02)
+const (
+	sftpProtocolVersion = 3
+
+	sftpPacketInit    = 1
+	sftpPacketVersion = 2
+	sftpPacketOpen    = 3
+	sftpPacketClose   = 4
+	sftpPacketRead    = 5
+	sftpPacketWrite   = 6
+	sftpPacketRemove  = 13
+	sftpPacketMkdir   = 14
+	sftpPacketStat    = 17
+	sftpPacketRename  = 18
+	sftpPacketExt     = 200
+	sftpPacketStatus  = 101
+	sftpPacketHandle  = 102
+	sftpPacketData    = 103
+	sftpPacketAttrs   = 105
+
+	sftpOpenRead   = 0x01
+	sftpOpenWrite  = 0x02
+	sftpOpenCreate = 0x08
+	sftpOpenTrunc  = 0x10
+
+	sftpAttrSize = 0x01
+
+	sftpStatusOk         = 0
+	sftpStatusEof        = 1
+	sftpStatusNoSuchFile = 2
+
+	// the maximum data in a write request that all servers must accept
+	sftpMaxWriteSize = 32 * 1024
+
+	// the maximum data in a read request that all servers must return
+	sftpMaxReadSize = 32 * 1024
+
+	// the maximum number of write requests of a file that are waiting for a response
+	sftpMaxInflightWrites = 64
+
+	// OpenSSH's extension to rename a file over an existing one
+	sftpPosixRenameExt = "posix-rename@openssh.com"
+)
+
+// The status response of a failed request
+type sftpStatusError struct {
+	code uint32
+	msg  string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("SFTP server returned status %d: %s", e.code, e.msg)
+}
+
+func (e *sftpStatusError) Is(target error) bool {
+	return target == os.ErrNotExist && e.code == sftpStatusNoSuchFile
+}
+
+type sftpPacket struct {
+	packetType byte
+	data       []byte // the data after the request ID
+}
+
+// Builds the payload of a request
+type sftpPayload []byte
+
+func (p sftpPayload) uint32(v uint32) sftpPayload {
+	return binary.BigEndian.AppendUint32(p, v)
+}
+
+func (p sftpPayload) uint64(v uint64) sftpPayload {
+	return binary.BigEndian.AppendUint64(p, v)
+}
+
+func (p sftpPayload) string(s string) sftpPayload {
+	return append(p.uint32(uint32(len(s))), s...)
+}
+
+func (p sftpPayload) bytes(b []byte) sftpPayload {
+	return append(p.uint32(uint32(len(b))), b...)
+}
+
+// Reads the length-prefixed string at the start of the data and returns the rest of the data
+func readSftpString(data []byte) (string, []byte, error) {
+	if len(data) < 4 {
+		return "", nil, errors.New("SFTP packet is too short")
+	}
+	strLen := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint32(len(data)) < strLen {
+		return "", nil, errors.New("SFTP packet is too short")
+	}
+	return string(data[:strLen]), data[strLen:], nil
+}
+
+// A minimal SFTP client over an SSH session that only supports what the backend needs.
+//
+// The requests can be sent from multiple goroutines at the same time
+// as the responses are matched to their requests by their ID.
+type sftpClient struct {
+	closeConn func() error      // closes the connection that the SFTP subsystem runs on
+	exts      map[string]string // the extensions that the server supports
+
+	writeMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex
+	nextId  uint32
+	pending map[uint32]chan sftpPacket
+	err     error // set once the connection is closed
+}
+
+// Starts the SFTP subsystem on the SSH connection
+func newSftpClient(sshClient *ssh.Client) (*sftpClient, error) {
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	client, err := startSftpClient(stdin, stdout, func() error {
+		session.Close()
+		return sshClient.Close()
+	})
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Starts the SFTP client on the input and output of the SFTP subsystem
+func startSftpClient(stdin io.WriteCloser, stdout io.Reader, closeConn func() error) (*sftpClient, error) {
+	client := &sftpClient{
+		closeConn: closeConn,
+		exts:      make(map[string]string),
+		stdin:     stdin,
+		pending:   make(map[uint32]chan sftpPacket),
+	}
+	if err := client.init(stdout); err != nil {
+		return nil, err
+	}
+	go client.readLoop(stdout)
+	return client, nil
+}
+
+// Writes a packet of the given type with the payload
+func (c *sftpClient) writePacket(packetType byte, payload []byte) error {
+	header := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	header = append(header, packetType)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.stdin.Write(header); err != nil {
+		return err
+	}
+	_, err := c.stdin.Write(payload)
+	return err
+}
+
+// Reads a packet and returns its type and the data after the type
+func readSftpPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > 1024*1024 {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], data, nil
+}
+
+// Negotiates the protocol version and reads the extensions that the server supports
+func (c *sftpClient) init(stdout io.Reader) error {
+	if err := c.writePacket(sftpPacketInit, sftpPayload{}.uint32(sftpProtocolVersion)); err != nil {
+		return err
+	}
+	packetType, data, err := readSftpPacket(stdout)
+	if err != nil {
+		return err
+	}
+	if packetType != sftpPacketVersion || len(data) < 4 {
+		return fmt.Errorf("SFTP server responded with packet type %d instead of its version", packetType)
+	}
+	if version := binary.BigEndian.Uint32(data); version != sftpProtocolVersion {
+		return fmt.Errorf("SFTP server only supports protocol version %d", version)
+	}
+
+	data = data[4:]
+	for len(data) > 0 {
+		var name, value string
+		if name, data, err = readSftpString(data); err != nil {
+			return err
+		}
+		if value, data, err = readSftpString(data); err != nil {
+			return err
+		}
+		c.exts[name] = value
+	}
+	return nil
+}
+
+// Passes the responses to the goroutines waiting for them until the connection is closed
+func (c *sftpClient) readLoop(stdout io.Reader) {
+	for {
+		packetType, data, err := readSftpPacket(stdout)
+		if err == nil && len(data) < 4 {
+			err = errors.New("SFTP response is missing its request ID")
+		}
+		if err != nil {
+			c.closeWithError(err)
+			return
+		}
+
+		id := binary.BigEndian.Uint32(data)
+		c.mu.Lock()
+		resCh, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ok {
+			resCh <- sftpPacket{packetType: packetType, data: data[4:]}
+		}
+	}
+}
+
+// Marks the client as closed and fails the requests waiting for a response
+func (c *sftpClient) closeWithError(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = fmt.Errorf("SFTP connection closed: %v", err)
+	}
+	pending := c.pending
+	c.pending = make(map[uint32]chan sftpPacket)
+	c.mu.Unlock()
+
+	for _, resCh := range pending {
+		close(resCh)
+	}
+}
+
+// Returns the error that closed the connection or nil if it is still open
+func (c *sftpClient) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *sftpClient) Close() error {
+	c.closeWithError(errors.New("closed by the client"))
+	return c.closeConn()
+}
+
+// Sends the request and returns the channel that will receive its response.
+//
+// The channel is closed without a response if the connection is closed.
+func (c *sftpClient) send(packetType byte, payload func(sftpPayload) sftpPayload) (<-chan sftpPacket, error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextId++
+	id := c.nextId
+	resCh := make(chan sftpPacket, 1)
+	c.pending[id] = resCh
+	c.mu.Unlock()
+
+	if err := c.writePacket(packetType, payload(sftpPayload{}.uint32(id))); err != nil {
+		c.closeWithError(err)
+		return nil, err
+	}
+	return resCh, nil
+}
+
+// Waits for the response of a request
+func (c *sftpClient) wait(ctx context.Context, resCh <-chan sftpPacket) (sftpPacket, error) {
+	select {
+	case res, ok := <-resCh:
+		if !ok {
+			return sftpPacket{}, c.closedErr()
+		}
+		return res, nil
+	case <-ctx.Done():
+		return sftpPacket{}, ctx.Err()
+	}
+}
+
+// Returns nil if the response is an OK status or the error of the status otherwise
+func checkSftpStatus(res sftpPacket) error {
+	if res.packetType != sftpPacketStatus || len(res.data) < 4 {
+		return fmt.Errorf("SFTP server responded with packet type %d instead of a status", res.packetType)
+	}
+	code := binary.BigEndian.Uint32(res.data)
+	if code == sftpStatusOk {
+		return nil
+	}
+	msg, _, _ := readSftpString(res.data[4:])
+	return &sftpStatusError{code: code, msg: msg}
+}
+
+// Sends the request and waits for its status response
+func (c *sftpClient) doStatus(ctx context.Context, packetType byte, payload func(sftpPayload) sftpPayload) error {
+	resCh, err := c.send(packetType, payload)
+	if err != nil {
+		return err
+	}
+	res, err := c.wait(ctx, resCh)
+	if err != nil {
+		return err
+	}
+	return checkSftpStatus(res)
+}
+
+// Returns the size of the file at the remote path
+func (c *sftpClient) Stat(ctx context.Context, remotePath string) (int64, error) {
+	resCh, err := c.send(sftpPacketStat, func(p sftpPayload) sftpPayload {
+		return p.string(remotePath)
+	})
+	if err != nil {
+		return -1, err
+	}
+	res, err := c.wait(ctx, resCh)
+	if err != nil {
+		return -1, err
+	}
+	if res.packetType != sftpPacketAttrs {
+		if err := checkSftpStatus(res); err != nil {
+			return -1, err
+		}
+		return -1, errors.New("SFTP server did not return the attributes of the file")
+	}
+
+	// the size is the first attribute after the flags
+	if len(res.data) < 4 || binary.BigEndian.Uint32(res.data)&sftpAttrSize == 0 || len(res.data) < 12 {
+		return -1, errors.New("SFTP server did not return the size of the file")
+	}
+	return int64(binary.BigEndian.Uint64(res.data[4:])), nil
+}
+
+func (c *sftpClient) Mkdir(ctx context.Context, remotePath string) error {
+	return c.doStatus(ctx, sftpPacketMkdir, func(p sftpPayload) sftpPayload {
+		return p.string(remotePath).uint32(0) // no attributes
+	})
+}
+
+func (c *sftpClient) Remove(ctx context.Context, remotePath string) error {
+	return c.doStatus(ctx, sftpPacketRemove, func(p sftpPayload) sftpPayload {
+		return p.string(remotePath)
+	})
+}
+
+// Renames the file, replacing the file at the new path if the server supports it.
+//
+// Otherwise, the file at the new path is removed first as the rename of version 3 fails if it exists.
+func (c *sftpClient) Rename(ctx context.Context, oldPath, newPath string) error {
+	if _, ok := c.exts[sftpPosixRenameExt]; ok {
+		return c.doStatus(ctx, sftpPacketExt, func(p sftpPayload) sftpPayload {
+			return p.string(sftpPosixRenameExt).string(oldPath).string(newPath)
+		})
+	}
+
+	if err := c.Remove(ctx, newPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return c.doStatus(ctx, sftpPacketRename, func(p sftpPayload) sftpPayload {
+		return p.string(oldPath).string(newPath)
+	})
+}
+
+// Creates or truncates the file at the remote path and returns its handle
+func (c *sftpClient) Create(ctx context.Context, remotePath string) (string, error) {
+	return c.open(ctx, remotePath, sftpOpenWrite|sftpOpenCreate|sftpOpenTrunc)
+}
+
+// Opens the file at the remote path for reading and returns its handle
+func (c *sftpClient) Open(ctx context.Context, remotePath string) (string, error) {
+	return c.open(ctx, remotePath, sftpOpenRead)
+}
+
+func (c *sftpClient) open(ctx context.Context, remotePath string, flags uint32) (string, error) {
+	resCh, err := c.send(sftpPacketOpen, func(p sftpPayload) sftpPayload {
+		return p.string(remotePath).uint32(flags).uint32(0) // no attributes
+	})
+	if err != nil {
+		return "", err
+	}
+	res, err := c.wait(ctx, resCh)
+	if err != nil {
+		return "", err
+	}
+	if res.packetType != sftpPacketHandle {
+		if err := checkSftpStatus(res); err != nil {
+			return "", err
+		}
+		return "", errors.New("SFTP server did not return a file handle")
+	}
+	handle, _, err := readSftpString(res.data)
+	return handle, err
+}
+
+// Reads up to length bytes of the file at the offset and returns io.EOF if the offset is at the end of the file
+func (c *sftpClient) ReadAt(ctx context.Context, handle string, offset int64, length uint32) ([]byte, error) {
+	resCh, err := c.send(sftpPacketRead, func(p sftpPayload) sftpPayload {
+		return p.string(handle).uint64(uint64(offset)).uint32(length)
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.wait(ctx, resCh)
+	if err != nil {
+		return nil, err
+	}
+	if res.packetType != sftpPacketData {
+		err := checkSftpStatus(res)
+		var statusErr *sftpStatusError
+		if errors.As(err, &statusErr) && statusErr.code == sftpStatusEof {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("SFTP server did not return the data of the file")
+	}
+	data, _, err := readSftpString(res.data)
+	return []byte(data), err
+}
+
+// Sends a write request without waiting for its response
+func (c *sftpClient) WriteAt(handle string, offset int64, data []byte) (<-chan sftpPacket, error) {
+	return c.send(sftpPacketWrite, func(p sftpPayload) sftpPayload {
+		return p.string(handle).uint64(uint64(offset)).bytes(data)
+	})
+}
+
+func (c *sftpClient) CloseHandle(ctx context.Context, handle string) error {
+	return c.doStatus(ctx, sftpPacketClose, func(p sftpPayload) sftpPayload {
+		return p.string(handle)
+	})
+}
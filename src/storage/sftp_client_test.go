@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// An in-memory SFTP server of version 3 that serves the requests sent by the client under test
+type fakeSftpServer struct {
+	exts map[string]string // the extensions sent in the version packet
+
+	mu      sync.Mutex
+	files   map[string][]byte
+	dirs    map[string]bool
+	handles map[string]string // the handles of the open files to their paths
+	nextId  int
+}
+
+func newFakeSftpServer(exts map[string]string) *fakeSftpServer {
+	return &fakeSftpServer{
+		exts:    exts,
+		files:   make(map[string][]byte),
+		dirs:    make(map[string]bool),
+		handles: make(map[string]string),
+	}
+}
+
+// Starts a client connected to the server with pipes and returns it with a function to close the server's side
+func (s *fakeSftpServer) start(t *testing.T) (*sftpClient, func()) {
+	t.Helper()
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	closeServer := func() {
+		serverOut.Close()
+		serverIn.Close()
+	}
+	go s.serve(serverIn, serverOut)
+
+	client, err := startSftpClient(clientOut, clientIn, func() error {
+		closeServer()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("startSftpClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, closeServer
+}
+
+func (s *fakeSftpServer) serve(in io.Reader, out io.Writer) {
+	for {
+		packetType, data, err := readSftpPacket(in)
+		if err != nil {
+			return
+		}
+		if packetType == sftpPacketInit {
+			payload := sftpPayload{}.uint32(sftpProtocolVersion)
+			for name, value := range s.exts {
+				payload = payload.string(name).string(value)
+			}
+			writeFakeSftpPacket(out, sftpPacketVersion, payload)
+			continue
+		}
+
+		id := binary.BigEndian.Uint32(data)
+		resType, payload := s.handle(packetType, data[4:])
+		writeFakeSftpPacket(out, resType, append(sftpPayload{}.uint32(id), payload...))
+	}
+}
+
+func writeFakeSftpPacket(out io.Writer, packetType byte, payload []byte) {
+	header := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	out.Write(append(append(header, packetType), payload...))
+}
+
+func fakeSftpStatus(code uint32) (byte, sftpPayload) {
+	return sftpPacketStatus, sftpPayload{}.uint32(code).string("status " + strconv.Itoa(int(code))).string("")
+}
+
+// Returns the response of the request whose data is after the request ID
+func (s *fakeSftpServer) handle(packetType byte, data []byte) (byte, sftpPayload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	const statusFailure = 4
+	readString := func() string {
+		str, rest, _ := readSftpString(data)
+		data = rest
+		return str
+	}
+	switch packetType {
+	case sftpPacketOpen:
+		filePath := readString()
+		flags := binary.BigEndian.Uint32(data)
+		if _, ok := s.files[filePath]; !ok {
+			if flags&sftpOpenCreate == 0 {
+				return fakeSftpStatus(sftpStatusNoSuchFile)
+			}
+			s.files[filePath] = nil
+		}
+		if flags&sftpOpenTrunc != 0 {
+			s.files[filePath] = nil
+		}
+		s.nextId++
+		handle := "h" + strconv.Itoa(s.nextId)
+		s.handles[handle] = filePath
+		return sftpPacketHandle, sftpPayload{}.string(handle)
+	case sftpPacketClose:
+		delete(s.handles, readString())
+		return fakeSftpStatus(sftpStatusOk)
+	case sftpPacketRead:
+		filePath := s.handles[readString()]
+		offset := binary.BigEndian.Uint64(data)
+		length := binary.BigEndian.Uint32(data[8:])
+		contents := s.files[filePath]
+		if offset >= uint64(len(contents)) {
+			return fakeSftpStatus(sftpStatusEof)
+		}
+		end := offset + uint64(length)
+		if end > uint64(len(contents)) {
+			end = uint64(len(contents))
+		}
+		return sftpPacketData, sftpPayload{}.bytes(contents[offset:end])
+	case sftpPacketWrite:
+		filePath := s.handles[readString()]
+		offset := binary.BigEndian.Uint64(data)
+		chunk, _, _ := readSftpString(data[8:])
+		contents := s.files[filePath]
+		if end := int(offset) + len(chunk); end > len(contents) {
+			contents = append(contents, make([]byte, end-len(contents))...)
+		}
+		copy(contents[offset:], chunk)
+		s.files[filePath] = contents
+		return fakeSftpStatus(sftpStatusOk)
+	case sftpPacketStat:
+		filePath := readString()
+		contents, ok := s.files[filePath]
+		if !ok {
+			if s.dirs[filePath] {
+				return sftpPacketAttrs, sftpPayload{}.uint32(0)
+			}
+			return fakeSftpStatus(sftpStatusNoSuchFile)
+		}
+		return sftpPacketAttrs, sftpPayload{}.uint32(sftpAttrSize).uint64(uint64(len(contents)))
+	case sftpPacketMkdir:
+		dirPath := readString()
+		if s.dirs[dirPath] {
+			return fakeSftpStatus(statusFailure)
+		}
+		s.dirs[dirPath] = true
+		return fakeSftpStatus(sftpStatusOk)
+	case sftpPacketRemove:
+		filePath := readString()
+		if _, ok := s.files[filePath]; !ok {
+			return fakeSftpStatus(sftpStatusNoSuchFile)
+		}
+		delete(s.files, filePath)
+		return fakeSftpStatus(sftpStatusOk)
+	case sftpPacketRename, sftpPacketExt:
+		isPosixRename := packetType == sftpPacketExt
+		if isPosixRename && readString() != sftpPosixRenameExt {
+			return fakeSftpStatus(statusFailure)
+		}
+		oldPath, newPath := readString(), readString()
+		if _, ok := s.files[oldPath]; !ok {
+			return fakeSftpStatus(sftpStatusNoSuchFile)
+		}
+		if _, ok := s.files[newPath]; ok && !isPosixRename {
+			// version 3 of the protocol fails if the new path exists
+			return fakeSftpStatus(statusFailure)
+		}
+		s.files[newPath] = s.files[oldPath]
+		delete(s.files, oldPath)
+		return fakeSftpStatus(sftpStatusOk)
+	default:
+		return fakeSftpStatus(statusFailure)
+	}
+}
+
+func TestSftpClientInit(t *testing.T) {
+	tests := []struct {
+		name string
+		exts map[string]string
+	}{
+		{name: "no extensions", exts: map[string]string{}},
+		{name: "posix rename", exts: map[string]string{sftpPosixRenameExt: "1"}},
+		{name: "multiple extensions", exts: map[string]string{sftpPosixRenameExt: "1", "statvfs@openssh.com": "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newFakeSftpServer(tt.exts).start(t)
+			if len(client.exts) != len(tt.exts) {
+				t.Fatalf("exts = %v, want %v", client.exts, tt.exts)
+			}
+			for name, value := range tt.exts {
+				if client.exts[name] != value {
+					t.Errorf("exts[%q] = %q, want %q", name, client.exts[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestSftpClientWriteAndRead(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "empty file", size: 0},
+		{name: "single write", size: 100},
+		{name: "multiple writes", size: sftpMaxWriteSize*3 + 7},
+		{name: "more writes than the inflight limit", size: sftpMaxWriteSize * (sftpMaxInflightWrites + 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSftpServer(nil)
+			client, _ := server.start(t)
+			ctx := context.Background()
+
+			contents := bytes.Repeat([]byte("0123456789"), tt.size/10+1)[:tt.size]
+			handle, err := client.Create(ctx, "/dl/file.bin.part")
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			file := &sftpFile{client: client, ctx: ctx, handle: handle, remotePath: "/dl/file.bin", partPath: "/dl/file.bin.part"}
+			if _, err := file.Write(contents); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := file.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if _, err := client.Stat(ctx, file.partPath); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Stat() of the partial file error = %v, want os.ErrNotExist", err)
+			}
+			size, err := client.Stat(ctx, file.remotePath)
+			if err != nil || size != int64(tt.size) {
+				t.Errorf("Stat() = %d, %v, want %d, nil", size, err, tt.size)
+			}
+
+			readHandle, err := client.Open(ctx, file.remotePath)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			var got []byte
+			for {
+				chunk, err := client.ReadAt(ctx, readHandle, int64(len(got)), sftpMaxReadSize)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("ReadAt() error = %v", err)
+				}
+				got = append(got, chunk...)
+			}
+			if !bytes.Equal(got, contents) {
+				t.Errorf("read %d bytes that do not match the %d bytes written", len(got), len(contents))
+			}
+		})
+	}
+}
+
+func TestSftpClientRename(t *testing.T) {
+	tests := []struct {
+		name     string
+		exts     map[string]string
+		existing bool
+	}{
+		{name: "posix rename over an existing file", exts: map[string]string{sftpPosixRenameExt: "1"}, existing: true},
+		{name: "rename over an existing file", existing: true},
+		{name: "rename to a new path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSftpServer(tt.exts)
+			server.files["/old"] = []byte("new contents")
+			if tt.existing {
+				server.files["/new"] = []byte("old contents")
+			}
+			client, _ := server.start(t)
+
+			if err := client.Rename(context.Background(), "/old", "/new"); err != nil {
+				t.Fatalf("Rename() error = %v", err)
+			}
+			if _, ok := server.files["/old"]; ok {
+				t.Error("the old path still exists")
+			}
+			if got := string(server.files["/new"]); got != "new contents" {
+				t.Errorf("contents of the new path = %q, want %q", got, "new contents")
+			}
+		})
+	}
+}
+
+func TestSftpClientStatusErrors(t *testing.T) {
+	server := newFakeSftpServer(nil)
+	client, _ := server.start(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "stat", call: func() error { _, err := client.Stat(ctx, "/missing"); return err }},
+		{name: "open", call: func() error { _, err := client.Open(ctx, "/missing"); return err }},
+		{name: "remove", call: func() error { return client.Remove(ctx, "/missing") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var statusErr *sftpStatusError
+			if !errors.As(err, &statusErr) || !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("error = %v, want a status error matching os.ErrNotExist", err)
+			}
+		})
+	}
+}
+
+func TestSftpClientConnectionClosed(t *testing.T) {
+	client, closeServer := newFakeSftpServer(nil).start(t)
+	closeServer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Stat(ctx, "/file"); err == nil || ctx.Err() != nil {
+		t.Fatalf("Stat() error = %v, want the connection to be closed", err)
+	}
+	if client.closedErr() == nil {
+		t.Error("closedErr() = nil, want the error that closed the connection")
+	}
+	if _, err := client.send(sftpPacketStat, func(p sftpPayload) sftpPayload { return p.string("/file") }); err == nil {
+		t.Error("send() error = nil after the connection was closed")
+	}
+}
+
+func TestReadSftpPacket(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   []byte
+		wantType byte
+		wantData []byte
+		wantErr  bool
+	}{
+		{name: "valid packet", packet: []byte{0, 0, 0, 3, sftpPacketStatus, 1, 2}, wantType: sftpPacketStatus, wantData: []byte{1, 2}},
+		{name: "zero length", packet: []byte{0, 0, 0, 0, 0}, wantErr: true},
+		{name: "too long", packet: []byte{0, 0x20, 0, 0, 0}, wantErr: true},
+		{name: "truncated data", packet: []byte{0, 0, 0, 5, sftpPacketStatus, 1}, wantErr: true},
+		{name: "truncated header", packet: []byte{0, 0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packetType, data, err := readSftpPacket(bytes.NewReader(tt.packet))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readSftpPacket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (packetType != tt.wantType || !bytes.Equal(data, tt.wantData)) {
+				t.Errorf("readSftpPacket() = %d, %v, want %d, %v", packetType, data, tt.wantType, tt.wantData)
+			}
+		})
+	}
+}
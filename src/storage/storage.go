@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// File is a file being written to a storage backend which is only saved once Close returns nil
+type File interface {
+	io.Writer
+
+	// Close finishes writing the file
+	Close() error
+
+	// Abort discards the file, e.g. when its download has failed
+	Abort()
+}
+
+// Backend is where the downloaded files are written to.
+//
+// The file paths given to the methods are the paths that the files would have on the local disk,
+// i.e. in the download directory, which remote backends convert to their own paths using RelPath.
+type Backend interface {
+	// Name is the name of the backend shown to the user, e.g. "S3"
+	Name() string
+
+	// Create starts writing the file where size is -1 if it is unknown
+	Create(ctx context.Context, filePath string, size int64) (File, error)
+
+	// Size returns the size of the file or os.ErrNotExist if it does not exist
+	Size(ctx context.Context, filePath string) (int64, error)
+
+	// Remove deletes the file
+	Remove(ctx context.Context, filePath string) error
+
+	// ReadFile returns the contents of the file or os.ErrNotExist if it does not exist
+	ReadFile(ctx context.Context, filePath string) ([]byte, error)
+}
+
+var backend Backend = &localBackend{}
+
+// Sets the backend that the downloaded files are written to
+func SetBackend(b Backend) {
+	backend = b
+}
+
+// Returns the backend that the downloaded files are written to
+func GetBackend() Backend {
+	return backend
+}
+
+// Returns true if the downloaded files are written to the local disk.
+//
+// Features that read the downloaded files afterwards, e.g. converting Pixiv ugoira,
+// are only available for the local disk.
+func IsLocal() bool {
+	_, ok := backend.(*localBackend)
+	return ok
+}
+
+// Returns the backend from the storage section of the config file which is the local disk if it is nil
+func NewBackend(config *utils.StorageConfig) (Backend, error) {
+	if config == nil {
+		return &localBackend{}, nil
+	}
+
+	switch config.Type {
+	case "", utils.STORAGE_LOCAL:
+		return &localBackend{}, nil
+	case utils.STORAGE_S3:
+		return newS3Backend(config.S3)
+	case utils.STORAGE_WEBDAV:
+		return newWebdavBackend(config.Webdav)
+	case utils.STORAGE_SFTP:
+		return newSftpBackend(config.Sftp)
+	default:
+		return nil, fmt.Errorf(
+			"storage error %d: unknown storage type %q, must be one of %s",
+			utils.INPUT_ERROR,
+			config.Type,
+			strings.Join(SUPPORTED_TYPES, ", "),
+		)
+	}
+}
+
+// The types of storage backends in the storage section of the config file
+var SUPPORTED_TYPES = []string{utils.STORAGE_LOCAL, utils.STORAGE_S3, utils.STORAGE_WEBDAV, utils.STORAGE_SFTP}
+
+// Returns the file path relative to the download directory with forward slashes, e.g. "Fantia/Creator/[1] Post/image.png"
+//
+// Files outside of the download directory are placed at the root by their filename.
+func RelPath(filePath string) string {
+	relPath, err := filepath.Rel(utils.DOWNLOAD_PATH, filePath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		relPath = filepath.Base(filePath)
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// Writes the data to the file in the backend, e.g. for the text files saved by the program
+func WriteFile(ctx context.Context, filePath string, data []byte) error {
+	file, err := backend.Create(ctx, filePath, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Abort()
+		return err
+	}
+	return file.Close()
+}
+
+// Returns the contents of the file in the backend, e.g. for the files that are updated on every run
+func ReadFile(ctx context.Context, filePath string) ([]byte, error) {
+	return backend.ReadFile(ctx, filePath)
+}
+
+// Returns true if the file exists in the backend
+func Exists(ctx context.Context, filePath string) bool {
+	_, err := backend.Size(ctx, filePath)
+	return err == nil
+}
+
+// Writes the files to the download directory on the local disk
+type localBackend struct{}
+
+func (b *localBackend) Name() string {
+	return "local disk"
+}
+
+type localFile struct {
+	*os.File
+}
+
+func (f *localFile) Abort() {
+	f.File.Close()
+	os.Remove(f.Name())
+}
+
+func (b *localBackend) Create(ctx context.Context, filePath string, size int64) (File, error) {
+	os.MkdirAll(filepath.Dir(filePath), 0666)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &localFile{File: file}, nil
+}
+
+func (b *localBackend) Size(ctx context.Context, filePath string) (int64, error) {
+	return utils.GetFileSize(filePath)
+}
+
+func (b *localBackend) Remove(ctx context.Context, filePath string) error {
+	return os.Remove(filePath)
+}
+
+func (b *localBackend) ReadFile(ctx context.Context, filePath string) ([]byte, error) {
+	return os.ReadFile(filePath)
+}
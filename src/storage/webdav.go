@@ -0,0 +1,427 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const webdavDefaultChunkSize = 10 * 1024 * 1024
+
+// Writes the files to a WebDAV folder, optionally with Nextcloud's chunked uploads
+type webdavBackend struct {
+	config     *utils.WebdavStorageConfig
+	baseUrl    *url.URL
+	uploadsUrl *url.URL // nil if the files are not uploaded in chunks
+	chunkSize  int64
+	client     *http.Client
+
+	// the folders that have been created with MKCOL or already exist
+	createdDirs sync.Map
+}
+
+// Returns the URL with a trailing slash removed or an error if it is not a HTTP(S) URL
+func parseWebdavUrl(rawUrl, key string) (*url.URL, error) {
+	parsedUrl, err := url.Parse(strings.TrimRight(rawUrl, "/"))
+	if err != nil || parsedUrl.Host == "" || (parsedUrl.Scheme != "https" && parsedUrl.Scheme != "http") {
+		return nil, fmt.Errorf(
+			"storage error %d: invalid WebDAV %s %q, please use a URL like \"https://cloud.example.com/remote.php/dav/files/<user>/Archive\"",
+			utils.INPUT_ERROR,
+			key,
+			rawUrl,
+		)
+	}
+	return parsedUrl, nil
+}
+
+func newWebdavBackend(config *utils.WebdavStorageConfig) (*webdavBackend, error) {
+	if config == nil || config.Url == "" {
+		return nil, fmt.Errorf(
+			"storage error %d: the url must be set in the webdav section of the storage config",
+			utils.INPUT_ERROR,
+		)
+	}
+	baseUrl, err := parseWebdavUrl(config.Url, "url")
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &webdavBackend{
+		config:    config,
+		baseUrl:   baseUrl,
+		chunkSize: webdavDefaultChunkSize,
+		client:    &http.Client{},
+	}
+	if config.ChunkUploadsUrl != "" {
+		if backend.uploadsUrl, err = parseWebdavUrl(config.ChunkUploadsUrl, "chunk_uploads_url"); err != nil {
+			return nil, err
+		}
+	}
+	if config.ChunkSize != "" {
+		if backend.chunkSize, err = utils.ParseFileSizeStr(config.ChunkSize); err != nil {
+			return nil, err
+		}
+		if backend.chunkSize <= 0 {
+			return nil, fmt.Errorf(
+				"storage error %d: the WebDAV chunk size must be more than 0 but got %q",
+				utils.INPUT_ERROR,
+				config.ChunkSize,
+			)
+		}
+	}
+	return backend, nil
+}
+
+func (b *webdavBackend) Name() string {
+	return "WebDAV"
+}
+
+// Returns the URL of the given path relative to the base URL, e.g. "Fantia/Creator/image.png"
+func joinWebdavUrl(baseUrl *url.URL, relPath string) string {
+	joinedUrl := *baseUrl
+	joinedUrl.Path = baseUrl.Path + "/" + relPath
+	joinedUrl.RawPath = ""
+	return joinedUrl.String()
+}
+
+// Creates a new request with the credentials in the config
+func (b *webdavBackend) newRequest(ctx context.Context, method, reqUrl string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	if b.config.Username != "" || b.config.Password != "" {
+		req.SetBasicAuth(b.config.Username, b.config.Password)
+	}
+	return req, nil
+}
+
+// Sends the request returned by newReq up to utils.RETRY_COUNTER times until the server
+// responds with one of the expected status codes or a client error that will not change on retrying.
+//
+// newReq is called for every attempt so that the request body can be sent again.
+func (b *webdavBackend) doWithRetry(ctx context.Context, newReq func() (*http.Request, error), okStatuses ...int) error {
+	var err error
+	for i := 1; i <= utils.RETRY_COUNTER; i++ {
+		var req *http.Request
+		if req, err = newReq(); err != nil {
+			return err
+		}
+
+		var res *http.Response
+		res, err = b.client.Do(req)
+		if err == nil {
+			body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+			res.Body.Close()
+			for _, status := range okStatuses {
+				if res.StatusCode == status {
+					return nil
+				}
+			}
+			err = fmt.Errorf(
+				"%s %s returned a %s response: %s",
+				req.Method,
+				req.URL.String(),
+				res.Status,
+				strings.TrimSpace(string(body)),
+			)
+			if res.StatusCode < 500 && res.StatusCode != 429 {
+				return err
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if i < utils.RETRY_COUNTER {
+			select {
+			case <-time.After(utils.GetRandomDelay()):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return err
+}
+
+// Creates the parent folders of the relative path that have not been created yet
+func (b *webdavBackend) mkdirAll(ctx context.Context, relPath string) error {
+	dir := path.Dir(relPath)
+	if dir == "." {
+		return nil
+	}
+
+	curDir := ""
+	for _, part := range strings.Split(dir, "/") {
+		curDir = path.Join(curDir, part)
+		if _, ok := b.createdDirs.Load(curDir); ok {
+			continue
+		}
+
+		// 405 Method Not Allowed is returned if the folder already exists
+		dirUrl := joinWebdavUrl(b.baseUrl, curDir) + "/"
+		err := b.doWithRetry(ctx, func() (*http.Request, error) {
+			return b.newRequest(ctx, "MKCOL", dirUrl, nil)
+		}, 201, 405)
+		if err != nil {
+			return fmt.Errorf(
+				"storage error %d: failed to create the WebDAV folder %s, more info => %v",
+				utils.CONNECTION_ERROR,
+				dirUrl,
+				err,
+			)
+		}
+		b.createdDirs.Store(curDir, struct{}{})
+	}
+	return nil
+}
+
+func (b *webdavBackend) Create(ctx context.Context, filePath string, size int64) (File, error) {
+	relPath := RelPath(filePath)
+	if err := b.mkdirAll(ctx, relPath); err != nil {
+		return nil, err
+	}
+
+	fileUrl := joinWebdavUrl(b.baseUrl, relPath)
+	if b.uploadsUrl != nil {
+		return b.newChunkedFile(ctx, fileUrl)
+	}
+
+	// the file is spooled to the local disk so that the upload can be retried
+	tmpFile, err := os.CreateTemp("", "cultured-downloader-*.part")
+	if err != nil {
+		return nil, err
+	}
+	return &spooledFile{
+		File: tmpFile,
+		upload: func(body io.Reader, size int64) error {
+			spooled := body.(*os.File)
+			err := b.doWithRetry(ctx, func() (*http.Request, error) {
+				if _, err := spooled.Seek(0, io.SeekStart); err != nil {
+					return nil, err
+				}
+				req, err := b.newRequest(ctx, "PUT", fileUrl, io.NopCloser(spooled))
+				if err != nil {
+					return nil, err
+				}
+				req.ContentLength = size
+				return req, nil
+			}, 200, 201, 204)
+			if err != nil {
+				return fmt.Errorf(
+					"storage error %d: failed to upload %s to WebDAV, more info => %v",
+					utils.CONNECTION_ERROR,
+					fileUrl,
+					err,
+				)
+			}
+			return nil
+		},
+	}, nil
+}
+
+func (b *webdavBackend) Size(ctx context.Context, filePath string) (int64, error) {
+	req, err := b.newRequest(ctx, "HEAD", joinWebdavUrl(b.baseUrl, RelPath(filePath)), nil)
+	if err != nil {
+		return -1, err
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		return -1, err
+	}
+	res.Body.Close()
+	switch {
+	case res.StatusCode == 404:
+		return -1, os.ErrNotExist
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return -1, fmt.Errorf("HEAD %s returned a %s response", req.URL.String(), res.Status)
+	}
+	return res.ContentLength, nil
+}
+
+func (b *webdavBackend) Remove(ctx context.Context, filePath string) error {
+	fileUrl := joinWebdavUrl(b.baseUrl, RelPath(filePath))
+	return b.doWithRetry(ctx, func() (*http.Request, error) {
+		return b.newRequest(ctx, "DELETE", fileUrl, nil)
+	}, 200, 204, 404)
+}
+
+func (b *webdavBackend) ReadFile(ctx context.Context, filePath string) ([]byte, error) {
+	req, err := b.newRequest(ctx, "GET", joinWebdavUrl(b.baseUrl, RelPath(filePath)), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == 404:
+		return nil, os.ErrNotExist
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return nil, fmt.Errorf("GET %s returned a %s response", req.URL.String(), res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// Uploads the written data in chunks with Nextcloud's chunked upload API (v2)
+// where each chunk is retried separately and only the current chunk is kept in memory.
+//
+// The chunks are assembled into the file once it is closed.
+type chunkedFile struct {
+	b         *webdavBackend
+	ctx       context.Context
+	fileUrl   string
+	uploadUrl string // the temporary folder of the chunks
+	buf       bytes.Buffer
+	chunkNum  int
+	written   int64
+	err       error // the error of the last failed chunk upload, if any
+}
+
+func (b *webdavBackend) newChunkedFile(ctx context.Context, fileUrl string) (*chunkedFile, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	file := &chunkedFile{
+		b:         b,
+		ctx:       ctx,
+		fileUrl:   fileUrl,
+		uploadUrl: joinWebdavUrl(b.uploadsUrl, "cultured-downloader-"+hex.EncodeToString(idBytes)),
+	}
+
+	err := b.doWithRetry(ctx, func() (*http.Request, error) {
+		return file.newRequest("MKCOL", file.uploadUrl, nil)
+	}, 201)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"storage error %d: failed to start the chunked upload of %s to WebDAV, more info => %v",
+			utils.CONNECTION_ERROR,
+			fileUrl,
+			err,
+		)
+	}
+	return file, nil
+}
+
+// Creates a new request with the Destination header that Nextcloud requires for all the requests of the chunked upload
+func (f *chunkedFile) newRequest(method, reqUrl string, body io.Reader) (*http.Request, error) {
+	req, err := f.b.newRequest(f.ctx, method, reqUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Destination", f.fileUrl)
+	return req, nil
+}
+
+// Uploads the buffered data as the next chunk
+func (f *chunkedFile) flush() error {
+	if f.buf.Len() == 0 {
+		return nil
+	}
+
+	f.chunkNum++
+	chunk := f.buf.Bytes()
+	// the chunks are assembled in the order of their names
+	chunkUrl := fmt.Sprintf("%s/%05d", f.uploadUrl, f.chunkNum)
+	err := f.b.doWithRetry(f.ctx, func() (*http.Request, error) {
+		req, err := f.newRequest("PUT", chunkUrl, bytes.NewReader(chunk))
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = int64(len(chunk))
+		return req, nil
+	}, 200, 201, 204)
+	if err != nil {
+		return fmt.Errorf(
+			"storage error %d: failed to upload chunk %d of %s to WebDAV, more info => %v",
+			utils.CONNECTION_ERROR,
+			f.chunkNum,
+			f.fileUrl,
+			err,
+		)
+	}
+	f.buf.Reset()
+	return nil
+}
+
+func (f *chunkedFile) Write(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		toWrite := int(f.b.chunkSize) - f.buf.Len()
+		if toWrite > len(p) {
+			toWrite = len(p)
+		}
+		f.buf.Write(p[:toWrite])
+		p = p[toWrite:]
+		written += toWrite
+		f.written += int64(toWrite)
+
+		if int64(f.buf.Len()) >= f.b.chunkSize {
+			if f.err = f.flush(); f.err != nil {
+				return written, f.err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (f *chunkedFile) Close() error {
+	if f.err == nil {
+		f.err = f.flush()
+	}
+	if f.err != nil {
+		f.Abort()
+		return f.err
+	}
+
+	err := f.b.doWithRetry(f.ctx, func() (*http.Request, error) {
+		req, err := f.newRequest("MOVE", f.uploadUrl+"/.file", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("OC-Total-Length", strconv.FormatInt(f.written, 10))
+		req.Header.Set("Overwrite", "T")
+		return req, nil
+	}, 200, 201, 204)
+	if err != nil {
+		f.Abort()
+		return fmt.Errorf(
+			"storage error %d: failed to assemble the chunks of %s on WebDAV, more info => %v",
+			utils.CONNECTION_ERROR,
+			f.fileUrl,
+			err,
+		)
+	}
+	return nil
+}
+
+func (f *chunkedFile) Abort() {
+	// the context may have been cancelled which is why the uploaded chunks are deleted with a new one
+	req, err := f.b.newRequest(context.Background(), "DELETE", f.uploadUrl, nil)
+	if err != nil {
+		return
+	}
+	if res, err := f.b.client.Do(req); err == nil {
+		res.Body.Close()
+	}
+}
@@ -0,0 +1,24 @@
+package systemd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Returns true if the program's output is connected to the systemd journal.
+//
+// JOURNAL_STREAM is inherited by the child processes even if their output is redirected,
+// so the device and inode numbers in it are compared with the ones of stderr as described in systemd.exec(5).
+func IsJournal() bool {
+	journalStream := os.Getenv("JOURNAL_STREAM")
+	if journalStream == "" {
+		return false
+	}
+
+	var stat syscall.Stat_t
+	if err := syscall.Fstat(int(os.Stderr.Fd()), &stat); err != nil {
+		return false
+	}
+	return journalStream == fmt.Sprintf("%d:%d", stat.Dev, stat.Ino)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package systemd
+
+// Returns false as the systemd journal is only available on Linux
+func IsJournal() bool {
+	return false
+}
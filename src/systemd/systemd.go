@@ -0,0 +1,166 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/KJHJason/Cultured-Downloader-CLI/spinner"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+	"github.com/fatih/color"
+)
+
+// Sends the given state, e.g. "READY=1", to the systemd service manager
+// if the program is run as a Type=notify service, otherwise it does nothing.
+//
+// See sd_notify(3) for the supported states.
+func Notify(state string) error {
+	socketAddr := os.Getenv("NOTIFY_SOCKET")
+	if socketAddr == "" {
+		return nil
+	}
+
+	// abstract socket addresses start with "@"
+	if strings.HasPrefix(socketAddr, "@") {
+		socketAddr = "\x00" + socketAddr[1:]
+	}
+	conn, err := net.DialUnix(
+		"unixgram",
+		nil,
+		&net.UnixAddr{Name: socketAddr, Net: "unixgram"},
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// Returns the interval that the watchdog has to be pinged at
+// if the systemd watchdog is enabled for this process, otherwise 0.
+func getWatchdogInterval() time.Duration {
+	watchdogPid := os.Getenv("WATCHDOG_PID")
+	if watchdogPid != "" && watchdogPid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	watchdogUsec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || watchdogUsec <= 0 {
+		return 0
+	}
+
+	// ping twice per interval as recommended in sd_watchdog_enabled(3)
+	return time.Duration(watchdogUsec) * time.Microsecond / 2
+}
+
+// Reports the number of downloaded files as the service status to the systemd service manager
+// and keeps track of whether the download process has made any progress for the watchdog
+type statusHandler struct {
+	events.BaseHandler
+
+	mu         sync.Mutex
+	downloaded int
+	progressed bool
+}
+
+func (s *statusHandler) setProgressed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progressed = true
+}
+
+// Returns true if the download process has made any progress since the last call
+func (s *statusHandler) popProgressed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	progressed := s.progressed
+	s.progressed = false
+	return progressed
+}
+
+func (s *statusHandler) OnPostResolved(post *events.Post) {
+	s.setProgressed()
+}
+
+func (s *statusHandler) OnPostNotFound(site, postId string) {
+	s.setProgressed()
+}
+
+func (s *statusHandler) OnFileStart(file *events.File) {
+	s.setProgressed()
+}
+
+func (s *statusHandler) OnFileProgress(file *events.File, downloaded, total int64) {
+	s.setProgressed()
+}
+
+func (s *statusHandler) OnFileDone(file *events.File, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progressed = true
+	if err != nil || file.Skipped {
+		return
+	}
+
+	s.downloaded++
+	Notify(fmt.Sprintf("STATUS=Downloaded %d file(s)", s.downloaded))
+}
+
+// Integrates the program with systemd when it is run as a service:
+//   - Colours and spinner animations are disabled when the output is connected to the journal
+//   - The service manager is notified once the program is ready and of the download progress
+//   - The watchdog, if enabled, is only pinged if a post or file has made progress since the last ping
+//     so that systemd can restart a run that is stuck, e.g. on a stalled download
+//
+// The returned function should be called before the program exits to notify the service manager
+// and is also called by utils.Exit if the program exits on a fatal error.
+func Setup() func() {
+	if IsJournal() {
+		color.NoColor = true
+		spinner.SetPlain(true)
+	}
+
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return func() {}
+	}
+
+	Notify("READY=1")
+	status := &statusHandler{}
+	unregister := events.Register(status)
+
+	stopWatchdog := make(chan struct{})
+	if interval := getWatchdogInterval(); interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if status.popProgressed() {
+						Notify("WATCHDOG=1")
+					}
+				case <-stopWatchdog:
+					return
+				}
+			}
+		}()
+	}
+
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() {
+			close(stopWatchdog)
+			unregister()
+			Notify("STOPPING=1")
+		})
+	}
+	utils.RegisterExitHook(stop)
+	return stop
+}
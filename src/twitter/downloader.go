@@ -0,0 +1,92 @@
+package twitter
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+// An external program that downloads the media of a tweet, i.e. gallery-dl or yt-dlp
+type mediaDownloader struct {
+	name string
+	path string
+
+	// returns the arguments to download the media of the tweet to the folder
+	args func(url, dest string) []string
+}
+
+// set by SetMediaDownloader if the media of the tweets should be downloaded
+var downloader *mediaDownloader
+
+// Sets the external program used by ProcessLinks to download the media of the tweets
+// from the gallery_dl_path or yt_dlp_path in the tools section of the config file
+// where gallery-dl is preferred as it can also download the images of the tweets.
+//
+// Returns an error if neither is configured or the configured program cannot be found.
+func SetMediaDownloader(tools *utils.ToolsConfig) error {
+	var candidates []*mediaDownloader
+	if tools != nil && tools.GalleryDlPath != "" {
+		candidates = append(candidates, &mediaDownloader{
+			name: "gallery-dl",
+			path: tools.GalleryDlPath,
+			args: func(url, dest string) []string {
+				return []string{"--directory", dest, url}
+			},
+		})
+	}
+	if tools != nil && tools.YtDlpPath != "" {
+		candidates = append(candidates, &mediaDownloader{
+			name: "yt-dlp",
+			path: tools.YtDlpPath,
+			args: func(url, dest string) []string {
+				return []string{"--paths", dest, "--output", "%(id)s.%(ext)s", "--no-playlist", url}
+			},
+		})
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf(
+			"twitter error %d: the gallery_dl_path or yt_dlp_path in the tools section of the config file at %s must be set to download the media of Twitter/X links",
+			utils.INPUT_ERROR,
+			utils.GetConfigFilePath(),
+		)
+	}
+
+	var errSlice []error
+	for _, candidate := range candidates {
+		foundPath, err := exec.LookPath(candidate.path)
+		if err == nil {
+			candidate.path = foundPath
+			downloader = candidate
+			return nil
+		}
+		errSlice = append(errSlice, err)
+	}
+	return fmt.Errorf(
+		"twitter error %d: the configured gallery-dl or yt-dlp could not be found, more info => %v",
+		utils.CMD_ERROR,
+		errSlice,
+	)
+}
+
+// Downloads the media of the tweet to the folder with the external program
+func (d *mediaDownloader) download(url, dest string) error {
+	os.MkdirAll(dest, 0666)
+	cmd := exec.Command(d.path, d.args(url, dest)...)
+	if utils.DEBUG_MODE {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf(
+			"twitter error %d: failed to download the media of %s with %s at %s, more info => %v",
+			utils.CMD_ERROR,
+			url,
+			d.name,
+			d.path,
+			err,
+		)
+	}
+	return nil
+}
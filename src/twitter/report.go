@@ -0,0 +1,100 @@
+package twitter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+const TWITTER_LINKS_FILENAME = "twitter_links.csv"
+
+var linksCsvHeader = []string{"url", "tweet_id", "post_folder", "recorded_at"}
+
+// Returns the links that have already been recorded in the report with their post folders
+func getRecordedLinks(reportPath string) (map[string]struct{}, error) {
+	recorded := make(map[string]struct{})
+	f, err := os.Open(reportPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return recorded, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) > 2 && record[0] != linksCsvHeader[0] {
+			recorded[record[0]+"|"+record[2]] = struct{}{}
+		}
+	}
+	return recorded, nil
+}
+
+// Records the Twitter/X links in the twitter_links.csv report in the download directory
+// so that the links of all the downloaded posts can be found in a single file.
+//
+// Links that have already been recorded for the same post in previous runs will not be duplicated.
+func writeLinksReport(detectedLinks []*tweetLink, downloadPath string) error {
+	reportPath := filepath.Join(downloadPath, TWITTER_LINKS_FILENAME)
+	recorded, err := getRecordedLinks(reportPath)
+	if err != nil {
+		return fmt.Errorf(
+			"twitter error %d: failed to read %s, more info => %v",
+			utils.OS_ERROR,
+			reportPath,
+			err,
+		)
+	}
+
+	os.MkdirAll(downloadPath, 0666)
+	f, err := os.OpenFile(reportPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf(
+			"twitter error %d: failed to open %s, more info => %v",
+			utils.OS_ERROR,
+			reportPath,
+			err,
+		)
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	if stat, err := f.Stat(); err == nil && stat.Size() == 0 {
+		writer.Write(linksCsvHeader)
+	}
+
+	recordedAt := time.Now().Format(time.RFC3339)
+	for _, link := range detectedLinks {
+		key := link.url + "|" + link.postFolder
+		if _, ok := recorded[key]; ok {
+			continue
+		}
+		recorded[key] = struct{}{}
+		writer.Write([]string{link.url, link.tweetId, link.postFolder, recordedAt})
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf(
+			"twitter error %d: failed to write to %s, more info => %v",
+			utils.OS_ERROR,
+			reportPath,
+			err,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package twitter
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/configs"
+	"github.com/KJHJason/Cultured-Downloader-CLI/utils"
+)
+
+var (
+	// e.g. https://twitter.com/username, https://x.com/username/status/1234567890,
+	// or https://mobile.twitter.com/i/web/status/1234567890
+	TWITTER_URL_REGEX = regexp.MustCompile(
+		`https?://(?:(?:www|mobile)\.)?(?:twitter|x)\.com/(?:i/web|[\w-]+)(?:/status(?:es)?/(?P<tweetId>\d+))?`,
+	)
+	TWITTER_TWEET_ID_IDX = TWITTER_URL_REGEX.SubexpIndex("tweetId")
+)
+
+// A Twitter/X link that was detected in a post
+type tweetLink struct {
+	url        string
+	tweetId    string // empty if the link is not to a tweet, e.g. a profile
+	postFolder string
+}
+
+var (
+	linksMu sync.Mutex
+	links   []*tweetLink
+	queued  = make(map[string]struct{})
+)
+
+// Detects the Twitter/X links in the text and queues them to be recorded
+// in the report and to have their media downloaded by ProcessLinks.
+//
+// Returns true if any Twitter/X link was detected.
+func DetectLinks(text, postFolderPath string) bool {
+	matches := TWITTER_URL_REGEX.FindAllStringSubmatch(text, -1)
+	for _, matched := range matches {
+		key := matched[0] + "|" + postFolderPath
+
+		linksMu.Lock()
+		if _, ok := queued[key]; !ok {
+			queued[key] = struct{}{}
+			links = append(links, &tweetLink{
+				url:        matched[0],
+				tweetId:    matched[TWITTER_TWEET_ID_IDX],
+				postFolder: postFolderPath,
+			})
+		}
+		linksMu.Unlock()
+	}
+	return len(matches) > 0
+}
+
+// Returns the queued links and clears the queue
+func popLinks() []*tweetLink {
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	queuedLinks := links
+	links = nil
+	queued = make(map[string]struct{})
+	return queuedLinks
+}
+
+// Records the Twitter/X links detected by DetectLinks in the TWITTER_LINKS_FILENAME report in the
+// download directory and downloads the media of the tweets to the post folders if SetMediaDownloader was called
+//
+// The tweets whose media could not be downloaded are logged to the post's OTHER_LINKS_FILENAME file instead.
+func ProcessLinks(config *configs.Config) {
+	detectedLinks := popLinks()
+	if len(detectedLinks) == 0 {
+		return
+	}
+	if err := writeLinksReport(detectedLinks, utils.DOWNLOAD_PATH); err != nil {
+		utils.LogError(err, "", false, utils.ERROR)
+	}
+	if downloader == nil {
+		return
+	}
+
+	var tweets []*tweetLink
+	for _, link := range detectedLinks {
+		if link.tweetId != "" && config.ShouldDlPost(link.postFolder) {
+			tweets = append(tweets, link)
+		}
+	}
+	for idx, tweet := range tweets {
+		fmt.Printf("[%d/%d] Downloading the media of %s with %s...\n", idx+1, len(tweets), tweet.url, downloader.name)
+		if err := downloader.download(tweet.url, filepath.Join(tweet.postFolder, utils.TWITTER_FOLDER)); err != nil {
+			utils.LogError(err, "", false, utils.ERROR)
+			utils.LogMessageToPath(
+				fmt.Sprintf(
+					"Could not download the media of the Twitter/X link in the post's description:\n%s\n\n",
+					tweet.url,
+				),
+				filepath.Join(tweet.postFolder, utils.OTHER_LINKS_FILENAME),
+				utils.INFO,
+			)
+		}
+	}
+}
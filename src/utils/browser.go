@@ -42,6 +42,15 @@ func GetDefaultChromedpAlloc(userAgent string) (context.Context, context.CancelF
 	return chromedp.NewExecAllocator(context.Background(), opts...)
 }
 
+// Returns a chromedp allocator with a visible browser window for the user to interact with
+func GetVisibleChromedpAlloc(userAgent string) (context.Context, context.CancelFunc) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.UserAgent(userAgent),
+		chromedp.Flag("headless", false),
+	)
+	return chromedp.NewExecAllocator(context.Background(), opts...)
+}
+
 func ExecuteChromedpActions(allocCtx context.Context, allocCancelFn context.CancelFunc, actions ...chromedp.Action) error {
 	if allocCtx == nil {
 		allocCtx = context.Background()
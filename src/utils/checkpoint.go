@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoints older than this are ignored as the paginated
+// results would have shifted too much from new posts since then
+const CRAWL_CHECKPOINT_MAX_AGE = 7 * 24 * time.Hour
+
+var crawlCheckpointMu sync.Mutex
+
+type crawlCheckpoint struct {
+	UpdatedAt time.Time       `json:"updated_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Returns the path to the file in the app data folder that stores
+// the checkpoints of interrupted crawls of paginated results
+func GetCrawlCheckpointFilePath() string {
+	return filepath.Join(APP_PATH, "crawl_checkpoints.json")
+}
+
+func loadCrawlCheckpoints() (map[string]*crawlCheckpoint, error) {
+	checkpoints := make(map[string]*crawlCheckpoint)
+	checkpointFile, err := os.ReadFile(GetCrawlCheckpointFilePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return checkpoints, nil
+		}
+		return nil, fmt.Errorf(
+			"error %d: failed to read crawl checkpoint file, more info => %v",
+			OS_ERROR,
+			err,
+		)
+	}
+
+	if err = json.Unmarshal(checkpointFile, &checkpoints); err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to unmarshal crawl checkpoint file, more info => %v",
+			JSON_ERROR,
+			err,
+		)
+	}
+	return checkpoints, nil
+}
+
+func saveCrawlCheckpoints(checkpoints map[string]*crawlCheckpoint) error {
+	checkpointFile, err := json.Marshal(checkpoints)
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to marshal crawl checkpoints, more info => %v",
+			JSON_ERROR,
+			err,
+		)
+	}
+
+	os.MkdirAll(APP_PATH, 0666)
+	if err = os.WriteFile(GetCrawlCheckpointFilePath(), checkpointFile, 0666); err != nil {
+		return fmt.Errorf(
+			"error %d: failed to write crawl checkpoint file, more info => %v",
+			OS_ERROR,
+			err,
+		)
+	}
+	return nil
+}
+
+// Loads the checkpoint saved with the given key into v.
+//
+// Returns false if there is no checkpoint or if it is older than CRAWL_CHECKPOINT_MAX_AGE.
+func LoadCrawlCheckpoint(key string, v any) bool {
+	crawlCheckpointMu.Lock()
+	defer crawlCheckpointMu.Unlock()
+
+	checkpoints, err := loadCrawlCheckpoints()
+	if err != nil {
+		LogError(err, "", false, ERROR)
+		return false
+	}
+
+	checkpoint, ok := checkpoints[key]
+	if !ok || time.Since(checkpoint.UpdatedAt) > CRAWL_CHECKPOINT_MAX_AGE {
+		return false
+	}
+	return json.Unmarshal(checkpoint.Data, v) == nil
+}
+
+// Saves v as the checkpoint of the crawl with the given key
+// so that the crawl can be resumed if it gets interrupted
+func SaveCrawlCheckpoint(key string, v any) {
+	crawlCheckpointMu.Lock()
+	defer crawlCheckpointMu.Unlock()
+
+	checkpoints, err := loadCrawlCheckpoints()
+	if err != nil {
+		LogError(err, "", false, ERROR)
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		LogError(err, "", false, ERROR)
+		return
+	}
+	checkpoints[key] = &crawlCheckpoint{
+		UpdatedAt: time.Now(),
+		Data:      data,
+	}
+	if err := saveCrawlCheckpoints(checkpoints); err != nil {
+		LogError(err, "", false, ERROR)
+	}
+}
+
+// Deletes the checkpoint of the crawl with the given key after the crawl has completed
+func DeleteCrawlCheckpoint(key string) {
+	crawlCheckpointMu.Lock()
+	defer crawlCheckpointMu.Unlock()
+
+	checkpoints, err := loadCrawlCheckpoints()
+	if err != nil {
+		LogError(err, "", false, ERROR)
+		return
+	}
+	if _, ok := checkpoints[key]; !ok {
+		return
+	}
+
+	delete(checkpoints, key)
+	if err := saveCrawlCheckpoints(checkpoints); err != nil {
+		LogError(err, "", false, ERROR)
+	}
+}
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const CHECKSUM_MANIFEST_FILENAME = "SHA256SUMS"
+
+// Returns the post folder, e.g. "[12345] Post Title", that the given file path is in
+//
+// Returns an empty string if the file path is not in a post folder.
+func GetPostFolderFromPath(filePath string) string {
+	for dir := filepath.Clean(filePath); ; {
+		if POST_FOLDER_REGEX.MatchString(filepath.Base(dir)) {
+			return dir
+		}
+
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return ""
+		}
+		dir = parentDir
+	}
+}
+
+// Returns the SHA-256 hash of the file at the given file path in hex
+func GetFileSha256(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Writes a SHA256SUMS manifest of all the files in the given folder
+// that can be verified with standard tools like "sha256sum -c SHA256SUMS".
+func writeChecksumManifest(folderPath string) error {
+	var lines []string
+	err := filepath.WalkDir(folderPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		isMarker := d.Name() == CHECKSUM_MANIFEST_FILENAME ||
+			d.Name() == POST_COMPLETE_FILENAME ||
+			d.Name() == POST_INCOMPLETE_FILENAME
+		if d.IsDir() || (isMarker && filepath.Dir(path) == folderPath) {
+			return nil
+		}
+
+		hash, err := GetFileSha256(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(folderPath, path)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s", hash, filepath.ToSlash(relPath)))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to hash the files in %s, more info => %v",
+			OS_ERROR,
+			folderPath,
+			err,
+		)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	// sort by the file path for a stable manifest
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i][sha256.Size*2:] < lines[j][sha256.Size*2:]
+	})
+	manifestPath := filepath.Join(folderPath, CHECKSUM_MANIFEST_FILENAME)
+	if err := os.WriteFile(manifestPath, []byte(strings.Join(lines, "\n")+"\n"), 0666); err != nil {
+		return fmt.Errorf(
+			"error %d: failed to write checksum manifest at %s, more info => %v",
+			OS_ERROR,
+			manifestPath,
+			err,
+		)
+	}
+	return nil
+}
+
+// Writes a SHA256SUMS manifest in each post folder that the given file paths are in.
+//
+// The manifest covers all the files in the post folder
+// so that it stays complete across multiple runs.
+func WriteChecksumManifests(filePaths []string) {
+	postFolders := make(map[string]struct{})
+	for _, filePath := range filePaths {
+		if postFolder := GetPostFolderFromPath(filePath); postFolder != "" && PathExists(postFolder) {
+			postFolders[postFolder] = struct{}{}
+		}
+	}
+	if len(postFolders) == 0 {
+		return
+	}
+
+	var errSlice []error
+	for postFolder := range postFolders {
+		if err := writeChecksumManifest(postFolder); err != nil {
+			errSlice = append(errSlice, err)
+		}
+	}
+	if len(errSlice) > 0 {
+		LogErrors(false, nil, ERROR, errSlice...)
+	}
+	color.Green("Wrote %s manifests for %d folder(s)", CHECKSUM_MANIFEST_FILENAME, len(postFolders)-len(errSlice))
+}
+
+const (
+	CHECKSUM_OK = iota
+	CHECKSUM_MISSING
+	CHECKSUM_MISMATCH
+	CHECKSUM_TRUNCATED // the file is smaller than its recorded size
+)
+
+type ChecksumResult struct {
+	FilePath string
+	Url      string // the source URL of the file if it is known, e.g. from the download log
+	Status   int
+	Err      error // set if the file could not be read
+}
+
+// Verifies the files listed in the SHA256SUMS manifest at the given path
+// and returns the results of the files that did not pass the verification
+// alongside the number of files that were checked.
+func VerifyChecksumManifest(manifestPath string) ([]*ChecksumResult, int, error) {
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf(
+			"error %d: failed to open checksum manifest at %s, more info => %v",
+			OS_ERROR,
+			manifestPath,
+			err,
+		)
+	}
+	defer f.Close()
+
+	var results []*ChecksumResult
+	checked := 0
+	folderPath := filepath.Dir(manifestPath)
+	reader := bufio.NewReader(f)
+	for {
+		lineBytes, err := ReadLine(reader)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, checked, fmt.Errorf(
+				"error %d: failed to read checksum manifest at %s, more info => %v",
+				OS_ERROR,
+				manifestPath,
+				err,
+			)
+		}
+
+		// format: "<hash>  <relative file path>"
+		expectedHash, relPath, found := strings.Cut(string(lineBytes), "  ")
+		if !found || len(expectedHash) != sha256.Size*2 {
+			continue
+		}
+
+		checked++
+		filePath := filepath.Join(folderPath, filepath.FromSlash(relPath))
+		if !PathExists(filePath) {
+			results = append(results, &ChecksumResult{FilePath: filePath, Status: CHECKSUM_MISSING})
+			continue
+		}
+
+		hash, err := GetFileSha256(filePath)
+		if err != nil {
+			results = append(results, &ChecksumResult{FilePath: filePath, Status: CHECKSUM_MISMATCH, Err: err})
+		} else if !strings.EqualFold(hash, expectedHash) {
+			results = append(results, &ChecksumResult{FilePath: filePath, Status: CHECKSUM_MISMATCH})
+		}
+	}
+	return results, checked, nil
+}
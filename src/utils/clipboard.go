@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Commands that print the text in the system clipboard, in order of preference
+var clipboardCmds = map[string][][]string{
+	"windows": {
+		{"powershell.exe", "-NoProfile", "-NonInteractive", "-Command", "Get-Clipboard -Raw"},
+	},
+	"darwin": {
+		{"pbpaste"},
+	},
+	"linux": {
+		{"wl-paste", "--no-newline"},
+		{"xclip", "-selection", "clipboard", "-out"},
+		{"xsel", "--clipboard", "--output"},
+	},
+}
+
+// Returns the command to read the system clipboard that is available on the user's system
+func getClipboardCmd() ([]string, error) {
+	for _, cmd := range clipboardCmds[runtime.GOOS] {
+		// wl-paste only works in a Wayland session
+		if cmd[0] == "wl-paste" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			continue
+		}
+		if _, err := exec.LookPath(cmd[0]); err == nil {
+			return cmd, nil
+		}
+	}
+
+	errMsg := fmt.Sprintf("no supported clipboard command found for %s", runtime.GOOS)
+	if runtime.GOOS == "linux" {
+		errMsg += ", please install wl-clipboard, xclip, or xsel"
+	}
+	return nil, errors.New(errMsg)
+}
+
+// Returns a function that reads the text in the system clipboard
+// using the clipboard command available on the user's system
+func NewClipboardReader() (func() (string, error), error) {
+	clipboardCmd, err := getClipboardCmd()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error %d: unable to read the clipboard, more info => %v",
+			OS_ERROR,
+			err,
+		)
+	}
+
+	return func() (string, error) {
+		output, err := exec.Command(clipboardCmd[0], clipboardCmd[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf(
+				"error %d: failed to read the clipboard with %s, more info => %v",
+				OS_ERROR,
+				clipboardCmd[0],
+				err,
+			)
+		}
+		return strings.TrimSpace(string(output)), nil
+	}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"time"
 )
 
 const (
@@ -22,6 +23,7 @@ const (
 	JSON_ERROR
 	HTML_ERROR
 	CAPTCHA_ERROR
+	AUTH_ERROR
 )
 
 // Returns the path to the application's config directory
@@ -40,16 +42,22 @@ func getAppPath() string {
 }
 
 const (
-	DEBUG_MODE                     = false // Will save a copy of all JSON response from the API
-	VERSION                        = "1.3.0"
-	MAX_RETRY_DELAY                = 3
-	MIN_RETRY_DELAY                = 1
-	RETRY_COUNTER                  = 4
-	MAX_CONCURRENT_DOWNLOADS       = 4
-	PIXIV_MAX_CONCURRENT_DOWNLOADS = 3
-	MAX_API_CALLS                  = 10
-
-	PAGE_NUM_REGEX_STR = `[1-9]\d*(-[1-9]\d*)?`
+	DEBUG_MODE                      = false // Will save a copy of all JSON response from the API
+	VERSION                         = "1.3.0"
+	MAX_RETRY_DELAY                 = 3
+	MIN_RETRY_DELAY                 = 1
+	RETRY_COUNTER                   = 4
+	MAX_CONCURRENT_DOWNLOADS        = 4
+	PIXIV_MAX_CONCURRENT_DOWNLOADS  = 3
+	GDRIVE_MAX_CONCURRENT_DOWNLOADS = 4
+	MAX_CONCURRENT_EXTRACTIONS      = 4
+	MAX_API_CALLS                   = 10
+	COOKIE_EXPIRY_WARNING_DAYS      = 7
+
+	// kept low as the file hosting services limit the downloads per IP address
+	FILE_HOSTS_MAX_CONCURRENT_DOWNLOADS = 2
+
+	PAGE_NUM_REGEX_STR = `(?:(?i:all)|[1-9]\d*(?:-(?:[1-9]\d*)?)?|-[1-9]\d*)`
 	DOWNLOAD_TIMEOUT   = 25 * 60 // 25 minutes in seconds as downloads
 	// can take quite a while for large files (especially for Pixiv)
 	// However, the average max file size on these platforms is around 300MB.
@@ -79,17 +87,41 @@ const (
 	KEMONO_URL      = "https://kemono.party"
 	KEMONO_API_URL  = "https://kemono.party/api"
 
-	PASSWORD_FILENAME = "detected_passwords.txt"
+	DLSITE              = "dlsite"
+	DLSITE_TITLE        = "DLsite"
+	DLSITE_URL          = "https://www.dlsite.com"
+	DLSITE_PLAY_URL     = "https://play.dlsite.com"
+	DLSITE_PLAY_API_URL = "https://play.dlsite.com/api"
+
+	// PASSWORD_FILENAME is the text of the post that contains a password for the user to read
+	// while EXTRACTED_PASSWORDS_FILENAME is the passwords extracted from it, one per line, for extracting the archives
+	PASSWORD_FILENAME            = "detected_passwords.txt"
+	EXTRACTED_PASSWORDS_FILENAME = "extracted_passwords.txt"
+
 	ATTACHMENT_FOLDER = "attachments"
 	IMAGES_FOLDER     = "images"
 
-	KEMONO_EMBEDS_FOLDER   = "embeds"
-	KEMONO_CONTENT_FOLDER  = "post_content"
+	KEMONO_EMBEDS_FOLDER        = "embeds"
+	KEMONO_CONTENT_FOLDER       = "post_content"
+	KEMONO_ANNOUNCEMENTS_FOLDER = "announcements"
+	KEMONO_FANCARDS_FOLDER      = "fancards"
 
-	GDRIVE_URL 	         = "https://drive.google.com"
+	GDRIVE_URL           = "https://drive.google.com"
+	GDOCS_URL            = "https://docs.google.com"
 	GDRIVE_FOLDER        = "gdrive"
 	GDRIVE_FILENAME      = "detected_gdrive_links.txt"
 	OTHER_LINKS_FILENAME = "detected_external_links.txt"
+	FILE_HOSTS_FOLDER    = "file_hosts"
+	TWITTER_FOLDER       = "twitter"
+
+	// Marker written to a post folder once all of the post's files have been downloaded and verified
+	POST_COMPLETE_FILENAME = ".complete"
+
+	// Marker written to a post folder while its files are being downloaded or if any of them was not downloaded
+	POST_INCOMPLETE_FILENAME = ".incomplete"
+
+	// Environment variable to override the download path for a single run
+	DOWNLOAD_PATH_ENV = "CULTURED_DOWNLOADER_DL_PATH"
 )
 
 type cookieInfo struct {
@@ -109,9 +141,20 @@ var (
 	PAGE_NUM_REGEX = regexp.MustCompile(
 		fmt.Sprintf(`^%s$`, PAGE_NUM_REGEX_STR),
 	)
-	NUMBER_REGEX             = regexp.MustCompile(`^\d+$`)
-	GDRIVE_URL_REGEX         = regexp.MustCompile(
-		`https://drive\.google\.com/(?P<type>file/d|drive/(u/\d+/)?folders)/(?P<id>[\w-]+)`,
+	NUMBER_REGEX              = regexp.MustCompile(`^\d+$`)
+	FILE_SIZE_REGEX           = regexp.MustCompile(`(?i)^(?P<size>\d+(\.\d+)?)\s*(?P<unit>[KMGT]?B)?$`)
+	DEBUG_DUMP_FILENAME_REGEX = regexp.MustCompile(`[^\w.-]+`)
+	POST_FOLDER_REGEX         = regexp.MustCompile(`^\[(?P<postId>[^\]]+)\]`) // based on the folder name from GetPostFolder
+	// Matches a password after a password label, e.g. "パスワード：abc123" or "Pass【abc123】",
+	// where the password can only contain ASCII characters to not match the rest of the sentence.
+	PASSWORD_REGEX = regexp.MustCompile(
+		`(?i)(?:^|[^a-z])(?:パスワード|パス|password|passwd|passcode|pass|pwd|pw|密码|密碼|解压码|解凍キー)\s*` +
+			`(?:は\s*[「『【\[（(<＜"“']?|[:：=＝]\s*[「『【\[（(<＜"“']?|[「『【\[（(<＜"“'])\s*` +
+			`(?P<password>[^[:^ascii:]\s"'()<>\[\]]+)`,
+	)
+	PASSWORD_REGEX_INDEX = PASSWORD_REGEX.SubexpIndex("password")
+	GDRIVE_URL_REGEX     = regexp.MustCompile(
+		`https://(?:drive|docs)\.google\.com/(?P<type>file/d|drive/(?:u/\d+/)?folders|(?:document|spreadsheets|presentation|drawings)(?:/u/\d+)?/d)/(?P<id>[\w-]+)`,
 	)
 	GDRIVE_REGEX_ID_INDEX   = GDRIVE_URL_REGEX.SubexpIndex("id")
 	GDRIVE_REGEX_TYPE_INDEX = GDRIVE_URL_REGEX.SubexpIndex("type")
@@ -120,9 +163,38 @@ var (
 	)
 	FANTIA_REGEX_URL_INDEX = FANTIA_IMAGE_URL_REGEX.SubexpIndex("url")
 
+	// Date formats of the publish date of a post from the APIs of the supported websites
+	POST_DATE_LAYOUTS = []string{
+		time.RFC3339,          // Pixiv Fanbox and Pixiv
+		time.RFC1123Z,         // Fantia
+		time.RFC1123,          // Kemono Party (older posts)
+		"2006-01-02T15:04:05", // Kemono Party
+	}
+
+	// File extensions of the MIME types that are used to fix the extension of downloaded files
+	// where the first extension is the one that will be used for the file
+	MIME_TYPE_EXTENSIONS = map[string][]string{
+		"image/jpeg":                   {".jpg", ".jpeg", ".jpe", ".jfif"},
+		"image/png":                    {".png"},
+		"image/gif":                    {".gif"},
+		"image/webp":                   {".webp"},
+		"image/bmp":                    {".bmp"},
+		"video/mp4":                    {".mp4"},
+		"video/webm":                   {".webm"},
+		"video/avi":                    {".avi"},
+		"video/quicktime":              {".mov"},
+		"audio/mpeg":                   {".mp3"},
+		"audio/wave":                   {".wav"},
+		"application/ogg":              {".ogg"},
+		"application/pdf":              {".pdf"},
+		"application/zip":              {".zip"},
+		"application/x-rar-compressed": {".rar"},
+		"application/x-gzip":           {".gz"},
+	}
+
 	// For Pixiv Fanbox
 	PASSWORD_TEXTS              = []string{"パス", "Pass", "pass", "密码"}
-	EXTERNAL_DOWNLOAD_PLATFORMS = []string{"mega", "gigafile", "dropbox", "mediafire"}
+	EXTERNAL_DOWNLOAD_PLATFORMS = []string{"mega", "gigafile", "xgf.nu", "firestorage", "dropbox", "mediafire"}
 )
 
 func init() {
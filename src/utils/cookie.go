@@ -44,6 +44,12 @@ func GetSessionCookieInfo(site string) *cookieInfo {
 			Name:     "session",
 			SameSite: http.SameSiteNoneMode,
 		}
+	case DLSITE:
+		return &cookieInfo{
+			Domain:   "play.dlsite.com",
+			Name:     "play_session",
+			SameSite: http.SameSiteLaxMode,
+		}
 	default:
 		panic(
 			fmt.Errorf(
@@ -231,3 +237,177 @@ func ParseNetscapeCookieFile(filePath, sessionId, website string) ([]*http.Cooki
 	}
 	return cookies, nil
 }
+
+// Checks the expiry date of the parsed session cookies and prints a warning
+// if the cookie has expired or will expire within the given number of days.
+//
+// If strict is true, an error will be returned instead of printing a warning.
+// Session cookies without an expiry date are not checked.
+func CheckCookieExpiry(cookies []*http.Cookie, website string, warningDays int, strict bool) error {
+	now := time.Now()
+	warningDate := now.AddDate(0, 0, warningDays)
+	for _, cookie := range cookies {
+		if cookie.Expires.IsZero() || cookie.Expires.After(warningDate) {
+			continue
+		}
+
+		var msg string
+		expiry := cookie.Expires.Local().Format("2006-01-02 15:04:05")
+		if cookie.Expires.Before(now) {
+			msg = fmt.Sprintf(
+				"your %s session cookie has expired on %s, please export a new cookie file",
+				GetReadableSiteStr(website),
+				expiry,
+			)
+		} else {
+			msg = fmt.Sprintf(
+				"your %s session cookie will expire on %s, please export a new cookie file soon",
+				GetReadableSiteStr(website),
+				expiry,
+			)
+		}
+
+		if strict {
+			return fmt.Errorf("error %d: %s", AUTH_ERROR, msg)
+		}
+		color.Yellow("WARNING: " + msg)
+	}
+	return nil
+}
+
+// Writes the updated value and expiry date of the given cookie back to the cookie file
+// while leaving the other cookies in the file untouched.
+//
+// Used when the website has rotated the session cookie via the Set-Cookie header.
+func UpdateCookieFile(filePath string, cookie *http.Cookie) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: reading cookie file at %s, more info => %v",
+			OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+
+	var updatedData []byte
+	switch filepath.Ext(filePath) {
+	case ".txt":
+		lines := strings.Split(string(data), "\n")
+		for idx, line := range lines {
+			cookieInfos := strings.Split(strings.TrimRight(line, "\r"), "\t")
+			if len(cookieInfos) < 7 || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if cookieInfos[5] != cookie.Name || cookieInfos[0] != cookie.Domain {
+				continue
+			}
+
+			cookieInfos[6] = cookie.Value
+			if !cookie.Expires.IsZero() {
+				cookieInfos[4] = strconv.FormatInt(cookie.Expires.Unix(), 10)
+			}
+			lines[idx] = strings.Join(cookieInfos, "\t")
+		}
+		updatedData = []byte(strings.Join(lines, "\n"))
+	case ".json":
+		// decode into maps to preserve any fields not defined in ExportedCookies
+		var exportedCookies []map[string]interface{}
+		if err := json.Unmarshal(data, &exportedCookies); err != nil {
+			return fmt.Errorf(
+				"error %d: failed to decode cookie JSON file at %s, more info => %v",
+				JSON_ERROR,
+				filePath,
+				err,
+			)
+		}
+
+		for _, exportedCookie := range exportedCookies {
+			if exportedCookie["name"] != cookie.Name || exportedCookie["domain"] != cookie.Domain {
+				continue
+			}
+
+			exportedCookie["value"] = cookie.Value
+			if isSession, _ := exportedCookie["session"].(bool); !isSession && !cookie.Expires.IsZero() {
+				exportedCookie["expirationDate"] = float64(cookie.Expires.Unix())
+			}
+		}
+
+		updatedData, err = json.MarshalIndent(exportedCookies, "", "    ")
+		if err != nil {
+			return fmt.Errorf(
+				"error %d: failed to encode cookie JSON file at %s, more info => %v",
+				JSON_ERROR,
+				filePath,
+				err,
+			)
+		}
+	default:
+		return fmt.Errorf(
+			"error %d: invalid cookie file extension, %q, at %s...\nOnly .txt and .json files are supported",
+			INPUT_ERROR,
+			filepath.Ext(filePath),
+			filePath,
+		)
+	}
+
+	if err := WritePrivateFile(filePath, updatedData); err != nil {
+		return fmt.Errorf(
+			"error %d: writing updated cookie to cookie file at %s, more info => %v",
+			OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+	return nil
+}
+
+// Writes the given cookies to a Netscape cookie file at the given file path.
+//
+// The file is only readable and writable by the current user as it contains the session cookies.
+func WriteNetscapeCookieFile(filePath string, cookies []*http.Cookie) error {
+	lines := []string{
+		"# Netscape HTTP Cookie File",
+		"# Generated by Cultured Downloader CLI. Do not share this file with anyone!",
+		"",
+	}
+	for _, cookie := range cookies {
+		includeSubdomains := "FALSE"
+		if strings.HasPrefix(cookie.Domain, ".") {
+			includeSubdomains = "TRUE"
+		}
+		secure := "FALSE"
+		if cookie.Secure {
+			secure = "TRUE"
+		}
+		var expires int64
+		if !cookie.Expires.IsZero() {
+			expires = cookie.Expires.Unix()
+		}
+		path := cookie.Path
+		if path == "" {
+			path = "/"
+		}
+
+		lines = append(lines, strings.Join([]string{
+			cookie.Domain,
+			includeSubdomains,
+			path,
+			secure,
+			strconv.FormatInt(expires, 10),
+			cookie.Name,
+			cookie.Value,
+		}, "\t"))
+	}
+
+	os.MkdirAll(filepath.Dir(filePath), 0700)
+	if err := WritePrivateFile(filePath, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		return fmt.Errorf(
+			"error %d: writing cookie file at %s, more info => %v",
+			OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+	return nil
+}
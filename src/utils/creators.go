@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// Keep saving the posts in the creator's original folder after a rename
+	CREATOR_RENAME_KEEP = "keep"
+
+	// Rename the creator's original folder to the new name
+	CREATOR_RENAME_MOVE = "rename"
+
+	// Rename the creator's original folder to the new name and leave a symlink with the original name
+	CREATOR_RENAME_SYMLINK = "symlink"
+)
+
+var ACCEPTED_CREATOR_RENAMES = []string{CREATOR_RENAME_KEEP, CREATOR_RENAME_MOVE, CREATOR_RENAME_SYMLINK}
+
+type creatorName struct {
+	Name      string    `json:"name"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// The names a creator has used, oldest first, and the name of the folder their posts are saved in
+type creatorRecord struct {
+	Folder string         `json:"folder"`
+	Names  []*creatorName `json:"names"`
+}
+
+var (
+	creatorsMu sync.Mutex
+	creators   map[string]*creatorRecord // nil until loaded
+)
+
+// Returns the path to the file in the app data folder that stores the name history of the creators
+func GetCreatorsFilePath() string {
+	return filepath.Join(APP_PATH, "creators.json")
+}
+
+func loadCreators() (map[string]*creatorRecord, error) {
+	records := make(map[string]*creatorRecord)
+	creatorsFile, err := os.ReadFile(GetCreatorsFilePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return records, nil
+		}
+		return nil, fmt.Errorf(
+			"error %d: failed to read creators file, more info => %v",
+			OS_ERROR,
+			err,
+		)
+	}
+
+	if err = json.Unmarshal(creatorsFile, &records); err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to unmarshal creators file, more info => %v",
+			JSON_ERROR,
+			err,
+		)
+	}
+	return records, nil
+}
+
+func saveCreators(records map[string]*creatorRecord) error {
+	creatorsFile, err := json.MarshalIndent(records, "", "\t")
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to marshal creators, more info => %v",
+			JSON_ERROR,
+			err,
+		)
+	}
+
+	os.MkdirAll(APP_PATH, 0666)
+	if err = os.WriteFile(GetCreatorsFilePath(), creatorsFile, 0666); err != nil {
+		return fmt.Errorf(
+			"error %d: failed to write creators file, more info => %v",
+			OS_ERROR,
+			err,
+		)
+	}
+	return nil
+}
+
+// Moves the creator's folder from oldFolder to newFolder in the site folder
+// and leaves a symlink at the old folder that points to the new one if symlink is true.
+//
+// Returns the folder name to use which is oldFolder if the folder could not be moved.
+func moveCreatorFolder(siteFolderPath, oldFolder, newFolder string, symlink bool) string {
+	oldPath := filepath.Join(siteFolderPath, oldFolder)
+	newPath := filepath.Join(siteFolderPath, newFolder)
+	if info, err := os.Lstat(oldPath); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		// nothing has been downloaded to the old folder or it was already moved
+		return newFolder
+	}
+	if PathExists(newPath) {
+		LogError(
+			fmt.Errorf(
+				"error %d: cannot rename the creator folder %s to %s as it already exists, the posts will be saved in %s",
+				OS_ERROR,
+				oldPath,
+				newPath,
+				oldPath,
+			),
+			"",
+			false,
+			ERROR,
+		)
+		return oldFolder
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		LogError(
+			fmt.Errorf(
+				"error %d: failed to rename the creator folder %s to %s, more info => %v",
+				OS_ERROR,
+				oldPath,
+				newPath,
+				err,
+			),
+			"",
+			false,
+			ERROR,
+		)
+		return oldFolder
+	}
+	if symlink {
+		// a relative target so that the symlink still works if the download folder is moved
+		if err := os.Symlink(newFolder, oldPath); err != nil {
+			LogError(
+				fmt.Errorf(
+					"error %d: failed to create a symlink from %s to %s, more info => %v",
+					OS_ERROR,
+					oldPath,
+					newPath,
+					err,
+				),
+				"",
+				false,
+				ERROR,
+			)
+		}
+	}
+	return newFolder
+}
+
+// Records the creator's name in the name history and returns the name of the creator's folder
+// in the site folder which stays the same when the creator renames so that there are no duplicate
+// creator folders, unless onRename is CREATOR_RENAME_MOVE or CREATOR_RENAME_SYMLINK
+// in which case the original folder is renamed to the new name.
+//
+// Returns the creator's name if the creator ID is unknown, i.e. empty or "0" if it was missing from the API response.
+func ResolveCreatorFolder(siteFolderPath, site, creatorId, name, onRename string) string {
+	if creatorId == "" || creatorId == "0" {
+		return name
+	}
+
+	creatorsMu.Lock()
+	defer creatorsMu.Unlock()
+	if creators == nil {
+		records, err := loadCreators()
+		if err != nil {
+			LogError(err, "", false, ERROR)
+			return name
+		}
+		creators = records
+	}
+
+	key := site + ":" + creatorId
+	folder := CleanPathName(name)
+	record, ok := creators[key]
+	if !ok {
+		record = &creatorRecord{Folder: folder}
+		creators[key] = record
+	} else if len(record.Names) > 0 && record.Names[len(record.Names)-1].Name == name {
+		return record.Folder
+	} else if folder != record.Folder {
+		switch onRename {
+		case CREATOR_RENAME_MOVE, CREATOR_RENAME_SYMLINK:
+			record.Folder = moveCreatorFolder(siteFolderPath, record.Folder, folder, onRename == CREATOR_RENAME_SYMLINK)
+		}
+	}
+
+	record.Names = append(record.Names, &creatorName{Name: name, FirstSeen: time.Now()})
+	if err := saveCreators(creators); err != nil {
+		LogError(err, "", false, ERROR)
+	}
+	return record.Folder
+}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The shortcuts that can be used instead of the five fields of a cron expression
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// The names that can be used in the month and day of week fields
+var (
+	cronMonthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronDayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// CronSchedule is a parsed cron expression with the standard five fields,
+// "minute hour day-of-month month day-of-week", e.g. "0 3 * * *" for every day at 3am.
+type CronSchedule struct {
+	expr string
+
+	minutes     [60]bool
+	hours       [24]bool
+	daysOfMonth [32]bool
+	months      [13]bool
+	daysOfWeek  [7]bool
+
+	// if both the day of month and day of week are restricted,
+	// a day matches if either of them match like in the standard cron
+	domRestricted bool
+	dowRestricted bool
+}
+
+// Parses a field of a cron expression, e.g. "1-5", "*/15", or "mon,wed,fri",
+// and marks the matching values in the given slice which starts at min.
+//
+// Returns true if the field restricts the values, i.e. it does not start with "*" and is not "?".
+func parseCronField(field string, values []bool, min, max int, names []string) (bool, error) {
+	restricted := !strings.HasPrefix(field, "*") && field != "?"
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return false, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		start, end := min, max
+		if rangePart != "*" && rangePart != "?" {
+			startStr, endStr, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(startStr, min, names); err != nil {
+				return false, err
+			}
+			end = start
+			if isRange {
+				if end, err = parseCronValue(endStr, min, names); err != nil {
+					return false, err
+				}
+			} else if hasStep {
+				// e.g. "5/15" means every 15 starting from 5
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return false, fmt.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			values[value-min] = true
+		}
+	}
+	return restricted, nil
+}
+
+// Parses a number or a name, e.g. "jan", in a field of a cron expression
+func parseCronValue(value string, min int, names []string) (int, error) {
+	for idx, name := range names {
+		if strings.EqualFold(value, name) {
+			return idx + min, nil
+		}
+	}
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return num, nil
+}
+
+// Parses the cron expression with the standard five fields or one of the macros like "@daily"
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	fieldsStr := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(fieldsStr)]; ok {
+		fieldsStr = macro
+	}
+	fields := strings.Fields(fieldsStr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf(
+			"error %d: invalid cron expression %q, it must have 5 fields, \"minute hour day-of-month month day-of-week\", e.g. \"0 3 * * *\"",
+			INPUT_ERROR,
+			expr,
+		)
+	}
+
+	schedule := &CronSchedule{expr: expr}
+	var daysOfWeek [8]bool // 7 is also Sunday
+	var err error
+	if _, err = parseCronField(fields[0], schedule.minutes[:], 0, 59, nil); err == nil {
+		if _, err = parseCronField(fields[1], schedule.hours[:], 0, 23, nil); err == nil {
+			if schedule.domRestricted, err = parseCronField(fields[2], schedule.daysOfMonth[1:], 1, 31, nil); err == nil {
+				if _, err = parseCronField(fields[3], schedule.months[1:], 1, 12, cronMonthNames); err == nil {
+					schedule.dowRestricted, err = parseCronField(fields[4], daysOfWeek[:], 0, 7, cronDayNames)
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error %d: invalid cron expression %q, more info => %v",
+			INPUT_ERROR,
+			expr,
+			err,
+		)
+	}
+	copy(schedule.daysOfWeek[:], daysOfWeek[:7])
+	schedule.daysOfWeek[0] = schedule.daysOfWeek[0] || daysOfWeek[7]
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf(
+			"error %d: cron expression %q never runs, please check the day of month and month",
+			INPUT_ERROR,
+			expr,
+		)
+	}
+	return schedule, nil
+}
+
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// Returns true if the schedule runs on the day of the given time
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	if !s.months[t.Month()] {
+		return false
+	}
+	domMatch := s.daysOfMonth[t.Day()]
+	dowMatch := s.daysOfWeek[t.Weekday()]
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Returns the next time after the given time that the schedule runs at in the given time's location
+//
+// Returns the zero time if the schedule never runs, e.g. "0 0 30 2 *" for the 30th of February.
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// every valid day and month combination occurs within 8 years, e.g. the 29th of February
+	limit := t.AddDate(8, 0, 0)
+	for t.Before(limit) {
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
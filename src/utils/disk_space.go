@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Returns the number of bytes available to the user on the file system of the given path.
+//
+// The path does not have to exist yet, e.g. the folder of a post that has yet to be downloaded,
+// in which case the free space of its closest existing parent folder is returned.
+func GetFreeSpace(path string) (int64, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return -1, fmt.Errorf(
+			"error %d: failed to get the absolute path of %s, more info => %v",
+			OS_ERROR,
+			path,
+			err,
+		)
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			break
+		}
+		dir = parentDir
+	}
+
+	free, err := getFreeSpace(dir)
+	if err != nil {
+		return -1, fmt.Errorf(
+			"error %d: failed to get the free space of %s, more info => %v",
+			OS_ERROR,
+			dir,
+			err,
+		)
+	}
+	return free, nil
+}
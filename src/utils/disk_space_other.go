@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || windows)
+
+package utils
+
+import (
+	"errors"
+	"runtime"
+)
+
+func getFreeSpace(dir string) (int64, error) {
+	return -1, errors.New("getting the free space is not supported on " + runtime.GOOS)
+}
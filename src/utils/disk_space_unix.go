@@ -0,0 +1,13 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package utils
+
+import "syscall"
+
+func getFreeSpace(dir string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return -1, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	exitHooksMu sync.Mutex
+	exitHooks   []func()
+)
+
+// Registers a function to be called by Exit before the program exits,
+// e.g. to save the statistics of the run when it is stopped by a fatal error
+func RegisterExitHook(hook func()) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+// Calls the registered exit hooks in the reverse order that they were
+// registered and exits the program with the given exit code.
+//
+// Should be used instead of os.Exit for the errors that can stop a run of a download command.
+func Exit(code int) {
+	exitHooksMu.Lock()
+	hooks := exitHooks
+	exitHooks = nil // in case a hook calls Exit
+	exitHooksMu.Unlock()
+
+	for idx := len(hooks) - 1; idx >= 0; idx-- {
+		hooks[idx]()
+	}
+	os.Exit(code)
+}
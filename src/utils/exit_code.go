@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+)
+
+// Exit codes of the program so that schedulers can tell the outcome of a run apart
+const (
+	EXIT_OK              = 0 // everything was downloaded successfully
+	EXIT_ERROR           = 1 // an unexpected error that does not fit the other exit codes
+	EXIT_INTERRUPTED     = 2 // the program was stopped with Ctrl + C
+	EXIT_INPUT_ERROR     = 3 // invalid flags, arguments, config file, or text file
+	EXIT_AUTH_ERROR      = 4 // the session cookie or refresh token is invalid or has expired, or a CAPTCHA has to be solved
+	EXIT_NETWORK_ERROR   = 5 // the websites could not be reached
+	EXIT_PARTIAL_FAILURE = 6 // some posts or files could not be downloaded
+	EXIT_NOTHING_TO_DO   = 7 // there were no new files to download
+)
+
+// Returns the error code at the start of the error message, e.g. 1003 for "pixiv error 1003: ...", or 0 if there is none
+func GetErrorCode(err error) int {
+	matched := errPrefixRegex.FindStringSubmatch(err.Error())
+	if matched == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(matched[2])
+	return code
+}
+
+// Returns the exit code of the program based on the error code of the error
+func GetExitCode(err error) int {
+	switch GetErrorCode(err) {
+	case INPUT_ERROR:
+		return EXIT_INPUT_ERROR
+	case CONNECTION_ERROR:
+		return EXIT_NETWORK_ERROR
+	case AUTH_ERROR, CAPTCHA_ERROR:
+		return EXIT_AUTH_ERROR
+	default:
+		return EXIT_ERROR
+	}
+}
+
+// RunStatus keeps track of the downloaded files and logged errors of a run to determine its exit code
+type RunStatus struct {
+	events.BaseHandler
+
+	mu            sync.Mutex
+	downloaded    int
+	errCount      int
+	networkErrors int
+}
+
+func (s *RunStatus) OnFileDone(file *events.File, err error) {
+	if err != nil || file.Skipped {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.downloaded++
+}
+
+func (s *RunStatus) OnError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errCount++
+	if GetErrorCode(err) == CONNECTION_ERROR {
+		s.networkErrors++
+	}
+}
+
+// Returns the exit code of the run:
+//   - EXIT_NETWORK_ERROR if nothing was downloaded and all the errors were connection errors
+//   - EXIT_PARTIAL_FAILURE if any errors were logged
+//   - EXIT_NOTHING_TO_DO if no files were downloaded, e.g. all the files already exist
+//   - EXIT_OK otherwise
+func (s *RunStatus) ExitCode() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.errCount > 0 && s.downloaded == 0 && s.networkErrors == s.errCount:
+		return EXIT_NETWORK_ERROR
+	case s.errCount > 0:
+		return EXIT_PARTIAL_FAILURE
+	case s.downloaded == 0:
+		return EXIT_NOTHING_TO_DO
+	default:
+		return EXIT_OK
+	}
+}
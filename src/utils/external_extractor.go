@@ -0,0 +1,328 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// An external program, e.g. 7-Zip, that is used to extract the archives that archiver cannot extract
+type externalExtractor struct {
+	name string
+	path string
+
+	// listArgs and extractArgs return the arguments to list the file paths in the archive and to extract it.
+	//
+	// If hasPassword is true, the arguments should make the program ask for the password which
+	// is written to its stdin so that the password is not visible to other users in the process list.
+	listArgs    func(src string, hasPassword bool) []string
+	extractArgs func(src, dest string, hasPassword bool) []string
+
+	// parseList returns the entries in the archive from the output of the list command
+	parseList func(output []byte) []*externalArchiveEntry
+}
+
+// An entry in the archive listed by an external program
+type externalArchiveEntry struct {
+	path   string
+	isLink bool // a symbolic or hard link which could be used to write outside of the destination folder
+}
+
+// Returns the 7-Zip arguments for the password where "-p" with an empty password stops 7-Zip
+// from asking for the password of an encrypted archive, otherwise it is left out so that 7-Zip
+// asks for the password which is read from its stdin when it is not a terminal
+func getSevenZipPasswordArgs(hasPassword bool) []string {
+	if hasPassword {
+		return nil
+	}
+	return []string{"-p"}
+}
+
+func getSevenZipExtractor(path string) *externalExtractor {
+	return &externalExtractor{
+		name: "7-Zip",
+		path: path,
+		listArgs: func(src string, hasPassword bool) []string {
+			args := append([]string{"l", "-slt"}, getSevenZipPasswordArgs(hasPassword)...)
+			return append(args, "--", src)
+		},
+		extractArgs: func(src, dest string, hasPassword bool) []string {
+			args := append([]string{"x", "-y"}, getSevenZipPasswordArgs(hasPassword)...)
+			return append(args, "-o"+dest, "--", src)
+		},
+		parseList: func(output []byte) []*externalArchiveEntry {
+			// the archive's own details are listed before the "----------" line
+			// and each entry's details are separated by an empty line
+			var entries []*externalArchiveEntry
+			var entry *externalArchiveEntry
+			inEntries := false
+			scanner := bufio.NewScanner(bytes.NewReader(output))
+			for scanner.Scan() {
+				line := strings.TrimRight(scanner.Text(), "\r")
+				if line == "----------" {
+					inEntries = true
+					continue
+				}
+				if !inEntries {
+					continue
+				}
+
+				key, value, _ := strings.Cut(line, " = ")
+				switch key {
+				case "Path":
+					entry = &externalArchiveEntry{path: value}
+					entries = append(entries, entry)
+				case "Symbolic Link", "Hard Link":
+					if entry != nil && value != "" {
+						entry.isLink = true
+					}
+				case "Attributes":
+					// e.g. "A_ lrwxrwxrwx" where the Unix permissions of a symbolic link start with "l"
+					for _, attr := range strings.Fields(value) {
+						if len(attr) == 10 && attr[0] == 'l' && entry != nil {
+							entry.isLink = true
+						}
+					}
+				}
+			}
+			return entries
+		},
+	}
+}
+
+// Returns the UnRAR argument for the password where "-p-" stops UnRAR from asking for the password
+// of an encrypted archive and "-p" makes UnRAR ask for the password which is read from its stdin
+// when it is not a terminal
+func getUnrarPasswordArg(hasPassword bool) string {
+	if hasPassword {
+		return "-p"
+	}
+	return "-p-"
+}
+
+func getUnrarExtractor(path string) *externalExtractor {
+	return &externalExtractor{
+		name: "UnRAR",
+		path: path,
+		listArgs: func(src string, hasPassword bool) []string {
+			return []string{"lt", getUnrarPasswordArg(hasPassword), "--", src}
+		},
+		extractArgs: func(src, dest string, hasPassword bool) []string {
+			// the trailing separator tells UnRAR that the destination is a folder
+			return []string{"x", "-o+", "-y", getUnrarPasswordArg(hasPassword), "--", src, dest + string(filepath.Separator)}
+		},
+		parseList: func(output []byte) []*externalArchiveEntry {
+			// the technical listing has a "Name: " line followed by a "Type: " line for each entry
+			var entries []*externalArchiveEntry
+			var entry *externalArchiveEntry
+			scanner := bufio.NewScanner(bytes.NewReader(output))
+			for scanner.Scan() {
+				key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ": ")
+				if !ok {
+					continue
+				}
+				switch key {
+				case "Name":
+					entry = &externalArchiveEntry{path: value}
+					entries = append(entries, entry)
+				case "Type":
+					// e.g. "Unix symbolic link", "Hard link", or "Junction"
+					value = strings.ToLower(value)
+					if entry != nil && (strings.Contains(value, "link") || strings.Contains(value, "junction")) {
+						entry.isLink = true
+					}
+				}
+			}
+			return entries
+		},
+	}
+}
+
+// Returns the external programs in the tools section of the config file that can extract the archive
+func getExternalExtractors(src string) []*externalExtractor {
+	config, err := LoadConfigFile()
+	if err != nil || config.Tools == nil {
+		return nil
+	}
+
+	var extractors []*externalExtractor
+	if config.Tools.UnrarPath != "" && strings.EqualFold(filepath.Ext(src), ".rar") {
+		extractors = append(extractors, getUnrarExtractor(config.Tools.UnrarPath))
+	}
+	if config.Tools.SevenZipPath != "" {
+		// 7-Zip is tried for any archive as it supports more
+		// formats and compression methods than archiver, e.g. 7z with BCJ2
+		extractors = append(extractors, getSevenZipExtractor(config.Tools.SevenZipPath))
+	}
+	return extractors
+}
+
+// Returns an error if any of the extracted files in the folder is a link or a special file, e.g. a device,
+// as the external programs create the links in the archive which can point to anywhere on the system
+func checkExtractedFiles(src, folder string) error {
+	return filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Type().IsRegular() {
+			return nil
+		}
+
+		relPath, _ := filepath.Rel(folder, path)
+		return fmt.Errorf(
+			"error %d: refusing to extract %s as %q in it is a link or a special file",
+			INPUT_ERROR,
+			src,
+			relPath,
+		)
+	})
+}
+
+// Moves the checked files extracted to the temporary folder into the destination folder
+func moveExtractedFiles(tmpDest, dest string) error {
+	return filepath.WalkDir(tmpDest, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(tmpDest, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+
+		extractedFilePath, err := getSafeExtractPath(dest, relPath)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(extractedFilePath, extractedFolderPerm)
+		}
+		return os.Rename(path, extractedFilePath)
+	})
+}
+
+// Returns the command to run the external program with the given arguments
+// where the password, if any, is written to its stdin instead of being passed as an argument
+func (e *externalExtractor) command(ctx context.Context, args []string, password string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, e.path, args...)
+	if password != "" {
+		cmd.Stdin = strings.NewReader(password + "\n")
+	}
+	return cmd
+}
+
+// Extracts the archive with the external program after checking that none of the files in the archive
+// will be extracted outside of the destination folder and that the archive does not contain any links.
+//
+// As the links may not be listed by every version of the programs, the archive is extracted to a temporary
+// folder next to the destination folder first and its files are only moved into the destination folder
+// if none of the extracted files are links either.
+func (e *externalExtractor) extract(ctx context.Context, src, dest, password string) error {
+	output, err := e.command(ctx, e.listArgs(src, password != ""), password).Output()
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return context.Canceled
+		}
+		return fmt.Errorf(
+			"error %d: failed to list the files in %s with %s at %s, more info => %v",
+			CMD_ERROR,
+			src,
+			e.name,
+			e.path,
+			err,
+		)
+	}
+	for _, entry := range e.parseList(output) {
+		if _, err := getSafeExtractPath(dest, entry.path); err != nil {
+			return err
+		}
+		if entry.isLink {
+			// the programs would create the link and could then write the next entries through it
+			return fmt.Errorf(
+				"error %d: refusing to extract %s as %q in it is a link",
+				INPUT_ERROR,
+				src,
+				entry.path,
+			)
+		}
+	}
+
+	os.MkdirAll(filepath.Dir(dest), extractedFolderPerm)
+	tmpDest, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".extracting-*")
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to create a temporary folder to extract %s to, more info => %v",
+			OS_ERROR,
+			src,
+			err,
+		)
+	}
+	defer os.RemoveAll(tmpDest)
+
+	cmd := e.command(ctx, e.extractArgs(src, tmpDest, password != ""), password)
+	if DEBUG_MODE {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.Canceled {
+			return context.Canceled
+		}
+		return fmt.Errorf(
+			"error %d: failed to extract %s with %s at %s, more info => %v",
+			CMD_ERROR,
+			src,
+			e.name,
+			e.path,
+			err,
+		)
+	}
+
+	if err := checkExtractedFiles(src, tmpDest); err != nil {
+		return err
+	}
+	os.MkdirAll(dest, extractedFolderPerm)
+	if err := moveExtractedFiles(tmpDest, dest); err != nil {
+		return fmt.Errorf(
+			"error %d: failed to move the files extracted from %s to %s, more info => %v",
+			OS_ERROR,
+			src,
+			dest,
+			err,
+		)
+	}
+	return nil
+}
+
+// Extracts the archive with the external programs in the tools section of the config file, if any,
+// and returns the given archiver error if there are none or the errors of all the programs that failed.
+func extractWithExternalTools(ctx context.Context, src, dest, password string, archiverErr error) error {
+	extractors := getExternalExtractors(src)
+	if len(extractors) == 0 {
+		return archiverErr
+	}
+
+	errMsgs := []string{archiverErr.Error()}
+	for _, extractor := range extractors {
+		err := extractor.extract(ctx, src, dest, password)
+		if err == nil || err == context.Canceled {
+			if err == context.Canceled {
+				// delete all the files that were extracted
+				os.RemoveAll(dest)
+			}
+			return err
+		}
+		errMsgs = append(errMsgs, err.Error())
+	}
+	return fmt.Errorf(
+		"error %d: unable to extract %s with any of the configured extractors, more info => %s",
+		OS_ERROR,
+		src,
+		strings.Join(errMsgs, "; "),
+	)
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+const (
+	FFMPEG_DOWNLOAD_URL = "https://ffmpeg.org/download.html"
+
+	// The oldest major version of FFmpeg that supports all the flags used by the program,
+	// e.g. the ffconcat durations for converting Pixiv ugoira
+	MIN_FFMPEG_MAJOR_VERSION = 4
+)
+
+// Matches the version in the first line of "ffmpeg -version", e.g.
+// "ffmpeg version 6.0-full_build-www.gyan.dev" or "ffmpeg version n5.1.2"
+var ffmpegVersionRegex = regexp.MustCompile(`^ffmpeg version n?(\S+)`)
+
+// Matches the major version at the start of a release version, e.g. "6" in "6.0-full_build-www.gyan.dev"
+var ffmpegMajorVersionRegex = regexp.MustCompile(`^(\d+)\.`)
+
+// Returns the path to the FFmpeg executable to use in the order of the given path, e.g. from the
+// "--ffmpeg_path" flag, the "ffmpeg_path" in the tools section of the config file, and "ffmpeg" in the PATH.
+//
+// The returned path will be an absolute path if FFmpeg was found, otherwise an error is returned.
+func FindFfmpeg(ffmpegPath string) (string, error) {
+	if ffmpegPath == "" {
+		if config, err := LoadConfigFile(); err == nil && config.Tools != nil {
+			ffmpegPath = config.Tools.FfmpegPath
+		}
+	}
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+
+	foundPath, err := exec.LookPath(ffmpegPath)
+	if err != nil {
+		return "", fmt.Errorf(
+			"error %d: FFmpeg could not be found at %q, more info => %v",
+			CMD_ERROR,
+			ffmpegPath,
+			err,
+		)
+	}
+	if absPath, err := filepath.Abs(foundPath); err == nil {
+		foundPath = absPath
+	}
+	return foundPath, nil
+}
+
+// Returns the version of the FFmpeg executable, e.g. "6.0-full_build-www.gyan.dev"
+func GetFfmpegVersion(ffmpegPath string) (string, error) {
+	output, err := exec.Command(ffmpegPath, "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf(
+			"error %d: failed to get the version of FFmpeg at %s, more info => %v",
+			CMD_ERROR,
+			ffmpegPath,
+			err,
+		)
+	}
+
+	firstLine, _, _ := bytes.Cut(output, []byte("\n"))
+	matched := ffmpegVersionRegex.FindSubmatch(bytes.TrimSpace(firstLine))
+	if matched == nil {
+		return "", fmt.Errorf(
+			"error %d: %s does not seem to be FFmpeg as its version could not be parsed from %q",
+			CMD_ERROR,
+			ffmpegPath,
+			firstLine,
+		)
+	}
+	return string(matched[1]), nil
+}
+
+// Returns false if the FFmpeg version is older than MIN_FFMPEG_MAJOR_VERSION.
+//
+// Versions that are not release versions, e.g. "N-112345-g1234567" for git builds, are assumed to be supported.
+func IsFfmpegVersionSupported(version string) bool {
+	matched := ffmpegMajorVersionRegex.FindStringSubmatch(version)
+	if matched == nil {
+		return true
+	}
+	majorVersion, err := strconv.Atoi(matched[1])
+	if err != nil {
+		return true
+	}
+	return majorVersion >= MIN_FFMPEG_MAJOR_VERSION
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/mholt/archiver/v4"
 )
@@ -16,42 +18,153 @@ type archiveExtractor struct {
 	ex         archiver.Extractor
 }
 
-func extractFileLogic(ctx context.Context, src, dest string, extractor *archiveExtractor) error {
-	handler := func(ctx context.Context, file archiver.File) error {
-		extractedFilePath := filepath.Join(dest, file.NameInArchive)
-		os.MkdirAll(filepath.Dir(extractedFilePath), 0666)
+// Default permissions of the extracted files and folders as the permissions
+// in the archive may be missing, e.g. for zip files created on Windows
+const (
+	extractedFilePerm   = 0644
+	extractedFolderPerm = 0755
+)
 
-		af, err := file.Open()
-		if err != nil {
-			return err
-		}
-		defer af.Close()
+// ArchiveEntry contains the details of a file or folder in an archive
+type ArchiveEntry struct {
+	Name    string // the path of the entry in the archive
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// Returns the permissions to extract the file with which keeps
+// the archive's permissions but ensures that the owner can read and write it
+func getExtractedFilePerm(file archiver.File) os.FileMode {
+	perm := file.Mode().Perm()
+	if perm == 0 {
+		return extractedFilePerm
+	}
+	return perm | 0600
+}
+
+// Writes the file in the archive to the extracted file path with its modification time
+func extractFile(file archiver.File, extractedFilePath string) error {
+	os.MkdirAll(filepath.Dir(extractedFilePath), extractedFolderPerm)
+
+	af, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer af.Close()
 
-		out, err := os.OpenFile(
-			extractedFilePath,
-			os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
-			file.Mode(),
+	out, err := os.OpenFile(
+		extractedFilePath,
+		os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+		getExtractedFilePerm(file),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, af)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if modTime := file.ModTime(); !modTime.IsZero() {
+		return os.Chtimes(extractedFilePath, modTime, modTime)
+	}
+	return nil
+}
+
+// Returns the path to extract the archive entry to.
+//
+// Since archives are from untrusted sources, an error is returned if the entry's name is an absolute path
+// or escapes the destination folder, e.g. "../../.bashrc", including through an existing symbolic link in it.
+func getSafeExtractPath(dest, nameInArchive string) (string, error) {
+	// the separators are normalised as zip files created on Windows may use backslashes
+	name := filepath.FromSlash(strings.ReplaceAll(nameInArchive, "\\", "/"))
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || strings.HasPrefix(name, string(filepath.Separator)) {
+		return "", fmt.Errorf(
+			"error %d: refusing to extract %q as it is an absolute path",
+			INPUT_ERROR,
+			nameInArchive,
 		)
-		if err != nil {
-			return err
-		}
-		defer out.Close()
+	}
+
+	extractedFilePath := filepath.Join(dest, name)
+	if !isWithinFolder(dest, extractedFilePath) {
+		return "", fmt.Errorf(
+			"error %d: refusing to extract %q as it is outside of the destination folder",
+			INPUT_ERROR,
+			nameInArchive,
+		)
+	}
+
+	// check the parent folders that already exist for symbolic links that point outside of the destination folder
+	realDest, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		// the destination folder has not been created yet
+		return extractedFilePath, nil
+	}
+	existingParent := filepath.Dir(extractedFilePath)
+	for !PathExists(existingParent) {
+		existingParent = filepath.Dir(existingParent)
+	}
+	realParent, err := filepath.EvalSymlinks(existingParent)
+	if err != nil || !isWithinFolder(realDest, realParent) {
+		return "", fmt.Errorf(
+			"error %d: refusing to extract %q as it resolves to outside of the destination folder",
+			INPUT_ERROR,
+			nameInArchive,
+		)
+	}
+	return extractedFilePath, nil
+}
+
+// Returns true if the path is the folder itself or is inside the folder
+func isWithinFolder(folder, path string) bool {
+	rel, err := filepath.Rel(folder, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
 
-		_, err = io.Copy(out, af)
+func extractFileLogic(ctx context.Context, src, dest string, extractor *archiveExtractor) error {
+	// the modification times of the folders are restored after all the files have
+	// been extracted since extracting a file into a folder updates its modification time
+	type folderModTime struct {
+		path    string
+		modTime time.Time
+	}
+	var folderModTimes []folderModTime
+	handler := func(ctx context.Context, file archiver.File) error {
+		extractedFilePath, err := getSafeExtractPath(dest, file.NameInArchive)
 		if err != nil {
 			return err
 		}
-		return nil
-	}
 
-	var input io.Reader
-	if extractor.readCloser != nil {
-		input = extractor.readCloser
-	} else {
-		input = extractor.reader
+		if file.LinkTarget != "" || file.Mode()&os.ModeSymlink != 0 {
+			// links are never created as they can point to anywhere on the system
+			return nil
+		}
+		if file.IsDir() {
+			if err := os.MkdirAll(extractedFilePath, extractedFolderPerm); err != nil {
+				return err
+			}
+			if modTime := file.ModTime(); !modTime.IsZero() {
+				folderModTimes = append(folderModTimes, folderModTime{path: extractedFilePath, modTime: modTime})
+			}
+			return nil
+		}
+		if file.Open == nil {
+			// other entries without any content, e.g. devices
+			return nil
+		}
+		return extractFile(file, extractedFilePath)
 	}
 
-	err := extractor.ex.Extract(ctx, input, nil, handler)
+	err := walkArchive(ctx, extractor, handler)
 	if err != nil {
 		if err == context.Canceled {
 			// delete all the files that were extracted
@@ -68,10 +181,27 @@ func extractFileLogic(ctx context.Context, src, dest string, extractor *archiveE
 			err,
 		)
 	}
+
+	// nested folders are restored first
+	for idx := len(folderModTimes) - 1; idx >= 0; idx-- {
+		folder := folderModTimes[idx]
+		os.Chtimes(folder.path, folder.modTime, folder.modTime)
+	}
 	return nil
 }
 
-func getExtractor(f *os.File, src string) (*archiveExtractor, error) {
+// Calls the handler for each file and folder in the archive
+func walkArchive(ctx context.Context, extractor *archiveExtractor, handler archiver.FileHandler) error {
+	var input io.Reader
+	if extractor.readCloser != nil {
+		input = extractor.readCloser
+	} else {
+		input = extractor.reader
+	}
+	return extractor.ex.Extract(ctx, input, nil, handler)
+}
+
+func getExtractor(f *os.File, src, password string) (*archiveExtractor, error) {
 	format, archiveReader, err := archiver.Identify(
 		filepath.Base(src),
 		f,
@@ -86,6 +216,17 @@ func getExtractor(f *os.File, src string) (*archiveExtractor, error) {
 		return nil, err
 	}
 
+	// only RAR and 7z archives can be decrypted by archiver,
+	// the other encrypted archives, e.g. zip files, are extracted by the external programs instead
+	switch archiveFormat := format.(type) {
+	case archiver.Rar:
+		archiveFormat.Password = password
+		format = archiveFormat
+	case archiver.SevenZip:
+		archiveFormat.Password = password
+		format = archiveFormat
+	}
+
 	var rc io.ReadCloser
 	if decom, ok := format.(archiver.Decompressor); ok {
 		rc, err = decom.OpenReader(archiveReader)
@@ -121,36 +262,102 @@ func getErrIfNotIgnored(src string, ignoreIfMissing bool) error {
 	)
 }
 
-// Extract all files from the given archive file to the given destination
+// Opens the archive file and returns its extractor and a function to close the archive file
 //
-// Code based on https://stackoverflow.com/a/24792688/2737403
-func ExtractFiles(ctx context.Context, src, dest string, ignoreIfMissing bool) error {
-	if !PathExists(src) {
-		return getErrIfNotIgnored(src, ignoreIfMissing)
-	}
-
+// The password is only used for encrypted archives and can be empty.
+func openArchive(src, password string) (*archiveExtractor, func(), error) {
 	f, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf(
+		return nil, nil, fmt.Errorf(
 			"error %d: unable to open zip file %s",
 			OS_ERROR,
 			src,
 		)
 	}
-	defer f.Close()
 
-	extractor, err := getExtractor(f, src)
+	extractor, err := getExtractor(f, src, password)
 	if err != nil {
-		return err
+		f.Close()
+		return nil, nil, err
 	}
 
-	if extractor.readCloser != nil {
-		defer extractor.readCloser.Close()
+	closeArchive := func() {
+		if extractor.readCloser != nil {
+			extractor.readCloser.Close()
+		}
+		f.Close()
 	}
-	return extractFileLogic(
+	return extractor, closeArchive, nil
+}
+
+// Extract all files from the given archive file to the given destination
+// while restoring their modification times and permissions.
+//
+// If the archive cannot be extracted, the "unrar_path" and "7z_path" in the
+// tools section of the config file will be used to extract it instead, if set.
+//
+// Code based on https://stackoverflow.com/a/24792688/2737403
+func ExtractFiles(ctx context.Context, src, dest string, ignoreIfMissing bool) error {
+	if !PathExists(src) {
+		return getErrIfNotIgnored(src, ignoreIfMissing)
+	}
+	return extractArchive(ctx, src, dest, "")
+}
+
+// Extracts the archive with the password which is only used if the archive is encrypted
+func extractArchive(ctx context.Context, src, dest, password string) error {
+	extractor, closeArchive, err := openArchive(src, password)
+	if err != nil {
+		return extractWithExternalTools(ctx, src, dest, password, err)
+	}
+	defer closeArchive()
+
+	err = extractFileLogic(
 		ctx, 
 		src, 
 		dest,
 		extractor,
 	)
+	if err != nil && err != context.Canceled {
+		// e.g. RAR5 archives or 7z archives with compression methods that are not supported by archiver
+		return extractWithExternalTools(ctx, src, dest, password, err)
+	}
+	return err
+}
+
+// Returns the files and folders in the given archive file without extracting them,
+// e.g. to preview what ExtractFiles will extract in a dry run.
+func ListArchiveFiles(ctx context.Context, src string) ([]*ArchiveEntry, error) {
+	if !PathExists(src) {
+		return nil, getErrIfNotIgnored(src, false)
+	}
+
+	extractor, closeArchive, err := openArchive(src, "")
+	if err != nil {
+		return nil, err
+	}
+	defer closeArchive()
+
+	var entries []*ArchiveEntry
+	err = walkArchive(ctx, extractor, func(ctx context.Context, file archiver.File) error {
+		entries = append(entries, &ArchiveEntry{
+			Name:    file.NameInArchive,
+			Size:    file.Size(),
+			ModTime: file.ModTime(),
+			IsDir:   file.IsDir(),
+		})
+		return nil
+	})
+	if err != nil {
+		if err == context.Canceled {
+			return nil, err
+		}
+		return nil, fmt.Errorf(
+			"error %d: unable to list the files in zip file %s, more info => %v",
+			OS_ERROR,
+			src,
+			err,
+		)
+	}
+	return entries, nil
 }
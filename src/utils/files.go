@@ -3,10 +3,14 @@ package utils
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // checks if a file or directory exists
@@ -63,49 +67,561 @@ func removeIllegalRuneInPath(r rune) rune {
 // Removes any illegal characters in a path name
 // to prevent any error with file I/O using the path name
 func CleanPathName(pathName string) string {
-	pathName = strings.TrimSpace(pathName)
-	if len(pathName) > 255 {
-		pathName = pathName[:255]
-	}
+	pathName = truncatePathName(strings.TrimSpace(pathName))
 	return strings.Map(removeIllegalRuneInPath, pathName)
 }
 
+// Truncates the path name to 255 bytes, which is the limit for most file systems,
+// without splitting any multi-byte characters
+func truncatePathName(pathName string) string {
+	if len(pathName) <= 255 {
+		return pathName
+	}
+
+	cutIdx := 0
+	for idx, r := range pathName {
+		runeEndIdx := idx + utf8.RuneLen(r)
+		if runeEndIdx > 255 {
+			break
+		}
+		cutIdx = runeEndIdx
+	}
+	return pathName[:cutIdx]
+}
+
+// Truncates the string to the given number of characters without splitting any multi-byte characters
+//
+// If maxLen is less than 1, the string will be returned as it is.
+func TruncateString(str string, maxLen int) string {
+	if maxLen < 1 || utf8.RuneCountInString(str) <= maxLen {
+		return str
+	}
+	return strings.TrimSpace(string([]rune(str)[:maxLen]))
+}
+
 // Returns a directory path for a post, artwork, etc.
 // based on the user's saved download path and the provided arguments
 func GetPostFolder(downloadPath, creatorName, postId, postTitle string) string {
 	creatorName = CleanPathName(creatorName)
 	postTitle = CleanPathName(postTitle)
+	postFolderName := truncatePathName(
+		fmt.Sprintf("[%s] %s", postId, postTitle),
+	)
 
 	postFolderPath := filepath.Join(
 		downloadPath,
 		creatorName,
+		postFolderName,
+	)
+	return postFolderPath
+}
+
+// Returns a directory path for a post like GetPostFolder but grouped
+// by the year and month of the post's publish date, e.g. "<creator>/2023/04/[12345] Post Title"
+//
+// If the publish date is unknown, i.e. the zero time, the post will not be grouped.
+func GetDatedPostFolder(downloadPath, creatorName, postId, postTitle string, publishedAt time.Time) string {
+	if publishedAt.IsZero() {
+		return GetPostFolder(downloadPath, creatorName, postId, postTitle)
+	}
+
+	creatorName = CleanPathName(creatorName)
+	postTitle = CleanPathName(postTitle)
+	postFolderName := truncatePathName(
 		fmt.Sprintf("[%s] %s", postId, postTitle),
 	)
+
+	postFolderPath := filepath.Join(
+		downloadPath,
+		creatorName,
+		publishedAt.Format("2006"),
+		publishedAt.Format("01"),
+		postFolderName,
+	)
 	return postFolderPath
 }
 
+// Parses the publish date of a post from the APIs of the supported websites
+//
+// Returns the zero time if the date string is not in any of the known formats.
+func ParsePostDate(dateStr string) time.Time {
+	for _, layout := range POST_DATE_LAYOUTS {
+		if publishedAt, err := time.Parse(layout, dateStr); err == nil {
+			return publishedAt
+		}
+	}
+	return time.Time{}
+}
+
 type ConfigFile struct {
 	DownloadDir string `json:"download_directory"`
 	Language    string `json:"language"`
+
+	// CookieFiles maps the website, e.g. "fantia", to the path of its cookie file
+	CookieFiles map[string]string `json:"cookie_files,omitempty"`
+
+	// Gdrive contains the default values for the GDrive flags of the download commands
+	Gdrive *GdriveConfig `json:"gdrive,omitempty"`
+
+	// UserAgent is the default User-Agent header used if the "--user_agent" flag is not given
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// SiteUserAgents maps the website, e.g. "fantia", to the User-Agent header
+	// to use for it which takes precedence over UserAgent
+	SiteUserAgents map[string]string `json:"site_user_agents,omitempty"`
+
+	// UserAgents are rotated through for each request if no User-Agent header was set by
+	// the "--user_agent" flag, UserAgent, or SiteUserAgents
+	UserAgents []string `json:"user_agents,omitempty"`
+
+	// HostHeaders maps a domain, e.g. "fantia.jp", to the headers
+	// to add to the requests sent to it and its subdomains
+	HostHeaders map[string]map[string]string `json:"host_headers,omitempty"`
+
+	// HostLimits maps a domain, e.g. "kemono.party" or "pximg.net",
+	// to the limits of the requests sent to it and its subdomains
+	HostLimits map[string]*HostLimitConfig `json:"host_limits,omitempty"`
+
+	// Tools contains the paths to the external programs used by the program, e.g. FFmpeg
+	Tools *ToolsConfig `json:"tools,omitempty"`
+
+	// BandwidthLimits limit the download speed during the given times of the day
+	// where the first matching limit is used and the download speed is unlimited if none match
+	BandwidthLimits []*BandwidthLimitConfig `json:"bandwidth_limits,omitempty"`
+
+	// Mirror copies the downloaded creator folders to an rclone remote after each run
+	Mirror *MirrorConfig `json:"mirror,omitempty"`
+
+	// Storage is where the downloaded files are written to, the download directory on the local disk if nil
+	Storage *StorageConfig `json:"storage,omitempty"`
+
+	// FeedFile is the RSS feed that the newly downloaded posts of each run are added to,
+	// e.g. to follow the archive in a feed reader when running the serve command or scheduled runs
+	FeedFile string `json:"feed_file,omitempty"`
+
+	// Progress is the appearance of the spinners shown for each stage of a run, e.g. getting the post details
+	Progress *ProgressConfig `json:"progress,omitempty"`
+}
+
+// Paths to the external programs where an empty path means that it will be searched for in the PATH
+type ToolsConfig struct {
+	FfmpegPath string `json:"ffmpeg_path,omitempty"`
+	RclonePath string `json:"rclone_path,omitempty"`
+
+	// SevenZipPath and UnrarPath are only used to extract the archives that
+	// cannot be extracted by the program itself, hence, they are not searched for in the PATH
+	SevenZipPath string `json:"7z_path,omitempty"`
+	UnrarPath    string `json:"unrar_path,omitempty"`
+
+	// GalleryDlPath and YtDlpPath are used to download the media of the Twitter/X links
+	// in the posts where gallery-dl is preferred over yt-dlp if both are configured
+	GalleryDlPath string `json:"gallery_dl_path,omitempty"`
+	YtDlpPath     string `json:"yt_dlp_path,omitempty"`
+}
+
+// Limits of the requests sent to a host where a zero value means no limit
+type HostLimitConfig struct {
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	MaxConnections    int     `json:"max_connections,omitempty"`
+}
+
+// Returns an error if the limits of the given host are negative
+func (h *HostLimitConfig) Validate(host string) error {
+	if h.RequestsPerSecond < 0 {
+		return fmt.Errorf(
+			"error %d: requests per second for %s must not be negative but got %v",
+			INPUT_ERROR,
+			host,
+			h.RequestsPerSecond,
+		)
+	}
+	if h.MaxConnections < 0 {
+		return fmt.Errorf(
+			"error %d: max connections for %s must not be negative but got %d",
+			INPUT_ERROR,
+			host,
+			h.MaxConnections,
+		)
+	}
+	return nil
+}
+
+// Limit of the download speed during a time of the day
+type BandwidthLimitConfig struct {
+	// Start and End are the local times of the day in the format of "HH:MM", e.g. "08:00" and "23:00".
+	// The limit applies overnight if End is before Start, e.g. "23:00" to "07:00",
+	// and all day if they are the same.
+	Start string `json:"start"`
+	End   string `json:"end"`
+
+	// Limit is the maximum download speed per second, e.g. "1MB"
+	Limit string `json:"limit"`
+}
+
+// Returns the time of the day in the format of "HH:MM" as the duration since midnight
+func parseTimeOfDay(timeStr string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", timeStr)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"error %d: invalid time of the day, %q, please use a format like \"08:00\" or \"23:30\"",
+			INPUT_ERROR,
+			timeStr,
+		)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// Returns the start and end of the limit as the durations since midnight and the limit in bytes per second
+func (b *BandwidthLimitConfig) Parse() (start, end time.Duration, bytesPerSecond int64, err error) {
+	if start, err = parseTimeOfDay(b.Start); err != nil {
+		return 0, 0, 0, err
+	}
+	if end, err = parseTimeOfDay(b.End); err != nil {
+		return 0, 0, 0, err
+	}
+	if bytesPerSecond, err = ParseFileSizeStr(b.Limit); err != nil {
+		return 0, 0, 0, err
+	}
+	if bytesPerSecond <= 0 {
+		return 0, 0, 0, fmt.Errorf(
+			"error %d: bandwidth limit from %s to %s must be more than 0 but got %q",
+			INPUT_ERROR,
+			b.Start,
+			b.End,
+			b.Limit,
+		)
+	}
+	return start, end, bytesPerSecond, nil
+}
+
+const (
+	PROGRESS_SPINNER = "spinner"
+	PROGRESS_LINE    = "line"
+
+	VERBOSITY_FULL    = "full"
+	VERBOSITY_COMPACT = "compact"
+)
+
+// Appearance of the spinners where an empty field uses the default of each spinner
+type ProgressConfig struct {
+	// Mode is either PROGRESS_SPINNER, the default, for the animated spinners
+	// or PROGRESS_LINE to only print a line with the outcome of each stage
+	Mode string `json:"mode,omitempty"`
+
+	// Style is the spinner type used for all the spinners, e.g. "dots"
+	Style string `json:"style,omitempty"`
+
+	// Colour is the colour of the spinners and SuccessColour and ErrorColour
+	// are the colours of the outcome messages, e.g. "cyan" or "fgHiCyan"
+	Colour        string `json:"colour,omitempty"`
+	SuccessColour string `json:"success_colour,omitempty"`
+	ErrorColour   string `json:"error_colour,omitempty"`
+
+	// Verbosity is either VERBOSITY_FULL, the default, or VERBOSITY_COMPACT to leave out
+	// the extra info in the spinner messages, e.g. the name of the last downloaded file
+	Verbosity string `json:"verbosity,omitempty"`
+}
+
+// Returns an error if the mode or the verbosity is invalid
+//
+// The style and colours are validated by the spinner package which knows the supported values.
+func (p *ProgressConfig) Validate() error {
+	if p.Mode != "" && p.Mode != PROGRESS_SPINNER && p.Mode != PROGRESS_LINE {
+		return fmt.Errorf(
+			"error %d: the progress mode must be either %q or %q but got %q",
+			INPUT_ERROR,
+			PROGRESS_SPINNER,
+			PROGRESS_LINE,
+			p.Mode,
+		)
+	}
+	if p.Verbosity != "" && p.Verbosity != VERBOSITY_FULL && p.Verbosity != VERBOSITY_COMPACT {
+		return fmt.Errorf(
+			"error %d: the progress verbosity must be either %q or %q but got %q",
+			INPUT_ERROR,
+			VERBOSITY_FULL,
+			VERBOSITY_COMPACT,
+			p.Verbosity,
+		)
+	}
+	return nil
+}
+
+const (
+	MIRROR_COPY = "copy"
+	MIRROR_SYNC = "sync"
+)
+
+// Where and how the downloaded creator folders are mirrored with rclone after each run
+type MirrorConfig struct {
+	// Remote is the rclone remote and path to mirror the download directory to, e.g. "b2:my-bucket/archive".
+	// The creator folders are mirrored to the same path relative to the download directory on the remote.
+	Remote string `json:"remote"`
+
+	// Mode is either MIRROR_COPY, the default, to only add and update the files on the remote
+	// or MIRROR_SYNC to also delete the files on the remote that are not in the creator folders
+	Mode string `json:"mode,omitempty"`
+
+	// Args are the extra flags to pass to rclone, e.g. ["--transfers", "8"]
+	Args []string `json:"args,omitempty"`
+}
+
+// Returns an error if the remote is empty or the mode is invalid
+func (m *MirrorConfig) Validate() error {
+	if m.Remote == "" {
+		return fmt.Errorf(
+			"error %d: the mirror remote must not be empty, e.g. \"b2:my-bucket/archive\"",
+			INPUT_ERROR,
+		)
+	}
+	if m.Mode != "" && m.Mode != MIRROR_COPY && m.Mode != MIRROR_SYNC {
+		return fmt.Errorf(
+			"error %d: the mirror mode must be either %q or %q but got %q",
+			INPUT_ERROR,
+			MIRROR_COPY,
+			MIRROR_SYNC,
+			m.Mode,
+		)
+	}
+	return nil
+}
+
+const (
+	STORAGE_LOCAL  = "local"
+	STORAGE_S3     = "s3"
+	STORAGE_WEBDAV = "webdav"
+	STORAGE_SFTP   = "sftp"
+)
+
+// Where the downloaded files are written to instead of the download directory on the local disk
+type StorageConfig struct {
+	// Type is the storage backend to use, e.g. STORAGE_S3, or STORAGE_LOCAL if empty
+	Type string `json:"type,omitempty"`
+
+	S3     *S3StorageConfig     `json:"s3,omitempty"`
+	Webdav *WebdavStorageConfig `json:"webdav,omitempty"`
+	Sftp   *SftpStorageConfig   `json:"sftp,omitempty"`
+}
+
+// An S3-compatible bucket, e.g. on AWS, MinIO, Backblaze B2, or Wasabi
+type S3StorageConfig struct {
+	// Endpoint is the URL of the S3 API, e.g. "https://s3.us-west-002.backblazeb2.com" or "http://localhost:9000"
+	Endpoint string `json:"endpoint"`
+
+	// Region defaults to "us-east-1" which most S3-compatible services accept
+	Region string `json:"region,omitempty"`
+	Bucket string `json:"bucket"`
+
+	// Prefix is prepended to the object keys which are the
+	// paths of the files relative to the download directory, e.g. "archive/"
+	Prefix string `json:"prefix,omitempty"`
+
+	// AccessKeyId and SecretAccessKey default to the
+	// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables
+	AccessKeyId     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+
+	// PathStyle uses "<endpoint>/<bucket>/<key>" URLs instead of
+	// "<bucket>.<endpoint host>/<key>", which is usually needed for MinIO
+	PathStyle bool `json:"path_style,omitempty"`
+}
+
+// A WebDAV folder, e.g. on Nextcloud or Synology
+type WebdavStorageConfig struct {
+	// Url is the WebDAV folder to write the files to in the same paths
+	// relative to the download directory, e.g. "https://cloud.example.com/remote.php/dav/files/<user>/Archive"
+	Url string `json:"url"`
+
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// ChunkUploadsUrl is Nextcloud's chunked upload folder, e.g. "https://cloud.example.com/remote.php/dav/uploads/<user>",
+	// to upload the files in chunks that are retried separately without having to spool the whole file to the local disk.
+	// The files are uploaded in a single request if it is empty.
+	ChunkUploadsUrl string `json:"chunk_uploads_url,omitempty"`
+
+	// ChunkSize is the size of the chunks, e.g. "50MB", which defaults to "10MB"
+	ChunkSize string `json:"chunk_size,omitempty"`
+}
+
+// A folder on an SSH server that is written to over SFTP
+type SftpStorageConfig struct {
+	// Host is the SSH server with an optional port, e.g. "nas.local" or "nas.local:2222"
+	Host string `json:"host"`
+	User string `json:"user"`
+
+	// Path is the folder on the server to write the files to in the same paths relative
+	// to the download directory, e.g. "/mnt/archive", or the user's home folder if empty
+	Path string `json:"path,omitempty"`
+
+	// KeyFile is the path to the private key, e.g. "/home/alice/.ssh/id_ed25519",
+	// with KeyPassphrase being its passphrase if it is encrypted
+	KeyFile       string `json:"key_file,omitempty"`
+	KeyPassphrase string `json:"key_passphrase,omitempty"`
+
+	// Password is used if the server does not accept the key or if there is no key
+	Password string `json:"password,omitempty"`
+
+	// KnownHostsFile is the known_hosts file to verify the server's host key
+	// with which defaults to the ".ssh/known_hosts" file in the user's home folder
+	KnownHostsFile string `json:"known_hosts_file,omitempty"`
 }
 
-// Returns the download path from the config file
+// Default values for the GDrive flags that will be used if the flags are not supplied
+type GdriveConfig struct {
+	ApiKey    string   `json:"api_key,omitempty"`
+	ApiKeys   []string `json:"api_keys,omitempty"` // more API keys to use when the API keys before them have exceeded their quota
+	Workers   int      `json:"workers,omitempty"`
+	MaxDepth  *int     `json:"max_depth,omitempty"` // pointer as 0 is a valid depth
+	FileTypes []string `json:"file_types,omitempty"`
+	MinSize   string   `json:"min_size,omitempty"`
+	MaxSize   string   `json:"max_size,omitempty"`
+}
+
+// Returns the API key followed by the other API keys without any empty or duplicate API keys
+func (c *GdriveConfig) GetApiKeys() []string {
+	var apiKeys []string
+	for _, apiKey := range append([]string{c.ApiKey}, c.ApiKeys...) {
+		if apiKey != "" && !SliceContains(apiKeys, apiKey) {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+	return apiKeys
+}
+
+// Returns the path to the config file in the app data folder
+func GetConfigFilePath() string {
+	return filepath.Join(APP_PATH, "config.json")
+}
+
+// Returns the parsed config file.
+//
+// If the config file does not exist, a default config will be returned instead.
+func LoadConfigFile() (*ConfigFile, error) {
+	config := &ConfigFile{
+		Language: "en",
+	}
+	configFilePath := GetConfigFilePath()
+	if !PathExists(configFilePath) {
+		return config, nil
+	}
+
+	configFile, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to read config file, more info => %v",
+			OS_ERROR,
+			err,
+		)
+	}
+
+	if err = json.Unmarshal(configFile, config); err != nil {
+		return nil, fmt.Errorf(
+			"error %d: failed to unmarshal config file, more info => %v",
+			JSON_ERROR,
+			err,
+		)
+	}
+	return config, nil
+}
+
+// Writes the data to the file which is only readable and writable by the current user.
+//
+// The permissions of an existing file are changed before writing to it
+// as os.WriteFile only sets the permissions when it creates the file.
+func WritePrivateFile(filePath string, data []byte) error {
+	if err := os.Chmod(filePath, 0600); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0600)
+}
+
+// Writes the given config to the config file
+func SaveConfigFile(config *ConfigFile) error {
+	configFile, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to marshal config file, more info => %v",
+			JSON_ERROR,
+			err,
+		)
+	}
+
+	os.MkdirAll(APP_PATH, 0666)
+	if err = WritePrivateFile(GetConfigFilePath(), configFile); err != nil {
+		return fmt.Errorf(
+			"error %d: failed to write config file, more info => %v",
+			OS_ERROR,
+			err,
+		)
+	}
+	return nil
+}
+
+// Returns the cookie file path of the website saved in the config file, if any
+func GetConfigCookieFile(website string) string {
+	config, err := LoadConfigFile()
+	if err != nil {
+		return ""
+	}
+	return config.CookieFiles[website]
+}
+
+// Saves the given cookie file paths of each website to the config file
+//
+// The map's keys should be the website, e.g. "fantia", and the values should be the cookie file paths.
+func SetConfigCookieFiles(cookieFiles map[string]string) error {
+	for website, cookieFile := range cookieFiles {
+		if !PathExists(cookieFile) {
+			return fmt.Errorf(
+				"error %d: %s cookie file at %s does not exist",
+				INPUT_ERROR,
+				GetReadableSiteStr(website),
+				cookieFile,
+			)
+		}
+	}
+
+	config, err := LoadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	if config.CookieFiles == nil {
+		config.CookieFiles = make(map[string]string, len(cookieFiles))
+	}
+	for website, cookieFile := range cookieFiles {
+		absPath, err := filepath.Abs(cookieFile)
+		if err != nil {
+			absPath = cookieFile
+		}
+		config.CookieFiles[website] = absPath
+	}
+	return SaveConfigFile(config)
+}
+
+// Returns the download path from the DOWNLOAD_PATH_ENV environment variable or the config file.
+//
+// The config file is left as it is if it cannot be read as it also holds the
+// API keys and the storage credentials, the error is reported when the config
+// file is loaded by the commands and by the "config doctor" command instead.
 func GetDefaultDownloadPath() string {
-	configFilePath := filepath.Join(APP_PATH, "config.json")
+	if envPath := os.Getenv(DOWNLOAD_PATH_ENV); envPath != "" {
+		return envPath
+	}
+
+	configFilePath := GetConfigFilePath()
 	if !PathExists(configFilePath) {
 		return ""
 	}
 
 	configFile, err := os.ReadFile(configFilePath)
 	if err != nil {
-		os.Remove(configFilePath)
 		return ""
 	}
 
 	var config ConfigFile
-	err = json.Unmarshal(configFile, &config)
-	if err != nil {
-		os.Remove(configFilePath)
+	if err = json.Unmarshal(configFile, &config); err != nil {
 		return ""
 	}
 
@@ -130,7 +646,7 @@ func saveConfig(newDownloadPath, configFilePath string) error {
 		)
 	}
 
-	err = os.WriteFile(configFilePath, configFile, 0666)
+	err = WritePrivateFile(configFilePath, configFile)
 	if err != nil {
 		return fmt.Errorf(
 			"error %d: failed to write config file, more info => %v",
@@ -178,7 +694,7 @@ func overwriteConfig(newDownloadPath, configFilePath string) error {
 		)
 	}
 
-	err = os.WriteFile(configFilePath, configFile, 0666)
+	err = WritePrivateFile(configFilePath, configFile)
 	if err != nil {
 		return fmt.Errorf(
 			"error %d: failed to write config file, more info => %v",
@@ -196,7 +712,7 @@ func SetDefaultDownloadPath(newDownloadPath string) error {
 	}
 
 	os.MkdirAll(APP_PATH, 0666)
-	configFilePath := filepath.Join(APP_PATH, "config.json")
+	configFilePath := GetConfigFilePath()
 	if !PathExists(configFilePath) {
 		return saveConfig(newDownloadPath, configFilePath)
 	}
@@ -24,6 +24,8 @@ func IsHttp3Supported(site string, isApi bool) bool {
 		return true
 	case KEMONO:
 		return false
+	case DLSITE:
+		return false
 	default:
 		panic(
 			fmt.Errorf(
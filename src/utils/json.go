@@ -3,55 +3,230 @@ package utils
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
 )
 
-func logJsonResponse(body []byte) {
-	var prettyJson bytes.Buffer
-	err := json.Indent(&prettyJson, body, "", "    ")
+// DebugDump contains the options for saving a copy of
+// the JSON responses from the APIs for debugging site API changes
+type DebugDump struct {
+	// Dir is the folder to save the JSON responses to
+	Dir string
+
+	// Pretty is a flag to indent the saved JSON responses
+	Pretty bool
+
+	// MaxFiles is the maximum number of saved JSON responses to keep, 0 means no limit
+	MaxFiles int
+
+	// MaxAge is the maximum age of the saved JSON responses to keep, 0 means no limit
+	MaxAge time.Duration
+}
+
+var (
+	debugDumpMu sync.Mutex
+	debugDump   = getDefaultDebugDump()
+
+	// debugDumpFiles is the filenames of the saved JSON responses from the oldest to the newest
+	// to delete the oldest ones without reading the folder again when debugDump.MaxFiles is exceeded
+	debugDumpFiles []string
+)
+
+// Returns the default debug dump options which is only enabled in DEBUG_MODE
+func getDefaultDebugDump() *DebugDump {
+	if !DEBUG_MODE {
+		return nil
+	}
+	return &DebugDump{Dir: "json", Pretty: true}
+}
+
+// Enables the saving of the JSON responses from the APIs with the given options
+// and deletes any saved JSON responses that exceed the retention limits.
+//
+// The saved JSON responses are only checked for their age once per run here
+// while the oldest ones are deleted as new ones are saved if debugDump.MaxFiles is exceeded.
+func SetDebugDump(dump *DebugDump) error {
+	debugDumpMu.Lock()
+	defer debugDumpMu.Unlock()
+	debugDump = dump
+	debugDumpFiles = nil
+	return deleteOldDebugDumps()
+}
+
+// Deletes the saved JSON responses that are older than debugDump.MaxAge
+// and the oldest ones that exceed debugDump.MaxFiles, and keeps track of the remaining ones in debugDumpFiles.
+//
+// Must be called with debugDumpMu held.
+func deleteOldDebugDumps() error {
+	if debugDump.MaxFiles <= 0 && debugDump.MaxAge <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(debugDump.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	// the filenames start with the time they were saved,
+	// hence os.ReadDir would have sorted them from the oldest to the newest
+	var dumpFiles []fs.DirEntry
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+			dumpFiles = append(dumpFiles, entry)
+		}
+	}
+
+	for idx, dumpFile := range dumpFiles {
+		shouldDelete := debugDump.MaxFiles > 0 && len(dumpFiles)-idx > debugDump.MaxFiles
+		if !shouldDelete && debugDump.MaxAge > 0 {
+			info, err := dumpFile.Info()
+			if err != nil {
+				return err
+			}
+			shouldDelete = info.ModTime().Before(time.Now().Add(-debugDump.MaxAge))
+		}
+
+		if !shouldDelete {
+			debugDumpFiles = append(debugDumpFiles, dumpFile.Name())
+			continue
+		}
+		if err := os.Remove(filepath.Join(debugDump.Dir, dumpFile.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds the newly saved JSON response to debugDumpFiles
+// and deletes the oldest saved JSON responses that exceed debugDump.MaxFiles.
+//
+// Must be called with debugDumpMu held.
+func addDebugDumpFile(filename string) error {
+	if debugDump.MaxFiles <= 0 {
+		return nil
+	}
+
+	debugDumpFiles = append(debugDumpFiles, filename)
+	for len(debugDumpFiles) > debugDump.MaxFiles {
+		err := os.Remove(filepath.Join(debugDump.Dir, debugDumpFiles[0]))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		debugDumpFiles = debugDumpFiles[1:]
+	}
+	return nil
+}
+
+// Returns the filename of the saved JSON response which contains the time and the request URL
+func getDebugDumpFilename(reqUrl *url.URL) string {
+	urlPart := DEBUG_DUMP_FILENAME_REGEX.ReplaceAllString(reqUrl.Host+reqUrl.Path, "_")
+	urlPart = TruncateString(urlPart, 100)
+	return fmt.Sprintf("%s_%s.json", time.Now().Format("2006-01-02_15-04-05.000"), urlPart)
+}
+
+// Saves a copy of the JSON response if the debug dump is enabled
+func logJsonResponse(reqUrl *url.URL, body []byte) {
+	debugDumpMu.Lock()
+	defer debugDumpMu.Unlock()
+	if debugDump == nil {
+		return
+	}
+
+	if debugDump.Pretty {
+		var prettyJson bytes.Buffer
+		err := json.Indent(&prettyJson, body, "", "    ")
+		if err != nil {
+			color.Red(
+				fmt.Sprintf(
+					"error %d: failed to indent JSON response body due to %v",
+					JSON_ERROR,
+					err,
+				),
+			)
+			return
+		}
+		body = prettyJson.Bytes()
+	}
+
+	filename := getDebugDumpFilename(reqUrl)
+	filePath := filepath.Join(debugDump.Dir, filename)
+	os.MkdirAll(filepath.Dir(filePath), 0666)
+	err := os.WriteFile(filePath, body, 0666)
 	if err != nil {
 		color.Red(
 			fmt.Sprintf(
-				"error %d: failed to indent JSON response body due to %v",
-				JSON_ERROR,
+				"error %d: failed to write JSON response body to file due to %v",
+				UNEXPECTED_ERROR,
 				err,
 			),
 		)
 		return
 	}
 
-	filename := fmt.Sprintf("saved_%s.json", time.Now().Format("2006-01-02_15-04-05"))
-	filePath := filepath.Join("json", filename)
-	os.MkdirAll(filepath.Dir(filePath), 0666)
-	err = os.WriteFile(filePath, prettyJson.Bytes(), 0666)
-	if err != nil {
+	if err := addDebugDumpFile(filename); err != nil {
 		color.Red(
 			fmt.Sprintf(
-				"error %d: failed to write JSON response body to file due to %v",
-				UNEXPECTED_ERROR,
+				"error %d: failed to delete old JSON responses due to %v",
+				OS_ERROR,
 				err,
 			),
 		)
 	}
 }
 
+// Maximum number of bytes of the response body to include
+// in the error message if the JSON response could not be decoded
+const jsonErrBodyLimit = 4 * 1024
+
+// Keeps the first bytes written to it up to its limit and discards the rest
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := l.limit - l.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			l.buf.Write(p[:remaining])
+		} else {
+			l.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
 // Read the response body and unmarshal it into a interface and returns it
+//
+// The response body is decoded as it is read unless the debug dump is enabled,
+// in which case the whole response body has to be read first to save a copy of it.
 func LoadJsonFromResponse(res *http.Response, format any) error {
+	debugDumpMu.Lock()
+	isDumping := debugDump != nil
+	debugDumpMu.Unlock()
+	if !isDumping {
+		return decodeJsonResponse(res, format)
+	}
+
 	body, err := ReadResBody(res)
 	if err != nil {
 		return err
 	}
 
-	// write to file if debug mode is on
-	if DEBUG_MODE {
-		logJsonResponse(body)
-	}
+	// write to file if debug mode is on or the debug dump was enabled
+	logJsonResponse(res.Request.URL, body)
 
 	if err = json.Unmarshal(body, &format); err != nil {
 		return fmt.Errorf(
@@ -65,6 +240,24 @@ func LoadJsonFromResponse(res *http.Response, format any) error {
 	return nil
 }
 
+// Decodes the JSON response body while it is being read and closes it
+// to avoid loading large responses entirely into memory
+func decodeJsonResponse(res *http.Response, format any) error {
+	defer res.Body.Close()
+	errBody := &limitedBuffer{limit: jsonErrBodyLimit}
+	decoder := json.NewDecoder(io.TeeReader(res.Body, errBody))
+	if err := decoder.Decode(&format); err != nil {
+		return fmt.Errorf(
+			"error %d: failed to unmarshal json response from %s due to %v\nBody: %s",
+			RESPONSE_ERROR,
+			res.Request.URL.String(),
+			err,
+			errBody.buf.String(),
+		)
+	}
+	return nil
+}
+
 func LoadJsonFromBytes(body []byte, format any) error {
 	if err := json.Unmarshal(body, &format); err != nil {
 		return fmt.Errorf(
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
+	"github.com/fatih/color"
+)
+
+const (
+	OUTPUT_TEXT = "text"
+	OUTPUT_JSON = "json"
+
+	// The maximum number of failed files kept until their errors are logged,
+	// as the errors of some failed files are never logged, e.g. when the download is cancelled
+	MAX_PENDING_FILE_ERRORS = 1000
+)
+
+var (
+	ACCEPTED_OUTPUT_FORMATS = []string{OUTPUT_TEXT, OUTPUT_JSON}
+
+	jsonOutput atomic.Bool
+
+	// Matches the site and error code at the start of the error messages, e.g. "pixiv fanbox error 1005:"
+	errPrefixRegex = regexp.MustCompile(`^(?:([a-z][a-z ]*?) )?error (\d{4})\b`)
+	errUrlRegex    = regexp.MustCompile(`https?://[^\s"',]+`)
+
+	errCategories = map[int]string{
+		DEV_ERROR:        "dev",
+		UNEXPECTED_ERROR: "unexpected",
+		OS_ERROR:         "os",
+		INPUT_ERROR:      "input",
+		CMD_ERROR:        "cmd",
+		CONNECTION_ERROR: "connection",
+		RESPONSE_ERROR:   "response",
+		DOWNLOAD_ERROR:   "download",
+		JSON_ERROR:       "json",
+		HTML_ERROR:       "html",
+		CAPTCHA_ERROR:    "captcha",
+		AUTH_ERROR:       "auth",
+	}
+
+	// Sites of the error message prefixes that are not the same as the site constants
+	errPrefixSites = map[string]string{
+		"pixiv mobile": PIXIV,
+		"pixiv fanbox": PIXIV_FANBOX,
+	}
+)
+
+// JsonError is the structured form of an error written to stderr when the output format is "json"
+type JsonError struct {
+	Category string `json:"category"`
+	Code     int    `json:"code,omitempty"`
+	Site     string `json:"site,omitempty"`
+	Post     string `json:"post,omitempty"`
+	Url      string `json:"url,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Returns true if the errors are written to stderr as JSON objects instead of coloured text
+func IsJsonOutput() bool {
+	return jsonOutput.Load()
+}
+
+// Sets the format of the errors shown to the user, either "text" or "json",
+// and returns a function to restore the text format.
+//
+// For "json", each logged error will be written to stderr as a JsonError on its own line.
+func SetOutputFormat(format string) (func(), error) {
+	switch format {
+	case OUTPUT_TEXT:
+		return func() {}, nil
+	case OUTPUT_JSON:
+		jsonOutput.Store(true)
+		unregister := events.Register(newJsonErrorHandler(os.Stderr))
+		return func() {
+			unregister()
+			jsonOutput.Store(false)
+		}, nil
+	default:
+		return nil, fmt.Errorf(
+			"error %d: output format must be one of %s but got %q",
+			INPUT_ERROR,
+			strings.Join(ACCEPTED_OUTPUT_FORMATS, ", "),
+			format,
+		)
+	}
+}
+
+// Returns the structured form of the error based on its message,
+// e.g. "pixiv error 1006: ..." will have the "response" category and the "pixiv" site.
+func NewJsonError(err error) *JsonError {
+	jsonErr := &JsonError{
+		Category: "unknown",
+		Message:  err.Error(),
+	}
+	if matched := errPrefixRegex.FindStringSubmatch(jsonErr.Message); matched != nil {
+		jsonErr.Code, _ = strconv.Atoi(matched[2])
+		if category, ok := errCategories[jsonErr.Code]; ok {
+			jsonErr.Category = category
+		}
+		if site, ok := errPrefixSites[matched[1]]; ok {
+			jsonErr.Site = site
+		} else if matched[1] != "" && isSite(matched[1]) {
+			jsonErr.Site = matched[1]
+		}
+	}
+	jsonErr.Url = errUrlRegex.FindString(jsonErr.Message)
+	return jsonErr
+}
+
+func isSite(site string) bool {
+	switch site {
+	case FANTIA, PIXIV, PIXIV_FANBOX, KEMONO, DLSITE:
+		return true
+	default:
+		return false
+	}
+}
+
+// Writes the logged errors as JSON objects alongside the post and URL of the file that failed to download, if any
+type jsonErrorHandler struct {
+	events.BaseHandler
+
+	mu    sync.Mutex
+	out   io.Writer
+	posts map[string]*events.Post // the resolved posts keyed by their folder
+	files map[string]*events.File // the files that failed to download keyed by their error message
+
+	// the keys of files in the order they were added to evict the oldest one
+	fileErrs []string
+}
+
+func newJsonErrorHandler(out io.Writer) *jsonErrorHandler {
+	return &jsonErrorHandler{
+		out:   out,
+		posts: make(map[string]*events.Post),
+		files: make(map[string]*events.File),
+	}
+}
+
+func (h *jsonErrorHandler) OnPostResolved(post *events.Post) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.posts[filepath.Clean(post.Folder)] = post
+}
+
+func (h *jsonErrorHandler) OnFileDone(file *events.File, err error) {
+	if err == nil {
+		return
+	}
+
+	// the error will be logged afterwards
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	errMsg := err.Error()
+	if _, ok := h.files[errMsg]; !ok {
+		h.fileErrs = append(h.fileErrs, errMsg)
+	}
+	h.files[errMsg] = file
+
+	for len(h.files) > MAX_PENDING_FILE_ERRORS {
+		oldest := h.fileErrs[0]
+		h.fileErrs = h.fileErrs[1:]
+		delete(h.files, oldest)
+	}
+}
+
+// Returns the post of the file by going up the file's parent folders, or nil if not found
+func (h *jsonErrorHandler) getPost(filePath string) *events.Post {
+	dir := filepath.Dir(filePath)
+	for {
+		if post, ok := h.posts[dir]; ok {
+			return post
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return nil
+		}
+		dir = parentDir
+	}
+}
+
+func (h *jsonErrorHandler) OnError(err error) {
+	jsonErr := NewJsonError(err)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if file, ok := h.files[jsonErr.Message]; ok {
+		delete(h.files, jsonErr.Message)
+		for idx, errMsg := range h.fileErrs {
+			if errMsg == jsonErr.Message {
+				h.fileErrs = append(h.fileErrs[:idx], h.fileErrs[idx+1:]...)
+				break
+			}
+		}
+		jsonErr.Url = file.Url
+		if post := h.getPost(file.FilePath); post != nil {
+			jsonErr.Site = post.Site
+			jsonErr.Post = post.Id
+		}
+	}
+
+	writeJsonError(h.out, jsonErr)
+}
+
+func writeJsonError(out io.Writer, jsonErr *JsonError) {
+	jsonBytes, err := json.Marshal(jsonErr)
+	if err != nil {
+		return
+	}
+	out.Write(append(jsonBytes, '\n'))
+}
+
+// Shows the error message to the user before exiting the program with the given exit code,
+// which is written to stderr as a JsonError if the output format is "json".
+func ExitWithErrorf(code int, format string, args ...any) {
+	errMsg := fmt.Sprintf(format, args...)
+	if IsJsonOutput() {
+		writeJsonError(os.Stderr, NewJsonError(errors.New(strings.TrimSpace(errMsg))))
+	} else {
+		color.Red(errMsg)
+	}
+	Exit(code)
+}
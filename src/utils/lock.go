@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	LOCK_FILENAME = ".cultured_downloader.lock"
+
+	lockPollInterval = 2 * time.Second
+)
+
+var errLockHeld = errors.New("the lock is held by another process")
+
+// Contents of a lock file to tell which run is holding the lock
+type lockInfo struct {
+	Pid       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Returns the details of the run holding the lock or nil if the lock file is empty or unreadable,
+// e.g. when the other run has only just taken the lock.
+//
+// The details are only used for the messages as the lock itself is held by the other process.
+func readLock(lockPath string) *lockInfo {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil
+	}
+
+	var info lockInfo
+	if err := json.Unmarshal(data, &info); err != nil || info.Pid <= 0 {
+		return nil
+	}
+	return &info
+}
+
+// Opens the lock file and takes an exclusive lock on it, returns errLockHeld if another process is holding it.
+//
+// The details of this run are written to the lock file only after the lock has been taken.
+func openLock(lockPath string) (*os.File, error) {
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	data, err := json.Marshal(&lockInfo{Pid: os.Getpid(), StartedAt: time.Now()})
+	if err == nil {
+		if err = f.Truncate(0); err == nil {
+			_, err = f.WriteAt(data, 0)
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Returns a description of the run holding the lock for the messages
+func getLockHolder(lockPath string) string {
+	holder := readLock(lockPath)
+	if holder == nil {
+		return "another run"
+	}
+	return fmt.Sprintf(
+		"another run (PID %d) started at %s",
+		holder.Pid,
+		holder.StartedAt.Local().Format("2006-01-02 15:04:05"),
+	)
+}
+
+// Acquires an advisory lock on the file path by taking an exclusive file lock on the lock file.
+//
+// If another run is holding the lock, an error will be returned unless wait is true,
+// in which case this will block until the other run has finished.
+// If force is true, the lock file will be replaced so that the lock is taken over from the other run.
+// As the lock is released by the operating system when the process holding it exits,
+// locks of runs that were killed or have exited early do not have to be taken over.
+//
+// Returns a function to release the lock.
+func AcquireLock(lockPath string, wait, force bool) (func(), error) {
+	os.MkdirAll(filepath.Dir(lockPath), 0755)
+
+	waiting := false
+	for {
+		f, err := openLock(lockPath)
+		if err == nil {
+			// the lock file is kept as removing it would allow another run
+			// to lock a new file while a waiting run locks the removed one
+			return func() { f.Close() }, nil
+		}
+		if !errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf(
+				"error %d: failed to lock the lock file at %s, more info => %v",
+				OS_ERROR,
+				lockPath,
+				err,
+			)
+		}
+
+		if force {
+			color.Yellow("WARNING: taking over the lock at %s from %s", lockPath, getLockHolder(lockPath))
+			if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf(
+					"error %d: failed to remove the lock file at %s, more info => %v",
+					OS_ERROR,
+					lockPath,
+					err,
+				)
+			}
+			force = false // only take over the lock once
+			continue
+		}
+
+		if !wait {
+			return nil, fmt.Errorf(
+				"error %d: %s is holding the lock at %s\n"+
+					"Use the --wait_for_lock flag to wait for it to finish or the --force_lock flag to take over the lock",
+				INPUT_ERROR,
+				getLockHolder(lockPath),
+				lockPath,
+			)
+		}
+		if !waiting {
+			waiting = true
+			color.Yellow("Waiting for %s holding the lock at %s to finish...", getLockHolder(lockPath), lockPath)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || windows)
+
+package utils
+
+import "os"
+
+// File locks are not supported on this platform, so overlapping runs are not prevented
+func lockFile(f *os.File) error {
+	return nil
+}
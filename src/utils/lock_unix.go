@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Takes an exclusive lock on the open file without blocking
+// which is released by the kernel when the file is closed or the process exits
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLockHeld
+	}
+	return err
+}
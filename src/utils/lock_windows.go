@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var lockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// Takes an exclusive lock on the open file without blocking
+// which is released by the system when the file is closed or the process exits
+func lockFile(f *os.File) error {
+	var overlapped syscall.Overlapped
+	ret, _, err := lockFileEx.Call(
+		f.Fd(),
+		uintptr(lockfileFailImmediately|lockfileExclusiveLock),
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if ret != 0 {
+		return nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return errLockHeld
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/events"
 	"github.com/fatih/color"
 )
 
@@ -90,13 +92,23 @@ func LogError(err error, errorMsg string, exit bool, level int) {
 		mainLogger.LogBasedOnLvlf(level, errorMsg + LogSuffix)
 	}
 
-	if exit {
+	if level == ERROR {
 		if err != nil {
-			color.Red(err.Error())
+			events.Error(err)
 		} else {
-			color.Red(errorMsg)
+			events.Error(errors.New(errorMsg))
 		}
-		os.Exit(1)
+	}
+
+	if exit {
+		if err == nil {
+			err = errors.New(errorMsg)
+		}
+		if !IsJsonOutput() {
+			// otherwise, the error has already been written to stderr by the JSON error handler
+			color.Red(err.Error())
+		}
+		Exit(GetExitCode(err))
 	}
 }
 
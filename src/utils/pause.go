@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// How often the pause file is checked for
+	pauseCheckInterval = time.Second
+)
+
+// Path to the control file which pauses the downloads of all running programs while it exists
+var PAUSE_FILE_PATH = filepath.Join(APP_PATH, "pause")
+
+// Path to the control file which only pauses the downloads of this program while it exists,
+// e.g. when the disk is running out of space, which is also removed by the "resume" command
+var RUN_PAUSE_FILE_PATH = filepath.Join(APP_PATH, fmt.Sprintf("pause_%d", os.Getpid()))
+
+var (
+	pauseMu        sync.Mutex
+	pausedPath     string // the pause file that exists, if any
+	pauseCheckedAt time.Time
+	pauseNotified  bool
+)
+
+// Returns the path of the pause file that pauses this program or an empty string if there is none
+func getPauseFilePath() string {
+	for _, filePath := range []string{PAUSE_FILE_PATH, RUN_PAUSE_FILE_PATH} {
+		if PathExists(filePath) {
+			return filePath
+		}
+	}
+	return ""
+}
+
+// Returns true if the pause file of all running programs or of this program exists,
+// checking them at most once every second
+func IsPaused() bool {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	if time.Since(pauseCheckedAt) >= pauseCheckInterval {
+		pausedPath = getPauseFilePath()
+		pauseCheckedAt = time.Now()
+	}
+	return pausedPath != ""
+}
+
+// Creates or removes the pause file at the file path
+func setPauseFile(filePath string, paused bool) error {
+	var err error
+	if paused {
+		os.MkdirAll(filepath.Dir(filePath), 0755)
+		err = os.WriteFile(filePath, nil, 0644)
+	} else if err = os.Remove(filePath); os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"error %d: failed to update the pause file at %s, more info => %v",
+			OS_ERROR,
+			filePath,
+			err,
+		)
+	}
+
+	// so that this program's workers do not have to wait for the next check
+	pauseMu.Lock()
+	pausedPath = getPauseFilePath()
+	pauseCheckedAt = time.Now()
+	pauseMu.Unlock()
+	return nil
+}
+
+// Creates or removes the pause file to pause or resume the downloads of all running programs.
+//
+// Resuming also removes the pause files of the programs that were paused on their own, see SetRunPaused.
+func SetPaused(paused bool) error {
+	if paused {
+		return setPauseFile(PAUSE_FILE_PATH, true)
+	}
+
+	runPauseFiles, _ := filepath.Glob(filepath.Join(APP_PATH, "pause_*"))
+	for _, runPauseFile := range append(runPauseFiles, PAUSE_FILE_PATH) {
+		if err := setPauseFile(runPauseFile, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Creates or removes the pause file of this program to only pause or resume its own downloads
+func SetRunPaused(paused bool) error {
+	return setPauseFile(RUN_PAUSE_FILE_PATH, paused)
+}
+
+// Prints whether the downloads have been paused or resumed
+// once for all the workers that are waiting
+func notifyPauseState(paused bool) {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	if pauseNotified == paused {
+		return
+	}
+
+	pauseNotified = paused
+	if paused {
+		color.Yellow(
+			"\nPaused the downloads, run the \"resume\" command or delete %s to resume...",
+			pausedPath,
+		)
+	} else {
+		color.Green("\nResumed the downloads")
+	}
+}
+
+// Blocks while the pause file of all running programs or of this program exists and returns the context's error if it is cancelled while paused.
+//
+// Used by the workers to stop starting new tasks and reading the response bodies while paused.
+func WaitIfPaused(ctx context.Context) error {
+	if !IsPaused() {
+		return nil
+	}
+
+	notifyPauseState(true)
+	ticker := time.NewTicker(pauseCheckInterval)
+	defer ticker.Stop()
+	for IsPaused() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	notifyPauseState(false)
+	return nil
+}
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var (
+	POST_ARCHIVE_EXTS = []string{".zip", ".rar", ".7z"}
+
+	// Matches the parts of a multi-part RAR archive other than the first
+	// part which are extracted together with the first part
+	RAR_PART_REGEX       = regexp.MustCompile(`(?i)\.part0*([2-9]|[1-9]\d+)\.rar$`)
+	RAR_FIRST_PART_REGEX = regexp.MustCompile(`(?i)\.part0*1$`)
+)
+
+// Returns the passwords saved in the EXTRACTED_PASSWORDS_FILENAME file of the post folder
+func getPostPasswords(postFolder string) []string {
+	passwordsFile, err := os.ReadFile(filepath.Join(postFolder, EXTRACTED_PASSWORDS_FILENAME))
+	if err != nil {
+		return nil
+	}
+
+	var passwords []string
+	for _, password := range strings.Split(string(passwordsFile), "\n") {
+		if password = strings.TrimRight(password, "\r"); password != "" {
+			passwords = append(passwords, password)
+		}
+	}
+	return passwords
+}
+
+// Returns true if the user can be prompted for a password, i.e. stdin is a terminal
+func CanPromptPassword() bool {
+	return IsTerminal(os.Stdin)
+}
+
+// Extracts the archive by trying each of the given passwords in order
+// and returns the password that worked or the error of the last password.
+func extractWithPasswords(ctx context.Context, src, dest string, passwords []string) (string, error) {
+	var err error
+	for _, password := range passwords {
+		if err = extractArchive(ctx, src, dest, password); err == nil || err == context.Canceled {
+			return password, err
+		}
+	}
+	return "", err
+}
+
+// Reads the password entered by the user which returns context.Canceled
+// without waiting for the user if the context is cancelled, e.g. by Ctrl+C
+func readPassword(ctx context.Context, reader *bufio.Reader) (string, error) {
+	type readResult struct {
+		input string
+		err   error
+	}
+	inputChan := make(chan readResult, 1)
+	go func() {
+		input, err := reader.ReadString('\n')
+		inputChan <- readResult{input: input, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		fmt.Println()
+		return "", context.Canceled
+	case result := <-inputChan:
+		return strings.TrimRight(result.input, "\r\n"), result.err
+	}
+}
+
+// Returns the archives in the post folder that have not been extracted yet,
+// i.e. there is no folder with the archive's name without its extension next to it
+func getUnextractedArchives(postFolder string) ([]string, error) {
+	var archives []string
+	err := filepath.WalkDir(postFolder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || RAR_PART_REGEX.MatchString(d.Name()) {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		if !SliceContains(POST_ARCHIVE_EXTS, ext) {
+			return nil
+		}
+		if !PathExists(getArchiveExtractPath(path)) {
+			archives = append(archives, path)
+		}
+		return nil
+	})
+	return archives, err
+}
+
+// Returns the folder to extract the archive to which is next to the archive,
+// e.g. "attachments/files" for "attachments/files.part1.rar"
+func getArchiveExtractPath(archivePath string) string {
+	name := strings.TrimSuffix(archivePath, filepath.Ext(archivePath))
+	if strings.EqualFold(filepath.Ext(archivePath), ".rar") {
+		name = RAR_FIRST_PART_REGEX.ReplaceAllString(name, "")
+	}
+	return name
+}
+
+// A post archive that has not been extracted yet
+type PostArchive struct {
+	FilePath   string
+	PostFolder string
+
+	triedPasswords []string // the passwords that Extract has tried
+}
+
+// Returns the archives that have not been extracted yet in each post folder that the given file paths are in
+func GetPostArchives(filePaths []string) ([]*PostArchive, []error) {
+	postFolders := make(map[string]struct{})
+	for _, filePath := range filePaths {
+		if postFolder := GetPostFolderFromPath(filePath); postFolder != "" && PathExists(postFolder) {
+			postFolders[postFolder] = struct{}{}
+		}
+	}
+
+	var postArchives []*PostArchive
+	var errSlice []error
+	for postFolder := range postFolders {
+		archives, err := getUnextractedArchives(postFolder)
+		if err != nil {
+			errSlice = append(errSlice, fmt.Errorf(
+				"error %d: failed to find the archives in %s, more info => %v",
+				OS_ERROR,
+				postFolder,
+				err,
+			))
+			continue
+		}
+		for _, archive := range archives {
+			postArchives = append(postArchives, &PostArchive{FilePath: archive, PostFolder: postFolder})
+		}
+	}
+	return postArchives, errSlice
+}
+
+// Extracts the archive by trying it without a password and then with
+// each of the passwords saved in the post folder's EXTRACTED_PASSWORDS_FILENAME file
+func (p *PostArchive) Extract(ctx context.Context) error {
+	dest := getArchiveExtractPath(p.FilePath)
+	p.triedPasswords = append([]string{""}, getPostPasswords(p.PostFolder)...)
+	if _, err := extractWithPasswords(ctx, p.FilePath, dest, p.triedPasswords); err != nil {
+		// remove the files that were extracted with the wrong passwords
+		os.RemoveAll(dest)
+		return err
+	}
+	return nil
+}
+
+// Prompts the user for the password of the archive that could not be extracted by Extract with the given error
+// until it works or the user enters an empty password, and saves the password that worked to the post folder's
+// EXTRACTED_PASSWORDS_FILENAME file.
+//
+// The passwords saved since Extract was called, e.g. the password of another archive in the same post folder,
+// are tried first before prompting the user.
+func (p *PostArchive) PromptPassword(ctx context.Context, reader *bufio.Reader, extractErr error) error {
+	dest := getArchiveExtractPath(p.FilePath)
+	err := extractErr
+	var newPasswords []string
+	for _, password := range getPostPasswords(p.PostFolder) {
+		if !SliceContains(p.triedPasswords, password) {
+			newPasswords = append(newPasswords, password)
+		}
+	}
+	if len(newPasswords) > 0 {
+		if _, err = extractWithPasswords(ctx, p.FilePath, dest, newPasswords); err == nil {
+			return nil
+		}
+	}
+
+	for err != context.Canceled {
+		color.Yellow("Failed to extract %s with the detected passwords, more info => %v", p.FilePath, err)
+		fmt.Print("Enter the password of the archive (leave empty to skip): ")
+		password, readErr := readPassword(ctx, reader)
+		if readErr == context.Canceled {
+			err = readErr
+			break
+		}
+		if readErr != nil || password == "" {
+			break
+		}
+
+		if err = extractArchive(ctx, p.FilePath, dest, password); err == nil {
+			SavePasswords([]string{password}, p.PostFolder)
+			return nil
+		}
+	}
+
+	// remove the files that were extracted with the wrong passwords
+	os.RemoveAll(dest)
+	return err
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	downloadedPostsMu    sync.Mutex
+	downloadedPostsCache = make(map[string]map[string]struct{})
+)
+
+// Returns the IDs of the posts that have already been downloaded to the given site folder
+// by looking for post folders, e.g. "[12345] Post Title", in the site folder and its subfolders.
+//
+// The post folders whose downloads did not finish are left out, see IsPostComplete,
+// so that they are resumed by the next run.
+//
+// The site folder is only walked once and the result is cached for subsequent calls.
+func GetDownloadedPostIds(siteFolderPath string) map[string]struct{} {
+	downloadedPostsMu.Lock()
+	defer downloadedPostsMu.Unlock()
+	if postIds, ok := downloadedPostsCache[siteFolderPath]; ok {
+		return postIds
+	}
+
+	postIds := make(map[string]struct{})
+	filepath.WalkDir(siteFolderPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+
+		matched := POST_FOLDER_REGEX.FindStringSubmatch(d.Name())
+		if matched == nil {
+			return nil
+		}
+		if IsPostComplete(path) {
+			postIds[matched[POST_FOLDER_REGEX.SubexpIndex("postId")]] = struct{}{}
+		}
+		return filepath.SkipDir // no need to walk inside the post folder
+	})
+	downloadedPostsCache[siteFolderPath] = postIds
+	return postIds
+}
+
+// Returns true if all of the files of the post in the post folder have been downloaded,
+// i.e. it has the POST_COMPLETE_FILENAME marker and not the POST_INCOMPLETE_FILENAME marker.
+//
+// The post folders without either marker are from the versions before the markers were
+// written or for the posts without any files to download, which are complete if they are not empty.
+func IsPostComplete(postFolder string) bool {
+	if PathExists(filepath.Join(postFolder, POST_INCOMPLETE_FILENAME)) {
+		return false
+	}
+	if PathExists(filepath.Join(postFolder, POST_COMPLETE_FILENAME)) {
+		return true
+	}
+
+	entries, err := os.ReadDir(postFolder)
+	return err == nil && len(entries) > 0
+}
+
+// Returns the ID of the post that the given file path is in, e.g. "12345" for "[12345] Post Title"
+//
+// Returns an empty string if the file path is not in a post folder.
+func GetPostIdFromPath(filePath string) string {
+	postFolder := GetPostFolderFromPath(filePath)
+	if postFolder == "" {
+		return ""
+	}
+
+	matched := POST_FOLDER_REGEX.FindStringSubmatch(filepath.Base(postFolder))
+	return matched[POST_FOLDER_REGEX.SubexpIndex("postId")]
+}
+
+// SeenTracker is used to stop the pagination of a creator's posts early
+// once a number of consecutive posts that have already been downloaded are encountered.
+type SeenTracker struct {
+	downloaded  map[string]struct{}
+	stopAfter   int
+	consecutive int
+}
+
+// Returns a new SeenTracker for the posts in the given site folder
+//
+// If stopAfter is less than 1, nil is returned which will never stop the pagination.
+func NewSeenTracker(siteFolderPath string, stopAfter int) *SeenTracker {
+	if stopAfter < 1 {
+		return nil
+	}
+	return &SeenTracker{
+		downloaded: GetDownloadedPostIds(siteFolderPath),
+		stopAfter:  stopAfter,
+	}
+}
+
+// Records the post ID in the order that the posts are paginated and
+// returns true if the pagination should be stopped.
+func (s *SeenTracker) Seen(postId string) bool {
+	if s == nil {
+		return false
+	}
+
+	if _, ok := s.downloaded[postId]; ok {
+		s.consecutive++
+	} else {
+		s.consecutive = 0
+	}
+	return s.consecutive >= s.stopAfter
+}
+
+// Same as Seen but for multiple post IDs
+//
+// Returns the number of post IDs that were recorded before the pagination
+// should be stopped and true if the pagination should be stopped.
+func (s *SeenTracker) SeenMultiple(postIds []string) (int, bool) {
+	for idx, postId := range postIds {
+		if s.Seen(postId) {
+			return idx + 1, true
+		}
+	}
+	return len(postIds), false
+}
@@ -8,16 +8,24 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/KJHJason/Cultured-Downloader-CLI/i18n"
 	"github.com/fatih/color"
 )
 
+// Returns true if the file, e.g. os.Stdout, is a terminal instead of being redirected to a file or a pipe
+func IsTerminal(file *os.File) bool {
+	fileInfo, err := file.Stat()
+	return err == nil && fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
 // Prints out a warning message to the user to not stop the program while it is downloading
 func PrintWarningMsg() {
-	color.Yellow("CAUTION:")
-	color.Yellow("Please do NOT terminate the program while it is downloading unless you really have to!")
-	color.Yellow("Doing so MAY result in incomplete downloads and corrupted files.")
+	color.Yellow(i18n.T("CAUTION:"))
+	color.Yellow(i18n.T("Please do NOT terminate the program while it is downloading unless you really have to!"))
+	color.Yellow(i18n.T("Doing so MAY result in incomplete downloads and corrupted files."))
 	fmt.Println()
 }
 
@@ -34,6 +42,8 @@ func GetReadableSiteStr(site string) string {
 		return PIXIV_TITLE
 	case KEMONO:
 		return KEMONO_TITLE
+	case DLSITE:
+		return DLSITE_TITLE
 	default:
 		// panic since this is a dev error
 		panic(
@@ -71,43 +81,71 @@ func ConvertPageNumToOffset(minPageNum, maxPageNum, perPage int) (int, int) {
 // check page nums if they are in the correct format.
 //
 // E.g. "1-10" is valid, but "0-9" is not valid because "0" is not accepted
-// If the page nums are not in the correct format, os.Exit(1) is called
+// If the page nums are not in the correct format, the program exits
 func ValidatePageNumInput(baseSliceLen int, pageNums []string, errMsgs []string) {
 	pageNumsLen := len(pageNums)
 	if baseSliceLen != pageNumsLen {
-		if len(errMsgs) > 0 {
-			for _, errMsg := range errMsgs {
-				color.Red(errMsg)
+		if len(errMsgs) == 0 {
+			errMsgs = []string{
+				fmt.Sprintf("Error: %d URLs provided, but %d page numbers provided.", baseSliceLen, pageNumsLen),
+				"Please provide the same number of page numbers as the number of URLs.",
 			}
-		} else {
-			color.Red("Error: %d URLs provided, but %d page numbers provided.", baseSliceLen, pageNumsLen)
-			color.Red("Please provide the same number of page numbers as the number of URLs.")
 		}
-		os.Exit(1)
+		ExitWithErrorf(EXIT_INPUT_ERROR, "%s", CombineStringsWithNewline(errMsgs...))
 	}
 
 	valid, outlier := SliceMatchesRegex(PAGE_NUM_REGEX, pageNums)
 	if !valid {
-		color.Red("Invalid page number format: %s", outlier)
-		color.Red("Please follow the format, \"1-10\", as an example.")
-		color.Red("Note that \"0\" are not accepted! E.g. \"0-9\" is invalid.")
-		os.Exit(1)
+		ExitWithErrorf(
+			EXIT_INPUT_ERROR,
+			"Invalid page number format: %s\n%s\n%s",
+			outlier,
+			"Please follow the format, \"1-10\", \"5-\", \"-5\", or \"all\", as an example.",
+			"Note that \"0\" are not accepted! E.g. \"0-9\" is invalid.",
+		)
 	}
 }
 
-// Returns the min, max, hasMaxNum, and error from the given string of "num" or "min-max"
+// Returns the min, max, hasMaxNum, and error from the given string of "num", "min-max", "min-", "-max", or "all"
 //
 // E.g.
 //
 //	"1-10" => 1, 10, true, nil
 //	"1" => 1, 1, true, nil
+//	"5-" => 5, 5, false, nil (from page 5 to the last page)
+//	"-5" => 1, 5, true, nil (the first 5 pages)
+//	"all" => 1, 1, false, nil (same as "")
 //	"" => 1, 1, false, nil (defaults to min = 1, max = inf)
 func GetMinMaxFromStr(numStr string) (int, int, bool, error) {
-	if numStr == "" {
+	numStr = strings.ToLower(strings.TrimSpace(numStr))
+	if numStr == "" || numStr == "all" {
 		// defaults to min = 1, max = inf
 		return 1, 1, false, nil
 	}
 
+	if strings.HasPrefix(numStr, "-") {
+		max, err := strconv.Atoi(numStr[1:])
+		if err != nil {
+			return -1, -1, false, fmt.Errorf(
+				"error %d: failed to convert max page number, %q, to int",
+				UNEXPECTED_ERROR,
+				numStr[1:],
+			)
+		}
+		return 1, max, true, nil
+	}
+	if strings.HasSuffix(numStr, "-") {
+		min, err := strconv.Atoi(strings.TrimSuffix(numStr, "-"))
+		if err != nil {
+			return -1, -1, false, fmt.Errorf(
+				"error %d: failed to convert min page number, %q, to int",
+				UNEXPECTED_ERROR,
+				strings.TrimSuffix(numStr, "-"),
+			)
+		}
+		return min, min, false, nil
+	}
+
 	var err error
 	var min, max int
 	if strings.Contains(numStr, "-") {
@@ -147,6 +185,63 @@ func GetMinMaxFromStr(numStr string) (int, int, bool, error) {
 	return min, max, true, nil
 }
 
+// Returns the number of bytes from the given file size string
+//
+// E.g.
+//
+//	"1024" => 1024, nil
+//	"1.5KB" => 1536, nil
+//	"500MB" => 524288000, nil
+func ParseFileSizeStr(sizeStr string) (int64, error) {
+	matched := FILE_SIZE_REGEX.FindStringSubmatch(strings.TrimSpace(sizeStr))
+	if matched == nil {
+		return -1, fmt.Errorf(
+			"error %d: invalid file size, %q, please use a format like \"500MB\" or \"1.5GB\"",
+			INPUT_ERROR,
+			sizeStr,
+		)
+	}
+
+	size, err := strconv.ParseFloat(matched[FILE_SIZE_REGEX.SubexpIndex("size")], 64)
+	if err != nil {
+		return -1, fmt.Errorf(
+			"error %d: failed to convert file size, %q, to float",
+			UNEXPECTED_ERROR,
+			sizeStr,
+		)
+	}
+
+	switch strings.ToUpper(matched[FILE_SIZE_REGEX.SubexpIndex("unit")]) {
+	case "KB":
+		size *= 1 << 10
+	case "MB":
+		size *= 1 << 20
+	case "GB":
+		size *= 1 << 30
+	case "TB":
+		size *= 1 << 40
+	}
+	return int64(size), nil
+}
+
+// Returns the file size in the same format as ParseFileSizeStr, e.g. 1610612736 => "1.50GB"
+func FormatFileSize(size int64) string {
+	units := []string{"KB", "MB", "GB", "TB"}
+	if size < 1<<10 {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	value := float64(size)
+	unit := ""
+	for _, unit = range units {
+		value /= 1 << 10
+		if value < 1<<10 {
+			break
+		}
+	}
+	return fmt.Sprintf("%.2f%s", value, unit)
+}
+
 // Returns a random time.Duration between the given min and max arguments
 func GetRandomTime(min, max float64) time.Duration {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -154,8 +249,47 @@ func GetRandomTime(min, max float64) time.Duration {
 	return time.Duration(randomDelay*1000) * time.Millisecond
 }
 
+// Range of the random delay in seconds between requests set by the "--delay" flag
+type delayRange struct {
+	min float64
+	max float64
+}
+
+var requestDelay *delayRange
+
+// Sets the range of the random delay in seconds before each request which
+// also replaces the default delays between retries and between Pixiv's API calls
+func SetRequestDelay(min, max float64) error {
+	if min < 0 || max < min {
+		return fmt.Errorf(
+			"error %d: invalid delay range of %v to %v seconds, the min must be at least 0 and not more than the max",
+			INPUT_ERROR,
+			min,
+			max,
+		)
+	}
+	requestDelay = &delayRange{min: min, max: max}
+	return nil
+}
+
+// Returns true if a range has been set by SetRequestDelay
+func HasRequestDelay() bool {
+	return requestDelay != nil
+}
+
+// Sleeps for a random duration within the range set by SetRequestDelay, if any
+func SleepRequestDelay() {
+	if requestDelay != nil {
+		time.Sleep(GetRandomTime(requestDelay.min, requestDelay.max))
+	}
+}
+
 // Returns a random time.Duration between the defined min and max delay values in the contants.go file
+// unless a range has been set by SetRequestDelay
 func GetRandomDelay() time.Duration {
+	if requestDelay != nil {
+		return GetRandomTime(requestDelay.min, requestDelay.max)
+	}
 	return GetRandomTime(MIN_RETRY_DELAY, MAX_RETRY_DELAY)
 }
 
@@ -186,6 +320,14 @@ func RemoveSliceDuplicates[T SliceTypes](s []T) []T {
 	return result
 }
 
+// Reverses the given slice in place and returns it.
+func ReverseSlice[T any](s []T) []T {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+	return s
+}
+
 // Used for removing duplicate IDs with its corresponding page number from the given slices.
 //
 // Returns the the new idSlice and pageSlice with the duplicates removed.
@@ -204,39 +346,30 @@ func RemoveDuplicateIdAndPageNum[T SliceTypes](idSlice, pageSlice []T) ([]T, []T
 
 // Checks if the slice of string contains the target str
 //
-// Otherwise, os.Exit(1) is called after printing error messages for the user to read
+// Otherwise, the program exits after printing error messages for the user to read
 func ValidateStrArgs(str string, slice, errMsgs []string) string {
 	if SliceContains(slice, str) {
 		return str
 	}
 
-	if len(errMsgs) > 0 {
-		for _, msg := range errMsgs {
-			color.Red(msg)
-		}
-	} else {
-		color.Red(
-			fmt.Sprintf("Input error, got: %s", str),
-		)
+	if len(errMsgs) == 0 {
+		errMsgs = []string{fmt.Sprintf("Input error, got: %s", str)}
 	}
-	color.Red(
-		fmt.Sprintf(
-			"Expecting one of the following: %s",
-			strings.TrimSpace(strings.Join(slice, ", ")),
-		),
+	ExitWithErrorf(
+		EXIT_INPUT_ERROR,
+		"%s\nExpecting one of the following: %s",
+		CombineStringsWithNewline(errMsgs...),
+		strings.TrimSpace(strings.Join(slice, ", ")),
 	)
-	os.Exit(1)
 	return ""
 }
 
 // Validates if the slice of strings contains only numbers
-// Otherwise, os.Exit(1) is called after printing error messages for the user to read
+// Otherwise, the program exits after printing error messages for the user to read
 func ValidateIds(args []string) {
 	for _, id := range args {
 		if !NUMBER_REGEX.MatchString(id) {
-			color.Red("Invalid ID: %s", id)
-			color.Red("IDs must be numbers!")
-			os.Exit(1)
+			ExitWithErrorf(EXIT_INPUT_ERROR, "Invalid ID: %s\nIDs must be numbers!", id)
 		}
 	}
 }
@@ -268,6 +401,78 @@ func DetectPasswordInText(text string) bool {
 	return false
 }
 
+// Extracts the passwords from the given text based on PASSWORD_REGEX, e.g. "abc123" from "パスワード：abc123"
+func ExtractPasswordsFromText(text string) []string {
+	var passwords []string
+	for _, matched := range PASSWORD_REGEX.FindAllStringSubmatch(text, -1) {
+		password := strings.TrimRight(matched[PASSWORD_REGEX_INDEX], ".,;:!?")
+		if password != "" && !SliceContains(passwords, password) {
+			passwords = append(passwords, password)
+		}
+	}
+	return passwords
+}
+
+var passwordsMu sync.Mutex
+
+// Appends the given passwords that have not been saved yet
+// to the EXTRACTED_PASSWORDS_FILENAME file in the post folder, one per line
+func SavePasswords(passwords []string, postFolderPath string) {
+	if len(passwords) == 0 {
+		return
+	}
+
+	passwordsMu.Lock()
+	defer passwordsMu.Unlock()
+	filePath := filepath.Join(postFolderPath, EXTRACTED_PASSWORDS_FILENAME)
+	var savedPasswords []string
+	if passwordsFile, err := os.ReadFile(filePath); err == nil {
+		savedPasswords = strings.Split(strings.ReplaceAll(string(passwordsFile), "\r\n", "\n"), "\n")
+	}
+
+	var newPasswords string
+	for _, password := range passwords {
+		if !SliceContains(savedPasswords, password) {
+			newPasswords += password + "\n"
+		}
+	}
+	if newPasswords == "" {
+		return
+	}
+
+	os.MkdirAll(postFolderPath, 0666)
+	passwordsFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		LogError(
+			fmt.Errorf(
+				"error %d: failed to open the passwords file %s, more info => %v",
+				OS_ERROR,
+				filePath,
+				err,
+			),
+			"",
+			false,
+			ERROR,
+		)
+		return
+	}
+	defer passwordsFile.Close()
+
+	if _, err := passwordsFile.WriteString(newPasswords); err != nil {
+		LogError(
+			fmt.Errorf(
+				"error %d: failed to write to the passwords file %s, more info => %v",
+				OS_ERROR,
+				filePath,
+				err,
+			),
+			"",
+			false,
+			ERROR,
+		)
+	}
+}
+
 // Detects if the given string contains any GDrive links and logs it if detected
 func DetectGDriveLinks(text, postFolderPath string, isUrl, logUrls bool) bool {
 	gdriveFilepath := filepath.Join(postFolderPath, GDRIVE_FILENAME)
@@ -276,6 +481,9 @@ func DetectGDriveLinks(text, postFolderPath string, isUrl, logUrls bool) bool {
 		containsGDriveLink = true
 	} else if strings.Contains(text, GDRIVE_URL) {
 		containsGDriveLink = true
+	} else if strings.Contains(text, GDOCS_URL) && GDRIVE_URL_REGEX.MatchString(text) {
+		// Google Docs, Sheets, Slides and Drawings links but not other links like Google Forms
+		containsGDriveLink = true
 	}
 
 	if !containsGDriveLink {
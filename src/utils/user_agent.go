@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+var (
+	rotatedUserAgents []string
+	userAgentIdx      uint64
+)
+
+const (
+	chromeUserAgent  = "Mozilla/5.0 (%s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	firefoxUserAgent = "Mozilla/5.0 (%s; rv:125.0) Gecko/20100101 Firefox/125.0"
+)
+
+// The platform part of the User-Agent header of each browser for your OS
+var browserPlatforms = map[string]map[string]string{
+	chromeUserAgent: {
+		"linux":   "X11; Linux x86_64",
+		"darwin":  "Macintosh; Intel Mac OS X 10_15_7",
+		"windows": "Windows NT 10.0; Win64; x64",
+	},
+	firefoxUserAgent: {
+		"linux":   "X11; Linux x86_64",
+		"darwin":  "Macintosh; Intel Mac OS X 10.15",
+		"windows": "Windows NT 10.0; Win64; x64",
+	},
+}
+
+// The browser whose User-Agent header is used by default for each website
+// which can be changed for a website if it starts rejecting the requests from the other browser
+var siteBrowsers = map[string]string{
+	FANTIA:       chromeUserAgent,
+	PIXIV:        chromeUserAgent,
+	PIXIV_FANBOX: chromeUserAgent,
+	KEMONO:       firefoxUserAgent,
+	DLSITE:       chromeUserAgent,
+}
+
+// Returns the built-in User-Agent header for the website which is of a recent version
+// of the website's default browser for your OS, or USER_AGENT if the website is not known
+func GetDefaultUserAgent(site string) string {
+	browser, ok := siteBrowsers[site]
+	if !ok {
+		return USER_AGENT
+	}
+	platform, ok := browserPlatforms[browser][runtime.GOOS]
+	if !ok {
+		return USER_AGENT
+	}
+	return fmt.Sprintf(browser, platform)
+}
+
+// Returns the User-Agent header to use for the website from the config file where the "site_user_agents"
+// take precedence over the "user_agent", or the built-in one for the website if neither are set.
+//
+// Returns an empty string if there are "user_agents" in the config file to rotate through for each request instead.
+func GetSiteUserAgent(site string, config *ConfigFile) string {
+	if config != nil {
+		if userAgent := config.SiteUserAgents[site]; userAgent != "" {
+			return userAgent
+		}
+		if config.UserAgent != "" {
+			return config.UserAgent
+		}
+		if len(config.UserAgents) > 0 {
+			return ""
+		}
+	}
+	return GetDefaultUserAgent(site)
+}
+
+// Sets the User-Agent headers to rotate through for the requests that have no User-Agent header set
+func SetUserAgentRotation(userAgents []string) {
+	rotatedUserAgents = nil
+	for _, userAgent := range userAgents {
+		if userAgent != "" {
+			rotatedUserAgents = append(rotatedUserAgents, userAgent)
+		}
+	}
+}
+
+// Returns the next User-Agent header in the rotation set by SetUserAgentRotation
+// or the default USER_AGENT if there is no rotation
+func NextUserAgent() string {
+	if len(rotatedUserAgents) == 0 {
+		return USER_AGENT
+	}
+	idx := atomic.AddUint64(&userAgentIdx, 1) - 1
+	return rotatedUserAgents[idx%uint64(len(rotatedUserAgents))]
+}